@@ -0,0 +1,330 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bargom/codeai/internal/ast"
+	"github.com/bargom/codeai/internal/auth/apikey"
+	"github.com/bargom/codeai/internal/database"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// apiKeyName is the name of the key to issue
+	apiKeyName string
+	// apiKeyRoles are the roles granted by the key to issue
+	apiKeyRoles []string
+	// apiKeyPermissions are the permissions granted by the key to issue
+	apiKeyPermissions []string
+	// apiKeyTTL is how long an issued key is valid (0 for no expiry)
+	apiKeyTTL time.Duration
+	// apiKeyOverlap is how long a rotated key keeps working
+	apiKeyOverlap time.Duration
+)
+
+// newAPIKeyCmd creates the apikey command with subcommands.
+func newAPIKeyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "apikey",
+		Short: "API key management commands",
+		Long: `Commands for managing the keys of apikey auth providers.
+
+Keys are kept in the database configured by the .cai file or the
+database flags, the same one the server uses.`,
+	}
+
+	cmd.AddCommand(newAPIKeyIssueCmd())
+	cmd.AddCommand(newAPIKeyListCmd())
+	cmd.AddCommand(newAPIKeyRevokeCmd())
+	cmd.AddCommand(newAPIKeyRotateCmd())
+
+	return cmd
+}
+
+// newAPIKeyIssueCmd creates the apikey issue subcommand.
+func newAPIKeyIssueCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "issue",
+		Short: "Issue a new API key",
+		Long: `Issue a new API key with the given roles and permissions.
+
+The key is printed once and cannot be retrieved later; only its hash
+is stored. When the .cai file declares roles, --role must name one of them.`,
+		Example: `  codeai apikey issue --name ci --role admin
+  codeai apikey issue --name reporting --permission reports:read --ttl 720h`,
+		Args: cobra.NoArgs,
+		RunE: runAPIKeyIssue,
+	}
+
+	cmd.Flags().StringVar(&apiKeyName, "name", "", "name describing the key's owner or purpose")
+	cmd.Flags().StringSliceVar(&apiKeyRoles, "role", nil, "role granted by the key (repeatable)")
+	cmd.Flags().StringSliceVar(&apiKeyPermissions, "permission", nil, "permission granted by the key (repeatable)")
+	cmd.Flags().DurationVar(&apiKeyTTL, "ttl", 0, "how long the key is valid (0 for no expiry)")
+	_ = cmd.MarkFlagRequired("name")
+	addDatabaseFlags(cmd)
+
+	return cmd
+}
+
+// newAPIKeyListCmd creates the apikey list subcommand.
+func newAPIKeyListCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List API keys",
+		Long:  `List all API keys with their status and last use. Key values are never shown.`,
+		Example: `  codeai apikey list
+  codeai apikey list -o json`,
+		Args: cobra.NoArgs,
+		RunE: runAPIKeyList,
+	}
+
+	addDatabaseFlags(cmd)
+
+	return cmd
+}
+
+// newAPIKeyRevokeCmd creates the apikey revoke subcommand.
+func newAPIKeyRevokeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "revoke <id>",
+		Short:   "Revoke an API key",
+		Long:    `Revoke an API key immediately.`,
+		Example: `  codeai apikey revoke 5f0c3e6a-1b2d-4c8e-9f7a-0d1e2f3a4b5c`,
+		Args:    cobra.ExactArgs(1),
+		RunE:    runAPIKeyRevoke,
+	}
+
+	addDatabaseFlags(cmd)
+
+	return cmd
+}
+
+// newAPIKeyRotateCmd creates the apikey rotate subcommand.
+func newAPIKeyRotateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rotate <id>",
+		Short: "Rotate an API key",
+		Long: `Issue a replacement for an API key with the same name, roles and
+permissions. The old key keeps working for the --overlap period so
+clients can switch over; with --overlap 0 it is revoked immediately.`,
+		Example: `  codeai apikey rotate 5f0c3e6a-1b2d-4c8e-9f7a-0d1e2f3a4b5c
+  codeai apikey rotate 5f0c3e6a-1b2d-4c8e-9f7a-0d1e2f3a4b5c --overlap 0`,
+		Args: cobra.ExactArgs(1),
+		RunE: runAPIKeyRotate,
+	}
+
+	cmd.Flags().DurationVar(&apiKeyOverlap, "overlap", 24*time.Hour, "how long the old key keeps working")
+	addDatabaseFlags(cmd)
+
+	return cmd
+}
+
+func runAPIKeyIssue(cmd *cobra.Command, args []string) error {
+	program, err := loadProgram()
+	if err != nil {
+		return err
+	}
+	if err := checkAPIKeyRoles(program, apiKeyRoles); err != nil {
+		return err
+	}
+
+	provider, closeDB, err := openAPIKeyProvider(cmd, program)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	raw, key, err := provider.Issue(cmd.Context(), apikey.IssueOptions{
+		Name:        apiKeyName,
+		Roles:       apiKeyRoles,
+		Permissions: apiKeyPermissions,
+		TTL:         apiKeyTTL,
+	})
+	if err != nil {
+		return err
+	}
+	return outputIssuedKey(cmd, raw, key)
+}
+
+func runAPIKeyList(cmd *cobra.Command, args []string) error {
+	program, err := loadProgram()
+	if err != nil {
+		return err
+	}
+	provider, closeDB, err := openAPIKeyProvider(cmd, program)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	keys, err := provider.List(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	if outputFormat == "json" {
+		encoder := json.NewEncoder(cmd.OutOrStdout())
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(keys)
+	}
+
+	if len(keys) == 0 {
+		fmt.Fprintln(cmd.OutOrStdout(), "No API keys")
+		return nil
+	}
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tPREFIX\tROLES\tSTATUS\tLAST USED")
+	now := time.Now()
+	for _, key := range keys {
+		lastUsed := "never"
+		if key.LastUsedAt != nil {
+			lastUsed = key.LastUsedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			key.ID, key.Name, key.Prefix, strings.Join(key.Roles, ","), apiKeyStatus(key, now), lastUsed)
+	}
+	return w.Flush()
+}
+
+func runAPIKeyRevoke(cmd *cobra.Command, args []string) error {
+	program, err := loadProgram()
+	if err != nil {
+		return err
+	}
+	provider, closeDB, err := openAPIKeyProvider(cmd, program)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	if err := provider.Revoke(cmd.Context(), args[0]); err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "API key %s revoked\n", args[0])
+	return nil
+}
+
+func runAPIKeyRotate(cmd *cobra.Command, args []string) error {
+	program, err := loadProgram()
+	if err != nil {
+		return err
+	}
+	provider, closeDB, err := openAPIKeyProvider(cmd, program)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	raw, key, err := provider.Rotate(cmd.Context(), args[0], apiKeyOverlap)
+	if err != nil {
+		return err
+	}
+	if err := outputIssuedKey(cmd, raw, key); err != nil {
+		return err
+	}
+	if outputFormat != "json" {
+		if apiKeyOverlap > 0 {
+			fmt.Fprintf(cmd.OutOrStdout(), "The old key %s stops working in %s\n", args[0], apiKeyOverlap)
+		} else {
+			fmt.Fprintf(cmd.OutOrStdout(), "The old key %s has been revoked\n", args[0])
+		}
+	}
+	return nil
+}
+
+// outputIssuedKey prints a newly issued key and its plaintext value.
+func outputIssuedKey(cmd *cobra.Command, raw string, key *apikey.Key) error {
+	if outputFormat == "json" {
+		encoder := json.NewEncoder(cmd.OutOrStdout())
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(struct {
+			*apikey.Key
+			Value string `json:"key"`
+		}{key, raw})
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "Issued API key %s (%s)\n", key.ID, key.Name)
+	if key.ExpiresAt != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Expires: %s\n", key.ExpiresAt.Format(time.RFC3339))
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "\n  %s\n\n", raw)
+	fmt.Fprintln(cmd.OutOrStdout(), "Store this key now; it cannot be shown again.")
+	return nil
+}
+
+// apiKeyStatus describes whether a key is active, expired or revoked.
+func apiKeyStatus(key *apikey.Key, now time.Time) string {
+	switch {
+	case key.Revoked():
+		return "revoked"
+	case key.Expired(now):
+		return "expired"
+	case key.ExpiresAt != nil:
+		return "active until " + key.ExpiresAt.Format(time.RFC3339)
+	default:
+		return "active"
+	}
+}
+
+// checkAPIKeyRoles verifies that the roles exist when the program declares
+// any roles.
+func checkAPIKeyRoles(program *ast.Program, roles []string) error {
+	if program == nil || len(roles) == 0 {
+		return nil
+	}
+	declared := make(map[string]bool)
+	for _, stmt := range program.Statements {
+		if role, ok := stmt.(*ast.RoleDecl); ok {
+			declared[role.Name] = true
+		}
+	}
+	if len(declared) == 0 {
+		return nil
+	}
+	for _, role := range roles {
+		if !declared[role] {
+			return fmt.Errorf("unknown role %q", role)
+		}
+	}
+	return nil
+}
+
+// openAPIKeyProvider connects to the key database and returns a provider
+// on it, along with a function closing the connection.
+func openAPIKeyProvider(cmd *cobra.Command, program *ast.Program) (*apikey.Provider, func(), error) {
+	dbConfig := buildDatabaseConfig(extractConfig(program))
+	conn, err := database.NewConnection(dbConfig)
+	if err != nil {
+		return nil, nil, fmt.Errorf("database connection failed: %w", err)
+	}
+	closeDB := func() { conn.Close() }
+
+	ctx, cancel := context.WithTimeout(cmd.Context(), 30*time.Second)
+	defer cancel()
+
+	var store apikey.Store
+	switch c := conn.(type) {
+	case *database.PostgresConnection:
+		sqlStore := apikey.NewSQLStore(c.DB)
+		err = sqlStore.CreateTable(ctx)
+		store = sqlStore
+	case *database.MongoDBConnection:
+		mongoStore := apikey.NewMongoStore(c.Client.Database())
+		err = mongoStore.EnsureIndexes(ctx)
+		store = mongoStore
+	default:
+		err = fmt.Errorf("unsupported database type %s", dbConfig.Type)
+	}
+	if err != nil {
+		closeDB()
+		return nil, nil, err
+	}
+
+	printVerbose(cmd, "Using API keys in %s\n", dbConfig.Type)
+	return apikey.NewProvider(store, apikey.Config{}), closeDB, nil
+}
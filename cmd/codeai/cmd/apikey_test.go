@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	clitest "github.com/bargom/codeai/cmd/codeai/testing"
+	"github.com/bargom/codeai/internal/auth/apikey"
+	"github.com/bargom/codeai/internal/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeyCommand(t *testing.T) {
+	t.Run("has subcommands", func(t *testing.T) {
+		rootCmd := NewRootCmd()
+		output, err := clitest.ExecuteCommand(rootCmd, "apikey", "--help")
+
+		require.NoError(t, err)
+		assert.Contains(t, output, "issue")
+		assert.Contains(t, output, "list")
+		assert.Contains(t, output, "revoke")
+		assert.Contains(t, output, "rotate")
+	})
+
+	t.Run("issue has flags", func(t *testing.T) {
+		rootCmd := NewRootCmd()
+		output, err := clitest.ExecuteCommand(rootCmd, "apikey", "issue", "--help")
+
+		require.NoError(t, err)
+		assert.Contains(t, output, "--name")
+		assert.Contains(t, output, "--role")
+		assert.Contains(t, output, "--permission")
+		assert.Contains(t, output, "--ttl")
+	})
+
+	t.Run("issue requires a name", func(t *testing.T) {
+		rootCmd := NewRootCmd()
+		_, err := clitest.ExecuteCommand(rootCmd, "apikey", "issue")
+
+		assert.Error(t, err)
+	})
+
+	t.Run("revoke requires an id", func(t *testing.T) {
+		rootCmd := NewRootCmd()
+		_, err := clitest.ExecuteCommand(rootCmd, "apikey", "revoke")
+
+		assert.Error(t, err)
+	})
+}
+
+func TestCheckAPIKeyRoles(t *testing.T) {
+	program, err := parser.Parse(`
+role admin {
+	permissions ["users:read", "users:write"]
+}
+`)
+	require.NoError(t, err)
+
+	assert.NoError(t, checkAPIKeyRoles(program, []string{"admin"}))
+	assert.ErrorContains(t, checkAPIKeyRoles(program, []string{"owner"}), `unknown role "owner"`)
+	assert.NoError(t, checkAPIKeyRoles(nil, []string{"owner"}), "roles are not checked without a .cai file")
+}
+
+func TestAPIKeyStatus(t *testing.T) {
+	now := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	later := now.Add(time.Hour)
+	earlier := now.Add(-time.Hour)
+
+	assert.Equal(t, "active", apiKeyStatus(&apikey.Key{}, now))
+	assert.Equal(t, "active until 2024-01-15T11:00:00Z", apiKeyStatus(&apikey.Key{ExpiresAt: &later}, now))
+	assert.Equal(t, "expired", apiKeyStatus(&apikey.Key{ExpiresAt: &earlier}, now))
+	assert.Equal(t, "revoked", apiKeyStatus(&apikey.Key{RevokedAt: &earlier}, now))
+}
@@ -0,0 +1,217 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/bargom/codeai/internal/codegen"
+	"github.com/bargom/codeai/internal/database"
+	"github.com/bargom/codeai/internal/event/bus"
+	"github.com/bargom/codeai/internal/event/replay"
+	eventrepository "github.com/bargom/codeai/internal/event/repository"
+	"github.com/bargom/codeai/internal/event/transport"
+	"github.com/bargom/codeai/internal/validator"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// replayTypes are the event types to replay; "*" matches any characters
+	replayTypes []string
+	// replaySince is the time of the oldest event to replay
+	replaySince string
+	// replayUntil is the time of the newest event to replay
+	replayUntil string
+	// replayHandlers are the handlers or subscribers to replay into
+	replayHandlers []string
+	// replayDryRun lists the events without replaying them
+	replayDryRun bool
+	// replayRate is the maximum number of events replayed per second
+	replayRate float64
+	// replayBatchSize is the number of events read from the store at once
+	replayBatchSize int
+	// replayCheckpoint is the name the replay's progress is saved under
+	replayCheckpoint string
+)
+
+// newEventsCmd creates the events command with subcommands.
+func newEventsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "events",
+		Short: "Event store commands",
+		Long: `Commands for the events the server persists to its PostgreSQL
+database, configured by the .cai file or the database flags.`,
+	}
+
+	cmd.AddCommand(newEventsReplayCmd())
+
+	return cmd
+}
+
+// newEventsReplayCmd creates the events replay subcommand.
+func newEventsReplayCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "replay",
+		Short: "Replay stored events into handlers",
+		Long: `Replay stored events, oldest first, into the selected event handlers
+of the .cai file, for example to rebuild a projection.
+
+Handlers are named by their action and target, such as
+"workflow:fulfil" or "emit:order_indexed"; "metrics" names the event
+metrics subscriber. Outbound webhooks and emails are suppressed during
+a replay, so replaying into "webhook:" handlers sends nothing, and
+events emitted by replayed handlers are neither persisted nor sent to
+other instances.
+
+With --checkpoint, progress is saved under the given name and an
+interrupted or failed replay resumes where it stopped when run again.`,
+		Example: `  codeai events replay --type 'order.*' --since 2026-01-01 --handler emit:order_indexed
+  codeai events replay --handler workflow:fulfil --dry-run
+  codeai events replay --handler metrics --rate 50 --checkpoint metrics-rebuild`,
+		Args: cobra.NoArgs,
+		RunE: runEventsReplay,
+	}
+
+	cmd.Flags().StringSliceVar(&replayTypes, "type", nil, "event type to replay, '*' matches any characters (repeatable; default all)")
+	cmd.Flags().StringVar(&replaySince, "since", "", "replay events at or after this time (2006-01-02 or RFC3339)")
+	cmd.Flags().StringVar(&replayUntil, "until", "", "replay events at or before this time (2006-01-02 or RFC3339)")
+	cmd.Flags().StringSliceVar(&replayHandlers, "handler", nil, "handler or subscriber to replay into (repeatable)")
+	cmd.Flags().BoolVar(&replayDryRun, "dry-run", false, "list the events that would be replayed without replaying them")
+	cmd.Flags().Float64Var(&replayRate, "rate", 0, "maximum events replayed per second (0 for unlimited)")
+	cmd.Flags().IntVar(&replayBatchSize, "batch-size", 100, "events read from the store at once")
+	cmd.Flags().StringVar(&replayCheckpoint, "checkpoint", "", "name to save progress under, so the replay can resume")
+	_ = cmd.MarkFlagRequired("handler")
+	addDatabaseFlags(cmd)
+
+	return cmd
+}
+
+func runEventsReplay(cmd *cobra.Command, args []string) error {
+	opts := replay.Options{
+		Targets:    replayHandlers,
+		DryRun:     replayDryRun,
+		Rate:       replayRate,
+		BatchSize:  replayBatchSize,
+		Checkpoint: replayCheckpoint,
+	}
+	for _, t := range replayTypes {
+		opts.Types = append(opts.Types, bus.EventType(t))
+	}
+	var err error
+	if opts.Since, err = parseReplayTime(replaySince); err != nil {
+		return fmt.Errorf("invalid --since: %w", err)
+	}
+	if opts.Until, err = parseReplayTime(replayUntil); err != nil {
+		return fmt.Errorf("invalid --until: %w", err)
+	}
+	if replayRate < 0 {
+		return fmt.Errorf("invalid --rate: must not be negative")
+	}
+
+	program, err := loadProgram()
+	if err != nil {
+		return err
+	}
+	if program == nil {
+		return fmt.Errorf("no .cai file found: the handlers to replay into are declared there")
+	}
+	if err := validator.New().Validate(program); err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
+
+	dbConfig := buildDatabaseConfig(extractConfig(program))
+	conn, err := database.NewConnection(dbConfig)
+	if err != nil {
+		return fmt.Errorf("database connection failed: %w", err)
+	}
+	defer conn.Close()
+	pgConn, ok := conn.(*database.PostgresConnection)
+	if !ok {
+		return fmt.Errorf("events are only stored in PostgreSQL, not %s", dbConfig.Type)
+	}
+
+	checkpoints := replay.NewSQLCheckpointStore(pgConn.DB)
+	if err := checkpoints.CreateTable(cmd.Context()); err != nil {
+		return err
+	}
+
+	// Events published outside the replay stay in this process instead of
+	// joining the servers' consumer group on a shared transport.
+	code, err := codegen.NewGenerator(&codegen.Config{
+		DatabaseURL:    buildDatabaseURL(dbConfig),
+		DBConnection:   conn,
+		EventTransport: transport.NewMemory(transport.DefaultConfig()),
+	}).GenerateFromAST(program)
+	if err != nil {
+		return fmt.Errorf("code generation failed: %w", err)
+	}
+	defer code.Events.Close()
+
+	replayer := code.NewReplayer(
+		eventrepository.NewPostgresEventRepository(pgConn.DB),
+		replay.WithCheckpoints(checkpoints),
+	)
+
+	if replayDryRun && outputFormat != "json" {
+		opts.OnEvent = func(event bus.Event, targets []string) {
+			fmt.Fprintf(cmd.OutOrStdout(), "%s  %s  %s -> %s\n",
+				event.Timestamp.UTC().Format(time.RFC3339), event.ID, event.Type, strings.Join(targets, ", "))
+		}
+	}
+
+	// An interrupted replay saves its checkpoint before exiting
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	result, err := replayer.Run(ctx, opts)
+	if errors.Is(err, replay.ErrUnknownTarget) {
+		return fmt.Errorf("%w (available: %s)", err, strings.Join(replayer.Targets(), ", "))
+	}
+	if outputErr := outputReplayResult(cmd, result); outputErr != nil && err == nil {
+		err = outputErr
+	}
+	if err != nil && replayCheckpoint != "" && !replayDryRun {
+		return fmt.Errorf("%w (progress saved to checkpoint %q)", err, replayCheckpoint)
+	}
+	return err
+}
+
+// outputReplayResult prints the outcome of a replay.
+func outputReplayResult(cmd *cobra.Command, result replay.Result) error {
+	if outputFormat == "json" {
+		encoder := json.NewEncoder(cmd.OutOrStdout())
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	}
+
+	switch {
+	case result.DryRun:
+		fmt.Fprintf(cmd.OutOrStdout(), "Would replay %d events\n", result.Replayed)
+	case result.Resumed:
+		fmt.Fprintf(cmd.OutOrStdout(), "Replayed %d events, resuming from checkpoint %s\n", result.Replayed, replayCheckpoint)
+	default:
+		fmt.Fprintf(cmd.OutOrStdout(), "Replayed %d events\n", result.Replayed)
+	}
+	return nil
+}
+
+// parseReplayTime parses a date or an RFC3339 time; empty values are the
+// zero time.
+func parseReplayTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected 2006-01-02 or RFC3339, got %q", value)
+	}
+	return t, nil
+}
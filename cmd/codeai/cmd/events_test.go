@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	clitest "github.com/bargom/codeai/cmd/codeai/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventsCommand(t *testing.T) {
+	t.Run("has subcommands", func(t *testing.T) {
+		rootCmd := NewRootCmd()
+		output, err := clitest.ExecuteCommand(rootCmd, "events", "--help")
+
+		require.NoError(t, err)
+		assert.Contains(t, output, "replay")
+	})
+
+	t.Run("replay has flags", func(t *testing.T) {
+		rootCmd := NewRootCmd()
+		output, err := clitest.ExecuteCommand(rootCmd, "events", "replay", "--help")
+
+		require.NoError(t, err)
+		assert.Contains(t, output, "--type")
+		assert.Contains(t, output, "--since")
+		assert.Contains(t, output, "--until")
+		assert.Contains(t, output, "--handler")
+		assert.Contains(t, output, "--dry-run")
+		assert.Contains(t, output, "--rate")
+		assert.Contains(t, output, "--checkpoint")
+	})
+
+	t.Run("replay requires a handler", func(t *testing.T) {
+		rootCmd := NewRootCmd()
+		_, err := clitest.ExecuteCommand(rootCmd, "events", "replay")
+
+		assert.Error(t, err)
+	})
+
+	t.Run("replay rejects invalid times", func(t *testing.T) {
+		rootCmd := NewRootCmd()
+		_, err := clitest.ExecuteCommand(rootCmd, "events", "replay", "--handler", "metrics", "--since", "yesterday")
+
+		assert.ErrorContains(t, err, "invalid --since")
+	})
+}
+
+func TestParseReplayTime(t *testing.T) {
+	got, err := parseReplayTime("2026-01-01")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), got)
+
+	got, err = parseReplayTime("2026-01-01T12:00:00+02:00")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC), got)
+
+	got, err = parseReplayTime("")
+	require.NoError(t, err)
+	assert.True(t, got.IsZero())
+
+	_, err = parseReplayTime("01/01/2026")
+	assert.Error(t, err)
+}
@@ -0,0 +1,211 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bargom/codeai/internal/database"
+	"github.com/bargom/codeai/internal/database/schema"
+	"github.com/bargom/codeai/internal/parser"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// migrateFrom is a previous .cai snapshot to diff against instead of the live database
+	migrateFrom string
+	// migrateDir is the directory migration files are written to
+	migrateDir string
+	// migrateName is the descriptive part of generated migration file names
+	migrateName string
+	// migrateAllowDestructive permits migrations that drop tables or columns or narrow types
+	migrateAllowDestructive bool
+	// migrateIgnoreTables lists live tables that are not managed by the DSL models
+	migrateIgnoreTables []string
+)
+
+// migrationTimeFormat is the version prefix format used for migration files.
+const migrationTimeFormat = "20060102150405"
+
+// newMigrateCmd creates the migrate command with subcommands.
+func newMigrateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Schema migration commands",
+		Long:  `Commands for generating database migrations from CodeAI model declarations.`,
+	}
+
+	cmd.AddCommand(newMigrateDiffCmd())
+
+	return cmd
+}
+
+// newMigrateDiffCmd creates the migrate diff subcommand.
+func newMigrateDiffCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff <file.cai>",
+		Short: "Generate a migration from model changes",
+		Long: `Compare the PostgreSQL models declared in a .cai file with the live
+database, or with a previous .cai snapshot given by --from, and write
+timestamped up and down migration files:
+
+  <dir>/<version>_<name>.up.sql
+  <dir>/<version>_<name>.down.sql
+
+Tables and columns are matched by name, so a renamed field is treated as
+a dropped and an added column. Changes that can lose data (dropping a
+table or column, narrowing a column type, removing enum values) are
+refused unless --allow-destructive is set.
+
+CodeAI's own tables, such as the events, outbox and webhook tables, are
+never compared; --ignore-table skips other tables the models do not
+manage.`,
+		Example: `  codeai migrate diff app.cai --from app.v1.cai
+  codeai migrate diff app.cai --db-name shop --name add_orders
+  codeai migrate diff app.cai --dry-run
+  codeai migrate diff app.cai --allow-destructive --ignore-table legacy_orders`,
+		Args: cobra.ExactArgs(1),
+		RunE: runMigrateDiff,
+	}
+
+	cmd.Flags().StringVar(&migrateFrom, "from", "", "previous .cai snapshot to diff against instead of the live database")
+	cmd.Flags().StringVar(&migrateDir, "dir", "migrations", "directory to write migration files to")
+	cmd.Flags().StringVar(&migrateName, "name", "schema_update", "migration name used in file names")
+	cmd.Flags().BoolVar(&migrateAllowDestructive, "allow-destructive", false, "allow changes that can lose data")
+	cmd.Flags().BoolVar(&migrateDryRun, "dry-run", false, "print the migration instead of writing files")
+	cmd.Flags().StringSliceVar(&migrateIgnoreTables, "ignore-table", nil, "live tables not managed by the models")
+	// PostgreSQL flags
+	cmd.Flags().StringVar(&dbHost, "db-host", "", "PostgreSQL host, overrides .cai config")
+	cmd.Flags().IntVar(&dbPort, "db-port", 0, "PostgreSQL port, overrides .cai config")
+	cmd.Flags().StringVar(&dbName, "db-name", "", "PostgreSQL database name, overrides .cai config")
+	cmd.Flags().StringVar(&dbUser, "db-user", "", "PostgreSQL user, overrides .cai config")
+	cmd.Flags().StringVar(&dbPassword, "db-password", "", "PostgreSQL password, overrides .cai config")
+	cmd.Flags().StringVar(&dbSSLMode, "db-sslmode", "", "PostgreSQL SSL mode, overrides .cai config")
+
+	return cmd
+}
+
+func runMigrateDiff(cmd *cobra.Command, args []string) error {
+	file := args[0]
+
+	program, err := parser.ParseFile(file)
+	if err != nil {
+		return fmt.Errorf("parsing %s: %w", file, err)
+	}
+	target, err := schema.FromProgram(program)
+	if err != nil {
+		return fmt.Errorf("building schema from %s: %w", file, err)
+	}
+
+	var current *schema.Schema
+	var source string
+	if migrateFrom != "" {
+		source = migrateFrom
+		previous, err := parser.ParseFile(migrateFrom)
+		if err != nil {
+			return fmt.Errorf("parsing %s: %w", migrateFrom, err)
+		}
+		current, err = schema.FromProgram(previous)
+		if err != nil {
+			return fmt.Errorf("building schema from %s: %w", migrateFrom, err)
+		}
+	} else {
+		cfg := buildDatabaseConfig(extractConfig(program))
+		if cfg.Type != database.DatabaseTypePostgres {
+			return fmt.Errorf("migrate diff requires a PostgreSQL database, got %s", cfg.Type)
+		}
+		source = fmt.Sprintf("%s:%d/%s", cfg.Postgres.Host, cfg.Postgres.Port, cfg.Postgres.Database)
+		printVerbose(cmd, "Inspecting PostgreSQL at %s\n", source)
+
+		current, err = inspectLiveSchema(cfg)
+		if err != nil {
+			return err
+		}
+	}
+
+	changes := schema.Diff(current, target)
+	if changes.Empty() {
+		fmt.Fprintf(cmd.OutOrStdout(), "No changes between %s and %s\n", source, file)
+		return nil
+	}
+
+	fmt.Fprintln(cmd.OutOrStdout(), "Changes:")
+	for _, ch := range changes.Changes {
+		if ch.Destructive {
+			fmt.Fprintf(cmd.OutOrStdout(), "  ! %s (destructive)\n", ch.Description)
+		} else {
+			fmt.Fprintf(cmd.OutOrStdout(), "  + %s\n", ch.Description)
+		}
+	}
+
+	if destructive := changes.Destructive(); len(destructive) > 0 && !migrateAllowDestructive {
+		return fmt.Errorf("%d destructive change(s) found; re-run with --allow-destructive to generate the migration", len(destructive))
+	}
+
+	header := fmt.Sprintf("-- Generated by codeai migrate diff\n-- From: %s\n-- To: %s\n\n", source, file)
+	up := header + changes.UpSQL()
+	down := header + changes.DownSQL()
+
+	if migrateDryRun {
+		fmt.Fprintf(cmd.OutOrStdout(), "\n-- up\n%s\n-- down\n%s", up, down)
+		return nil
+	}
+
+	version := time.Now().UTC().Format(migrationTimeFormat)
+	base := filepath.Join(migrateDir, version+"_"+migrationName(migrateName))
+	if err := os.MkdirAll(migrateDir, 0o755); err != nil {
+		return fmt.Errorf("creating %s: %w", migrateDir, err)
+	}
+	for path, content := range map[string]string{base + ".up.sql": up, base + ".down.sql": down} {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			return fmt.Errorf("writing %s: %w", path, err)
+		}
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "\nWrote %s.up.sql\nWrote %s.down.sql\n", base, base)
+	return nil
+}
+
+// inspectLiveSchema connects to PostgreSQL and reads its current schema.
+func inspectLiveSchema(cfg database.DatabaseConfig) (*schema.Schema, error) {
+	conn, err := database.NewConnection(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("database connection failed: %w", err)
+	}
+	defer conn.Close()
+
+	pgConn, ok := conn.(*database.PostgresConnection)
+	if !ok {
+		return nil, fmt.Errorf("migrate diff requires a PostgreSQL database")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	current, err := schema.NewManager(pgConn.DB).Inspect(ctx, migrateIgnoreTables...)
+	if err != nil {
+		return nil, fmt.Errorf("inspecting database: %w", err)
+	}
+	return current, nil
+}
+
+// migrationName normalizes a migration name for use in file names, e.g.
+// "Add Orders" -> "add_orders".
+func migrationName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(name)) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	if s := strings.Trim(b.String(), "_"); s != "" {
+		return s
+	}
+	return "schema_update"
+}
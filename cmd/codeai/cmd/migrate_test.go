@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	clitest "github.com/bargom/codeai/cmd/codeai/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const migrateV1 = `
+database postgres {
+    model User {
+        id: uuid, primary, auto
+        email: string, required, unique
+        nickname: string
+    }
+}
+`
+
+const migrateV2 = `
+database postgres {
+    model User {
+        id: uuid, primary, auto
+        email: string, required, unique
+        nickname: string
+        active: boolean, default(true)
+    }
+}
+`
+
+const migrateV3 = `
+database postgres {
+    model User {
+        id: uuid, primary, auto
+        email: string, required, unique
+    }
+}
+`
+
+func TestMigrateCommand(t *testing.T) {
+	t.Run("has diff subcommand", func(t *testing.T) {
+		rootCmd := NewRootCmd()
+		output, err := clitest.ExecuteCommand(rootCmd, "migrate", "--help")
+
+		require.NoError(t, err)
+		assert.Contains(t, output, "diff")
+	})
+
+	t.Run("diff has flags", func(t *testing.T) {
+		rootCmd := NewRootCmd()
+		output, err := clitest.ExecuteCommand(rootCmd, "migrate", "diff", "--help")
+
+		require.NoError(t, err)
+		assert.Contains(t, output, "--from")
+		assert.Contains(t, output, "--allow-destructive")
+		assert.Contains(t, output, "--dir")
+	})
+
+	t.Run("diff requires a file", func(t *testing.T) {
+		rootCmd := NewRootCmd()
+		_, err := clitest.ExecuteCommand(rootCmd, "migrate", "diff")
+
+		assert.Error(t, err)
+	})
+}
+
+func TestMigrateDiffCommand(t *testing.T) {
+	v1 := clitest.CreateTempFile(t, migrateV1)
+	defer os.Remove(v1)
+	v2 := clitest.CreateTempFile(t, migrateV2)
+	defer os.Remove(v2)
+	v3 := clitest.CreateTempFile(t, migrateV3)
+	defer os.Remove(v3)
+
+	t.Run("writes up and down files", func(t *testing.T) {
+		dir := t.TempDir()
+		rootCmd := NewRootCmd()
+		output, err := clitest.ExecuteCommand(rootCmd, "migrate", "diff", v2,
+			"--from", v1, "--dir", dir, "--name", "Add user active flag")
+
+		require.NoError(t, err)
+		assert.Contains(t, output, "+ add column users.active")
+
+		up, err := filepath.Glob(filepath.Join(dir, "*.up.sql"))
+		require.NoError(t, err)
+		require.Len(t, up, 1)
+		assert.Regexp(t, regexp.MustCompile(`^\d{14}_add_user_active_flag\.up\.sql$`), filepath.Base(up[0]))
+
+		upSQL, err := os.ReadFile(up[0])
+		require.NoError(t, err)
+		assert.Contains(t, string(upSQL), `ALTER TABLE "users" ADD COLUMN "active" BOOLEAN DEFAULT TRUE;`)
+
+		downPath := up[0][:len(up[0])-len(".up.sql")] + ".down.sql"
+		downSQL, err := os.ReadFile(downPath)
+		require.NoError(t, err)
+		assert.Contains(t, string(downSQL), `ALTER TABLE "users" DROP COLUMN "active";`)
+	})
+
+	t.Run("reports no changes", func(t *testing.T) {
+		dir := t.TempDir()
+		rootCmd := NewRootCmd()
+		output, err := clitest.ExecuteCommand(rootCmd, "migrate", "diff", v1, "--from", v1, "--dir", dir)
+
+		require.NoError(t, err)
+		assert.Contains(t, output, "No changes")
+		entries, _ := os.ReadDir(dir)
+		assert.Empty(t, entries)
+	})
+
+	t.Run("refuses destructive changes", func(t *testing.T) {
+		dir := t.TempDir()
+		rootCmd := NewRootCmd()
+		output, err := clitest.ExecuteCommand(rootCmd, "migrate", "diff", v3, "--from", v1, "--dir", dir)
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "--allow-destructive")
+		assert.Contains(t, output, "! drop column users.nickname (destructive)")
+		entries, _ := os.ReadDir(dir)
+		assert.Empty(t, entries)
+	})
+
+	t.Run("allows destructive changes when requested", func(t *testing.T) {
+		rootCmd := NewRootCmd()
+		output, err := clitest.ExecuteCommand(rootCmd, "migrate", "diff", v3,
+			"--from", v1, "--allow-destructive", "--dry-run")
+
+		require.NoError(t, err)
+		assert.Contains(t, output, "-- DESTRUCTIVE: drop column users.nickname")
+		assert.Contains(t, output, `ALTER TABLE "users" ADD COLUMN "nickname" VARCHAR(255);`)
+	})
+}
+
+func TestMigrationName(t *testing.T) {
+	assert.Equal(t, "add_orders", migrationName("Add Orders"))
+	assert.Equal(t, "v2_schema", migrationName("  v2-schema! "))
+	assert.Equal(t, "schema_update", migrationName("!!!"))
+}
@@ -62,6 +62,10 @@ func NewRootCmd() *cobra.Command {
 	cmd.AddCommand(newDeployCmd())
 	cmd.AddCommand(newConfigCmd())
 	cmd.AddCommand(newServerCmd())
+	cmd.AddCommand(newMigrateCmd())
+	cmd.AddCommand(newAPIKeyCmd())
+	cmd.AddCommand(newEventsCmd())
+	cmd.AddCommand(newWebhookCmd())
 	cmd.AddCommand(newCompletionCmd())
 
 	return cmd
@@ -80,6 +84,10 @@ func init() {
 	rootCmd.AddCommand(newDeployCmd())
 	rootCmd.AddCommand(newConfigCmd())
 	rootCmd.AddCommand(newServerCmd())
+	rootCmd.AddCommand(newMigrateCmd())
+	rootCmd.AddCommand(newAPIKeyCmd())
+	rootCmd.AddCommand(newEventsCmd())
+	rootCmd.AddCommand(newWebhookCmd())
 	rootCmd.AddCommand(newCompletionCmd())
 }
 
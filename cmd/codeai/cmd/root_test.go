@@ -87,6 +87,7 @@ func TestNewRootCmd(t *testing.T) {
 	assert.True(t, subcommands["deploy"])
 	assert.True(t, subcommands["config"])
 	assert.True(t, subcommands["server"])
+	assert.True(t, subcommands["apikey"])
 }
 
 func TestExecute(t *testing.T) {
@@ -3,21 +3,36 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"os"
-	"os/signal"
 	"path/filepath"
-	"syscall"
 	"time"
 
 	"github.com/bargom/codeai/internal/api"
 	"github.com/bargom/codeai/internal/api/handlers"
 	"github.com/bargom/codeai/internal/ast"
+	"github.com/bargom/codeai/internal/cache"
 	"github.com/bargom/codeai/internal/codegen"
 	"github.com/bargom/codeai/internal/database"
 	"github.com/bargom/codeai/internal/database/repository"
+	"github.com/bargom/codeai/internal/database/schema"
+	"github.com/bargom/codeai/internal/event/outbox"
+	"github.com/bargom/codeai/internal/event/replay"
+	eventrepository "github.com/bargom/codeai/internal/event/repository"
 	"github.com/bargom/codeai/internal/parser"
+	"github.com/bargom/codeai/internal/ratelimit"
+	"github.com/bargom/codeai/internal/rbac"
+	"github.com/bargom/codeai/internal/shutdown"
+	"github.com/bargom/codeai/internal/shutdown/hooks"
 	"github.com/bargom/codeai/internal/validator"
+	"github.com/bargom/codeai/internal/webhook/inbound"
+	"github.com/bargom/codeai/internal/webhook/queue"
+	webhookrepository "github.com/bargom/codeai/internal/webhook/repository"
+	"github.com/bargom/codeai/internal/webhook/retry"
+	"github.com/bargom/codeai/internal/webhook/service"
+	"github.com/bargom/codeai/internal/workflow"
+	"github.com/bargom/codeai/internal/workflow/engine"
 	"github.com/spf13/cobra"
 )
 
@@ -48,6 +63,23 @@ var (
 	caiFile string
 	// migrateDryRun shows pending migrations without applying
 	migrateDryRun bool
+	// schemaMode controls how DSL models are synced to PostgreSQL (create, verify or skip)
+	schemaMode string
+	// webhookMaxFailures is how many consecutive failed deliveries disable a webhook
+	webhookMaxFailures int
+	// webhookEndpointConcurrency limits the deliveries in flight to each webhook
+	webhookEndpointConcurrency int
+	// webhookEndpointRate limits the deliveries per second to each webhook
+	webhookEndpointRate int
+	// temporalHost is the Temporal server the workflow worker connects to
+	temporalHost string
+)
+
+// Schema sync modes for the server start command.
+const (
+	schemaModeCreate = "create"
+	schemaModeVerify = "verify"
+	schemaModeSkip   = "skip"
 )
 
 // newServerCmd creates the server command with subcommands.
@@ -73,10 +105,18 @@ func newServerStartCmd() *cobra.Command {
 		Long: `Start the CodeAI HTTP API server.
 
 The server provides REST endpoints for managing deployments,
-configurations, and executions.`,
+configurations, and executions.
+
+When the .cai file declares endpoints and PostgreSQL models, the tables
+for those models are created (--schema create, the default) or checked
+against the models (--schema verify) before the server starts.
+
+When it declares workflows, the server runs a Temporal worker for them on
+--temporal-host, and "do workflow" event handlers start them there.`,
 		Example: `  codeai server start
   codeai server start --port 3000
-  codeai server start --host 0.0.0.0 --port 8080`,
+  codeai server start --host 0.0.0.0 --port 8080
+  codeai server start --schema verify`,
 		RunE: runServerStart,
 	}
 
@@ -94,6 +134,18 @@ configurations, and executions.`,
 	// MongoDB flags
 	cmd.Flags().StringVar(&mongodbURI, "mongodb-uri", "", "MongoDB connection URI, overrides .cai config")
 	cmd.Flags().StringVar(&mongodbDatabase, "mongodb-database", "", "MongoDB database name, overrides .cai config")
+	// Schema flags
+	cmd.Flags().StringVar(&schemaMode, "schema", schemaModeCreate, "model schema handling: create, verify or skip")
+	// Webhook flags
+	cmd.Flags().IntVar(&webhookMaxFailures, "webhook-max-failures", service.DefaultConfig().MaxFailureCount,
+		"consecutive failed deliveries that disable a webhook, 0 to never disable")
+	cmd.Flags().IntVar(&webhookEndpointConcurrency, "webhook-endpoint-concurrency", 0,
+		"deliveries in flight to each webhook, 0 for no limit")
+	cmd.Flags().IntVar(&webhookEndpointRate, "webhook-endpoint-rate", 0,
+		"deliveries per second to each webhook on this instance, 0 for no limit")
+	// Workflow flags
+	cmd.Flags().StringVar(&temporalHost, "temporal-host", codegen.DefaultConfig().TemporalHost,
+		"Temporal server address, used when the .cai file declares workflows")
 
 	return cmd
 }
@@ -101,6 +153,12 @@ configurations, and executions.`,
 func runServerStart(cmd *cobra.Command, args []string) error {
 	addr := fmt.Sprintf("%s:%d", serverHost, serverPort)
 
+	switch schemaMode {
+	case schemaModeCreate, schemaModeVerify, schemaModeSkip:
+	default:
+		return fmt.Errorf("invalid --schema value %q (expected create, verify or skip)", schemaMode)
+	}
+
 	if verbose {
 		fmt.Fprintf(cmd.OutOrStdout(), "Starting server on %s\n", addr)
 	}
@@ -165,6 +223,9 @@ func runServerStart(cmd *cobra.Command, args []string) error {
 	// Create handler with repositories
 	handler := handlers.NewHandler(deploymentRepo, configRepo, executionRepo)
 
+	shutdownCfg := shutdown.DefaultConfig()
+	var shutdownHooks []shutdown.Hook
+
 	// Create router - use codegen if program has endpoints
 	var router http.Handler
 	if program != nil && hasEndpoints(program) {
@@ -174,19 +235,137 @@ func runServerStart(cmd *cobra.Command, args []string) error {
 			return fmt.Errorf("validation failed: %w", err)
 		}
 
+		// Create or verify model tables before serving endpoints
+		if err := syncSchema(cmd, conn, program); err != nil {
+			return err
+		}
+
+		// Record emitted events in an outbox when the program declares
+		// events, keep published events for replay, and keep webhooks and
+		// their deliveries in the database, where every instance retries
+		// failed deliveries
+		var eventOutbox outbox.Store
+		var eventStore eventrepository.EventRepository
+		var replayCheckpoints replay.CheckpointStore
+		var webhookRepo webhookrepository.WebhookRepository
+		var webhookOpts []service.Option
+		if hasEvents(program) {
+			if eventOutbox, err = newOutboxStore(conn); err != nil {
+				return fmt.Errorf("creating event outbox: %w", err)
+			}
+			if eventStore, err = newEventStore(conn); err != nil {
+				return fmt.Errorf("creating event store: %w", err)
+			}
+			if replayCheckpoints, err = newReplayCheckpointStore(conn); err != nil {
+				return fmt.Errorf("creating replay checkpoint store: %w", err)
+			}
+			if webhookRepo, err = newWebhookRepository(conn); err != nil {
+				return fmt.Errorf("creating webhook repository: %w", err)
+			}
+			if webhookRepo != nil {
+				if webhookOpts, err = webhookServiceOptions(); err != nil {
+					return err
+				}
+			}
+		}
+
+		// Remember the deliveries of received webhooks in the database, so
+		// that a delivery is emitted once whichever instance receives it
+		var receiptStore inbound.Store
+		if hasWebhookReceivers(program) {
+			if receiptStore, err = newWebhookReceiptStore(conn); err != nil {
+				return fmt.Errorf("creating webhook receipt store: %w", err)
+			}
+		}
+
+		// Keep roles changed through the admin endpoints in the database
+		rbacStorage, err := newRBACStorage(conn)
+		if err != nil {
+			return fmt.Errorf("creating role storage: %w", err)
+		}
+
+		genConfig := &codegen.Config{
+			DatabaseURL:         buildDatabaseURL(dbConfig),
+			DBConnection:        conn,
+			TemporalHost:        temporalHost,
+			Outbox:              eventOutbox,
+			EventRepository:     eventStore,
+			ReplayCheckpoints:   replayCheckpoints,
+			WebhookRepository:   webhookRepo,
+			WebhookOptions:      webhookOpts,
+			WebhookReceiptStore: receiptStore,
+			RBACStorage:         rbacStorage,
+		}
+
+		// Workflows run on Temporal; the worker starts once the generated
+		// code has loaded them
+		var workflowEngine *engine.Engine
+		if hasWorkflows(program) {
+			if workflowEngine, err = newWorkflowEngine(temporalHost); err != nil {
+				return fmt.Errorf("creating workflow engine: %w", err)
+			}
+			genConfig.WorkflowEngine = workflowEngine
+		}
+
 		// Generate code from AST
-		gen := codegen.NewGenerator(&codegen.Config{
-			DatabaseURL:  buildDatabaseURL(dbConfig),
-			DBConnection: conn,
-		})
+		gen := codegen.NewGenerator(genConfig)
 
 		generatedCode, err := gen.GenerateFromAST(program)
 		if err != nil {
 			return fmt.Errorf("code generation failed: %w", err)
 		}
 
+		if workflowEngine != nil {
+			workflow.RegisterDSLWorkflows(workflowEngine, generatedCode.Workflows, generatedCode.Integrations)
+			if err := workflowEngine.Start(context.Background()); err != nil {
+				return fmt.Errorf("starting workflow worker: %w", err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Workflow worker connected to %s\n", temporalHost)
+		}
+
+		// Webhooks are delivered through the delivery queue, and every
+		// instance retries failed deliveries
+		if webhookRepo != nil {
+			generatedCode.Webhooks.Start(context.Background())
+			retryHandler := retry.NewRetryHandler(webhookRepo, generatedCode.Webhooks, retry.WithLogger(slog.Default()))
+			retryHandler.Start(context.Background())
+			shutdownHooks = append(shutdownHooks, hooks.WebhookRetryShutdown(retryHandler))
+		}
+
+		// Clear cached roles when another instance changes them
+		if generatedCode.RBACInvalidator != nil {
+			listenCtx, cancel := context.WithCancel(context.Background())
+			listenDone := make(chan struct{})
+			go func() {
+				defer close(listenDone)
+				generatedCode.RBACInvalidator.Listen(listenCtx)
+			}()
+			shutdownHooks = append(shutdownHooks, hooks.WorkerPoolShutdown("rbac-invalidation", cancel, listenDone))
+		}
+
 		fmt.Fprintf(cmd.OutOrStdout(), "Generated %d endpoints from %s\n", generatedCode.EndpointCount, caiFilePath)
 		router = generatedCode.Router
+
+		// The relay publishes what is left in the outbox before the event
+		// bus closes
+		if generatedCode.Relay != nil {
+			generatedCode.Relay.Start(context.Background())
+			shutdownHooks = append(shutdownHooks, hooks.OutboxRelayShutdown(generatedCode.Relay, shutdownCfg.DrainTimeout))
+		}
+		// Replays started through the admin endpoints stop before the bus
+		// closes, and resume from their checkpoints when started again
+		if generatedCode.Replays != nil {
+			shutdownHooks = append(shutdownHooks, hooks.EventReplayShutdown(generatedCode.Replays))
+		}
+		shutdownHooks = append(shutdownHooks, hooks.EventBusShutdown(generatedCode.Events))
+		// Handlers draining from the bus may still start workflows and
+		// webhook deliveries
+		if workflowEngine != nil {
+			shutdownHooks = append(shutdownHooks, hooks.WorkflowEngineShutdown(workflowEngine))
+		}
+		if generatedCode.Webhooks != nil {
+			shutdownHooks = append(shutdownHooks, hooks.WebhookDeliveryShutdown(generatedCode.Webhooks))
+		}
 	} else {
 		// Use default API router
 		router = api.NewRouter(handler)
@@ -201,38 +380,229 @@ func runServerStart(cmd *cobra.Command, args []string) error {
 		IdleTimeout:  60 * time.Second,
 	}
 
-	// Handle graceful shutdown
-	done := make(chan bool, 1)
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-
-	go func() {
-		<-quit
-		fmt.Fprintln(cmd.OutOrStdout(), "\nShutting down server...")
-
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-		defer cancel()
-
-		server.SetKeepAlivesEnabled(false)
-		if err := server.Shutdown(ctx); err != nil {
-			fmt.Fprintf(cmd.ErrOrStderr(), "Server forced to shutdown: %v\n", err)
-		}
-
-		close(done)
-	}()
+	// Handle graceful shutdown: the server drains its requests before
+	// background workers and the event bus stop
+	manager := shutdown.NewManager(shutdownCfg, nil)
+	manager.RegisterHook(hooks.HTTPServerShutdown(server, shutdownCfg.DrainTimeout))
+	for _, hook := range shutdownHooks {
+		manager.RegisterHook(hook)
+	}
+	done := manager.ListenForSignals()
 
 	fmt.Fprintf(cmd.OutOrStdout(), "Server listening on %s\n", addr)
 
 	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		manager.Shutdown()
 		return fmt.Errorf("server error: %w", err)
 	}
 
+	fmt.Fprintln(cmd.OutOrStdout(), "\nShutting down server...")
 	<-done
+	for _, err := range manager.Errors() {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Shutdown error: %v\n", err)
+	}
 	fmt.Fprintln(cmd.OutOrStdout(), "Server stopped")
 
 	return nil
 }
 
+// syncSchema creates or verifies the PostgreSQL tables for the program's
+// models according to the --schema flag. It is a no-op for MongoDB
+// connections and programs without PostgreSQL models.
+func syncSchema(cmd *cobra.Command, conn database.Connection, program *ast.Program) error {
+	if schemaMode == schemaModeSkip {
+		return nil
+	}
+	pgConn, ok := conn.(*database.PostgresConnection)
+	if !ok {
+		return nil
+	}
+
+	s, err := schema.FromProgram(program)
+	if err != nil {
+		return fmt.Errorf("building schema: %w", err)
+	}
+	if len(s.Tables) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	mgr := schema.NewManager(pgConn.DB)
+	if schemaMode == schemaModeVerify {
+		if err := mgr.Verify(ctx, s); err != nil {
+			return fmt.Errorf("schema verification failed: %w", err)
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Verified %d tables\n", len(s.Tables))
+		return nil
+	}
+
+	if err := mgr.Apply(ctx, s); err != nil {
+		return fmt.Errorf("applying schema: %w", err)
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Schema ready: %d tables\n", len(s.Tables))
+	return nil
+}
+
+// newOutboxStore creates the event outbox on the server's database, with
+// its table or indexes.
+func newOutboxStore(conn database.Connection) (outbox.Store, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	switch c := conn.(type) {
+	case *database.PostgresConnection:
+		store := outbox.NewSQLStore(c.DB)
+		if err := store.CreateTable(ctx); err != nil {
+			return nil, err
+		}
+		return store, nil
+	case *database.MongoDBConnection:
+		store := outbox.NewMongoStore(c.Client.Database())
+		if err := store.EnsureIndexes(ctx); err != nil {
+			return nil, err
+		}
+		return store, nil
+	}
+	return nil, nil
+}
+
+// newEventStore creates the events table on the server's PostgreSQL
+// database, where `codeai events replay` reads events from. Events are not
+// stored on MongoDB.
+func newEventStore(conn database.Connection) (eventrepository.EventRepository, error) {
+	pgConn, ok := conn.(*database.PostgresConnection)
+	if !ok {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	repo := eventrepository.NewPostgresEventRepository(pgConn.DB)
+	if err := repo.CreateEventsTable(ctx); err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+// newReplayCheckpointStore creates the checkpoint table of the replays
+// started through the admin endpoints, next to the events table.
+func newReplayCheckpointStore(conn database.Connection) (replay.CheckpointStore, error) {
+	pgConn, ok := conn.(*database.PostgresConnection)
+	if !ok {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	store := replay.NewSQLCheckpointStore(pgConn.DB)
+	if err := store.CreateTable(ctx); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// webhookServiceOptions configures the server's webhook service: the
+// failures that disable a webhook, and the delivery queue with its limits
+// per webhook.
+func webhookServiceOptions() ([]service.Option, error) {
+	config := service.DefaultConfig()
+	config.MaxFailureCount = webhookMaxFailures
+
+	queueConfig := queue.DefaultConfig()
+	queueConfig.EndpointConcurrency = webhookEndpointConcurrency
+	queueOpts := []queue.Option{queue.WithLogger(slog.Default())}
+	if webhookEndpointRate > 0 {
+		limiter, err := ratelimit.New(ratelimit.Config{
+			Limit:    webhookEndpointRate,
+			Window:   time.Second,
+			Strategy: ratelimit.TokenBucket,
+			Prefix:   "webhook",
+		}, ratelimit.NewMemoryStore(cache.NewMemoryCache(cache.Config{})))
+		if err != nil {
+			return nil, fmt.Errorf("invalid --webhook-endpoint-rate: %w", err)
+		}
+		queueOpts = append(queueOpts, queue.WithRateLimiter(limiter))
+	}
+
+	return []service.Option{
+		service.WithConfig(config),
+		service.WithDeliveryQueue(queueConfig, queueOpts...),
+	}, nil
+}
+
+// newWebhookRepository creates the webhook repository on the server's
+// database, with its tables or indexes.
+func newWebhookRepository(conn database.Connection) (webhookrepository.WebhookRepository, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	switch c := conn.(type) {
+	case *database.PostgresConnection:
+		repo := webhookrepository.NewSQLRepository(c.DB)
+		if err := repo.CreateTables(ctx); err != nil {
+			return nil, err
+		}
+		return repo, nil
+	case *database.MongoDBConnection:
+		repo := webhookrepository.NewMongoRepository(c.Client.Database())
+		if err := repo.EnsureIndexes(ctx); err != nil {
+			return nil, err
+		}
+		return repo, nil
+	}
+	return nil, nil
+}
+
+// newWebhookReceiptStore creates the store of received webhook deliveries
+// on the server's database, with its table or indexes.
+func newWebhookReceiptStore(conn database.Connection) (inbound.Store, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	switch c := conn.(type) {
+	case *database.PostgresConnection:
+		store := inbound.NewSQLStore(c.DB)
+		if err := store.CreateTables(ctx); err != nil {
+			return nil, err
+		}
+		return store, nil
+	case *database.MongoDBConnection:
+		store := inbound.NewMongoStore(c.Client.Database())
+		if err := store.EnsureIndexes(ctx); err != nil {
+			return nil, err
+		}
+		return store, nil
+	}
+	return nil, nil
+}
+
+// newRBACStorage creates the storage of runtime roles and user role
+// assignments on the server's database, with its tables or indexes.
+func newRBACStorage(conn database.Connection) (rbac.Storage, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	switch c := conn.(type) {
+	case *database.PostgresConnection:
+		storage := rbac.NewSQLStorage(c.DB)
+		if err := storage.CreateTables(ctx); err != nil {
+			return nil, err
+		}
+		return storage, nil
+	case *database.MongoDBConnection:
+		storage := rbac.NewMongoStorage(c.Client.Database())
+		if err := storage.EnsureIndexes(ctx); err != nil {
+			return nil, err
+		}
+		return storage, nil
+	}
+	return nil, nil
+}
+
 // newServerMigrateCmd creates the server migrate subcommand.
 func newServerMigrateCmd() *cobra.Command {
 	cmd := &cobra.Command{
@@ -362,6 +732,40 @@ func findCaiFile(explicit string) string {
 	return matches[0]
 }
 
+// loadProgram parses the .cai file, if any, for its database config
+// and declarations.
+func loadProgram() (*ast.Program, error) {
+	path := findCaiFile(caiFile)
+	if path == "" {
+		if caiFile != "" {
+			return nil, fmt.Errorf("file not found: %s", caiFile)
+		}
+		return nil, nil
+	}
+	program, err := parser.ParseFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return program, nil
+}
+
+// addDatabaseFlags adds the flags selecting the database of a command
+// that works on the server's data outside the server.
+func addDatabaseFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&caiFile, "file", "f", "", "path to .cai file (auto-detects app.cai or *.cai in current dir)")
+	cmd.Flags().StringVar(&dbType, "db-type", "", "database type (postgres or mongodb), overrides .cai config")
+	// PostgreSQL flags
+	cmd.Flags().StringVar(&dbHost, "db-host", "", "PostgreSQL host, overrides .cai config")
+	cmd.Flags().IntVar(&dbPort, "db-port", 0, "PostgreSQL port, overrides .cai config")
+	cmd.Flags().StringVar(&dbName, "db-name", "", "PostgreSQL database name, overrides .cai config")
+	cmd.Flags().StringVar(&dbUser, "db-user", "", "PostgreSQL user, overrides .cai config")
+	cmd.Flags().StringVar(&dbPassword, "db-password", "", "PostgreSQL password, overrides .cai config")
+	cmd.Flags().StringVar(&dbSSLMode, "db-sslmode", "", "PostgreSQL SSL mode, overrides .cai config")
+	// MongoDB flags
+	cmd.Flags().StringVar(&mongodbURI, "mongodb-uri", "", "MongoDB connection URI, overrides .cai config")
+	cmd.Flags().StringVar(&mongodbDatabase, "mongodb-database", "", "MongoDB database name, overrides .cai config")
+}
+
 // extractConfig extracts the ConfigDecl from a parsed program.
 func extractConfig(program *ast.Program) *ast.ConfigDecl {
 	if program == nil {
@@ -441,16 +845,57 @@ func buildDatabaseConfig(fileConfig *ast.ConfigDecl) database.DatabaseConfig {
 	return cfg
 }
 
-// hasEndpoints checks if the program has endpoint declarations.
+// hasEndpoints checks if the program has endpoint or webhook receiver
+// declarations.
 func hasEndpoints(program *ast.Program) bool {
 	for _, stmt := range program.Statements {
-		if _, ok := stmt.(*ast.EndpointDecl); ok {
+		switch stmt.(type) {
+		case *ast.EndpointDecl, *ast.WebhookReceiverDecl:
+			return true
+		}
+	}
+	return false
+}
+
+// hasWebhookReceivers checks if the program has webhook receiver
+// declarations.
+func hasWebhookReceivers(program *ast.Program) bool {
+	for _, stmt := range program.Statements {
+		if _, ok := stmt.(*ast.WebhookReceiverDecl); ok {
 			return true
 		}
 	}
 	return false
 }
 
+// hasEvents checks if the program has event declarations.
+func hasEvents(program *ast.Program) bool {
+	for _, stmt := range program.Statements {
+		if _, ok := stmt.(*ast.EventDecl); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// hasWorkflows checks if the program has workflow declarations.
+func hasWorkflows(program *ast.Program) bool {
+	for _, stmt := range program.Statements {
+		if _, ok := stmt.(*ast.WorkflowDecl); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// newWorkflowEngine creates the engine whose worker runs the program's
+// workflows on the Temporal server at host.
+func newWorkflowEngine(host string) (*engine.Engine, error) {
+	cfg := engine.DefaultConfig()
+	cfg.TemporalHostPort = host
+	return engine.NewEngine(cfg)
+}
+
 // buildDatabaseURL constructs a database URL from config.
 func buildDatabaseURL(cfg database.DatabaseConfig) string {
 	switch cfg.Type {
@@ -43,6 +43,31 @@ func TestServerStartCommand(t *testing.T) {
 		require.NoError(t, err)
 		assert.Contains(t, output, "8080") // default port
 	})
+
+	t.Run("has schema flag", func(t *testing.T) {
+		rootCmd := NewRootCmd()
+		output, err := clitest.ExecuteCommand(rootCmd, "server", "start", "--help")
+
+		require.NoError(t, err)
+		assert.Contains(t, output, "--schema")
+		assert.Contains(t, output, "create, verify or skip")
+	})
+
+	t.Run("has webhook max failures flag", func(t *testing.T) {
+		rootCmd := NewRootCmd()
+		output, err := clitest.ExecuteCommand(rootCmd, "server", "start", "--help")
+
+		require.NoError(t, err)
+		assert.Contains(t, output, "--webhook-max-failures")
+	})
+
+	t.Run("rejects invalid schema mode", func(t *testing.T) {
+		rootCmd := NewRootCmd()
+		_, err := clitest.ExecuteCommand(rootCmd, "server", "start", "--schema", "drop")
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid --schema value")
+	})
 }
 
 func TestServerMigrateCommand(t *testing.T) {
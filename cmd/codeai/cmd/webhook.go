@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bargom/codeai/internal/webhook/security"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// webhookSecrets are the secrets a signature may be made with
+	webhookSecrets []string
+	// webhookScheme is the signature scheme of the webhook
+	webhookScheme string
+	// webhookHeaders are the request headers, as "Name: value"
+	webhookHeaders []string
+	// webhookBody is the file of the request body ("-" for stdin)
+	webhookBody string
+	// webhookTolerance is how old or new a Standard Webhooks timestamp may be
+	webhookTolerance time.Duration
+)
+
+// newWebhookCmd creates the webhook command with subcommands.
+func newWebhookCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "webhook",
+		Short: "Webhook commands",
+		Long:  `Commands for the webhooks the server delivers.`,
+	}
+
+	cmd.AddCommand(newWebhookVerifyCmd())
+
+	return cmd
+}
+
+// newWebhookVerifyCmd creates the webhook verify subcommand.
+func newWebhookVerifyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify",
+		Short: "Verify the signature of a received webhook",
+		Long: `Verify the signature of a webhook request, for example one captured
+by a receiver under development, against the webhook's secrets.
+
+With the standard-webhooks scheme the request needs the webhook-id,
+webhook-timestamp and webhook-signature headers, and its timestamp must
+be within --tolerance of the current time. With the default hmac-sha256
+scheme it needs the X-Webhook-Signature header.
+
+While a webhook's secret is rotated, requests are signed with both the
+old and the new secret; pass either with --secret, or both.`,
+		Example: `  codeai webhook verify --scheme standard-webhooks --secret whsec_MfKQ9r8GKYqrTwjUPD8ILPZIo2LaLaSw \
+    --header 'webhook-id: msg_p5jXN8AQM9LWM0D4loKWxJek' \
+    --header 'webhook-timestamp: 1614265330' \
+    --header 'webhook-signature: v1,g0hM9SsE+OTPJTGt/tmIKtSyZlE3uFJELVlNIOLJ1OE=' \
+    --body payload.json
+  cat payload.json | codeai webhook verify --secret s3cret --header 'X-Webhook-Signature: 9f86d0...'`,
+		Args: cobra.NoArgs,
+		RunE: runWebhookVerify,
+	}
+
+	cmd.Flags().StringArrayVar(&webhookSecrets, "secret", nil, "secret the request may be signed with (repeatable)")
+	cmd.Flags().StringVar(&webhookScheme, "scheme", security.SchemeHMAC, "signature scheme (hmac-sha256 or standard-webhooks)")
+	cmd.Flags().StringArrayVarP(&webhookHeaders, "header", "H", nil, "request header as 'Name: value' (repeatable)")
+	cmd.Flags().StringVar(&webhookBody, "body", "-", "file with the request body, '-' for stdin")
+	cmd.Flags().DurationVar(&webhookTolerance, "tolerance", security.DefaultTolerance, "maximum age of a standard-webhooks timestamp")
+	_ = cmd.MarkFlagRequired("secret")
+
+	return cmd
+}
+
+func runWebhookVerify(cmd *cobra.Command, args []string) error {
+	if err := security.ValidateScheme(webhookScheme); err != nil {
+		return err
+	}
+
+	header := make(http.Header)
+	for _, h := range webhookHeaders {
+		name, value, ok := strings.Cut(h, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return fmt.Errorf("invalid --header %q: expected 'Name: value'", h)
+		}
+		header.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+
+	var (
+		body []byte
+		err  error
+	)
+	if webhookBody == "-" {
+		body, err = io.ReadAll(cmd.InOrStdin())
+	} else {
+		body, err = os.ReadFile(webhookBody)
+	}
+	if err != nil {
+		return fmt.Errorf("reading body: %w", err)
+	}
+
+	if err := security.Verify(webhookScheme, webhookSecrets, header, body, time.Now(), webhookTolerance); err != nil {
+		return fmt.Errorf("signature invalid: %w", err)
+	}
+
+	fmt.Fprintln(cmd.OutOrStdout(), "Signature valid")
+	return nil
+}
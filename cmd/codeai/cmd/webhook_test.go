@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"testing"
+
+	clitest "github.com/bargom/codeai/cmd/codeai/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookCommand(t *testing.T) {
+	t.Run("has subcommands", func(t *testing.T) {
+		rootCmd := NewRootCmd()
+		output, err := clitest.ExecuteCommand(rootCmd, "webhook", "--help")
+
+		require.NoError(t, err)
+		assert.Contains(t, output, "verify")
+	})
+
+	t.Run("verify requires a secret", func(t *testing.T) {
+		rootCmd := NewRootCmd()
+		_, err := clitest.ExecuteCommand(rootCmd, "webhook", "verify")
+
+		assert.Error(t, err)
+	})
+
+	// The example of the Standard Webhooks specification
+	body := clitest.CreateTempFile(t, `{"test": 2432232314}`)
+	standardArgs := []string{
+		"webhook", "verify", "--scheme", "standard-webhooks",
+		"--header", "webhook-id: msg_p5jXN8AQM9LWM0D4loKWxJek",
+		"--header", "webhook-timestamp: 1614265330",
+		"--header", "webhook-signature: v1,g0hM9SsE+OTPJTGt/tmIKtSyZlE3uFJELVlNIOLJ1OE=",
+		"--body", body,
+	}
+
+	t.Run("verify accepts a valid signature", func(t *testing.T) {
+		rootCmd := NewRootCmd()
+		args := append([]string{}, standardArgs...)
+		args = append(args, "--secret", "whsec_b2xk", "--secret", "whsec_MfKQ9r8GKYqrTwjUPD8ILPZIo2LaLaSw", "--tolerance", "876000h")
+		output, err := clitest.ExecuteCommand(rootCmd, args...)
+
+		require.NoError(t, err)
+		assert.Contains(t, output, "Signature valid")
+	})
+
+	t.Run("verify rejects an old timestamp", func(t *testing.T) {
+		rootCmd := NewRootCmd()
+		args := append([]string{}, standardArgs...)
+		args = append(args, "--secret", "whsec_MfKQ9r8GKYqrTwjUPD8ILPZIo2LaLaSw")
+		_, err := clitest.ExecuteCommand(rootCmd, args...)
+
+		assert.ErrorContains(t, err, "invalid webhook timestamp")
+	})
+
+	t.Run("verify rejects a wrong secret", func(t *testing.T) {
+		rootCmd := NewRootCmd()
+		_, err := clitest.ExecuteCommand(rootCmd, "webhook", "verify", "--secret", "other",
+			"--header", "X-Webhook-Signature: 6b86b273ff34fce19d6b804eff5a3f5747ada4ea22f1d49c01e52ddb7875b4b", "--body", body)
+
+		assert.ErrorContains(t, err, "signature invalid")
+	})
+
+	t.Run("verify rejects malformed headers", func(t *testing.T) {
+		rootCmd := NewRootCmd()
+		_, err := clitest.ExecuteCommand(rootCmd, "webhook", "verify", "--secret", "s", "--header", "no-colon", "--body", body)
+
+		assert.ErrorContains(t, err, "invalid --header")
+	})
+}
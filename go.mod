@@ -18,6 +18,7 @@ require (
 	github.com/testcontainers/testcontainers-go/modules/redis v0.40.0
 	go.mongodb.org/mongo-driver v1.17.6
 	go.temporal.io/sdk v1.39.0
+	golang.org/x/crypto v0.46.0
 	modernc.org/sqlite v1.43.0
 )
 
@@ -105,7 +106,6 @@ require (
 	go.opentelemetry.io/otel/trace v1.38.0 // indirect
 	go.temporal.io/api v1.59.0 // indirect
 	go.yaml.in/yaml/v2 v2.4.2 // indirect
-	golang.org/x/crypto v0.46.0 // indirect
 	golang.org/x/exp v0.0.0-20250620022241-b7579e27df2b // indirect
 	golang.org/x/net v0.47.0 // indirect
 	golang.org/x/sync v0.19.0 // indirect
@@ -0,0 +1,218 @@
+// Package abac provides attribute-based authorization policies, evaluated
+// beside the role checks of package rbac.
+//
+// A policy names a resource and an action and gives the condition under
+// which the authenticated user may perform the action:
+//
+//	authorize post.update when resource.author_id == auth.sub or auth.role == "admin"
+//
+// Conditions use the expression language of package expr and may reference
+// three variables:
+//
+//   - resource: the record the action applies to, such as a row loaded
+//     with db.findOne
+//   - auth: the authenticated user; its claims plus sub, roles, role,
+//     permissions and authenticated
+//   - request: the request input
+//
+// Missing values are null, so comparisons with auth fields only make sense
+// on endpoints that require authentication.
+//
+// Besides checking a single resource, a policy can be turned into a row
+// filter for list queries: every value but the fields of resource is known
+// before the query runs, so the condition reduces to a WHERE clause.
+package abac
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/bargom/codeai/internal/auth"
+	"github.com/bargom/codeai/internal/workflow/expr"
+)
+
+// Variables available to policy conditions.
+const (
+	VarResource = "resource"
+	VarAuth     = "auth"
+	VarRequest  = "request"
+)
+
+// Policy errors.
+var (
+	ErrPolicyNotFound    = errors.New("policy not found")
+	ErrPolicyExists      = errors.New("policy already exists")
+	ErrInvalidPolicyName = errors.New("invalid policy name: expected resource.action")
+	ErrAccessDenied      = errors.New("access denied")
+	ErrNotFilterable     = errors.New("policy cannot be used as a row filter")
+)
+
+var policyNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*\.[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// Policy is a compiled authorization rule for an action on a resource.
+type Policy struct {
+	// Name is the policy name in the format "resource.action".
+	Name string
+	// Condition is the source of the condition.
+	Condition string
+
+	program *expr.Program
+}
+
+// Compile compiles a policy.
+func Compile(name, condition string) (*Policy, error) {
+	if !policyNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidPolicyName, name)
+	}
+	program, err := expr.CompileWithVars(condition, VarResource, VarAuth, VarRequest)
+	if err != nil {
+		return nil, fmt.Errorf("policy %s: %w", name, err)
+	}
+	return &Policy{Name: name, Condition: condition, program: program}, nil
+}
+
+// ResourceFields returns the sorted names of the resource fields the
+// condition references.
+func (p *Policy) ResourceFields() []string {
+	return p.program.VarFields(VarResource)
+}
+
+// Allowed reports whether user may perform the action on resource.
+func (p *Policy) Allowed(user *auth.User, resource, request any) (bool, error) {
+	allowed, err := p.program.EvalBool(p.env(user, resource, request))
+	if err != nil {
+		return false, fmt.Errorf("policy %s: %w", p.Name, err)
+	}
+	return allowed, nil
+}
+
+// Filter returns the row filter selecting the resources user may perform
+// the action on.
+func (p *Policy) Filter(user *auth.User, request any) (*RowFilter, error) {
+	f, err := p.program.Filter(p.env(user, nil, request), VarResource)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrNotFilterable, p.Name, err)
+	}
+	rf, err := newRowFilter(f)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrNotFilterable, p.Name, err)
+	}
+	return rf, nil
+}
+
+func (p *Policy) env(user *auth.User, resource, request any) expr.Env {
+	return expr.Env{Vars: map[string]any{
+		VarResource: resource,
+		VarAuth:     AuthVars(user),
+		VarRequest:  request,
+	}}
+}
+
+// AuthVars returns the auth variable for user: its token claims, with sub,
+// roles and permissions taken from the user. role is the role claim if the
+// token has one, else the user's only role.
+func AuthVars(user *auth.User) map[string]any {
+	if user == nil {
+		return map[string]any{"authenticated": false}
+	}
+
+	vars := make(map[string]any, len(user.Claims)+5)
+	for k, v := range user.Claims {
+		vars[k] = v
+	}
+	vars["authenticated"] = true
+	vars["sub"] = user.ID
+	vars["roles"] = toList(user.Roles)
+	vars["permissions"] = toList(user.Permissions)
+	if _, ok := vars["role"]; !ok && len(user.Roles) == 1 {
+		vars["role"] = user.Roles[0]
+	}
+	if user.Email != "" {
+		vars["email"] = user.Email
+	}
+	return vars
+}
+
+func toList(values []string) []any {
+	out := make([]any, len(values))
+	for i, v := range values {
+		out[i] = v
+	}
+	return out
+}
+
+// Engine holds the policies of an application.
+type Engine struct {
+	mu       sync.RWMutex
+	policies map[string]*Policy
+}
+
+// NewEngine creates an engine without policies.
+func NewEngine() *Engine {
+	return &Engine{policies: make(map[string]*Policy)}
+}
+
+// Add compiles and registers a policy.
+func (e *Engine) Add(name, condition string) error {
+	policy, err := Compile(name, condition)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, exists := e.policies[name]; exists {
+		return fmt.Errorf("%w: %s", ErrPolicyExists, name)
+	}
+	e.policies[name] = policy
+	return nil
+}
+
+// Policy returns the named policy.
+func (e *Engine) Policy(name string) (*Policy, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	p, ok := e.policies[name]
+	return p, ok
+}
+
+// Names returns the sorted names of the registered policies.
+func (e *Engine) Names() []string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	names := make([]string, 0, len(e.policies))
+	for name := range e.policies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Authorize checks that user may perform the action of the named policy on
+// resource. It returns an error wrapping ErrAccessDenied if not.
+func (e *Engine) Authorize(user *auth.User, name string, resource, request any) error {
+	policy, ok := e.Policy(name)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrPolicyNotFound, name)
+	}
+	allowed, err := policy.Allowed(user, resource, request)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return fmt.Errorf("%w by policy %s", ErrAccessDenied, name)
+	}
+	return nil
+}
+
+// Filter returns the row filter of the named policy for user.
+func (e *Engine) Filter(user *auth.User, name string, request any) (*RowFilter, error) {
+	policy, ok := e.Policy(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrPolicyNotFound, name)
+	}
+	return policy.Filter(user, request)
+}
@@ -0,0 +1,167 @@
+package abac
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bargom/codeai/internal/auth"
+	"github.com/bargom/codeai/internal/query"
+)
+
+const ownerOrAdmin = `resource.author_id == auth.sub or auth.role == "admin"`
+
+func TestCompile(t *testing.T) {
+	p, err := Compile("post.update", ownerOrAdmin)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"author_id"}, p.ResourceFields())
+
+	_, err = Compile("post", ownerOrAdmin)
+	assert.ErrorIs(t, err, ErrInvalidPolicyName)
+	_, err = Compile("post.update", "resource.author_id ==")
+	assert.Error(t, err)
+	_, err = Compile("post.update", "user.id == resource.author_id")
+	assert.ErrorContains(t, err, `unknown identifier "user"`)
+}
+
+func TestEngine_Authorize(t *testing.T) {
+	engine := NewEngine()
+	require.NoError(t, engine.Add("post.update", ownerOrAdmin))
+	require.NoError(t, engine.Add("post.publish", `resource.status == request.from and contains(auth.roles, "editor")`))
+	assert.ErrorIs(t, engine.Add("post.update", "true"), ErrPolicyExists)
+	assert.Equal(t, []string{"post.publish", "post.update"}, engine.Names())
+
+	post := map[string]any{"id": "p1", "author_id": "u1", "status": "draft"}
+	author := &auth.User{ID: "u1", Roles: []string{"writer"}}
+	other := &auth.User{ID: "u2", Roles: []string{"writer"}}
+	admin := &auth.User{ID: "u3", Roles: []string{"admin"}}
+	editor := &auth.User{ID: "u4", Roles: []string{"writer", "editor"}}
+
+	assert.NoError(t, engine.Authorize(author, "post.update", post, nil))
+	assert.NoError(t, engine.Authorize(admin, "post.update", post, nil))
+	assert.ErrorIs(t, engine.Authorize(other, "post.update", post, nil), ErrAccessDenied)
+	assert.ErrorIs(t, engine.Authorize(nil, "post.update", post, nil), ErrAccessDenied)
+
+	request := map[string]any{"from": "draft"}
+	assert.NoError(t, engine.Authorize(editor, "post.publish", post, request))
+	assert.ErrorIs(t, engine.Authorize(author, "post.publish", post, request), ErrAccessDenied)
+
+	assert.ErrorIs(t, engine.Authorize(author, "post.delete", post, nil), ErrPolicyNotFound)
+}
+
+func TestAuthVars(t *testing.T) {
+	vars := AuthVars(&auth.User{
+		ID:     "u1",
+		Email:  "ada@example.com",
+		Roles:  []string{"admin", "writer"},
+		Claims: map[string]any{"tenant": "acme", "sub": "ignored"},
+	})
+	assert.Equal(t, "u1", vars["sub"])
+	assert.Equal(t, "acme", vars["tenant"])
+	assert.Equal(t, []any{"admin", "writer"}, vars["roles"])
+	assert.Equal(t, true, vars["authenticated"])
+	assert.NotContains(t, vars, "role")
+
+	vars = AuthVars(&auth.User{ID: "u1", Roles: []string{"admin"}})
+	assert.Equal(t, "admin", vars["role"])
+	vars = AuthVars(&auth.User{ID: "u1", Roles: []string{"admin"}, Claims: map[string]any{"role": "owner"}})
+	assert.Equal(t, "owner", vars["role"])
+
+	assert.Equal(t, map[string]any{"authenticated": false}, AuthVars(nil))
+}
+
+func TestEngine_Filter(t *testing.T) {
+	engine := NewEngine()
+	require.NoError(t, engine.Add("post.update", ownerOrAdmin))
+	require.NoError(t, engine.Add("post.read", `resource.published or resource.author_id == auth.sub`))
+	require.NoError(t, engine.Add("post.archive", `auth.role == "admin" and resource.status != "archived"`))
+	require.NoError(t, engine.Add("post.tagged", `contains(resource.tags, "go")`))
+
+	author := &auth.User{ID: "u1", Roles: []string{"writer"}}
+	admin := &auth.User{ID: "u3", Roles: []string{"admin"}}
+
+	f, err := engine.Filter(author, "post.update", nil)
+	require.NoError(t, err)
+	assert.False(t, f.None)
+	assert.Equal(t, &query.WhereClause{
+		Operator:   query.LogicalAnd,
+		Conditions: []query.Condition{{Field: "author_id", Operator: query.OpEquals, Value: "u1"}},
+	}, f.Where)
+
+	f, err = engine.Filter(admin, "post.update", nil)
+	require.NoError(t, err)
+	assert.Nil(t, f.Where)
+	assert.False(t, f.None)
+
+	f, err = engine.Filter(author, "post.archive", nil)
+	require.NoError(t, err)
+	assert.True(t, f.None)
+
+	f, err = engine.Filter(admin, "post.archive", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []query.Condition{{Nested: &query.WhereClause{
+		Operator: query.LogicalOr,
+		Conditions: []query.Condition{
+			{Field: "status", Operator: query.OpNotEquals, Value: "archived"},
+			{Field: "status", Operator: query.OpIsNull},
+		},
+	}}}, f.Where.Conditions)
+
+	_, err = engine.Filter(author, "post.tagged", nil)
+	assert.ErrorIs(t, err, ErrNotFilterable)
+	_, err = engine.Filter(author, "post.delete", nil)
+	assert.ErrorIs(t, err, ErrPolicyNotFound)
+}
+
+func TestRowFilter_CompilesToSQL(t *testing.T) {
+	engine := NewEngine()
+	require.NoError(t, engine.Add("post.read",
+		`resource.published and resource.deleted_at == null or resource.author_id == auth.sub`))
+
+	f, err := engine.Filter(&auth.User{ID: "u1"}, "post.read", nil)
+	require.NoError(t, err)
+
+	q := query.Select("posts").WhereClause(f.Where).Build()
+	compiled, err := query.NewSQLCompiler(map[string]*query.EntityMeta{
+		"posts": {TableName: "posts", PrimaryKey: "id"},
+	}).Compile(q)
+	require.NoError(t, err)
+	assert.Contains(t, compiled.SQL, `WHERE (("published" = $1 AND "deleted_at" IS NULL) OR "author_id" = $2)`)
+	assert.Equal(t, []interface{}{true, "u1"}, compiled.Params)
+}
+
+func TestRowFilter_MatchesAllowed(t *testing.T) {
+	engine := NewEngine()
+	require.NoError(t, engine.Add("post.open", `resource.status != "archived"`))
+	require.NoError(t, engine.Add("post.live", `not (resource.status == "draft")`))
+
+	// A null field differs from the value, so Allowed accepts the record
+	// and the filter must select its row too
+	user := &auth.User{ID: "u1"}
+	for _, name := range []string{"post.open", "post.live"} {
+		policy, ok := engine.Policy(name)
+		require.True(t, ok)
+		allowed, err := policy.Allowed(user, map[string]any{"status": nil}, nil)
+		require.NoError(t, err)
+		assert.True(t, allowed, name)
+
+		f, err := engine.Filter(user, name, nil)
+		require.NoError(t, err)
+		q := query.Select("posts").WhereClause(f.Where).Build()
+		compiled, err := query.NewSQLCompiler(map[string]*query.EntityMeta{
+			"posts": {TableName: "posts", PrimaryKey: "id"},
+		}).Compile(q)
+		require.NoError(t, err)
+		assert.Contains(t, compiled.SQL, `("status" != $1 OR "status" IS NULL)`, name)
+	}
+}
+
+func TestRowFilter_RejectsNestedFields(t *testing.T) {
+	engine := NewEngine()
+	require.NoError(t, engine.Add("post.owned", `resource.owner.id == auth.sub`))
+
+	_, err := engine.Filter(&auth.User{ID: "u1"}, "post.owned", nil)
+	assert.ErrorIs(t, err, ErrNotFilterable)
+	assert.ErrorContains(t, err, "owner.id")
+}
@@ -0,0 +1,105 @@
+package abac
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bargom/codeai/internal/query"
+	"github.com/bargom/codeai/internal/workflow/expr"
+)
+
+// RowFilter restricts a list query to the rows a policy allows.
+type RowFilter struct {
+	// Where is the condition rows must match, or nil if every row is
+	// allowed.
+	Where *query.WhereClause
+	// None is set when no row is allowed, so the query can be skipped.
+	None bool
+}
+
+func newRowFilter(f *expr.Filter) (*RowFilter, error) {
+	if field, ok := nestedField(f); ok {
+		return nil, fmt.Errorf("field %q is not a column", field)
+	}
+	switch f.Kind {
+	case expr.FilterTrue:
+		return &RowFilter{}, nil
+	case expr.FilterFalse:
+		return &RowFilter{None: true}, nil
+	case expr.FilterCompare:
+		return &RowFilter{Where: &query.WhereClause{
+			Operator:   query.LogicalAnd,
+			Conditions: []query.Condition{toCondition(f)},
+		}}, nil
+	}
+	return &RowFilter{Where: toWhereClause(f)}, nil
+}
+
+// nestedField returns the first field of f that is a path into a nested
+// object, such as owner.id, which has no column to filter on.
+func nestedField(f *expr.Filter) (string, bool) {
+	if f.Kind == expr.FilterCompare {
+		return f.Field, strings.Contains(f.Field, ".")
+	}
+	for _, sub := range f.Filters {
+		if field, ok := nestedField(sub); ok {
+			return field, true
+		}
+	}
+	return "", false
+}
+
+// toWhereClause converts an and or or filter. Operands that are themselves
+// combinations become nested conditions, which both the SQL compiler and
+// the MongoDB translation support.
+func toWhereClause(f *expr.Filter) *query.WhereClause {
+	where := &query.WhereClause{
+		Operator:   query.LogicalAnd,
+		Conditions: make([]query.Condition, len(f.Filters)),
+	}
+	if f.Kind == expr.FilterOr {
+		where.Operator = query.LogicalOr
+	}
+	for i, sub := range f.Filters {
+		if sub.Kind == expr.FilterCompare {
+			where.Conditions[i] = toCondition(sub)
+		} else {
+			where.Conditions[i] = query.Condition{Nested: toWhereClause(sub)}
+		}
+	}
+	return where
+}
+
+func toCondition(f *expr.Filter) query.Condition {
+	cond := query.Condition{Field: f.Field, Value: f.Value}
+	switch f.Op {
+	case "==":
+		cond.Operator = query.OpEquals
+		if f.Value == nil {
+			cond.Operator = query.OpIsNull
+		}
+	case "!=":
+		if f.Value == nil {
+			cond.Operator = query.OpIsNotNull
+			break
+		}
+		// Rows where the field is null differ from the value too, but
+		// field <> value is not true for them
+		return query.Condition{Nested: &query.WhereClause{
+			Operator: query.LogicalOr,
+			Conditions: []query.Condition{
+				{Field: f.Field, Operator: query.OpNotEquals, Value: f.Value},
+				{Field: f.Field, Operator: query.OpIsNull},
+			},
+		}}
+	case "<":
+		cond.Operator = query.OpLessThan
+	case "<=":
+		cond.Operator = query.OpLessThanOrEqual
+	case ">":
+		cond.Operator = query.OpGreaterThan
+	case ">=":
+		cond.Operator = query.OpGreaterThanOrEqual
+	}
+	return cond
+}
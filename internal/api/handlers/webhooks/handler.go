@@ -4,13 +4,17 @@ package webhooks
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-playground/validator/v10"
 
 	"github.com/bargom/codeai/internal/event/bus"
+	"github.com/bargom/codeai/internal/webhook/filter"
 	"github.com/bargom/codeai/internal/webhook/repository"
 	"github.com/bargom/codeai/internal/webhook/service"
 )
@@ -37,21 +41,43 @@ type ErrorResponse struct {
 
 // CreateWebhookRequest represents a request to create a webhook.
 type CreateWebhookRequest struct {
-	URL      string                 `json:"url" validate:"required,url"`
-	Events   []string               `json:"events,omitempty"` // Empty means all events
-	Secret   string                 `json:"secret,omitempty"`
-	Headers  map[string]string      `json:"headers,omitempty"`
-	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	URL        string                 `json:"url" validate:"required,url"`
+	Events     []string               `json:"events,omitempty"` // Empty means all events; may be patterns such as order.*
+	Filter     string                 `json:"filter,omitempty"`
+	Projection map[string]string      `json:"projection,omitempty"`
+	Secret     string                 `json:"secret,omitempty"`
+	Headers    map[string]string      `json:"headers,omitempty"`
+	Metadata   map[string]interface{} `json:"metadata,omitempty"`
+
+	SignatureScheme string `json:"signature_scheme,omitempty" validate:"omitempty,oneof=hmac-sha256 standard-webhooks"`
 }
 
 // UpdateWebhookRequest represents a request to update a webhook.
 type UpdateWebhookRequest struct {
-	URL      *string                `json:"url,omitempty" validate:"omitempty,url"`
-	Events   []string               `json:"events,omitempty"`
-	Secret   *string                `json:"secret,omitempty"`
-	Headers  map[string]string      `json:"headers,omitempty"`
-	Active   *bool                  `json:"active,omitempty"`
-	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	URL        *string                `json:"url,omitempty" validate:"omitempty,url"`
+	Events     []string               `json:"events,omitempty"`
+	Filter     *string                `json:"filter,omitempty"`
+	Projection map[string]string      `json:"projection,omitempty"`
+	Secret     *string                `json:"secret,omitempty"`
+	Headers    map[string]string      `json:"headers,omitempty"`
+	Active     *bool                  `json:"active,omitempty"`
+	Metadata   map[string]interface{} `json:"metadata,omitempty"`
+
+	SignatureScheme *string `json:"signature_scheme,omitempty" validate:"omitempty,oneof=hmac-sha256 standard-webhooks"`
+}
+
+// RotateSecretRequest represents a request to rotate a webhook's secret.
+type RotateSecretRequest struct {
+	// GracePeriod is how long the old secret keeps signing requests, such
+	// as "48h"; the service's default applies if it is empty.
+	GracePeriod string `json:"grace_period,omitempty"`
+}
+
+// RotateSecretResponse carries a webhook's new secret, which is only shown
+// once.
+type RotateSecretResponse struct {
+	Secret                  string `json:"secret"`
+	PreviousSecretExpiresAt string `json:"previous_secret_expires_at"`
 }
 
 // WebhookResponse represents a webhook in API responses.
@@ -59,6 +85,8 @@ type WebhookResponse struct {
 	ID           string                 `json:"id"`
 	URL          string                 `json:"url"`
 	Events       []string               `json:"events"`
+	Filter       string                 `json:"filter,omitempty"`
+	Projection   map[string]string      `json:"projection,omitempty"`
 	Headers      map[string]string      `json:"headers,omitempty"`
 	Active       bool                   `json:"active"`
 	CreatedAt    string                 `json:"created_at"`
@@ -66,6 +94,9 @@ type WebhookResponse struct {
 	LastDelivery *string                `json:"last_delivery,omitempty"`
 	FailureCount int                    `json:"failure_count"`
 	Metadata     map[string]interface{} `json:"metadata,omitempty"`
+
+	SignatureScheme         string  `json:"signature_scheme,omitempty"`
+	PreviousSecretExpiresAt *string `json:"previous_secret_expires_at,omitempty"`
 }
 
 // ListWebhooksResponse represents a paginated list of webhooks.
@@ -100,6 +131,11 @@ type ListDeliveriesResponse struct {
 	Offset     int                `json:"offset"`
 }
 
+// RedriveResponse reports how many dead letters were scheduled for retry.
+type RedriveResponse struct {
+	Redriven int64 `json:"redriven"`
+}
+
 // Create handles POST /api/v1/webhooks
 func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 	var req CreateWebhookRequest
@@ -107,6 +143,10 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 		h.respondValidationError(w, err)
 		return
 	}
+	if details := validateRules(req.Events, &req.Filter, req.Projection); len(details) > 0 {
+		h.respondJSON(w, http.StatusBadRequest, ErrorResponse{Error: "validation failed", Details: details})
+		return
+	}
 
 	// Convert string event types to bus.EventType
 	events := make([]bus.EventType, len(req.Events))
@@ -115,11 +155,15 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 	}
 
 	registerReq := service.RegisterWebhookRequest{
-		URL:      req.URL,
-		Events:   events,
-		Secret:   req.Secret,
-		Headers:  req.Headers,
-		Metadata: req.Metadata,
+		URL:        req.URL,
+		Events:     events,
+		Filter:     req.Filter,
+		Projection: req.Projection,
+		Secret:     req.Secret,
+		Headers:    req.Headers,
+		Metadata:   req.Metadata,
+
+		SignatureScheme: req.SignatureScheme,
 	}
 
 	webhookID, err := h.webhookService.RegisterWebhook(r.Context(), registerReq)
@@ -161,6 +205,10 @@ func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
 		h.respondValidationError(w, err)
 		return
 	}
+	if details := validateRules(req.Events, req.Filter, req.Projection); len(details) > 0 {
+		h.respondJSON(w, http.StatusBadRequest, ErrorResponse{Error: "validation failed", Details: details})
+		return
+	}
 
 	// Convert string event types to bus.EventType
 	var events []bus.EventType
@@ -172,12 +220,16 @@ func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
 	}
 
 	updateReq := service.UpdateWebhookRequest{
-		URL:      req.URL,
-		Events:   events,
-		Secret:   req.Secret,
-		Headers:  req.Headers,
-		Active:   req.Active,
-		Metadata: req.Metadata,
+		URL:        req.URL,
+		Events:     events,
+		Filter:     req.Filter,
+		Projection: req.Projection,
+		Secret:     req.Secret,
+		Headers:    req.Headers,
+		Active:     req.Active,
+		Metadata:   req.Metadata,
+
+		SignatureScheme: req.SignatureScheme,
 	}
 
 	if err := h.webhookService.UpdateWebhook(r.Context(), webhookID, updateReq); err != nil {
@@ -307,6 +359,49 @@ func (h *Handler) Test(w http.ResponseWriter, r *http.Request) {
 	h.respondJSON(w, http.StatusOK, h.toDeliveryResponse(delivery))
 }
 
+// RotateSecret handles POST /api/v1/webhooks/{id}/rotate-secret
+func (h *Handler) RotateSecret(w http.ResponseWriter, r *http.Request) {
+	webhookID := chi.URLParam(r, "id")
+	if webhookID == "" {
+		h.respondError(w, http.StatusBadRequest, "webhook id is required")
+		return
+	}
+
+	var req RotateSecretRequest
+	if err := h.decodeJSON(r, &req); err != nil && !errors.Is(err, io.EOF) {
+		h.respondError(w, http.StatusBadRequest, "invalid input")
+		return
+	}
+	var gracePeriod time.Duration
+	if req.GracePeriod != "" {
+		parsed, err := time.ParseDuration(req.GracePeriod)
+		if err != nil || parsed <= 0 {
+			h.respondJSON(w, http.StatusBadRequest, ErrorResponse{
+				Error:   "validation failed",
+				Details: map[string]string{"GracePeriod": "must be a positive duration such as 48h"},
+			})
+			return
+		}
+		gracePeriod = parsed
+	}
+
+	if _, err := h.webhookService.GetWebhook(r.Context(), webhookID); err != nil {
+		h.respondError(w, http.StatusNotFound, "webhook not found")
+		return
+	}
+
+	secret, expiresAt, err := h.webhookService.RotateSecret(r.Context(), webhookID, gracePeriod)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, RotateSecretResponse{
+		Secret:                  secret,
+		PreviousSecretExpiresAt: expiresAt.UTC().Format("2006-01-02T15:04:05Z"),
+	})
+}
+
 // RetryDelivery handles POST /api/v1/webhooks/deliveries/{id}/retry
 func (h *Handler) RetryDelivery(w http.ResponseWriter, r *http.Request) {
 	deliveryID := chi.URLParam(r, "id")
@@ -323,8 +418,145 @@ func (h *Handler) RetryDelivery(w http.ResponseWriter, r *http.Request) {
 	h.respondJSON(w, http.StatusOK, map[string]string{"status": "retry initiated"})
 }
 
+// ListDeadLetters handles GET /api/v1/webhooks/dead-letters and
+// GET /api/v1/webhooks/{id}/dead-letters. Dead letters are failed
+// deliveries that will not be retried; the optional since parameter is an
+// RFC 3339 time or a duration before now, such as 24h.
+func (h *Handler) ListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	since, err := parseSince(r)
+	if err != nil {
+		h.respondSinceError(w)
+		return
+	}
+
+	limit, offset := h.getPaginationParams(r)
+
+	filter := repository.DeadLetterFilter{
+		WebhookID: chi.URLParam(r, "id"),
+		Since:     since,
+		Limit:     limit,
+		Offset:    offset,
+	}
+	if filter.WebhookID == "" {
+		filter.WebhookID = r.URL.Query().Get("webhook_id")
+	}
+
+	deliveries, err := h.webhookService.GetDeadLetters(r.Context(), filter)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	responses := make([]DeliveryResponse, len(deliveries))
+	for i, d := range deliveries {
+		responses[i] = h.toDeliveryResponse(&d)
+	}
+
+	h.respondJSON(w, http.StatusOK, ListDeliveriesResponse{
+		Deliveries: responses,
+		Total:      len(responses),
+		Limit:      limit,
+		Offset:     offset,
+	})
+}
+
+// Redrive handles POST /api/v1/webhooks/{id}/redrive
+//
+// It schedules the webhook's dead letters delivered since the optional
+// since parameter for retry, up to the optional limit; all of them by
+// default. A disabled webhook must be enabled first.
+func (h *Handler) Redrive(w http.ResponseWriter, r *http.Request) {
+	webhookID := chi.URLParam(r, "id")
+	if webhookID == "" {
+		h.respondError(w, http.StatusBadRequest, "webhook id is required")
+		return
+	}
+
+	since, err := parseSince(r)
+	if err != nil {
+		h.respondSinceError(w)
+		return
+	}
+	var limit int
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if limit, err = strconv.Atoi(l); err != nil || limit <= 0 {
+			h.respondJSON(w, http.StatusBadRequest, ErrorResponse{
+				Error:   "validation failed",
+				Details: map[string]string{"limit": "must be a positive number"},
+			})
+			return
+		}
+	}
+
+	config, err := h.webhookService.GetWebhook(r.Context(), webhookID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "webhook not found")
+		return
+	}
+	if !config.Active {
+		h.respondError(w, http.StatusConflict, "webhook is disabled; enable it before redriving its deliveries")
+		return
+	}
+
+	redriven, err := h.webhookService.RedriveDeadLetters(r.Context(), webhookID, since, limit)
+	if err != nil {
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.respondJSON(w, http.StatusAccepted, RedriveResponse{Redriven: redriven})
+}
+
 // Helper methods
 
+// parseSince parses the since query parameter, an RFC 3339 time or a
+// duration before now. It returns the zero time if there is none.
+func parseSince(r *http.Request) (time.Time, error) {
+	value := r.URL.Query().Get("since")
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if since, err := time.Parse(time.RFC3339, value); err == nil {
+		return since, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return time.Time{}, fmt.Errorf("invalid since %q", value)
+	}
+	return time.Now().Add(-d), nil
+}
+
+func (h *Handler) respondSinceError(w http.ResponseWriter) {
+	h.respondJSON(w, http.StatusBadRequest, ErrorResponse{
+		Error:   "validation failed",
+		Details: map[string]string{"since": "must be an RFC 3339 time or a duration such as 24h"},
+	})
+}
+
+// validateRules checks the event patterns, filter and projection of a
+// request and returns the problems found by field.
+func validateRules(events []string, filterSrc *string, projection map[string]string) map[string]string {
+	details := make(map[string]string)
+	for _, pattern := range events {
+		if err := filter.ValidatePattern(pattern); err != nil {
+			details["Events"] = err.Error()
+			break
+		}
+	}
+
+	var src string
+	if filterSrc != nil {
+		src = *filterSrc
+	}
+	if _, err := filter.Compile(src, nil); err != nil {
+		details["Filter"] = err.Error()
+	}
+	if _, err := filter.Compile("", projection); err != nil {
+		details["Projection"] = err.Error()
+	}
+	return details
+}
+
 func (h *Handler) toWebhookResponse(wh *repository.WebhookConfig) WebhookResponse {
 	events := make([]string, len(wh.Events))
 	for i, e := range wh.Events {
@@ -335,18 +567,26 @@ func (h *Handler) toWebhookResponse(wh *repository.WebhookConfig) WebhookRespons
 		ID:           wh.ID,
 		URL:          wh.URL,
 		Events:       events,
+		Filter:       wh.Filter,
+		Projection:   wh.Projection,
 		Headers:      wh.Headers,
 		Active:       wh.Active,
 		CreatedAt:    wh.CreatedAt.Format("2006-01-02T15:04:05Z"),
 		UpdatedAt:    wh.UpdatedAt.Format("2006-01-02T15:04:05Z"),
 		FailureCount: wh.FailureCount,
 		Metadata:     wh.Metadata,
+
+		SignatureScheme: wh.SignatureScheme,
 	}
 
 	if wh.LastDelivery != nil {
 		s := wh.LastDelivery.Format("2006-01-02T15:04:05Z")
 		resp.LastDelivery = &s
 	}
+	if len(wh.SigningSecrets(time.Now())) > 1 {
+		s := wh.PreviousSecretExpiresAt.Format("2006-01-02T15:04:05Z")
+		resp.PreviousSecretExpiresAt = &s
+	}
 
 	return resp
 }
@@ -0,0 +1,248 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bargom/codeai/internal/webhook/repository"
+	"github.com/bargom/codeai/internal/webhook/security"
+	"github.com/bargom/codeai/internal/webhook/service"
+	"github.com/bargom/codeai/pkg/integration/webhook"
+)
+
+func newTestRouter() (chi.Router, *service.WebhookService) {
+	return newTestRouterWith(repository.NewMemoryRepository())
+}
+
+func newTestRouterWith(repo repository.WebhookRepository) (chi.Router, *service.WebhookService) {
+	svc := service.NewWebhookService(webhook.NewClient(webhook.DefaultConfig()), repo)
+	r := chi.NewRouter()
+	NewHandler(svc).RegisterRoutes(r)
+	return r, svc
+}
+
+func TestHandler_Create_Rules(t *testing.T) {
+	router, svc := newTestRouter()
+
+	body, err := json.Marshal(CreateWebhookRequest{
+		URL:        "https://example.com/orders",
+		Events:     []string{"order.*"},
+		Filter:     `payload.amount > 1000 && payload.currency == "EUR"`,
+		Projection: map[string]string{"total": "payload.amount"},
+	})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/webhooks", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	var created map[string]string
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&created))
+	wh, err := svc.GetWebhook(context.Background(), created["id"])
+	require.NoError(t, err)
+	assert.Equal(t, `payload.amount > 1000 && payload.currency == "EUR"`, wh.Filter)
+	assert.Equal(t, map[string]string{"total": "payload.amount"}, wh.Projection)
+}
+
+func TestHandler_Create_InvalidRules(t *testing.T) {
+	router, _ := newTestRouter()
+
+	tests := []struct {
+		name      string
+		body      CreateWebhookRequest
+		wantField string
+	}{
+		{
+			name:      "invalid filter",
+			body:      CreateWebhookRequest{URL: "https://example.com", Filter: "payload.amount >"},
+			wantField: "Filter",
+		},
+		{
+			name:      "filter on unknown variable",
+			body:      CreateWebhookRequest{URL: "https://example.com", Filter: "order.amount > 1"},
+			wantField: "Filter",
+		},
+		{
+			name:      "invalid projection",
+			body:      CreateWebhookRequest{URL: "https://example.com", Projection: map[string]string{"total": "payload.("}},
+			wantField: "Projection",
+		},
+		{
+			name:      "invalid event pattern",
+			body:      CreateWebhookRequest{URL: "https://example.com", Events: []string{"order.["}},
+			wantField: "Events",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := json.Marshal(tt.body)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodPost, "/webhooks", bytes.NewReader(body))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			require.Equal(t, http.StatusBadRequest, w.Code)
+
+			var resp ErrorResponse
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+			assert.Contains(t, resp.Details, tt.wantField)
+		})
+	}
+}
+
+func TestHandler_RotateSecret(t *testing.T) {
+	router, svc := newTestRouter()
+
+	var received http.Header
+	var receivedBody []byte
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Clone()
+		receivedBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer receiver.Close()
+
+	body, err := json.Marshal(CreateWebhookRequest{
+		URL:             receiver.URL,
+		Secret:          "whsec_b2xk",
+		SignatureScheme: security.SchemeStandard,
+	})
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, "/webhooks", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var created map[string]string
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&created))
+
+	req = httptest.NewRequest(http.MethodPost, "/webhooks/"+created["id"]+"/rotate-secret", strings.NewReader(`{"grace_period":"48h"}`))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var rotated RotateSecretResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&rotated))
+	assert.True(t, strings.HasPrefix(rotated.Secret, security.StandardSecretPrefix))
+	assert.NotEmpty(t, rotated.PreviousSecretExpiresAt)
+
+	_, err = svc.SendTestWebhook(context.Background(), created["id"])
+	require.NoError(t, err)
+	for _, secret := range []string{"whsec_b2xk", rotated.Secret} {
+		assert.NoError(t, security.VerifyStandard([]string{secret}, received, receivedBody, time.Now(), 0),
+			"the old and new secret both sign during the grace period")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/webhooks/"+created["id"], nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var resp WebhookResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, security.SchemeStandard, resp.SignatureScheme)
+	assert.NotNil(t, resp.PreviousSecretExpiresAt)
+
+	req = httptest.NewRequest(http.MethodPost, "/webhooks/"+created["id"]+"/rotate-secret", strings.NewReader(`{"grace_period":"soon"}`))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/webhooks/nonexistent/rotate-secret", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestHandler_Create_InvalidScheme(t *testing.T) {
+	router, _ := newTestRouter()
+
+	body, err := json.Marshal(CreateWebhookRequest{URL: "https://example.com", SignatureScheme: "rsa"})
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, "/webhooks", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	var resp ErrorResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Contains(t, resp.Details, "SignatureScheme")
+}
+
+func TestHandler_DeadLetters(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	router, _ := newTestRouterWith(repo)
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	require.NoError(t, repo.CreateWebhook(ctx, &repository.WebhookConfig{ID: "wh-1", URL: "https://example.com", Active: true}))
+	require.NoError(t, repo.CreateWebhook(ctx, &repository.WebhookConfig{ID: "wh-2", URL: "https://example.com", FailureCount: 10}))
+	deliveries := []*repository.WebhookDelivery{
+		{ID: "d-old", WebhookID: "wh-1", Attempts: 3, DeliveredAt: now.Add(-48 * time.Hour)},
+		{ID: "d-new", WebhookID: "wh-1", Attempts: 3, DeliveredAt: now.Add(-time.Hour)},
+		{ID: "d-retry", WebhookID: "wh-1", DeliveredAt: now, NextRetryAt: &now},
+		{ID: "d-other", WebhookID: "wh-2", Attempts: 1, DeliveredAt: now.Add(-time.Hour)},
+	}
+	for _, d := range deliveries {
+		require.NoError(t, repo.SaveDelivery(ctx, d))
+	}
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	deadLetters := func(path string) []string {
+		w := serve(http.MethodGet, path, "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var resp ListDeliveriesResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		var ids []string
+		for _, d := range resp.Deliveries {
+			ids = append(ids, d.ID)
+		}
+		return ids
+	}
+
+	assert.Equal(t, []string{"d-new", "d-other", "d-old"}, deadLetters("/webhooks/dead-letters"))
+	assert.Equal(t, []string{"d-other"}, deadLetters("/webhooks/dead-letters?webhook_id=wh-2"))
+	assert.Equal(t, []string{"d-new"}, deadLetters("/webhooks/wh-1/dead-letters?since=24h"))
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodGet, "/webhooks/dead-letters?since=yesterday", "").Code)
+
+	since := now.Add(-2 * time.Hour).Format(time.RFC3339)
+	w := serve(http.MethodPost, "/webhooks/wh-1/redrive?since="+since, "")
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"redriven":1}`, w.Body.String())
+	assert.Equal(t, []string{"d-old"}, deadLetters("/webhooks/wh-1/dead-letters"))
+
+	redriven, err := repo.GetDelivery(ctx, "d-new")
+	require.NoError(t, err)
+	assert.Equal(t, 0, redriven.Attempts)
+	assert.NotNil(t, redriven.NextRetryAt, "the retry handler sends redriven deliveries")
+
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodPost, "/webhooks/wh-1/redrive?limit=0", "").Code)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodPost, "/webhooks/nonexistent/redrive", "").Code)
+	assert.Equal(t, http.StatusConflict, serve(http.MethodPost, "/webhooks/wh-2/redrive", "").Code)
+
+	// Enabling a disabled webhook resets its failure count
+	require.Equal(t, http.StatusOK, serve(http.MethodPut, "/webhooks/wh-2", `{"active":true}`).Code)
+	w = serve(http.MethodGet, "/webhooks/wh-2", "")
+	var resp WebhookResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.True(t, resp.Active)
+	assert.Equal(t, 0, resp.FailureCount)
+
+	w = serve(http.MethodPost, "/webhooks/wh-2/redrive", "")
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"redriven":1}`, w.Body.String())
+}
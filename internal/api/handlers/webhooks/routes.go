@@ -10,13 +10,17 @@ func (h *Handler) RegisterRoutes(r chi.Router) {
 		// Webhook CRUD
 		r.Post("/", h.Create)
 		r.Get("/", h.List)
+		r.Get("/dead-letters", h.ListDeadLetters)
 		r.Get("/{id}", h.Get)
 		r.Put("/{id}", h.Update)
 		r.Delete("/{id}", h.Delete)
 
 		// Webhook actions
 		r.Post("/{id}/test", h.Test)
+		r.Post("/{id}/rotate-secret", h.RotateSecret)
 		r.Get("/{id}/deliveries", h.ListDeliveries)
+		r.Get("/{id}/dead-letters", h.ListDeadLetters)
+		r.Post("/{id}/redrive", h.Redrive)
 
 		// Delivery actions
 		r.Post("/deliveries/{id}/retry", h.RetryDelivery)
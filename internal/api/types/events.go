@@ -72,6 +72,7 @@ func GetEventTypes() []EventTypeInfo {
 		{Type: string(bus.EventAgentExecuted), Description: "Agent has executed an action", Category: "agent"},
 		{Type: string(bus.EventTestSuiteCompleted), Description: "Test suite execution completed", Category: "test"},
 		{Type: string(bus.EventWebhookTriggered), Description: "Webhook was triggered", Category: "webhook"},
+		{Type: string(bus.EventWebhookDisabled), Description: "Webhook was disabled after consecutive delivery failures", Category: "webhook"},
 		{Type: string(bus.EventEmailSent), Description: "Email was sent", Category: "notification"},
 	}
 }
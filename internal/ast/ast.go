@@ -63,14 +63,17 @@ type Application struct {
 	pos         Position
 	Config      *ConfigDecl       // Configuration block
 	Database    *DatabaseBlock    // Database definition
+	Tenancy     *TenancyDecl      // Multi-tenancy configuration
 	Endpoints   []*EndpointDecl   // API endpoints
 	Middlewares []*MiddlewareDecl // Middleware definitions
 	Auths       []*AuthDecl       // Authentication providers
 	Roles       []*RoleDecl       // Role definitions
+	Policies    []*PolicyDecl     // Attribute-based authorization policies
 	Events      []*EventDecl      // Event definitions
 	Handlers    []*EventHandlerDecl // Event handlers
 	Integrations []*IntegrationDecl // External integrations
 	Webhooks    []*WebhookDecl    // Webhook configurations
+	Receivers   []*WebhookReceiverDecl // Inbound webhook receivers
 	Workflows   []*WorkflowDecl   // Temporal workflows
 	Jobs        []*JobDecl        // Asynq jobs
 	Variables   []*VarDecl        // Variable declarations
@@ -97,6 +100,8 @@ func (p *Program) ToApplication() *Application {
 			app.Config = s
 		case *DatabaseBlock:
 			app.Database = s
+		case *TenancyDecl:
+			app.Tenancy = s
 		case *EndpointDecl:
 			app.Endpoints = append(app.Endpoints, s)
 		case *MiddlewareDecl:
@@ -105,6 +110,8 @@ func (p *Program) ToApplication() *Application {
 			app.Auths = append(app.Auths, s)
 		case *RoleDecl:
 			app.Roles = append(app.Roles, s)
+		case *PolicyDecl:
+			app.Policies = append(app.Policies, s)
 		case *EventDecl:
 			app.Events = append(app.Events, s)
 		case *EventHandlerDecl:
@@ -113,6 +120,8 @@ func (p *Program) ToApplication() *Application {
 			app.Integrations = append(app.Integrations, s)
 		case *WebhookDecl:
 			app.Webhooks = append(app.Webhooks, s)
+		case *WebhookReceiverDecl:
+			app.Receivers = append(app.Receivers, s)
 		case *WorkflowDecl:
 			app.Workflows = append(app.Workflows, s)
 		case *JobDecl:
@@ -446,6 +455,25 @@ func (d *DatabaseBlock) String() string {
 	return fmt.Sprintf("DatabaseBlock{DBType: %q, Name: %q}", d.DBType, d.Name)
 }
 
+// TenancyDecl configures multi-tenancy: where the tenant of a request comes
+// from and how the data of tenants is kept apart.
+// Example: tenancy { strategy: column, claim: "org_id" }
+type TenancyDecl struct {
+	pos      Position
+	Strategy string                // "column", "schema" or "database"
+	Settings map[string]Expression // claim, header, subdomain, column
+}
+
+// SetPos records where the declaration appears in the source.
+func (t *TenancyDecl) SetPos(pos Position) { t.pos = pos }
+
+func (t *TenancyDecl) Pos() Position  { return t.pos }
+func (t *TenancyDecl) Type() NodeType { return NodeTenancyDecl }
+func (t *TenancyDecl) stmtNode()      {}
+func (t *TenancyDecl) String() string {
+	return fmt.Sprintf("TenancyDecl{Strategy: %q}", t.Strategy)
+}
+
 // =============================================================================
 // PostgreSQL Model Nodes
 // =============================================================================
@@ -468,6 +496,9 @@ func (m *ModelDecl) String() string {
 		m.Name, len(m.Fields), len(m.Indexes))
 }
 
+// SetPos records where the declaration appears in the source.
+func (m *ModelDecl) SetPos(pos Position) { m.pos = pos }
+
 // FieldDecl represents a field in a PostgreSQL model.
 type FieldDecl struct {
 	pos       Position
@@ -483,6 +514,9 @@ func (f *FieldDecl) String() string {
 	return fmt.Sprintf("FieldDecl{Name: %q, Type: %s}", f.Name, f.FieldType.String())
 }
 
+// SetPos records where the declaration appears in the source.
+func (f *FieldDecl) SetPos(pos Position) { f.pos = pos }
+
 // TypeRef represents a type reference (e.g., string, uuid, ref(User), list(string)).
 type TypeRef struct {
 	pos    Position
@@ -555,6 +589,9 @@ func (c *CollectionDecl) String() string {
 		c.Name, len(c.Fields), len(c.Indexes))
 }
 
+// SetPos records where the declaration appears in the source.
+func (c *CollectionDecl) SetPos(pos Position) { c.pos = pos }
+
 // MongoFieldDecl represents a field in a MongoDB collection.
 type MongoFieldDecl struct {
 	pos         Position
@@ -570,6 +607,9 @@ func (f *MongoFieldDecl) String() string {
 	return fmt.Sprintf("MongoFieldDecl{Name: %q, Type: %s}", f.Name, f.FieldType.String())
 }
 
+// SetPos records where the declaration appears in the source.
+func (f *MongoFieldDecl) SetPos(pos Position) { f.pos = pos }
+
 // MongoTypeRef represents a MongoDB-specific type reference.
 // Supports: objectid, string, int, double, bool, date, binary, array(T), embedded { ... }
 type MongoTypeRef struct {
@@ -646,6 +686,7 @@ type AuthDecl struct {
 	Method AuthMethod
 	JWKS   *JWKSConfig
 	Config map[string]Expression
+	Issuer *AuthIssuerDecl // Token issuance, nil unless an issuer block is declared
 }
 
 func (a *AuthDecl) Pos() Position  { return a.pos }
@@ -669,6 +710,19 @@ func (j *JWKSConfig) String() string {
 	return fmt.Sprintf("JWKSConfig{URL: %q}", j.URL)
 }
 
+// AuthIssuerDecl configures built-in token issuance for a JWT auth provider:
+// the credential source for logins, token lifetimes and signing keys.
+type AuthIssuerDecl struct {
+	pos    Position
+	Config map[string]Expression
+}
+
+func (i *AuthIssuerDecl) Pos() Position  { return i.pos }
+func (i *AuthIssuerDecl) Type() NodeType { return NodeAuthIssuer }
+func (i *AuthIssuerDecl) String() string {
+	return fmt.Sprintf("AuthIssuerDecl{Settings: %d}", len(i.Config))
+}
+
 // RoleDecl represents a role definition with permissions.
 type RoleDecl struct {
 	pos         Position
@@ -683,6 +737,28 @@ func (r *RoleDecl) String() string {
 	return fmt.Sprintf("RoleDecl{Name: %q}", r.Name)
 }
 
+// PolicyDecl represents an attribute-based authorization policy:
+// `authorize post.update when resource.author_id == auth.sub`
+type PolicyDecl struct {
+	pos       Position
+	Resource  string // e.g. "post"
+	Action    string // e.g. "update"
+	Condition string // expression source
+}
+
+// SetPos records where the declaration appears in the source.
+func (p *PolicyDecl) SetPos(pos Position) { p.pos = pos }
+
+// Name returns the policy name in the format "resource.action".
+func (p *PolicyDecl) Name() string { return p.Resource + "." + p.Action }
+
+func (p *PolicyDecl) Pos() Position  { return p.pos }
+func (p *PolicyDecl) Type() NodeType { return NodePolicyDecl }
+func (p *PolicyDecl) stmtNode()      {}
+func (p *PolicyDecl) String() string {
+	return fmt.Sprintf("PolicyDecl{Name: %q, Condition: %q}", p.Name(), p.Condition)
+}
+
 // =============================================================================
 // Middleware Nodes
 // =============================================================================
@@ -994,12 +1070,16 @@ func (a *Annotation) String() string {
 // Event Nodes
 // =============================================================================
 
-// EventDecl represents an event definition.
-// Example: event user.created { schema { user_id string ... } }
+// EventDecl represents an event definition. An event may be declared once
+// per version; a declaration without a version is version 1.
+// Example: event user.created version 2 { schema { user_id string ... } upcast from 1 { ... } }
 type EventDecl struct {
 	pos      Position
 	Name     string              // Event name (e.g., "user.created")
+	Version  int                 // Schema version, 1 when not declared
+	Breaking bool                // Whether the version may break compatibility with the previous one
 	Schema   *EventSchema        // Event payload schema
+	Upcast   *EventUpcast        // Migrates payloads of the previous version to this one
 	Handlers []*EventHandlerDecl // Handlers registered for this event
 }
 
@@ -1007,7 +1087,19 @@ func (e *EventDecl) Pos() Position  { return e.pos }
 func (e *EventDecl) Type() NodeType { return NodeEventDecl }
 func (e *EventDecl) stmtNode()      {}
 func (e *EventDecl) String() string {
-	return fmt.Sprintf("EventDecl{Name: %q, Handlers: %d}", e.Name, len(e.Handlers))
+	return fmt.Sprintf("EventDecl{Name: %q, Version: %d, Handlers: %d}", e.Name, e.Version, len(e.Handlers))
+}
+
+// SetPos records where the declaration appears in the source.
+func (e *EventDecl) SetPos(pos Position) { e.pos = pos }
+
+// SchemaVersion returns the version of the declaration, 1 when it declares
+// none.
+func (e *EventDecl) SchemaVersion() int {
+	if e.Version == 0 {
+		return 1
+	}
+	return e.Version
 }
 
 // EventSchema represents the schema definition for an event payload.
@@ -1035,6 +1127,46 @@ func (f *EventSchemaField) String() string {
 	return fmt.Sprintf("EventSchemaField{Name: %q, Type: %q}", f.Name, f.FieldType)
 }
 
+// SetPos records where the field appears in the source.
+func (f *EventSchemaField) SetPos(pos Position) { f.pos = pos }
+
+// EventUpcast migrates payloads of an earlier version of an event to the
+// version declaring it. The payload keeps its fields, each mapping sets a
+// field to the value of an expression over the old payload, and fields the
+// new schema does not declare are dropped.
+// Example: upcast from 1 { full_name: "payload.first_name" }
+type EventUpcast struct {
+	pos      Position
+	From     int                   // Version the payloads are migrated from
+	Mappings []*EventUpcastMapping // Fields set on the migrated payload
+}
+
+func (u *EventUpcast) Pos() Position  { return u.pos }
+func (u *EventUpcast) Type() NodeType { return NodeEventUpcast }
+func (u *EventUpcast) String() string {
+	return fmt.Sprintf("EventUpcast{From: %d, Mappings: %d}", u.From, len(u.Mappings))
+}
+
+// SetPos records where the upcast appears in the source.
+func (u *EventUpcast) SetPos(pos Position) { u.pos = pos }
+
+// UpcastVar is the variable upcast expressions reference the payload being
+// migrated by.
+const UpcastVar = "payload"
+
+// EventUpcastMapping sets a field of an upcast payload.
+type EventUpcastMapping struct {
+	pos   Position
+	Field string // Field of the new payload
+	Expr  string // Expression over the old payload, referenced as UpcastVar
+}
+
+// Pos returns where the mapping appears in the source.
+func (m *EventUpcastMapping) Pos() Position { return m.pos }
+
+// SetPos records where the mapping appears in the source.
+func (m *EventUpcastMapping) SetPos(pos Position) { m.pos = pos }
+
 // EventHandlerDecl represents an event handler declaration.
 // Example: on "user.created" do workflow "send_welcome_email" async
 type EventHandlerDecl struct {
@@ -1175,3 +1307,38 @@ func (h *WebhookHeader) Type() NodeType { return NodeWebhookHeader }
 func (h *WebhookHeader) String() string {
 	return fmt.Sprintf("WebhookHeader{%q: %q}", h.Key, h.Value)
 }
+
+// Webhook receiver verification schemes.
+const (
+	ReceiverSchemeHMAC     = "hmac_sha256"
+	ReceiverSchemeStandard = "standard_webhooks"
+)
+
+// WebhookReceiverDecl declares an endpoint receiving webhooks from another
+// service, which verifies their signature and emits them as an event.
+// Example: receive webhook stripe at "/hooks/stripe" { verify hmac_sha256 header "Stripe-Signature" secret env(STRIPE_SECRET) emit "payment.received" }
+type WebhookReceiverDecl struct {
+	pos             Position
+	Name            string // Receiver name
+	Path            string // Path of the endpoint receiving POST requests
+	Scheme          string // ReceiverSchemeHMAC or ReceiverSchemeStandard
+	SignatureHeader string // Header of the signature; defaults by scheme
+	SecretEnv       string // Environment variable holding the secret
+	Secret          string // Secret given in the source, if not SecretEnv
+	TimestampHeader string // Header of the signed timestamp, if separate
+	Tolerance       string // Maximum age of the timestamp, such as "5m"
+	DeliveryHeader  string // Header of the delivery ID requests are deduplicated by
+	DeliveryField   string // Payload field of the delivery ID, if not DeliveryHeader
+	Event           string // Event emitted with the request's payload
+}
+
+// SetPos records where the declaration appears in the source.
+func (r *WebhookReceiverDecl) SetPos(pos Position) { r.pos = pos }
+
+func (r *WebhookReceiverDecl) Pos() Position  { return r.pos }
+func (r *WebhookReceiverDecl) Type() NodeType { return NodeWebhookReceiverDecl }
+func (r *WebhookReceiverDecl) stmtNode()      {}
+func (r *WebhookReceiverDecl) String() string {
+	return fmt.Sprintf("WebhookReceiverDecl{Name: %q, Path: %q, Scheme: %q, Event: %q}",
+		r.Name, r.Path, r.Scheme, r.Event)
+}
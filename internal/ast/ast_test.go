@@ -2177,3 +2177,30 @@ func TestPrintAllNodeTypes(t *testing.T) {
 		}
 	})
 }
+
+func TestSchemaCandidates(t *testing.T) {
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"User", []string{"User"}},
+		{"CreateUser", []string{"CreateUser", "User"}},
+		{"UserInput", []string{"UserInput", "User"}},
+		{"UpdateOrderRequest", []string{"UpdateOrderRequest", "Order"}},
+		{"Request", []string{"Request"}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		got := SchemaCandidates(tt.name)
+		if len(got) != len(tt.want) {
+			t.Errorf("SchemaCandidates(%q) = %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("SchemaCandidates(%q) = %v, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+}
@@ -604,3 +604,25 @@ func Clone(node Node) Node {
 
 	return nil
 }
+
+// SchemaCandidates returns the record names a request type name may refer
+// to: the name itself, then the model it is derived from once a verb prefix
+// such as Create and a suffix such as Input are stripped. CreateUserRequest
+// yields CreateUserRequest and User.
+func SchemaCandidates(name string) []string {
+	if name == "" {
+		return nil
+	}
+	candidates := []string{name}
+	base := name
+	for _, prefix := range []string{"Create", "Update", "Patch", "Replace"} {
+		base = strings.TrimPrefix(base, prefix)
+	}
+	for _, suffix := range []string{"Request", "Input", "Params", "Body"} {
+		base = strings.TrimSuffix(base, suffix)
+	}
+	if base != name && base != "" {
+		candidates = append(candidates, base)
+	}
+	return candidates
+}
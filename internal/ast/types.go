@@ -51,6 +51,7 @@ const (
 	NodeUnaryExpr
 	NodeConfigDecl
 	NodeDatabaseBlock
+	NodeTenancyDecl
 	// PostgreSQL model types
 	NodeModelDecl
 	NodeFieldDecl
@@ -66,7 +67,9 @@ const (
 	// Authentication & Authorization types
 	NodeAuthDecl
 	NodeJWKSConfig
+	NodeAuthIssuer
 	NodeRoleDecl
+	NodePolicyDecl
 	// Middleware types
 	NodeMiddlewareDecl
 	NodeMiddlewareRef
@@ -92,6 +95,7 @@ const (
 	NodeEventHandler
 	NodeEventSchema
 	NodeEventSchemaField
+	NodeEventUpcast
 	// Integration types
 	NodeIntegrationDecl
 	NodeIntegrationAuth
@@ -99,6 +103,7 @@ const (
 	// Webhook types
 	NodeWebhookDecl
 	NodeWebhookHeader
+	NodeWebhookReceiverDecl
 )
 
 // nodeTypeNames maps NodeType values to their string representations.
@@ -122,6 +127,7 @@ var nodeTypeNames = map[NodeType]string{
 	NodeUnaryExpr:      "UnaryExpr",
 	NodeConfigDecl:     "ConfigDecl",
 	NodeDatabaseBlock:  "DatabaseBlock",
+	NodeTenancyDecl:    "TenancyDecl",
 	// PostgreSQL model types
 	NodeModelDecl:       "ModelDecl",
 	NodeFieldDecl:       "FieldDecl",
@@ -137,7 +143,9 @@ var nodeTypeNames = map[NodeType]string{
 	// Authentication & Authorization types
 	NodeAuthDecl:       "AuthDecl",
 	NodeJWKSConfig:     "JWKSConfig",
+	NodeAuthIssuer:     "AuthIssuer",
 	NodeRoleDecl:       "RoleDecl",
+	NodePolicyDecl:     "PolicyDecl",
 	// Middleware types
 	NodeMiddlewareDecl:      "MiddlewareDecl",
 	NodeMiddlewareRef:       "MiddlewareRef",
@@ -163,13 +171,15 @@ var nodeTypeNames = map[NodeType]string{
 	NodeEventHandler:      "EventHandler",
 	NodeEventSchema:       "EventSchema",
 	NodeEventSchemaField:  "EventSchemaField",
+	NodeEventUpcast:       "EventUpcast",
 	// Integration types
 	NodeIntegrationDecl:     "IntegrationDecl",
 	NodeIntegrationAuth:     "IntegrationAuth",
 	NodeCircuitBreakerConfig: "CircuitBreakerConfig",
 	// Webhook types
-	NodeWebhookDecl:   "WebhookDecl",
-	NodeWebhookHeader: "WebhookHeader",
+	NodeWebhookDecl:         "WebhookDecl",
+	NodeWebhookHeader:       "WebhookHeader",
+	NodeWebhookReceiverDecl: "WebhookReceiverDecl",
 }
 
 // String returns the string representation of the NodeType.
@@ -0,0 +1,159 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const apiKeysCollection = "api_keys"
+
+// MongoStore implements Store on MongoDB.
+type MongoStore struct {
+	collection *mongo.Collection
+}
+
+// NewMongoStore creates a key store on the given MongoDB database.
+func NewMongoStore(db *mongo.Database) *MongoStore {
+	return &MongoStore{collection: db.Collection(apiKeysCollection)}
+}
+
+// mongoKey is the document representation of a Key.
+type mongoKey struct {
+	ID          string     `bson:"_id"`
+	Name        string     `bson:"name"`
+	Prefix      string     `bson:"prefix"`
+	Hash        string     `bson:"hash"`
+	Roles       []string   `bson:"roles"`
+	Permissions []string   `bson:"permissions"`
+	RotatedFrom string     `bson:"rotatedFrom,omitempty"`
+	CreatedAt   time.Time  `bson:"createdAt"`
+	ExpiresAt   *time.Time `bson:"expiresAt,omitempty"`
+	RevokedAt   *time.Time `bson:"revokedAt,omitempty"`
+	LastUsedAt  *time.Time `bson:"lastUsedAt,omitempty"`
+}
+
+// EnsureIndexes creates the unique index on key hashes.
+func (s *MongoStore) EnsureIndexes(ctx context.Context) error {
+	_, err := s.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "hash", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("creating api key indexes: %w", err)
+	}
+	return nil
+}
+
+// Create stores a new key.
+func (s *MongoStore) Create(ctx context.Context, key *Key) error {
+	doc := mongoKey{
+		ID:          key.ID,
+		Name:        key.Name,
+		Prefix:      key.Prefix,
+		Hash:        key.Hash,
+		Roles:       nonNil(key.Roles),
+		Permissions: nonNil(key.Permissions),
+		RotatedFrom: key.RotatedFrom,
+		CreatedAt:   key.CreatedAt,
+		ExpiresAt:   key.ExpiresAt,
+		RevokedAt:   key.RevokedAt,
+		LastUsedAt:  key.LastUsedAt,
+	}
+	if _, err := s.collection.InsertOne(ctx, doc); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrKeyExists
+		}
+		return fmt.Errorf("inserting api key: %w", err)
+	}
+	return nil
+}
+
+// Get retrieves a key by ID.
+func (s *MongoStore) Get(ctx context.Context, id string) (*Key, error) {
+	return s.findOne(ctx, bson.M{"_id": id})
+}
+
+// GetByHash retrieves a key by the hash of its plaintext value.
+func (s *MongoStore) GetByHash(ctx context.Context, hash string) (*Key, error) {
+	return s.findOne(ctx, bson.M{"hash": hash})
+}
+
+// List returns all keys, oldest first.
+func (s *MongoStore) List(ctx context.Context) ([]*Key, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := s.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("listing api keys: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var docs []mongoKey
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("decoding api keys: %w", err)
+	}
+	keys := make([]*Key, len(docs))
+	for i := range docs {
+		keys[i] = docs[i].toKey()
+	}
+	return keys, nil
+}
+
+// Revoke marks a key as revoked at the given time.
+func (s *MongoStore) Revoke(ctx context.Context, id string, at time.Time) error {
+	return s.set(ctx, id, "revokedAt", at)
+}
+
+// SetExpiry sets the time a key expires.
+func (s *MongoStore) SetExpiry(ctx context.Context, id string, at time.Time) error {
+	return s.set(ctx, id, "expiresAt", at)
+}
+
+// TouchLastUsed records the time a key was last used.
+func (s *MongoStore) TouchLastUsed(ctx context.Context, id string, at time.Time) error {
+	return s.set(ctx, id, "lastUsedAt", at)
+}
+
+func (s *MongoStore) set(ctx context.Context, id, field string, at time.Time) error {
+	result, err := s.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{field: at}})
+	if err != nil {
+		return fmt.Errorf("updating api key %s: %w", field, err)
+	}
+	if result.MatchedCount == 0 {
+		return ErrKeyNotFound
+	}
+	return nil
+}
+
+func (s *MongoStore) findOne(ctx context.Context, filter bson.M) (*Key, error) {
+	var doc mongoKey
+	err := s.collection.FindOne(ctx, filter).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("finding api key: %w", err)
+	}
+	return doc.toKey(), nil
+}
+
+func (d *mongoKey) toKey() *Key {
+	return &Key{
+		ID:          d.ID,
+		Name:        d.Name,
+		Prefix:      d.Prefix,
+		Hash:        d.Hash,
+		Roles:       d.Roles,
+		Permissions: d.Permissions,
+		RotatedFrom: d.RotatedFrom,
+		CreatedAt:   d.CreatedAt,
+		ExpiresAt:   d.ExpiresAt,
+		RevokedAt:   d.RevokedAt,
+		LastUsedAt:  d.LastUsedAt,
+	}
+}
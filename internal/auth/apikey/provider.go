@@ -0,0 +1,287 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bargom/codeai/internal/auth"
+)
+
+// DefaultHeader is the request header API keys are read from by default.
+const DefaultHeader = "X-API-Key"
+
+// DefaultLastUsedInterval is how often the last-used time of a key is
+// written back to the store.
+const DefaultLastUsedInterval = time.Minute
+
+// Config configures where a Provider looks for API keys.
+type Config struct {
+	// Header is the request header carrying the key (default X-API-Key).
+	// For the Authorization header, a "Bearer" or "ApiKey" scheme is stripped.
+	Header string
+
+	// QueryParam is the query parameter carrying the key. Query lookup is
+	// disabled when empty, since URLs tend to end up in logs.
+	QueryParam string
+
+	// LastUsedInterval limits how often last-used tracking writes to the
+	// store (default one minute).
+	LastUsedInterval time.Duration
+}
+
+// ConfigFromSettings builds a Config from the settings of an
+// "auth ... { method apikey config { ... } }" declaration: header, query
+// and last_used_interval.
+func ConfigFromSettings(settings map[string]any) (Config, error) {
+	var cfg Config
+	var err error
+	if cfg.Header, err = stringSetting(settings, "header"); err != nil {
+		return Config{}, err
+	}
+	if cfg.QueryParam, err = stringSetting(settings, "query"); err != nil {
+		return Config{}, err
+	}
+
+	interval, err := stringSetting(settings, "last_used_interval")
+	if err != nil {
+		return Config{}, err
+	}
+	if interval != "" {
+		if cfg.LastUsedInterval, err = time.ParseDuration(interval); err != nil {
+			return Config{}, fmt.Errorf("invalid last_used_interval %q: %w", interval, err)
+		}
+	}
+	return cfg, nil
+}
+
+func stringSetting(settings map[string]any, key string) (string, error) {
+	value, ok := settings[key]
+	if !ok {
+		return "", nil
+	}
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("%s must be a string", key)
+	}
+	return s, nil
+}
+
+// IssueOptions describes a key to issue.
+type IssueOptions struct {
+	Name        string
+	Roles       []string
+	Permissions []string
+	// TTL is how long the key is valid; zero means it does not expire.
+	TTL time.Duration
+}
+
+// Provider authenticates requests by API key and manages the key lifecycle.
+// It implements auth.Authenticator.
+type Provider struct {
+	store  Store
+	config Config
+	logger *slog.Logger
+	now    func() time.Time
+}
+
+// Option configures a Provider.
+type Option func(*Provider)
+
+// WithLogger sets the logger used to report last-used tracking failures.
+func WithLogger(logger *slog.Logger) Option {
+	return func(p *Provider) {
+		p.logger = logger
+	}
+}
+
+// NewProvider creates an API key provider on the given store.
+func NewProvider(store Store, config Config, opts ...Option) *Provider {
+	if config.Header == "" {
+		config.Header = DefaultHeader
+	}
+	if config.LastUsedInterval <= 0 {
+		config.LastUsedInterval = DefaultLastUsedInterval
+	}
+
+	p := &Provider{
+		store:  store,
+		config: config,
+		logger: slog.Default(),
+		now:    time.Now,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	p.logger = p.logger.With("component", "apikey-provider")
+	return p
+}
+
+// Authenticate resolves the API key carried by the request to a user with
+// the key's roles and permissions.
+func (p *Provider) Authenticate(r *http.Request) (*auth.User, error) {
+	raw := p.extractKey(r)
+	if raw == "" {
+		return nil, auth.ErrMissingToken
+	}
+	if !looksLikeKey(raw) {
+		return nil, auth.ErrInvalidAPIKey
+	}
+
+	ctx := r.Context()
+	key, err := p.store.GetByHash(ctx, Hash(raw))
+	if errors.Is(err, ErrKeyNotFound) {
+		return nil, auth.ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, fmt.Errorf("looking up api key: %w", err)
+	}
+
+	now := p.now()
+	if key.Revoked() {
+		return nil, auth.ErrInvalidAPIKey
+	}
+	if key.Expired(now) {
+		return nil, auth.ErrExpiredAPIKey
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= p.config.LastUsedInterval {
+		if err := p.store.TouchLastUsed(ctx, key.ID, now); err != nil {
+			p.logger.Warn("failed to record api key use", "key_id", key.ID, "error", err)
+		}
+	}
+
+	return userFromKey(key), nil
+}
+
+// extractKey returns the key from the configured header or query parameter.
+func (p *Provider) extractKey(r *http.Request) string {
+	if value := strings.TrimSpace(r.Header.Get(p.config.Header)); value != "" {
+		if strings.EqualFold(p.config.Header, "Authorization") {
+			if scheme, key, ok := strings.Cut(value, " "); ok &&
+				(strings.EqualFold(scheme, "Bearer") || strings.EqualFold(scheme, "ApiKey")) {
+				return strings.TrimSpace(key)
+			}
+		}
+		return value
+	}
+	if p.config.QueryParam != "" {
+		return r.URL.Query().Get(p.config.QueryParam)
+	}
+	return ""
+}
+
+func userFromKey(key *Key) *auth.User {
+	user := &auth.User{
+		ID:          key.ID,
+		Name:        key.Name,
+		Roles:       key.Roles,
+		Permissions: key.Permissions,
+		Claims: map[string]any{
+			"auth_method": "apikey",
+			"key_id":      key.ID,
+			"key_prefix":  key.Prefix,
+		},
+	}
+	if key.ExpiresAt != nil {
+		user.ExpiresAt = *key.ExpiresAt
+	}
+	return user
+}
+
+// Issue creates a new key and returns its plaintext value, which is not
+// stored and cannot be retrieved later.
+func (p *Provider) Issue(ctx context.Context, opts IssueOptions) (string, *Key, error) {
+	if opts.Name == "" {
+		return "", nil, errors.New("api key name is required")
+	}
+	if opts.TTL < 0 {
+		return "", nil, errors.New("api key TTL must not be negative")
+	}
+	return p.issue(ctx, opts, "")
+}
+
+func (p *Provider) issue(ctx context.Context, opts IssueOptions, rotatedFrom string) (string, *Key, error) {
+	raw, err := generate()
+	if err != nil {
+		return "", nil, fmt.Errorf("generating api key: %w", err)
+	}
+
+	now := p.now().UTC()
+	key := &Key{
+		ID:          uuid.New().String(),
+		Name:        opts.Name,
+		Prefix:      displayPrefix(raw),
+		Hash:        Hash(raw),
+		Roles:       opts.Roles,
+		Permissions: opts.Permissions,
+		CreatedAt:   now,
+		RotatedFrom: rotatedFrom,
+	}
+	if opts.TTL > 0 {
+		expires := now.Add(opts.TTL)
+		key.ExpiresAt = &expires
+	}
+
+	if err := p.store.Create(ctx, key); err != nil {
+		return "", nil, fmt.Errorf("storing api key: %w", err)
+	}
+	return raw, key, nil
+}
+
+// Rotate issues a replacement for a key with the same name, roles and
+// permissions. The old key keeps working for the overlap period so clients
+// can switch over; with no overlap it is revoked immediately.
+func (p *Provider) Rotate(ctx context.Context, id string, overlap time.Duration) (string, *Key, error) {
+	old, err := p.store.Get(ctx, id)
+	if err != nil {
+		return "", nil, err
+	}
+	now := p.now()
+	if old.Revoked() || old.Expired(now) {
+		return "", nil, fmt.Errorf("api key %s is no longer active", id)
+	}
+
+	opts := IssueOptions{Name: old.Name, Roles: old.Roles, Permissions: old.Permissions}
+	if old.ExpiresAt != nil {
+		// Keep the lifetime of the original key
+		opts.TTL = old.ExpiresAt.Sub(old.CreatedAt)
+	}
+	raw, key, err := p.issue(ctx, opts, old.ID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if overlap <= 0 {
+		err = p.store.Revoke(ctx, old.ID, now)
+	} else if cutoff := now.Add(overlap); old.ExpiresAt == nil || cutoff.Before(*old.ExpiresAt) {
+		err = p.store.SetExpiry(ctx, old.ID, cutoff)
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("retiring api key %s: %w", old.ID, err)
+	}
+	return raw, key, nil
+}
+
+// Revoke revokes a key immediately.
+func (p *Provider) Revoke(ctx context.Context, id string) error {
+	key, err := p.store.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if key.Revoked() {
+		return nil
+	}
+	return p.store.Revoke(ctx, id, p.now())
+}
+
+// List returns all keys.
+func (p *Provider) List(ctx context.Context) ([]*Key, error) {
+	return p.store.List(ctx)
+}
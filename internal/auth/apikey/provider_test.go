@@ -0,0 +1,236 @@
+package apikey
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bargom/codeai/internal/auth"
+)
+
+// newTestProvider returns a provider whose clock is set by the returned func.
+func newTestProvider(t *testing.T, store Store, cfg Config) (*Provider, func(time.Time)) {
+	t.Helper()
+	p := NewProvider(store, cfg)
+	now := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	p.now = func() time.Time { return now }
+	return p, func(t time.Time) { now = t }
+}
+
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+})
+
+func TestProvider_IssueAndAuthenticate(t *testing.T) {
+	store := NewMemoryStore()
+	p, _ := newTestProvider(t, store, Config{})
+	ctx := context.Background()
+
+	raw, key, err := p.Issue(ctx, IssueOptions{
+		Name:        "ci",
+		Roles:       []string{"admin"},
+		Permissions: []string{"users:read"},
+	})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(raw, "cai_"))
+	assert.Equal(t, raw[:12], key.Prefix)
+	assert.Nil(t, key.ExpiresAt)
+
+	stored, err := store.Get(ctx, key.ID)
+	require.NoError(t, err)
+	assert.Equal(t, Hash(raw), stored.Hash)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-API-Key", raw)
+	user, err := p.Authenticate(req)
+	require.NoError(t, err)
+	assert.Equal(t, key.ID, user.ID)
+	assert.Equal(t, "ci", user.Name)
+	assert.Equal(t, []string{"admin"}, user.Roles)
+	assert.Equal(t, []string{"users:read"}, user.Permissions)
+	assert.Equal(t, "apikey", user.Claims["auth_method"])
+
+	_, _, err = p.Issue(ctx, IssueOptions{})
+	assert.Error(t, err, "name is required")
+}
+
+func TestProvider_Authenticate_Errors(t *testing.T) {
+	p, setNow := newTestProvider(t, NewMemoryStore(), Config{})
+	ctx := context.Background()
+	start := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+
+	authenticate := func(key string) error {
+		req := httptest.NewRequest("GET", "/", nil)
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		_, err := p.Authenticate(req)
+		return err
+	}
+
+	assert.ErrorIs(t, authenticate(""), auth.ErrMissingToken)
+	assert.ErrorIs(t, authenticate("not-a-key"), auth.ErrInvalidAPIKey)
+	assert.ErrorIs(t, authenticate("cai_unknown"), auth.ErrInvalidAPIKey)
+
+	expiring, _, err := p.Issue(ctx, IssueOptions{Name: "temp", TTL: time.Hour})
+	require.NoError(t, err)
+	assert.NoError(t, authenticate(expiring))
+	setNow(start.Add(time.Hour))
+	assert.ErrorIs(t, authenticate(expiring), auth.ErrExpiredAPIKey)
+
+	revoked, key, err := p.Issue(ctx, IssueOptions{Name: "old"})
+	require.NoError(t, err)
+	require.NoError(t, p.Revoke(ctx, key.ID))
+	assert.ErrorIs(t, authenticate(revoked), auth.ErrInvalidAPIKey)
+	assert.ErrorIs(t, p.Revoke(ctx, "missing"), ErrKeyNotFound)
+}
+
+func TestProvider_Lookup(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	t.Run("query parameter", func(t *testing.T) {
+		p, _ := newTestProvider(t, store, Config{QueryParam: "api_key"})
+		raw, _, err := p.Issue(ctx, IssueOptions{Name: "query"})
+		require.NoError(t, err)
+
+		_, err = p.Authenticate(httptest.NewRequest("GET", "/?api_key="+raw, nil))
+		assert.NoError(t, err)
+	})
+
+	t.Run("query disabled by default", func(t *testing.T) {
+		p, _ := newTestProvider(t, store, Config{})
+		raw, _, err := p.Issue(ctx, IssueOptions{Name: "query"})
+		require.NoError(t, err)
+
+		_, err = p.Authenticate(httptest.NewRequest("GET", "/?api_key="+raw, nil))
+		assert.ErrorIs(t, err, auth.ErrMissingToken)
+	})
+
+	t.Run("authorization header", func(t *testing.T) {
+		p, _ := newTestProvider(t, store, Config{Header: "Authorization"})
+		raw, _, err := p.Issue(ctx, IssueOptions{Name: "bearer"})
+		require.NoError(t, err)
+
+		for _, value := range []string{"Bearer " + raw, "ApiKey " + raw, raw} {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("Authorization", value)
+			_, err = p.Authenticate(req)
+			assert.NoError(t, err, value)
+		}
+	})
+}
+
+func TestProvider_LastUsed(t *testing.T) {
+	store := NewMemoryStore()
+	p, setNow := newTestProvider(t, store, Config{LastUsedInterval: time.Minute})
+	ctx := context.Background()
+	start := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+
+	raw, key, err := p.Issue(ctx, IssueOptions{Name: "ci"})
+	require.NoError(t, err)
+
+	use := func(at time.Time) *time.Time {
+		setNow(at)
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-API-Key", raw)
+		_, err := p.Authenticate(req)
+		require.NoError(t, err)
+		stored, err := store.Get(ctx, key.ID)
+		require.NoError(t, err)
+		return stored.LastUsedAt
+	}
+
+	require.NotNil(t, use(start))
+	assert.Equal(t, start, *use(start.Add(30 * time.Second)), "writes are throttled")
+	assert.Equal(t, start.Add(2*time.Minute), *use(start.Add(2 * time.Minute)))
+}
+
+func TestProvider_Rotate(t *testing.T) {
+	store := NewMemoryStore()
+	p, setNow := newTestProvider(t, store, Config{})
+	ctx := context.Background()
+	start := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+
+	oldRaw, old, err := p.Issue(ctx, IssueOptions{Name: "ci", Roles: []string{"editor"}, TTL: 30 * 24 * time.Hour})
+	require.NoError(t, err)
+
+	newRaw, rotated, err := p.Rotate(ctx, old.ID, time.Hour)
+	require.NoError(t, err)
+	assert.NotEqual(t, oldRaw, newRaw)
+	assert.Equal(t, old.ID, rotated.RotatedFrom)
+	assert.Equal(t, []string{"editor"}, rotated.Roles)
+	require.NotNil(t, rotated.ExpiresAt)
+	assert.Equal(t, start.Add(30*24*time.Hour), *rotated.ExpiresAt)
+
+	authenticate := func(key string) error {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-API-Key", key)
+		_, err := p.Authenticate(req)
+		return err
+	}
+
+	// Both keys work during the overlap
+	assert.NoError(t, authenticate(oldRaw))
+	assert.NoError(t, authenticate(newRaw))
+
+	setNow(start.Add(time.Hour))
+	assert.ErrorIs(t, authenticate(oldRaw), auth.ErrExpiredAPIKey)
+	assert.NoError(t, authenticate(newRaw))
+
+	_, _, err = p.Rotate(ctx, old.ID, time.Hour)
+	assert.Error(t, err, "expired keys cannot be rotated")
+
+	// Without overlap the old key is revoked at once
+	_, _, err = p.Rotate(ctx, rotated.ID, 0)
+	require.NoError(t, err)
+	assert.ErrorIs(t, authenticate(newRaw), auth.ErrInvalidAPIKey)
+}
+
+func TestProvider_Middleware(t *testing.T) {
+	p, _ := newTestProvider(t, NewMemoryStore(), Config{})
+	raw, _, err := p.Issue(context.Background(), IssueOptions{Name: "ci", Roles: []string{"admin"}})
+	require.NoError(t, err)
+
+	mw := auth.NewAuthenticatorMiddleware(p)
+	handler := mw.RequireAuth()(mw.RequireRole("admin")(okHandler))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, 401, w.Code)
+	assert.Contains(t, w.Body.String(), "authentication required")
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-API-Key", "cai_wrong")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, 401, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid API key")
+
+	req.Header.Set("X-API-Key", raw)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+}
+
+func TestConfigFromSettings(t *testing.T) {
+	cfg, err := ConfigFromSettings(map[string]any{
+		"header":             "X-Service-Key",
+		"query":              "key",
+		"last_used_interval": "5m",
+		"storage":            "memory",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, Config{Header: "X-Service-Key", QueryParam: "key", LastUsedInterval: 5 * time.Minute}, cfg)
+
+	_, err = ConfigFromSettings(map[string]any{"header": true})
+	assert.Error(t, err)
+	_, err = ConfigFromSettings(map[string]any{"last_used_interval": "soon"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,194 @@
+package apikey
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SQLStore implements Store on PostgreSQL.
+type SQLStore struct {
+	db *sql.DB
+}
+
+// NewSQLStore creates a key store on the given PostgreSQL database.
+func NewSQLStore(db *sql.DB) *SQLStore {
+	return &SQLStore{db: db}
+}
+
+// CreateTable creates the api_keys table if it doesn't exist.
+func (s *SQLStore) CreateTable(ctx context.Context) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS api_keys (
+			id TEXT PRIMARY KEY,
+			name TEXT NOT NULL,
+			prefix TEXT NOT NULL,
+			hash TEXT NOT NULL UNIQUE,
+			roles TEXT NOT NULL DEFAULT '[]',
+			permissions TEXT NOT NULL DEFAULT '[]',
+			rotated_from TEXT,
+			created_at TIMESTAMP NOT NULL,
+			expires_at TIMESTAMP,
+			revoked_at TIMESTAMP,
+			last_used_at TIMESTAMP
+		)
+	`
+	if _, err := s.db.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("creating api_keys table: %w", err)
+	}
+	return nil
+}
+
+// Create stores a new key.
+func (s *SQLStore) Create(ctx context.Context, key *Key) error {
+	roles, err := json.Marshal(nonNil(key.Roles))
+	if err != nil {
+		return fmt.Errorf("marshaling roles: %w", err)
+	}
+	perms, err := json.Marshal(nonNil(key.Permissions))
+	if err != nil {
+		return fmt.Errorf("marshaling permissions: %w", err)
+	}
+
+	query := `
+		INSERT INTO api_keys (id, name, prefix, hash, roles, permissions, rotated_from,
+			created_at, expires_at, revoked_at, last_used_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`
+	_, err = s.db.ExecContext(ctx, query,
+		key.ID, key.Name, key.Prefix, key.Hash, string(roles), string(perms),
+		nullString(key.RotatedFrom), key.CreatedAt.UTC(),
+		nullTime(key.ExpiresAt), nullTime(key.RevokedAt), nullTime(key.LastUsedAt),
+	)
+	if err != nil {
+		return fmt.Errorf("inserting api key: %w", err)
+	}
+	return nil
+}
+
+const selectKey = `
+	SELECT id, name, prefix, hash, roles, permissions, rotated_from,
+		created_at, expires_at, revoked_at, last_used_at
+	FROM api_keys
+`
+
+// Get retrieves a key by ID.
+func (s *SQLStore) Get(ctx context.Context, id string) (*Key, error) {
+	return scanKey(s.db.QueryRowContext(ctx, selectKey+"WHERE id = $1", id))
+}
+
+// GetByHash retrieves a key by the hash of its plaintext value.
+func (s *SQLStore) GetByHash(ctx context.Context, hash string) (*Key, error) {
+	return scanKey(s.db.QueryRowContext(ctx, selectKey+"WHERE hash = $1", hash))
+}
+
+// List returns all keys, oldest first.
+func (s *SQLStore) List(ctx context.Context) ([]*Key, error) {
+	rows, err := s.db.QueryContext(ctx, selectKey+"ORDER BY created_at, id")
+	if err != nil {
+		return nil, fmt.Errorf("listing api keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []*Key
+	for rows.Next() {
+		key, err := scanKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// Revoke marks a key as revoked at the given time.
+func (s *SQLStore) Revoke(ctx context.Context, id string, at time.Time) error {
+	return s.setTime(ctx, "revoked_at", id, at)
+}
+
+// SetExpiry sets the time a key expires.
+func (s *SQLStore) SetExpiry(ctx context.Context, id string, at time.Time) error {
+	return s.setTime(ctx, "expires_at", id, at)
+}
+
+// TouchLastUsed records the time a key was last used.
+func (s *SQLStore) TouchLastUsed(ctx context.Context, id string, at time.Time) error {
+	return s.setTime(ctx, "last_used_at", id, at)
+}
+
+// setTime sets a timestamp column; column is always a constant.
+func (s *SQLStore) setTime(ctx context.Context, column, id string, at time.Time) error {
+	result, err := s.db.ExecContext(ctx, "UPDATE api_keys SET "+column+" = $1 WHERE id = $2", at.UTC(), id)
+	if err != nil {
+		return fmt.Errorf("updating api key %s: %w", column, err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("updating api key %s: %w", column, err)
+	}
+	if n == 0 {
+		return ErrKeyNotFound
+	}
+	return nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanKey(row scanner) (*Key, error) {
+	var (
+		key                            Key
+		roles, perms                   string
+		rotatedFrom                    sql.NullString
+		expiresAt, revokedAt, lastUsed sql.NullTime
+	)
+	err := row.Scan(&key.ID, &key.Name, &key.Prefix, &key.Hash, &roles, &perms, &rotatedFrom,
+		&key.CreatedAt, &expiresAt, &revokedAt, &lastUsed)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scanning api key: %w", err)
+	}
+
+	if err := json.Unmarshal([]byte(roles), &key.Roles); err != nil {
+		return nil, fmt.Errorf("unmarshaling roles: %w", err)
+	}
+	if err := json.Unmarshal([]byte(perms), &key.Permissions); err != nil {
+		return nil, fmt.Errorf("unmarshaling permissions: %w", err)
+	}
+	key.RotatedFrom = rotatedFrom.String
+	key.ExpiresAt = timePtr(expiresAt)
+	key.RevokedAt = timePtr(revokedAt)
+	key.LastUsedAt = timePtr(lastUsed)
+	return &key, nil
+}
+
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func nullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: t.UTC(), Valid: true}
+}
+
+func timePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
@@ -0,0 +1,74 @@
+package apikey
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+func newSQLStore(t *testing.T) *SQLStore {
+	t.Helper()
+	db, err := sql.Open("sqlite", ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	store := NewSQLStore(db)
+	require.NoError(t, store.CreateTable(context.Background()))
+	return store
+}
+
+func TestSQLStore(t *testing.T) {
+	store := newSQLStore(t)
+	ctx := context.Background()
+	created := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	expires := created.Add(24 * time.Hour)
+
+	key := &Key{
+		ID:          "key-1",
+		Name:        "ci",
+		Prefix:      "cai_abcdefgh",
+		Hash:        Hash("cai_abcdefgh123"),
+		Roles:       []string{"admin", "editor"},
+		Permissions: []string{"users:read"},
+		CreatedAt:   created,
+		ExpiresAt:   &expires,
+	}
+	require.NoError(t, store.Create(ctx, key))
+	require.NoError(t, store.Create(ctx, &Key{ID: "key-2", Name: "other", Prefix: "cai_x", Hash: "h2", CreatedAt: created.Add(time.Minute)}))
+
+	got, err := store.GetByHash(ctx, key.Hash)
+	require.NoError(t, err)
+	assert.Equal(t, "key-1", got.ID)
+	assert.Equal(t, []string{"admin", "editor"}, got.Roles)
+	assert.Equal(t, []string{"users:read"}, got.Permissions)
+	require.NotNil(t, got.ExpiresAt)
+	assert.True(t, expires.Equal(*got.ExpiresAt))
+	assert.Nil(t, got.RevokedAt)
+	assert.Nil(t, got.LastUsedAt)
+
+	_, err = store.Get(ctx, "missing")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+	_, err = store.GetByHash(ctx, "missing")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	used := created.Add(time.Hour)
+	require.NoError(t, store.TouchLastUsed(ctx, "key-1", used))
+	require.NoError(t, store.Revoke(ctx, "key-1", used))
+	require.NoError(t, store.SetExpiry(ctx, "key-2", used))
+	assert.ErrorIs(t, store.Revoke(ctx, "missing", used), ErrKeyNotFound)
+
+	keys, err := store.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.Equal(t, "key-1", keys[0].ID)
+	require.NotNil(t, keys[0].LastUsedAt)
+	assert.True(t, used.Equal(*keys[0].LastUsedAt))
+	assert.True(t, keys[0].Revoked())
+	assert.Empty(t, keys[1].Roles)
+	assert.True(t, keys[1].Expired(used))
+}
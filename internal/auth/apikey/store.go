@@ -0,0 +1,132 @@
+package apikey
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Store persists API keys.
+type Store interface {
+	// Create stores a new key.
+	Create(ctx context.Context, key *Key) error
+
+	// Get retrieves a key by ID.
+	Get(ctx context.Context, id string) (*Key, error)
+
+	// GetByHash retrieves a key by the hash of its plaintext value.
+	GetByHash(ctx context.Context, hash string) (*Key, error)
+
+	// List returns all keys, oldest first.
+	List(ctx context.Context) ([]*Key, error)
+
+	// Revoke marks a key as revoked at the given time.
+	Revoke(ctx context.Context, id string, at time.Time) error
+
+	// SetExpiry sets the time a key expires.
+	SetExpiry(ctx context.Context, id string, at time.Time) error
+
+	// TouchLastUsed records the time a key was last used.
+	TouchLastUsed(ctx context.Context, id string, at time.Time) error
+}
+
+// MemoryStore is an in-memory implementation of Store.
+type MemoryStore struct {
+	keys   map[string]*Key
+	byHash map[string]string
+	mu     sync.RWMutex
+}
+
+// NewMemoryStore creates a new in-memory key store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		keys:   make(map[string]*Key),
+		byHash: make(map[string]string),
+	}
+}
+
+// Create stores a new key.
+func (s *MemoryStore) Create(ctx context.Context, key *Key) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.keys[key.ID]; ok {
+		return ErrKeyExists
+	}
+	if _, ok := s.byHash[key.Hash]; ok {
+		return ErrKeyExists
+	}
+	s.keys[key.ID] = copyKey(key)
+	s.byHash[key.Hash] = key.ID
+	return nil
+}
+
+// Get retrieves a key by ID.
+func (s *MemoryStore) Get(ctx context.Context, id string) (*Key, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key, ok := s.keys[id]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	return copyKey(key), nil
+}
+
+// GetByHash retrieves a key by the hash of its plaintext value.
+func (s *MemoryStore) GetByHash(ctx context.Context, hash string) (*Key, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	id, ok := s.byHash[hash]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	return copyKey(s.keys[id]), nil
+}
+
+// List returns all keys, oldest first.
+func (s *MemoryStore) List(ctx context.Context) ([]*Key, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := make([]*Key, 0, len(s.keys))
+	for _, key := range s.keys {
+		keys = append(keys, copyKey(key))
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].ID < keys[j].ID
+		}
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+	return keys, nil
+}
+
+// Revoke marks a key as revoked at the given time.
+func (s *MemoryStore) Revoke(ctx context.Context, id string, at time.Time) error {
+	return s.update(id, func(k *Key) { k.RevokedAt = &at })
+}
+
+// SetExpiry sets the time a key expires.
+func (s *MemoryStore) SetExpiry(ctx context.Context, id string, at time.Time) error {
+	return s.update(id, func(k *Key) { k.ExpiresAt = &at })
+}
+
+// TouchLastUsed records the time a key was last used.
+func (s *MemoryStore) TouchLastUsed(ctx context.Context, id string, at time.Time) error {
+	return s.update(id, func(k *Key) { k.LastUsedAt = &at })
+}
+
+func (s *MemoryStore) update(id string, fn func(*Key)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.keys[id]
+	if !ok {
+		return ErrKeyNotFound
+	}
+	fn(key)
+	return nil
+}
@@ -0,0 +1,188 @@
+package basic
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ModelFields names the fields of a user model that hold credentials.
+type ModelFields struct {
+	ID          string // default "id" ("_id" for MongoDB)
+	Username    string // default "username"
+	Password    string // default "password_hash"
+	Roles       string // optional; a single role, a list, or a comma-separated string
+	Permissions string // optional; as Roles
+}
+
+var fieldNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// withDefaults fills in default field names and checks that all names are
+// plain identifiers, since they are interpolated into queries.
+func (f ModelFields) withDefaults(idField string) (ModelFields, error) {
+	if f.ID == "" {
+		f.ID = idField
+	}
+	if f.Username == "" {
+		f.Username = "username"
+	}
+	if f.Password == "" {
+		f.Password = "password_hash"
+	}
+	for _, name := range []string{f.ID, f.Username, f.Password, f.Roles, f.Permissions} {
+		if name != "" && !fieldNamePattern.MatchString(name) {
+			return ModelFields{}, fmt.Errorf("invalid field name %q", name)
+		}
+	}
+	return f, nil
+}
+
+// SQLStore looks up credentials in the table of a PostgreSQL model.
+type SQLStore struct {
+	db    *sql.DB
+	query string
+}
+
+// NewSQLStore creates a credential store on the given table.
+func NewSQLStore(db *sql.DB, table string, fields ModelFields) (*SQLStore, error) {
+	if !fieldNamePattern.MatchString(table) {
+		return nil, fmt.Errorf("invalid table name %q", table)
+	}
+	fields, err := fields.withDefaults("id")
+	if err != nil {
+		return nil, err
+	}
+
+	columns := []string{fields.ID, fields.Username, fields.Password, "NULL", "NULL"}
+	if fields.Roles != "" {
+		columns[3] = fields.Roles
+	}
+	if fields.Permissions != "" {
+		columns[4] = fields.Permissions
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s = $1",
+		strings.Join(columns, ", "), table, fields.Username)
+	return &SQLStore{db: db, query: query}, nil
+}
+
+// Lookup returns the credential for the username.
+func (s *SQLStore) Lookup(ctx context.Context, username string) (*Credential, error) {
+	var (
+		cred         Credential
+		id           any
+		roles, perms any
+	)
+	err := s.db.QueryRowContext(ctx, s.query, username).Scan(&id, &cred.Username, &cred.PasswordHash, &roles, &perms)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("looking up credentials: %w", err)
+	}
+
+	cred.ID = stringValue(id)
+	cred.Roles = listValue(roles)
+	cred.Permissions = listValue(perms)
+	return &cred, nil
+}
+
+// MongoStore looks up credentials in a MongoDB collection.
+type MongoStore struct {
+	collection *mongo.Collection
+	fields     ModelFields
+}
+
+// NewMongoStore creates a credential store on the given collection.
+func NewMongoStore(db *mongo.Database, collection string, fields ModelFields) (*MongoStore, error) {
+	fields, err := fields.withDefaults("_id")
+	if err != nil {
+		return nil, err
+	}
+	return &MongoStore{collection: db.Collection(collection), fields: fields}, nil
+}
+
+// Lookup returns the credential for the username.
+func (s *MongoStore) Lookup(ctx context.Context, username string) (*Credential, error) {
+	var doc bson.M
+	err := s.collection.FindOne(ctx, bson.M{s.fields.Username: username}).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("looking up credentials: %w", err)
+	}
+
+	cred := &Credential{
+		ID:           stringValue(doc[s.fields.ID]),
+		Username:     username,
+		PasswordHash: stringValue(doc[s.fields.Password]),
+	}
+	if s.fields.Roles != "" {
+		cred.Roles = listValue(doc[s.fields.Roles])
+	}
+	if s.fields.Permissions != "" {
+		cred.Permissions = listValue(doc[s.fields.Permissions])
+	}
+	return cred, nil
+}
+
+// stringValue converts a scanned or decoded ID value to a string.
+func stringValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case primitive.ObjectID:
+		return v.Hex()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// listValue converts a roles or permissions value to a list. Strings may
+// hold a JSON array, a PostgreSQL array literal or comma-separated items.
+func listValue(v any) []string {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return listValue(string(v))
+	case string:
+		v = strings.TrimSpace(v)
+		switch {
+		case strings.HasPrefix(v, "["):
+			var items []string
+			if err := json.Unmarshal([]byte(v), &items); err == nil {
+				return items
+			}
+		case strings.HasPrefix(v, "{") && strings.HasSuffix(v, "}"):
+			v = strings.ReplaceAll(v[1:len(v)-1], `"`, "")
+		}
+		return splitList(v)
+	case []string:
+		return v
+	case bson.A:
+		return listValue([]any(v))
+	case []any:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				items = append(items, s)
+			}
+		}
+		return items
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,82 @@
+package basic
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	_ "modernc.org/sqlite"
+)
+
+func TestSQLStore(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	_, err = db.ExecContext(ctx, `CREATE TABLE users (id TEXT, email TEXT, password_hash TEXT, role TEXT, scopes TEXT)`)
+	require.NoError(t, err)
+	_, err = db.ExecContext(ctx, `INSERT INTO users VALUES
+		('u1', 'alice@example.com', '$2y$hash', 'admin', '["users:read","users:write"]'),
+		('u2', 'bob@example.com', '$2y$hash', NULL, NULL)`)
+	require.NoError(t, err)
+
+	store, err := NewSQLStore(db, "users", ModelFields{Username: "email", Roles: "role", Permissions: "scopes"})
+	require.NoError(t, err)
+
+	cred, err := store.Lookup(ctx, "alice@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, &Credential{
+		ID:           "u1",
+		Username:     "alice@example.com",
+		PasswordHash: "$2y$hash",
+		Roles:        []string{"admin"},
+		Permissions:  []string{"users:read", "users:write"},
+	}, cred)
+
+	cred, err = store.Lookup(ctx, "bob@example.com")
+	require.NoError(t, err)
+	assert.Nil(t, cred.Roles)
+
+	_, err = store.Lookup(ctx, "carol@example.com")
+	assert.ErrorIs(t, err, ErrUserNotFound)
+
+	// Roles and permissions fields are optional
+	store, err = NewSQLStore(db, "users", ModelFields{Username: "email"})
+	require.NoError(t, err)
+	cred, err = store.Lookup(ctx, "alice@example.com")
+	require.NoError(t, err)
+	assert.Nil(t, cred.Roles)
+
+	_, err = NewSQLStore(db, "users; DROP TABLE users", ModelFields{})
+	assert.Error(t, err)
+	_, err = NewSQLStore(db, "users", ModelFields{Roles: "role, password_hash"})
+	assert.Error(t, err)
+}
+
+func TestListValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+		want  []string
+	}{
+		{"nil", nil, nil},
+		{"single role", "admin", []string{"admin"}},
+		{"comma separated", "admin, editor", []string{"admin", "editor"}},
+		{"json array", `["admin","editor"]`, []string{"admin", "editor"}},
+		{"postgres array", []byte(`{admin,"editor"}`), []string{"admin", "editor"}},
+		{"bson array", bson.A{"admin", "editor"}, []string{"admin", "editor"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, listValue(tt.value))
+		})
+	}
+
+	id := primitive.NewObjectID()
+	assert.Equal(t, id.Hex(), stringValue(id))
+}
@@ -0,0 +1,162 @@
+// Package basic implements HTTP Basic authentication for CodeAI.
+//
+// Credentials come from an htpasswd-style file or from a user model in
+// PostgreSQL or MongoDB; password hashes are bcrypt or argon2id. The
+// authenticated auth.User carries the roles and permissions stored with the
+// credential, so role checks and the rbac engine work as with JWTs.
+package basic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/bargom/codeai/internal/auth"
+)
+
+// DefaultRealm is the realm sent in the WWW-Authenticate challenge.
+const DefaultRealm = "Restricted"
+
+// Config configures a Provider and where its credentials come from.
+type Config struct {
+	// Realm is sent in the WWW-Authenticate challenge (default Restricted).
+	Realm string
+
+	// File is the path of an htpasswd-style credentials file.
+	File string
+
+	// Model is the user model (PostgreSQL) or collection (MongoDB) holding
+	// credentials, with Fields naming its columns.
+	Model  string
+	Fields ModelFields
+}
+
+// ConfigFromSettings builds a Config from the settings of an
+// "auth ... { method basic config { ... } }" declaration: realm, file,
+// model, username_field, password_field, roles_field and permissions_field.
+func ConfigFromSettings(settings map[string]any) (Config, error) {
+	var cfg Config
+	targets := []struct {
+		key string
+		dst *string
+	}{
+		{"realm", &cfg.Realm},
+		{"file", &cfg.File},
+		{"model", &cfg.Model},
+		{"username_field", &cfg.Fields.Username},
+		{"password_field", &cfg.Fields.Password},
+		{"roles_field", &cfg.Fields.Roles},
+		{"permissions_field", &cfg.Fields.Permissions},
+	}
+	for _, t := range targets {
+		value, ok := settings[t.key]
+		if !ok {
+			continue
+		}
+		s, ok := value.(string)
+		if !ok {
+			return Config{}, fmt.Errorf("%s must be a string", t.key)
+		}
+		*t.dst = s
+	}
+
+	if cfg.File != "" && cfg.Model != "" {
+		return Config{}, errors.New("file and model are mutually exclusive")
+	}
+	return cfg, nil
+}
+
+// Provider authenticates requests with HTTP Basic credentials.
+// It implements auth.Authenticator and auth.Challenger.
+type Provider struct {
+	store  CredentialStore
+	realm  string
+	logger *slog.Logger
+}
+
+// Option configures a Provider.
+type Option func(*Provider)
+
+// WithLogger sets the logger used to report credential lookup failures.
+func WithLogger(logger *slog.Logger) Option {
+	return func(p *Provider) {
+		p.logger = logger
+	}
+}
+
+// NewProvider creates a Basic auth provider on the given credential store.
+func NewProvider(store CredentialStore, config Config, opts ...Option) *Provider {
+	p := &Provider{
+		store:  store,
+		realm:  config.Realm,
+		logger: slog.Default(),
+	}
+	if p.realm == "" {
+		p.realm = DefaultRealm
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	p.logger = p.logger.With("component", "basic-auth-provider")
+	return p
+}
+
+// Authenticate checks the request's Basic credentials and returns a user
+// with the credential's roles and permissions.
+func (p *Provider) Authenticate(r *http.Request) (*auth.User, error) {
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return nil, auth.ErrMissingToken
+	}
+	return p.Verify(r.Context(), username, password)
+}
+
+// Verify checks a username and password against the credential store and
+// returns the user they belong to, or auth.ErrInvalidCredentials.
+func (p *Provider) Verify(ctx context.Context, username, password string) (*auth.User, error) {
+	cred, err := p.store.Lookup(ctx, username)
+	if errors.Is(err, ErrUserNotFound) {
+		// Spend the same time as for a wrong password, so response times
+		// don't reveal which usernames exist
+		bcrypt.CompareHashAndPassword(dummyHash(), []byte(password))
+		return nil, auth.ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	match, err := VerifyPassword(cred.PasswordHash, password)
+	if err != nil {
+		p.logger.Warn("cannot verify password", "username", username, "error", err)
+		return nil, auth.ErrInvalidCredentials
+	}
+	if !match {
+		return nil, auth.ErrInvalidCredentials
+	}
+	return cred.User(), nil
+}
+
+// Challenge returns the WWW-Authenticate challenge for 401 responses.
+func (p *Provider) Challenge() string {
+	realm := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(p.realm)
+	return fmt.Sprintf(`Basic realm="%s", charset="UTF-8"`, realm)
+}
+
+var (
+	dummyOnce sync.Once
+	dummy     []byte
+)
+
+// dummyHash returns a bcrypt hash compared against for unknown users.
+func dummyHash() []byte {
+	dummyOnce.Do(func() {
+		dummy, _ = bcrypt.GenerateFromPassword([]byte("codeai-dummy-password"), bcrypt.DefaultCost)
+	})
+	return dummy
+}
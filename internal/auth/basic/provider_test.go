@@ -0,0 +1,164 @@
+package basic
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/bargom/codeai/internal/auth"
+)
+
+func bcryptHash(t *testing.T, password string) string {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	require.NoError(t, err)
+	return string(hash)
+}
+
+func TestVerifyPassword(t *testing.T) {
+	argon, err := HashPassword("correct horse")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(argon, "$argon2id$v=19$m=65536,t=3,p=4$"))
+
+	tests := []struct {
+		name     string
+		hash     string
+		password string
+		want     bool
+	}{
+		{"argon2id match", argon, "correct horse", true},
+		{"argon2id mismatch", argon, "battery staple", false},
+		{"bcrypt match", bcryptHash(t, "s3cret"), "s3cret", true},
+		{"bcrypt mismatch", bcryptHash(t, "s3cret"), "secret", false},
+		// htpasswd -B writes $2y$ hashes
+		{"bcrypt 2y", "$2y" + bcryptHash(t, "s3cret")[3:], "s3cret", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := VerifyPassword(tt.hash, tt.password)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err = VerifyPassword("{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=", "password")
+	assert.ErrorIs(t, err, ErrUnsupportedHash)
+	_, err = VerifyPassword("$argon2id$v=19$m=65536$bad", "password")
+	assert.ErrorIs(t, err, ErrUnsupportedHash)
+}
+
+func TestParseFile(t *testing.T) {
+	store, err := ParseFile(strings.NewReader(`
+# Service accounts
+deploy:$2y$05$abc:admin,ops:deploy:run
+reader:$argon2id$v=19$m=65536,t=3,p=4$c2FsdA$a2V5
+`))
+	require.NoError(t, err)
+
+	cred, err := store.Lookup(context.Background(), "deploy")
+	require.NoError(t, err)
+	assert.Equal(t, "$2y$05$abc", cred.PasswordHash)
+	assert.Equal(t, []string{"admin", "ops"}, cred.Roles)
+	assert.Equal(t, []string{"deploy:run"}, cred.Permissions)
+
+	cred, err = store.Lookup(context.Background(), "reader")
+	require.NoError(t, err)
+	assert.Nil(t, cred.Roles)
+
+	_, err = store.Lookup(context.Background(), "nobody")
+	assert.ErrorIs(t, err, ErrUserNotFound)
+
+	_, err = ParseFile(strings.NewReader("justaname\n"))
+	assert.ErrorContains(t, err, "line 1")
+	_, err = ParseFile(strings.NewReader("a:$2y$x\na:$2y$y\n"))
+	assert.ErrorContains(t, err, `line 2: duplicate user "a"`)
+}
+
+func TestProvider_Authenticate(t *testing.T) {
+	store, err := ParseFile(strings.NewReader(
+		"alice:" + bcryptHash(t, "wonderland") + ":editor:posts:publish\n"))
+	require.NoError(t, err)
+	p := NewProvider(store, Config{Realm: "Admin"})
+
+	authenticate := func(username, password string) (*auth.User, error) {
+		req := httptest.NewRequest("GET", "/", nil)
+		if username != "" {
+			req.SetBasicAuth(username, password)
+		}
+		return p.Authenticate(req)
+	}
+
+	user, err := authenticate("alice", "wonderland")
+	require.NoError(t, err)
+	assert.Equal(t, "alice", user.ID)
+	assert.Equal(t, []string{"editor"}, user.Roles)
+	assert.Equal(t, []string{"posts:publish"}, user.Permissions)
+	assert.Equal(t, "basic", user.Claims["auth_method"])
+
+	_, err = authenticate("", "")
+	assert.ErrorIs(t, err, auth.ErrMissingToken)
+	_, err = authenticate("alice", "looking-glass")
+	assert.ErrorIs(t, err, auth.ErrInvalidCredentials)
+	_, err = authenticate("bob", "wonderland")
+	assert.ErrorIs(t, err, auth.ErrInvalidCredentials)
+}
+
+func TestProvider_Middleware(t *testing.T) {
+	store, err := ParseFile(strings.NewReader(
+		"alice:" + bcryptHash(t, "wonderland") + ":editor\n" +
+			"bob:" + bcryptHash(t, "builder") + ":reader\n"))
+	require.NoError(t, err)
+
+	mw := auth.NewAuthenticatorMiddleware(NewProvider(store, Config{Realm: "Admin"}))
+	handler := mw.RequireAuth()(mw.RequireRole("editor")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+
+	serve := func(username, password string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/", nil)
+		if username != "" {
+			req.SetBasicAuth(username, password)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve("", "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, `Basic realm="Admin", charset="UTF-8"`, w.Header().Get("WWW-Authenticate"))
+
+	w = serve("alice", "wrong")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid credentials")
+	assert.NotEmpty(t, w.Header().Get("WWW-Authenticate"))
+
+	assert.Equal(t, http.StatusForbidden, serve("bob", "builder").Code)
+	assert.Equal(t, http.StatusOK, serve("alice", "wonderland").Code)
+}
+
+func TestConfigFromSettings(t *testing.T) {
+	cfg, err := ConfigFromSettings(map[string]any{
+		"model":          "User",
+		"username_field": "email",
+		"roles_field":    "role",
+		"realm":          "Admin",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, Config{
+		Realm:  "Admin",
+		Model:  "User",
+		Fields: ModelFields{Username: "email", Roles: "role"},
+	}, cfg)
+
+	_, err = ConfigFromSettings(map[string]any{"file": "users.htpasswd", "model": "User"})
+	assert.ErrorContains(t, err, "mutually exclusive")
+	_, err = ConfigFromSettings(map[string]any{"realm": 1.0})
+	assert.ErrorContains(t, err, "realm must be a string")
+}
@@ -0,0 +1,131 @@
+package basic
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/bargom/codeai/internal/auth"
+)
+
+// ErrUserNotFound indicates no credential exists for the username.
+var ErrUserNotFound = errors.New("user not found")
+
+// Credential is a user's password hash along with what it grants.
+type Credential struct {
+	ID           string // Defaults to the username
+	Username     string
+	PasswordHash string
+	Roles        []string
+	Permissions  []string
+}
+
+// User returns the authenticated user for the credential.
+func (c *Credential) User() *auth.User {
+	id := c.ID
+	if id == "" {
+		id = c.Username
+	}
+	return &auth.User{
+		ID:          id,
+		Name:        c.Username,
+		Roles:       c.Roles,
+		Permissions: c.Permissions,
+		Claims: map[string]any{
+			"auth_method": "basic",
+			"username":    c.Username,
+		},
+	}
+}
+
+// CredentialStore looks up credentials by username.
+type CredentialStore interface {
+	// Lookup returns the credential for the username, or ErrUserNotFound.
+	Lookup(ctx context.Context, username string) (*Credential, error)
+}
+
+// FileStore holds credentials read from an htpasswd-style file.
+type FileStore struct {
+	credentials map[string]*Credential
+}
+
+// LoadFile reads credentials from the file at path. See ParseFile for the
+// format.
+func LoadFile(path string) (*FileStore, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening credentials file: %w", err)
+	}
+	defer f.Close()
+
+	store, err := ParseFile(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return store, nil
+}
+
+// ParseFile reads credentials in an extended htpasswd format, one user per
+// line:
+//
+//	username:hash[:role,role...[:permission,permission...]]
+//
+// Blank lines and lines starting with # are ignored. Hashes are bcrypt (as
+// written by htpasswd -B) or argon2id.
+func ParseFile(r io.Reader) (*FileStore, error) {
+	store := &FileStore{credentials: make(map[string]*Credential)}
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		// Permissions contain colons, so they take the rest of the line
+		fields := strings.SplitN(text, ":", 4)
+		if len(fields) < 2 || fields[0] == "" || fields[1] == "" {
+			return nil, fmt.Errorf("line %d: expected username:hash[:roles[:permissions]]", line)
+		}
+		if _, exists := store.credentials[fields[0]]; exists {
+			return nil, fmt.Errorf("line %d: duplicate user %q", line, fields[0])
+		}
+
+		cred := &Credential{ID: fields[0], Username: fields[0], PasswordHash: fields[1]}
+		if len(fields) > 2 {
+			cred.Roles = splitList(fields[2])
+		}
+		if len(fields) > 3 {
+			cred.Permissions = splitList(fields[3])
+		}
+		store.credentials[cred.Username] = cred
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading credentials: %w", err)
+	}
+	return store, nil
+}
+
+// Lookup returns the credential for the username.
+func (s *FileStore) Lookup(ctx context.Context, username string) (*Credential, error) {
+	cred, ok := s.credentials[username]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	return cred, nil
+}
+
+// splitList splits a comma-separated list, dropping empty items.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
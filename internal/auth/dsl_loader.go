@@ -2,6 +2,7 @@ package auth
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/bargom/codeai/internal/ast"
 )
@@ -12,7 +13,12 @@ type LoadedAuth struct {
 	Method   ast.AuthMethod
 	Config   *Config
 	JWKS     *ast.JWKSConfig
+	Settings map[string]any // Values from the provider's config block
 	RawDecl  *ast.AuthDecl
+
+	// IssuerSettings holds the values of the provider's issuer block, or
+	// nil if it does not issue tokens.
+	IssuerSettings map[string]any
 }
 
 // LoadedRole represents a role loaded from DSL.
@@ -37,14 +43,17 @@ type DSLLoader struct {
 	auths       map[string]*LoadedAuth
 	roles       map[string]*LoadedRole
 	middlewares map[string]*LoadedMiddleware
+	// authenticators holds providers that are not JWT based, by auth name
+	authenticators map[string]Authenticator
 }
 
 // NewDSLLoader creates a new DSL loader.
 func NewDSLLoader() *DSLLoader {
 	return &DSLLoader{
-		auths:       make(map[string]*LoadedAuth),
-		roles:       make(map[string]*LoadedRole),
-		middlewares: make(map[string]*LoadedMiddleware),
+		auths:          make(map[string]*LoadedAuth),
+		roles:          make(map[string]*LoadedRole),
+		middlewares:    make(map[string]*LoadedMiddleware),
+		authenticators: make(map[string]Authenticator),
 	}
 }
 
@@ -76,10 +85,20 @@ func (l *DSLLoader) loadAuth(decl *ast.AuthDecl) error {
 	}
 
 	loaded := &LoadedAuth{
-		Name:    decl.Name,
-		Method:  decl.Method,
-		JWKS:    decl.JWKS,
-		RawDecl: decl,
+		Name:     decl.Name,
+		Method:   decl.Method,
+		JWKS:     decl.JWKS,
+		Settings: make(map[string]any, len(decl.Config)),
+		RawDecl:  decl,
+	}
+	for key, expr := range decl.Config {
+		loaded.Settings[key] = l.extractExpressionValue(expr)
+	}
+	if decl.Issuer != nil {
+		loaded.IssuerSettings = make(map[string]any, len(decl.Issuer.Config))
+		for key, expr := range decl.Issuer.Config {
+			loaded.IssuerSettings[key] = l.extractExpressionValue(expr)
+		}
 	}
 
 	// Build Config from JWKS if present
@@ -155,6 +174,14 @@ func (l *DSLLoader) extractExpressionValue(expr ast.Expression) any {
 		return e.Value
 	case *ast.Identifier:
 		return e.Name
+	case *ast.FunctionCall:
+		// env("NAME") reads the value from the environment at load time
+		if e.Name == "env" && len(e.Args) == 1 {
+			if name, ok := e.Args[0].(*ast.StringLiteral); ok {
+				return os.Getenv(name.Value)
+			}
+		}
+		return nil
 	default:
 		return nil
 	}
@@ -193,6 +220,17 @@ func (l *DSLLoader) AllMiddlewares() map[string]*LoadedMiddleware {
 	return l.middlewares
 }
 
+// RegisterAuthenticator sets the authenticator used by middleware that
+// reference the named auth provider, for methods other than JWT such as
+// API keys.
+func (l *DSLLoader) RegisterAuthenticator(authName string, authenticator Authenticator) error {
+	if _, ok := l.auths[authName]; !ok {
+		return fmt.Errorf("unknown auth provider: %s", authName)
+	}
+	l.authenticators[authName] = authenticator
+	return nil
+}
+
 // CreateValidator creates a JWT validator for the specified auth provider.
 func (l *DSLLoader) CreateValidator(authName string) (*Validator, error) {
 	auth, ok := l.auths[authName]
@@ -222,6 +260,10 @@ func (l *DSLLoader) CreateMiddlewareChain(middlewareName string) (*Middleware, e
 		return nil, fmt.Errorf("middleware %s has no provider configured", middlewareName)
 	}
 
+	if authenticator, ok := l.authenticators[mw.Provider]; ok {
+		return NewAuthenticatorMiddleware(authenticator), nil
+	}
+
 	validator, err := l.CreateValidator(mw.Provider)
 	if err != nil {
 		return nil, fmt.Errorf("creating validator for middleware %s: %w", middlewareName, err)
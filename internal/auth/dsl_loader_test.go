@@ -418,7 +418,7 @@ func TestDSLLoaderFromExampleFile(t *testing.T) {
 
 	// Verify auth providers
 	allAuths := loader.AllAuths()
-	assert.Len(t, allAuths, 4)
+	assert.Len(t, allAuths, 6)
 
 	// Verify roles
 	allRoles := loader.AllRoles()
@@ -458,6 +458,67 @@ auth basic_auth {
 	assert.Nil(t, auth.Config)
 }
 
+// TestDSLLoaderAuthSettingsEnv tests that env() calls in auth config blocks
+// are resolved when the program is loaded.
+func TestDSLLoaderAuthSettingsEnv(t *testing.T) {
+	t.Setenv("CODEAI_TEST_CLIENT_SECRET", "s3cret")
+
+	input := `
+auth idp {
+	method oauth2
+	config {
+		introspection_url: "https://idp.example.com/introspect"
+		client_secret: env("CODEAI_TEST_CLIENT_SECRET")
+	}
+}
+`
+
+	prog, err := parser.Parse(input)
+	require.NoError(t, err)
+
+	loader := NewDSLLoader()
+	require.NoError(t, loader.LoadProgram(prog))
+
+	auth, ok := loader.GetAuth("idp")
+	require.True(t, ok)
+	assert.Equal(t, "https://idp.example.com/introspect", auth.Settings["introspection_url"])
+	assert.Equal(t, "s3cret", auth.Settings["client_secret"])
+}
+
+func TestDSLLoaderAuthIssuerSettings(t *testing.T) {
+	t.Parallel()
+
+	input := `
+auth app_tokens {
+	method jwt
+	issuer {
+		model: "User"
+		access_ttl: "10m"
+		storage: memory
+	}
+}
+
+auth idp {
+	method jwt
+	jwks_url "https://idp.example.com/.well-known/jwks.json"
+}
+`
+
+	prog, err := parser.Parse(input)
+	require.NoError(t, err)
+
+	loader := NewDSLLoader()
+	require.NoError(t, loader.LoadProgram(prog))
+
+	auth, ok := loader.GetAuth("app_tokens")
+	require.True(t, ok)
+	assert.Equal(t, map[string]any{"model": "User", "access_ttl": "10m", "storage": "memory"}, auth.IssuerSettings)
+
+	auth, ok = loader.GetAuth("idp")
+	require.True(t, ok)
+	assert.Nil(t, auth.IssuerSettings)
+}
+
 // TestLoadAuthConfig tests loading auth configuration directly.
 func TestLoadAuthConfig(t *testing.T) {
 	t.Parallel()
@@ -11,6 +11,9 @@ var (
 	// ErrExpiredToken indicates the token has expired.
 	ErrExpiredToken = errors.New("token has expired")
 
+	// ErrRevokedToken indicates the token, or the session it belongs to, has been revoked.
+	ErrRevokedToken = errors.New("token has been revoked")
+
 	// ErrInvalidIssuer indicates the token issuer doesn't match the expected value.
 	ErrInvalidIssuer = errors.New("invalid token issuer")
 
@@ -26,10 +29,13 @@ var (
 	// ErrUnsupportedAlgorithm indicates the token uses an unsupported signing algorithm.
 	ErrUnsupportedAlgorithm = errors.New("unsupported signing algorithm")
 
+	// ErrAlgorithmMismatch indicates the token's algorithm is not one the signing key may be used with.
+	ErrAlgorithmMismatch = errors.New("signing algorithm not allowed for key")
+
 	// ErrNoSecretConfigured indicates HS256 was requested but no secret is configured.
 	ErrNoSecretConfigured = errors.New("no secret configured for symmetric algorithm")
 
-	// ErrNoPublicKeyConfigured indicates RS256 was requested but no public key is available.
+	// ErrNoPublicKeyConfigured indicates an asymmetric algorithm was requested but no public key is available.
 	ErrNoPublicKeyConfigured = errors.New("no public key configured for asymmetric algorithm")
 
 	// ErrJWKSFetchFailed indicates failure to fetch keys from the JWKS endpoint.
@@ -37,4 +43,13 @@ var (
 
 	// ErrJWKSDecodeFailed indicates failure to decode the JWKS response.
 	ErrJWKSDecodeFailed = errors.New("failed to decode JWKS")
+
+	// ErrInvalidAPIKey indicates the API key is unknown or has been revoked.
+	ErrInvalidAPIKey = errors.New("invalid API key")
+
+	// ErrExpiredAPIKey indicates the API key has expired.
+	ErrExpiredAPIKey = errors.New("API key has expired")
+
+	// ErrInvalidCredentials indicates the username or password is wrong.
+	ErrInvalidCredentials = errors.New("invalid credentials")
 )
@@ -14,11 +14,13 @@ func TestErrorsAreSentinelValues(t *testing.T) {
 	}{
 		{"ErrInvalidToken", ErrInvalidToken},
 		{"ErrExpiredToken", ErrExpiredToken},
+		{"ErrRevokedToken", ErrRevokedToken},
 		{"ErrInvalidIssuer", ErrInvalidIssuer},
 		{"ErrInvalidAudience", ErrInvalidAudience},
 		{"ErrMissingToken", ErrMissingToken},
 		{"ErrKeyNotFound", ErrKeyNotFound},
 		{"ErrUnsupportedAlgorithm", ErrUnsupportedAlgorithm},
+		{"ErrAlgorithmMismatch", ErrAlgorithmMismatch},
 		{"ErrNoSecretConfigured", ErrNoSecretConfigured},
 		{"ErrNoPublicKeyConfigured", ErrNoPublicKeyConfigured},
 		{"ErrJWKSFetchFailed", ErrJWKSFetchFailed},
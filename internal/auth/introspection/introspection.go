@@ -0,0 +1,346 @@
+// Package introspection implements OAuth2 bearer token authentication for
+// CodeAI using token introspection (RFC 7662).
+//
+// Opaque access tokens are sent to the authorization server's introspection
+// endpoint; active tokens become an auth.User with roles and permissions
+// taken from the response. Responses are cached, keyed by a hash of the
+// token, for at most the cache TTL and never beyond the token's expiry.
+package introspection
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/bargom/codeai/internal/auth"
+	"github.com/bargom/codeai/internal/cache"
+)
+
+// DefaultCacheTTL is how long introspection responses are cached by default.
+const DefaultCacheTTL = time.Minute
+
+// cacheKeyPrefix prefixes the cache keys of introspection responses.
+const cacheKeyPrefix = "auth:introspection:"
+
+// Config configures an introspection Provider.
+type Config struct {
+	// URL is the introspection endpoint.
+	URL string
+
+	// ClientID and ClientSecret authenticate the provider to the
+	// introspection endpoint with HTTP Basic authentication.
+	ClientID     string
+	ClientSecret string
+
+	// CacheTTL bounds how long responses are cached (default one minute);
+	// a negative value disables caching.
+	CacheTTL time.Duration
+
+	// Issuer and Audience, when set, must match the iss and aud members of
+	// the response.
+	Issuer   string
+	Audience string
+
+	// RolesClaim is the response member holding roles (default "roles").
+	RolesClaim string
+
+	// PermissionsClaim is the response member holding permissions
+	// (default "scope").
+	PermissionsClaim string
+}
+
+// ConfigFromSettings builds a Config from the settings of an
+// "auth ... { method oauth2 config { ... } }" declaration:
+// introspection_url, client_id, client_secret, cache_ttl, issuer,
+// audience, roles_claim and permissions_claim.
+func ConfigFromSettings(settings map[string]any) (Config, error) {
+	var cfg Config
+	var cacheTTL string
+	targets := []struct {
+		key string
+		dst *string
+	}{
+		{"introspection_url", &cfg.URL},
+		{"client_id", &cfg.ClientID},
+		{"client_secret", &cfg.ClientSecret},
+		{"cache_ttl", &cacheTTL},
+		{"issuer", &cfg.Issuer},
+		{"audience", &cfg.Audience},
+		{"roles_claim", &cfg.RolesClaim},
+		{"permissions_claim", &cfg.PermissionsClaim},
+	}
+	for _, t := range targets {
+		value, ok := settings[t.key]
+		if !ok {
+			continue
+		}
+		s, ok := value.(string)
+		if !ok {
+			return Config{}, fmt.Errorf("%s must be a string", t.key)
+		}
+		*t.dst = s
+	}
+
+	if cfg.URL == "" {
+		return Config{}, errors.New("introspection_url is required")
+	}
+	if cacheTTL != "" {
+		ttl, err := time.ParseDuration(cacheTTL)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid cache_ttl %q: %w", cacheTTL, err)
+		}
+		cfg.CacheTTL = ttl
+	}
+	return cfg, nil
+}
+
+// Provider authenticates bearer tokens by introspecting them.
+// It implements auth.Authenticator.
+type Provider struct {
+	config Config
+	client auth.HTTPClient
+	cache  cache.Cache
+	logger *slog.Logger
+	now    func() time.Time
+}
+
+// Option configures a Provider.
+type Option func(*Provider)
+
+// WithHTTPClient sets the client used to call the introspection endpoint.
+func WithHTTPClient(client auth.HTTPClient) Option {
+	return func(p *Provider) {
+		p.client = client
+	}
+}
+
+// WithCache sets the cache for introspection responses, e.g. a Redis cache
+// shared between instances. The default is an in-memory cache.
+func WithCache(c cache.Cache) Option {
+	return func(p *Provider) {
+		p.cache = c
+	}
+}
+
+// WithLogger sets the logger used to report introspection failures.
+func WithLogger(logger *slog.Logger) Option {
+	return func(p *Provider) {
+		p.logger = logger
+	}
+}
+
+// NewProvider creates an introspection provider.
+func NewProvider(config Config, opts ...Option) *Provider {
+	if config.CacheTTL == 0 {
+		config.CacheTTL = DefaultCacheTTL
+	}
+	if config.RolesClaim == "" {
+		config.RolesClaim = "roles"
+	}
+	if config.PermissionsClaim == "" {
+		config.PermissionsClaim = "scope"
+	}
+
+	p := &Provider{
+		config: config,
+		logger: slog.Default(),
+		now:    time.Now,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if p.client == nil {
+		p.client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if p.cache == nil && config.CacheTTL > 0 {
+		p.cache = cache.NewMemoryCache(cache.Config{DefaultTTL: config.CacheTTL})
+	}
+	p.logger = p.logger.With("component", "oauth2-introspection")
+	return p
+}
+
+// Authenticate introspects the request's bearer token and returns the user
+// it was issued to.
+func (p *Provider) Authenticate(r *http.Request) (*auth.User, error) {
+	token := auth.ExtractToken(r)
+	if token == "" {
+		return nil, auth.ErrMissingToken
+	}
+
+	claims, err := p.introspect(r.Context(), token)
+	if err != nil {
+		return nil, err
+	}
+	return p.userFromClaims(token, claims)
+}
+
+// introspect returns the introspection response for a token, from the
+// cache if possible.
+func (p *Provider) introspect(ctx context.Context, token string) (map[string]any, error) {
+	key := cacheKeyPrefix + hashToken(token)
+	if p.cache != nil {
+		var claims map[string]any
+		if err := p.cache.GetJSON(ctx, key, &claims); err == nil {
+			return claims, nil
+		} else if !errors.Is(err, cache.ErrCacheMiss) {
+			p.logger.Warn("introspection cache lookup failed", "error", err)
+		}
+	}
+
+	claims, err := p.request(ctx, token)
+	if err != nil {
+		p.logger.Warn("token introspection failed", "error", err)
+		return nil, err
+	}
+
+	if ttl := p.cacheTTL(claims); ttl > 0 && p.cache != nil {
+		if err := p.cache.SetJSON(ctx, key, claims, ttl); err != nil {
+			p.logger.Warn("introspection cache write failed", "error", err)
+		}
+	}
+	return claims, nil
+}
+
+// request calls the introspection endpoint.
+func (p *Provider) request(ctx context.Context, token string) (map[string]any, error) {
+	form := url.Values{"token": {token}, "token_type_hint": {"access_token"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.config.URL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("creating introspection request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.config.ClientID != "" {
+		req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("introspection request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("introspection endpoint returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var claims map[string]any
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&claims); err != nil {
+		return nil, fmt.Errorf("decoding introspection response: %w", err)
+	}
+	if _, ok := claims["active"].(bool); !ok {
+		return nil, errors.New("introspection response has no active member")
+	}
+	return claims, nil
+}
+
+// cacheTTL returns how long a response may be cached: the configured TTL,
+// cut short by the token's expiry.
+func (p *Provider) cacheTTL(claims map[string]any) time.Duration {
+	ttl := p.config.CacheTTL
+	if exp, ok := numericDate(claims, "exp"); ok {
+		if remaining := exp.Sub(p.now()); remaining < ttl {
+			ttl = remaining
+		}
+	}
+	return ttl
+}
+
+// userFromClaims checks an introspection response and converts it to a user.
+func (p *Provider) userFromClaims(token string, claims map[string]any) (*auth.User, error) {
+	if active, _ := claims["active"].(bool); !active {
+		return nil, auth.ErrInvalidToken
+	}
+
+	now := p.now()
+	exp, hasExp := numericDate(claims, "exp")
+	if hasExp && !now.Before(exp) {
+		return nil, auth.ErrExpiredToken
+	}
+	if nbf, ok := numericDate(claims, "nbf"); ok && now.Before(nbf) {
+		return nil, auth.ErrInvalidToken
+	}
+	if p.config.Issuer != "" && stringClaim(claims, "iss") != p.config.Issuer {
+		return nil, auth.ErrInvalidIssuer
+	}
+	if p.config.Audience != "" && !containsString(listClaim(claims, "aud"), p.config.Audience) {
+		return nil, auth.ErrInvalidAudience
+	}
+
+	user := &auth.User{
+		ID:          stringClaim(claims, "sub"),
+		Email:       stringClaim(claims, "email"),
+		Name:        stringClaim(claims, "username"),
+		Roles:       listClaim(claims, p.config.RolesClaim),
+		Permissions: listClaim(claims, p.config.PermissionsClaim),
+		Claims:      claims,
+		Token:       token,
+	}
+	if user.ID == "" {
+		// Tokens from the client credentials grant have no subject
+		user.ID = stringClaim(claims, "client_id")
+	}
+	if hasExp {
+		user.ExpiresAt = exp
+	}
+	return user, nil
+}
+
+// hashToken returns the cache key part for a token, so tokens are not
+// stored in the cache in plaintext.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func stringClaim(claims map[string]any, key string) string {
+	s, _ := claims[key].(string)
+	return s
+}
+
+// listClaim reads a list member given as a JSON array or a space-separated
+// string, like scope.
+func listClaim(claims map[string]any, key string) []string {
+	switch v := claims[key].(type) {
+	case string:
+		return strings.Fields(v)
+	case []any:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				items = append(items, s)
+			}
+		}
+		return items
+	default:
+		return nil
+	}
+}
+
+// numericDate reads a time given in seconds since the epoch.
+func numericDate(claims map[string]any, key string) (time.Time, bool) {
+	v, ok := claims[key].(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(v), 0), true
+}
+
+func containsString(items []string, s string) bool {
+	for _, item := range items {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,182 @@
+package introspection
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bargom/codeai/internal/auth"
+	"github.com/bargom/codeai/internal/cache"
+)
+
+var testNow = time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+
+// newIntrospectionServer returns a server answering with the response for
+// each token, and a counter of the requests it received.
+func newIntrospectionServer(t *testing.T, responses map[string]map[string]any) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		id, secret, ok := r.BasicAuth()
+		if !ok || id != "api" || secret != "s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "access_token", r.PostFormValue("token_type_hint"))
+
+		resp, ok := responses[r.PostFormValue("token")]
+		if !ok {
+			resp = map[string]any{"active": false}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func newTestProvider(t *testing.T, cfg Config, c cache.Cache) *Provider {
+	t.Helper()
+	cfg.ClientID, cfg.ClientSecret = "api", "s3cret"
+	p := NewProvider(cfg, WithCache(c))
+	p.now = func() time.Time { return testNow }
+	return p
+}
+
+func authenticate(p *Provider, token string) (*auth.User, error) {
+	req := httptest.NewRequest("GET", "/", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return p.Authenticate(req)
+}
+
+func TestProvider_Authenticate(t *testing.T) {
+	srv, _ := newIntrospectionServer(t, map[string]map[string]any{
+		"good": {
+			"active":   true,
+			"sub":      "user-1",
+			"username": "alice",
+			"scope":    "posts:read posts:write",
+			"roles":    []string{"editor"},
+			"exp":      testNow.Add(time.Hour).Unix(),
+		},
+		"service": {
+			"active":    true,
+			"client_id": "billing",
+			"scope":     "invoices:read",
+		},
+		"expired": {"active": true, "sub": "user-2", "exp": testNow.Add(-time.Minute).Unix()},
+	})
+	p := newTestProvider(t, Config{URL: srv.URL}, nil)
+
+	user, err := authenticate(p, "good")
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", user.ID)
+	assert.Equal(t, "alice", user.Name)
+	assert.Equal(t, []string{"editor"}, user.Roles)
+	assert.Equal(t, []string{"posts:read", "posts:write"}, user.Permissions)
+	assert.Equal(t, testNow.Add(time.Hour).Unix(), user.ExpiresAt.Unix())
+	assert.Equal(t, "good", user.Token)
+
+	user, err = authenticate(p, "service")
+	require.NoError(t, err)
+	assert.Equal(t, "billing", user.ID, "client credentials tokens use the client ID")
+
+	_, err = authenticate(p, "")
+	assert.ErrorIs(t, err, auth.ErrMissingToken)
+	_, err = authenticate(p, "unknown")
+	assert.ErrorIs(t, err, auth.ErrInvalidToken)
+	_, err = authenticate(p, "expired")
+	assert.ErrorIs(t, err, auth.ErrExpiredToken)
+}
+
+func TestProvider_IssuerAndAudience(t *testing.T) {
+	srv, _ := newIntrospectionServer(t, map[string]map[string]any{
+		"token": {"active": true, "sub": "u", "iss": "https://auth.example.com", "aud": []string{"api", "admin"}},
+	})
+
+	p := newTestProvider(t, Config{URL: srv.URL, Issuer: "https://auth.example.com", Audience: "api"}, nil)
+	_, err := authenticate(p, "token")
+	assert.NoError(t, err)
+
+	p = newTestProvider(t, Config{URL: srv.URL, Issuer: "https://other.example.com"}, nil)
+	_, err = authenticate(p, "token")
+	assert.ErrorIs(t, err, auth.ErrInvalidIssuer)
+
+	p = newTestProvider(t, Config{URL: srv.URL, Audience: "billing"}, nil)
+	_, err = authenticate(p, "token")
+	assert.ErrorIs(t, err, auth.ErrInvalidAudience)
+}
+
+func TestProvider_Caching(t *testing.T) {
+	srv, calls := newIntrospectionServer(t, map[string]map[string]any{
+		"long":  {"active": true, "sub": "u1", "exp": testNow.Add(time.Hour).Unix()},
+		"short": {"active": true, "sub": "u2", "exp": testNow.Add(10 * time.Second).Unix()},
+	})
+	c := cache.NewMemoryCache(cache.Config{})
+	defer c.Close()
+	p := newTestProvider(t, Config{URL: srv.URL, CacheTTL: 5 * time.Minute}, c)
+
+	for i := 0; i < 3; i++ {
+		_, err := authenticate(p, "long")
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int32(1), calls.Load(), "responses are cached")
+
+	keys, err := c.Keys(t.Context(), cacheKeyPrefix+"*")
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.NotContains(t, keys[0], "long", "tokens are hashed in cache keys")
+
+	_, err = authenticate(p, "unknown")
+	assert.ErrorIs(t, err, auth.ErrInvalidToken)
+	_, err = authenticate(p, "unknown")
+	assert.ErrorIs(t, err, auth.ErrInvalidToken)
+	assert.Equal(t, int32(2), calls.Load(), "inactive responses are cached")
+
+	claims := map[string]any{"active": true, "exp": float64(testNow.Add(10 * time.Second).Unix())}
+	assert.Equal(t, 10*time.Second, p.cacheTTL(claims), "cache TTL is bounded by the token expiry")
+}
+
+func TestProvider_EndpointFailure(t *testing.T) {
+	srv, _ := newIntrospectionServer(t, nil)
+	p := NewProvider(Config{URL: srv.URL, ClientID: "api", ClientSecret: "wrong"})
+
+	_, err := authenticate(p, "token")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "introspection endpoint returned 401")
+
+	// Failures are not cached
+	p.config.ClientSecret = "s3cret"
+	_, err = authenticate(p, "token")
+	assert.ErrorIs(t, err, auth.ErrInvalidToken)
+}
+
+func TestConfigFromSettings(t *testing.T) {
+	cfg, err := ConfigFromSettings(map[string]any{
+		"introspection_url": "https://auth.example.com/introspect",
+		"client_id":         "api",
+		"client_secret":     "s3cret",
+		"cache_ttl":         "30s",
+		"roles_claim":       "groups",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "https://auth.example.com/introspect", cfg.URL)
+	assert.Equal(t, "api", cfg.ClientID)
+	assert.Equal(t, 30*time.Second, cfg.CacheTTL)
+	assert.Equal(t, "groups", cfg.RolesClaim)
+
+	_, err = ConfigFromSettings(map[string]any{})
+	assert.ErrorContains(t, err, "introspection_url is required")
+	_, err = ConfigFromSettings(map[string]any{"introspection_url": "https://x", "cache_ttl": "later"})
+	assert.ErrorContains(t, err, "invalid cache_ttl")
+}
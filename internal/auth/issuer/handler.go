@@ -0,0 +1,147 @@
+package issuer
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/bargom/codeai/internal/auth"
+)
+
+// maxRequestBody limits the size of login, refresh and logout requests.
+const maxRequestBody = 64 << 10
+
+// Handler serves the issuer's HTTP endpoints.
+type Handler struct {
+	issuer *Issuer
+	logger *slog.Logger
+}
+
+// NewHandler creates the HTTP handler of an issuer.
+func NewHandler(issuer *Issuer) *Handler {
+	return &Handler{
+		issuer: issuer,
+		logger: issuer.logger,
+	}
+}
+
+// RegisterRoutes registers the login, refresh, logout and JWKS routes.
+// Routes are registered individually rather than mounted under /auth, so
+// DSL endpoints may share the prefix.
+func (h *Handler) RegisterRoutes(r chi.Router) {
+	r.Post("/auth/login", h.Login)
+	r.Post("/auth/refresh", h.Refresh)
+	r.Post("/auth/logout", h.Logout)
+	r.Get("/.well-known/jwks.json", h.JWKS)
+}
+
+// loginRequest is the body of POST /auth/login.
+type loginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// refreshRequest is the body of POST /auth/refresh and POST /auth/logout.
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Login handles POST /auth/login.
+func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
+	var req loginRequest
+	if err := decodeRequest(w, r, &req); err != nil || req.Username == "" || req.Password == "" {
+		writeError(w, http.StatusBadRequest, "username and password are required")
+		return
+	}
+
+	tokens, err := h.issuer.Login(r.Context(), req.Username, req.Password)
+	if errors.Is(err, auth.ErrInvalidCredentials) {
+		writeError(w, http.StatusUnauthorized, "invalid username or password")
+		return
+	}
+	if err != nil {
+		h.logger.Error("login failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	writeTokens(w, tokens)
+}
+
+// Refresh handles POST /auth/refresh.
+func (h *Handler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req refreshRequest
+	if err := decodeRequest(w, r, &req); err != nil || req.RefreshToken == "" {
+		writeError(w, http.StatusBadRequest, "refresh_token is required")
+		return
+	}
+
+	tokens, err := h.issuer.Refresh(r.Context(), req.RefreshToken)
+	if errors.Is(err, ErrInvalidRefreshToken) {
+		writeError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+	if err != nil {
+		h.logger.Error("token refresh failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	writeTokens(w, tokens)
+}
+
+// Logout handles POST /auth/logout.
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
+	var req refreshRequest
+	if err := decodeRequest(w, r, &req); err != nil || req.RefreshToken == "" {
+		writeError(w, http.StatusBadRequest, "refresh_token is required")
+		return
+	}
+
+	err := h.issuer.Logout(r.Context(), req.RefreshToken)
+	if errors.Is(err, ErrInvalidRefreshToken) {
+		writeError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+	if err != nil {
+		h.logger.Error("logout failed", "error", err)
+		writeError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// JWKS handles GET /.well-known/jwks.json.
+func (h *Handler) JWKS(w http.ResponseWriter, r *http.Request) {
+	set, err := h.issuer.keys.JWKS(r.Context())
+	if err != nil {
+		h.logger.Error("cannot build JWKS", "error", err)
+		writeError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	writeJSON(w, http.StatusOK, set)
+}
+
+func decodeRequest(w http.ResponseWriter, r *http.Request, v any) error {
+	return json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody)).Decode(v)
+}
+
+// writeTokens writes issued tokens; token responses must not be cached.
+func writeTokens(w http.ResponseWriter, tokens *Tokens) {
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, tokens)
+}
+
+func writeJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{
+		"error": message,
+	})
+}
@@ -0,0 +1,100 @@
+package issuer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bargom/codeai/internal/auth"
+)
+
+func newTestServer(t *testing.T) (*httptest.Server, *Issuer) {
+	t.Helper()
+	iss, _ := newTestIssuer(t, NewMemoryStore(), Config{Issuer: "codeai"})
+	r := chi.NewRouter()
+	NewHandler(iss).RegisterRoutes(r)
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+	return server, iss
+}
+
+func post(t *testing.T, url, body string) *http.Response {
+	t.Helper()
+	resp, err := http.Post(url, "application/json", strings.NewReader(body))
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func decodeTokens(t *testing.T, resp *http.Response) Tokens {
+	t.Helper()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "no-store", resp.Header.Get("Cache-Control"))
+	var tokens Tokens
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&tokens))
+	return tokens
+}
+
+func TestHandler_LoginRefreshLogout(t *testing.T) {
+	server, iss := newTestServer(t)
+
+	resp := post(t, server.URL+"/auth/login", `{"username":"alice","password":"wrong"}`)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	resp = post(t, server.URL+"/auth/login", `{"username":"alice"}`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp = post(t, server.URL+"/auth/login", `not json`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	tokens := decodeTokens(t, post(t, server.URL+"/auth/login", `{"username":"alice","password":"s3cret"}`))
+	assert.Equal(t, "Bearer", tokens.TokenType)
+
+	refreshed := decodeTokens(t, post(t, server.URL+"/auth/refresh", `{"refresh_token":"`+tokens.RefreshToken+`"}`))
+	assert.NotEqual(t, tokens.RefreshToken, refreshed.RefreshToken)
+
+	resp = post(t, server.URL+"/auth/refresh", `{"refresh_token":"unknown"}`)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	resp = post(t, server.URL+"/auth/refresh", `{}`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp = post(t, server.URL+"/auth/logout", `{"refresh_token":"`+refreshed.RefreshToken+`"}`)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	v, err := iss.Validator()
+	require.NoError(t, err)
+	_, err = v.ValidateToken(context.Background(), refreshed.AccessToken)
+	assert.ErrorIs(t, err, auth.ErrRevokedToken)
+}
+
+func TestHandler_JWKS(t *testing.T) {
+	server, iss := newTestServer(t)
+
+	resp, err := http.Get(server.URL + "/.well-known/jwks.json")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	assert.Contains(t, resp.Header.Get("Cache-Control"), "max-age")
+
+	var set auth.JWKS
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&set))
+	require.Len(t, set.Keys, 1)
+	assert.Equal(t, "sig", set.Keys[0].Use)
+	assert.Equal(t, "P-256", set.Keys[0].Crv)
+
+	// Tokens verify against the published keys, as an external service
+	// using a JWKS URL would check them
+	tokens := decodeTokens(t, post(t, server.URL+"/auth/login", `{"username":"alice","password":"s3cret"}`))
+	v, err := auth.NewValidator(auth.Config{Issuer: "codeai", JWKSURL: server.URL + "/.well-known/jwks.json"})
+	require.NoError(t, err)
+	user, err := v.ValidateToken(context.Background(), tokens.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", user.ID)
+	assert.Equal(t, set.Keys[0].Kid, iss.keys.keys[0].ID)
+}
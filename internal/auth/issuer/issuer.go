@@ -0,0 +1,373 @@
+// Package issuer implements CodeAI's built-in token issuer.
+//
+// An "auth ... { method jwt issuer { ... } }" declaration makes CodeAI mint
+// its own JWTs instead of only validating tokens from an external identity
+// provider. Users log in with a username and password checked against a
+// user model or credentials file (see package basic) and receive a
+// short-lived access token and a refresh token. Access tokens are signed
+// with rotating keys published as a JSON Web Key Set; refresh tokens are
+// opaque, single-use and stored only as hashes. Logging out revokes the
+// session, and the revocation list is consulted by auth.Validator so
+// revoked access tokens are rejected before they expire.
+package issuer
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+
+	"github.com/bargom/codeai/internal/auth"
+	"github.com/bargom/codeai/internal/auth/basic"
+)
+
+// Defaults for Config.
+const (
+	DefaultAlgorithm   = "ES256"
+	DefaultAccessTTL   = 15 * time.Minute
+	DefaultRefreshTTL  = 30 * 24 * time.Hour
+	DefaultKeyRotation = 24 * time.Hour
+)
+
+// clockSkew is added to the access token lifetime when deciding how long
+// retired keys and revocations must be kept.
+const clockSkew = time.Minute
+
+// ErrInvalidRefreshToken indicates the refresh token is unknown, expired or
+// revoked.
+var ErrInvalidRefreshToken = errors.New("invalid refresh token")
+
+// Config configures an Issuer.
+type Config struct {
+	Issuer      string        // iss claim of issued tokens
+	Audience    string        // aud claim of issued tokens
+	Algorithm   string        // Signing algorithm (default ES256)
+	AccessTTL   time.Duration // Access token lifetime (default 15m)
+	RefreshTTL  time.Duration // Refresh token lifetime (default 30 days)
+	KeyRotation time.Duration // Signing key lifetime (default 24h)
+
+	// Credentials configures where users and password hashes come from.
+	Credentials basic.Config
+}
+
+// ConfigFromSettings builds a Config from the settings of an
+// "auth ... { method jwt issuer { ... } }" block: issuer, audience,
+// algorithm, access_ttl, refresh_ttl and key_rotation, plus the credential
+// settings of basic.ConfigFromSettings.
+func ConfigFromSettings(settings map[string]any) (Config, error) {
+	credentials, err := basic.ConfigFromSettings(settings)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg := Config{Credentials: credentials}
+
+	texts := []struct {
+		key string
+		dst *string
+	}{
+		{"issuer", &cfg.Issuer},
+		{"audience", &cfg.Audience},
+		{"algorithm", &cfg.Algorithm},
+	}
+	for _, t := range texts {
+		if value, ok := settings[t.key]; ok {
+			s, ok := value.(string)
+			if !ok {
+				return Config{}, fmt.Errorf("%s must be a string", t.key)
+			}
+			*t.dst = s
+		}
+	}
+
+	durations := []struct {
+		key string
+		dst *time.Duration
+	}{
+		{"access_ttl", &cfg.AccessTTL},
+		{"refresh_ttl", &cfg.RefreshTTL},
+		{"key_rotation", &cfg.KeyRotation},
+	}
+	for _, t := range durations {
+		if value, ok := settings[t.key]; ok {
+			s, ok := value.(string)
+			if !ok {
+				return Config{}, fmt.Errorf("%s must be a duration string", t.key)
+			}
+			d, err := time.ParseDuration(s)
+			if err != nil {
+				return Config{}, fmt.Errorf("%s: %w", t.key, err)
+			}
+			if d <= 0 {
+				return Config{}, fmt.Errorf("%s must be positive", t.key)
+			}
+			*t.dst = d
+		}
+	}
+	return cfg, nil
+}
+
+// Tokens is the response of a successful login or refresh.
+type Tokens struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Issuer issues, refreshes and revokes tokens for users of a credential
+// store. It implements auth.RevocationList.
+type Issuer struct {
+	config      Config
+	credentials basic.CredentialStore
+	users       *basic.Provider
+	store       Store
+	keys        *KeySet
+	logger      *slog.Logger
+	now         func() time.Time
+}
+
+// Option configures an Issuer.
+type Option func(*Issuer)
+
+// WithLogger sets the logger.
+func WithLogger(logger *slog.Logger) Option {
+	return func(i *Issuer) {
+		i.logger = logger
+	}
+}
+
+// NewIssuer creates an issuer authenticating users against credentials and
+// keeping refresh tokens, revocations and signing keys in store.
+func NewIssuer(credentials basic.CredentialStore, store Store, config Config, opts ...Option) (*Issuer, error) {
+	if config.Algorithm == "" {
+		config.Algorithm = DefaultAlgorithm
+	}
+	if config.AccessTTL == 0 {
+		config.AccessTTL = DefaultAccessTTL
+	}
+	if config.RefreshTTL == 0 {
+		config.RefreshTTL = DefaultRefreshTTL
+	}
+	if config.KeyRotation == 0 {
+		config.KeyRotation = DefaultKeyRotation
+	}
+	if !supportedAlgorithm(config.Algorithm) {
+		return nil, fmt.Errorf("unsupported signing algorithm %q", config.Algorithm)
+	}
+
+	i := &Issuer{
+		config:      config,
+		credentials: credentials,
+		store:       store,
+		logger:      slog.Default(),
+		now:         time.Now,
+	}
+	for _, opt := range opts {
+		opt(i)
+	}
+	i.logger = i.logger.With("component", "token-issuer")
+	i.users = basic.NewProvider(credentials, config.Credentials, basic.WithLogger(i.logger))
+	i.keys = NewKeySet(store, config.Algorithm, config.KeyRotation, config.AccessTTL+clockSkew, i.logger)
+	return i, nil
+}
+
+// Keys returns the issuer's signing key set.
+func (i *Issuer) Keys() *KeySet {
+	return i.keys
+}
+
+// Validator returns a JWT validator for the tokens this issuer signs.
+// It checks issuer and audience, looks up keys in the key set and rejects
+// revoked tokens.
+func (i *Issuer) Validator() (*auth.Validator, error) {
+	v, err := auth.NewValidatorWithLogger(auth.Config{
+		Issuer:     i.config.Issuer,
+		Audience:   i.config.Audience,
+		RolesClaim: "roles",
+		PermsClaim: "permissions",
+	}, i.logger)
+	if err != nil {
+		return nil, err
+	}
+	v.SetKeySource(i.keys)
+	v.SetRevocationList(i)
+	return v, nil
+}
+
+// Login checks the username and password and starts a new session.
+// It returns auth.ErrInvalidCredentials if they don't match.
+func (i *Issuer) Login(ctx context.Context, username, password string) (*Tokens, error) {
+	user, err := i.users.Verify(ctx, username, password)
+	if err != nil {
+		return nil, err
+	}
+	return i.issue(ctx, user, username, uuid.New().String())
+}
+
+// Refresh exchanges a refresh token for new tokens. Each refresh token can
+// be used once; presenting one that was already used revokes the whole
+// session, since it has likely been stolen.
+func (i *Issuer) Refresh(ctx context.Context, refreshToken string) (*Tokens, error) {
+	now := i.now()
+	token, err := i.store.GetRefreshToken(ctx, hashToken(refreshToken))
+	if errors.Is(err, ErrTokenNotFound) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !now.Before(token.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	err = i.store.RevokeRefreshToken(ctx, token.ID, now)
+	switch {
+	case errors.Is(err, ErrTokenNotFound):
+		return nil, ErrInvalidRefreshToken
+	case errors.Is(err, ErrTokenRevoked):
+		if !i.revokedSession(ctx, token.SessionID) {
+			i.logger.Warn("refresh token reused, revoking session",
+				"session_id", token.SessionID, "username", token.Username)
+			if err := i.revokeSession(ctx, token.SessionID, now); err != nil {
+				return nil, err
+			}
+		}
+		return nil, ErrInvalidRefreshToken
+	case err != nil:
+		return nil, err
+	}
+
+	// Load the user again so role changes apply and deleted users are
+	// locked out
+	cred, err := i.credentials.Lookup(ctx, token.Username)
+	if errors.Is(err, basic.ErrUserNotFound) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	return i.issue(ctx, cred.User(), token.Username, token.SessionID)
+}
+
+// Logout ends the session of the refresh token. Access tokens of the
+// session are rejected from then on.
+func (i *Issuer) Logout(ctx context.Context, refreshToken string) error {
+	token, err := i.store.GetRefreshToken(ctx, hashToken(refreshToken))
+	if errors.Is(err, ErrTokenNotFound) {
+		return ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return err
+	}
+	return i.revokeSession(ctx, token.SessionID, i.now())
+}
+
+// IsRevoked reports whether the access token or its session was revoked.
+func (i *Issuer) IsRevoked(ctx context.Context, tokenID, sessionID string) (bool, error) {
+	var ids []string
+	for _, id := range []string{tokenID, sessionID} {
+		if id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return i.store.IsRevoked(ctx, ids...)
+}
+
+// revokeSession revokes the session's refresh tokens and puts the session
+// on the revocation list for as long as its access tokens are valid.
+func (i *Issuer) revokeSession(ctx context.Context, sessionID string, now time.Time) error {
+	if err := i.store.RevokeSession(ctx, sessionID, now); err != nil {
+		return err
+	}
+	return i.store.AddRevocation(ctx, sessionID, now.Add(i.config.AccessTTL+clockSkew))
+}
+
+// revokedSession reports whether the session is on the revocation list.
+func (i *Issuer) revokedSession(ctx context.Context, sessionID string) bool {
+	revoked, err := i.store.IsRevoked(ctx, sessionID)
+	return err == nil && revoked
+}
+
+// issue signs an access token for the user and stores a new refresh token
+// in the session.
+func (i *Issuer) issue(ctx context.Context, user *auth.User, username, sessionID string) (*Tokens, error) {
+	key, err := i.keys.signingKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := i.now()
+	claims := jwt.MapClaims{
+		"sub":  user.ID,
+		"name": username,
+		"iat":  now.Unix(),
+		"exp":  now.Add(i.config.AccessTTL).Unix(),
+		"jti":  uuid.New().String(),
+		"sid":  sessionID,
+	}
+	if i.config.Issuer != "" {
+		claims["iss"] = i.config.Issuer
+	}
+	if i.config.Audience != "" {
+		claims["aud"] = i.config.Audience
+	}
+	if len(user.Roles) > 0 {
+		claims["roles"] = user.Roles
+	}
+	if len(user.Permissions) > 0 {
+		claims["permissions"] = user.Permissions
+	}
+
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.Algorithm), claims)
+	token.Header["kid"] = key.ID
+	accessToken, err := token.SignedString(key.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("signing access token: %w", err)
+	}
+
+	refreshToken, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	err = i.store.CreateRefreshToken(ctx, &RefreshToken{
+		ID:        uuid.New().String(),
+		SessionID: sessionID,
+		Hash:      hashToken(refreshToken),
+		Username:  username,
+		CreatedAt: now,
+		ExpiresAt: now.Add(i.config.RefreshTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Tokens{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(i.config.AccessTTL.Seconds()),
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+// randomToken returns a random, URL-safe refresh token value.
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generating refresh token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken returns the hash a refresh token is stored under.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
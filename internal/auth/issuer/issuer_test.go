@@ -0,0 +1,251 @@
+package issuer
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/bargom/codeai/internal/auth"
+	"github.com/bargom/codeai/internal/auth/basic"
+)
+
+// fakeClock is a settable time source.
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestIssuer(t *testing.T, store Store, cfg Config) (*Issuer, *fakeClock) {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte("s3cret"), bcrypt.MinCost)
+	require.NoError(t, err)
+	credentials, err := basic.ParseFile(strings.NewReader(
+		"alice:" + string(hash) + ":admin,editor:users:read\n"))
+	require.NoError(t, err)
+
+	iss, err := NewIssuer(credentials, store, cfg)
+	require.NoError(t, err)
+
+	clock := &fakeClock{t: time.Now()}
+	iss.now = clock.now
+	iss.keys.now = clock.now
+	return iss, clock
+}
+
+func TestIssuer_LoginAndValidate(t *testing.T) {
+	iss, _ := newTestIssuer(t, NewMemoryStore(), Config{Issuer: "codeai", Audience: "api"})
+	ctx := context.Background()
+
+	_, err := iss.Login(ctx, "alice", "wrong")
+	assert.ErrorIs(t, err, auth.ErrInvalidCredentials)
+	_, err = iss.Login(ctx, "bob", "s3cret")
+	assert.ErrorIs(t, err, auth.ErrInvalidCredentials)
+
+	tokens, err := iss.Login(ctx, "alice", "s3cret")
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer", tokens.TokenType)
+	assert.Equal(t, 900, tokens.ExpiresIn)
+	assert.NotEmpty(t, tokens.RefreshToken)
+
+	token, _, err := jwt.NewParser().ParseUnverified(tokens.AccessToken, jwt.MapClaims{})
+	require.NoError(t, err)
+	assert.Equal(t, "ES256", token.Method.Alg())
+	assert.NotEmpty(t, token.Header["kid"])
+
+	v, err := iss.Validator()
+	require.NoError(t, err)
+	user, err := v.ValidateToken(ctx, tokens.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", user.ID)
+	assert.Equal(t, "alice", user.Name)
+	assert.Equal(t, []string{"admin", "editor"}, user.Roles)
+	assert.Equal(t, []string{"users:read"}, user.Permissions)
+	assert.Equal(t, "codeai", user.Claims["iss"])
+}
+
+func TestIssuer_Refresh(t *testing.T) {
+	iss, _ := newTestIssuer(t, NewMemoryStore(), Config{})
+	ctx := context.Background()
+
+	first, err := iss.Login(ctx, "alice", "s3cret")
+	require.NoError(t, err)
+	second, err := iss.Refresh(ctx, first.RefreshToken)
+	require.NoError(t, err)
+	assert.NotEqual(t, first.RefreshToken, second.RefreshToken)
+	assert.NotEqual(t, first.AccessToken, second.AccessToken)
+
+	_, err = iss.Refresh(ctx, "unknown")
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+
+	// Reusing a rotated refresh token revokes the whole session
+	_, err = iss.Refresh(ctx, first.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	_, err = iss.Refresh(ctx, second.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+
+	v, err := iss.Validator()
+	require.NoError(t, err)
+	_, err = v.ValidateToken(ctx, second.AccessToken)
+	assert.ErrorIs(t, err, auth.ErrRevokedToken)
+}
+
+func TestIssuer_RefreshExpired(t *testing.T) {
+	iss, clock := newTestIssuer(t, NewMemoryStore(), Config{RefreshTTL: time.Hour})
+	ctx := context.Background()
+
+	tokens, err := iss.Login(ctx, "alice", "s3cret")
+	require.NoError(t, err)
+
+	clock.advance(time.Hour)
+	_, err = iss.Refresh(ctx, tokens.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+}
+
+func TestIssuer_Logout(t *testing.T) {
+	iss, _ := newTestIssuer(t, NewMemoryStore(), Config{})
+	ctx := context.Background()
+	v, err := iss.Validator()
+	require.NoError(t, err)
+
+	session, err := iss.Login(ctx, "alice", "s3cret")
+	require.NoError(t, err)
+	other, err := iss.Login(ctx, "alice", "s3cret")
+	require.NoError(t, err)
+
+	require.NoError(t, iss.Logout(ctx, session.RefreshToken))
+
+	_, err = v.ValidateToken(ctx, session.AccessToken)
+	assert.ErrorIs(t, err, auth.ErrRevokedToken)
+	_, err = iss.Refresh(ctx, session.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	assert.ErrorIs(t, iss.Logout(ctx, "unknown"), ErrInvalidRefreshToken)
+
+	// Other sessions are unaffected
+	_, err = v.ValidateToken(ctx, other.AccessToken)
+	assert.NoError(t, err)
+	_, err = iss.Refresh(ctx, other.RefreshToken)
+	assert.NoError(t, err)
+}
+
+func TestIssuer_KeyRotation(t *testing.T) {
+	store := NewMemoryStore()
+	iss, clock := newTestIssuer(t, store, Config{AccessTTL: 10 * time.Minute, KeyRotation: time.Hour})
+	ctx := context.Background()
+	v, err := iss.Validator()
+	require.NoError(t, err)
+
+	old, err := iss.Login(ctx, "alice", "s3cret")
+	require.NoError(t, err)
+
+	clock.advance(time.Hour)
+	rotated, err := iss.Login(ctx, "alice", "s3cret")
+	require.NoError(t, err)
+	assert.NotEqual(t, kid(t, old.AccessToken), kid(t, rotated.AccessToken))
+
+	keys, err := store.SigningKeys(ctx)
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.Equal(t, kid(t, rotated.AccessToken), keys[0].ID)
+
+	set, err := iss.Keys().JWKS(ctx)
+	require.NoError(t, err)
+	require.Len(t, set.Keys, 2)
+	assert.Equal(t, "EC", set.Keys[0].Kty)
+	assert.Equal(t, "ES256", set.Keys[0].Alg)
+
+	// Tokens signed with the retired key still verify
+	_, err = v.ValidateToken(ctx, old.AccessToken)
+	assert.NoError(t, err)
+	_, err = v.ValidateToken(ctx, rotated.AccessToken)
+	assert.NoError(t, err)
+
+	// The retired key is deleted at the next rotation once its tokens expired
+	clock.advance(time.Hour)
+	_, err = iss.Login(ctx, "alice", "s3cret")
+	require.NoError(t, err)
+	keys, err = store.SigningKeys(ctx)
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.NotContains(t, []string{keys[0].ID, keys[1].ID}, kid(t, old.AccessToken))
+}
+
+func TestIssuer_KeysSharedAcrossInstances(t *testing.T) {
+	store := NewMemoryStore()
+	first, _ := newTestIssuer(t, store, Config{})
+	second, _ := newTestIssuer(t, store, Config{})
+	ctx := context.Background()
+
+	tokens, err := first.Login(ctx, "alice", "s3cret")
+	require.NoError(t, err)
+
+	v, err := second.Validator()
+	require.NoError(t, err)
+	_, err = v.ValidateToken(ctx, tokens.AccessToken)
+	assert.NoError(t, err)
+
+	_, err = second.Refresh(ctx, tokens.RefreshToken)
+	assert.NoError(t, err)
+}
+
+func TestIssuer_Algorithms(t *testing.T) {
+	for _, alg := range []string{"ES384", "RS256", "PS256", "EdDSA"} {
+		t.Run(alg, func(t *testing.T) {
+			iss, _ := newTestIssuer(t, NewMemoryStore(), Config{Algorithm: alg})
+			ctx := context.Background()
+
+			tokens, err := iss.Login(ctx, "alice", "s3cret")
+			require.NoError(t, err)
+			v, err := iss.Validator()
+			require.NoError(t, err)
+			_, err = v.ValidateToken(ctx, tokens.AccessToken)
+			assert.NoError(t, err)
+		})
+	}
+
+	_, err := NewIssuer(nil, NewMemoryStore(), Config{Algorithm: "HS256"})
+	assert.ErrorContains(t, err, "unsupported signing algorithm")
+}
+
+func TestConfigFromSettings(t *testing.T) {
+	cfg, err := ConfigFromSettings(map[string]any{
+		"model":          "User",
+		"username_field": "email",
+		"issuer":         "https://api.example.com",
+		"audience":       "example",
+		"algorithm":      "EdDSA",
+		"access_ttl":     "5m",
+		"refresh_ttl":    "168h",
+		"key_rotation":   "12h",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "User", cfg.Credentials.Model)
+	assert.Equal(t, "email", cfg.Credentials.Fields.Username)
+	assert.Equal(t, "https://api.example.com", cfg.Issuer)
+	assert.Equal(t, "example", cfg.Audience)
+	assert.Equal(t, "EdDSA", cfg.Algorithm)
+	assert.Equal(t, 5*time.Minute, cfg.AccessTTL)
+	assert.Equal(t, 168*time.Hour, cfg.RefreshTTL)
+	assert.Equal(t, 12*time.Hour, cfg.KeyRotation)
+
+	_, err = ConfigFromSettings(map[string]any{"access_ttl": "soon"})
+	assert.ErrorContains(t, err, "access_ttl")
+	_, err = ConfigFromSettings(map[string]any{"refresh_ttl": "-1h"})
+	assert.ErrorContains(t, err, "must be positive")
+	_, err = ConfigFromSettings(map[string]any{"model": "User", "file": "users.htpasswd"})
+	assert.ErrorContains(t, err, "mutually exclusive")
+}
+
+func kid(t *testing.T, tokenString string) string {
+	t.Helper()
+	token, _, err := jwt.NewParser().ParseUnverified(tokenString, jwt.MapClaims{})
+	require.NoError(t, err)
+	return token.Header["kid"].(string)
+}
@@ -0,0 +1,263 @@
+package issuer
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bargom/codeai/internal/auth"
+)
+
+const (
+	// keyCacheTTL is how long signing keys are cached before they are
+	// reloaded from the store, picking up keys rotated by other instances.
+	keyCacheTTL = time.Minute
+
+	// minReloadInterval limits reloads triggered by unknown key IDs.
+	minReloadInterval = 5 * time.Second
+)
+
+// KeySet manages the rotating signing keys of an Issuer. Keys live in the
+// Store, so all instances sharing it sign with and publish the same keys.
+// Rotation happens lazily when a token is signed: once the newest key is
+// older than the rotation period, a new key is generated. Retired keys are
+// kept for the retention period so tokens they signed still verify.
+//
+// KeySet implements auth.KeySource.
+type KeySet struct {
+	store     Store
+	algorithm string
+	rotation  time.Duration
+	retention time.Duration
+	logger    *slog.Logger
+	now       func() time.Time
+
+	mu       sync.Mutex
+	keys     []*SigningKey // Newest first
+	loadedAt time.Time
+}
+
+// NewKeySet creates a key set signing with algorithm that rotates keys
+// every rotation period and keeps retired keys for retention.
+func NewKeySet(store Store, algorithm string, rotation, retention time.Duration, logger *slog.Logger) *KeySet {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &KeySet{
+		store:     store,
+		algorithm: algorithm,
+		rotation:  rotation,
+		retention: retention,
+		logger:    logger,
+		now:       time.Now,
+	}
+}
+
+// signingKey returns the key new tokens are signed with, rotating and
+// pruning keys as needed.
+func (ks *KeySet) signingKey(ctx context.Context) (*SigningKey, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	now := ks.now()
+	if now.Sub(ks.loadedAt) >= keyCacheTTL {
+		if err := ks.load(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if key := ks.current(now); key != nil {
+		return key, nil
+	}
+
+	// Another instance may have rotated since the keys were loaded
+	if err := ks.load(ctx); err != nil {
+		return nil, err
+	}
+	if key := ks.current(now); key != nil {
+		return key, nil
+	}
+	return ks.rotate(ctx, now)
+}
+
+// current returns the newest key if it is still within its rotation period
+// and uses the configured algorithm.
+func (ks *KeySet) current(now time.Time) *SigningKey {
+	if len(ks.keys) == 0 {
+		return nil
+	}
+	key := ks.keys[0]
+	if key.Algorithm != ks.algorithm || now.Sub(key.CreatedAt) >= ks.rotation {
+		return nil
+	}
+	return key
+}
+
+// rotate generates and stores a new signing key, then deletes keys retired
+// longer than the retention period.
+func (ks *KeySet) rotate(ctx context.Context, now time.Time) (*SigningKey, error) {
+	privateKey, err := generateKey(ks.algorithm)
+	if err != nil {
+		return nil, err
+	}
+	key := &SigningKey{
+		ID:         uuid.New().String(),
+		Algorithm:  ks.algorithm,
+		PrivateKey: privateKey,
+		CreatedAt:  now,
+	}
+	if err := ks.store.AddSigningKey(ctx, key); err != nil {
+		return nil, fmt.Errorf("storing signing key: %w", err)
+	}
+	ks.logger.Info("rotated signing key", "kid", key.ID, "alg", key.Algorithm)
+
+	// A key retires when its successor is created
+	keys := []*SigningKey{key}
+	retiredAt := now
+	for _, old := range ks.keys {
+		if now.Sub(retiredAt) > ks.retention {
+			if err := ks.store.DeleteSigningKey(ctx, old.ID); err != nil {
+				ks.logger.Warn("cannot delete retired signing key", "kid", old.ID, "error", err)
+				keys = append(keys, old)
+			}
+		} else {
+			keys = append(keys, old)
+		}
+		retiredAt = old.CreatedAt
+	}
+	ks.keys = keys
+
+	if err := ks.store.DeleteExpired(ctx, now); err != nil {
+		ks.logger.Warn("cannot delete expired tokens", "error", err)
+	}
+	return key, nil
+}
+
+// load replaces the cached keys with those in the store.
+func (ks *KeySet) load(ctx context.Context) error {
+	keys, err := ks.store.SigningKeys(ctx)
+	if err != nil {
+		return fmt.Errorf("loading signing keys: %w", err)
+	}
+	ks.keys = keys
+	ks.loadedAt = ks.now()
+	return nil
+}
+
+// GetKeyForAlgorithm returns the public key with the given ID if it signs
+// with alg. Unknown key IDs trigger a reload from the store, at most once
+// every few seconds.
+func (ks *KeySet) GetKeyForAlgorithm(ctx context.Context, kid, alg string) (crypto.PublicKey, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	key := ks.find(kid)
+	if key == nil && ks.now().Sub(ks.loadedAt) >= minReloadInterval {
+		if err := ks.load(ctx); err != nil {
+			return nil, err
+		}
+		key = ks.find(kid)
+	}
+	if key == nil {
+		return nil, auth.ErrKeyNotFound
+	}
+	if key.Algorithm != alg {
+		return nil, fmt.Errorf("%w: key %s signs %s, token uses %s", auth.ErrAlgorithmMismatch, kid, key.Algorithm, alg)
+	}
+	return key.PrivateKey.Public(), nil
+}
+
+func (ks *KeySet) find(kid string) *SigningKey {
+	for _, key := range ks.keys {
+		if key.ID == kid {
+			return key
+		}
+	}
+	return nil
+}
+
+// JWKS returns the public keys as a JSON Web Key Set, ensuring a current
+// signing key exists so clients can fetch it before the first login.
+func (ks *KeySet) JWKS(ctx context.Context) (*auth.JWKS, error) {
+	if _, err := ks.signingKey(ctx); err != nil {
+		return nil, err
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	set := &auth.JWKS{Keys: make([]auth.JWK, 0, len(ks.keys))}
+	for _, key := range ks.keys {
+		jwk, err := auth.NewJWK(key.ID, key.Algorithm, key.PrivateKey.Public())
+		if err != nil {
+			return nil, fmt.Errorf("signing key %s: %w", key.ID, err)
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set, nil
+}
+
+// supportedAlgorithm reports whether tokens can be signed with alg.
+func supportedAlgorithm(alg string) bool {
+	switch alg {
+	case "ES256", "ES384", "ES512", "RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "EdDSA":
+		return true
+	}
+	return false
+}
+
+// generateKey creates a private key for the signing algorithm.
+func generateKey(alg string) (crypto.Signer, error) {
+	switch alg {
+	case "ES256":
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case "ES384":
+		return ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case "ES512":
+		return ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	case "RS256", "RS384", "RS512", "PS256", "PS384", "PS512":
+		return rsa.GenerateKey(rand.Reader, 2048)
+	case "EdDSA":
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		return key, err
+	}
+	return nil, fmt.Errorf("unsupported signing algorithm %q", alg)
+}
+
+// encodePrivateKey encodes a private key as a PKCS #8 PEM block.
+func encodePrivateKey(key crypto.Signer) (string, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", fmt.Errorf("encoding signing key: %w", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
+}
+
+// decodePrivateKey decodes a private key encoded by encodePrivateKey.
+func decodePrivateKey(data string) (crypto.Signer, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("decoding signing key: %w", err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	return signer, nil
+}
@@ -0,0 +1,235 @@
+package issuer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	refreshTokensCollection = "auth_refresh_tokens"
+	revocationsCollection   = "auth_revocations"
+	signingKeysCollection   = "auth_signing_keys"
+)
+
+// MongoStore implements Store on MongoDB.
+type MongoStore struct {
+	tokens      *mongo.Collection
+	revocations *mongo.Collection
+	keys        *mongo.Collection
+}
+
+// NewMongoStore creates a store on the given MongoDB database.
+func NewMongoStore(db *mongo.Database) *MongoStore {
+	return &MongoStore{
+		tokens:      db.Collection(refreshTokensCollection),
+		revocations: db.Collection(revocationsCollection),
+		keys:        db.Collection(signingKeysCollection),
+	}
+}
+
+// mongoRefreshToken is the document representation of a RefreshToken.
+type mongoRefreshToken struct {
+	ID        string     `bson:"_id"`
+	SessionID string     `bson:"sessionId"`
+	Hash      string     `bson:"hash"`
+	Username  string     `bson:"username"`
+	CreatedAt time.Time  `bson:"createdAt"`
+	ExpiresAt time.Time  `bson:"expiresAt"`
+	RevokedAt *time.Time `bson:"revokedAt,omitempty"`
+}
+
+// mongoSigningKey is the document representation of a SigningKey.
+type mongoSigningKey struct {
+	ID         string    `bson:"_id"`
+	Algorithm  string    `bson:"algorithm"`
+	PrivateKey string    `bson:"privateKey"`
+	CreatedAt  time.Time `bson:"createdAt"`
+}
+
+// EnsureIndexes creates the unique index on refresh token hashes, the
+// session index, and TTL indexes that purge expired tokens and revocations.
+func (s *MongoStore) EnsureIndexes(ctx context.Context) error {
+	_, err := s.tokens.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "sessionId", Value: 1}}},
+		{Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		return fmt.Errorf("creating refresh token indexes: %w", err)
+	}
+	_, err = s.revocations.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		return fmt.Errorf("creating revocation indexes: %w", err)
+	}
+	return nil
+}
+
+// CreateRefreshToken stores a new refresh token.
+func (s *MongoStore) CreateRefreshToken(ctx context.Context, token *RefreshToken) error {
+	doc := mongoRefreshToken{
+		ID:        token.ID,
+		SessionID: token.SessionID,
+		Hash:      token.Hash,
+		Username:  token.Username,
+		CreatedAt: token.CreatedAt,
+		ExpiresAt: token.ExpiresAt,
+		RevokedAt: token.RevokedAt,
+	}
+	if _, err := s.tokens.InsertOne(ctx, doc); err != nil {
+		return fmt.Errorf("inserting refresh token: %w", err)
+	}
+	return nil
+}
+
+// GetRefreshToken retrieves a refresh token by the hash of its value.
+func (s *MongoStore) GetRefreshToken(ctx context.Context, hash string) (*RefreshToken, error) {
+	var doc mongoRefreshToken
+	err := s.tokens.FindOne(ctx, bson.M{"hash": hash}).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrTokenNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("finding refresh token: %w", err)
+	}
+	return &RefreshToken{
+		ID:        doc.ID,
+		SessionID: doc.SessionID,
+		Hash:      doc.Hash,
+		Username:  doc.Username,
+		CreatedAt: doc.CreatedAt,
+		ExpiresAt: doc.ExpiresAt,
+		RevokedAt: doc.RevokedAt,
+	}, nil
+}
+
+// RevokeRefreshToken revokes a refresh token that is not yet revoked.
+func (s *MongoStore) RevokeRefreshToken(ctx context.Context, id string, at time.Time) error {
+	filter := bson.M{"_id": id, "revokedAt": bson.M{"$exists": false}}
+	result, err := s.tokens.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"revokedAt": at}})
+	if err != nil {
+		return fmt.Errorf("revoking refresh token: %w", err)
+	}
+	if result.MatchedCount > 0 {
+		return nil
+	}
+
+	n, err := s.tokens.CountDocuments(ctx, bson.M{"_id": id})
+	if err != nil {
+		return fmt.Errorf("revoking refresh token: %w", err)
+	}
+	if n == 0 {
+		return ErrTokenNotFound
+	}
+	return ErrTokenRevoked
+}
+
+// RevokeSession revokes all refresh tokens of a session.
+func (s *MongoStore) RevokeSession(ctx context.Context, sessionID string, at time.Time) error {
+	filter := bson.M{"sessionId": sessionID, "revokedAt": bson.M{"$exists": false}}
+	if _, err := s.tokens.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"revokedAt": at}}); err != nil {
+		return fmt.Errorf("revoking session: %w", err)
+	}
+	return nil
+}
+
+// AddRevocation puts a token or session ID on the revocation list.
+func (s *MongoStore) AddRevocation(ctx context.Context, id string, until time.Time) error {
+	_, err := s.revocations.UpdateOne(ctx,
+		bson.M{"_id": id},
+		bson.M{"$max": bson.M{"expiresAt": until}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return fmt.Errorf("adding revocation: %w", err)
+	}
+	return nil
+}
+
+// IsRevoked reports whether any of the IDs is on the revocation list.
+func (s *MongoStore) IsRevoked(ctx context.Context, ids ...string) (bool, error) {
+	if len(ids) == 0 {
+		return false, nil
+	}
+	n, err := s.revocations.CountDocuments(ctx, bson.M{"_id": bson.M{"$in": ids}}, options.Count().SetLimit(1))
+	if err != nil {
+		return false, fmt.Errorf("checking revocations: %w", err)
+	}
+	return n > 0, nil
+}
+
+// SigningKeys returns all signing keys, newest first.
+func (s *MongoStore) SigningKeys(ctx context.Context) ([]*SigningKey, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}})
+	cursor, err := s.keys.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("listing signing keys: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var docs []mongoSigningKey
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("decoding signing keys: %w", err)
+	}
+	keys := make([]*SigningKey, len(docs))
+	for i, doc := range docs {
+		privateKey, err := decodePrivateKey(doc.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("signing key %s: %w", doc.ID, err)
+		}
+		keys[i] = &SigningKey{
+			ID:         doc.ID,
+			Algorithm:  doc.Algorithm,
+			PrivateKey: privateKey,
+			CreatedAt:  doc.CreatedAt,
+		}
+	}
+	return keys, nil
+}
+
+// AddSigningKey stores a new signing key.
+func (s *MongoStore) AddSigningKey(ctx context.Context, key *SigningKey) error {
+	encoded, err := encodePrivateKey(key.PrivateKey)
+	if err != nil {
+		return err
+	}
+	doc := mongoSigningKey{
+		ID:         key.ID,
+		Algorithm:  key.Algorithm,
+		PrivateKey: encoded,
+		CreatedAt:  key.CreatedAt,
+	}
+	if _, err := s.keys.InsertOne(ctx, doc); err != nil {
+		return fmt.Errorf("inserting signing key: %w", err)
+	}
+	return nil
+}
+
+// DeleteSigningKey removes a signing key.
+func (s *MongoStore) DeleteSigningKey(ctx context.Context, id string) error {
+	if _, err := s.keys.DeleteOne(ctx, bson.M{"_id": id}); err != nil {
+		return fmt.Errorf("deleting signing key: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpired removes refresh tokens and revocations that expired before
+// the given time. The TTL indexes do the same in the background.
+func (s *MongoStore) DeleteExpired(ctx context.Context, before time.Time) error {
+	filter := bson.M{"expiresAt": bson.M{"$lt": before}}
+	if _, err := s.tokens.DeleteMany(ctx, filter); err != nil {
+		return fmt.Errorf("deleting expired refresh tokens: %w", err)
+	}
+	if _, err := s.revocations.DeleteMany(ctx, filter); err != nil {
+		return fmt.Errorf("deleting expired revocations: %w", err)
+	}
+	return nil
+}
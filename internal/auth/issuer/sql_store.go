@@ -0,0 +1,236 @@
+package issuer
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SQLStore implements Store on PostgreSQL.
+type SQLStore struct {
+	db *sql.DB
+}
+
+// NewSQLStore creates a store on the given PostgreSQL database.
+func NewSQLStore(db *sql.DB) *SQLStore {
+	return &SQLStore{db: db}
+}
+
+// CreateTables creates the auth_refresh_tokens, auth_revocations and
+// auth_signing_keys tables if they don't exist.
+func (s *SQLStore) CreateTables(ctx context.Context) error {
+	queries := []string{`
+		CREATE TABLE IF NOT EXISTS auth_refresh_tokens (
+			id TEXT PRIMARY KEY,
+			session_id TEXT NOT NULL,
+			hash TEXT NOT NULL UNIQUE,
+			username TEXT NOT NULL,
+			created_at TIMESTAMP NOT NULL,
+			expires_at TIMESTAMP NOT NULL,
+			revoked_at TIMESTAMP
+		)`, `
+		CREATE INDEX IF NOT EXISTS auth_refresh_tokens_session_id_idx
+			ON auth_refresh_tokens (session_id)`, `
+		CREATE TABLE IF NOT EXISTS auth_revocations (
+			id TEXT PRIMARY KEY,
+			expires_at TIMESTAMP NOT NULL
+		)`, `
+		CREATE TABLE IF NOT EXISTS auth_signing_keys (
+			id TEXT PRIMARY KEY,
+			algorithm TEXT NOT NULL,
+			private_key TEXT NOT NULL,
+			created_at TIMESTAMP NOT NULL
+		)`,
+	}
+	for _, query := range queries {
+		if _, err := s.db.ExecContext(ctx, query); err != nil {
+			return fmt.Errorf("creating token issuer tables: %w", err)
+		}
+	}
+	return nil
+}
+
+// CreateRefreshToken stores a new refresh token.
+func (s *SQLStore) CreateRefreshToken(ctx context.Context, token *RefreshToken) error {
+	query := `
+		INSERT INTO auth_refresh_tokens (id, session_id, hash, username, created_at, expires_at, revoked_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	_, err := s.db.ExecContext(ctx, query,
+		token.ID, token.SessionID, token.Hash, token.Username,
+		token.CreatedAt.UTC(), token.ExpiresAt.UTC(), nullTime(token.RevokedAt),
+	)
+	if err != nil {
+		return fmt.Errorf("inserting refresh token: %w", err)
+	}
+	return nil
+}
+
+// GetRefreshToken retrieves a refresh token by the hash of its value.
+func (s *SQLStore) GetRefreshToken(ctx context.Context, hash string) (*RefreshToken, error) {
+	query := `
+		SELECT id, session_id, hash, username, created_at, expires_at, revoked_at
+		FROM auth_refresh_tokens
+		WHERE hash = $1
+	`
+	var (
+		token     RefreshToken
+		revokedAt sql.NullTime
+	)
+	err := s.db.QueryRowContext(ctx, query, hash).Scan(&token.ID, &token.SessionID, &token.Hash,
+		&token.Username, &token.CreatedAt, &token.ExpiresAt, &revokedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTokenNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scanning refresh token: %w", err)
+	}
+	if revokedAt.Valid {
+		token.RevokedAt = &revokedAt.Time
+	}
+	return &token, nil
+}
+
+// RevokeRefreshToken revokes a refresh token that is not yet revoked.
+func (s *SQLStore) RevokeRefreshToken(ctx context.Context, id string, at time.Time) error {
+	result, err := s.db.ExecContext(ctx,
+		"UPDATE auth_refresh_tokens SET revoked_at = $1 WHERE id = $2 AND revoked_at IS NULL", at.UTC(), id)
+	if err != nil {
+		return fmt.Errorf("revoking refresh token: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("revoking refresh token: %w", err)
+	}
+	if n > 0 {
+		return nil
+	}
+
+	var exists int
+	err = s.db.QueryRowContext(ctx, "SELECT 1 FROM auth_refresh_tokens WHERE id = $1", id).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrTokenNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("revoking refresh token: %w", err)
+	}
+	return ErrTokenRevoked
+}
+
+// RevokeSession revokes all refresh tokens of a session.
+func (s *SQLStore) RevokeSession(ctx context.Context, sessionID string, at time.Time) error {
+	_, err := s.db.ExecContext(ctx,
+		"UPDATE auth_refresh_tokens SET revoked_at = $1 WHERE session_id = $2 AND revoked_at IS NULL", at.UTC(), sessionID)
+	if err != nil {
+		return fmt.Errorf("revoking session: %w", err)
+	}
+	return nil
+}
+
+// AddRevocation puts a token or session ID on the revocation list.
+func (s *SQLStore) AddRevocation(ctx context.Context, id string, until time.Time) error {
+	query := `
+		INSERT INTO auth_revocations (id, expires_at) VALUES ($1, $2)
+		ON CONFLICT (id) DO UPDATE SET expires_at = EXCLUDED.expires_at
+		WHERE auth_revocations.expires_at < EXCLUDED.expires_at
+	`
+	if _, err := s.db.ExecContext(ctx, query, id, until.UTC()); err != nil {
+		return fmt.Errorf("adding revocation: %w", err)
+	}
+	return nil
+}
+
+// IsRevoked reports whether any of the IDs is on the revocation list.
+func (s *SQLStore) IsRevoked(ctx context.Context, ids ...string) (bool, error) {
+	if len(ids) == 0 {
+		return false, nil
+	}
+	placeholders := make([]string, len(ids))
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = id
+	}
+
+	var exists int
+	query := "SELECT 1 FROM auth_revocations WHERE id IN (" + strings.Join(placeholders, ", ") + ") LIMIT 1"
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("checking revocations: %w", err)
+	}
+	return true, nil
+}
+
+// SigningKeys returns all signing keys, newest first.
+func (s *SQLStore) SigningKeys(ctx context.Context) ([]*SigningKey, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT id, algorithm, private_key, created_at FROM auth_signing_keys ORDER BY created_at DESC, id DESC")
+	if err != nil {
+		return nil, fmt.Errorf("listing signing keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []*SigningKey
+	for rows.Next() {
+		var (
+			key     SigningKey
+			encoded string
+		)
+		if err := rows.Scan(&key.ID, &key.Algorithm, &encoded, &key.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scanning signing key: %w", err)
+		}
+		if key.PrivateKey, err = decodePrivateKey(encoded); err != nil {
+			return nil, fmt.Errorf("signing key %s: %w", key.ID, err)
+		}
+		keys = append(keys, &key)
+	}
+	return keys, rows.Err()
+}
+
+// AddSigningKey stores a new signing key.
+func (s *SQLStore) AddSigningKey(ctx context.Context, key *SigningKey) error {
+	encoded, err := encodePrivateKey(key.PrivateKey)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx,
+		"INSERT INTO auth_signing_keys (id, algorithm, private_key, created_at) VALUES ($1, $2, $3, $4)",
+		key.ID, key.Algorithm, encoded, key.CreatedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("inserting signing key: %w", err)
+	}
+	return nil
+}
+
+// DeleteSigningKey removes a signing key.
+func (s *SQLStore) DeleteSigningKey(ctx context.Context, id string) error {
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM auth_signing_keys WHERE id = $1", id); err != nil {
+		return fmt.Errorf("deleting signing key: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpired removes refresh tokens and revocations that expired before
+// the given time.
+func (s *SQLStore) DeleteExpired(ctx context.Context, before time.Time) error {
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM auth_refresh_tokens WHERE expires_at < $1", before.UTC()); err != nil {
+		return fmt.Errorf("deleting expired refresh tokens: %w", err)
+	}
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM auth_revocations WHERE expires_at < $1", before.UTC()); err != nil {
+		return fmt.Errorf("deleting expired revocations: %w", err)
+	}
+	return nil
+}
+
+func nullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: t.UTC(), Valid: true}
+}
@@ -0,0 +1,137 @@
+package issuer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+func newSQLStore(t *testing.T) *SQLStore {
+	t.Helper()
+	db, err := sql.Open("sqlite", ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	store := NewSQLStore(db)
+	require.NoError(t, store.CreateTables(context.Background()))
+	return store
+}
+
+func TestSQLStore_RefreshTokens(t *testing.T) {
+	store := newSQLStore(t)
+	ctx := context.Background()
+	created := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+
+	tokens := []*RefreshToken{
+		{ID: "t1", SessionID: "s1", Hash: "h1", Username: "alice", CreatedAt: created, ExpiresAt: created.Add(time.Hour)},
+		{ID: "t2", SessionID: "s1", Hash: "h2", Username: "alice", CreatedAt: created, ExpiresAt: created.Add(2 * time.Hour)},
+		{ID: "t3", SessionID: "s2", Hash: "h3", Username: "bob", CreatedAt: created, ExpiresAt: created.Add(2 * time.Hour)},
+	}
+	for _, token := range tokens {
+		require.NoError(t, store.CreateRefreshToken(ctx, token))
+	}
+
+	got, err := store.GetRefreshToken(ctx, "h1")
+	require.NoError(t, err)
+	assert.Equal(t, "t1", got.ID)
+	assert.Equal(t, "s1", got.SessionID)
+	assert.Equal(t, "alice", got.Username)
+	assert.True(t, created.Add(time.Hour).Equal(got.ExpiresAt))
+	assert.Nil(t, got.RevokedAt)
+
+	_, err = store.GetRefreshToken(ctx, "missing")
+	assert.ErrorIs(t, err, ErrTokenNotFound)
+
+	revoked := created.Add(time.Minute)
+	require.NoError(t, store.RevokeRefreshToken(ctx, "t1", revoked))
+	assert.ErrorIs(t, store.RevokeRefreshToken(ctx, "t1", revoked), ErrTokenRevoked)
+	assert.ErrorIs(t, store.RevokeRefreshToken(ctx, "missing", revoked), ErrTokenNotFound)
+	got, err = store.GetRefreshToken(ctx, "h1")
+	require.NoError(t, err)
+	require.NotNil(t, got.RevokedAt)
+	assert.True(t, revoked.Equal(*got.RevokedAt))
+
+	require.NoError(t, store.RevokeSession(ctx, "s1", created.Add(time.Hour)))
+	got, err = store.GetRefreshToken(ctx, "h2")
+	require.NoError(t, err)
+	assert.NotNil(t, got.RevokedAt)
+	got, err = store.GetRefreshToken(ctx, "h3")
+	require.NoError(t, err)
+	assert.Nil(t, got.RevokedAt)
+
+	require.NoError(t, store.DeleteExpired(ctx, created.Add(90*time.Minute)))
+	_, err = store.GetRefreshToken(ctx, "h1")
+	assert.ErrorIs(t, err, ErrTokenNotFound)
+	_, err = store.GetRefreshToken(ctx, "h3")
+	assert.NoError(t, err)
+}
+
+func TestSQLStore_Revocations(t *testing.T) {
+	store := newSQLStore(t)
+	ctx := context.Background()
+	now := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+
+	revoked, err := store.IsRevoked(ctx, "s1")
+	require.NoError(t, err)
+	assert.False(t, revoked)
+
+	require.NoError(t, store.AddRevocation(ctx, "s1", now.Add(time.Hour)))
+	// A shorter revocation does not cut an existing one short
+	require.NoError(t, store.AddRevocation(ctx, "s1", now.Add(time.Minute)))
+
+	revoked, err = store.IsRevoked(ctx, "jti", "s1")
+	require.NoError(t, err)
+	assert.True(t, revoked)
+	revoked, err = store.IsRevoked(ctx)
+	require.NoError(t, err)
+	assert.False(t, revoked)
+
+	require.NoError(t, store.DeleteExpired(ctx, now.Add(30*time.Minute)))
+	revoked, err = store.IsRevoked(ctx, "s1")
+	require.NoError(t, err)
+	assert.True(t, revoked)
+
+	require.NoError(t, store.DeleteExpired(ctx, now.Add(2*time.Hour)))
+	revoked, err = store.IsRevoked(ctx, "s1")
+	require.NoError(t, err)
+	assert.False(t, revoked)
+}
+
+func TestSQLStore_SigningKeys(t *testing.T) {
+	store := newSQLStore(t)
+	ctx := context.Background()
+	created := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+
+	for i, id := range []string{"k1", "k2"} {
+		privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		require.NoError(t, store.AddSigningKey(ctx, &SigningKey{
+			ID:         id,
+			Algorithm:  "ES256",
+			PrivateKey: privateKey,
+			CreatedAt:  created.Add(time.Duration(i) * time.Hour),
+		}))
+	}
+
+	keys, err := store.SigningKeys(ctx)
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.Equal(t, "k2", keys[0].ID)
+	assert.Equal(t, "k1", keys[1].ID)
+	assert.Equal(t, "ES256", keys[0].Algorithm)
+	assert.IsType(t, &ecdsa.PrivateKey{}, keys[0].PrivateKey)
+
+	require.NoError(t, store.DeleteSigningKey(ctx, "k2"))
+	keys, err = store.SigningKeys(ctx)
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.Equal(t, "k1", keys[0].ID)
+}
@@ -0,0 +1,232 @@
+package issuer
+
+import (
+	"context"
+	"crypto"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrTokenNotFound indicates no refresh token has the given hash.
+	ErrTokenNotFound = errors.New("refresh token not found")
+
+	// ErrTokenRevoked indicates the refresh token was already revoked.
+	ErrTokenRevoked = errors.New("refresh token already revoked")
+)
+
+// RefreshToken is a stored refresh token. Only the hash of the token value
+// is kept.
+type RefreshToken struct {
+	ID        string
+	SessionID string // Shared by all tokens issued from one login
+	Hash      string
+	Username  string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	RevokedAt *time.Time
+}
+
+// SigningKey is a private key access tokens are signed with.
+type SigningKey struct {
+	ID         string
+	Algorithm  string
+	PrivateKey crypto.Signer
+	CreatedAt  time.Time
+}
+
+// Store persists refresh tokens, the revocation list and signing keys.
+type Store interface {
+	// CreateRefreshToken stores a new refresh token.
+	CreateRefreshToken(ctx context.Context, token *RefreshToken) error
+
+	// GetRefreshToken retrieves a refresh token by the hash of its value.
+	GetRefreshToken(ctx context.Context, hash string) (*RefreshToken, error)
+
+	// RevokeRefreshToken revokes a refresh token, or returns ErrTokenRevoked
+	// if it was already revoked. Checking and revoking is atomic, so a
+	// token can only be exchanged once.
+	RevokeRefreshToken(ctx context.Context, id string, at time.Time) error
+
+	// RevokeSession revokes all refresh tokens of a session.
+	RevokeSession(ctx context.Context, sessionID string, at time.Time) error
+
+	// AddRevocation puts a token or session ID on the revocation list until
+	// the given time, after which its tokens have expired anyway.
+	AddRevocation(ctx context.Context, id string, until time.Time) error
+
+	// IsRevoked reports whether any of the IDs is on the revocation list.
+	IsRevoked(ctx context.Context, ids ...string) (bool, error)
+
+	// SigningKeys returns all signing keys, newest first.
+	SigningKeys(ctx context.Context) ([]*SigningKey, error)
+
+	// AddSigningKey stores a new signing key.
+	AddSigningKey(ctx context.Context, key *SigningKey) error
+
+	// DeleteSigningKey removes a signing key.
+	DeleteSigningKey(ctx context.Context, id string) error
+
+	// DeleteExpired removes refresh tokens and revocations that expired
+	// before the given time.
+	DeleteExpired(ctx context.Context, before time.Time) error
+}
+
+// MemoryStore is an in-memory implementation of Store.
+type MemoryStore struct {
+	tokens      map[string]*RefreshToken // by hash
+	revocations map[string]time.Time
+	keys        map[string]*SigningKey
+	mu          sync.RWMutex
+}
+
+// NewMemoryStore creates a new in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		tokens:      make(map[string]*RefreshToken),
+		revocations: make(map[string]time.Time),
+		keys:        make(map[string]*SigningKey),
+	}
+}
+
+// CreateRefreshToken stores a new refresh token.
+func (s *MemoryStore) CreateRefreshToken(ctx context.Context, token *RefreshToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := *token
+	s.tokens[token.Hash] = &c
+	return nil
+}
+
+// GetRefreshToken retrieves a refresh token by the hash of its value.
+func (s *MemoryStore) GetRefreshToken(ctx context.Context, hash string) (*RefreshToken, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	token, ok := s.tokens[hash]
+	if !ok {
+		return nil, ErrTokenNotFound
+	}
+	c := *token
+	return &c, nil
+}
+
+// RevokeRefreshToken revokes a refresh token that is not yet revoked.
+func (s *MemoryStore) RevokeRefreshToken(ctx context.Context, id string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, token := range s.tokens {
+		if token.ID != id {
+			continue
+		}
+		if token.RevokedAt != nil {
+			return ErrTokenRevoked
+		}
+		token.RevokedAt = &at
+		return nil
+	}
+	return ErrTokenNotFound
+}
+
+// RevokeSession revokes all refresh tokens of a session.
+func (s *MemoryStore) RevokeSession(ctx context.Context, sessionID string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, token := range s.tokens {
+		if token.SessionID == sessionID && token.RevokedAt == nil {
+			token.RevokedAt = &at
+		}
+	}
+	return nil
+}
+
+// AddRevocation puts a token or session ID on the revocation list.
+func (s *MemoryStore) AddRevocation(ctx context.Context, id string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, ok := s.revocations[id]; !ok || until.After(existing) {
+		s.revocations[id] = until
+	}
+	return nil
+}
+
+// IsRevoked reports whether any of the IDs is on the revocation list.
+func (s *MemoryStore) IsRevoked(ctx context.Context, ids ...string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, id := range ids {
+		if _, ok := s.revocations[id]; ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// SigningKeys returns all signing keys, newest first.
+func (s *MemoryStore) SigningKeys(ctx context.Context) ([]*SigningKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := make([]*SigningKey, 0, len(s.keys))
+	for _, key := range s.keys {
+		c := *key
+		keys = append(keys, &c)
+	}
+	sortNewestFirst(keys)
+	return keys, nil
+}
+
+// AddSigningKey stores a new signing key.
+func (s *MemoryStore) AddSigningKey(ctx context.Context, key *SigningKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := *key
+	s.keys[key.ID] = &c
+	return nil
+}
+
+// DeleteSigningKey removes a signing key.
+func (s *MemoryStore) DeleteSigningKey(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.keys, id)
+	return nil
+}
+
+// DeleteExpired removes refresh tokens and revocations that expired before
+// the given time.
+func (s *MemoryStore) DeleteExpired(ctx context.Context, before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for hash, token := range s.tokens {
+		if token.ExpiresAt.Before(before) {
+			delete(s.tokens, hash)
+		}
+	}
+	for id, until := range s.revocations {
+		if until.Before(before) {
+			delete(s.revocations, id)
+		}
+	}
+	return nil
+}
+
+// sortNewestFirst orders signing keys by creation time, newest first.
+func sortNewestFirst(keys []*SigningKey) {
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].ID > keys[j].ID
+		}
+		return keys[i].CreatedAt.After(keys[j].CreatedAt)
+	})
+}
@@ -2,6 +2,11 @@ package auth
 
 import (
 	"context"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rsa"
 	"encoding/base64"
 	"encoding/json"
@@ -13,10 +18,12 @@ import (
 	"time"
 )
 
-// JWKSCache caches RSA public keys fetched from a JWKS endpoint.
+// JWKSCache caches public keys fetched from a JWKS endpoint. RSA, ECDSA
+// (kty EC) and Ed25519 (kty OKP) signing keys are supported.
 type JWKSCache struct {
 	url         string
-	keys        map[string]*rsa.PublicKey
+	keys        map[string]crypto.PublicKey
+	algs        map[string]string // Algorithm each key is pinned to, if any
 	mu          sync.RWMutex
 	refreshTTL  time.Duration
 	lastRefresh time.Time
@@ -36,19 +43,23 @@ type JWKS struct {
 
 // JWK represents a JSON Web Key.
 type JWK struct {
-	Kid string `json:"kid"` // Key ID
-	Kty string `json:"kty"` // Key Type (RSA, EC, etc.)
-	Alg string `json:"alg"` // Algorithm
-	Use string `json:"use"` // Usage (sig, enc)
-	N   string `json:"n"`   // RSA modulus
-	E   string `json:"e"`   // RSA exponent
+	Kid string `json:"kid"`           // Key ID
+	Kty string `json:"kty"`           // Key Type (RSA, EC, OKP)
+	Alg string `json:"alg,omitempty"` // Algorithm
+	Use string `json:"use,omitempty"` // Usage (sig, enc)
+	N   string `json:"n,omitempty"`   // RSA modulus
+	E   string `json:"e,omitempty"`   // RSA exponent
+	Crv string `json:"crv,omitempty"` // Curve (P-256, P-384, P-521, Ed25519)
+	X   string `json:"x,omitempty"`   // EC x coordinate or OKP public key
+	Y   string `json:"y,omitempty"`   // EC y coordinate
 }
 
 // NewJWKSCache creates a new JWKS cache with the given URL and refresh interval.
 func NewJWKSCache(url string, refreshTTL time.Duration) *JWKSCache {
 	return &JWKSCache{
 		url:        url,
-		keys:       make(map[string]*rsa.PublicKey),
+		keys:       make(map[string]crypto.PublicKey),
+		algs:       make(map[string]string),
 		refreshTTL: refreshTTL,
 		client: &http.Client{
 			Timeout: 10 * time.Second,
@@ -71,37 +82,60 @@ func (c *JWKSCache) SetLogger(logger *slog.Logger) {
 
 // GetKey retrieves a public key by its key ID.
 // If the key is not in the cache, it attempts to refresh from the JWKS endpoint.
-func (c *JWKSCache) GetKey(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+func (c *JWKSCache) GetKey(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	key, _, err := c.lookup(ctx, kid)
+	return key, err
+}
+
+// GetKeyForAlgorithm retrieves a public key by its key ID and checks that it
+// may verify signatures made with alg: the key type must support alg, and a
+// key published with an "alg" member only accepts that algorithm.
+func (c *JWKSCache) GetKeyForAlgorithm(ctx context.Context, kid, alg string) (crypto.PublicKey, error) {
+	key, pinned, err := c.lookup(ctx, kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkKeyAlgorithm(key, pinned, alg); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// GetKeyByID retrieves a public key by its key ID without triggering a refresh.
+func (c *JWKSCache) GetKeyByID(kid string) (crypto.PublicKey, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	key, ok := c.keys[kid]
+	return key, ok
+}
+
+// lookup returns a key and its pinned algorithm, refreshing the cache once
+// if the key is unknown.
+func (c *JWKSCache) lookup(ctx context.Context, kid string) (crypto.PublicKey, string, error) {
 	c.mu.RLock()
 	key, ok := c.keys[kid]
+	alg := c.algs[kid]
 	c.mu.RUnlock()
 
 	if ok {
-		return key, nil
+		return key, alg, nil
 	}
 
 	// Key not found, try refreshing
 	if err := c.Refresh(ctx); err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	c.mu.RLock()
 	key, ok = c.keys[kid]
+	alg = c.algs[kid]
 	c.mu.RUnlock()
 
 	if !ok {
-		return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, kid)
+		return nil, "", fmt.Errorf("%w: %s", ErrKeyNotFound, kid)
 	}
 
-	return key, nil
-}
-
-// GetKeyByID retrieves a public key by its key ID without triggering a refresh.
-func (c *JWKSCache) GetKeyByID(kid string) (*rsa.PublicKey, bool) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-	key, ok := c.keys[kid]
-	return key, ok
+	return key, alg, nil
 }
 
 // Refresh fetches the JWKS from the configured URL and updates the cache.
@@ -126,24 +160,35 @@ func (c *JWKSCache) Refresh(ctx context.Context) error {
 		return fmt.Errorf("%w: %v", ErrJWKSDecodeFailed, err)
 	}
 
-	keys := make(map[string]*rsa.PublicKey)
+	keys := make(map[string]crypto.PublicKey)
+	algs := make(map[string]string)
 	for _, jwk := range jwks.Keys {
-		if jwk.Kty != "RSA" {
-			c.logger.Debug("skipping non-RSA key", "kid", jwk.Kid, "kty", jwk.Kty)
+		if jwk.Use != "" && jwk.Use != "sig" {
+			c.logger.Debug("skipping non-signing key", "kid", jwk.Kid, "use", jwk.Use)
 			continue
 		}
 
-		key, err := jwkToRSAPublicKey(jwk)
+		key, err := jwkToPublicKey(jwk)
 		if err != nil {
-			c.logger.Warn("failed to parse JWK", "kid", jwk.Kid, "error", err)
+			c.logger.Warn("failed to parse JWK", "kid", jwk.Kid, "kty", jwk.Kty, "error", err)
 			continue
 		}
 
+		// A key must not advertise an algorithm its type cannot verify
+		if jwk.Alg != "" {
+			if err := checkKeyAlgorithm(key, "", jwk.Alg); err != nil {
+				c.logger.Warn("skipping JWK with mismatched algorithm", "kid", jwk.Kid, "alg", jwk.Alg)
+				continue
+			}
+		}
+
 		keys[jwk.Kid] = key
+		algs[jwk.Kid] = jwk.Alg
 	}
 
 	c.mu.Lock()
 	c.keys = keys
+	c.algs = algs
 	c.lastRefresh = time.Now()
 	c.mu.Unlock()
 
@@ -183,6 +228,50 @@ func (c *JWKSCache) KeyCount() int {
 	return len(c.keys)
 }
 
+// NewJWK returns the JWK publishing an RSA, ECDSA or Ed25519 public key
+// under the given key ID, pinned to alg when it is not empty.
+func NewJWK(kid, alg string, key crypto.PublicKey) (JWK, error) {
+	jwk := JWK{Kid: kid, Alg: alg, Use: "sig"}
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(k.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = k.Curve.Params().Name
+		jwk.X = base64.RawURLEncoding.EncodeToString(k.X.FillBytes(make([]byte, size)))
+		jwk.Y = base64.RawURLEncoding.EncodeToString(k.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(k)
+	default:
+		return JWK{}, fmt.Errorf("unsupported public key type %T", key)
+	}
+	if alg != "" {
+		if err := checkKeyAlgorithm(key, "", alg); err != nil {
+			return JWK{}, err
+		}
+	}
+	return jwk, nil
+}
+
+// jwkToPublicKey converts a JWK to a public key according to its key type.
+func jwkToPublicKey(jwk JWK) (crypto.PublicKey, error) {
+	switch jwk.Kty {
+	case "RSA":
+		return jwkToRSAPublicKey(jwk)
+	case "EC":
+		return jwkToECDSAPublicKey(jwk)
+	case "OKP":
+		return jwkToEd25519PublicKey(jwk)
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", jwk.Kty)
+	}
+}
+
 // jwkToRSAPublicKey converts a JWK to an RSA public key.
 func jwkToRSAPublicKey(jwk JWK) (*rsa.PublicKey, error) {
 	if jwk.N == "" || jwk.E == "" {
@@ -204,3 +293,75 @@ func jwkToRSAPublicKey(jwk JWK) (*rsa.PublicKey, error) {
 
 	return &rsa.PublicKey{N: n, E: e}, nil
 }
+
+// jwkToECDSAPublicKey converts an EC JWK to an ECDSA public key. The point
+// must lie on the named curve.
+func jwkToECDSAPublicKey(jwk JWK) (*ecdsa.PublicKey, error) {
+	var (
+		curve elliptic.Curve
+		check ecdh.Curve
+	)
+	switch jwk.Crv {
+	case "P-256":
+		curve, check = elliptic.P256(), ecdh.P256()
+	case "P-384":
+		curve, check = elliptic.P384(), ecdh.P384()
+	case "P-521":
+		curve, check = elliptic.P521(), ecdh.P521()
+	default:
+		return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+	}
+
+	if jwk.X == "" || jwk.Y == "" {
+		return nil, fmt.Errorf("missing x or y coordinate")
+	}
+
+	xBytes, err := base64.RawURLEncoding.DecodeString(jwk.X)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode x coordinate: %w", err)
+	}
+
+	yBytes, err := base64.RawURLEncoding.DecodeString(jwk.Y)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode y coordinate: %w", err)
+	}
+
+	size := (curve.Params().BitSize + 7) / 8
+	if len(xBytes) != size || len(yBytes) != size {
+		return nil, fmt.Errorf("coordinates must be %d bytes for %s", size, jwk.Crv)
+	}
+
+	// Parsing the uncompressed point validates it
+	point := append(append([]byte{4}, xBytes...), yBytes...)
+	if _, err := check.NewPublicKey(point); err != nil {
+		return nil, fmt.Errorf("invalid %s point: %w", jwk.Crv, err)
+	}
+
+	return &ecdsa.PublicKey{
+		Curve: curve,
+		X:     new(big.Int).SetBytes(xBytes),
+		Y:     new(big.Int).SetBytes(yBytes),
+	}, nil
+}
+
+// jwkToEd25519PublicKey converts an OKP JWK to an Ed25519 public key.
+func jwkToEd25519PublicKey(jwk JWK) (ed25519.PublicKey, error) {
+	if jwk.Crv != "Ed25519" {
+		return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+	}
+
+	if jwk.X == "" {
+		return nil, fmt.Errorf("missing public key")
+	}
+
+	xBytes, err := base64.RawURLEncoding.DecodeString(jwk.X)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode public key: %w", err)
+	}
+
+	if len(xBytes) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("public key must be %d bytes", ed25519.PublicKeySize)
+	}
+
+	return ed25519.PublicKey(xBytes), nil
+}
@@ -3,6 +3,10 @@ package auth
 import (
 	"bytes"
 	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"encoding/base64"
@@ -12,9 +16,11 @@ import (
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -51,6 +57,45 @@ func generateTestJWKS(t *testing.T, keys map[string]*rsa.PublicKey) []byte {
 	return data
 }
 
+// ecJWK builds the JWK of an ECDSA public key.
+func ecJWK(kid, alg string, key *ecdsa.PublicKey) JWK {
+	size := (key.Curve.Params().BitSize + 7) / 8
+	return JWK{
+		Kid: kid,
+		Kty: "EC",
+		Alg: alg,
+		Crv: key.Curve.Params().Name,
+		X:   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, size))),
+		Y:   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, size))),
+	}
+}
+
+// okpJWK builds the JWK of an Ed25519 public key.
+func okpJWK(kid, alg string, key ed25519.PublicKey) JWK {
+	return JWK{
+		Kid: kid,
+		Kty: "OKP",
+		Alg: alg,
+		Crv: "Ed25519",
+		X:   base64.RawURLEncoding.EncodeToString(key),
+	}
+}
+
+// jwksClient returns a client serving the given keys as a JWKS.
+func jwksClient(t *testing.T, keys ...JWK) *mockHTTPClient {
+	t.Helper()
+	data, err := json.Marshal(JWKS{Keys: keys})
+	require.NoError(t, err)
+	return &mockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(bytes.NewReader(data)),
+			}, nil
+		},
+	}
+}
+
 func TestNewJWKSCache(t *testing.T) {
 	cache := NewJWKSCache("https://example.com/.well-known/jwks.json", 5*time.Minute)
 
@@ -157,10 +202,25 @@ func TestJWKSCache_Refresh_InvalidJSON(t *testing.T) {
 	assert.ErrorIs(t, err, ErrJWKSDecodeFailed)
 }
 
-func TestJWKSCache_Refresh_SkipsNonRSAKeys(t *testing.T) {
+func TestJWKSCache_Refresh_SkipsUnusableKeys(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	rsaJWK := JWK{
+		Kty: "RSA",
+		N:   base64.RawURLEncoding.EncodeToString(privateKey.PublicKey.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString([]byte{1, 0, 1}),
+	}
+	encKey, mismatchedKey := rsaJWK, rsaJWK
+	encKey.Kid, encKey.Use = "enc-key", "enc"
+	mismatchedKey.Kid, mismatchedKey.Alg = "mismatched-key", "ES256"
+
 	jwks := JWKS{
 		Keys: []JWK{
-			{Kid: "ec-key", Kty: "EC", Alg: "ES256"},
+			{Kid: "ec-key", Kty: "EC", Alg: "ES256"}, // No coordinates
+			{Kid: "oct-key", Kty: "oct"},
+			encKey,
+			mismatchedKey,
 		},
 	}
 	jwksData, _ := json.Marshal(jwks)
@@ -176,7 +236,7 @@ func TestJWKSCache_Refresh_SkipsNonRSAKeys(t *testing.T) {
 
 	cache := NewJWKSCacheWithClient("https://example.com/jwks", 5*time.Minute, mockClient)
 
-	err := cache.Refresh(context.Background())
+	err = cache.Refresh(context.Background())
 
 	require.NoError(t, err)
 	assert.Equal(t, 0, cache.KeyCount())
@@ -466,3 +526,200 @@ func TestJWKSCache_StartRefreshLoop_RefreshError(t *testing.T) {
 	// Should have attempted multiple refreshes despite errors
 	assert.Greater(t, callCount, 0)
 }
+
+// TestJWKToPublicKey_RFCVectors verifies the signed examples of RFC 7515
+// (ES256) and RFC 8037 (EdDSA) with keys converted from their JWKs.
+func TestJWKToPublicKey_RFCVectors(t *testing.T) {
+	tests := []struct {
+		name   string
+		jwk    JWK
+		method jwt.SigningMethod
+		jws    string
+	}{
+		{
+			name: "RFC 7515 A.3 ES256",
+			jwk: JWK{
+				Kty: "EC",
+				Crv: "P-256",
+				X:   "f83OJ3D2xF1Bg8vub9tLe1gHMzV76e8Tus9uPHvRVEU",
+				Y:   "x_FEzRu9m36HLN_tue659LNpXW6pCyStikYjKIWI5a0",
+			},
+			method: jwt.SigningMethodES256,
+			jws: "eyJhbGciOiJFUzI1NiJ9" +
+				".eyJpc3MiOiJqb2UiLA0KICJleHAiOjEzMDA4MTkzODAsDQogImh0dHA6Ly9leGFtcGxlLmNvbS9pc19yb290Ijp0cnVlfQ" +
+				".DtEhU3ljbEg8L38VWAfUAqOyKAM6-Xx-F4GawxaepmXFCgfTjDxw5djxLa8ISlSApmWQxfKTUJqPP3-Kg6NU1Q",
+		},
+		{
+			name: "RFC 8037 A.4 Ed25519",
+			jwk: JWK{
+				Kty: "OKP",
+				Crv: "Ed25519",
+				X:   "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo",
+			},
+			method: jwt.SigningMethodEdDSA,
+			jws: "eyJhbGciOiJFZERTQSJ9" +
+				".RXhhbXBsZSBvZiBFZDI1NTE5IHNpZ25pbmc" +
+				".hgyY0il_MGCjP0JzlnLWG1PPOt7-09PGcvMg3AIbQR6dWbhijcNR4ki4iylGjg5BhVsPt9g7sVvpAr_MuM0KAg",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := jwkToPublicKey(tt.jwk)
+			require.NoError(t, err)
+			require.NoError(t, checkKeyAlgorithm(key, "", tt.method.Alg()))
+
+			dot := strings.LastIndex(tt.jws, ".")
+			sig, err := base64.RawURLEncoding.DecodeString(tt.jws[dot+1:])
+			require.NoError(t, err)
+
+			assert.NoError(t, tt.method.Verify(tt.jws[:dot], sig, key))
+			assert.Error(t, tt.method.Verify(tt.jws[:dot]+"x", sig, key))
+		})
+	}
+}
+
+func TestJWKToECDSAPublicKey_Curves(t *testing.T) {
+	for _, curve := range []elliptic.Curve{elliptic.P256(), elliptic.P384(), elliptic.P521()} {
+		t.Run(curve.Params().Name, func(t *testing.T) {
+			privateKey, err := ecdsa.GenerateKey(curve, rand.Reader)
+			require.NoError(t, err)
+
+			key, err := jwkToPublicKey(ecJWK("test", "", &privateKey.PublicKey))
+
+			require.NoError(t, err)
+			assert.True(t, privateKey.PublicKey.Equal(key))
+		})
+	}
+}
+
+func TestJWKToECDSAPublicKey_Invalid(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	valid := ecJWK("test", "", &privateKey.PublicKey)
+
+	unsupportedCurve := valid
+	unsupportedCurve.Crv = "secp256k1"
+
+	missingY := valid
+	missingY.Y = ""
+
+	shortX := valid
+	shortX.X = base64.RawURLEncoding.EncodeToString([]byte{1, 2, 3})
+
+	// Changing y moves the point off the curve
+	offCurve := valid
+	y, _ := base64.RawURLEncoding.DecodeString(valid.Y)
+	y[len(y)-1] ^= 1
+	offCurve.Y = base64.RawURLEncoding.EncodeToString(y)
+
+	tests := []struct {
+		name    string
+		jwk     JWK
+		message string
+	}{
+		{"unsupported curve", unsupportedCurve, "unsupported curve"},
+		{"missing coordinate", missingY, "missing x or y"},
+		{"short coordinate", shortX, "must be 32 bytes"},
+		{"point not on curve", offCurve, "invalid P-256 point"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := jwkToECDSAPublicKey(tt.jwk)
+
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), tt.message)
+		})
+	}
+}
+
+func TestJWKToEd25519PublicKey_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		jwk     JWK
+		message string
+	}{
+		{"X25519 key", JWK{Kty: "OKP", Crv: "X25519", X: "AAAA"}, "unsupported curve"},
+		{"missing key", JWK{Kty: "OKP", Crv: "Ed25519"}, "missing public key"},
+		{"short key", JWK{Kty: "OKP", Crv: "Ed25519", X: "AAAA"}, "must be 32 bytes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := jwkToEd25519PublicKey(tt.jwk)
+
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), tt.message)
+		})
+	}
+}
+
+func TestJWKSCache_GetKeyForAlgorithm(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+	edKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	client := jwksClient(t,
+		ecJWK("ec-key", "ES384", &ecKey.PublicKey),
+		okpJWK("ed-key", "", edKey),
+	)
+	cache := NewJWKSCacheWithClient("https://example.com/jwks", 5*time.Minute, client)
+	ctx := context.Background()
+
+	key, err := cache.GetKeyForAlgorithm(ctx, "ec-key", "ES384")
+	require.NoError(t, err)
+	assert.True(t, ecKey.PublicKey.Equal(key))
+
+	key, err = cache.GetKeyForAlgorithm(ctx, "ed-key", "EdDSA")
+	require.NoError(t, err)
+	assert.True(t, edKey.Equal(key))
+
+	_, err = cache.GetKeyForAlgorithm(ctx, "ec-key", "ES256")
+	assert.ErrorIs(t, err, ErrAlgorithmMismatch)
+
+	_, err = cache.GetKeyForAlgorithm(ctx, "ed-key", "RS256")
+	assert.ErrorIs(t, err, ErrAlgorithmMismatch)
+
+	_, err = cache.GetKeyForAlgorithm(ctx, "missing-key", "EdDSA")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+}
+
+func TestNewJWK(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	require.NoError(t, err)
+	edKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		alg  string
+		key  interface{ Equal(x crypto.PublicKey) bool }
+	}{
+		{"RSA", "PS256", &rsaKey.PublicKey},
+		{"ECDSA", "ES512", &ecKey.PublicKey},
+		{"Ed25519", "EdDSA", edKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jwk, err := NewJWK("kid-1", tt.alg, tt.key)
+			require.NoError(t, err)
+			assert.Equal(t, "kid-1", jwk.Kid)
+			assert.Equal(t, tt.alg, jwk.Alg)
+			assert.Equal(t, "sig", jwk.Use)
+
+			key, err := jwkToPublicKey(jwk)
+			require.NoError(t, err)
+			assert.True(t, tt.key.Equal(key))
+		})
+	}
+
+	_, err = NewJWK("kid-1", "ES256", &ecKey.PublicKey)
+	assert.ErrorIs(t, err, ErrAlgorithmMismatch)
+	_, err = NewJWK("kid-1", "", []byte("not a key"))
+	assert.ErrorContains(t, err, "unsupported public key type")
+}
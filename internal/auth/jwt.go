@@ -2,9 +2,10 @@ package auth
 
 import (
 	"context"
-	"crypto/rsa"
+	"crypto"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"strings"
 	"sync"
 	"time"
@@ -46,39 +47,65 @@ func (u *User) HasPermission(permission string) bool {
 
 // Config holds JWT validation configuration.
 type Config struct {
-	Issuer     string // Expected issuer (iss claim)
-	Audience   string // Expected audience (aud claim)
-	Secret     string // Secret key for HS256/HS384/HS512
-	PublicKey  string // PEM-encoded public key for RS256/RS384/RS512
-	JWKSURL    string // URL to fetch JWKS from
-	RolesClaim string // Claim name containing roles (default: "roles")
-	PermsClaim string // Claim name containing permissions
+	Issuer       string // Expected issuer (iss claim)
+	Audience     string // Expected audience (aud claim)
+	Secret       string // Secret key for HS256/HS384/HS512
+	PublicKey    string // PEM-encoded RSA, ECDSA or Ed25519 public key
+	PublicKeyAlg string // Algorithm the public key is pinned to (default: any its type supports)
+	JWKSURL      string // URL to fetch JWKS from
+	RolesClaim   string // Claim name containing roles (default: "roles")
+	PermsClaim   string // Claim name containing permissions
+}
+
+// KeySource provides verification keys by key ID, such as the signing keys
+// of a built-in token issuer. JWKSCache implements it.
+type KeySource interface {
+	// GetKeyForAlgorithm returns the key with the given ID if it may verify
+	// signatures made with alg.
+	GetKeyForAlgorithm(ctx context.Context, kid, alg string) (crypto.PublicKey, error)
+}
+
+// RevocationList reports whether tokens were revoked before they expired.
+type RevocationList interface {
+	// IsRevoked reports whether the token ID (jti claim) or the session ID
+	// (sid claim) has been revoked. Either may be empty.
+	IsRevoked(ctx context.Context, tokenID, sessionID string) (bool, error)
 }
 
 // Validator validates JWTs and extracts user information.
 type Validator struct {
-	config     Config
-	publicKeys map[string]*rsa.PublicKey
-	jwksCache  *JWKSCache
-	mu         sync.RWMutex
-	logger     *slog.Logger
+	config      Config
+	publicKeys  map[string]crypto.PublicKey
+	keyAlgs     map[string]string // Algorithm each public key is pinned to, if any
+	jwksCache   *JWKSCache
+	keySource   KeySource
+	revocations RevocationList
+	mu          sync.RWMutex
+	logger      *slog.Logger
 }
 
 // NewValidator creates a new JWT validator with the given configuration.
 func NewValidator(config Config) (*Validator, error) {
 	v := &Validator{
 		config:     config,
-		publicKeys: make(map[string]*rsa.PublicKey),
+		publicKeys: make(map[string]crypto.PublicKey),
+		keyAlgs:    make(map[string]string),
 		logger:     slog.Default().With("component", "jwt-validator"),
 	}
 
 	// Load static public key if provided
 	if config.PublicKey != "" {
-		key, err := jwt.ParseRSAPublicKeyFromPEM([]byte(config.PublicKey))
+		key, err := ParsePublicKeyPEM([]byte(config.PublicKey))
 		if err != nil {
 			return nil, fmt.Errorf("invalid public key: %w", err)
 		}
+		if config.PublicKeyAlg != "" {
+			if err := checkKeyAlgorithm(key, "", config.PublicKeyAlg); err != nil {
+				return nil, fmt.Errorf("invalid public key algorithm: %w", err)
+			}
+		}
 		v.publicKeys["default"] = key
+		v.keyAlgs["default"] = config.PublicKeyAlg
 	}
 
 	// Initialize JWKS cache if URL provided
@@ -109,6 +136,24 @@ func (v *Validator) SetJWKSCache(cache *JWKSCache) {
 	v.jwksCache = cache
 }
 
+// SetKeySource sets an additional source of verification keys, consulted
+// after the JWKS cache and before the static public key.
+func (v *Validator) SetKeySource(source KeySource) {
+	v.keySource = source
+}
+
+// SetRevocationList sets the list consulted to reject tokens that were
+// revoked before they expired.
+func (v *Validator) SetRevocationList(list RevocationList) {
+	v.revocations = list
+}
+
+// Authenticate validates the JWT carried by the request.
+// It implements Authenticator.
+func (v *Validator) Authenticate(r *http.Request) (*User, error) {
+	return v.ValidateToken(r.Context(), ExtractToken(r))
+}
+
 // ValidateToken validates a JWT string and returns the extracted user.
 func (v *Validator) ValidateToken(ctx context.Context, tokenStr string) (*User, error) {
 	if tokenStr == "" {
@@ -158,6 +203,21 @@ func (v *Validator) ValidateToken(ctx context.Context, tokenStr string) (*User,
 		}
 	}
 
+	// Reject revoked tokens
+	if v.revocations != nil {
+		jti, sid := getStringClaim(claims, "jti"), getStringClaim(claims, "sid")
+		if jti != "" || sid != "" {
+			revoked, err := v.revocations.IsRevoked(ctx, jti, sid)
+			if err != nil {
+				v.logger.Warn("revocation check failed", "error", err)
+				return nil, ErrInvalidToken
+			}
+			if revoked {
+				return nil, ErrRevokedToken
+			}
+		}
+	}
+
 	// Extract user info
 	user := &User{
 		ID:     getStringClaim(claims, "sub"),
@@ -188,39 +248,49 @@ func (v *Validator) ValidateToken(ctx context.Context, tokenStr string) (*User,
 }
 
 // getKey retrieves the appropriate signing key based on algorithm and key ID.
+// Public keys are only returned for algorithms they may be used with.
 func (v *Validator) getKey(ctx context.Context, kid, alg string) (interface{}, error) {
-	switch alg {
-	case "HS256", "HS384", "HS512":
+	switch {
+	case alg == "HS256" || alg == "HS384" || alg == "HS512":
 		if v.config.Secret == "" {
 			return nil, ErrNoSecretConfigured
 		}
 		return []byte(v.config.Secret), nil
 
-	case "RS256", "RS384", "RS512":
+	case isAsymmetricAlgorithm(alg):
 		// Try JWKS first
 		if v.jwksCache != nil {
-			key, err := v.jwksCache.GetKey(ctx, kid)
+			key, err := v.jwksCache.GetKeyForAlgorithm(ctx, kid, alg)
+			if err == nil {
+				return key, nil
+			}
+			v.logger.Debug("JWKS key lookup failed", "kid", kid, "alg", alg, "error", err)
+		}
+		if v.keySource != nil {
+			key, err := v.keySource.GetKeyForAlgorithm(ctx, kid, alg)
 			if err == nil {
 				return key, nil
 			}
-			v.logger.Debug("JWKS key lookup failed", "kid", kid, "error", err)
+			v.logger.Debug("key source lookup failed", "kid", kid, "alg", alg, "error", err)
 		}
 
 		// Fall back to static key
 		v.mu.RLock()
 		defer v.mu.RUnlock()
 
-		if kid != "" {
-			if key, ok := v.publicKeys[kid]; ok {
-				return key, nil
-			}
+		name := "default"
+		if _, ok := v.publicKeys[kid]; ok && kid != "" {
+			name = kid
 		}
 
-		if key, ok := v.publicKeys["default"]; ok {
-			return key, nil
+		key, ok := v.publicKeys[name]
+		if !ok {
+			return nil, ErrNoPublicKeyConfigured
 		}
-
-		return nil, ErrNoPublicKeyConfigured
+		if err := checkKeyAlgorithm(key, v.keyAlgs[name], alg); err != nil {
+			return nil, err
+		}
+		return key, nil
 
 	default:
 		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, alg)
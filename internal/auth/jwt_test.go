@@ -2,10 +2,16 @@ package auth
 
 import (
 	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
+	"encoding/base64"
 	"encoding/pem"
+	"errors"
 	"log/slog"
 	"testing"
 	"time"
@@ -548,7 +554,7 @@ func TestValidator_ValidateToken_UnsupportedAlgorithm(t *testing.T) {
 	v, err := NewValidator(Config{Secret: testSecret})
 	require.NoError(t, err)
 
-	// Create a token with ES256 algorithm (not supported)
+	// Create a token with ES256 algorithm (no EC key configured)
 	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
 		"sub": "user123",
 		"exp": time.Now().Add(time.Hour).Unix(),
@@ -682,3 +688,179 @@ func TestValidator_ValidateToken_RS256_JWKSCacheMiss_FallbackToStatic(t *testing
 	require.NoError(t, err)
 	assert.Equal(t, "user-fallback", user.ID)
 }
+
+// signToken signs a token with the given method, key and key ID.
+func signToken(t *testing.T, method jwt.SigningMethod, key crypto.PrivateKey, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	tokenStr, err := token.SignedString(key)
+	require.NoError(t, err)
+	return tokenStr
+}
+
+func TestValidator_ValidateToken_KeyFamiliesFromJWKS(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	p256Key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	p384Key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+	p521Key, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	require.NoError(t, err)
+	edPublic, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	client := jwksClient(t,
+		JWK{
+			Kid: "rsa-pss",
+			Kty: "RSA",
+			Alg: "PS256",
+			N:   base64.RawURLEncoding.EncodeToString(rsaKey.PublicKey.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString([]byte{1, 0, 1}),
+		},
+		ecJWK("p256", "ES256", &p256Key.PublicKey),
+		ecJWK("p384", "ES384", &p384Key.PublicKey),
+		ecJWK("p521", "", &p521Key.PublicKey),
+		okpJWK("ed25519", "EdDSA", edPublic),
+	)
+
+	v, err := NewValidator(Config{})
+	require.NoError(t, err)
+	v.SetJWKSCache(NewJWKSCacheWithClient("https://example.com/jwks", 5*time.Minute, client))
+
+	tests := []struct {
+		name   string
+		method jwt.SigningMethod
+		key    crypto.PrivateKey
+		kid    string
+		err    error
+	}{
+		{"PS256", jwt.SigningMethodPS256, rsaKey, "rsa-pss", nil},
+		{"ES256", jwt.SigningMethodES256, p256Key, "p256", nil},
+		{"ES384", jwt.SigningMethodES384, p384Key, "p384", nil},
+		{"ES512", jwt.SigningMethodES512, p521Key, "p521", nil},
+		{"EdDSA", jwt.SigningMethodEdDSA, edKey, "ed25519", nil},
+		{"RS256 with key pinned to PS256", jwt.SigningMethodRS256, rsaKey, "rsa-pss", ErrInvalidToken},
+		{"ES256 with kid of P-384 key", jwt.SigningMethodES256, p256Key, "p384", ErrInvalidToken},
+		{"EdDSA with wrong kid", jwt.SigningMethodEdDSA, edKey, "p256", ErrInvalidToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := signToken(t, tt.method, tt.key, tt.kid, jwt.MapClaims{
+				"sub": "user-" + tt.kid,
+				"exp": time.Now().Add(time.Hour).Unix(),
+			})
+
+			user, err := v.ValidateToken(context.Background(), token)
+
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "user-"+tt.kid, user.ID)
+		})
+	}
+}
+
+func TestValidator_ValidateToken_PEMKeyFamilies(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+	edPublic, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	claims := jwt.MapClaims{
+		"sub": "user123",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+
+	ecValidator, err := NewValidator(Config{PublicKey: pkixPEM(t, &ecKey.PublicKey)})
+	require.NoError(t, err)
+	user, err := ecValidator.ValidateToken(context.Background(), signToken(t, jwt.SigningMethodES384, ecKey, "", claims))
+	require.NoError(t, err)
+	assert.Equal(t, "user123", user.ID)
+
+	edValidator, err := NewValidator(Config{PublicKey: pkixPEM(t, edPublic), PublicKeyAlg: "EdDSA"})
+	require.NoError(t, err)
+	user, err = edValidator.ValidateToken(context.Background(), signToken(t, jwt.SigningMethodEdDSA, edKey, "", claims))
+	require.NoError(t, err)
+	assert.Equal(t, "user123", user.ID)
+}
+
+func TestValidator_ValidateToken_PinnedPEMKey(t *testing.T) {
+	privateKey, publicKeyPEM := generateTestRSAKeys(t)
+
+	v, err := NewValidator(Config{PublicKey: publicKeyPEM, PublicKeyAlg: "RS256"})
+	require.NoError(t, err)
+
+	claims := jwt.MapClaims{
+		"sub": "user123",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+
+	_, err = v.ValidateToken(context.Background(), signToken(t, jwt.SigningMethodRS256, privateKey, "", claims))
+	assert.NoError(t, err)
+
+	_, err = v.ValidateToken(context.Background(), signToken(t, jwt.SigningMethodPS256, privateKey, "", claims))
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestNewValidator_PublicKeyAlgMismatch(t *testing.T) {
+	_, publicKeyPEM := generateTestRSAKeys(t)
+
+	_, err := NewValidator(Config{PublicKey: publicKeyPEM, PublicKeyAlg: "ES256"})
+
+	assert.Error(t, err)
+	assert.ErrorIs(t, err, ErrAlgorithmMismatch)
+}
+
+// revocationListFunc adapts a function to RevocationList.
+type revocationListFunc func(ctx context.Context, tokenID, sessionID string) (bool, error)
+
+func (f revocationListFunc) IsRevoked(ctx context.Context, tokenID, sessionID string) (bool, error) {
+	return f(ctx, tokenID, sessionID)
+}
+
+func TestValidator_ValidateToken_RevocationList(t *testing.T) {
+	v, err := NewValidator(Config{Secret: "test-secret"})
+	require.NoError(t, err)
+
+	var checked []string
+	v.SetRevocationList(revocationListFunc(func(ctx context.Context, tokenID, sessionID string) (bool, error) {
+		checked = append(checked, tokenID+"/"+sessionID)
+		switch {
+		case sessionID == "broken":
+			return false, errors.New("store unavailable")
+		case tokenID == "revoked" || sessionID == "ended":
+			return true, nil
+		}
+		return false, nil
+	}))
+
+	token := func(extra jwt.MapClaims) string {
+		claims := jwt.MapClaims{"sub": "user123", "exp": time.Now().Add(time.Hour).Unix()}
+		for k, val := range extra {
+			claims[k] = val
+		}
+		return signToken(t, jwt.SigningMethodHS256, []byte("test-secret"), "", claims)
+	}
+	ctx := context.Background()
+
+	_, err = v.ValidateToken(ctx, token(jwt.MapClaims{"jti": "active", "sid": "s1"}))
+	assert.NoError(t, err)
+	_, err = v.ValidateToken(ctx, token(jwt.MapClaims{"jti": "revoked"}))
+	assert.ErrorIs(t, err, ErrRevokedToken)
+	_, err = v.ValidateToken(ctx, token(jwt.MapClaims{"sid": "ended"}))
+	assert.ErrorIs(t, err, ErrRevokedToken)
+	_, err = v.ValidateToken(ctx, token(jwt.MapClaims{"sid": "broken"}))
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	// Tokens without jti or sid are not checked
+	_, err = v.ValidateToken(ctx, token(nil))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"active/s1", "revoked/", "/ended", "/broken"}, checked)
+}
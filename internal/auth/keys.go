@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+)
+
+// ParsePublicKeyPEM parses a PEM-encoded RSA, ECDSA or Ed25519 public key.
+// It accepts PKIX ("PUBLIC KEY") and PKCS #1 ("RSA PUBLIC KEY") blocks as
+// well as certificates, whose public key is returned.
+func ParsePublicKeyPEM(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var (
+		key crypto.PublicKey
+		err error
+	)
+	switch block.Type {
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		var cert *x509.Certificate
+		cert, err = x509.ParseCertificate(block.Bytes)
+		if err == nil {
+			key = cert.PublicKey
+		}
+	default:
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(keyAlgorithms(key)) == 0 {
+		return nil, fmt.Errorf("unsupported public key type %T", key)
+	}
+	return key, nil
+}
+
+// keyAlgorithms returns the signing algorithms a public key can verify.
+// ECDSA keys are tied to the algorithm matching their curve.
+func keyAlgorithms(key crypto.PublicKey) []string {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512"}
+	case *ecdsa.PublicKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return []string{"ES256"}
+		case elliptic.P384():
+			return []string{"ES384"}
+		case elliptic.P521():
+			return []string{"ES512"}
+		}
+	case ed25519.PublicKey:
+		return []string{"EdDSA"}
+	}
+	return nil
+}
+
+// checkKeyAlgorithm returns an error unless the key can verify signatures of
+// alg and, when the key is pinned to an algorithm, alg is that algorithm.
+func checkKeyAlgorithm(key crypto.PublicKey, pinned, alg string) error {
+	if pinned != "" && pinned != alg {
+		return fmt.Errorf("%w: key is pinned to %s, token uses %s", ErrAlgorithmMismatch, pinned, alg)
+	}
+	for _, a := range keyAlgorithms(key) {
+		if a == alg {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %T key cannot verify %s", ErrAlgorithmMismatch, key, alg)
+}
+
+// isAsymmetricAlgorithm reports whether alg is a supported public key
+// signing algorithm.
+func isAsymmetricAlgorithm(alg string) bool {
+	switch alg {
+	case "RS256", "RS384", "RS512", "PS256", "PS384", "PS512",
+		"ES256", "ES384", "ES512", "EdDSA":
+		return true
+	}
+	return false
+}
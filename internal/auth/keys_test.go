@@ -0,0 +1,125 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pkixPEM encodes a public key as a PKIX "PUBLIC KEY" PEM block.
+func pkixPEM(t *testing.T, key crypto.PublicKey) string {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key)
+	require.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func TestParsePublicKeyPEM(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	edKey, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "codeai-test"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, edKey, edPrivate)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		pem  string
+		want crypto.PublicKey
+	}{
+		{"PKIX RSA", pkixPEM(t, &rsaKey.PublicKey), &rsaKey.PublicKey},
+		{"PKCS1 RSA", string(pem.EncodeToMemory(&pem.Block{
+			Type:  "RSA PUBLIC KEY",
+			Bytes: x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey),
+		})), &rsaKey.PublicKey},
+		{"PKIX ECDSA", pkixPEM(t, &ecKey.PublicKey), &ecKey.PublicKey},
+		{"PKIX Ed25519", pkixPEM(t, edKey), edKey},
+		{"certificate", string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER})), edKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := ParsePublicKeyPEM([]byte(tt.pem))
+
+			require.NoError(t, err)
+			assert.True(t, tt.want.(interface{ Equal(crypto.PublicKey) bool }).Equal(key))
+		})
+	}
+}
+
+func TestParsePublicKeyPEM_Invalid(t *testing.T) {
+	x25519Key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	_, err = ParsePublicKeyPEM([]byte("not a key"))
+	assert.ErrorContains(t, err, "no PEM block")
+
+	_, err = ParsePublicKeyPEM(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: []byte("garbage")}))
+	assert.Error(t, err)
+
+	_, err = ParsePublicKeyPEM([]byte(pkixPEM(t, x25519Key.PublicKey())))
+	assert.ErrorContains(t, err, "unsupported public key type")
+}
+
+func TestCheckKeyAlgorithm(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	p256Key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	p521Key, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	require.NoError(t, err)
+	edKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		key     crypto.PublicKey
+		pinned  string
+		alg     string
+		allowed bool
+	}{
+		{"RSA RS256", &rsaKey.PublicKey, "", "RS256", true},
+		{"RSA PS384", &rsaKey.PublicKey, "", "PS384", true},
+		{"RSA pinned to RS256 rejects PS256", &rsaKey.PublicKey, "RS256", "PS256", false},
+		{"RSA ES256", &rsaKey.PublicKey, "", "ES256", false},
+		{"P-256 ES256", &p256Key.PublicKey, "", "ES256", true},
+		{"P-256 ES384", &p256Key.PublicKey, "", "ES384", false},
+		{"P-521 ES512", &p521Key.PublicKey, "", "ES512", true},
+		{"Ed25519 EdDSA", edKey, "", "EdDSA", true},
+		{"Ed25519 pinned to EdDSA", edKey, "EdDSA", "EdDSA", true},
+		{"Ed25519 HS256", edKey, "", "HS256", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkKeyAlgorithm(tt.key, tt.pinned, tt.alg)
+			if tt.allowed {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrAlgorithmMismatch)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package auth
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
 )
@@ -38,14 +39,32 @@ const (
 	AuthPublic AuthRequirement = "public"
 )
 
+// Authenticator authenticates the credentials carried by a request.
+// It returns ErrMissingToken when the request has no credentials.
+type Authenticator interface {
+	Authenticate(r *http.Request) (*User, error)
+}
+
+// Challenger is implemented by authenticators that send a WWW-Authenticate
+// challenge with 401 responses, such as HTTP Basic authentication.
+type Challenger interface {
+	Challenge() string
+}
+
 // Middleware creates HTTP middleware that validates JWTs and attaches user info to the context.
 type Middleware struct {
-	validator *Validator
+	authenticator Authenticator
 }
 
 // NewMiddleware creates a new authentication middleware with the given validator.
 func NewMiddleware(validator *Validator) *Middleware {
-	return &Middleware{validator: validator}
+	return &Middleware{authenticator: validator}
+}
+
+// NewAuthenticatorMiddleware creates an authentication middleware that uses
+// the given authenticator instead of JWT validation, e.g. an API key provider.
+func NewAuthenticatorMiddleware(authenticator Authenticator) *Middleware {
+	return &Middleware{authenticator: authenticator}
 }
 
 // Authenticate returns middleware that enforces the specified authentication requirement.
@@ -58,10 +77,10 @@ func (m *Middleware) Authenticate(requirement AuthRequirement) func(http.Handler
 				return
 			}
 
-			token := ExtractToken(r)
-
-			if token == "" {
+			user, err := m.authenticator.Authenticate(r)
+			if errors.Is(err, ErrMissingToken) {
 				if requirement == AuthRequired {
+					m.challenge(w)
 					writeJSONError(w, http.StatusUnauthorized, "authentication required")
 					return
 				}
@@ -70,7 +89,6 @@ func (m *Middleware) Authenticate(requirement AuthRequirement) func(http.Handler
 				return
 			}
 
-			user, err := m.validator.ValidateToken(r.Context(), token)
 			if err != nil {
 				if requirement == AuthRequired {
 					status := http.StatusUnauthorized
@@ -79,12 +97,17 @@ func (m *Middleware) Authenticate(requirement AuthRequirement) func(http.Handler
 					switch err {
 					case ErrExpiredToken:
 						message = "token has expired"
+					case ErrRevokedToken:
+						message = "token has been revoked"
 					case ErrInvalidIssuer:
 						message = "invalid token issuer"
 					case ErrInvalidAudience:
 						message = "invalid token audience"
+					case ErrInvalidAPIKey, ErrExpiredAPIKey, ErrInvalidCredentials:
+						message = err.Error()
 					}
 
+					m.challenge(w)
 					writeJSONError(w, status, message)
 					return
 				}
@@ -99,6 +122,13 @@ func (m *Middleware) Authenticate(requirement AuthRequirement) func(http.Handler
 	}
 }
 
+// challenge sets the WWW-Authenticate header if the authenticator has one.
+func (m *Middleware) challenge(w http.ResponseWriter) {
+	if c, ok := m.authenticator.(Challenger); ok {
+		w.Header().Set("WWW-Authenticate", c.Challenge())
+	}
+}
+
 // RequireAuth is a convenience method that creates middleware requiring authentication.
 func (m *Middleware) RequireAuth() func(http.Handler) http.Handler {
 	return m.Authenticate(AuthRequired)
@@ -9,6 +9,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/bargom/codeai/internal/tenant"
 )
 
 func TestDefaultConfig(t *testing.T) {
@@ -751,3 +753,38 @@ func TestMemoryCache_DefaultTTL_Zero(t *testing.T) {
 
 	assert.Equal(t, 5*time.Minute, cache.config.DefaultTTL)
 }
+
+func TestTenantCache(t *testing.T) {
+	mem := NewMemoryCache(DefaultConfig())
+	defer mem.Close()
+	c := NewTenantCache(mem)
+
+	acme := tenant.WithTenant(context.Background(), "acme")
+	globex := tenant.WithTenant(context.Background(), "globex")
+
+	require.NoError(t, c.Set(acme, "settings", []byte("a"), time.Minute))
+	require.NoError(t, c.Set(globex, "settings", []byte("g"), time.Minute))
+
+	got, err := c.Get(acme, "settings")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("a"), got)
+	got, err = c.Get(globex, "settings")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("g"), got)
+
+	_, err = c.Get(context.Background(), "settings")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+	exists, err := mem.Exists(context.Background(), "tenant:acme:settings")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	keys, err := c.Keys(acme, "*")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"settings"}, keys)
+
+	require.NoError(t, c.DeletePattern(globex, "*"))
+	_, err = c.Get(globex, "settings")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+	_, err = c.Get(acme, "settings")
+	assert.NoError(t, err)
+}
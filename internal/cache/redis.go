@@ -278,6 +278,19 @@ func (c *RedisCache) Decr(ctx context.Context, key string) (int64, error) {
 	return val, nil
 }
 
+// Eval runs a Lua script. Keys are prefixed like all other cache keys.
+func (c *RedisCache) Eval(ctx context.Context, script string, keys []string, args ...any) (any, error) {
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = c.prefixKey(key)
+	}
+	result, err := c.client.Eval(ctx, script, prefixed, args...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis eval: %w", err)
+	}
+	return result, nil
+}
+
 // Close closes the Redis connection.
 func (c *RedisCache) Close() error {
 	if err := c.client.Close(); err != nil {
@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/bargom/codeai/internal/tenant"
+)
+
+// TenantCache prefixes the keys of a cache with the tenant carried by the
+// context of each call, so tenants sharing a cache never see each other's
+// entries. Calls without a tenant use unprefixed keys.
+type TenantCache struct {
+	Cache
+}
+
+// NewTenantCache wraps c to scope its keys to tenants.
+func NewTenantCache(c Cache) *TenantCache {
+	return &TenantCache{Cache: c}
+}
+
+func tenantKey(ctx context.Context, key string) string {
+	if id := tenant.FromContext(ctx); id != "" {
+		return tenant.KeyPrefix(id) + key
+	}
+	return key
+}
+
+// Get implements Cache.
+func (c *TenantCache) Get(ctx context.Context, key string) ([]byte, error) {
+	return c.Cache.Get(ctx, tenantKey(ctx, key))
+}
+
+// Set implements Cache.
+func (c *TenantCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return c.Cache.Set(ctx, tenantKey(ctx, key), value, ttl)
+}
+
+// Delete implements Cache.
+func (c *TenantCache) Delete(ctx context.Context, key string) error {
+	return c.Cache.Delete(ctx, tenantKey(ctx, key))
+}
+
+// Exists implements Cache.
+func (c *TenantCache) Exists(ctx context.Context, key string) (bool, error) {
+	return c.Cache.Exists(ctx, tenantKey(ctx, key))
+}
+
+// GetJSON implements Cache.
+func (c *TenantCache) GetJSON(ctx context.Context, key string, dest any) error {
+	return c.Cache.GetJSON(ctx, tenantKey(ctx, key), dest)
+}
+
+// SetJSON implements Cache.
+func (c *TenantCache) SetJSON(ctx context.Context, key string, value any, ttl time.Duration) error {
+	return c.Cache.SetJSON(ctx, tenantKey(ctx, key), value, ttl)
+}
+
+// GetOrSet implements Cache.
+func (c *TenantCache) GetOrSet(ctx context.Context, key string, ttl time.Duration, fn func() (any, error)) (any, error) {
+	return c.Cache.GetOrSet(ctx, tenantKey(ctx, key), ttl, fn)
+}
+
+// MGet implements Cache.
+func (c *TenantCache) MGet(ctx context.Context, keys ...string) ([][]byte, error) {
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = tenantKey(ctx, key)
+	}
+	return c.Cache.MGet(ctx, prefixed...)
+}
+
+// MSet implements Cache.
+func (c *TenantCache) MSet(ctx context.Context, items map[string][]byte, ttl time.Duration) error {
+	prefixed := make(map[string][]byte, len(items))
+	for key, value := range items {
+		prefixed[tenantKey(ctx, key)] = value
+	}
+	return c.Cache.MSet(ctx, prefixed, ttl)
+}
+
+// DeletePattern implements Cache.
+func (c *TenantCache) DeletePattern(ctx context.Context, pattern string) error {
+	return c.Cache.DeletePattern(ctx, tenantKey(ctx, pattern))
+}
+
+// Keys implements Cache. The returned keys are not prefixed.
+func (c *TenantCache) Keys(ctx context.Context, pattern string) ([]string, error) {
+	keys, err := c.Cache.Keys(ctx, tenantKey(ctx, pattern))
+	if err != nil {
+		return nil, err
+	}
+	prefix := tenantKey(ctx, "")
+	for i, key := range keys {
+		keys[i] = strings.TrimPrefix(key, prefix)
+	}
+	return keys, nil
+}
+
+// Incr implements Cache.
+func (c *TenantCache) Incr(ctx context.Context, key string) (int64, error) {
+	return c.Cache.Incr(ctx, tenantKey(ctx, key))
+}
+
+// Decr implements Cache.
+func (c *TenantCache) Decr(ctx context.Context, key string) (int64, error) {
+	return c.Cache.Decr(ctx, tenantKey(ctx, key))
+}
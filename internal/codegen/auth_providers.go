@@ -0,0 +1,266 @@
+package codegen
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/bargom/codeai/internal/ast"
+	"github.com/bargom/codeai/internal/auth"
+	"github.com/bargom/codeai/internal/auth/apikey"
+	"github.com/bargom/codeai/internal/auth/basic"
+	"github.com/bargom/codeai/internal/auth/introspection"
+	"github.com/bargom/codeai/internal/auth/issuer"
+	"github.com/bargom/codeai/internal/cache"
+	"github.com/bargom/codeai/internal/database"
+	"github.com/bargom/codeai/internal/database/mongodb"
+	"github.com/bargom/codeai/internal/database/schema"
+)
+
+// loadAuthProviders creates the authenticators for auth providers that are
+// not JWT based and registers them with the auth loader, so authentication
+// middleware referencing them use the right method. OAuth2 providers
+// without an introspection_url keep validating tokens as JWTs. JWT
+// providers with an issuer block get a token issuer and validate the
+// tokens it signs.
+func (g *generator) loadAuthProviders(code *GeneratedCode) error {
+	auths := code.AuthLoader.AllAuths()
+	names := make([]string, 0, len(auths))
+	for name := range auths {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		loaded := auths[name]
+
+		var authenticator auth.Authenticator
+		var err error
+		switch loaded.Method {
+		case ast.AuthMethodAPIKey:
+			var provider *apikey.Provider
+			provider, err = g.apiKeyProvider(loaded)
+			if err == nil {
+				code.APIKeys[name] = provider
+				authenticator = provider
+			}
+		case ast.AuthMethodBasic:
+			authenticator, err = g.basicProvider(loaded)
+		case ast.AuthMethodOAuth2:
+			if _, ok := loaded.Settings["introspection_url"]; !ok {
+				continue
+			}
+			authenticator, err = g.introspectionProvider(loaded)
+		case ast.AuthMethodJWT:
+			if loaded.IssuerSettings == nil {
+				continue
+			}
+			var iss *issuer.Issuer
+			iss, err = g.tokenIssuer(loaded)
+			if err == nil {
+				code.Issuers[name] = iss
+				authenticator, err = iss.Validator()
+			}
+		default:
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("auth provider %q: %w", name, err)
+		}
+		if err := code.AuthLoader.RegisterAuthenticator(name, authenticator); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// apiKeyProvider creates the provider for an apikey auth declaration.
+func (g *generator) apiKeyProvider(loaded *auth.LoadedAuth) (*apikey.Provider, error) {
+	cfg, err := apikey.ConfigFromSettings(loaded.Settings)
+	if err != nil {
+		return nil, err
+	}
+	storage := configString(loaded.Settings, "storage")
+	if storage == "" {
+		storage = "database"
+	}
+	store, err := g.apiKeyStore(storage)
+	if err != nil {
+		return nil, err
+	}
+	return apikey.NewProvider(store, cfg, apikey.WithLogger(g.logger)), nil
+}
+
+// basicProvider creates the provider for a basic auth declaration, with
+// credentials from its file or from the user model in the configured
+// database.
+func (g *generator) basicProvider(loaded *auth.LoadedAuth) (*basic.Provider, error) {
+	cfg, err := basic.ConfigFromSettings(loaded.Settings)
+	if err != nil {
+		return nil, err
+	}
+
+	var store basic.CredentialStore
+	switch {
+	case cfg.File != "":
+		if store, err = basic.LoadFile(cfg.File); err != nil {
+			return nil, err
+		}
+	case cfg.Model != "":
+		if store, err = g.credentialStore(cfg); err != nil {
+			return nil, err
+		}
+	default:
+		g.logger.Warn("basic auth provider has no file or model; all credentials are rejected", "auth", loaded.Name)
+		store = &basic.FileStore{}
+	}
+	return basic.NewProvider(store, cfg, basic.WithLogger(g.logger)), nil
+}
+
+// tokenIssuer creates the issuer for a jwt declaration with an issuer block,
+// authenticating users from its file or user model.
+func (g *generator) tokenIssuer(loaded *auth.LoadedAuth) (*issuer.Issuer, error) {
+	cfg, err := issuer.ConfigFromSettings(loaded.IssuerSettings)
+	if err != nil {
+		return nil, err
+	}
+
+	var credentials basic.CredentialStore
+	switch {
+	case cfg.Credentials.File != "":
+		credentials, err = basic.LoadFile(cfg.Credentials.File)
+	case cfg.Credentials.Model != "":
+		credentials, err = g.credentialStore(cfg.Credentials)
+	default:
+		err = fmt.Errorf("issuer requires a model or file")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	storage := configString(loaded.IssuerSettings, "storage")
+	if storage == "" {
+		storage = "database"
+	}
+	store, err := g.issuerStore(storage)
+	if err != nil {
+		return nil, err
+	}
+	return issuer.NewIssuer(credentials, store, cfg, issuer.WithLogger(g.logger))
+}
+
+// credentialStore returns the store reading basic auth credentials from the
+// table or collection of cfg.Model.
+func (g *generator) credentialStore(cfg basic.Config) (basic.CredentialStore, error) {
+	table := schema.TableName(cfg.Model)
+	if pg, ok := g.config.DBConnection.(*database.PostgresConnection); ok && pg.DB != nil {
+		return basic.NewSQLStore(pg.DB, table, cfg.Fields)
+	}
+	if g.config.DBConnection != nil {
+		if client, ok := g.config.DBConnection.MongoClient().(*mongodb.Client); ok {
+			return basic.NewMongoStore(client.Database(), table, cfg.Fields)
+		}
+	}
+	return nil, fmt.Errorf("model %s requires a database connection", cfg.Model)
+}
+
+// introspectionProvider creates the provider for an oauth2 declaration with
+// an introspection_url. Responses are cached in memory, or in Redis with
+// "cache: redis".
+func (g *generator) introspectionProvider(loaded *auth.LoadedAuth) (*introspection.Provider, error) {
+	cfg, err := introspection.ConfigFromSettings(loaded.Settings)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []introspection.Option{introspection.WithLogger(g.logger)}
+	switch storage := configString(loaded.Settings, "cache"); storage {
+	case "", "memory":
+	case "redis":
+		c, err := cache.NewRedisCache(cache.Config{URL: g.config.RedisURL})
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, introspection.WithCache(c))
+	default:
+		return nil, fmt.Errorf("unknown introspection cache %q (expected memory or redis)", storage)
+	}
+	return introspection.NewProvider(cfg, opts...), nil
+}
+
+// apiKeyStore returns the key store for the storage setting of an apikey
+// provider: "database" keeps keys in the configured PostgreSQL or MongoDB
+// database, "memory" in process memory.
+func (g *generator) apiKeyStore(storage string) (apikey.Store, error) {
+	if g.config.APIKeyStore != nil {
+		return g.config.APIKeyStore, nil
+	}
+
+	switch storage {
+	case "memory":
+		return apikey.NewMemoryStore(), nil
+	case "database":
+	default:
+		return nil, fmt.Errorf("unknown api key storage %q (expected database or memory)", storage)
+	}
+
+	ctx := context.Background()
+	if pg, ok := g.config.DBConnection.(*database.PostgresConnection); ok && pg.DB != nil {
+		store := apikey.NewSQLStore(pg.DB)
+		if err := store.CreateTable(ctx); err != nil {
+			return nil, err
+		}
+		return store, nil
+	}
+	if g.config.DBConnection != nil {
+		if client, ok := g.config.DBConnection.MongoClient().(*mongodb.Client); ok {
+			store := apikey.NewMongoStore(client.Database())
+			if err := store.EnsureIndexes(ctx); err != nil {
+				return nil, err
+			}
+			return store, nil
+		}
+	}
+
+	g.logger.Warn("no database configured, api keys are kept in memory")
+	return apikey.NewMemoryStore(), nil
+}
+
+// issuerStore returns the store for refresh tokens, revocations and signing
+// keys of a token issuer, following the storage setting like apiKeyStore.
+// With memory storage, sessions and keys don't survive restarts and are not
+// shared between instances.
+func (g *generator) issuerStore(storage string) (issuer.Store, error) {
+	if g.config.IssuerStore != nil {
+		return g.config.IssuerStore, nil
+	}
+
+	switch storage {
+	case "memory":
+		return issuer.NewMemoryStore(), nil
+	case "database":
+	default:
+		return nil, fmt.Errorf("unknown issuer storage %q (expected database or memory)", storage)
+	}
+
+	ctx := context.Background()
+	if pg, ok := g.config.DBConnection.(*database.PostgresConnection); ok && pg.DB != nil {
+		store := issuer.NewSQLStore(pg.DB)
+		if err := store.CreateTables(ctx); err != nil {
+			return nil, err
+		}
+		return store, nil
+	}
+	if g.config.DBConnection != nil {
+		if client, ok := g.config.DBConnection.MongoClient().(*mongodb.Client); ok {
+			store := issuer.NewMongoStore(client.Database())
+			if err := store.EnsureIndexes(ctx); err != nil {
+				return nil, err
+			}
+			return store, nil
+		}
+	}
+
+	g.logger.Warn("no database configured, issued sessions and signing keys are kept in memory")
+	return issuer.NewMemoryStore(), nil
+}
@@ -2,6 +2,7 @@ package codegen
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -10,7 +11,9 @@ import (
 
 	"github.com/go-chi/chi/v5"
 
+	"github.com/bargom/codeai/internal/abac"
 	"github.com/bargom/codeai/internal/ast"
+	"github.com/bargom/codeai/internal/tenant"
 )
 
 // GenerateEndpointHandler generates an HTTP handler from an endpoint declaration.
@@ -28,15 +31,25 @@ func GenerateEndpointHandler(ep *ast.EndpointDecl, factory *ExecutionContextFact
 				writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
 				return
 			}
+			execCtx.SetRequestType(ep.Handler.Request)
 			execCtx.SetInput(requestData)
 		}
 
 		// 2. Execute logic steps
 		if ep.Handler != nil && ep.Handler.Logic != nil {
-			result, err := executeLogicSteps(execCtx, ep.Handler.Logic.Steps)
+			result, err := executeEndpointLogic(execCtx, ep.Handler.Logic.Steps)
 			if err != nil {
 				// Determine appropriate status code based on error type
 				statusCode := determineErrorStatusCode(err)
+				var schemaErr *SchemaValidationError
+				if errors.As(err, &schemaErr) {
+					writeJSON(w, statusCode, map[string]interface{}{
+						"error":   http.StatusText(statusCode),
+						"message": fmt.Sprintf("request does not match %s", schemaErr.Schema),
+						"details": schemaErr.Errors,
+					})
+					return
+				}
 				writeError(w, statusCode, err.Error())
 				return
 			}
@@ -183,38 +196,80 @@ func executeLogicStep(ctx *ExecutionContext, step *ast.LogicStep) error {
 	}
 }
 
-// executeValidate validates input data.
+// executeValidate validates input data against a model or collection.
+// validate(request) checks the request against the model named by the
+// endpoint's request type, validate(x) checks x against the model named x,
+// and validate(x, Model) names the model explicitly. Unknown models are
+// errors, except for validate(x), where x need only be present, and for
+// programs that declare no models or collections at all.
 func executeValidate(ctx *ExecutionContext, step *ast.LogicStep) error {
 	// Get the target to validate
 	var target interface{}
+	schemaName := ""
+	required := ctx.hasSchemas()
 	if len(step.Args) > 0 {
 		argName := step.Args[0]
 		if argName == "request" || argName == "input" {
 			target = ctx.Input()
 		} else {
 			target = ctx.Get(argName)
+			schemaName = argName
+			required = false
 		}
 	} else {
 		target = ctx.Input()
 	}
+	if len(step.Args) > 1 {
+		schemaName = step.Args[1]
+		required = true
+	} else if schemaName == "" {
+		if req := ctx.RequestType(); req != nil {
+			schemaName = req.TypeName
+		}
+	}
 
 	if target == nil {
 		return &ValidationError{Field: "request", Message: "no data to validate"}
 	}
 
-	// For now, just verify the data exists
-	// TODO: Add schema-based validation
-	return nil
+	schema := ctx.lookupSchema(schemaName)
+	if schema == nil {
+		if !required {
+			return nil
+		}
+		if schemaName == "" {
+			return fmt.Errorf("validate: no model or collection to validate against")
+		}
+		return fmt.Errorf("validate: unknown model or collection %q", schemaName)
+	}
+
+	data, ok := target.(map[string]interface{})
+	if !ok {
+		return &ValidationError{Field: "request", Message: fmt.Sprintf("expected an object to validate against %s", schema.name)}
+	}
+
+	return validateRecord(ctx, schema, data)
 }
 
-// executeAuthorize checks authorization.
+// executeAuthorize checks authorization. The second argument is either a
+// policy, evaluated against the resource named by the first argument, or a
+// required role.
 func executeAuthorize(ctx *ExecutionContext, step *ast.LogicStep) error {
 	// Extract required role/permission from args
 	if len(step.Args) < 2 {
 		return nil // No specific role required
 	}
 
+	if policies := ctx.policies(); policies != nil {
+		if _, ok := policies.Policy(step.Args[1]); ok {
+			return authorizePolicy(ctx, policies, step.Args[1], step.Args[0])
+		}
+	}
+
 	requiredRole := step.Args[1]
+	if engine := ctx.rbac(); engine != nil && ctx.User() != nil {
+		return authorizeRole(ctx, engine, requiredRole)
+	}
 	claims := ctx.Claims()
 
 	if claims == nil {
@@ -248,7 +303,26 @@ func executeAuthorize(ctx *ExecutionContext, step *ast.LogicStep) error {
 	}
 }
 
-// executeDBFind executes a database query returning multiple results.
+// authorizePolicy evaluates a policy against the resource stored under
+// resourceVar, or the request input for "request" and "input".
+func authorizePolicy(ctx *ExecutionContext, policies *abac.Engine, name, resourceVar string) error {
+	var resource interface{}
+	switch resourceVar {
+	case "request", "input":
+		resource = ctx.Input()
+	default:
+		resource = ctx.Get(resourceVar)
+	}
+
+	err := policies.Authorize(ctx.User(), name, resource, ctx.Input())
+	if errors.Is(err, abac.ErrAccessDenied) {
+		return &AuthorizationError{Message: "access denied", Policy: name}
+	}
+	return err
+}
+
+// executeDBFind executes a database query returning multiple results. With an
+// authorize option, only the rows the named policy allows are returned.
 func executeDBFind(ctx *ExecutionContext, step *ast.LogicStep) error {
 	tableName := ""
 	if len(step.Args) > 0 {
@@ -263,7 +337,13 @@ func executeDBFind(ctx *ExecutionContext, step *ast.LogicStep) error {
 	}
 
 	// Execute query through execution context
-	result, err := ctx.QueryDatabase(tableName, conditions)
+	var result interface{}
+	var err error
+	if policy := stringOption(step, "authorize"); policy != "" {
+		result, err = queryAuthorized(ctx, tableName, conditions, policy)
+	} else {
+		result, err = ctx.QueryDatabase(tableName, conditions)
+	}
 	if err != nil {
 		return fmt.Errorf("database query failed: %w", err)
 	}
@@ -278,6 +358,35 @@ func executeDBFind(ctx *ExecutionContext, step *ast.LogicStep) error {
 	return nil
 }
 
+// queryAuthorized queries the rows of table the named policy allows.
+func queryAuthorized(ctx *ExecutionContext, table string, conditions map[string]interface{}, policy string) (interface{}, error) {
+	policies := ctx.policies()
+	if policies == nil {
+		return nil, fmt.Errorf("%w: %s", abac.ErrPolicyNotFound, policy)
+	}
+	filter, err := policies.Filter(ctx.User(), policy, ctx.Input())
+	if err != nil {
+		return nil, err
+	}
+	if filter.None {
+		return []map[string]interface{}{}, nil
+	}
+	return ctx.QueryDatabaseWhere(table, conditions, filter.Where)
+}
+
+// stringOption returns the value of a string literal option of step.
+func stringOption(step *ast.LogicStep, key string) string {
+	for _, opt := range step.Options {
+		if opt.Key != key {
+			continue
+		}
+		if lit, ok := opt.Value.(*ast.StringLiteral); ok {
+			return lit.Value
+		}
+	}
+	return ""
+}
+
 // executeDBFindOne executes a database query returning a single result.
 func executeDBFindOne(ctx *ExecutionContext, step *ast.LogicStep) error {
 	tableName := ""
@@ -310,7 +419,7 @@ func executeDBFindOne(ctx *ExecutionContext, step *ast.LogicStep) error {
 	}
 
 	if result == nil {
-		return &NotFoundError{Resource: tableName}
+		return &NotFoundError{Resource: tableName, ID: id}
 	}
 
 	// Store result
@@ -396,14 +505,18 @@ func executeDBUpdate(ctx *ExecutionContext, step *ast.LogicStep) error {
 	}
 
 	// Execute update
-	err := ctx.UpdateDatabase(tableName, id, data)
+	result, err := ctx.UpdateDatabase(tableName, id, data)
 	if err != nil {
 		return fmt.Errorf("database update failed: %w", err)
 	}
 
 	// Store success result
 	if step.Target != "" {
-		ctx.Set(step.Target, map[string]interface{}{"updated": true})
+		ctx.Set(step.Target, map[string]interface{}{
+			"updated":  true,
+			"matched":  result.Matched,
+			"modified": result.Modified,
+		})
 	}
 
 	return nil
@@ -611,12 +724,19 @@ func parseCondition(condition string, ctx *ExecutionContext) map[string]interfac
 
 // determineErrorStatusCode determines the HTTP status code for an error.
 func determineErrorStatusCode(err error) int {
-	switch err.(type) {
-	case *ValidationError:
+	var schemaErr *SchemaValidationError
+	var validationErr *ValidationError
+	var authErr *AuthorizationError
+	var notFoundErr *NotFoundError
+
+	switch {
+	case errors.As(err, &schemaErr):
+		return http.StatusUnprocessableEntity
+	case errors.As(err, &validationErr):
 		return http.StatusBadRequest
-	case *AuthorizationError:
+	case errors.As(err, &authErr), errors.Is(err, tenant.ErrCrossTenant):
 		return http.StatusForbidden
-	case *NotFoundError:
+	case errors.As(err, &notFoundErr):
 		return http.StatusNotFound
 	default:
 		return http.StatusInternalServerError
@@ -657,9 +777,13 @@ func (e *ValidationError) Error() string {
 type AuthorizationError struct {
 	Message      string
 	RequiredRole string
+	Policy       string
 }
 
 func (e *AuthorizationError) Error() string {
+	if e.Policy != "" {
+		return fmt.Sprintf("authorization failed: %s (policy: %s)", e.Message, e.Policy)
+	}
 	if e.RequiredRole != "" {
 		return fmt.Sprintf("authorization failed: %s (required role: %s)", e.Message, e.RequiredRole)
 	}
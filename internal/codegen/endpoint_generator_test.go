@@ -2,12 +2,15 @@ package codegen
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 
+	"github.com/bargom/codeai/internal/abac"
 	"github.com/bargom/codeai/internal/ast"
+	"github.com/bargom/codeai/internal/auth"
 )
 
 func TestExtractRequestData_Body(t *testing.T) {
@@ -305,6 +308,49 @@ func TestParseCondition(t *testing.T) {
 	}
 }
 
+func TestExecuteDBSteps(t *testing.T) {
+	adapter, _ := newTestAdapter(t)
+	factory := NewExecutionContextFactory(&GeneratedCode{ModelRegistry: testPostgresRegistry()})
+	factory.db = adapter
+	ctx := factory.NewContext(context.Background(), httptest.NewRequest("PUT", "/users/1", nil))
+	ctx.SetInput(map[string]interface{}{"id": "1", "email": "new@example.com"})
+
+	err := executeDBFindOne(ctx, &ast.LogicStep{Action: "findOne", Args: []string{"User", "request.id"}})
+	if determineErrorStatusCode(err) != http.StatusNotFound {
+		t.Errorf("expected 404 for missing record, got %v", err)
+	}
+
+	err = executeDBUpdate(ctx, &ast.LogicStep{Action: "update", Args: []string{"User", "request.id"}, Target: "result"})
+	if determineErrorStatusCode(err) != http.StatusNotFound {
+		t.Errorf("expected 404 updating missing record, got %v", err)
+	}
+
+	if _, err := adapter.Insert(context.Background(), "User", map[string]interface{}{"email": "old@example.com"}); err != nil {
+		t.Fatalf("insert failed: %v", err)
+	}
+
+	err = executeDBUpdate(ctx, &ast.LogicStep{Action: "update", Args: []string{"User", "request.id"}, Target: "result"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result, ok := ctx.Get("result").(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected update result map, got %T", ctx.Get("result"))
+	}
+	if result["matched"] != int64(1) || result["modified"] != int64(1) {
+		t.Errorf("expected matched and modified counts of 1, got %v", result)
+	}
+
+	err = executeDBDelete(ctx, &ast.LogicStep{Action: "delete", Args: []string{"User", "request.id"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = executeDBDelete(ctx, &ast.LogicStep{Action: "delete", Args: []string{"User", "request.id"}})
+	if determineErrorStatusCode(err) != http.StatusNotFound {
+		t.Errorf("expected 404 deleting twice, got %v", err)
+	}
+}
+
 func TestDetermineErrorStatusCode(t *testing.T) {
 	tests := []struct {
 		err      error
@@ -313,6 +359,7 @@ func TestDetermineErrorStatusCode(t *testing.T) {
 		{&ValidationError{}, http.StatusBadRequest},
 		{&AuthorizationError{}, http.StatusForbidden},
 		{&NotFoundError{}, http.StatusNotFound},
+		{fmt.Errorf("database update failed: %w", &NotFoundError{}), http.StatusNotFound},
 		{context.DeadlineExceeded, http.StatusInternalServerError},
 	}
 
@@ -403,3 +450,78 @@ func TestWriteError(t *testing.T) {
 		t.Errorf("expected body to contain message: %s", body)
 	}
 }
+
+func newPolicyContext(t *testing.T, user *auth.User) *ExecutionContext {
+	t.Helper()
+
+	policies := abac.NewEngine()
+	if err := policies.Add("post.update", `resource.author_id == auth.sub or auth.role == "admin"`); err != nil {
+		t.Fatalf("adding policy: %v", err)
+	}
+	factory := NewExecutionContextFactory(&GeneratedCode{
+		Middlewares:   make(map[string]func(http.Handler) http.Handler),
+		ModelRegistry: NewTypeRegistry(),
+		Policies:      policies,
+	})
+
+	req := httptest.NewRequest("PUT", "/posts/p1", nil)
+	if user != nil {
+		req = req.WithContext(auth.ContextWithUser(req.Context(), user))
+	}
+	ctx := factory.NewContext(req.Context(), req)
+	ctx.Set("post", map[string]interface{}{"id": "p1", "author_id": "u1"})
+	return ctx
+}
+
+func TestExecuteAuthorize_Policy(t *testing.T) {
+	step := &ast.LogicStep{
+		Action: "authorize",
+		Args:   []string{"post", "post.update"},
+	}
+
+	tests := []struct {
+		name    string
+		user    *auth.User
+		allowed bool
+	}{
+		{"author", &auth.User{ID: "u1", Roles: []string{"writer"}}, true},
+		{"admin", &auth.User{ID: "u2", Roles: []string{"admin"}}, true},
+		{"other user", &auth.User{ID: "u3", Roles: []string{"writer"}}, false},
+		{"anonymous", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := executeAuthorize(newPolicyContext(t, tt.user), step)
+			if tt.allowed {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			authErr, ok := err.(*AuthorizationError)
+			if !ok {
+				t.Fatalf("expected AuthorizationError, got %T (%v)", err, err)
+			}
+			if authErr.Policy != "post.update" {
+				t.Errorf("expected policy post.update, got %q", authErr.Policy)
+			}
+			if code := determineErrorStatusCode(err); code != http.StatusForbidden {
+				t.Errorf("expected status 403, got %d", code)
+			}
+		})
+	}
+}
+
+func TestExecuteAuthorize_RoleWithPolicies(t *testing.T) {
+	ctx := newPolicyContext(t, &auth.User{ID: "u1", Roles: []string{"admin"}})
+
+	step := &ast.LogicStep{
+		Action: "authorize",
+		Args:   []string{"request", "admin"},
+	}
+	if err := executeAuthorize(ctx, step); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
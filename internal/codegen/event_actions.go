@@ -0,0 +1,204 @@
+package codegen
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bargom/codeai/internal/ast"
+	"github.com/bargom/codeai/internal/event"
+	"github.com/bargom/codeai/internal/event/bus"
+	"github.com/bargom/codeai/internal/event/dispatcher"
+	"github.com/bargom/codeai/internal/event/subscribers"
+	"github.com/bargom/codeai/internal/tenant"
+	"github.com/bargom/codeai/internal/webhook/repository"
+	"github.com/bargom/codeai/internal/webhook/service"
+	"github.com/bargom/codeai/internal/webhook/subscriber"
+	"github.com/bargom/codeai/internal/workflow"
+	"github.com/bargom/codeai/pkg/integration/webhook"
+)
+
+// eventActionOptions connects event handler actions to the generated
+// integrations and webhooks and the configured workflow engine.
+func (g *generator) eventActionOptions(code *GeneratedCode) []event.RegistryOption {
+	opts := []event.RegistryOption{
+		event.WithRegistryLogger(g.logger),
+		event.WithIntegrationCaller(code.Integrations),
+		event.WithWebhookSender(dslWebhookSender{service: code.Webhooks}),
+	}
+	if g.config.WorkflowEngine != nil {
+		opts = append(opts, event.WithWorkflowStarter(
+			workflow.NewDSLWorkflowStarter(code.Workflows, g.config.WorkflowEngine),
+		))
+	}
+	return opts
+}
+
+// newEventRegistry creates the event registry on top of the event bus, so
+// that events emitted by endpoints and handlers are upcast to the latest
+// version of their event and validated against its schema, persisted when
+// an event repository is configured, and published to the metrics, logging
+// and webhook subscribers as well as to the DSL handlers. With an outbox,
+// the registry appends events to the outbox and the relay publishes them.
+func (g *generator) newEventRegistry(code *GeneratedCode) *event.EventRegistry {
+	opts := []dispatcher.Option{dispatcher.WithLogger(g.logger)}
+	if g.config.EventRepository != nil {
+		opts = append(opts, dispatcher.WithRepository(g.config.EventRepository))
+	}
+	code.EventBus = bus.NewEventBus(g.logger)
+	code.Events = dispatcher.NewDispatcher(code.EventBus, opts...)
+	code.EventMetrics = subscribers.NewMetricsSubscriber()
+	code.Events.Subscribe(bus.AllEvents, code.EventMetrics)
+	code.Events.Subscribe(bus.AllEvents, subscribers.NewLoggingSubscriber(g.logger))
+	code.Webhooks = g.newWebhookService(code)
+	code.Events.Subscribe(bus.AllEvents, subscriber.NewWebhookEventSubscriber(code.Webhooks, subscriber.WithLogger(g.logger)))
+
+	var events event.Dispatcher = event.NewBusDispatcher(code.Events, dslEventSource)
+	if g.config.Outbox != nil {
+		code.Outbox = g.config.Outbox
+		code.Relay = g.newOutboxRelay(code)
+		events = outboxDispatcher{Dispatcher: events, store: code.Outbox, relay: code.Relay}
+	}
+
+	registry := event.NewEventRegistry(events, g.eventActionOptions(code)...)
+	// The registry holds the schemas, so it upcasts and validates after it
+	// is created.
+	code.Events.UseUpcaster(registry)
+	code.Events.UseValidator(registry)
+	code.EventBus.UseUpcaster(registry)
+	return registry
+}
+
+// dslEventSource returns the source of events emitted from the DSL, which
+// names the tenant in ctx if there is one.
+func dslEventSource(ctx context.Context) string {
+	return tenant.Source(ctx, "codeai.dsl")
+}
+
+// newWebhookService creates the webhook service on the configured webhook
+// repository, or on one keeping webhooks and delivery records in memory.
+// Webhooks disabled for failing are announced on code.Events as
+// webhook.disabled.
+func (g *generator) newWebhookService(code *GeneratedCode) *service.WebhookService {
+	repo := g.config.WebhookRepository
+	if repo == nil {
+		repo = repository.NewMemoryRepository()
+	}
+	opts := append([]service.Option{service.WithLogger(g.logger)}, g.config.WebhookOptions...)
+	opts = append(opts, service.WithEventPublisher(code.Events))
+	return service.NewWebhookService(webhook.NewClient(webhook.DefaultConfig()), repo, opts...)
+}
+
+// loadWebhooks registers the webhooks declared in the DSL with the webhook
+// service so that delivery records and failure counts are kept for them.
+func (g *generator) loadWebhooks(program *ast.Program, code *GeneratedCode) error {
+	ctx := context.Background()
+	for _, stmt := range program.Statements {
+		decl, ok := stmt.(*ast.WebhookDecl)
+		if !ok {
+			continue
+		}
+		config, err := webhookConfigFromDecl(decl)
+		if err != nil {
+			return fmt.Errorf("webhook %q: %w", decl.Name, err)
+		}
+		if err := code.Webhooks.EnsureWebhook(ctx, config); err != nil {
+			return fmt.Errorf("registering webhook %q: %w", decl.Name, err)
+		}
+		g.logger.Debug("loaded webhook", "name", decl.Name, "url", decl.URL)
+	}
+	return nil
+}
+
+// checkEventHandlerTarget warns about handlers whose action cannot run.
+func (g *generator) checkEventHandlerTarget(decl *ast.EventHandlerDecl, code *GeneratedCode) {
+	switch decl.ActionType {
+	case "workflow":
+		if g.config.WorkflowEngine == nil {
+			g.logger.Warn("no workflow engine configured, handler will fail",
+				"event", decl.EventName, "workflow", decl.Target)
+		} else if _, ok := code.Workflows.Get(decl.Target); !ok {
+			g.logger.Warn("handler references unknown workflow",
+				"event", decl.EventName, "workflow", decl.Target)
+		}
+	case "webhook":
+		if _, err := code.Webhooks.GetWebhook(context.Background(), dslWebhookID(decl.Target)); err != nil {
+			g.logger.Warn("handler references unknown webhook",
+				"event", decl.EventName, "webhook", decl.Target)
+		}
+	}
+}
+
+// webhookConfigFromDecl converts a webhook declaration to the webhook
+// service's configuration.
+func webhookConfigFromDecl(decl *ast.WebhookDecl) (*repository.WebhookConfig, error) {
+	headers := make(map[string]string, len(decl.Headers))
+	for _, h := range decl.Headers {
+		headers[h.Key] = h.Value
+	}
+
+	config := &repository.WebhookConfig{
+		ID:      dslWebhookID(decl.Name),
+		URL:     decl.URL,
+		Events:  []bus.EventType{bus.EventType(decl.Event)},
+		Headers: headers,
+		Method:  string(decl.Method),
+		Active:  true,
+		Metadata: map[string]interface{}{
+			"source": "dsl",
+			"name":   decl.Name,
+		},
+	}
+
+	if decl.Retry != nil {
+		policy := webhook.DefaultRetryPolicy()
+		if decl.Retry.MaxAttempts > 0 {
+			policy.MaxAttempts = decl.Retry.MaxAttempts
+		}
+		if decl.Retry.InitialInterval != "" {
+			interval, err := time.ParseDuration(decl.Retry.InitialInterval)
+			if err != nil {
+				return nil, fmt.Errorf("invalid initial_interval %q: %w", decl.Retry.InitialInterval, err)
+			}
+			policy.InitialBackoff = interval
+		}
+		if decl.Retry.BackoffMultiplier > 0 {
+			policy.Multiplier = decl.Retry.BackoffMultiplier
+		}
+		config.RetryPolicy = policy
+	}
+
+	return config, nil
+}
+
+// dslWebhookID returns the stable ID of the webhook declared as name.
+func dslWebhookID(name string) string {
+	return uuid.NewSHA1(uuid.NameSpaceURL, []byte("codeai:webhook:"+name)).String()
+}
+
+// dslWebhookSender delivers "do webhook" handlers through the webhook service.
+type dslWebhookSender struct {
+	service *service.WebhookService
+}
+
+// SendWebhook implements event.WebhookSender. The delivered event keeps the
+// ID and source of the emitted event; events emitted on behalf of a tenant
+// carry it in their metadata.
+func (s dslWebhookSender) SendWebhook(ctx context.Context, name string, ev event.Event, async bool) error {
+	busEvent := event.ToBusEvent(ev)
+	if busEvent.Source == "" {
+		busEvent.Source = dslEventSource(ctx)
+	}
+	metadata := make(map[string]string, len(ev.Metadata)+2)
+	for k, v := range ev.Metadata {
+		metadata[k] = v
+	}
+	metadata["webhook"] = name
+	if id := tenant.FromContext(ctx); id != "" {
+		metadata["tenant"] = id
+	}
+	busEvent.Metadata = metadata
+	return s.service.DeliverWebhook(ctx, dslWebhookID(name), busEvent, async)
+}
@@ -0,0 +1,309 @@
+package codegen
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/bargom/codeai/internal/event/bus"
+	"github.com/bargom/codeai/internal/event/dispatcher"
+	eventrepository "github.com/bargom/codeai/internal/event/repository"
+	"github.com/bargom/codeai/internal/parser"
+	"github.com/bargom/codeai/internal/webhook/repository"
+	"github.com/bargom/codeai/internal/webhook/service"
+)
+
+type recordedRequest struct {
+	method string
+	path   string
+	body   map[string]interface{}
+}
+
+// recordingServer records the requests it receives.
+func recordingServer(t *testing.T) (*httptest.Server, func() []recordedRequest) {
+	t.Helper()
+	var mu sync.Mutex
+	var requests []recordedRequest
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		var body map[string]interface{}
+		_ = json.Unmarshal(data, &body)
+
+		mu.Lock()
+		requests = append(requests, recordedRequest{method: r.Method, path: r.URL.Path, body: body})
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, func() []recordedRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]recordedRequest(nil), requests...)
+	}
+}
+
+func TestEventHandlerActions(t *testing.T) {
+	crm, crmRequests := recordingServer(t)
+	hooks, hookRequests := recordingServer(t)
+
+	input := fmt.Sprintf(`
+integration crm {
+	type rest
+	base_url "%s"
+}
+
+webhook shipping {
+	event "order_created"
+	url "%s/shipping"
+	method PUT
+}
+
+event order_created {
+	schema {
+		order_id string
+	}
+}
+
+on "order_created" do integration "crm.update_contact"
+on "order_created" do webhook "shipping"
+`, crm.URL, hooks.URL)
+
+	program, err := parser.Parse(input)
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	code, err := NewGenerator(nil).GenerateFromAST(program)
+	if err != nil {
+		t.Fatalf("code generation failed: %v", err)
+	}
+
+	ctx := context.Background()
+	payload := map[string]interface{}{"order_id": "o-1"}
+	if err := code.EventHandlers.EmitEvent(ctx, "order_created", payload); err != nil {
+		t.Fatalf("emit failed: %v", err)
+	}
+
+	got := crmRequests()
+	if len(got) != 1 {
+		t.Fatalf("expected 1 integration call, got %d", len(got))
+	}
+	if got[0].method != http.MethodPost || got[0].path != "/update_contact" {
+		t.Errorf("expected POST /update_contact, got %s %s", got[0].method, got[0].path)
+	}
+	if got[0].body["order_id"] != "o-1" {
+		t.Errorf("expected payload to be sent, got %v", got[0].body)
+	}
+
+	got = hookRequests()
+	if len(got) != 1 {
+		t.Fatalf("expected 1 webhook delivery, got %d", len(got))
+	}
+	if got[0].method != http.MethodPut || got[0].path != "/shipping" {
+		t.Errorf("expected PUT /shipping, got %s %s", got[0].method, got[0].path)
+	}
+	if got[0].body["type"] != "order_created" {
+		t.Errorf("expected event type in webhook body, got %v", got[0].body)
+	}
+	data, _ := got[0].body["data"].(map[string]interface{})
+	if data["order_id"] != "o-1" {
+		t.Errorf("expected event data in webhook body, got %v", got[0].body)
+	}
+
+	deliveries, err := code.Webhooks.GetDeliveries(ctx, dslWebhookID("shipping"), repository.DeliveryFilter{})
+	if err != nil {
+		t.Fatalf("listing deliveries: %v", err)
+	}
+	if len(deliveries) != 1 || !deliveries[0].Success {
+		t.Errorf("expected 1 successful delivery record, got %+v", deliveries)
+	}
+}
+
+func TestEventHandlerActions_NoWorkflowEngine(t *testing.T) {
+	input := `
+event order_created {
+}
+
+on "order_created" do workflow "fulfil"
+`
+	program, err := parser.Parse(input)
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	code, err := NewGenerator(nil).GenerateFromAST(program)
+	if err != nil {
+		t.Fatalf("code generation failed: %v", err)
+	}
+
+	handlers := code.EventHandlers.GetHandlers("order_created")
+	if len(handlers) != 1 {
+		t.Fatalf("expected 1 handler, got %d", len(handlers))
+	}
+	// The dispatcher drops handler errors, so emitting still succeeds
+	if err := code.EventHandlers.EmitEvent(context.Background(), "order_created", nil); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+// recordingEventRepository records the events it is asked to save.
+type recordingEventRepository struct {
+	eventrepository.EventRepository
+	mu     sync.Mutex
+	events []bus.Event
+}
+
+func (r *recordingEventRepository) SaveEvent(_ context.Context, event bus.Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+	return nil
+}
+
+func TestEventPipeline(t *testing.T) {
+	dslHooks, dslRequests := recordingServer(t)
+	apiHooks, apiRequests := recordingServer(t)
+
+	program, err := parser.Parse(fmt.Sprintf(`
+webhook shipping {
+	event "order_created"
+	url "%s/shipping"
+	method POST
+}
+
+event order_created {
+	schema {
+		order_id string
+	}
+}
+
+on "order_created" do webhook "shipping"
+`, dslHooks.URL))
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	repo := &recordingEventRepository{}
+	code, err := NewGenerator(&Config{EventRepository: repo}).GenerateFromAST(program)
+	if err != nil {
+		t.Fatalf("code generation failed: %v", err)
+	}
+
+	ctx := context.Background()
+	if _, err := code.Webhooks.RegisterWebhook(ctx, service.RegisterWebhookRequest{
+		URL:    apiHooks.URL + "/orders",
+		Events: []bus.EventType{"order_created"},
+	}); err != nil {
+		t.Fatalf("registering webhook: %v", err)
+	}
+
+	if err := code.EventHandlers.EmitEvent(ctx, "order_created", map[string]interface{}{"order_id": "o-1"}); err != nil {
+		t.Fatalf("emit failed: %v", err)
+	}
+
+	if len(repo.events) != 1 || repo.events[0].Type != "order_created" {
+		t.Fatalf("expected the event to be persisted, got %+v", repo.events)
+	}
+	if got := code.EventMetrics.GetTypeCount("order_created"); got != 1 {
+		t.Errorf("expected 1 event counted, got %d", got)
+	}
+
+	dsl := dslRequests()
+	if len(dsl) != 1 {
+		t.Fatalf("expected 1 delivery to the DSL webhook, got %d", len(dsl))
+	}
+	if dsl[0].body["id"] != repo.events[0].ID {
+		t.Errorf("expected the delivered event to keep the persisted ID %s, got %v", repo.events[0].ID, dsl[0].body["id"])
+	}
+	if api := apiRequests(); len(api) != 1 || api[0].path != "/orders" {
+		t.Errorf("expected 1 delivery to the registered webhook, got %+v", api)
+	}
+
+	// Events published on the bus directly are validated against the schema.
+	err = code.Events.Dispatch(ctx, bus.Event{ID: "e-2", Type: "order_created", Data: map[string]interface{}{"order_id": 7}})
+	if !errors.Is(err, dispatcher.ErrInvalidEvent) {
+		t.Errorf("expected ErrInvalidEvent, got %v", err)
+	}
+	if len(repo.events) != 1 {
+		t.Errorf("expected the invalid event not to be persisted, got %d events", len(repo.events))
+	}
+}
+
+func TestEventPipelineWebhookRules(t *testing.T) {
+	apiHooks, apiRequests := recordingServer(t)
+
+	program, err := parser.Parse(`
+event order.created {
+	schema {
+		order_id string
+		total decimal
+		currency string
+	}
+}
+
+event order.shipped {
+	schema {
+		order_id string
+	}
+}
+
+event user.created {
+	schema {
+		user_id string
+	}
+}
+`)
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	code, err := NewGenerator(&Config{}).GenerateFromAST(program)
+	if err != nil {
+		t.Fatalf("code generation failed: %v", err)
+	}
+	t.Cleanup(code.Events.Close)
+
+	ctx := context.Background()
+	if _, err := code.Webhooks.RegisterWebhook(ctx, service.RegisterWebhookRequest{
+		URL:        apiHooks.URL + "/orders",
+		Events:     []bus.EventType{"order.*"},
+		Filter:     `event.type == "order.shipped" || (payload.total > 1000 && payload.currency == "EUR")`,
+		Projection: map[string]string{"order": "payload.order_id"},
+	}); err != nil {
+		t.Fatalf("registering webhook: %v", err)
+	}
+
+	emits := []struct {
+		name    string
+		payload map[string]interface{}
+	}{
+		{"order.created", map[string]interface{}{"order_id": "o-1", "total": 50.0, "currency": "EUR"}},
+		{"order.created", map[string]interface{}{"order_id": "o-2", "total": 1500.0, "currency": "EUR"}},
+		{"order.shipped", map[string]interface{}{"order_id": "o-3"}},
+		{"user.created", map[string]interface{}{"user_id": "u-1"}},
+	}
+	for _, e := range emits {
+		if err := code.EventHandlers.EmitEvent(ctx, e.name, e.payload); err != nil {
+			t.Fatalf("emit %s failed: %v", e.name, err)
+		}
+	}
+
+	requests := apiRequests()
+	if len(requests) != 2 {
+		t.Fatalf("expected 2 deliveries matching the pattern and filter, got %d", len(requests))
+	}
+	for i, want := range []string{"o-2", "o-3"} {
+		data, _ := requests[i].body["data"].(map[string]interface{})
+		if len(data) != 1 || data["order"] != want {
+			t.Errorf("expected delivery %d to carry the projected payload {order: %s}, got %v", i, want, data)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package codegen
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/bargom/codeai/internal/ast"
+	"github.com/bargom/codeai/internal/event/bus"
+	"github.com/bargom/codeai/internal/event/transport"
+)
+
+// eventTransport returns the transport the event bus publishes through,
+// from Config.EventTransport or the event_* properties of the DSL config
+// block, or nil to keep events in process.
+//
+//	config {
+//	  event_transport: "redis"
+//	  event_redis_url: "redis://localhost:6379"
+//	  event_stream: "orders:events"
+//	  event_group: "orders"
+//	  event_max_deliveries: 5
+//	}
+func (g *generator) eventTransport(program *ast.Program) (bus.Transport, error) {
+	if g.config.EventTransport != nil {
+		return g.config.EventTransport, nil
+	}
+
+	settings := configSettings(program)
+	cfg := transport.DefaultConfig()
+	if stream := configString(settings, "event_stream"); stream != "" {
+		cfg.Stream = stream
+	}
+	if group := configString(settings, "event_group"); group != "" {
+		cfg.Group = group
+	}
+	if n, ok := settings["event_max_deliveries"].(float64); ok && n >= 1 {
+		cfg.MaxDeliveries = int(n)
+	}
+	opts := []transport.Option{transport.WithLogger(g.logger)}
+
+	switch kind := configString(settings, "event_transport"); kind {
+	case "", "local":
+		return nil, nil
+	case "memory":
+		return transport.NewMemory(cfg, opts...), nil
+	case "redis":
+		url := configString(settings, "event_redis_url")
+		if url == "" {
+			url = g.config.RedisURL
+		}
+		redisOpts, err := redis.ParseURL(url)
+		if err != nil {
+			return nil, fmt.Errorf("parsing event_redis_url: %w", err)
+		}
+		return transport.NewRedisStreams(redis.NewClient(redisOpts), cfg, opts...), nil
+	default:
+		return nil, fmt.Errorf("unknown event_transport %q", kind)
+	}
+}
+
+// connectEventTransport connects the event bus to the configured transport
+// once every subscriber is registered, so no consumed event misses its
+// handlers.
+func (g *generator) connectEventTransport(program *ast.Program, code *GeneratedCode) error {
+	t, err := g.eventTransport(program)
+	if err != nil || t == nil {
+		return err
+	}
+	if err := code.EventBus.Connect(context.Background(), t); err != nil {
+		t.Close()
+		return err
+	}
+	code.EventTransport = t
+	g.logger.Info("event bus connected to transport", "transport", fmt.Sprintf("%T", t))
+	return nil
+}
@@ -0,0 +1,116 @@
+package codegen
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bargom/codeai/internal/event/transport"
+	"github.com/bargom/codeai/internal/parser"
+)
+
+func TestGenerateEventTransport(t *testing.T) {
+	hooks, hookRequests := recordingServer(t)
+
+	program, err := parser.Parse(fmt.Sprintf(`
+webhook shipping {
+	event "order_created"
+	url "%s/shipping"
+	method POST
+}
+
+event order_created {
+	schema {
+		order_id string
+	}
+}
+
+on "order_created" do webhook "shipping"
+`, hooks.URL))
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	// Two instances sharing a broker, as two servers share a Redis server
+	broker := transport.NewMemoryBroker()
+	cfg := transport.DefaultConfig()
+	var instances []*GeneratedCode
+	for i := 0; i < 2; i++ {
+		code, err := NewGenerator(&Config{EventTransport: broker.Transport(cfg)}).GenerateFromAST(program)
+		if err != nil {
+			t.Fatalf("code generation failed: %v", err)
+		}
+		t.Cleanup(code.Events.Close)
+		instances = append(instances, code)
+	}
+
+	ctx := context.Background()
+	for i := 0; i < 4; i++ {
+		payload := map[string]interface{}{"order_id": fmt.Sprintf("o-%d", i)}
+		if err := instances[0].EventHandlers.EmitEvent(ctx, "order_created", payload); err != nil {
+			t.Fatalf("emit failed: %v", err)
+		}
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(hookRequests()) < 4 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	// Each event is handled by one of the instances
+	got := hookRequests()
+	if len(got) != 4 {
+		t.Fatalf("expected 4 webhook deliveries, got %d", len(got))
+	}
+	seen := make(map[interface{}]bool)
+	for _, req := range got {
+		data, _ := req.body["data"].(map[string]interface{})
+		seen[data["order_id"]] = true
+	}
+	if len(seen) != 4 {
+		t.Errorf("expected every event to be delivered once, got %v", seen)
+	}
+}
+
+func TestGenerateEventTransport_FromConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		want    string
+		wantErr string
+	}{
+		{name: "default", config: `database_type: "postgres"`, want: "<nil>"},
+		{name: "local", config: `event_transport: "local"`, want: "<nil>"},
+		{name: "memory", config: `event_transport: "memory" event_group: "orders"`, want: "*transport.Memory"},
+		{name: "unknown", config: `event_transport: "kafka"`, wantErr: `unknown event_transport "kafka"`},
+		{name: "invalid redis url", config: `event_transport: "redis" event_redis_url: "localhost"`, wantErr: "parsing event_redis_url"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			program, err := parser.Parse("config { " + tt.config + " }")
+			if err != nil {
+				t.Fatalf("failed to parse: %v", err)
+			}
+
+			code, err := NewGenerator(nil).GenerateFromAST(program)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("code generation failed: %v", err)
+			}
+			defer code.Events.Close()
+
+			if got := fmt.Sprintf("%T", code.EventTransport); got != tt.want {
+				t.Errorf("expected transport %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
@@ -4,14 +4,25 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 
+	"github.com/bargom/codeai/internal/abac"
 	"github.com/bargom/codeai/internal/ast"
 	"github.com/bargom/codeai/internal/auth"
-	"github.com/bargom/codeai/internal/event"
+	"github.com/bargom/codeai/internal/auth/apikey"
+	"github.com/bargom/codeai/internal/auth/issuer"
+	"github.com/bargom/codeai/internal/cache"
+	"github.com/bargom/codeai/internal/database"
 	"github.com/bargom/codeai/internal/integration"
+	"github.com/bargom/codeai/internal/ratelimit"
+	"github.com/bargom/codeai/internal/tenant"
 	"github.com/bargom/codeai/internal/workflow"
 )
 
@@ -25,6 +36,9 @@ type Generator interface {
 type generator struct {
 	config *Config
 	logger *slog.Logger
+
+	// rateLimitStores caches the stores rate limit middleware share, by storage kind
+	rateLimitStores map[string]ratelimit.Store
 }
 
 // NewGenerator creates a new code generator with the given configuration.
@@ -51,10 +65,13 @@ func (g *generator) GenerateFromAST(program *ast.Program) (*GeneratedCode, error
 		Middlewares:   make(map[string]func(http.Handler) http.Handler),
 		Integrations:  integration.NewIntegrationRegistry(),
 		Workflows:     workflow.NewDSLWorkflowRegistry(),
-		EventHandlers: event.NewEventRegistry(nil),
 		AuthLoader:    auth.NewDSLLoader(),
+		Policies:      abac.NewEngine(),
+		APIKeys:       make(map[string]*apikey.Provider),
+		Issuers:       make(map[string]*issuer.Issuer),
 		ModelRegistry: NewTypeRegistry(),
 	}
+	code.EventHandlers = g.newEventRegistry(code)
 
 	// First pass: load configurations (auth, middleware, models, etc.)
 	if err := g.loadConfigurations(program, code); err != nil {
@@ -66,10 +83,13 @@ func (g *generator) GenerateFromAST(program *ast.Program) (*GeneratedCode, error
 		return nil, fmt.Errorf("loading integrations: %w", err)
 	}
 
-	// Third pass: load workflows
+	// Third pass: load workflows and webhooks
 	if err := g.loadWorkflows(program, code); err != nil {
 		return nil, fmt.Errorf("loading workflows: %w", err)
 	}
+	if err := g.loadWebhooks(program, code); err != nil {
+		return nil, fmt.Errorf("loading webhooks: %w", err)
+	}
 
 	// Fourth pass: load events and handlers
 	if err := g.loadEvents(program, code); err != nil {
@@ -84,6 +104,11 @@ func (g *generator) GenerateFromAST(program *ast.Program) (*GeneratedCode, error
 	code.Router = router
 	code.EndpointCount = endpointCount
 
+	// Consume events from other instances once all handlers are registered
+	if err := g.connectEventTransport(program, code); err != nil {
+		return nil, fmt.Errorf("connecting event transport: %w", err)
+	}
+
 	g.logger.Info("code generation complete",
 		"endpoints", endpointCount,
 		"integrations", code.Integrations.Count(),
@@ -100,10 +125,26 @@ func (g *generator) loadConfigurations(program *ast.Program, code *GeneratedCode
 	if err := code.AuthLoader.LoadProgram(program); err != nil {
 		return fmt.Errorf("loading auth configurations: %w", err)
 	}
+	if err := g.loadAuthProviders(code); err != nil {
+		return fmt.Errorf("loading auth providers: %w", err)
+	}
+	if err := g.loadRBAC(program, code); err != nil {
+		return fmt.Errorf("loading roles: %w", err)
+	}
 
-	// Load models and collections
+	// Load policies, tenancy, models and collections
 	for _, stmt := range program.Statements {
 		switch decl := stmt.(type) {
+		case *ast.PolicyDecl:
+			if err := code.Policies.Add(decl.Name(), decl.Condition); err != nil {
+				return fmt.Errorf("loading policy: %w", err)
+			}
+		case *ast.TenancyDecl:
+			cfg, err := tenancyConfig(decl)
+			if err != nil {
+				return fmt.Errorf("loading tenancy: %w", err)
+			}
+			code.Tenancy = tenant.NewResolver(cfg)
 		case *ast.DatabaseBlock:
 			if err := g.loadDatabaseBlock(decl, code); err != nil {
 				return err
@@ -144,23 +185,9 @@ func (g *generator) loadModel(model *ast.ModelDecl, code *GeneratedCode) {
 		fieldInfo := FieldInfo{
 			Name:      field.Name,
 			FieldType: field.FieldType.String(),
+			Pos:       field.Pos(),
 		}
-
-		// Extract modifiers
-		for _, mod := range field.Modifiers {
-			switch mod.Name {
-			case "required":
-				fieldInfo.Required = true
-			case "unique":
-				fieldInfo.Unique = true
-			case "primary":
-				fieldInfo.Primary = true
-			case "default":
-				if mod.Value != nil {
-					fieldInfo.Default = extractExprValue(mod.Value)
-				}
-			}
-		}
+		g.applyFieldModifiers(&fieldInfo, field.Modifiers)
 
 		info.Fields = append(info.Fields, fieldInfo)
 	}
@@ -188,22 +215,9 @@ func (g *generator) loadCollection(coll *ast.CollectionDecl, code *GeneratedCode
 		fieldInfo := FieldInfo{
 			Name:      field.Name,
 			FieldType: field.FieldType.String(),
+			Pos:       field.Pos(),
 		}
-
-		for _, mod := range field.Modifiers {
-			switch mod.Name {
-			case "required":
-				fieldInfo.Required = true
-			case "unique":
-				fieldInfo.Unique = true
-			case "primary":
-				fieldInfo.Primary = true
-			case "default":
-				if mod.Value != nil {
-					fieldInfo.Default = extractExprValue(mod.Value)
-				}
-			}
-		}
+		g.applyFieldModifiers(&fieldInfo, field.Modifiers)
 
 		info.Fields = append(info.Fields, fieldInfo)
 	}
@@ -219,6 +233,70 @@ func (g *generator) loadCollection(coll *ast.CollectionDecl, code *GeneratedCode
 	code.ModelRegistry.Collections[coll.Name] = info
 }
 
+// applyFieldModifiers records the modifiers of a model or collection field.
+func (g *generator) applyFieldModifiers(info *FieldInfo, modifiers []*ast.Modifier) {
+	info.Enum = enumValues(info.FieldType)
+
+	for _, mod := range modifiers {
+		var value interface{}
+		if mod.Value != nil {
+			value = extractExprValue(mod.Value)
+		}
+
+		switch mod.Name {
+		case "required":
+			info.Required = true
+		case "unique":
+			info.Unique = true
+		case "primary":
+			info.Primary = true
+		case "auto":
+			info.Auto = true
+		case "default":
+			info.Default = value
+		case "min":
+			if n, ok := value.(float64); ok {
+				info.Min = &n
+			}
+		case "max":
+			if n, ok := value.(float64); ok {
+				info.Max = &n
+			}
+		case "min_length":
+			if n, ok := value.(float64); ok {
+				length := int(n)
+				info.MinLength = &length
+			}
+		case "max_length":
+			if n, ok := value.(float64); ok {
+				length := int(n)
+				info.MaxLength = &length
+			}
+		case "pattern":
+			if p, ok := value.(string); ok {
+				re, err := regexp.Compile(p)
+				if err != nil {
+					g.logger.Warn("ignoring invalid field pattern", "field", info.Name, "pattern", p, "error", err)
+					continue
+				}
+				info.Pattern = re
+			}
+		}
+	}
+}
+
+// enumValues returns the values of an enum field type such as "enum(a, b)".
+func enumValues(fieldType string) []string {
+	if !strings.HasPrefix(fieldType, "enum(") || !strings.HasSuffix(fieldType, ")") {
+		return nil
+	}
+	values := strings.Split(strings.TrimSuffix(strings.TrimPrefix(fieldType, "enum("), ")"), ",")
+	for i, v := range values {
+		values[i] = strings.TrimSpace(v)
+	}
+	return values
+}
+
 // loadIntegrations loads external API integration configurations.
 func (g *generator) loadIntegrations(program *ast.Program, code *GeneratedCode) error {
 	for _, stmt := range program.Statements {
@@ -261,6 +339,7 @@ func (g *generator) loadEvents(program *ast.Program, code *GeneratedCode) error
 			}
 			g.logger.Debug("registered event", "name", decl.Name)
 		case *ast.EventHandlerDecl:
+			g.checkEventHandlerTarget(decl, code)
 			if err := code.EventHandlers.SubscribeHandlerFromAST(decl); err != nil {
 				return fmt.Errorf("subscribing handler for %q: %w", decl.EventName, err)
 			}
@@ -281,9 +360,38 @@ func (g *generator) generateRouter(program *ast.Program, code *GeneratedCode) (c
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.Timeout(60 * 1000000000)) // 60 seconds
 
+	// Login, refresh, logout and JWKS endpoints of token issuers
+	issuerNames := make([]string, 0, len(code.Issuers))
+	for name := range code.Issuers {
+		issuerNames = append(issuerNames, name)
+	}
+	sort.Strings(issuerNames)
+	for _, name := range issuerNames {
+		issuer.NewHandler(code.Issuers[name]).RegisterRoutes(r)
+	}
+
+	// Runtime role administration
+	if err := g.registerAdminRoutes(r, program, code); err != nil {
+		return nil, 0, err
+	}
+
 	// Create execution context factory
 	execCtxFactory := NewExecutionContextFactory(code)
 	execCtxFactory.dbConnection = g.config.DBConnection
+	var adapterOpts []AdapterOption
+	if code.Tenancy != nil {
+		cfg := code.Tenancy.Config()
+		if cfg.Strategy == tenant.StrategyDatabase && g.config.TenantDatabases == nil &&
+			g.config.DBConnection != nil && g.config.DBConnection.Type() == database.DatabaseTypePostgres {
+			return nil, 0, fmt.Errorf("tenancy strategy %q requires Config.TenantDatabases", cfg.Strategy)
+		}
+		if cfg.Strategy == tenant.StrategyDatabase && g.config.Outbox != nil &&
+			g.config.DBConnection != nil && g.config.DBConnection.Type() == database.DatabaseTypePostgres {
+			return nil, 0, fmt.Errorf("tenancy strategy %q cannot share transactions with Config.Outbox", cfg.Strategy)
+		}
+		adapterOpts = append(adapterOpts, WithTenancy(cfg), WithTenantDatabases(g.config.TenantDatabases))
+	}
+	execCtxFactory.db = NewDatabaseAdapter(g.config.DBConnection, code.ModelRegistry, adapterOpts...)
 
 	// Generate endpoint handlers
 	endpointCount := 0
@@ -296,19 +404,33 @@ func (g *generator) generateRouter(program *ast.Program, code *GeneratedCode) (c
 		}
 	}
 
+	// Inbound webhook receivers
+	receiverCount, err := g.registerWebhookReceivers(r, program, code)
+	if err != nil {
+		return nil, 0, err
+	}
+	endpointCount += receiverCount
+
 	return r, endpointCount, nil
 }
 
 // registerEndpoint registers a single endpoint in the router.
 func (g *generator) registerEndpoint(r chi.Router, ep *ast.EndpointDecl, code *GeneratedCode, factory *ExecutionContextFactory) error {
 	// Build middleware chain for this endpoint
-	middlewareChain := g.buildMiddlewareChain(ep.Middlewares, code)
+	middlewareChain, err := g.buildMiddlewareChain(ep.Middlewares, code)
+	if err != nil {
+		return err
+	}
 
 	// Generate the handler
 	handler := GenerateEndpointHandler(ep, factory)
 
-	// Wrap handler with middleware
+	// Wrap handler with middleware, resolving the tenant after
+	// authentication
 	var finalHandler http.Handler = handler
+	if code.Tenancy != nil {
+		finalHandler = code.Tenancy.Middleware(finalHandler)
+	}
 	for i := len(middlewareChain) - 1; i >= 0; i-- {
 		finalHandler = middlewareChain[i](finalHandler)
 	}
@@ -337,7 +459,7 @@ func (g *generator) registerEndpoint(r chi.Router, ep *ast.EndpointDecl, code *G
 }
 
 // buildMiddlewareChain builds the middleware chain for an endpoint.
-func (g *generator) buildMiddlewareChain(refs []*ast.MiddlewareRef, code *GeneratedCode) []func(http.Handler) http.Handler {
+func (g *generator) buildMiddlewareChain(refs []*ast.MiddlewareRef, code *GeneratedCode) ([]func(http.Handler) http.Handler, error) {
 	chain := make([]func(http.Handler) http.Handler, 0, len(refs))
 
 	for _, ref := range refs {
@@ -355,14 +477,17 @@ func (g *generator) buildMiddlewareChain(refs []*ast.MiddlewareRef, code *Genera
 		}
 
 		// Generate middleware based on type
-		mw := generateMiddlewareFunc(loadedMW, code.AuthLoader)
+		mw, err := g.generateMiddlewareFunc(loadedMW, code.AuthLoader)
+		if err != nil {
+			return nil, fmt.Errorf("middleware %s: %w", ref.Name, err)
+		}
 		if mw != nil {
 			chain = append(chain, mw)
 			code.Middlewares[ref.Name] = mw
 		}
 	}
 
-	return chain
+	return chain, nil
 }
 
 // convertPathToChi converts :param style paths to {param} for chi router.
@@ -405,22 +530,23 @@ func extractExprValue(expr ast.Expression) interface{} {
 }
 
 // generateMiddlewareFunc creates an HTTP middleware from loaded configuration.
-func generateMiddlewareFunc(mw *auth.LoadedMiddleware, authLoader *auth.DSLLoader) func(http.Handler) http.Handler {
+func (g *generator) generateMiddlewareFunc(mw *auth.LoadedMiddleware, authLoader *auth.DSLLoader) (func(http.Handler) http.Handler, error) {
 	switch mw.MiddlewareType {
 	case "authentication":
-		return generateAuthMiddlewareFunc(mw, authLoader)
+		return generateAuthMiddlewareFunc(mw, authLoader), nil
 	case "rate_limiting":
-		return generateRateLimitMiddleware(mw)
+		return g.generateRateLimitMiddleware(mw)
 	case "cors":
-		return generateCORSMiddleware(mw)
+		return generateCORSMiddleware(mw), nil
 	case "logging":
-		return generateLoggingMiddleware(mw)
+		return generateLoggingMiddleware(mw), nil
 	default:
-		return nil
+		return nil, nil
 	}
 }
 
-// generateAuthMiddlewareFunc creates JWT authentication middleware.
+// generateAuthMiddlewareFunc creates authentication middleware for the
+// provider the middleware references.
 func generateAuthMiddlewareFunc(mw *auth.LoadedMiddleware, authLoader *auth.DSLLoader) func(http.Handler) http.Handler {
 	authMW, err := authLoader.CreateMiddlewareChain(mw.Name)
 	if err != nil {
@@ -437,27 +563,115 @@ func generateAuthMiddlewareFunc(mw *auth.LoadedMiddleware, authLoader *auth.DSLL
 	return authMW.Authenticate(requirement)
 }
 
-// generateRateLimitMiddleware creates rate limiting middleware.
-func generateRateLimitMiddleware(mw *auth.LoadedMiddleware) func(http.Handler) http.Handler {
-	// Extract rate limit config
-	limit := 100 // default
-	if v, ok := mw.Config["limit"].(float64); ok {
-		limit = int(v)
+// generateRateLimitMiddleware creates rate limiting middleware from the
+// requests, window, strategy, key_by and storage config values. An invalid
+// config is an error rather than an endpoint without a limit.
+func (g *generator) generateRateLimitMiddleware(mw *auth.LoadedMiddleware) (func(http.Handler) http.Handler, error) {
+	cfg := ratelimit.Config{
+		Limit:    100,         // default
+		Window:   time.Minute, // default
+		Strategy: ratelimit.Strategy(configString(mw.Config, "strategy")),
+		Prefix:   mw.Name,
+	}
+	if v, ok := mw.Config["requests"].(float64); ok {
+		cfg.Limit = int(v)
+	} else if v, ok := mw.Config["limit"].(float64); ok {
+		cfg.Limit = int(v)
+	}
+	switch v := mw.Config["window"].(type) {
+	case float64:
+		cfg.Window = time.Duration(v * float64(time.Second))
+	case string:
+		window, err := parseWindow(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate limit window %q: %w", v, err)
+		}
+		cfg.Window = window
 	}
-	window := 60 // default: 60 seconds
-	if v, ok := mw.Config["window"].(float64); ok {
-		window = int(v)
+
+	var keyFunc ratelimit.KeyFunc
+	switch keyBy := configString(mw.Config, "key_by"); keyBy {
+	case "", "ip":
+		keyFunc = ratelimit.KeyByIP
+	case "user", "subject", "jwt":
+		keyFunc = ratelimit.KeyBySubject
+	case "api_key":
+		keyFunc = ratelimit.KeyByAPIKey(configString(mw.Config, "header"))
+	default:
+		return nil, fmt.Errorf("unknown rate limit key_by %q", keyBy)
 	}
 
-	_ = limit  // TODO: Implement actual rate limiting
-	_ = window // TODO: Implement actual rate limiting
+	store, err := g.rateLimitStore(configString(mw.Config, "storage"))
+	if err != nil {
+		return nil, fmt.Errorf("creating rate limit store: %w", err)
+	}
 
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Placeholder: actual rate limiting would use Redis
-			next.ServeHTTP(w, r)
-		})
+	limiter, err := ratelimit.New(cfg, store)
+	if err != nil {
+		return nil, err
+	}
+	return ratelimit.Middleware(limiter, keyFunc), nil
+}
+
+// rateLimitStore returns the store for the given storage kind, "memory" or
+// "redis". Config.RateLimitStore, when set, is used for every kind.
+func (g *generator) rateLimitStore(storage string) (ratelimit.Store, error) {
+	if g.config.RateLimitStore != nil {
+		return g.config.RateLimitStore, nil
+	}
+	if storage == "" {
+		storage = "memory"
+	}
+	if store, ok := g.rateLimitStores[storage]; ok {
+		return store, nil
+	}
+
+	var store ratelimit.Store
+	switch storage {
+	case "memory":
+		store = ratelimit.NewMemoryStore(cache.NewMemoryCache(cache.Config{}))
+	case "redis":
+		c, err := cache.NewRedisCache(cache.Config{URL: g.config.RedisURL})
+		if err != nil {
+			return nil, err
+		}
+		store = ratelimit.NewRedisStore(c)
+	default:
+		return nil, fmt.Errorf("unknown rate limit storage %q", storage)
+	}
+
+	if g.rateLimitStores == nil {
+		g.rateLimitStores = make(map[string]ratelimit.Store)
+	}
+	g.rateLimitStores[storage] = store
+	return store, nil
+}
+
+// parseWindow parses a window such as "1m" or "30s"; a bare number is seconds.
+func parseWindow(s string) (time.Duration, error) {
+	if n, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Duration(n * float64(time.Second)), nil
+	}
+	return time.ParseDuration(s)
+}
+
+// configString returns the string config value for key, or "".
+func configString(config map[string]any, key string) string {
+	s, _ := config[key].(string)
+	return s
+}
+
+// configSettings returns the properties of the DSL config blocks.
+func configSettings(program *ast.Program) map[string]any {
+	settings := make(map[string]any)
+	for _, stmt := range program.Statements {
+		if decl, ok := stmt.(*ast.ConfigDecl); ok {
+			for key, expr := range decl.Properties {
+				settings[key] = extractExprValue(expr)
+			}
+		}
 	}
+	return settings
 }
 
 // generateCORSMiddleware creates CORS middleware.
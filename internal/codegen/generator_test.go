@@ -1,13 +1,24 @@
 package codegen
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/bargom/codeai/internal/ast"
+	"github.com/bargom/codeai/internal/auth/apikey"
+	"github.com/bargom/codeai/internal/auth/basic"
+	"github.com/bargom/codeai/internal/auth/issuer"
+	"github.com/bargom/codeai/internal/event/replay"
 	"github.com/bargom/codeai/internal/parser"
+	"github.com/bargom/codeai/internal/rbac"
 )
 
 func TestNewGenerator(t *testing.T) {
@@ -449,3 +460,575 @@ func TestDefaultConfig(t *testing.T) {
 		t.Error("expected EnableMetrics to be true by default")
 	}
 }
+
+func TestGenerateRateLimitMiddleware(t *testing.T) {
+	input := `
+middleware rate_limit {
+	type rate_limiting
+	config {
+		requests: 2
+		window: "1m"
+		strategy: fixed_window
+	}
+}
+
+endpoint GET "/health" {
+	middleware rate_limit
+	response HealthResponse status 200
+}
+`
+
+	program, err := parser.Parse(input)
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	gen := NewGenerator(nil)
+	code, err := gen.GenerateFromAST(program)
+	if err != nil {
+		t.Fatalf("code generation failed: %v", err)
+	}
+
+	wantStatus := []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests}
+	for i, want := range wantStatus {
+		req := httptest.NewRequest("GET", "/health", nil)
+		w := httptest.NewRecorder()
+		code.Router.ServeHTTP(w, req)
+
+		if w.Code != want {
+			t.Errorf("request %d: expected status %d, got %d", i+1, want, w.Code)
+		}
+		if got := w.Header().Get("RateLimit-Limit"); got != "2" {
+			t.Errorf("request %d: expected RateLimit-Limit 2, got %q", i+1, got)
+		}
+		if want == http.StatusTooManyRequests && w.Header().Get("Retry-After") == "" {
+			t.Errorf("request %d: expected Retry-After header", i+1)
+		}
+	}
+}
+
+func TestGenerateRateLimitMiddleware_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   string
+	}{
+		{"bad window", `requests: 2
+		window: "1 minute"`, "invalid rate limit window"},
+		{"unknown key_by", `requests: 2
+		window: "1m"
+		key_by: "cookie"`, "unknown rate limit key_by"},
+		{"unknown storage", `requests: 2
+		window: "1m"
+		storage: "disk"`, "unknown rate limit storage"},
+		{"unknown strategy", `requests: 2
+		window: "1m"
+		strategy: leaky_bucket`, "unknown rate limit strategy"},
+		{"zero requests", `requests: 0
+		window: "1m"`, "rate limit must be positive"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := `
+middleware rate_limit {
+	type rate_limiting
+	config {
+		` + tt.config + `
+	}
+}
+
+endpoint GET "/health" {
+	middleware rate_limit
+	response HealthResponse status 200
+}
+`
+			program, err := parser.Parse(input)
+			if err != nil {
+				t.Fatalf("failed to parse: %v", err)
+			}
+
+			code, err := NewGenerator(nil).GenerateFromAST(program)
+			if err == nil {
+				t.Fatalf("expected code generation to fail, got router %v", code.Router)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestGenerateAPIKeyAuthentication(t *testing.T) {
+	input := `
+auth service_keys {
+	method apikey
+	config {
+		header: "X-Service-Key"
+	}
+}
+
+middleware require_key {
+	type authentication
+	config {
+		provider: service_keys
+		required: true
+	}
+}
+
+endpoint GET "/reports" {
+	middleware require_key
+	response ReportList status 200
+}
+`
+
+	program, err := parser.Parse(input)
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	gen := NewGenerator(&Config{APIKeyStore: apikey.NewMemoryStore()})
+	code, err := gen.GenerateFromAST(program)
+	if err != nil {
+		t.Fatalf("code generation failed: %v", err)
+	}
+
+	provider, ok := code.APIKeys["service_keys"]
+	if !ok {
+		t.Fatal("expected API key provider service_keys")
+	}
+	key, _, err := provider.Issue(context.Background(), apikey.IssueOptions{Name: "reporting"})
+	if err != nil {
+		t.Fatalf("issuing key: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		key  string
+		want int
+	}{
+		{"missing key", "", http.StatusUnauthorized},
+		{"unknown key", "cai_unknown", http.StatusUnauthorized},
+		{"valid key", key, http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/reports", nil)
+		if tt.key != "" {
+			req.Header.Set("X-Service-Key", tt.key)
+		}
+		w := httptest.NewRecorder()
+		code.Router.ServeHTTP(w, req)
+
+		if w.Code != tt.want {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.want, w.Code)
+		}
+	}
+}
+
+func TestGenerateRuntimeRoles(t *testing.T) {
+	input := `
+auth service_keys {
+	method apikey
+	config {
+		header: "X-Service-Key"
+	}
+}
+
+role editor {
+	permissions ["drafts:read"]
+}
+
+middleware require_key {
+	type authentication
+	config {
+		provider: service_keys
+		required: true
+	}
+}
+
+endpoint GET "/drafts" {
+	middleware require_key
+	response DraftList status 200
+	do {
+		authorize(request, "editor")
+	}
+}
+`
+
+	program, err := parser.Parse(input)
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	storage := rbac.NewMemoryStorage()
+	gen := NewGenerator(&Config{APIKeyStore: apikey.NewMemoryStore(), RBACStorage: storage})
+	code, err := gen.GenerateFromAST(program)
+	if err != nil {
+		t.Fatalf("code generation failed: %v", err)
+	}
+	if _, err := storage.GetRole(context.Background(), "editor"); err != nil {
+		t.Fatalf("expected the DSL role to be stored: %v", err)
+	}
+
+	provider := code.APIKeys["service_keys"]
+	adminKey, _, err := provider.Issue(context.Background(), apikey.IssueOptions{
+		Name:        "admin",
+		Permissions: []string{rbac.AdminPermission},
+	})
+	if err != nil {
+		t.Fatalf("issuing key: %v", err)
+	}
+	writerKey, writer, err := provider.Issue(context.Background(), apikey.IssueOptions{Name: "writer"})
+	if err != nil {
+		t.Fatalf("issuing key: %v", err)
+	}
+
+	do := func(method, path, key, body string) int {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if key != "" {
+			req.Header.Set("X-Service-Key", key)
+		}
+		w := httptest.NewRecorder()
+		code.Router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if got := do("GET", "/admin/roles", "", ""); got != http.StatusUnauthorized {
+		t.Errorf("admin routes without a key: expected status 401, got %d", got)
+	}
+	if got := do("GET", "/admin/roles", writerKey, ""); got != http.StatusForbidden {
+		t.Errorf("admin routes without permission: expected status 403, got %d", got)
+	}
+	if got := do("GET", "/drafts", writerKey, ""); got != http.StatusForbidden {
+		t.Errorf("before the role is assigned: expected status 403, got %d", got)
+	}
+
+	path := "/admin/users/" + writer.ID + "/roles"
+	if got := do("PUT", path, adminKey, `{"roles":["editor"]}`); got != http.StatusOK {
+		t.Fatalf("assigning role: expected status 200, got %d", got)
+	}
+	if got := do("GET", "/drafts", writerKey, ""); got != http.StatusOK {
+		t.Errorf("after the role is assigned: expected status 200, got %d", got)
+	}
+}
+
+func TestGenerateReplayRoutes(t *testing.T) {
+	input := `
+auth service_keys {
+	method apikey
+}
+
+middleware require_key {
+	type authentication
+	config {
+		provider: service_keys
+		required: true
+	}
+}
+`
+
+	program, err := parser.Parse(input)
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	code, err := NewGenerator(&Config{APIKeyStore: apikey.NewMemoryStore()}).GenerateFromAST(program)
+	if err != nil {
+		t.Fatalf("code generation failed: %v", err)
+	}
+	if code.Replays != nil {
+		t.Error("expected no replay routes without an event repository")
+	}
+
+	code, err = NewGenerator(&Config{
+		APIKeyStore:     apikey.NewMemoryStore(),
+		EventRepository: &recordingEventRepository{},
+	}).GenerateFromAST(program)
+	if err != nil {
+		t.Fatalf("code generation failed: %v", err)
+	}
+	if code.Replays == nil {
+		t.Fatal("expected replay routes with an event repository")
+	}
+	defer code.Replays.Shutdown()
+
+	provider := code.APIKeys["service_keys"]
+	replayKey, _, err := provider.Issue(context.Background(), apikey.IssueOptions{
+		Name:        "replayer",
+		Permissions: []string{replay.AdminPermission},
+	})
+	if err != nil {
+		t.Fatalf("issuing key: %v", err)
+	}
+	otherKey, _, err := provider.Issue(context.Background(), apikey.IssueOptions{Name: "other"})
+	if err != nil {
+		t.Fatalf("issuing key: %v", err)
+	}
+
+	do := func(key string) int {
+		req := httptest.NewRequest("GET", "/admin/events/replays/targets", nil)
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		w := httptest.NewRecorder()
+		code.Router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if got := do(""); got != http.StatusUnauthorized {
+		t.Errorf("without a key: expected status 401, got %d", got)
+	}
+	if got := do(otherKey); got != http.StatusForbidden {
+		t.Errorf("without permission: expected status 403, got %d", got)
+	}
+	if got := do(replayKey); got != http.StatusOK {
+		t.Errorf("with permission: expected status 200, got %d", got)
+	}
+}
+
+func TestGenerateBasicAndIntrospectionAuthentication(t *testing.T) {
+	hash, err := basic.HashPassword("wonderland")
+	if err != nil {
+		t.Fatalf("hashing password: %v", err)
+	}
+	credentials := filepath.Join(t.TempDir(), "users.htpasswd")
+	if err := os.WriteFile(credentials, []byte("alice:"+hash+":editor\n"), 0o600); err != nil {
+		t.Fatalf("writing credentials: %v", err)
+	}
+
+	introspect := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		active := r.PostFormValue("token") == "opaque-token"
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"active": %t, "sub": "partner-1", "scope": "reports:read"}`, active)
+	}))
+	defer introspect.Close()
+
+	input := fmt.Sprintf(`
+auth staff {
+	method basic
+	config {
+		file: %q
+		realm: "Staff"
+	}
+}
+
+auth partners {
+	method oauth2
+	config {
+		introspection_url: %q
+	}
+}
+
+middleware staff_only {
+	type authentication
+	config {
+		provider: staff
+		required: true
+	}
+}
+
+middleware partners_only {
+	type authentication
+	config {
+		provider: partners
+		required: true
+	}
+}
+
+endpoint GET "/staff" {
+	middleware staff_only
+	response Report status 200
+}
+
+endpoint GET "/partners" {
+	middleware partners_only
+	response Report status 200
+}
+`, credentials, introspect.URL)
+
+	program, err := parser.Parse(input)
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+	code, err := NewGenerator(nil).GenerateFromAST(program)
+	if err != nil {
+		t.Fatalf("code generation failed: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		path      string
+		setAuth   func(r *http.Request)
+		want      int
+		challenge string
+	}{
+		{"basic missing", "/staff", func(r *http.Request) {}, http.StatusUnauthorized, `Basic realm="Staff", charset="UTF-8"`},
+		{"basic wrong password", "/staff", func(r *http.Request) { r.SetBasicAuth("alice", "nope") }, http.StatusUnauthorized, `Basic realm="Staff", charset="UTF-8"`},
+		{"basic valid", "/staff", func(r *http.Request) { r.SetBasicAuth("alice", "wonderland") }, http.StatusOK, ""},
+		{"introspection inactive", "/partners", func(r *http.Request) { r.Header.Set("Authorization", "Bearer revoked") }, http.StatusUnauthorized, ""},
+		{"introspection active", "/partners", func(r *http.Request) { r.Header.Set("Authorization", "Bearer opaque-token") }, http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		tt.setAuth(req)
+		w := httptest.NewRecorder()
+		code.Router.ServeHTTP(w, req)
+
+		if w.Code != tt.want {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.want, w.Code)
+		}
+		if got := w.Header().Get("WWW-Authenticate"); got != tt.challenge {
+			t.Errorf("%s: expected WWW-Authenticate %q, got %q", tt.name, tt.challenge, got)
+		}
+	}
+}
+
+func TestGenerateTokenIssuer(t *testing.T) {
+	hash, err := basic.HashPassword("wonderland")
+	if err != nil {
+		t.Fatalf("hashing password: %v", err)
+	}
+	credentials := filepath.Join(t.TempDir(), "users.htpasswd")
+	if err := os.WriteFile(credentials, []byte("alice:"+hash+":editor\n"), 0o600); err != nil {
+		t.Fatalf("writing credentials: %v", err)
+	}
+
+	input := fmt.Sprintf(`
+auth app_tokens {
+	method jwt
+	issuer {
+		file: %q
+		issuer: "https://api.example.com"
+		access_ttl: "5m"
+	}
+}
+
+middleware signed_in {
+	type authentication
+	config {
+		provider: app_tokens
+		required: true
+	}
+}
+
+endpoint GET "/me" {
+	middleware signed_in
+	response User status 200
+}
+`, credentials)
+
+	program, err := parser.Parse(input)
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+	code, err := NewGenerator(&Config{IssuerStore: issuer.NewMemoryStore()}).GenerateFromAST(program)
+	if err != nil {
+		t.Fatalf("code generation failed: %v", err)
+	}
+	if _, ok := code.Issuers["app_tokens"]; !ok {
+		t.Fatal("expected token issuer app_tokens")
+	}
+
+	send := func(method, path, body, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		code.Router.ServeHTTP(w, req)
+		return w
+	}
+	login := func(body string) issuer.Tokens {
+		t.Helper()
+		w := send("POST", "/auth/login", body, "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("login: expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var tokens issuer.Tokens
+		if err := json.Unmarshal(w.Body.Bytes(), &tokens); err != nil {
+			t.Fatalf("decoding tokens: %v", err)
+		}
+		return tokens
+	}
+
+	if w := send("POST", "/auth/login", `{"username":"alice","password":"nope"}`, ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("wrong password: expected status 401, got %d", w.Code)
+	}
+	if w := send("GET", "/me", "", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("no token: expected status 401, got %d", w.Code)
+	}
+
+	tokens := login(`{"username":"alice","password":"wonderland"}`)
+	if w := send("GET", "/me", "", tokens.AccessToken); w.Code != http.StatusOK {
+		t.Errorf("access token: expected status 200, got %d", w.Code)
+	}
+
+	w := send("POST", "/auth/refresh", `{"refresh_token":"`+tokens.RefreshToken+`"}`, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("refresh: expected status 200, got %d", w.Code)
+	}
+	var refreshed issuer.Tokens
+	if err := json.Unmarshal(w.Body.Bytes(), &refreshed); err != nil {
+		t.Fatalf("decoding tokens: %v", err)
+	}
+
+	if w := send("POST", "/auth/logout", `{"refresh_token":"`+refreshed.RefreshToken+`"}`, ""); w.Code != http.StatusNoContent {
+		t.Errorf("logout: expected status 204, got %d", w.Code)
+	}
+	w = send("GET", "/me", "", refreshed.AccessToken)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("revoked token: expected status 401, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "token has been revoked") {
+		t.Errorf("revoked token: unexpected body %s", w.Body.String())
+	}
+
+	w = send("GET", "/.well-known/jwks.json", "", "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"crv":"P-256"`) {
+		t.Errorf("jwks: unexpected response %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestGeneratePolicies(t *testing.T) {
+	program, err := parser.Parse(`authorize post.update when resource.author_id == auth.sub or auth.role == "admin"
+authorize post.read when resource.published`)
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+
+	code, err := NewGenerator(nil).GenerateFromAST(program)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	names := code.Policies.Names()
+	if len(names) != 2 || names[0] != "post.read" || names[1] != "post.update" {
+		t.Errorf("expected policies post.read and post.update, got %v", names)
+	}
+}
+
+func TestParseWindow(t *testing.T) {
+	tests := []struct {
+		input string
+		want  time.Duration
+	}{
+		{"1m", time.Minute},
+		{"30s", 30 * time.Second},
+		{"1h30m", 90 * time.Minute},
+		{"60", time.Minute},
+	}
+	for _, tt := range tests {
+		got, err := parseWindow(tt.input)
+		if err != nil {
+			t.Errorf("parseWindow(%q) failed: %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseWindow(%q) = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+
+	if _, err := parseWindow("soon"); err == nil {
+		t.Error("expected error for invalid window")
+	}
+}
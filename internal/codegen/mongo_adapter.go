@@ -0,0 +1,244 @@
+package codegen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/bargom/codeai/internal/database/mongodb"
+	"github.com/bargom/codeai/internal/query"
+	"github.com/bargom/codeai/internal/tenant"
+)
+
+// MongoAdapter implements DatabaseAdapter on top of mongodb.Repository.
+// Tables are collection names and ids are matched against _id; hex strings
+// are converted to ObjectIDs. With tenancy, every operation is scoped to
+// the tenant of its context: by a tenant field, a collection prefix or a
+// database per tenant.
+type MongoAdapter struct {
+	client  *mongodb.Client
+	logger  *slog.Logger
+	tenancy *tenant.Config
+}
+
+// NewMongoAdapter creates a MongoDB adapter.
+func NewMongoAdapter(client *mongodb.Client, logger *slog.Logger, opts ...AdapterOption) *MongoAdapter {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	o := newAdapterOptions(opts)
+	return &MongoAdapter{client: client, logger: logger, tenancy: o.tenancy}
+}
+
+// Query returns the documents matching conditions.
+func (a *MongoAdapter) Query(ctx context.Context, table string, conditions map[string]interface{}) ([]map[string]interface{}, error) {
+	a.logger.Info("querying documents", "collection", table, "conditions", conditions)
+
+	repo, filter, err := a.scoped(ctx, table, conditions)
+	if err != nil {
+		return nil, err
+	}
+	docs, err := repo.Find(ctx, filter, nil)
+	if err != nil {
+		return nil, fmt.Errorf("MongoDB find failed: %w", err)
+	}
+	return toResults(docs), nil
+}
+
+// QueryWhere is Query with the documents further restricted to those
+// matching where.
+func (a *MongoAdapter) QueryWhere(ctx context.Context, table string, conditions map[string]interface{}, where *query.WhereClause) ([]map[string]interface{}, error) {
+	if where == nil {
+		return a.Query(ctx, table, conditions)
+	}
+	a.logger.Info("querying documents", "collection", table, "conditions", conditions, "filtered", true)
+
+	repo, filter, err := a.scoped(ctx, table, conditions)
+	if err != nil {
+		return nil, err
+	}
+	combined := &query.WhereClause{Operator: query.LogicalAnd}
+	for field, value := range filter {
+		combined.Conditions = append(combined.Conditions, query.Condition{Field: field, Operator: query.OpEquals, Value: value})
+	}
+	combined.Conditions = append(combined.Conditions, query.Condition{Nested: where})
+
+	docs, err := repo.ExecuteQuery(ctx, &query.Query{Type: query.QuerySelect, Entity: table, Where: combined})
+	if err != nil {
+		return nil, fmt.Errorf("MongoDB find failed: %w", err)
+	}
+	return toResults(docs), nil
+}
+
+func toResults(docs []mongodb.Document) []map[string]interface{} {
+	results := make([]map[string]interface{}, len(docs))
+	for i, doc := range docs {
+		results[i] = doc
+	}
+	return results
+}
+
+// FindOne returns the document with the given id, or nil if there is none.
+func (a *MongoAdapter) FindOne(ctx context.Context, table string, id interface{}) (map[string]interface{}, error) {
+	repo, filter, err := a.scoped(ctx, table, map[string]interface{}{"_id": id})
+	if err != nil {
+		return nil, err
+	}
+	doc, err := repo.FindOne(ctx, filter)
+	if err != nil {
+		if errors.Is(err, mongodb.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("MongoDB findOne failed: %w", err)
+	}
+	return doc, nil
+}
+
+// Insert inserts data as a new document and returns its id.
+func (a *MongoAdapter) Insert(ctx context.Context, table string, data interface{}) (interface{}, error) {
+	dataMap, err := toRecord(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Add timestamps if not present
+	now := time.Now()
+	if _, ok := dataMap["created_at"]; !ok {
+		dataMap["created_at"] = now
+	}
+	if _, ok := dataMap["updated_at"]; !ok {
+		dataMap["updated_at"] = now
+	}
+
+	repo, scope, err := a.scoped(ctx, table, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.checkTenantWrite(dataMap, scope); err != nil {
+		return nil, err
+	}
+	for k, v := range scope {
+		dataMap[k] = v
+	}
+
+	a.logger.Info("inserting document", "collection", table, "data", dataMap)
+
+	result, err := repo.Collection().InsertOne(ctx, dataMap)
+	if err != nil {
+		return nil, fmt.Errorf("MongoDB insert failed: %w", err)
+	}
+
+	// Return the inserted ID
+	return result.InsertedID, nil
+}
+
+// Update sets the fields in data on the document with the given id. It
+// returns a NotFoundError if no document matched.
+func (a *MongoAdapter) Update(ctx context.Context, table string, id interface{}, data interface{}) (*WriteResult, error) {
+	dataMap, err := toRecord(data)
+	if err != nil {
+		return nil, err
+	}
+
+	repo, filter, err := a.scoped(ctx, table, map[string]interface{}{"_id": id})
+	if err != nil {
+		return nil, err
+	}
+	if err := a.checkTenantWrite(dataMap, filter); err != nil {
+		return nil, err
+	}
+
+	set := bson.M{}
+	for k, v := range dataMap {
+		if _, scoped := filter[k]; !scoped {
+			set[k] = v
+		}
+	}
+
+	a.logger.Info("updating document", "collection", table, "id", id)
+
+	// updated_at changes only with the document, so an update that changes
+	// nothing is reported as not modified
+	result, err := repo.SetOne(ctx, filter, set, "updated_at")
+	if err != nil {
+		return nil, fmt.Errorf("MongoDB update failed: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return nil, &NotFoundError{Resource: table, ID: id}
+	}
+
+	return &WriteResult{Matched: result.MatchedCount, Modified: result.ModifiedCount}, nil
+}
+
+// Delete removes the document with the given id. It returns a NotFoundError
+// if no document matched.
+func (a *MongoAdapter) Delete(ctx context.Context, table string, id interface{}) error {
+	a.logger.Info("deleting document", "collection", table, "id", id)
+
+	repo, filter, err := a.scoped(ctx, table, map[string]interface{}{"_id": id})
+	if err != nil {
+		return err
+	}
+	count, err := repo.DeleteOne(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("MongoDB delete failed: %w", err)
+	}
+	if count == 0 {
+		return &NotFoundError{Resource: table, ID: id}
+	}
+	return nil
+}
+
+// InTransaction runs fn in a transaction. Operations using the session
+// context passed to fn join the transaction, which commits if fn returns nil
+// and aborts otherwise. The driver retries fn on transient transaction
+// errors, so fn may run more than once.
+func (a *MongoAdapter) InTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if mongo.SessionFromContext(ctx) != nil {
+		return fn(ctx)
+	}
+	return a.client.WithTransaction(ctx, func(sessCtx mongo.SessionContext) error {
+		return fn(sessCtx)
+	})
+}
+
+// scoped returns the repository of the named collection for the tenant in
+// ctx, and conditions as a filter restricted to that tenant.
+func (a *MongoAdapter) scoped(ctx context.Context, table string, conditions map[string]interface{}) (*mongodb.Repository, mongodb.Filter, error) {
+	filter := make(mongodb.Filter, len(conditions)+1)
+	for k, v := range conditions {
+		filter[k] = v
+	}
+	if a.tenancy == nil {
+		return mongodb.NewRepository(a.client, table, a.logger), filter, nil
+	}
+
+	id, err := tenantOf(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch a.tenancy.Strategy {
+	case tenant.StrategySchema:
+		return mongodb.NewRepository(a.client, tenant.SchemaName(id)+"_"+table, a.logger), filter, nil
+	case tenant.StrategyDatabase:
+		name := a.client.Database().Name() + "_" + id
+		return mongodb.NewDatabaseRepository(a.client, name, table, a.logger), filter, nil
+	}
+	filter[a.tenancy.Column] = id
+	return mongodb.NewRepository(a.client, table, a.logger), filter, nil
+}
+
+// checkTenantWrite rejects documents setting the tenant field to another
+// tenant than the one in scope.
+func (a *MongoAdapter) checkTenantWrite(doc map[string]interface{}, scope mongodb.Filter) error {
+	if a.tenancy == nil || a.tenancy.Strategy != tenant.StrategyColumn {
+		return nil
+	}
+	id, _ := scope[a.tenancy.Column].(string)
+	return checkTenantWrite(doc, a.tenancy.Column, id)
+}
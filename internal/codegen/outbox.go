@@ -0,0 +1,108 @@
+package codegen
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"time"
+
+	"github.com/bargom/codeai/internal/ast"
+	"github.com/bargom/codeai/internal/event"
+	"github.com/bargom/codeai/internal/event/bus"
+	"github.com/bargom/codeai/internal/event/dispatcher"
+	"github.com/bargom/codeai/internal/event/outbox"
+	"github.com/bargom/codeai/internal/tenant"
+)
+
+// outboxDispatcher appends the events of the event registry to the outbox
+// instead of publishing them; the relay publishes them to the event bus.
+// Subscriptions go to the dispatcher the relay publishes to.
+type outboxDispatcher struct {
+	event.Dispatcher
+	store outbox.Store
+	relay *outbox.Relay
+}
+
+// Dispatch appends ev to the outbox, in the transaction of ctx if there is
+// one. The tenant in ctx is kept in the event's metadata, from which the
+// relay restores it. Events emitted during a replay bypass the outbox, so
+// that they stay in the replay.
+func (d outboxDispatcher) Dispatch(ctx context.Context, ev event.Event) error {
+	if bus.IsReplay(ctx) {
+		return d.Dispatcher.Dispatch(ctx, ev)
+	}
+	if ev.Source == "" {
+		ev.Source = dslEventSource(ctx)
+	}
+	busEvent := event.ToBusEvent(ev)
+	if id := tenant.FromContext(ctx); id != "" {
+		metadata := maps.Clone(busEvent.Metadata)
+		if metadata == nil {
+			metadata = make(map[string]string, 1)
+		}
+		metadata["tenant"] = id
+		busEvent.Metadata = metadata
+	}
+
+	if err := d.store.Append(ctx, outbox.NewMessage(busEvent, time.Now())); err != nil {
+		return err
+	}
+	d.relay.Notify()
+	return nil
+}
+
+// newOutboxRelay creates the relay publishing the outbox to the event bus.
+// Events that no longer match their schema are dropped rather than retried
+// forever.
+func (g *generator) newOutboxRelay(code *GeneratedCode) *outbox.Relay {
+	publish := func(ctx context.Context, ev bus.Event) error {
+		if id := ev.Metadata["tenant"]; id != "" {
+			ctx = tenant.WithTenant(ctx, id)
+		}
+		err := code.Events.Dispatch(ctx, ev)
+		if errors.Is(err, dispatcher.ErrInvalidEvent) {
+			g.logger.Error("dropping invalid outbox event", "id", ev.ID, "type", ev.Type, "error", err)
+			return nil
+		}
+		return err
+	}
+	opts := append([]outbox.Option{outbox.WithLogger(g.logger)}, g.config.OutboxOptions...)
+	return outbox.NewRelay(g.config.Outbox, outbox.PublisherFunc(publish), opts...)
+}
+
+// executeEndpointLogic executes the logic steps of an endpoint. With an
+// outbox, the steps of endpoints that emit events run in one transaction,
+// so that the events are recorded if and only if the endpoint's writes are
+// committed.
+func executeEndpointLogic(ctx *ExecutionContext, steps []*ast.LogicStep) (interface{}, error) {
+	tx, ok := ctx.db.(Transactor)
+	if ctx.generatedCode.Outbox == nil || !ok || !emitsEvents(steps) {
+		return executeLogicSteps(ctx, steps)
+	}
+
+	var result interface{}
+	outer := ctx.ctx
+	err := tx.InTransaction(outer, func(txCtx context.Context) error {
+		ctx.ctx = txCtx
+		defer func() { ctx.ctx = outer }()
+
+		var err error
+		result, err = executeLogicSteps(ctx, steps)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	ctx.generatedCode.Relay.Notify()
+	return result, nil
+}
+
+// emitsEvents reports whether steps emit an event.
+func emitsEvents(steps []*ast.LogicStep) bool {
+	for _, step := range steps {
+		if step.Action == "emit" {
+			return true
+		}
+	}
+	return false
+}
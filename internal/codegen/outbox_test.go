@@ -0,0 +1,161 @@
+package codegen
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bargom/codeai/internal/database"
+	"github.com/bargom/codeai/internal/event/bus"
+	"github.com/bargom/codeai/internal/event/outbox"
+	"github.com/bargom/codeai/internal/parser"
+)
+
+// flakyEventRepository fails to save the first failures events.
+type flakyEventRepository struct {
+	recordingEventRepository
+	failures int
+}
+
+func (r *flakyEventRepository) SaveEvent(ctx context.Context, event bus.Event) error {
+	r.mu.Lock()
+	if r.failures > 0 {
+		r.failures--
+		r.mu.Unlock()
+		return errors.New("database unavailable")
+	}
+	r.mu.Unlock()
+	return r.recordingEventRepository.SaveEvent(ctx, event)
+}
+
+func TestGenerateOutbox(t *testing.T) {
+	program, err := parser.Parse(`database postgres {
+    model Project {
+        id: int, primary, auto
+        name: string, required
+    }
+}
+
+event project_created {
+	schema {
+		id int
+	}
+}
+
+endpoint POST "/projects" {
+    request CreateProject from body
+    response Project status 201
+    do {
+        insert(Project, request)
+        emit(project_created)
+    }
+}
+
+endpoint POST "/drafts" {
+    request CreateProject from body
+    response Project status 201
+    do {
+        insert(Project, request)
+        emit(project_drafted)
+    }
+}`)
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+
+	db := openTenantDB(t)
+	db.SetMaxOpenConns(1) // Every connection to :memory: opens a new database
+	store := outbox.NewSQLStore(db)
+	if err := store.CreateTable(context.Background()); err != nil {
+		t.Fatalf("creating outbox table: %v", err)
+	}
+
+	now := time.Now()
+	repo := &flakyEventRepository{failures: 1}
+	code, err := NewGenerator(&Config{
+		DBConnection:    &database.PostgresConnection{DB: db},
+		EventRepository: repo,
+		Outbox:          store,
+		OutboxOptions:   []outbox.Option{outbox.WithClock(func() time.Time { return now })},
+	}).GenerateFromAST(program)
+	if err != nil {
+		t.Fatalf("code generation failed: %v", err)
+	}
+
+	count := func(table string) int {
+		t.Helper()
+		var n int
+		if err := db.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&n); err != nil {
+			t.Fatalf("counting %s: %v", table, err)
+		}
+		return n
+	}
+	post := func(path string) int {
+		req := httptest.NewRequest("POST", path, strings.NewReader(`{"name": "rocket"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		code.Router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if status := post("/projects"); status != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d", http.StatusCreated, status)
+	}
+	if n := count("event_outbox"); n != 1 {
+		t.Fatalf("expected 1 outbox message, got %d", n)
+	}
+	if len(repo.events) != 0 {
+		t.Fatalf("expected the event to wait for the relay, got %v", repo.events)
+	}
+
+	now = time.Now()
+	published, err := code.Relay.RelayOnce(context.Background())
+	if err != nil || published != 0 {
+		t.Fatalf("expected the failed dispatch not to count as published, got %d (err %v)", published, err)
+	}
+	now = now.Add(time.Minute)
+	published, err = code.Relay.RelayOnce(context.Background())
+	if err != nil || published != 1 {
+		t.Fatalf("expected the event to be published on retry, got %d (err %v)", published, err)
+	}
+	if len(repo.events) != 1 || repo.events[0].Type != "project_created" || repo.events[0].Data["id"] == nil {
+		t.Errorf("expected the project_created event to be saved once, got %v", repo.events)
+	}
+
+	// The emit fails, so the insert is rolled back along with it.
+	if status := post("/drafts"); status < 400 {
+		t.Errorf("expected an error status, got %d", status)
+	}
+	if n := count("projects"); n != 1 {
+		t.Errorf("expected the failed endpoint's insert to be rolled back, got %d projects", n)
+	}
+	if n := count("event_outbox"); n != 1 {
+		t.Errorf("expected no outbox message for the failed endpoint, got %d", n)
+	}
+}
+
+func TestGenerateOutbox_DatabaseTenancy(t *testing.T) {
+	program, err := parser.Parse(`tenancy {
+	strategy: database
+	claim: "org_id"
+}`)
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+
+	gen := NewGenerator(&Config{
+		DBConnection: &database.PostgresConnection{DB: openTenantDB(t)},
+		TenantDatabases: func(context.Context, string) (*sql.DB, error) {
+			return nil, errors.New("unused")
+		},
+		Outbox: outbox.NewMemoryStore(),
+	})
+	if _, err := gen.GenerateFromAST(program); err == nil {
+		t.Error("expected error combining the outbox with tenant databases")
+	}
+}
@@ -0,0 +1,594 @@
+package codegen
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bargom/codeai/internal/database"
+	"github.com/bargom/codeai/internal/database/schema"
+	"github.com/bargom/codeai/internal/query"
+	"github.com/bargom/codeai/internal/tenant"
+	"github.com/lib/pq"
+)
+
+// softDeleteField is the model field that turns deletes into soft deletes.
+const softDeleteField = "deleted_at"
+
+// PostgresAdapter implements DatabaseAdapter on top of the query package,
+// using the model metadata in a TypeRegistry to map model names to tables.
+// With tenancy, every query is scoped to the tenant of its context.
+type PostgresAdapter struct {
+	db        query.DB
+	entities  map[string]*query.EntityMeta
+	models    map[string]*pgModel
+	tenancy   *tenant.Config
+	databases TenantDatabases
+}
+
+// pgModel describes how a model's fields are stored in PostgreSQL.
+type pgModel struct {
+	name       string
+	primaryKey string
+	fields     map[string]string // field name -> base DSL type
+	elemTypes  map[string]string // list field name -> element DSL type
+}
+
+// NewPostgresAdapter creates a PostgreSQL adapter for the models in registry.
+// Tables are named as created by the schema package, e.g. User -> users.
+func NewPostgresAdapter(db query.DB, registry *TypeRegistry, opts ...AdapterOption) *PostgresAdapter {
+	o := newAdapterOptions(opts)
+	a := &PostgresAdapter{
+		db:        db,
+		entities:  make(map[string]*query.EntityMeta),
+		models:    make(map[string]*pgModel),
+		tenancy:   o.tenancy,
+		databases: o.databases,
+	}
+	if registry == nil {
+		return a
+	}
+
+	for _, info := range registry.Models {
+		m := &pgModel{
+			name:       info.Name,
+			primaryKey: "id",
+			fields:     make(map[string]string, len(info.Fields)),
+			elemTypes:  make(map[string]string),
+		}
+		meta := &query.EntityMeta{
+			TableName:   schema.TableName(info.Name),
+			Columns:     make(map[string]string, len(info.Fields)),
+			JSONColumns: make(map[string]bool),
+		}
+
+		for _, f := range info.Fields {
+			base, elem := splitFieldType(f.FieldType)
+			m.fields[f.Name] = base
+			if elem != "" {
+				m.elemTypes[f.Name] = elem
+			}
+			if f.Primary {
+				m.primaryKey = f.Name
+			}
+			if base == "json" || base == "jsonb" {
+				meta.JSONColumns[f.Name] = true
+			}
+			if f.Name == softDeleteField {
+				meta.SoftDelete = softDeleteField
+			}
+			// Field names are the column names; don't let the compiler re-case them.
+			meta.Columns[f.Name] = f.Name
+		}
+		meta.PrimaryKey = m.primaryKey
+		if a.tenancy != nil && a.tenancy.Strategy == tenant.StrategyColumn {
+			meta.TenantColumn = a.tenancy.Column
+		}
+
+		a.entities[info.Name] = meta
+		a.models[strings.ToLower(info.Name)] = m
+		a.models[meta.TableName] = m
+	}
+
+	return a
+}
+
+// Query returns the rows of a model matching all equality conditions.
+func (a *PostgresAdapter) Query(ctx context.Context, table string, conditions map[string]interface{}) ([]map[string]interface{}, error) {
+	return a.QueryWhere(ctx, table, conditions, nil)
+}
+
+// QueryWhere is Query with the rows further restricted to those matching where.
+func (a *PostgresAdapter) QueryWhere(ctx context.Context, table string, conditions map[string]interface{}, where *query.WhereClause) ([]map[string]interface{}, error) {
+	m, err := a.model(table)
+	if err != nil {
+		return nil, err
+	}
+
+	qb := query.Select(m.name)
+	fields := make([]string, 0, len(conditions))
+	for field := range conditions {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		if _, ok := m.fields[field]; !ok {
+			return nil, &ValidationError{Field: field, Message: fmt.Sprintf("unknown field on %s", m.name)}
+		}
+		qb.Where(field, query.OpEquals, a.bindValue(m, field, conditions[field]))
+	}
+	q := qb.Build()
+	if where != nil {
+		bound, err := a.bindWhere(m, where)
+		if err != nil {
+			return nil, err
+		}
+		if q.Where == nil {
+			q.Where = &query.WhereClause{Operator: query.LogicalAnd}
+		}
+		q.Where.Conditions = append(q.Where.Conditions, query.Condition{Nested: bound})
+	}
+
+	exec, err := a.scoped(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := exec.Execute(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		a.decodeRow(m, row)
+	}
+	return rows, nil
+}
+
+// bindWhere returns a copy of where with its fields checked against the
+// model and its values bound like those of equality conditions.
+func (a *PostgresAdapter) bindWhere(m *pgModel, where *query.WhereClause) (*query.WhereClause, error) {
+	bound := &query.WhereClause{
+		Operator:   where.Operator,
+		Conditions: make([]query.Condition, len(where.Conditions)),
+	}
+	for i, cond := range where.Conditions {
+		if cond.Nested != nil {
+			nested, err := a.bindWhere(m, cond.Nested)
+			if err != nil {
+				return nil, err
+			}
+			cond.Nested = nested
+		} else {
+			if _, ok := m.fields[cond.Field]; !ok {
+				return nil, &ValidationError{Field: cond.Field, Message: fmt.Sprintf("unknown field on %s", m.name)}
+			}
+			cond.Value = a.bindValue(m, cond.Field, cond.Value)
+		}
+		bound.Conditions[i] = cond
+	}
+	return bound, nil
+}
+
+// FindOne returns the row with the given primary key, or nil if there is none.
+func (a *PostgresAdapter) FindOne(ctx context.Context, table string, id interface{}) (map[string]interface{}, error) {
+	m, err := a.model(table)
+	if err != nil {
+		return nil, err
+	}
+
+	q := query.Select(m.name).Where(m.primaryKey, query.OpEquals, id).Build()
+	exec, err := a.scoped(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	row, err := exec.ExecuteOne(ctx, q)
+	if err != nil || row == nil {
+		return nil, err
+	}
+	a.decodeRow(m, row)
+	return row, nil
+}
+
+// Insert inserts a row built from the model fields in data and returns its
+// primary key. Keys that are not model fields are ignored.
+func (a *PostgresAdapter) Insert(ctx context.Context, table string, data interface{}) (interface{}, error) {
+	m, err := a.model(table)
+	if err != nil {
+		return nil, err
+	}
+	record, err := toRecord(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.checkTenantWrite(ctx, record); err != nil {
+		return nil, err
+	}
+
+	qb := query.Insert(m.name).Returning(m.primaryKey)
+	for _, field := range sortedFields(m, record) {
+		if field == m.primaryKey && record[field] == nil || a.isTenantColumn(field) {
+			continue
+		}
+		qb.Set(field, a.bindValue(m, field, record[field]))
+	}
+
+	q := qb.Build()
+	exec, err := a.scoped(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	row, err := exec.ExecuteInsert(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	if row == nil {
+		return nil, fmt.Errorf("insert into %s returned no row", m.name)
+	}
+	a.decodeRow(m, row)
+	return row[m.primaryKey], nil
+}
+
+// Update sets the model fields in data on the row with the given primary key.
+// It returns a NotFoundError if no row was updated.
+func (a *PostgresAdapter) Update(ctx context.Context, table string, id interface{}, data interface{}) (*WriteResult, error) {
+	m, err := a.model(table)
+	if err != nil {
+		return nil, err
+	}
+	record, err := toRecord(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.checkTenantWrite(ctx, record); err != nil {
+		return nil, err
+	}
+
+	qb := query.Update(m.name)
+	updates := 0
+	for _, field := range sortedFields(m, record) {
+		if field == m.primaryKey || a.isTenantColumn(field) {
+			continue
+		}
+		qb.Set(field, a.bindValue(m, field, record[field]))
+		updates++
+	}
+	if updates == 0 {
+		return nil, &ValidationError{Field: "request", Message: fmt.Sprintf("no %s fields to update", m.name)}
+	}
+	if _, ok := m.fields["updated_at"]; ok {
+		if _, set := record["updated_at"]; !set {
+			qb.Set("updated_at", time.Now().UTC())
+		}
+	}
+
+	q := qb.Where(m.primaryKey, query.OpEquals, id).Build()
+	exec, err := a.scoped(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	affected, err := exec.ExecuteUpdate(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	if affected == 0 {
+		return nil, &NotFoundError{Resource: m.name, ID: id}
+	}
+	return &WriteResult{Matched: affected, Modified: affected}, nil
+}
+
+// Delete removes the row with the given primary key, or marks it deleted when
+// the model has a deleted_at field. It returns a NotFoundError if no row matched.
+func (a *PostgresAdapter) Delete(ctx context.Context, table string, id interface{}) error {
+	m, err := a.model(table)
+	if err != nil {
+		return err
+	}
+
+	q := query.Delete(m.name).Where(m.primaryKey, query.OpEquals, id).Build()
+	exec, err := a.scoped(ctx, q)
+	if err != nil {
+		return err
+	}
+	affected, err := exec.ExecuteDelete(ctx, q)
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return &NotFoundError{Resource: m.name, ID: id}
+	}
+	return nil
+}
+
+// scoped scopes q to the tenant in ctx and returns a new executor to run it
+// with, on the tenant's database under the database strategy. The SQL
+// compiler keeps per-query state, so executors are not shared between
+// concurrent requests.
+func (a *PostgresAdapter) scoped(ctx context.Context, q *query.Query) (*query.Executor, error) {
+	if a.tenancy == nil {
+		return a.executor(ctx, a.db), nil
+	}
+	id, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	q.Tenant = &query.TenantScope{ID: id}
+	switch a.tenancy.Strategy {
+	case tenant.StrategySchema:
+		q.Tenant.Schema = tenant.SchemaName(id)
+	case tenant.StrategyDatabase:
+		db, err := a.tenantDB(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return a.executor(ctx, db), nil
+	}
+	return a.executor(ctx, a.db), nil
+}
+
+// executor returns an executor on db, or on the transaction carried by ctx.
+func (a *PostgresAdapter) executor(ctx context.Context, db query.DB) *query.Executor {
+	if tx := database.TxFromContext(ctx); tx != nil {
+		db = tx
+	}
+	return query.NewExecutor(db, a.entities)
+}
+
+// tenantDB returns the database of a tenant under the database strategy.
+func (a *PostgresAdapter) tenantDB(ctx context.Context, id string) (*sql.DB, error) {
+	if a.databases == nil {
+		return nil, fmt.Errorf("no database configured for tenant %q", id)
+	}
+	db, err := a.databases(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("database of tenant %q: %w", id, err)
+	}
+	return db, nil
+}
+
+// InTransaction runs fn in a transaction, on the tenant's database under
+// the database strategy. Operations using the context passed to fn, such
+// as those of the adapter and of stores using database.TxFromContext, join
+// the transaction. It commits if fn returns nil and rolls back otherwise.
+func (a *PostgresAdapter) InTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if database.TxFromContext(ctx) != nil {
+		return fn(ctx)
+	}
+
+	db := a.db
+	if a.tenancy != nil && a.tenancy.Strategy == tenant.StrategyDatabase {
+		id, err := tenantOf(ctx)
+		if err != nil {
+			return err
+		}
+		if db, err = a.tenantDB(ctx, id); err != nil {
+			return err
+		}
+	}
+	beginner, ok := db.(interface {
+		BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+	})
+	if !ok {
+		return fn(ctx)
+	}
+
+	tx, err := beginner.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(database.WithTx(ctx, tx)); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing transaction: %w", err)
+	}
+	return nil
+}
+
+// checkTenantWrite rejects records setting the tenant column to another
+// tenant under the column strategy.
+func (a *PostgresAdapter) checkTenantWrite(ctx context.Context, record map[string]interface{}) error {
+	if a.tenancy == nil || a.tenancy.Strategy != tenant.StrategyColumn {
+		return nil
+	}
+	id, err := tenantOf(ctx)
+	if err != nil {
+		return err
+	}
+	return checkTenantWrite(record, a.tenancy.Column, id)
+}
+
+// isTenantColumn reports whether field is the tenant column, which queries
+// set from their tenant scope.
+func (a *PostgresAdapter) isTenantColumn(field string) bool {
+	return a.tenancy != nil && a.tenancy.Strategy == tenant.StrategyColumn && field == a.tenancy.Column
+}
+
+// model looks up a model by name or table name.
+func (a *PostgresAdapter) model(name string) (*pgModel, error) {
+	if m, ok := a.models[strings.ToLower(name)]; ok {
+		return m, nil
+	}
+	return nil, fmt.Errorf("unknown model %q", name)
+}
+
+// bindValue converts a request value into a parameter PostgreSQL accepts for
+// the field's column type.
+func (a *PostgresAdapter) bindValue(m *pgModel, field string, value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+
+	switch m.fields[field] {
+	case "json", "jsonb":
+		b, err := json.Marshal(value)
+		if err != nil {
+			return value
+		}
+		return string(b)
+	case "list", "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return pq.Array(value)
+		}
+		switch m.elemTypes[field] {
+		case "int", "integer":
+			arr := make(pq.Int64Array, len(items))
+			for i, v := range items {
+				arr[i] = toInt64(v)
+			}
+			return arr
+		case "decimal":
+			arr := make(pq.Float64Array, len(items))
+			for i, v := range items {
+				arr[i], _ = strconv.ParseFloat(fmt.Sprint(v), 64)
+			}
+			return arr
+		case "bool", "boolean":
+			arr := make(pq.BoolArray, len(items))
+			for i, v := range items {
+				arr[i], _ = v.(bool)
+			}
+			return arr
+		default:
+			arr := make(pq.StringArray, len(items))
+			for i, v := range items {
+				arr[i] = fmt.Sprint(v)
+			}
+			return arr
+		}
+	}
+	return value
+}
+
+// decodeRow converts the raw driver values in row into JSON-friendly values:
+// JSON columns are unmarshaled, arrays become slices and numerics numbers.
+func (a *PostgresAdapter) decodeRow(m *pgModel, row map[string]interface{}) {
+	for col, v := range row {
+		var raw string
+		switch val := v.(type) {
+		case []byte:
+			raw = string(val)
+		case string:
+			raw = val
+		default:
+			continue
+		}
+
+		switch m.fields[col] {
+		case "json", "jsonb":
+			var decoded interface{}
+			if err := json.Unmarshal([]byte(raw), &decoded); err == nil {
+				row[col] = decoded
+				continue
+			}
+		case "list", "array":
+			var items pq.StringArray
+			if err := items.Scan([]byte(raw)); err == nil {
+				row[col] = decodeArray(items, m.elemTypes[col])
+				continue
+			}
+		case "decimal":
+			if f, err := strconv.ParseFloat(raw, 64); err == nil {
+				row[col] = f
+				continue
+			}
+		}
+		row[col] = raw
+	}
+}
+
+// decodeArray converts PostgreSQL array elements to the list element type.
+func decodeArray(items []string, elem string) []interface{} {
+	out := make([]interface{}, len(items))
+	for i, s := range items {
+		out[i] = s
+		switch elem {
+		case "int", "integer":
+			if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+				out[i] = n
+			}
+		case "decimal":
+			if f, err := strconv.ParseFloat(s, 64); err == nil {
+				out[i] = f
+			}
+		case "bool", "boolean":
+			if b, err := strconv.ParseBool(s); err == nil {
+				out[i] = b
+			}
+		}
+	}
+	return out
+}
+
+// splitFieldType splits a field type such as "list(int)" into its base type
+// and list element type.
+func splitFieldType(fieldType string) (base, elem string) {
+	open := strings.Index(fieldType, "(")
+	if open < 0 {
+		return fieldType, ""
+	}
+	base = fieldType[:open]
+	if base == "list" || base == "array" {
+		elem = strings.TrimSuffix(fieldType[open+1:], ")")
+		if i := strings.Index(elem, "("); i >= 0 {
+			elem = elem[:i]
+		}
+	}
+	return base, elem
+}
+
+// sortedFields returns the keys of record that are fields of m, in order.
+func sortedFields(m *pgModel, record map[string]interface{}) []string {
+	fields := make([]string, 0, len(record))
+	for field := range record {
+		if _, ok := m.fields[field]; ok {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// toRecord converts request data into a field map.
+func toRecord(data interface{}) (map[string]interface{}, error) {
+	if data == nil {
+		return map[string]interface{}{}, nil
+	}
+	if record, ok := data.(map[string]interface{}); ok {
+		return record, nil
+	}
+
+	jsonBytes, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("converting data to map: %w", err)
+	}
+	var record map[string]interface{}
+	if err := json.Unmarshal(jsonBytes, &record); err != nil {
+		return nil, fmt.Errorf("unmarshaling data: %w", err)
+	}
+	return record, nil
+}
+
+// toInt64 converts a decoded JSON number or numeric string to int64.
+func toInt64(v interface{}) int64 {
+	switch n := v.(type) {
+	case int:
+		return int64(n)
+	case int64:
+		return n
+	case float64:
+		return int64(n)
+	default:
+		i, _ := strconv.ParseInt(fmt.Sprint(v), 10, 64)
+		return i
+	}
+}
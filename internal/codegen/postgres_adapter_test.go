@@ -0,0 +1,319 @@
+package codegen
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/bargom/codeai/internal/abac"
+	"github.com/bargom/codeai/internal/ast"
+	"github.com/bargom/codeai/internal/auth"
+	"github.com/bargom/codeai/internal/query"
+)
+
+func testPostgresRegistry() *TypeRegistry {
+	registry := NewTypeRegistry()
+	registry.Models["User"] = &ModelInfo{
+		Name: "User",
+		Fields: []FieldInfo{
+			{Name: "id", FieldType: "int", Primary: true},
+			{Name: "email", FieldType: "string", Required: true},
+			{Name: "score", FieldType: "decimal"},
+			{Name: "profile", FieldType: "json"},
+			{Name: "updated_at", FieldType: "timestamp"},
+		},
+	}
+	return registry
+}
+
+// newTestAdapter returns an adapter over an in-memory SQLite database, which
+// accepts the $n placeholders and RETURNING clauses the compiler emits.
+func newTestAdapter(t *testing.T) (*PostgresAdapter, *sql.DB) {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("opening database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	_, err = db.Exec(`CREATE TABLE users (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		email TEXT NOT NULL,
+		score NUMERIC,
+		profile TEXT,
+		updated_at TIMESTAMP
+	)`)
+	if err != nil {
+		t.Fatalf("creating table: %v", err)
+	}
+
+	return NewPostgresAdapter(db, testPostgresRegistry()), db
+}
+
+func TestPostgresAdapter_CRUD(t *testing.T) {
+	adapter, _ := newTestAdapter(t)
+	ctx := context.Background()
+
+	id, err := adapter.Insert(ctx, "User", map[string]interface{}{
+		"email":   "ada@example.com",
+		"profile": map[string]interface{}{"lang": "en"},
+		"unknown": "ignored",
+	})
+	if err != nil {
+		t.Fatalf("insert failed: %v", err)
+	}
+	if id != int64(1) {
+		t.Fatalf("expected id 1, got %#v", id)
+	}
+
+	row, err := adapter.FindOne(ctx, "User", id)
+	if err != nil {
+		t.Fatalf("find failed: %v", err)
+	}
+	if row["email"] != "ada@example.com" {
+		t.Errorf("expected email, got %#v", row["email"])
+	}
+	if !reflect.DeepEqual(row["profile"], map[string]interface{}{"lang": "en"}) {
+		t.Errorf("expected decoded profile, got %#v", row["profile"])
+	}
+
+	result, err := adapter.Update(ctx, "users", id, map[string]interface{}{"email": "ada@lovelace.dev"})
+	if err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	if result.Matched != 1 || result.Modified != 1 {
+		t.Errorf("expected 1 matched and modified, got %+v", result)
+	}
+	rows, err := adapter.Query(ctx, "User", map[string]interface{}{"email": "ada@lovelace.dev"})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("expected 1 row, got %d", len(rows))
+	}
+	if rows[0]["updated_at"] == nil {
+		t.Error("expected updated_at to be set on update")
+	}
+
+	if err := adapter.Delete(ctx, "User", id); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	row, err = adapter.FindOne(ctx, "User", id)
+	if err != nil {
+		t.Fatalf("find failed: %v", err)
+	}
+	if row != nil {
+		t.Errorf("expected no row after delete, got %v", row)
+	}
+}
+
+func TestPostgresAdapter_NotFound(t *testing.T) {
+	adapter, _ := newTestAdapter(t)
+	ctx := context.Background()
+
+	var notFound *NotFoundError
+	_, err := adapter.Update(ctx, "User", 42, map[string]interface{}{"email": "x@example.com"})
+	if !errors.As(err, &notFound) {
+		t.Errorf("expected NotFoundError from update, got %v", err)
+	}
+	if err := adapter.Delete(ctx, "User", 42); !errors.As(err, &notFound) {
+		t.Errorf("expected NotFoundError from delete, got %v", err)
+	}
+}
+
+func TestPostgresAdapter_Errors(t *testing.T) {
+	adapter, _ := newTestAdapter(t)
+	ctx := context.Background()
+
+	if _, err := adapter.Query(ctx, "Order", nil); err == nil {
+		t.Error("expected error for unknown model")
+	}
+
+	var validationErr *ValidationError
+	if _, err := adapter.Query(ctx, "User", map[string]interface{}{"password": "x"}); !errors.As(err, &validationErr) {
+		t.Errorf("expected ValidationError for unknown field, got %v", err)
+	}
+	if _, err := adapter.Update(ctx, "User", 1, map[string]interface{}{"id": 2}); !errors.As(err, &validationErr) {
+		t.Errorf("expected ValidationError for empty update, got %v", err)
+	}
+}
+
+func TestPostgresAdapter_SoftDelete(t *testing.T) {
+	registry := NewTypeRegistry()
+	registry.Models["Post"] = &ModelInfo{
+		Name: "Post",
+		Fields: []FieldInfo{
+			{Name: "id", FieldType: "uuid", Primary: true},
+			{Name: "deleted_at", FieldType: "timestamp"},
+		},
+	}
+
+	adapter := NewPostgresAdapter(nil, registry)
+	meta := adapter.entities["Post"]
+	if meta.TableName != "posts" {
+		t.Errorf("expected table posts, got %s", meta.TableName)
+	}
+	if meta.SoftDelete != "deleted_at" {
+		t.Errorf("expected soft delete column deleted_at, got %q", meta.SoftDelete)
+	}
+}
+
+func TestPostgresAdapter_DecodeRow(t *testing.T) {
+	adapter := NewPostgresAdapter(nil, NewTypeRegistry())
+	m := &pgModel{
+		fields:    map[string]string{"tags": "list", "scores": "list", "price": "decimal", "meta": "jsonb", "id": "uuid"},
+		elemTypes: map[string]string{"tags": "string", "scores": "int"},
+	}
+	row := map[string]interface{}{
+		"tags":   []byte(`{go,"hello world"}`),
+		"scores": []byte(`{1,2}`),
+		"price":  []byte("12.50"),
+		"meta":   []byte(`{"a":1}`),
+		"id":     []byte("4c3b8a9e-0000-0000-0000-000000000000"),
+	}
+
+	adapter.decodeRow(m, row)
+
+	want := map[string]interface{}{
+		"tags":   []interface{}{"go", "hello world"},
+		"scores": []interface{}{int64(1), int64(2)},
+		"price":  12.5,
+		"meta":   map[string]interface{}{"a": float64(1)},
+		"id":     "4c3b8a9e-0000-0000-0000-000000000000",
+	}
+	if !reflect.DeepEqual(row, want) {
+		t.Errorf("decodeRow = %#v, want %#v", row, want)
+	}
+}
+
+func TestSplitFieldType(t *testing.T) {
+	tests := []struct {
+		fieldType  string
+		base, elem string
+	}{
+		{"string", "string", ""},
+		{"ref(User)", "ref", ""},
+		{"list(int)", "list", "int"},
+		{"list(enum(a, b))", "list", "enum"},
+	}
+	for _, tt := range tests {
+		base, elem := splitFieldType(tt.fieldType)
+		if base != tt.base || elem != tt.elem {
+			t.Errorf("splitFieldType(%q) = %q, %q, want %q, %q", tt.fieldType, base, elem, tt.base, tt.elem)
+		}
+	}
+}
+
+func TestExecutionContext_UsesDatabaseAdapter(t *testing.T) {
+	adapter, _ := newTestAdapter(t)
+	code := &GeneratedCode{
+		Middlewares:   make(map[string]func(http.Handler) http.Handler),
+		ModelRegistry: testPostgresRegistry(),
+	}
+
+	factory := NewExecutionContextFactory(code)
+	factory.db = adapter
+	ctx := factory.NewContext(context.Background(), httptest.NewRequest("GET", "/", nil))
+
+	result, err := ctx.FindOne("User", 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != nil {
+		t.Errorf("expected nil result for a missing row, got %#v", result)
+	}
+
+	id, err := ctx.InsertDatabase("User", map[string]interface{}{"email": "grace@example.com"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := ctx.DeleteDatabase("User", id); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := ctx.DeleteDatabase("User", id); !errors.As(err, new(*NotFoundError)) {
+		t.Errorf("expected NotFoundError deleting twice, got %v", err)
+	}
+}
+
+func TestExecuteDBFind_PolicyRowFilter(t *testing.T) {
+	adapter, _ := newTestAdapter(t)
+	for _, email := range []string{"ada@example.com", "grace@example.com"} {
+		if _, err := adapter.Insert(context.Background(), "User", map[string]interface{}{"email": email}); err != nil {
+			t.Fatalf("insert failed: %v", err)
+		}
+	}
+
+	policies := abac.NewEngine()
+	if err := policies.Add("user.read", `resource.email == auth.email or auth.role == "admin"`); err != nil {
+		t.Fatalf("adding policy: %v", err)
+	}
+	if err := policies.Add("user.audit", `auth.role == "auditor"`); err != nil {
+		t.Fatalf("adding policy: %v", err)
+	}
+	factory := NewExecutionContextFactory(&GeneratedCode{
+		Middlewares:   make(map[string]func(http.Handler) http.Handler),
+		ModelRegistry: testPostgresRegistry(),
+		Policies:      policies,
+	})
+	factory.db = adapter
+
+	tests := []struct {
+		name   string
+		user   *auth.User
+		policy string
+		want   int
+	}{
+		{"own row", &auth.User{ID: "1", Email: "ada@example.com", Roles: []string{"member"}}, "user.read", 1},
+		{"admin", &auth.User{ID: "9", Roles: []string{"admin"}}, "user.read", 2},
+		{"no rows", &auth.User{ID: "9", Roles: []string{"admin"}}, "user.audit", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/users", nil)
+			req = req.WithContext(auth.ContextWithUser(req.Context(), tt.user))
+			ctx := factory.NewContext(req.Context(), req)
+
+			step := &ast.LogicStep{
+				Action: "db.find",
+				Target: "users",
+				Args:   []string{"User"},
+				Options: []*ast.Option{{
+					Key:   "authorize",
+					Value: &ast.StringLiteral{Value: tt.policy},
+				}},
+			}
+			if err := executeDBFind(ctx, step); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			rows, ok := ctx.Get("users").([]map[string]interface{})
+			if !ok {
+				t.Fatalf("expected rows, got %T", ctx.Get("users"))
+			}
+			if len(rows) != tt.want {
+				t.Errorf("expected %d rows, got %d", tt.want, len(rows))
+			}
+		})
+	}
+}
+
+func TestPostgresAdapter_QueryWhere_UnknownField(t *testing.T) {
+	adapter, _ := newTestAdapter(t)
+
+	where := &query.WhereClause{
+		Operator:   query.LogicalAnd,
+		Conditions: []query.Condition{{Field: "owner_id", Operator: query.OpEquals, Value: "u1"}},
+	}
+	_, err := adapter.QueryWhere(context.Background(), "User", nil, where)
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Errorf("expected ValidationError, got %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+package codegen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-chi/chi/v5"
+	goredis "github.com/redis/go-redis/v9"
+
+	"github.com/bargom/codeai/internal/ast"
+	"github.com/bargom/codeai/internal/event/replay"
+	"github.com/bargom/codeai/internal/rbac"
+	"github.com/bargom/codeai/pkg/integration/redis"
+)
+
+// loadRBAC creates the RBAC engine on Config.RBACStorage, or in memory, and
+// stores the roles declared in the DSL that the storage does not have yet,
+// so roles changed at runtime survive a restart. With an rbac_redis_url in
+// the DSL config block, role changes are announced to the other instances
+// sharing the storage.
+//
+//	config {
+//	  rbac_redis_url: "redis://localhost:6379"
+//	}
+func (g *generator) loadRBAC(program *ast.Program, code *GeneratedCode) error {
+	storage := g.config.RBACStorage
+	if storage == nil {
+		storage = rbac.NewMemoryStorage()
+	}
+
+	ctx := context.Background()
+	for name, loaded := range code.AuthLoader.AllRoles() {
+		_, err := storage.GetRole(ctx, name)
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, rbac.ErrRoleNotFound) {
+			return fmt.Errorf("loading role %q: %w", name, err)
+		}
+		role := &rbac.Role{Name: name, Permissions: make([]rbac.Permission, 0, len(loaded.Permissions))}
+		for _, perm := range loaded.Permissions {
+			role.Permissions = append(role.Permissions, rbac.Permission(perm))
+		}
+		if err := storage.SaveRole(ctx, role); err != nil {
+			return fmt.Errorf("storing role %q: %w", name, err)
+		}
+	}
+	code.RBAC = rbac.NewEngineWithLogger(storage, g.logger)
+
+	url := configString(configSettings(program), "rbac_redis_url")
+	if url == "" {
+		return nil
+	}
+	opts, err := goredis.ParseURL(url)
+	if err != nil {
+		return fmt.Errorf("parsing rbac_redis_url: %w", err)
+	}
+	cfg := redis.DefaultConfig()
+	cfg.Addr = opts.Addr
+	cfg.Password = opts.Password
+	cfg.DB = opts.DB
+	client, err := redis.NewClient(cfg)
+	if err != nil {
+		return fmt.Errorf("connecting to rbac_redis_url: %w", err)
+	}
+	code.RBACInvalidator = rbac.NewRedisInvalidator(client, code.RBAC, rbac.WithInvalidatorLogger(g.logger))
+	return nil
+}
+
+// registerAdminRoutes mounts the runtime role administration endpoints,
+// and with Config.EventRepository the event replay endpoints, behind the
+// first authentication middleware declared in the DSL. Without one, nobody
+// could be authorized for them, so they are not mounted. Replays further
+// require the replay.AdminPermission.
+func (g *generator) registerAdminRoutes(r chi.Router, program *ast.Program, code *GeneratedCode) error {
+	authName := ""
+	for _, stmt := range program.Statements {
+		if decl, ok := stmt.(*ast.MiddlewareDecl); ok && decl.MiddlewareType == "authentication" {
+			authName = decl.Name
+			break
+		}
+	}
+	if authName == "" {
+		g.logger.Debug("no authentication middleware, admin routes not mounted")
+		return nil
+	}
+
+	chain, err := g.buildMiddlewareChain([]*ast.MiddlewareRef{{Name: authName}}, code)
+	if err != nil {
+		return fmt.Errorf("building admin middleware: %w", err)
+	}
+
+	opts := []rbac.HandlerOption{rbac.WithHandlerLogger(g.logger)}
+	if code.RBACInvalidator != nil {
+		opts = append(opts, rbac.WithInvalidator(code.RBACInvalidator))
+	}
+	r.Group(func(r chi.Router) {
+		for _, mw := range chain {
+			r.Use(mw)
+		}
+		rbac.NewHandler(code.RBAC, opts...).RegisterRoutes(r)
+
+		if g.config.EventRepository == nil {
+			return
+		}
+		var replayOpts []replay.Option
+		if g.config.ReplayCheckpoints != nil {
+			replayOpts = append(replayOpts, replay.WithCheckpoints(g.config.ReplayCheckpoints))
+		}
+		code.Replays = replay.NewHandler(code.NewReplayer(g.config.EventRepository, replayOpts...))
+		r.With(rbac.NewMiddleware(code.RBAC).RequirePermission(replay.AdminPermission)).Group(code.Replays.RegisterRoutes)
+	})
+	g.logger.Debug("registered admin routes", "middleware", authName)
+	return nil
+}
+
+// authorizeRole reports whether the user of ctx has role, as granted by
+// their token, assigned at runtime or inherited.
+func authorizeRole(ctx *ExecutionContext, engine *rbac.Engine, role string) error {
+	if engine.CheckRole(ctx.Context(), ctx.User(), role) {
+		return nil
+	}
+	return &AuthorizationError{
+		Message:      "insufficient permissions",
+		RequiredRole: role,
+	}
+}
@@ -0,0 +1,50 @@
+package codegen
+
+import (
+	"context"
+
+	"github.com/bargom/codeai/internal/event"
+	"github.com/bargom/codeai/internal/event/bus"
+	"github.com/bargom/codeai/internal/event/replay"
+	eventrepository "github.com/bargom/codeai/internal/event/repository"
+)
+
+// metricsReplayTarget names the event metrics subscriber as a replay target.
+const metricsReplayTarget = "metrics"
+
+// ReplayTargets returns what stored events can be replayed into: the DSL
+// event handlers, named by action and target such as "workflow:fulfil",
+// and the event metrics subscriber, named "metrics".
+func (code *GeneratedCode) ReplayTargets() []replay.Target {
+	var targets []replay.Target
+	if code.EventHandlers != nil {
+		for _, rh := range code.EventHandlers.AllHandlers() {
+			rh := rh
+			targets = append(targets, replay.Target{
+				Name:      rh.Name(),
+				EventType: bus.EventType(rh.EventName),
+				Subscriber: bus.SubscriberFunc(func(ctx context.Context, e bus.Event) error {
+					return rh.Handle(ctx, event.FromBusEvent(e))
+				}),
+			})
+		}
+	}
+	if code.EventMetrics != nil {
+		targets = append(targets, replay.Target{
+			Name:       metricsReplayTarget,
+			EventType:  bus.AllEvents,
+			Subscriber: code.EventMetrics,
+		})
+	}
+	return targets
+}
+
+// NewReplayer creates a replayer reading events from events and replaying
+// them into the ReplayTargets, upcast to the latest version of their DSL
+// event.
+func (code *GeneratedCode) NewReplayer(events eventrepository.EventRepository, opts ...replay.Option) *replay.Replayer {
+	if code.EventHandlers != nil {
+		opts = append([]replay.Option{replay.WithUpcaster(code.EventHandlers)}, opts...)
+	}
+	return replay.NewReplayer(events, code.ReplayTargets(), opts...)
+}
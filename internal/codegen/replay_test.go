@@ -0,0 +1,160 @@
+package codegen
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/bargom/codeai/internal/event/bus"
+	"github.com/bargom/codeai/internal/event/replay"
+	eventrepository "github.com/bargom/codeai/internal/event/repository"
+	"github.com/bargom/codeai/internal/parser"
+)
+
+// ListEvents returns the saved events, oldest first, as a single page.
+func (r *recordingEventRepository) ListEvents(_ context.Context, _ eventrepository.EventFilter) ([]bus.Event, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]bus.Event(nil), r.events...), nil
+}
+
+func TestReplayTargets(t *testing.T) {
+	hooks, hookRequests := recordingServer(t)
+
+	program, err := parser.Parse(fmt.Sprintf(`
+webhook shipping {
+	event "order_created"
+	url "%s/shipping"
+	method POST
+}
+
+event order_created {
+	schema {
+		order_id string
+	}
+}
+
+event order_audited {
+	schema {
+		order_id string
+	}
+}
+
+on "order_created" do webhook "shipping"
+on "order_created" do emit "order_audited"
+`, hooks.URL))
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	repo := &recordingEventRepository{}
+	code, err := NewGenerator(&Config{EventRepository: repo}).GenerateFromAST(program)
+	if err != nil {
+		t.Fatalf("code generation failed: %v", err)
+	}
+	t.Cleanup(code.Events.Close)
+
+	ctx := context.Background()
+	if err := code.EventHandlers.EmitEvent(ctx, "order_created", map[string]interface{}{"order_id": "o-1"}); err != nil {
+		t.Fatalf("emit failed: %v", err)
+	}
+	if len(repo.events) != 2 || len(hookRequests()) != 1 {
+		t.Fatalf("expected 2 persisted events and 1 webhook delivery, got %d and %d", len(repo.events), len(hookRequests()))
+	}
+
+	replayer := code.NewReplayer(repo)
+	want := []string{"emit:order_audited", "metrics", "webhook:shipping"}
+	if got := replayer.Targets(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected targets %v, got %v", want, got)
+	}
+
+	result, err := replayer.Run(ctx, replay.Options{Targets: []string{"webhook:shipping", "emit:order_audited", "metrics"}})
+	if err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+	if result.Replayed != 2 {
+		t.Errorf("expected 2 events replayed, got %d", result.Replayed)
+	}
+
+	// Handlers ran, but the webhook was not sent again and the re-emitted
+	// event was published without being persisted
+	if got := len(hookRequests()); got != 1 {
+		t.Errorf("expected webhooks to be suppressed during replay, got %d deliveries", got)
+	}
+	if got := len(repo.events); got != 2 {
+		t.Errorf("expected replayed events not to be persisted, got %d events", got)
+	}
+	if got := code.EventMetrics.GetTypeCount("order_created"); got != 2 {
+		t.Errorf("expected order_created counted twice, got %d", got)
+	}
+	if got := code.EventMetrics.GetTypeCount("order_audited"); got != 3 {
+		t.Errorf("expected order_audited counted three times, got %d", got)
+	}
+}
+
+func TestReplayUpcastsStoredEvents(t *testing.T) {
+	program, err := parser.Parse(`
+event user.created {
+	schema {
+		user_id string
+		name string
+	}
+}
+
+event user.created version 2 {
+	breaking
+	schema {
+		user_id string
+		full_name string
+	}
+	upcast from 1 {
+		full_name: "payload.name"
+	}
+}
+
+event user.indexed {
+	schema {
+		user_id string
+		full_name string
+	}
+}
+
+on "user.created" do emit "user.indexed"
+`)
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	repo := &recordingEventRepository{}
+	code, err := NewGenerator(&Config{EventRepository: repo}).GenerateFromAST(program)
+	if err != nil {
+		t.Fatalf("code generation failed: %v", err)
+	}
+	t.Cleanup(code.Events.Close)
+
+	var indexed []bus.Event
+	code.Events.Subscribe("user.indexed", bus.SubscriberFunc(func(_ context.Context, e bus.Event) error {
+		indexed = append(indexed, e)
+		return nil
+	}))
+
+	// An event stored before version 2 was declared
+	repo.events = append(repo.events, bus.Event{
+		ID:      "e1",
+		Type:    "user.created",
+		Version: 1,
+		Data:    map[string]interface{}{"user_id": "u-1", "name": "Ada"},
+	})
+
+	if _, err := code.NewReplayer(repo).Run(context.Background(), replay.Options{Targets: []string{"emit:user.indexed"}}); err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+	if len(indexed) != 1 {
+		t.Fatalf("expected 1 indexed event, got %d", len(indexed))
+	}
+	want := map[string]interface{}{"user_id": "u-1", "full_name": "Ada"}
+	if !reflect.DeepEqual(indexed[0].Data, want) {
+		t.Errorf("expected the replayed payload upcast to %v, got %v", want, indexed[0].Data)
+	}
+}
@@ -9,12 +9,18 @@ import (
 	"log/slog"
 	"net/http"
 	"sync"
-	"time"
 
+	"github.com/bargom/codeai/internal/abac"
+	"github.com/bargom/codeai/internal/ast"
+	"github.com/bargom/codeai/internal/auth"
 	"github.com/bargom/codeai/internal/database"
 	"github.com/bargom/codeai/internal/database/mongodb"
 	"github.com/bargom/codeai/internal/event"
+	"github.com/bargom/codeai/internal/event/bus"
+	"github.com/bargom/codeai/internal/event/dispatcher"
 	"github.com/bargom/codeai/internal/integration"
+	"github.com/bargom/codeai/internal/query"
+	"github.com/bargom/codeai/internal/rbac"
 )
 
 // ExecutionContextFactory creates execution contexts for handlers.
@@ -26,6 +32,7 @@ type ExecutionContextFactory struct {
 		Ping() error
 		MongoClient() interface{}
 	}
+	db         DatabaseAdapter
 	transforms map[string]TransformFunc
 	mu         sync.RWMutex
 }
@@ -48,33 +55,67 @@ func (f *ExecutionContextFactory) RegisterTransform(name string, fn TransformFun
 	f.transforms[name] = fn
 }
 
-// NewContext creates a new execution context for a request.
+// NewContext creates a new execution context for a request. The claims of
+// the context are those of the user authenticated by the endpoint's
+// middleware, if any.
 func (f *ExecutionContextFactory) NewContext(ctx context.Context, r *http.Request) *ExecutionContext {
-	return &ExecutionContext{
+	c := &ExecutionContext{
 		ctx:           ctx,
 		request:       r,
 		data:          make(map[string]interface{}),
 		dbConnection:  f.dbConnection,
+		db:            f.db,
 		generatedCode: f.generatedCode,
 		transforms:    f.transforms,
 		logger:        slog.Default(),
 	}
+	if r != nil {
+		if user := auth.UserFromContext(r.Context()); user != nil {
+			c.user = user
+			c.claims = userClaims(user)
+		}
+	}
+	return c
+}
+
+// userClaims returns the claims of an authenticated user, with its roles and
+// permissions in the form executeAuthorize checks.
+func userClaims(user *auth.User) map[string]interface{} {
+	claims := make(map[string]interface{}, len(user.Claims)+3)
+	for k, v := range user.Claims {
+		claims[k] = v
+	}
+	claims["sub"] = user.ID
+	roles := make([]interface{}, len(user.Roles))
+	for i, role := range user.Roles {
+		roles[i] = role
+	}
+	claims["roles"] = roles
+	permissions := make([]interface{}, len(user.Permissions))
+	for i, p := range user.Permissions {
+		permissions[i] = p
+	}
+	claims["permissions"] = permissions
+	return claims
 }
 
 // ExecutionContext holds the runtime state for executing handler logic.
 type ExecutionContext struct {
 	ctx          context.Context
 	request      *http.Request
+	requestType  *ast.RequestType
 	input        interface{}
 	result       interface{}
 	data         map[string]interface{}
 	claims       map[string]interface{}
+	user         *auth.User
 	dbConnection interface {
 		Type() database.DatabaseType
 		Close() error
 		Ping() error
 		MongoClient() interface{}
 	}
+	db            DatabaseAdapter
 	generatedCode *GeneratedCode
 	transforms    map[string]TransformFunc
 	logger        *slog.Logger
@@ -91,6 +132,21 @@ func (c *ExecutionContext) Request() *http.Request {
 	return c.request
 }
 
+// SetRequestType sets the endpoint's request declaration.
+func (c *ExecutionContext) SetRequestType(req *ast.RequestType) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.requestType = req
+}
+
+// RequestType returns the endpoint's request declaration, or nil if the
+// endpoint declares none.
+func (c *ExecutionContext) RequestType() *ast.RequestType {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.requestType
+}
+
 // SetInput sets the parsed request input.
 func (c *ExecutionContext) SetInput(input interface{}) {
 	c.mu.Lock()
@@ -162,6 +218,30 @@ func (c *ExecutionContext) Claims() map[string]interface{} {
 	return c.claims
 }
 
+// User returns the authenticated user, or nil if the request is not
+// authenticated.
+func (c *ExecutionContext) User() *auth.User {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.user
+}
+
+// policies returns the authorization policies of the application, if any.
+func (c *ExecutionContext) policies() *abac.Engine {
+	if c.generatedCode == nil {
+		return nil
+	}
+	return c.generatedCode.Policies
+}
+
+// rbac returns the role engine of the application, if any.
+func (c *ExecutionContext) rbac() *rbac.Engine {
+	if c.generatedCode == nil {
+		return nil
+	}
+	return c.generatedCode.RBAC
+}
+
 // Data returns all stored data.
 func (c *ExecutionContext) Data() map[string]interface{} {
 	c.mu.RLock()
@@ -180,55 +260,42 @@ func (c *ExecutionContext) QueryDatabase(table string, conditions map[string]int
 	c.logger.Debug("query database", "table", table, "conditions", conditions)
 
 	// If no database connection, return empty array
-	if c.dbConnection == nil {
+	if c.db == nil {
 		c.logger.Warn("no database connection, returning empty array")
 		return []map[string]interface{}{}, nil
 	}
 
-	// Get MongoDB client if available
-	mongoClientInterface := c.dbConnection.MongoClient()
-	if mongoClientInterface == nil {
-		c.logger.Warn("MongoDB client not available")
-		return []map[string]interface{}{}, nil
-	}
-
-	// Type assert to actual mongodb.Client
-	mongoClient, ok := mongoClientInterface.(*mongodb.Client)
-	if !ok {
-		c.logger.Warn("failed to assert MongoDB client type")
-		return []map[string]interface{}{}, nil
-	}
-
-	// Get collection
-	collection := mongoClient.Collection(table)
-	if collection == nil {
-		return nil, fmt.Errorf("failed to get collection: %s", table)
-	}
+	return c.db.Query(c.ctx, table, conditions)
+}
 
-	c.logger.Info("querying documents", "collection", table, "conditions", conditions)
+// QueryDatabaseWhere executes a database query returning the results that
+// match the equality conditions and where.
+func (c *ExecutionContext) QueryDatabaseWhere(table string, conditions map[string]interface{}, where *query.WhereClause) (interface{}, error) {
+	c.logger.Debug("query database", "table", table, "conditions", conditions, "filtered", where != nil)
 
-	// Execute query
-	cursor, err := collection.Find(c.ctx, conditions)
-	if err != nil {
-		return nil, fmt.Errorf("MongoDB find failed: %w", err)
-	}
-	defer cursor.Close(c.ctx)
-
-	// Decode results
-	var results []map[string]interface{}
-	if err := cursor.All(c.ctx, &results); err != nil {
-		return nil, fmt.Errorf("decoding results: %w", err)
+	if c.db == nil {
+		c.logger.Warn("no database connection, returning empty array")
+		return []map[string]interface{}{}, nil
 	}
 
-	return results, nil
+	return c.db.QueryWhere(c.ctx, table, conditions, where)
 }
 
-// FindOne executes a database query returning a single result.
+// FindOne executes a database query returning a single result. It returns
+// nil if no record has the given id.
 func (c *ExecutionContext) FindOne(table string, id interface{}) (interface{}, error) {
 	c.logger.Debug("find one", "table", table, "id", id)
 
-	// TODO: Integrate with actual database layer
-	return nil, nil
+	if c.db == nil {
+		c.logger.Warn("no database connection, returning no record")
+		return nil, nil
+	}
+
+	row, err := c.db.FindOne(c.ctx, table, id)
+	if err != nil || row == nil {
+		return nil, err
+	}
+	return row, nil
 }
 
 // InsertDatabase inserts a record into the database.
@@ -236,78 +303,38 @@ func (c *ExecutionContext) InsertDatabase(table string, data interface{}) (inter
 	c.logger.Debug("insert database", "table", table)
 
 	// If no database connection, return mock data (for testing)
-	if c.dbConnection == nil {
+	if c.db == nil {
 		c.logger.Warn("no database connection, returning mock data")
 		return "mock-id-123", nil
 	}
 
-	// Get MongoDB client if available
-	mongoClientInterface := c.dbConnection.MongoClient()
-	if mongoClientInterface == nil {
-		c.logger.Warn("MongoDB client not available")
-		return "mock-id-123", nil
-	}
-
-	// Type assert to actual mongodb.Client
-	mongoClient, ok := mongoClientInterface.(*mongodb.Client)
-	if !ok {
-		c.logger.Warn("failed to assert MongoDB client type")
-		return "mock-id-123", nil
-	}
-
-	// Convert data to map if it isn't already
-	dataMap, ok := data.(map[string]interface{})
-	if !ok {
-		// Try to convert via JSON
-		jsonBytes, err := json.Marshal(data)
-		if err != nil {
-			return nil, fmt.Errorf("converting data to map: %w", err)
-		}
-		if err := json.Unmarshal(jsonBytes, &dataMap); err != nil {
-			return nil, fmt.Errorf("unmarshaling data: %w", err)
-		}
-	}
-
-	// Add timestamps if not present
-	now := time.Now()
-	if _, ok := dataMap["created_at"]; !ok {
-		dataMap["created_at"] = now
-	}
-	if _, ok := dataMap["updated_at"]; !ok {
-		dataMap["updated_at"] = now
-	}
-
-	// Get collection and insert document
-	collection := mongoClient.Collection(table)
-	if collection == nil {
-		return nil, fmt.Errorf("failed to get collection: %s", table)
-	}
-
-	c.logger.Info("inserting document", "collection", table, "data", dataMap)
-
-	result, err := collection.InsertOne(c.ctx, dataMap)
-	if err != nil {
-		return nil, fmt.Errorf("MongoDB insert failed: %w", err)
-	}
-
-	// Return the inserted ID
-	return result.InsertedID, nil
+	return c.db.Insert(c.ctx, table, data)
 }
 
-// UpdateDatabase updates a record in the database.
-func (c *ExecutionContext) UpdateDatabase(table string, id interface{}, data interface{}) error {
+// UpdateDatabase updates a record in the database. It returns a NotFoundError
+// if no record has the given id.
+func (c *ExecutionContext) UpdateDatabase(table string, id interface{}, data interface{}) (*WriteResult, error) {
 	c.logger.Debug("update database", "table", table, "id", id)
 
-	// TODO: Integrate with actual database layer
-	return nil
+	if c.db == nil {
+		c.logger.Warn("no database connection, skipping update")
+		return &WriteResult{}, nil
+	}
+
+	return c.db.Update(c.ctx, table, id, data)
 }
 
-// DeleteDatabase deletes a record from the database.
+// DeleteDatabase deletes a record from the database. It returns a
+// NotFoundError if no record has the given id.
 func (c *ExecutionContext) DeleteDatabase(table string, id interface{}) error {
 	c.logger.Debug("delete database", "table", table, "id", id)
 
-	// TODO: Integrate with actual database layer
-	return nil
+	if c.db == nil {
+		c.logger.Warn("no database connection, skipping delete")
+		return nil
+	}
+
+	return c.db.Delete(c.ctx, table, id)
 }
 
 // Transform applies a named transformation to data.
@@ -475,54 +502,53 @@ type Dispatcher interface {
 	Unsubscribe(eventType event.EventType, handler event.Handler)
 }
 
-// NewDispatcher creates a new in-memory event dispatcher.
-func NewDispatcher() Dispatcher {
-	return &inMemoryDispatcher{
-		handlers: make(map[event.EventType][]event.Handler),
-	}
-}
-
-type inMemoryDispatcher struct {
-	mu       sync.RWMutex
-	handlers map[event.EventType][]event.Handler
-}
-
-func (d *inMemoryDispatcher) Dispatch(ctx context.Context, evt event.Event) error {
-	d.mu.RLock()
-	handlers := d.handlers[evt.Type]
-	d.mu.RUnlock()
-
-	for _, h := range handlers {
-		if err := h(ctx, evt); err != nil {
-			slog.Error("event handler failed", "event", evt.Type, "error", err)
-		}
-	}
-
-	return nil
-}
-
-func (d *inMemoryDispatcher) Subscribe(eventType event.EventType, handler event.Handler) {
-	d.mu.Lock()
-	defer d.mu.Unlock()
-	d.handlers[eventType] = append(d.handlers[eventType], handler)
-}
-
-func (d *inMemoryDispatcher) Unsubscribe(eventType event.EventType, handler event.Handler) {
-	d.mu.Lock()
-	defer d.mu.Unlock()
-	// Note: Simple implementation, doesn't remove specific handler
-	// In production, would need to compare function pointers
+// NewDispatcher creates an event dispatcher publishing to eb, such as the
+// shared GeneratedCode.EventBus. The caller owns eb and closes it.
+func NewDispatcher(eb *bus.EventBus) Dispatcher {
+	return event.NewBusDispatcher(dispatcher.NewDispatcher(eb), nil)
 }
 
 // DatabaseAdapter provides an interface for database operations.
 type DatabaseAdapter interface {
 	Query(ctx context.Context, table string, conditions map[string]interface{}) ([]map[string]interface{}, error)
+	// QueryWhere is Query with an additional condition, such as the row
+	// filter of an authorization policy. A nil where adds no condition.
+	QueryWhere(ctx context.Context, table string, conditions map[string]interface{}, where *query.WhereClause) ([]map[string]interface{}, error)
 	FindOne(ctx context.Context, table string, id interface{}) (map[string]interface{}, error)
 	Insert(ctx context.Context, table string, data interface{}) (interface{}, error)
-	Update(ctx context.Context, table string, id interface{}, data interface{}) error
+	Update(ctx context.Context, table string, id interface{}, data interface{}) (*WriteResult, error)
 	Delete(ctx context.Context, table string, id interface{}) error
 }
 
+// Transactor is implemented by database adapters that can run a function in
+// a transaction. Operations using the context passed to fn join it.
+type Transactor interface {
+	InTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// WriteResult reports how many records an update matched and modified.
+type WriteResult struct {
+	Matched  int64 `json:"matched"`
+	Modified int64 `json:"modified"`
+}
+
+// NewDatabaseAdapter returns the adapter for a database connection, or nil if
+// the connection type is not supported.
+func NewDatabaseAdapter(conn interface {
+	Type() database.DatabaseType
+	MongoClient() interface{}
+}, registry *TypeRegistry, opts ...AdapterOption) DatabaseAdapter {
+	if pg, ok := conn.(*database.PostgresConnection); ok && pg.DB != nil {
+		return NewPostgresAdapter(pg.DB, registry, opts...)
+	}
+	if conn != nil {
+		if client, ok := conn.MongoClient().(*mongodb.Client); ok {
+			return NewMongoAdapter(client, nil, opts...)
+		}
+	}
+	return nil
+}
+
 // CacheAdapter provides an interface for cache operations.
 type CacheAdapter interface {
 	Get(ctx context.Context, key string) (interface{}, error)
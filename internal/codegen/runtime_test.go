@@ -6,7 +6,10 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/bargom/codeai/internal/ast"
+	"github.com/bargom/codeai/internal/auth"
 	"github.com/bargom/codeai/internal/event"
+	"github.com/bargom/codeai/internal/event/bus"
 	"github.com/bargom/codeai/internal/integration"
 	"github.com/bargom/codeai/internal/workflow"
 )
@@ -192,6 +195,30 @@ func TestExecutionContext_Claims(t *testing.T) {
 	}
 }
 
+func TestExecutionContext_ClaimsFromUser(t *testing.T) {
+	code := &GeneratedCode{
+		Middlewares:   make(map[string]func(http.Handler) http.Handler),
+		ModelRegistry: NewTypeRegistry(),
+	}
+
+	user := &auth.User{ID: "user123", Roles: []string{"admin"}, Claims: map[string]interface{}{"tenant": "acme"}}
+	req := httptest.NewRequest("GET", "/", nil)
+	req = req.WithContext(auth.ContextWithUser(req.Context(), user))
+
+	ctx := NewExecutionContextFactory(code).NewContext(req.Context(), req)
+	if ctx.User() != user {
+		t.Errorf("expected authenticated user, got %v", ctx.User())
+	}
+
+	claims := ctx.Claims()
+	if claims["sub"] != "user123" || claims["tenant"] != "acme" {
+		t.Errorf("unexpected claims: %v", claims)
+	}
+	if err := executeAuthorize(ctx, &ast.LogicStep{Action: "authorize", Args: []string{"request", "admin"}}); err != nil {
+		t.Errorf("expected admin role from user, got %v", err)
+	}
+}
+
 func TestExecutionContext_Data(t *testing.T) {
 	code := &GeneratedCode{
 		Middlewares:   make(map[string]func(http.Handler) http.Handler),
@@ -373,7 +400,9 @@ func TestExecutionContext_StartWorkflow_NotFound(t *testing.T) {
 }
 
 func TestInMemoryDispatcher(t *testing.T) {
-	dispatcher := NewDispatcher()
+	eb := bus.NewEventBus(nil)
+	defer eb.Close()
+	dispatcher := NewDispatcher(eb)
 
 	handlerCalled := false
 	handler := func(ctx context.Context, evt event.Event) error {
@@ -452,3 +481,9 @@ func TestExecutionContext_Request(t *testing.T) {
 		t.Errorf("expected method POST, got %s", ctx.Request().Method)
 	}
 }
+
+func TestNewDatabaseAdapter_Unsupported(t *testing.T) {
+	if adapter := NewDatabaseAdapter(nil, NewTypeRegistry()); adapter != nil {
+		t.Errorf("expected nil adapter without a connection, got %T", adapter)
+	}
+}
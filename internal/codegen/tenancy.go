@@ -0,0 +1,92 @@
+package codegen
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/bargom/codeai/internal/ast"
+	"github.com/bargom/codeai/internal/tenant"
+)
+
+// TenantDatabases returns the database of a tenant under the database
+// tenancy strategy.
+type TenantDatabases func(ctx context.Context, tenantID string) (*sql.DB, error)
+
+// AdapterOption configures a database adapter.
+type AdapterOption func(*adapterOptions)
+
+type adapterOptions struct {
+	tenancy   *tenant.Config
+	databases TenantDatabases
+}
+
+// WithTenancy scopes all data access of an adapter to the tenant in the
+// context of each call. Calls without a tenant fail.
+func WithTenancy(config tenant.Config) AdapterOption {
+	if config.Strategy == "" {
+		config.Strategy = tenant.StrategyColumn
+	}
+	if config.Column == "" {
+		config.Column = tenant.DefaultColumn
+	}
+	return func(o *adapterOptions) {
+		o.tenancy = &config
+	}
+}
+
+// WithTenantDatabases sets the PostgreSQL databases of tenants under the
+// database strategy.
+func WithTenantDatabases(databases TenantDatabases) AdapterOption {
+	return func(o *adapterOptions) {
+		o.databases = databases
+	}
+}
+
+func newAdapterOptions(opts []AdapterOption) adapterOptions {
+	var o adapterOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// tenantOf returns the tenant in ctx, failing if there is none.
+func tenantOf(ctx context.Context) (string, error) {
+	id := tenant.FromContext(ctx)
+	if id == "" {
+		return "", tenant.ErrNoTenant
+	}
+	return id, nil
+}
+
+// checkTenantWrite returns an error if record sets the tenant column to
+// another tenant than id. Adapters set the column themselves.
+func checkTenantWrite(record map[string]interface{}, column, id string) error {
+	if v, ok := record[column]; ok && v != nil && fmt.Sprint(v) != id {
+		return tenant.ErrCrossTenant
+	}
+	return nil
+}
+
+// tenancyConfig converts a tenancy declaration to the tenant configuration.
+func tenancyConfig(decl *ast.TenancyDecl) (tenant.Config, error) {
+	config := tenant.Config{Strategy: tenant.StrategyColumn}
+	if decl.Strategy != "" {
+		strategy, err := tenant.ParseStrategy(decl.Strategy)
+		if err != nil {
+			return config, err
+		}
+		config.Strategy = strategy
+	}
+
+	settings := make(map[string]any, len(decl.Settings))
+	for key, expr := range decl.Settings {
+		settings[key] = extractExprValue(expr)
+	}
+	config.Claim = configString(settings, "claim")
+	config.Header = configString(settings, "header")
+	config.Column = configString(settings, "column")
+	config.Subdomain, _ = settings["subdomain"].(bool)
+	return config, nil
+}
@@ -0,0 +1,241 @@
+package codegen
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/bargom/codeai/internal/database"
+	"github.com/bargom/codeai/internal/parser"
+	"github.com/bargom/codeai/internal/tenant"
+)
+
+func testTenantRegistry() *TypeRegistry {
+	registry := NewTypeRegistry()
+	registry.Models["Project"] = &ModelInfo{
+		Name: "Project",
+		Fields: []FieldInfo{
+			{Name: "id", FieldType: "int", Primary: true},
+			{Name: "name", FieldType: "string", Required: true},
+			{Name: "tenant_id", FieldType: "string", Required: true},
+		},
+	}
+	return registry
+}
+
+func openTenantDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("opening database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	_, err = db.Exec(`CREATE TABLE projects (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		tenant_id TEXT
+	)`)
+	if err != nil {
+		t.Fatalf("creating table: %v", err)
+	}
+	return db
+}
+
+func TestPostgresAdapter_TenantIsolation(t *testing.T) {
+	adapter := NewPostgresAdapter(openTenantDB(t), testTenantRegistry(),
+		WithTenancy(tenant.Config{Claim: "org_id"}))
+	acme := tenant.WithTenant(context.Background(), "acme")
+	globex := tenant.WithTenant(context.Background(), "globex")
+
+	acmeID, err := adapter.Insert(acme, "Project", map[string]interface{}{"name": "rocket"})
+	if err != nil {
+		t.Fatalf("insert failed: %v", err)
+	}
+	globexID, err := adapter.Insert(globex, "Project", map[string]interface{}{"name": "volcano", "tenant_id": "globex"})
+	if err != nil {
+		t.Fatalf("insert failed: %v", err)
+	}
+
+	rows, err := adapter.Query(acme, "Project", nil)
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if len(rows) != 1 || rows[0]["name"] != "rocket" || rows[0]["tenant_id"] != "acme" {
+		t.Errorf("expected only acme's project, got %v", rows)
+	}
+
+	row, err := adapter.FindOne(acme, "Project", globexID)
+	if err != nil {
+		t.Fatalf("find failed: %v", err)
+	}
+	if row != nil {
+		t.Errorf("expected globex's project to be invisible to acme, got %v", row)
+	}
+
+	var notFound *NotFoundError
+	_, err = adapter.Update(acme, "Project", globexID, map[string]interface{}{"name": "stolen"})
+	if !errors.As(err, &notFound) {
+		t.Errorf("expected NotFoundError updating another tenant's project, got %v", err)
+	}
+	if err := adapter.Delete(acme, "Project", globexID); !errors.As(err, &notFound) {
+		t.Errorf("expected NotFoundError deleting another tenant's project, got %v", err)
+	}
+
+	_, err = adapter.Insert(acme, "Project", map[string]interface{}{"name": "spy", "tenant_id": "globex"})
+	if !errors.Is(err, tenant.ErrCrossTenant) {
+		t.Errorf("expected ErrCrossTenant inserting into another tenant, got %v", err)
+	}
+	if code := determineErrorStatusCode(err); code != http.StatusForbidden {
+		t.Errorf("expected status 403 for cross-tenant access, got %d", code)
+	}
+	_, err = adapter.Update(acme, "Project", acmeID, map[string]interface{}{"tenant_id": "globex"})
+	if !errors.Is(err, tenant.ErrCrossTenant) {
+		t.Errorf("expected ErrCrossTenant moving a project to another tenant, got %v", err)
+	}
+
+	if _, err := adapter.Query(context.Background(), "Project", nil); !errors.Is(err, tenant.ErrNoTenant) {
+		t.Errorf("expected ErrNoTenant without a tenant, got %v", err)
+	}
+
+	row, err = adapter.FindOne(globex, "Project", globexID)
+	if err != nil || row == nil || row["name"] != "volcano" {
+		t.Errorf("expected globex's project unchanged, got %v (err %v)", row, err)
+	}
+}
+
+func TestPostgresAdapter_TenantDatabases(t *testing.T) {
+	databases := map[string]*sql.DB{"acme": openTenantDB(t), "globex": openTenantDB(t)}
+	adapter := NewPostgresAdapter(openTenantDB(t), testTenantRegistry(),
+		WithTenancy(tenant.Config{Strategy: tenant.StrategyDatabase, Header: "X-Tenant-ID"}),
+		WithTenantDatabases(func(_ context.Context, id string) (*sql.DB, error) {
+			db, ok := databases[id]
+			if !ok {
+				return nil, errors.New("unknown tenant")
+			}
+			return db, nil
+		}))
+
+	acme := tenant.WithTenant(context.Background(), "acme")
+	if _, err := adapter.Insert(acme, "Project", map[string]interface{}{"name": "rocket"}); err != nil {
+		t.Fatalf("insert failed: %v", err)
+	}
+
+	var count int
+	if err := databases["acme"].QueryRow(`SELECT COUNT(*) FROM projects`).Scan(&count); err != nil || count != 1 {
+		t.Errorf("expected 1 project in acme's database, got %d (err %v)", count, err)
+	}
+	if err := databases["globex"].QueryRow(`SELECT COUNT(*) FROM projects`).Scan(&count); err != nil || count != 0 {
+		t.Errorf("expected no project in globex's database, got %d (err %v)", count, err)
+	}
+
+	if _, err := adapter.Query(tenant.WithTenant(context.Background(), "initech"), "Project", nil); err == nil {
+		t.Error("expected error for a tenant without a database")
+	}
+}
+
+func TestGenerateTenancy(t *testing.T) {
+	program, err := parser.Parse(`tenancy {
+	strategy: column
+	header: "X-Tenant-ID"
+}
+
+endpoint GET "/health" {
+	response HealthResponse status 200
+}`)
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+
+	code, err := NewGenerator(nil).GenerateFromAST(program)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if code.Tenancy == nil {
+		t.Fatal("expected tenancy resolver")
+	}
+	if cfg := code.Tenancy.Config(); cfg.Header != "X-Tenant-ID" || cfg.Column != tenant.DefaultColumn {
+		t.Errorf("unexpected tenancy config %+v", cfg)
+	}
+
+	w := httptest.NewRecorder()
+	code.Router.ServeHTTP(w, httptest.NewRequest("GET", "/health", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d without a tenant, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	req := httptest.NewRequest("GET", "/health", nil)
+	req.Header.Set("X-Tenant-ID", "acme")
+	w = httptest.NewRecorder()
+	code.Router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+}
+
+func TestGenerateTenancy_DatabaseStrategyRequiresDatabases(t *testing.T) {
+	program, err := parser.Parse(`tenancy {
+	strategy: database
+	claim: "org_id"
+}`)
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+
+	gen := NewGenerator(&Config{DBConnection: &database.PostgresConnection{DB: openTenantDB(t)}})
+	if _, err := gen.GenerateFromAST(program); err == nil {
+		t.Error("expected error for database strategy without tenant databases")
+	}
+}
+
+func TestDSLWebhookSender_TenantSource(t *testing.T) {
+	hooks, hookRequests := recordingServer(t)
+
+	program, err := parser.Parse(fmt.Sprintf(`
+webhook audit {
+	event "project_created"
+	url "%s/audit"
+	method POST
+}
+
+event project_created {
+	schema {
+		id int
+	}
+}
+
+on "project_created" do webhook "audit"
+`, hooks.URL))
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	code, err := NewGenerator(nil).GenerateFromAST(program)
+	if err != nil {
+		t.Fatalf("code generation failed: %v", err)
+	}
+
+	ctx := tenant.WithTenant(context.Background(), "acme")
+	if err := code.EventHandlers.EmitEvent(ctx, "project_created", map[string]interface{}{"id": 1}); err != nil {
+		t.Fatalf("emit failed: %v", err)
+	}
+
+	got := hookRequests()
+	if len(got) != 1 {
+		t.Fatalf("expected 1 webhook delivery, got %d", len(got))
+	}
+	if got[0].body["source"] != "acme/codeai.dsl" {
+		t.Errorf("expected tenant-prefixed source, got %v", got[0].body["source"])
+	}
+	metadata, _ := got[0].body["metadata"].(map[string]interface{})
+	if metadata["tenant"] != "acme" {
+		t.Errorf("expected tenant metadata, got %v", got[0].body["metadata"])
+	}
+}
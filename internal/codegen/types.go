@@ -5,13 +5,30 @@ package codegen
 
 import (
 	"net/http"
+	"regexp"
 
 	"github.com/go-chi/chi/v5"
 
+	"github.com/bargom/codeai/internal/abac"
+	"github.com/bargom/codeai/internal/ast"
 	"github.com/bargom/codeai/internal/auth"
+	"github.com/bargom/codeai/internal/auth/apikey"
+	"github.com/bargom/codeai/internal/auth/issuer"
 	"github.com/bargom/codeai/internal/database"
 	"github.com/bargom/codeai/internal/event"
+	"github.com/bargom/codeai/internal/event/bus"
+	"github.com/bargom/codeai/internal/event/dispatcher"
+	"github.com/bargom/codeai/internal/event/outbox"
+	"github.com/bargom/codeai/internal/event/replay"
+	eventrepository "github.com/bargom/codeai/internal/event/repository"
+	"github.com/bargom/codeai/internal/event/subscribers"
 	"github.com/bargom/codeai/internal/integration"
+	"github.com/bargom/codeai/internal/ratelimit"
+	"github.com/bargom/codeai/internal/rbac"
+	"github.com/bargom/codeai/internal/tenant"
+	"github.com/bargom/codeai/internal/webhook/inbound"
+	webhookrepository "github.com/bargom/codeai/internal/webhook/repository"
+	"github.com/bargom/codeai/internal/webhook/service"
 	"github.com/bargom/codeai/internal/workflow"
 )
 
@@ -29,12 +46,62 @@ type GeneratedCode struct {
 	// Workflows holds Temporal workflow configurations
 	Workflows *workflow.DSLWorkflowRegistry
 
+	// Webhooks delivers the webhooks declared in the DSL
+	Webhooks *service.WebhookService
+
 	// EventHandlers holds the event registry with handlers
 	EventHandlers *event.EventRegistry
 
+	// Events publishes events to the event bus; DSL events, handlers and
+	// the persistence, metrics, logging and webhook subscribers share it
+	Events *dispatcher.EventDispatcher
+
+	// EventBus is the event bus Events publishes to
+	EventBus *bus.EventBus
+
+	// EventTransport carries the events of EventBus between instances; nil
+	// when events stay in process
+	EventTransport bus.Transport
+
+	// EventMetrics counts the events published on Events
+	EventMetrics *subscribers.MetricsSubscriber
+
+	// Outbox holds the events emitted from the DSL until Relay publishes
+	// them on Events; nil without Config.Outbox
+	Outbox outbox.Store
+
+	// Relay publishes the events in Outbox; it must be started to do so
+	Relay *outbox.Relay
+
 	// AuthLoader holds authentication and authorization configuration
 	AuthLoader *auth.DSLLoader
 
+	// RBAC checks the roles required by authorize steps, including roles
+	// assigned to users at runtime
+	RBAC *rbac.Engine
+
+	// RBACInvalidator announces role changes to the other instances; nil
+	// without rbac_redis_url. Listen must run for this instance to see
+	// theirs.
+	RBACInvalidator *rbac.RedisInvalidator
+
+	// Replays runs the replays started through the admin endpoints; nil
+	// without Config.EventRepository. Shutdown stops them.
+	Replays *replay.Handler
+
+	// Policies holds the attribute-based authorization policies
+	Policies *abac.Engine
+
+	// Tenancy resolves the tenant of requests; nil without a tenancy block
+	Tenancy *tenant.Resolver
+
+	// APIKeys holds the providers of apikey auth declarations, by auth name
+	APIKeys map[string]*apikey.Provider
+
+	// Issuers holds the token issuers of jwt auth declarations with an
+	// issuer block, by auth name
+	Issuers map[string]*issuer.Issuer
+
 	// EndpointCount tracks the number of generated endpoints
 	EndpointCount int
 
@@ -82,7 +149,22 @@ type FieldInfo struct {
 	Required  bool
 	Unique    bool
 	Primary   bool
+	Auto      bool
 	Default   interface{}
+
+	// Enum holds the allowed values of enum fields.
+	Enum []string
+
+	// Constraints from the min, max, min_length, max_length and pattern
+	// modifiers.
+	Min       *float64
+	Max       *float64
+	MinLength *int
+	MaxLength *int
+	Pattern   *regexp.Regexp
+
+	// Pos is where the field is declared in the DSL source.
+	Pos ast.Position
 }
 
 // IndexInfo holds metadata about an index.
@@ -108,9 +190,63 @@ type Config struct {
 	// RedisURL is the Redis connection string for caching
 	RedisURL string
 
+	// TenantDatabases opens the PostgreSQL database of a tenant; required
+	// by the database tenancy strategy on PostgreSQL
+	TenantDatabases TenantDatabases
+
+	// RateLimitStore overrides the storage used by rate limit middleware
+	RateLimitStore ratelimit.Store
+
+	// APIKeyStore overrides the storage used by apikey auth providers
+	APIKeyStore apikey.Store
+
+	// IssuerStore overrides the storage of refresh tokens, revocations and
+	// signing keys used by token issuers
+	IssuerStore issuer.Store
+
+	// RBACStorage keeps the roles and user role assignments changed at
+	// runtime through the /admin/roles endpoints; defaults to memory
+	RBACStorage rbac.Storage
+
 	// TemporalHost is the Temporal server address
 	TemporalHost string
 
+	// WorkflowEngine starts workflows for "do workflow" event handlers;
+	// without one those handlers fail. Its workers must register the
+	// workflows with workflow.RegisterDSLWorkflows.
+	WorkflowEngine workflow.WorkflowExecutor
+
+	// WebhookRepository keeps the webhooks and their delivery records for
+	// GeneratedCode.Webhooks; defaults to memory
+	WebhookRepository webhookrepository.WebhookRepository
+
+	// WebhookOptions configure GeneratedCode.Webhooks, such as its delivery
+	// queue
+	WebhookOptions []service.Option
+
+	// EventRepository persists every published event when set
+	EventRepository eventrepository.EventRepository
+
+	// ReplayCheckpoints keeps the progress of replays started through the
+	// admin endpoints; defaults to memory
+	ReplayCheckpoints replay.CheckpointStore
+
+	// Outbox, when set, records the events emitted from the DSL in the
+	// transaction of the endpoint that emits them; GeneratedCode.Relay then
+	// publishes them at least once
+	Outbox outbox.Store
+
+	// OutboxOptions configure the outbox relay
+	OutboxOptions []outbox.Option
+
+	// EventTransport, when set, overrides the event transport selected by
+	// the event_transport property of the DSL config block
+	EventTransport bus.Transport
+
+	// WebhookReceiptStore records the delivery IDs of received webhooks;
+	// defaults to an in-memory store
+	WebhookReceiptStore inbound.Store
+
 	// EnableMetrics enables Prometheus metrics
 	EnableMetrics bool
 
@@ -0,0 +1,254 @@
+package codegen
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/bargom/codeai/internal/ast"
+	"github.com/bargom/codeai/internal/validation"
+)
+
+// SchemaValidationError reports the request fields that don't satisfy the
+// model or collection they were validated against.
+type SchemaValidationError struct {
+	Schema string
+	Errors []FieldError
+}
+
+func (e *SchemaValidationError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		msgs[i] = fe.Message
+	}
+	return fmt.Sprintf("validation failed for %s: %s", e.Schema, strings.Join(msgs, "; "))
+}
+
+// FieldError is a single failing field, with the position of the field's
+// declaration in the DSL source when it is known.
+type FieldError struct {
+	validation.ValidationError
+	Position *SourcePosition `json:"position,omitempty"`
+}
+
+// SourcePosition is a DSL source position as reported in error responses.
+type SourcePosition struct {
+	File   string `json:"file,omitempty"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
+
+// recordSchema is a model or collection that records are validated against.
+type recordSchema struct {
+	name   string
+	fields []FieldInfo
+}
+
+// lookupSchema finds the model or collection for a request type name. Names
+// are matched case-insensitively, and request names such as CreateUser or
+// UserInput fall back to the model they are derived from.
+func (c *ExecutionContext) lookupSchema(name string) *recordSchema {
+	if name == "" || c.generatedCode == nil || c.generatedCode.ModelRegistry == nil {
+		return nil
+	}
+	registry := c.generatedCode.ModelRegistry
+
+	for _, candidate := range ast.SchemaCandidates(name) {
+		for modelName, info := range registry.Models {
+			if strings.EqualFold(modelName, candidate) {
+				return &recordSchema{name: info.Name, fields: info.Fields}
+			}
+		}
+		for collName, info := range registry.Collections {
+			if strings.EqualFold(collName, candidate) {
+				return &recordSchema{name: info.Name, fields: info.Fields}
+			}
+		}
+	}
+	return nil
+}
+
+// hasSchemas reports whether the program declares any model or collection.
+func (c *ExecutionContext) hasSchemas() bool {
+	if c.generatedCode == nil || c.generatedCode.ModelRegistry == nil {
+		return false
+	}
+	registry := c.generatedCode.ModelRegistry
+	return len(registry.Models) > 0 || len(registry.Collections) > 0
+}
+
+// validateRecord checks data against the fields of schema, then checks that
+// values of unique fields are not already taken. PATCH requests are partial
+// updates, so their required fields may be omitted.
+func validateRecord(ctx *ExecutionContext, schema *recordSchema, data map[string]interface{}) error {
+	partial := ctx.Request() != nil && ctx.Request().Method == http.MethodPatch
+	data = coerceStrings(data, schema.fields, stringValued(ctx, data))
+
+	params := make([]validation.ParamDef, len(schema.fields))
+	for i, f := range schema.fields {
+		params[i] = fieldParam(f, partial)
+	}
+
+	var fieldErrors []FieldError
+	failed := make(map[string]bool)
+	if errs := validation.NewValidator().Validate(data, params); errs != nil {
+		for _, ve := range errs.Errors {
+			failed[ve.Field] = true
+			fieldErrors = append(fieldErrors, FieldError{ValidationError: ve})
+		}
+	}
+
+	primaryKey := "id"
+	for _, f := range schema.fields {
+		if f.Primary {
+			primaryKey = f.Name
+		}
+	}
+	for _, f := range schema.fields {
+		value, ok := data[f.Name]
+		if !f.Unique || !ok || value == nil || failed[f.Name] {
+			continue
+		}
+		taken, err := valueTaken(ctx, schema.name, f.Name, value, primaryKey, data[primaryKey])
+		if err != nil {
+			return fmt.Errorf("checking unique %s: %w", f.Name, err)
+		}
+		if taken {
+			fieldErrors = append(fieldErrors, FieldError{ValidationError: validation.ValidationError{
+				Field:      f.Name,
+				Value:      value,
+				Rule:       "unique",
+				Message:    fmt.Sprintf("%s is already in use", f.Name),
+				Suggestion: fmt.Sprintf("Choose a different value for '%s'", f.Name),
+			}})
+		}
+	}
+
+	if len(fieldErrors) == 0 {
+		return nil
+	}
+
+	positions := make(map[string]ast.Position, len(schema.fields))
+	for _, f := range schema.fields {
+		positions[f.Name] = f.Pos
+	}
+	for i := range fieldErrors {
+		if pos, ok := positions[fieldErrors[i].Field]; ok && pos.IsValid() {
+			fieldErrors[i].Position = &SourcePosition{File: pos.Filename, Line: pos.Line, Column: pos.Column}
+		}
+	}
+	return &SchemaValidationError{Schema: schema.name, Errors: fieldErrors}
+}
+
+// valueTaken reports whether another record already has value in field.
+// A record with the same primary key as the request is the record being
+// updated and doesn't count.
+func valueTaken(ctx *ExecutionContext, table, field string, value interface{}, primaryKey string, id interface{}) (bool, error) {
+	result, err := ctx.QueryDatabase(table, map[string]interface{}{field: value})
+	if err != nil {
+		return false, err
+	}
+	rows, _ := result.([]map[string]interface{})
+	for _, row := range rows {
+		if id == nil || fmt.Sprint(row[primaryKey]) != fmt.Sprint(id) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// fieldParam builds the validation rules for a model field.
+func fieldParam(f FieldInfo, partial bool) validation.ParamDef {
+	return validation.ParamDef{
+		Name: f.Name,
+		Type: paramType(f.FieldType),
+		// Generated and defaulted fields are filled in by the database
+		Required:  f.Required && !partial && !f.Primary && !f.Auto && f.Default == nil,
+		Min:       f.Min,
+		Max:       f.Max,
+		MinLength: f.MinLength,
+		MaxLength: f.MaxLength,
+		Pattern:   f.Pattern,
+		Enum:      f.Enum,
+	}
+}
+
+// paramType maps a DSL field type to a validation type. Types without a
+// validation equivalent, such as refs, map to "" and are not type checked.
+func paramType(fieldType string) string {
+	if strings.HasPrefix(fieldType, "embedded{") {
+		return "object"
+	}
+
+	base, _ := splitFieldType(fieldType)
+	switch base {
+	case "string", "text", "enum", "objectid":
+		return "string"
+	case "int", "integer", "bigint":
+		return "integer"
+	case "decimal", "float", "double", "number":
+		return "decimal"
+	case "bool", "boolean":
+		return "boolean"
+	case "uuid", "email":
+		return base
+	case "timestamp", "datetime", "date":
+		return "timestamp"
+	case "list", "array":
+		return "array"
+	case "json", "jsonb", "object":
+		return "object"
+	default:
+		return ""
+	}
+}
+
+// stringValued returns the keys of data that were read from the query
+// string, path or headers, whose values are always strings.
+func stringValued(ctx *ExecutionContext, data map[string]interface{}) map[string]bool {
+	keys := make(map[string]bool)
+	if req := ctx.RequestType(); req != nil && req.Source != ast.RequestSourceBody {
+		for k := range data {
+			keys[k] = true
+		}
+		return keys
+	}
+	if ctx.Request() != nil {
+		if rctx := chi.RouteContext(ctx.Request().Context()); rctx != nil {
+			for _, k := range rctx.URLParams.Keys {
+				keys[k] = true
+			}
+		}
+	}
+	return keys
+}
+
+// coerceStrings returns a copy of data with the string values of keys
+// converted to numbers and booleans for numeric and boolean fields.
+func coerceStrings(data map[string]interface{}, fields []FieldInfo, keys map[string]bool) map[string]interface{} {
+	coerced := make(map[string]interface{}, len(data))
+	for k, v := range data {
+		coerced[k] = v
+	}
+
+	for _, f := range fields {
+		s, ok := data[f.Name].(string)
+		if !ok || !keys[f.Name] {
+			continue
+		}
+		switch paramType(f.FieldType) {
+		case "integer", "decimal":
+			if n, err := strconv.ParseFloat(s, 64); err == nil {
+				coerced[f.Name] = n
+			}
+		case "boolean":
+			if b, err := strconv.ParseBool(s); err == nil {
+				coerced[f.Name] = b
+			}
+		}
+	}
+	return coerced
+}
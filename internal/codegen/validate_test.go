@@ -0,0 +1,292 @@
+package codegen
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bargom/codeai/internal/ast"
+	"github.com/bargom/codeai/internal/parser"
+)
+
+const validateTestDSL = `database postgres {
+    model User {
+        id: uuid, primary, auto
+        email: email, required, unique
+        role: enum(reader, author), default(reader)
+        age: int, min(0), max(150)
+        name: string, required, max_length(5)
+    }
+}
+
+endpoint POST "/users" {
+    request CreateUser from body
+    response User status 201
+    do {
+        validate(request)
+    }
+}
+
+endpoint PATCH "/users/:id" {
+    request UpdateUser from body
+    response User status 200
+    do {
+        validate(request)
+    }
+}
+`
+
+func generateValidateTestCode(t *testing.T) *GeneratedCode {
+	t.Helper()
+
+	program, err := parser.Parse(validateTestDSL)
+	if err != nil {
+		t.Fatalf("failed to parse DSL: %v", err)
+	}
+	code, err := NewGenerator(nil).GenerateFromAST(program)
+	if err != nil {
+		t.Fatalf("code generation failed: %v", err)
+	}
+	return code
+}
+
+func TestGenerator_LoadsFieldConstraints(t *testing.T) {
+	code := generateValidateTestCode(t)
+
+	user := code.ModelRegistry.Models["User"]
+	if user == nil {
+		t.Fatal("expected User model in registry")
+	}
+	fields := make(map[string]FieldInfo)
+	for _, f := range user.Fields {
+		fields[f.Name] = f
+	}
+
+	if !fields["id"].Auto || !fields["id"].Primary {
+		t.Errorf("expected id to be primary and auto, got %+v", fields["id"])
+	}
+	if got := fields["role"].Enum; len(got) != 2 || got[0] != "reader" || got[1] != "author" {
+		t.Errorf("expected role enum [reader author], got %v", got)
+	}
+	if f := fields["age"]; f.Min == nil || *f.Min != 0 || f.Max == nil || *f.Max != 150 {
+		t.Errorf("expected age min 0 and max 150, got %+v", f)
+	}
+	if f := fields["name"]; f.MaxLength == nil || *f.MaxLength != 5 {
+		t.Errorf("expected name max_length 5, got %+v", f)
+	}
+	if pos := fields["email"].Pos; pos.Line != 4 || pos.Column != 9 {
+		t.Errorf("expected email declared at 4:9, got %s", pos)
+	}
+}
+
+func TestValidate_RejectsInvalidRequest(t *testing.T) {
+	code := generateValidateTestCode(t)
+
+	body := `{"email": "not-an-email", "role": "owner", "age": -1, "name": "Ada Lovelace"}`
+	req := httptest.NewRequest("POST", "/users", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	code.Router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status 422, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		Error   string       `json:"error"`
+		Message string       `json:"message"`
+		Details []FieldError `json:"details"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Message != "request does not match User" {
+		t.Errorf("unexpected message %q", resp.Message)
+	}
+
+	rules := make(map[string]string)
+	for _, d := range resp.Details {
+		rules[d.Field] = d.Rule
+		if d.Position == nil || d.Position.Line == 0 {
+			t.Errorf("expected a source position for %s", d.Field)
+		}
+	}
+	want := map[string]string{"email": "type", "role": "enum", "age": "min", "name": "maxLength"}
+	for field, rule := range want {
+		if rules[field] != rule {
+			t.Errorf("expected %s to fail %q, got %q", field, rule, rules[field])
+		}
+	}
+	if len(resp.Details) != len(want) {
+		t.Errorf("expected %d failing fields, got %+v", len(want), resp.Details)
+	}
+}
+
+func TestValidate_RequiredFields(t *testing.T) {
+	code := generateValidateTestCode(t)
+
+	req := httptest.NewRequest("POST", "/users", strings.NewReader(`{}`))
+	w := httptest.NewRecorder()
+	code.Router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status 422, got %d", w.Code)
+	}
+	// Only email and name are required; id is generated and role has a default
+	for _, field := range []string{"email", "name"} {
+		if !strings.Contains(w.Body.String(), `"field":"`+field+`","rule":"required"`) {
+			t.Errorf("expected required error for %s in %s", field, w.Body.String())
+		}
+	}
+	if strings.Contains(w.Body.String(), `"field":"id"`) || strings.Contains(w.Body.String(), `"field":"role"`) {
+		t.Errorf("unexpected errors for id or role: %s", w.Body.String())
+	}
+
+	// PATCH requests are partial updates
+	req = httptest.NewRequest("PATCH", "/users/4c3b8a9e-0000-4000-8000-000000000000", strings.NewReader(`{"age": 30}`))
+	w = httptest.NewRecorder()
+	code.Router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200 for partial update, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestValidate_UniquePreCheck(t *testing.T) {
+	adapter, _ := newTestAdapter(t)
+	code := &GeneratedCode{
+		Middlewares:   make(map[string]func(http.Handler) http.Handler),
+		ModelRegistry: testPostgresRegistry(),
+	}
+	code.ModelRegistry.Models["User"].Fields[1].Unique = true
+
+	factory := NewExecutionContextFactory(code)
+	factory.db = adapter
+	ctx := factory.NewContext(context.Background(), httptest.NewRequest("POST", "/users", nil))
+
+	id, err := ctx.InsertDatabase("User", map[string]interface{}{"email": "ada@example.com"})
+	if err != nil {
+		t.Fatalf("insert failed: %v", err)
+	}
+
+	step := &ast.LogicStep{Action: "validate", Args: []string{"request", "User"}}
+
+	ctx.SetInput(map[string]interface{}{"email": "ada@example.com"})
+	err = executeValidate(ctx, step)
+	var schemaErr *SchemaValidationError
+	if !errors.As(err, &schemaErr) {
+		t.Fatalf("expected SchemaValidationError, got %v", err)
+	}
+	if len(schemaErr.Errors) != 1 || schemaErr.Errors[0].Rule != "unique" {
+		t.Errorf("expected a unique error, got %+v", schemaErr.Errors)
+	}
+	if got := determineErrorStatusCode(err); got != http.StatusUnprocessableEntity {
+		t.Errorf("expected status 422, got %d", got)
+	}
+
+	// The record being updated may keep its own value
+	ctx.SetInput(map[string]interface{}{"id": id, "email": "ada@example.com"})
+	if err := executeValidate(ctx, step); err != nil {
+		t.Errorf("unexpected error validating the owning record: %v", err)
+	}
+
+	ctx.SetInput(map[string]interface{}{"email": "grace@example.com"})
+	if err := executeValidate(ctx, step); err != nil {
+		t.Errorf("unexpected error for an unused value: %v", err)
+	}
+}
+
+func TestExecutionContext_LookupSchema(t *testing.T) {
+	registry := NewTypeRegistry()
+	registry.Models["User"] = &ModelInfo{Name: "User"}
+	registry.Collections["events"] = &CollectionInfo{Name: "events"}
+
+	factory := NewExecutionContextFactory(&GeneratedCode{ModelRegistry: registry})
+	ctx := factory.NewContext(context.Background(), httptest.NewRequest("GET", "/", nil))
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"User", "User"},
+		{"user", "User"},
+		{"CreateUser", "User"},
+		{"UpdateUserRequest", "User"},
+		{"Events", "events"},
+		{"UserID", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		schema := ctx.lookupSchema(tt.name)
+		got := ""
+		if schema != nil {
+			got = schema.name
+		}
+		if got != tt.want {
+			t.Errorf("lookupSchema(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestValidate_UnknownSchema(t *testing.T) {
+	registry := NewTypeRegistry()
+	registry.Models["User"] = &ModelInfo{Name: "User"}
+
+	factory := NewExecutionContextFactory(&GeneratedCode{ModelRegistry: registry})
+	ctx := factory.NewContext(context.Background(), httptest.NewRequest("POST", "/", nil))
+	ctx.SetInput(map[string]interface{}{"email": "grace@example.com"})
+	ctx.Set("id", "42")
+
+	tests := []struct {
+		name       string
+		args       []string
+		wantStatus int
+	}{
+		{"request without request type", []string{"request"}, http.StatusInternalServerError},
+		{"explicit unknown model", []string{"request", "Usr"}, http.StatusInternalServerError},
+		{"non-object against model", []string{"id", "User"}, http.StatusBadRequest},
+		{"value without model", []string{"id"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := executeValidate(ctx, &ast.LogicStep{Action: "validate", Args: tt.args})
+			if tt.wantStatus == 0 {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected validation to fail")
+			}
+			if got := determineErrorStatusCode(err); got != tt.wantStatus {
+				t.Errorf("expected status %d, got %d: %v", tt.wantStatus, got, err)
+			}
+		})
+	}
+}
+
+func TestCoerceStrings(t *testing.T) {
+	fields := []FieldInfo{
+		{Name: "page", FieldType: "int"},
+		{Name: "active", FieldType: "bool"},
+		{Name: "q", FieldType: "string"},
+	}
+	data := map[string]interface{}{"page": "2", "active": "true", "q": "42"}
+
+	coerced := coerceStrings(data, fields, map[string]bool{"page": true, "active": true, "q": true})
+	if coerced["page"] != float64(2) || coerced["active"] != true || coerced["q"] != "42" {
+		t.Errorf("unexpected coerced values: %#v", coerced)
+	}
+
+	// Body values keep their JSON types
+	coerced = coerceStrings(data, fields, map[string]bool{"active": true})
+	if coerced["page"] != "2" || coerced["active"] != true {
+		t.Errorf("expected only active to be coerced, got %#v", coerced)
+	}
+	if data["page"] != "2" {
+		t.Error("expected the input map to be left unchanged")
+	}
+}
@@ -0,0 +1,93 @@
+package codegen
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/bargom/codeai/internal/ast"
+	"github.com/bargom/codeai/internal/event/bus"
+	"github.com/bargom/codeai/internal/webhook/inbound"
+	"github.com/bargom/codeai/internal/webhook/security"
+)
+
+// receiverSchemes maps the verify schemes of the DSL to signature schemes.
+var receiverSchemes = map[string]string{
+	ast.ReceiverSchemeHMAC:     security.SchemeHMAC,
+	ast.ReceiverSchemeStandard: security.SchemeStandard,
+}
+
+// registerWebhookReceivers mounts the inbound webhook receivers declared in
+// the DSL on r and returns how many there are. Receivers authenticate
+// requests by their signature, so endpoint middleware does not apply.
+func (g *generator) registerWebhookReceivers(r chi.Router, program *ast.Program, code *GeneratedCode) (int, error) {
+	store := g.config.WebhookReceiptStore
+	if store == nil {
+		store = inbound.NewMemoryStore()
+	}
+
+	count := 0
+	for _, stmt := range program.Statements {
+		decl, ok := stmt.(*ast.WebhookReceiverDecl)
+		if !ok {
+			continue
+		}
+		receiver, err := g.webhookReceiver(decl, code, store)
+		if err != nil {
+			return 0, fmt.Errorf("webhook receiver %q: %w", decl.Name, err)
+		}
+		r.Post(decl.Path, receiver.ServeHTTP)
+		g.logger.Debug("registered webhook receiver", "name", decl.Name, "path", decl.Path, "event", decl.Event)
+		count++
+	}
+	return count, nil
+}
+
+// webhookReceiver creates the receiver of a declaration, which emits the
+// payloads of verified requests as its event.
+func (g *generator) webhookReceiver(decl *ast.WebhookReceiverDecl, code *GeneratedCode, store inbound.Store) (*inbound.Receiver, error) {
+	scheme, ok := receiverSchemes[decl.Scheme]
+	if !ok {
+		return nil, fmt.Errorf("unknown verify scheme %q", decl.Scheme)
+	}
+
+	secret := decl.Secret
+	if decl.SecretEnv != "" {
+		secret = os.Getenv(decl.SecretEnv)
+		if secret == "" {
+			return nil, fmt.Errorf("environment variable %s holding the secret is not set", decl.SecretEnv)
+		}
+	}
+
+	config := inbound.Config{
+		Name:             decl.Name,
+		Scheme:           scheme,
+		Secrets:          []string{secret},
+		SignatureHeader:  decl.SignatureHeader,
+		TimestampHeader:  decl.TimestampHeader,
+		DeliveryIDHeader: decl.DeliveryHeader,
+		DeliveryIDField:  decl.DeliveryField,
+	}
+	if decl.Tolerance != "" {
+		tolerance, err := time.ParseDuration(decl.Tolerance)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tolerance: %w", err)
+		}
+		config.Tolerance = tolerance
+	}
+
+	if _, ok := code.EventHandlers.GetEvent(decl.Event); !ok {
+		return nil, fmt.Errorf("event %q is not declared", decl.Event)
+	}
+	emit := func(ctx context.Context, payload map[string]interface{}) error {
+		if err := code.EventHandlers.Validate(bus.Event{Type: bus.EventType(decl.Event), Data: payload}); err != nil {
+			return fmt.Errorf("%w: %v", inbound.ErrInvalidPayload, err)
+		}
+		return code.EventHandlers.EmitEvent(ctx, decl.Event, payload)
+	}
+
+	return inbound.NewReceiver(config, emit, inbound.WithStore(store), inbound.WithLogger(g.logger))
+}
@@ -0,0 +1,95 @@
+package codegen
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bargom/codeai/internal/event/bus"
+	"github.com/bargom/codeai/internal/parser"
+	"github.com/bargom/codeai/internal/webhook/security"
+)
+
+const receiverSource = `
+event payment.received {
+	schema {
+		id string
+		amount int
+	}
+}
+
+receive webhook stripe at "/hooks/stripe" {
+	verify hmac_sha256 header "Stripe-Signature" secret env(CODEAI_TEST_STRIPE_SECRET)
+	delivery_id field "id"
+	emit "payment.received"
+}
+`
+
+func TestWebhookReceivers(t *testing.T) {
+	t.Setenv("CODEAI_TEST_STRIPE_SECRET", "whsec_test")
+	program, err := parser.Parse(receiverSource)
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	code, err := NewGenerator(&Config{}).GenerateFromAST(program)
+	if err != nil {
+		t.Fatalf("code generation failed: %v", err)
+	}
+	if code.EndpointCount != 1 {
+		t.Errorf("expected the receiver to be counted as an endpoint, got %d", code.EndpointCount)
+	}
+
+	var received []bus.Event
+	code.Events.Subscribe("payment.received", bus.SubscriberFunc(func(ctx context.Context, event bus.Event) error {
+		received = append(received, event)
+		return nil
+	}))
+
+	send := func(body string) int {
+		ts := time.Now().Unix()
+		signature := security.SignPayloadWithTimestamp("whsec_test", ts, []byte(body))
+		req := httptest.NewRequest(http.MethodPost, "/hooks/stripe", strings.NewReader(body))
+		req.Header.Set("Stripe-Signature", fmt.Sprintf("t=%d,v1=%s", ts, signature))
+		w := httptest.NewRecorder()
+		code.Router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if status := send(`{"id":"evt_1","amount":100}`); status != http.StatusOK {
+		t.Fatalf("expected 200, got %d", status)
+	}
+	if status := send(`{"id":"evt_1","amount":100}`); status != http.StatusOK {
+		t.Fatalf("expected 200 for a duplicate, got %d", status)
+	}
+	if len(received) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(received))
+	}
+	if received[0].Data["id"] != "evt_1" {
+		t.Errorf("expected the payload as the event's data, got %v", received[0].Data)
+	}
+
+	if status := send(`{"id":"evt_2","amount":"lots"}`); status != http.StatusUnprocessableEntity {
+		t.Errorf("expected 422 for a payload not matching the schema, got %d", status)
+	}
+	if len(received) != 1 {
+		t.Errorf("expected the invalid payload not to be emitted, got %d events", len(received))
+	}
+}
+
+func TestWebhookReceivers_MissingSecret(t *testing.T) {
+	t.Setenv("CODEAI_TEST_STRIPE_SECRET", "")
+	program, err := parser.Parse(receiverSource)
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	_, err = NewGenerator(&Config{}).GenerateFromAST(program)
+	if err == nil || !strings.Contains(err.Error(), "CODEAI_TEST_STRIPE_SECRET") {
+		t.Errorf("expected an error naming the unset variable, got %v", err)
+	}
+}
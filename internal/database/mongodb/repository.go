@@ -71,6 +71,23 @@ func NewRepository(client *Client, collectionName string, logger *slog.Logger) *
 	}
 }
 
+// NewDatabaseRepository creates a new repository for the specified
+// collection in another database than the client's, on the same connection.
+func NewDatabaseRepository(client *Client, databaseName, collectionName string, logger *slog.Logger) *Repository {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	db := client.Client().Database(databaseName)
+	return &Repository{
+		client:     client,
+		database:   db,
+		collection: db.Collection(collectionName),
+		collName:   collectionName,
+		logger:     logger.With(slog.String("database", databaseName), slog.String("collection", collectionName)),
+	}
+}
+
 // Collection returns the underlying mongo.Collection.
 func (r *Repository) Collection() *mongo.Collection {
 	return r.collection
@@ -157,35 +174,108 @@ func (r *Repository) InsertOne(ctx context.Context, doc Document) (string, error
 	}
 }
 
-// UpdateOne updates a single document matching the filter.
+// UpdateResult reports how many documents an update matched and modified.
+type UpdateResult struct {
+	MatchedCount  int64
+	ModifiedCount int64
+}
+
+// UpdateOne updates a single document matching the filter and returns the
+// number of modified documents.
 func (r *Repository) UpdateOne(ctx context.Context, filter Filter, update Update) (int64, error) {
+	result, err := r.UpdateOneWithResult(ctx, filter, update)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// UpdateOneWithResult updates a single document matching the filter and
+// returns both the matched and modified counts. A document that matched but
+// already held the new values counts as matched but not modified.
+func (r *Repository) UpdateOneWithResult(ctx context.Context, filter Filter, update Update) (*UpdateResult, error) {
 	if r.client.IsClosed() {
-		return 0, ErrClientClosed
+		return nil, ErrClientClosed
 	}
 
-	mongoFilter := translateFilter(filter)
+	// A plain set only stamps updatedAt when it changes the document
+	if !hasUpdateOperators(update) {
+		return r.SetOne(ctx, filter, bson.M(update), "updatedAt")
+	}
+	if setDoc, ok := update["$set"].(bson.M); ok && len(update) == 1 {
+		return r.SetOne(ctx, filter, setDoc, "updatedAt")
+	}
 
 	// Ensure updatedAt is set
-	if _, ok := update["$set"]; ok {
-		if setDoc, ok := update["$set"].(bson.M); ok {
-			setDoc["updatedAt"] = time.Now().UTC()
-		}
-	} else if _, ok := update["$set"]; !ok {
-		// Wrap raw update in $set
-		update = Update{"$set": bson.M{"updatedAt": time.Now().UTC()}}
-		for k, v := range update {
-			if k != "$set" {
-				update["$set"].(bson.M)[k] = v
-			}
-		}
+	now := time.Now().UTC()
+	if setDoc, ok := update["$set"].(bson.M); ok {
+		setDoc["updatedAt"] = now
+	} else {
+		update["$set"] = bson.M{"updatedAt": now}
 	}
 
-	result, err := r.collection.UpdateOne(ctx, mongoFilter, update)
+	result, err := r.collection.UpdateOne(ctx, translateFilter(filter), update)
 	if err != nil {
-		return 0, fmt.Errorf("updateOne failed: %w", err)
+		return nil, fmt.Errorf("updateOne failed: %w", err)
 	}
 
-	return result.ModifiedCount, nil
+	return &UpdateResult{
+		MatchedCount:  result.MatchedCount,
+		ModifiedCount: result.ModifiedCount,
+	}, nil
+}
+
+// SetOne sets the fields of set on a single document matching the filter.
+// When stampField is not empty it is set to the current time, but only if
+// the update changes another field, so a document that already held the
+// values counts as matched but not modified.
+func (r *Repository) SetOne(ctx context.Context, filter Filter, set bson.M, stampField string) (*UpdateResult, error) {
+	if r.client.IsClosed() {
+		return nil, ErrClientClosed
+	}
+
+	var update interface{} = bson.M{"$set": set}
+	if stampField != "" {
+		update = stampedSet(set, stampField, time.Now().UTC())
+	}
+
+	result, err := r.collection.UpdateOne(ctx, translateFilter(filter), update)
+	if err != nil {
+		return nil, fmt.Errorf("updateOne failed: %w", err)
+	}
+
+	return &UpdateResult{
+		MatchedCount:  result.MatchedCount,
+		ModifiedCount: result.ModifiedCount,
+	}, nil
+}
+
+// stampedSet returns an update that sets the fields of set, and stampField
+// to now if any of them differs from the stored document. It is an
+// aggregation pipeline, whose field references see the document as it was
+// before the update. Nested field paths cannot be set by a pipeline, so
+// they are always stamped.
+func stampedSet(set bson.M, stampField string, now time.Time) interface{} {
+	for k := range set {
+		if strings.ContainsAny(k, ".$") {
+			withStamp := bson.M{stampField: now}
+			for k, v := range set {
+				withStamp[k] = v
+			}
+			return bson.M{"$set": withStamp}
+		}
+	}
+
+	stage := bson.M{}
+	changed := bson.A{}
+	for k, v := range set {
+		stage[k] = bson.M{"$literal": v}
+		changed = append(changed, bson.M{"$ne": bson.A{"$" + k, bson.M{"$literal": v}}})
+	}
+	if _, ok := set[stampField]; !ok {
+		stage[stampField] = bson.M{"$cond": bson.A{bson.M{"$or": changed}, now, "$" + stampField}}
+	}
+	return mongo.Pipeline{{{Key: "$set", Value: stage}}}
 }
 
 // DeleteOne deletes a single document matching the filter.
@@ -905,6 +995,17 @@ func likeToRegex(pattern string) string {
 	return "^" + escaped + "$"
 }
 
+// hasUpdateOperators reports whether an update uses operators such as $inc
+// rather than being a plain field document.
+func hasUpdateOperators(update Update) bool {
+	for k := range update {
+		if strings.HasPrefix(k, "$") {
+			return true
+		}
+	}
+	return false
+}
+
 // formatID converts an ID value to a string.
 func formatID(id interface{}) string {
 	switch v := id.(type) {
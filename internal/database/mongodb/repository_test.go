@@ -93,6 +93,35 @@ func TestRepository_CRUD(t *testing.T) {
 			assert.Equal(t, int32(31), found["age"])
 		})
 
+		t.Run("UpdateOneWithResult", func(t *testing.T) {
+			filter := Filter{"name": "John Doe"}
+
+			result, err := repo.UpdateOneWithResult(ctx, filter, Update{"age": 32})
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), result.MatchedCount)
+			assert.Equal(t, int64(1), result.ModifiedCount)
+
+			found, err := repo.FindOne(ctx, filter)
+			require.NoError(t, err)
+			stamped := found["updatedAt"]
+			require.NotNil(t, stamped)
+
+			// Repeating the update changes nothing, so the timestamp stays put.
+			result, err = repo.UpdateOneWithResult(ctx, filter, Update{"age": 32})
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), result.MatchedCount)
+			assert.Zero(t, result.ModifiedCount)
+
+			result, err = repo.UpdateOneWithResult(ctx, Filter{"name": "Nobody"}, Update{"age": 1})
+			require.NoError(t, err)
+			assert.Zero(t, result.MatchedCount)
+
+			found, err = repo.FindOne(ctx, filter)
+			require.NoError(t, err)
+			assert.Equal(t, int32(32), found["age"])
+			assert.Equal(t, stamped, found["updatedAt"])
+		})
+
 		t.Run("DeleteOne", func(t *testing.T) {
 			// Insert a document to delete
 			doc := Document{
@@ -1044,6 +1073,12 @@ func TestLikeToRegex(t *testing.T) {
 	}
 }
 
+func TestHasUpdateOperators(t *testing.T) {
+	assert.True(t, hasUpdateOperators(Update{"$inc": bson.M{"count": 1}}))
+	assert.False(t, hasUpdateOperators(Update{"name": "x"}))
+	assert.False(t, hasUpdateOperators(Update{}))
+}
+
 func TestFormatID(t *testing.T) {
 	t.Run("ObjectID", func(t *testing.T) {
 		oid := primitive.NewObjectID()
@@ -0,0 +1,160 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Statements returns the DDL statements that create the schema.
+// Tables are created in dependency order with IF NOT EXISTS so the
+// statements can be re-run safely. Foreign keys that cannot be declared
+// inline because of reference cycles are added afterwards.
+func (s *Schema) Statements() []string {
+	var stmts []string
+	created := make(map[string]bool, len(s.Tables))
+
+	type deferredFK struct {
+		table string
+		fk    *ForeignKey
+	}
+	var deferred []deferredFK
+
+	sorted := s.SortedTables()
+	for _, t := range sorted {
+		inline := make([]*ForeignKey, 0, len(t.ForeignKeys))
+		for _, fk := range t.ForeignKeys {
+			if fk.RefTable == t.Name || created[fk.RefTable] {
+				inline = append(inline, fk)
+			} else {
+				deferred = append(deferred, deferredFK{table: t.Name, fk: fk})
+			}
+		}
+		stmts = append(stmts, createTableSQL(t, inline))
+		created[t.Name] = true
+	}
+
+	for _, d := range deferred {
+		stmts = append(stmts, AddForeignKeySQL(d.table, d.fk))
+	}
+
+	for _, t := range sorted {
+		for _, idx := range t.Indexes {
+			stmts = append(stmts, idx.CreateSQL())
+		}
+	}
+
+	return stmts
+}
+
+// SQL returns the complete DDL script for the schema.
+func (s *Schema) SQL() string {
+	stmts := s.Statements()
+	if len(stmts) == 0 {
+		return ""
+	}
+	return strings.Join(stmts, ";\n\n") + ";\n"
+}
+
+// CreateSQL returns the CREATE TABLE statement for the table, including all
+// of its foreign keys.
+func (t *Table) CreateSQL() string {
+	return createTableSQL(t, t.ForeignKeys)
+}
+
+// DropSQL returns the DROP TABLE statement for the table.
+func (t *Table) DropSQL() string {
+	return fmt.Sprintf("DROP TABLE IF EXISTS %s", quoteIdent(t.Name))
+}
+
+// Definition returns the column definition used in CREATE TABLE and
+// ALTER TABLE ... ADD COLUMN statements.
+func (c *Column) Definition() string {
+	var b strings.Builder
+	b.WriteString(quoteIdent(c.Name))
+	b.WriteString(" ")
+	b.WriteString(c.Type)
+
+	if !c.Nullable && !c.Primary {
+		b.WriteString(" NOT NULL")
+	}
+	if c.Default != "" {
+		b.WriteString(" DEFAULT ")
+		b.WriteString(c.Default)
+	}
+	if c.Unique && !c.Primary {
+		b.WriteString(" UNIQUE")
+	}
+	if len(c.Enum) > 0 {
+		values := make([]string, len(c.Enum))
+		for i, v := range c.Enum {
+			values[i] = quoteLiteral(v)
+		}
+		fmt.Fprintf(&b, " CHECK (%s IN (%s))", quoteIdent(c.Name), strings.Join(values, ", "))
+	}
+
+	return b.String()
+}
+
+// Definition returns the table constraint clause for the foreign key.
+func (fk *ForeignKey) Definition() string {
+	return fmt.Sprintf("CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s (%s) ON DELETE %s",
+		quoteIdent(fk.Name), quoteIdent(fk.Column), quoteIdent(fk.RefTable), quoteIdent(fk.RefColumn), fk.OnDelete)
+}
+
+// AddForeignKeySQL returns an idempotent statement adding a foreign key
+// constraint to an existing table.
+func AddForeignKeySQL(table string, fk *ForeignKey) string {
+	return fmt.Sprintf(`DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = %s) THEN
+        ALTER TABLE %s ADD %s;
+    END IF;
+END $$`, quoteLiteral(fk.Name), quoteIdent(table), fk.Definition())
+}
+
+// CreateSQL returns the CREATE INDEX statement for the index.
+func (i *Index) CreateSQL() string {
+	cols := make([]string, len(i.Columns))
+	for j, c := range i.Columns {
+		cols[j] = quoteIdent(c)
+	}
+	unique := ""
+	if i.Unique {
+		unique = "UNIQUE "
+	}
+	return fmt.Sprintf("CREATE %sINDEX IF NOT EXISTS %s ON %s (%s)",
+		unique, quoteIdent(i.Name), quoteIdent(i.Table), strings.Join(cols, ", "))
+}
+
+// DropSQL returns the DROP INDEX statement for the index.
+func (i *Index) DropSQL() string {
+	return fmt.Sprintf("DROP INDEX IF EXISTS %s", quoteIdent(i.Name))
+}
+
+// createTableSQL renders a CREATE TABLE statement with the given inline
+// foreign keys.
+func createTableSQL(t *Table, fks []*ForeignKey) string {
+	lines := make([]string, 0, len(t.Columns)+len(fks)+1)
+	for _, c := range t.Columns {
+		def := c.Definition()
+		if c.Primary && len(t.PrimaryKey) == 1 {
+			def += " PRIMARY KEY"
+		}
+		lines = append(lines, def)
+	}
+
+	if len(t.PrimaryKey) > 1 {
+		cols := make([]string, len(t.PrimaryKey))
+		for i, c := range t.PrimaryKey {
+			cols[i] = quoteIdent(c)
+		}
+		lines = append(lines, fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(cols, ", ")))
+	}
+
+	for _, fk := range fks {
+		lines = append(lines, fk.Definition())
+	}
+
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n    %s\n)",
+		quoteIdent(t.Name), strings.Join(lines, ",\n    "))
+}
@@ -0,0 +1,575 @@
+package schema
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ChangeKind identifies the kind of a schema change.
+type ChangeKind string
+
+// Schema change kinds produced by Diff.
+const (
+	ChangeCreateTable    ChangeKind = "create_table"
+	ChangeDropTable      ChangeKind = "drop_table"
+	ChangeAddColumn      ChangeKind = "add_column"
+	ChangeDropColumn     ChangeKind = "drop_column"
+	ChangeAlterType      ChangeKind = "alter_type"
+	ChangeAlterNullable  ChangeKind = "alter_nullable"
+	ChangeAlterDefault   ChangeKind = "alter_default"
+	ChangeAlterUnique    ChangeKind = "alter_unique"
+	ChangeAlterEnum      ChangeKind = "alter_enum"
+	ChangeAddForeignKey  ChangeKind = "add_foreign_key"
+	ChangeDropForeignKey ChangeKind = "drop_foreign_key"
+	ChangeAddIndex       ChangeKind = "add_index"
+	ChangeDropIndex      ChangeKind = "drop_index"
+)
+
+// Change is a single difference between two schemas together with the SQL
+// that applies (Up) and reverts (Down) it.
+type Change struct {
+	Kind   ChangeKind
+	Table  string
+	Column string // empty for table-level changes
+	// Description is a human readable summary, e.g. "drop column users.age".
+	Description string
+	// Destructive is set for changes that can lose data, such as dropping
+	// a table or column or narrowing a column type.
+	Destructive bool
+	Up          []string
+	Down        []string
+}
+
+// Changeset is the ordered list of changes that migrates one schema to another.
+type Changeset struct {
+	Changes []*Change
+}
+
+// Empty reports whether the changeset contains no changes.
+func (c *Changeset) Empty() bool {
+	return len(c.Changes) == 0
+}
+
+// Destructive returns the changes that can lose data.
+func (c *Changeset) Destructive() []*Change {
+	var out []*Change
+	for _, ch := range c.Changes {
+		if ch.Destructive {
+			out = append(out, ch)
+		}
+	}
+	return out
+}
+
+// UpSQL returns the migration script applying the changeset.
+func (c *Changeset) UpSQL() string {
+	var stmts []string
+	for _, ch := range c.Changes {
+		stmts = append(stmts, annotate(ch, ch.Up)...)
+	}
+	return joinStatements(stmts)
+}
+
+// DownSQL returns the migration script reverting the changeset. Changes are
+// reverted in reverse order.
+func (c *Changeset) DownSQL() string {
+	var stmts []string
+	for i := len(c.Changes) - 1; i >= 0; i-- {
+		ch := c.Changes[i]
+		stmts = append(stmts, annotate(ch, ch.Down)...)
+	}
+	return joinStatements(stmts)
+}
+
+// Diff computes the changes needed to migrate the from schema to the to
+// schema. Tables are matched by table name and columns by column name, so a
+// renamed field shows up as a dropped and an added column.
+//
+// Changes are ordered so the up script can run as is: constraints and
+// indexes are dropped first, then tables and columns are created, altered
+// and dropped, and finally new foreign keys and indexes are added.
+func Diff(from, to *Schema) *Changeset {
+	if from == nil {
+		from = &Schema{}
+	}
+	if to == nil {
+		to = &Schema{}
+	}
+
+	var (
+		dropFKs, dropIndexes, createTables, columns []*Change
+		dropColumns, dropTables, addFKs, addIndexes []*Change
+	)
+
+	oldTables := tablesByName(from)
+	newTables := tablesByName(to)
+
+	// Foreign keys and indexes are compared across all tables so that the
+	// constraints of created and dropped tables are handled uniformly.
+	oldFKs, newFKs := foreignKeysByName(from), foreignKeysByName(to)
+	for _, key := range sortedKeys(oldFKs) {
+		old := oldFKs[key]
+		if cur, ok := newFKs[key]; ok && sameForeignKey(old.fk, cur.fk) {
+			continue
+		}
+		dropFKs = append(dropFKs, &Change{
+			Kind:        ChangeDropForeignKey,
+			Table:       old.table,
+			Column:      old.fk.Column,
+			Description: fmt.Sprintf("drop foreign key %s on %s", old.fk.Name, old.table),
+			Up:          []string{dropConstraintSQL(old.table, old.fk.Name)},
+			Down:        []string{addConstraintSQL(old.table, old.fk.Definition())},
+		})
+	}
+	for _, key := range sortedKeys(newFKs) {
+		cur := newFKs[key]
+		if old, ok := oldFKs[key]; ok && sameForeignKey(old.fk, cur.fk) {
+			continue
+		}
+		addFKs = append(addFKs, &Change{
+			Kind:        ChangeAddForeignKey,
+			Table:       cur.table,
+			Column:      cur.fk.Column,
+			Description: fmt.Sprintf("add foreign key %s on %s", cur.fk.Name, cur.table),
+			Up:          []string{addConstraintSQL(cur.table, cur.fk.Definition())},
+			Down:        []string{dropConstraintSQL(cur.table, cur.fk.Name)},
+		})
+	}
+
+	oldIdx, newIdx := indexesByName(from), indexesByName(to)
+	for _, name := range sortedKeys(oldIdx) {
+		old := oldIdx[name]
+		if cur, ok := newIdx[name]; ok && sameIndex(old, cur) {
+			continue
+		}
+		dropIndexes = append(dropIndexes, &Change{
+			Kind:        ChangeDropIndex,
+			Table:       old.Table,
+			Description: fmt.Sprintf("drop index %s on %s", old.Name, old.Table),
+			Up:          []string{old.DropSQL()},
+			Down:        []string{old.CreateSQL()},
+		})
+	}
+	for _, name := range sortedKeys(newIdx) {
+		cur := newIdx[name]
+		if old, ok := oldIdx[name]; ok && sameIndex(old, cur) {
+			continue
+		}
+		addIndexes = append(addIndexes, &Change{
+			Kind:        ChangeAddIndex,
+			Table:       cur.Table,
+			Description: fmt.Sprintf("add index %s on %s", cur.Name, cur.Table),
+			Up:          []string{cur.CreateSQL()},
+			Down:        []string{cur.DropSQL()},
+		})
+	}
+
+	for _, t := range to.SortedTables() {
+		old, ok := oldTables[t.Name]
+		if !ok {
+			createTables = append(createTables, &Change{
+				Kind:        ChangeCreateTable,
+				Table:       t.Name,
+				Description: "create table " + t.Name,
+				Up:          []string{createTableSQL(t, nil)},
+				Down:        []string{t.DropSQL()},
+			})
+			continue
+		}
+
+		for _, col := range t.Columns {
+			oldCol, ok := old.Column(col.Name)
+			if !ok {
+				columns = append(columns, addColumnChange(t.Name, col))
+				continue
+			}
+			columns = append(columns, diffColumn(t.Name, oldCol, col)...)
+		}
+		for _, oldCol := range old.Columns {
+			if _, ok := t.Column(oldCol.Name); !ok {
+				dropColumns = append(dropColumns, dropColumnChange(t.Name, oldCol))
+			}
+		}
+	}
+
+	for _, t := range reversedTables(from.SortedTables()) {
+		if _, ok := newTables[t.Name]; ok {
+			continue
+		}
+		dropTables = append(dropTables, &Change{
+			Kind:        ChangeDropTable,
+			Table:       t.Name,
+			Description: "drop table " + t.Name,
+			Destructive: true,
+			Up:          []string{t.DropSQL()},
+			Down:        []string{createTableSQL(t, nil)},
+		})
+	}
+
+	cs := &Changeset{}
+	for _, group := range [][]*Change{
+		dropFKs, dropIndexes, createTables, columns, dropColumns, dropTables, addFKs, addIndexes,
+	} {
+		cs.Changes = append(cs.Changes, group...)
+	}
+	return cs
+}
+
+// addColumnChange adds a column to an existing table.
+func addColumnChange(table string, col *Column) *Change {
+	return &Change{
+		Kind:        ChangeAddColumn,
+		Table:       table,
+		Column:      col.Name,
+		Description: fmt.Sprintf("add column %s.%s", table, col.Name),
+		Up:          []string{fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", quoteIdent(table), col.Definition())},
+		Down:        []string{fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", quoteIdent(table), quoteIdent(col.Name))},
+	}
+}
+
+// dropColumnChange drops a column from an existing table.
+func dropColumnChange(table string, col *Column) *Change {
+	return &Change{
+		Kind:        ChangeDropColumn,
+		Table:       table,
+		Column:      col.Name,
+		Description: fmt.Sprintf("drop column %s.%s", table, col.Name),
+		Destructive: true,
+		Up:          []string{fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", quoteIdent(table), quoteIdent(col.Name))},
+		Down:        []string{fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", quoteIdent(table), col.Definition())},
+	}
+}
+
+// diffColumn compares two versions of the same column.
+func diffColumn(table string, old, cur *Column) []*Change {
+	var changes []*Change
+	alter := func(action string) string {
+		return fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s %s", quoteIdent(table), quoteIdent(cur.Name), action)
+	}
+	ref := table + "." + cur.Name
+
+	if !SameType(old.Type, cur.Type) {
+		narrowing := !isWidening(old.Type, cur.Type)
+		desc := fmt.Sprintf("change type of %s from %s to %s", ref, old.Type, cur.Type)
+		if narrowing {
+			desc += " (narrowing)"
+		}
+		changes = append(changes, &Change{
+			Kind:        ChangeAlterType,
+			Table:       table,
+			Column:      cur.Name,
+			Description: desc,
+			Destructive: narrowing,
+			Up:          []string{alter(typeChangeAction(cur.Name, cur.Type))},
+			Down:        []string{alter(typeChangeAction(cur.Name, old.Type))},
+		})
+	}
+
+	oldNotNull := !old.Nullable || old.Primary
+	curNotNull := !cur.Nullable || cur.Primary
+	if oldNotNull != curNotNull {
+		set, drop := alter("SET NOT NULL"), alter("DROP NOT NULL")
+		ch := &Change{Kind: ChangeAlterNullable, Table: table, Column: cur.Name}
+		if curNotNull {
+			ch.Description = fmt.Sprintf("make %s NOT NULL", ref)
+			ch.Up, ch.Down = []string{set}, []string{drop}
+		} else {
+			ch.Description = fmt.Sprintf("make %s nullable", ref)
+			ch.Up, ch.Down = []string{drop}, []string{set}
+		}
+		changes = append(changes, ch)
+	}
+
+	if !isSerial(old.Type) && !isSerial(cur.Type) && normalizeDefault(old.Default) != normalizeDefault(cur.Default) {
+		changes = append(changes, &Change{
+			Kind:        ChangeAlterDefault,
+			Table:       table,
+			Column:      cur.Name,
+			Description: fmt.Sprintf("change default of %s", ref),
+			Up:          []string{alter(defaultAction(cur.Default))},
+			Down:        []string{alter(defaultAction(old.Default))},
+		})
+	}
+
+	oldUnique := old.Unique && !old.Primary
+	curUnique := cur.Unique && !cur.Primary
+	if oldUnique != curUnique {
+		name := fmt.Sprintf("%s_%s_key", table, cur.Name)
+		add := addConstraintSQL(table, fmt.Sprintf("CONSTRAINT %s UNIQUE (%s)", quoteIdent(name), quoteIdent(cur.Name)))
+		drop := dropConstraintSQL(table, name)
+		ch := &Change{Kind: ChangeAlterUnique, Table: table, Column: cur.Name}
+		if curUnique {
+			ch.Description = fmt.Sprintf("add unique constraint on %s", ref)
+			ch.Up, ch.Down = []string{add}, []string{drop}
+		} else {
+			ch.Description = fmt.Sprintf("drop unique constraint on %s", ref)
+			ch.Up, ch.Down = []string{drop}, []string{add}
+		}
+		changes = append(changes, ch)
+	}
+
+	if !sameStrings(old.Enum, cur.Enum) {
+		removed := missingStrings(old.Enum, cur.Enum)
+		desc := fmt.Sprintf("change allowed values of %s", ref)
+		if len(cur.Enum) > 0 && len(removed) > 0 {
+			desc += fmt.Sprintf(" (removes %s)", strings.Join(removed, ", "))
+		}
+		changes = append(changes, &Change{
+			Kind:        ChangeAlterEnum,
+			Table:       table,
+			Column:      cur.Name,
+			Description: desc,
+			Destructive: len(cur.Enum) > 0 && len(removed) > 0,
+			Up:          enumCheckSQL(table, cur.Name, cur.Enum),
+			Down:        enumCheckSQL(table, cur.Name, old.Enum),
+		})
+	}
+
+	return changes
+}
+
+// typeChangeAction renders the ALTER COLUMN action converting a column to
+// the given type.
+func typeChangeAction(column, sqlType string) string {
+	sqlType = storageType(sqlType)
+	return fmt.Sprintf("TYPE %s USING %s::%s", sqlType, quoteIdent(column), sqlType)
+}
+
+// defaultAction renders the ALTER COLUMN action setting a default.
+func defaultAction(def string) string {
+	if def == "" {
+		return "DROP DEFAULT"
+	}
+	return "SET DEFAULT " + def
+}
+
+// enumCheckSQL replaces the CHECK constraint restricting a column to a set
+// of values. The constraint uses PostgreSQL's default name for inline
+// column checks so it matches tables created from Statements.
+func enumCheckSQL(table, column string, values []string) []string {
+	name := fmt.Sprintf("%s_%s_check", table, column)
+	stmts := []string{dropConstraintSQL(table, name)}
+	if len(values) == 0 {
+		return stmts
+	}
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = quoteLiteral(v)
+	}
+	return append(stmts, addConstraintSQL(table, fmt.Sprintf("CONSTRAINT %s CHECK (%s IN (%s))",
+		quoteIdent(name), quoteIdent(column), strings.Join(quoted, ", "))))
+}
+
+func addConstraintSQL(table, definition string) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD %s", quoteIdent(table), definition)
+}
+
+func dropConstraintSQL(table, name string) string {
+	return fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT IF EXISTS %s", quoteIdent(table), quoteIdent(name))
+}
+
+// varcharPattern matches VARCHAR types with an explicit length.
+var varcharPattern = regexp.MustCompile(`^VARCHAR\((\d+)\)$`)
+
+// widenings lists the type conversions that never lose data.
+var widenings = map[string][]string{
+	"SMALLINT":  {"INTEGER", "BIGINT", "NUMERIC", "DOUBLE PRECISION"},
+	"INTEGER":   {"BIGINT", "NUMERIC", "DOUBLE PRECISION"},
+	"BIGINT":    {"NUMERIC"},
+	"REAL":      {"DOUBLE PRECISION", "NUMERIC"},
+	"VARCHAR":   {"TEXT"},
+	"DATE":      {"TIMESTAMP", "TIMESTAMPTZ"},
+	"TIMESTAMP": {"TIMESTAMPTZ"},
+	"JSON":      {"JSONB", "TEXT"},
+	"UUID":      {"TEXT"},
+}
+
+// isWidening reports whether converting a column from one type to another
+// preserves all existing values.
+func isWidening(from, to string) bool {
+	from, to = canonicalType(storageType(from)), canonicalType(storageType(to))
+	if from == to {
+		return true
+	}
+
+	fromElem, fromArray := strings.CutSuffix(from, "[]")
+	toElem, toArray := strings.CutSuffix(to, "[]")
+	if fromArray != toArray {
+		return false
+	}
+	if fromArray {
+		return isWidening(fromElem, toElem)
+	}
+
+	fm, tm := varcharPattern.FindStringSubmatch(from), varcharPattern.FindStringSubmatch(to)
+	switch {
+	case fm != nil && tm != nil:
+		fromLen, _ := strconv.Atoi(fm[1])
+		toLen, _ := strconv.Atoi(tm[1])
+		return toLen >= fromLen
+	case fm != nil && to == "VARCHAR":
+		return true
+	case fm != nil:
+		from = "VARCHAR"
+	}
+
+	for _, wider := range widenings[from] {
+		if wider == to {
+			return true
+		}
+	}
+	return false
+}
+
+// storageType returns the type a column is stored as. Serial types are
+// integers with a sequence default and cannot be used in ALTER COLUMN TYPE.
+func storageType(t string) string {
+	switch strings.ToUpper(t) {
+	case "SERIAL":
+		return "INTEGER"
+	case "BIGSERIAL":
+		return "BIGINT"
+	}
+	return t
+}
+
+func isSerial(t string) bool {
+	t = strings.ToUpper(t)
+	return t == "SERIAL" || t == "BIGSERIAL"
+}
+
+// castPattern matches PostgreSQL type casts appended to introspected
+// default expressions, e.g. 'draft'::character varying.
+var castPattern = regexp.MustCompile(`::[a-z ]+(\(\d+\))?(\[\])?`)
+
+// normalizeDefault canonicalizes a default expression so that defaults
+// rendered by the compiler compare equal to those read back from the
+// database.
+func normalizeDefault(def string) string {
+	def = strings.ToLower(strings.TrimSpace(def))
+	def = castPattern.ReplaceAllString(def, "")
+	for strings.HasPrefix(def, "(") && strings.HasSuffix(def, ")") {
+		def = strings.TrimSpace(def[1 : len(def)-1])
+	}
+	switch def {
+	case "current_timestamp", "now()":
+		return "now()"
+	case "true", "false":
+		return def
+	}
+	if n, err := strconv.ParseFloat(def, 64); err == nil {
+		return strconv.FormatFloat(n, 'f', -1, 64)
+	}
+	return def
+}
+
+// tableFK pairs a foreign key with the table that declares it.
+type tableFK struct {
+	table string
+	fk    *ForeignKey
+}
+
+func tablesByName(s *Schema) map[string]*Table {
+	m := make(map[string]*Table, len(s.Tables))
+	for _, t := range s.Tables {
+		m[t.Name] = t
+	}
+	return m
+}
+
+func foreignKeysByName(s *Schema) map[string]tableFK {
+	m := make(map[string]tableFK)
+	for _, t := range s.Tables {
+		for _, fk := range t.ForeignKeys {
+			m[t.Name+"."+fk.Name] = tableFK{table: t.Name, fk: fk}
+		}
+	}
+	return m
+}
+
+func indexesByName(s *Schema) map[string]*Index {
+	m := make(map[string]*Index)
+	for _, t := range s.Tables {
+		for _, idx := range t.Indexes {
+			m[idx.Name] = idx
+		}
+	}
+	return m
+}
+
+func sameForeignKey(a, b *ForeignKey) bool {
+	return a.Column == b.Column && a.RefTable == b.RefTable &&
+		a.RefColumn == b.RefColumn && strings.EqualFold(a.OnDelete, b.OnDelete)
+}
+
+func sameIndex(a, b *Index) bool {
+	return a.Table == b.Table && a.Unique == b.Unique && sameStrings(a.Columns, b.Columns)
+}
+
+func sameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// missingStrings returns the values of a that are not in b.
+func missingStrings(a, b []string) []string {
+	set := make(map[string]bool, len(b))
+	for _, v := range b {
+		set[v] = true
+	}
+	var out []string
+	for _, v := range a {
+		if !set[v] {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func reversedTables(tables []*Table) []*Table {
+	out := make([]*Table, len(tables))
+	for i, t := range tables {
+		out[len(tables)-1-i] = t
+	}
+	return out
+}
+
+// annotate prefixes a change's statements with a comment describing it.
+func annotate(ch *Change, stmts []string) []string {
+	if len(stmts) == 0 {
+		return nil
+	}
+	prefix := "-- " + ch.Description
+	if ch.Destructive {
+		prefix = "-- DESTRUCTIVE: " + ch.Description
+	}
+	out := append([]string(nil), stmts...)
+	out[0] = prefix + "\n" + out[0]
+	return out
+}
+
+func joinStatements(stmts []string) string {
+	if len(stmts) == 0 {
+		return ""
+	}
+	return strings.Join(stmts, ";\n\n") + ";\n"
+}
@@ -0,0 +1,280 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const diffBase = `
+database postgres {
+    model User {
+        id: uuid, primary, auto
+        email: string, required, unique
+        name: text
+        age: int
+        role: enum(reader, author, admin), default(reader)
+    }
+}
+`
+
+func diffSchemas(t *testing.T, from, to string) *Changeset {
+	t.Helper()
+	return Diff(mustParseSchema(t, from), mustParseSchema(t, to))
+}
+
+func changeKinds(cs *Changeset) []ChangeKind {
+	kinds := make([]ChangeKind, len(cs.Changes))
+	for i, ch := range cs.Changes {
+		kinds[i] = ch.Kind
+	}
+	return kinds
+}
+
+func TestDiff_NoChanges(t *testing.T) {
+	cs := diffSchemas(t, diffBase, diffBase)
+	assert.True(t, cs.Empty())
+	assert.Empty(t, cs.UpSQL())
+	assert.Empty(t, cs.DownSQL())
+}
+
+func TestDiff_CreateTable(t *testing.T) {
+	cs := diffSchemas(t, diffBase, diffBase+`
+database postgres {
+    model Post {
+        id: uuid, primary, auto
+        author_id: ref(User), required
+
+        index: [author_id]
+    }
+}
+`)
+	require.Equal(t, []ChangeKind{ChangeCreateTable, ChangeAddForeignKey, ChangeAddIndex}, changeKinds(cs))
+	assert.Empty(t, cs.Destructive())
+
+	up := cs.UpSQL()
+	assert.Contains(t, up, "-- create table posts\nCREATE TABLE IF NOT EXISTS \"posts\"")
+	assert.NotContains(t, cs.Changes[0].Up[0], "FOREIGN KEY")
+	assert.Contains(t, up, `ALTER TABLE "posts" ADD CONSTRAINT "fk_posts_author_id" FOREIGN KEY ("author_id") REFERENCES "users" ("id") ON DELETE CASCADE`)
+	assert.Contains(t, up, `CREATE INDEX IF NOT EXISTS "idx_posts_author_id" ON "posts" ("author_id")`)
+
+	assert.Equal(t, `-- add index idx_posts_author_id on posts
+DROP INDEX IF EXISTS "idx_posts_author_id";
+
+-- add foreign key fk_posts_author_id on posts
+ALTER TABLE "posts" DROP CONSTRAINT IF EXISTS "fk_posts_author_id";
+
+-- create table posts
+DROP TABLE IF EXISTS "posts";
+`, cs.DownSQL())
+}
+
+func TestDiff_DropTable(t *testing.T) {
+	cs := diffSchemas(t, diffBase+`
+database postgres {
+    model Post {
+        id: uuid, primary, auto
+        author_id: ref(User)
+    }
+}
+`, diffBase)
+	require.Equal(t, []ChangeKind{ChangeDropForeignKey, ChangeDropTable}, changeKinds(cs))
+
+	destructive := cs.Destructive()
+	require.Len(t, destructive, 1)
+	assert.Equal(t, "drop table posts", destructive[0].Description)
+	assert.Contains(t, cs.UpSQL(), "-- DESTRUCTIVE: drop table posts\nDROP TABLE IF EXISTS \"posts\"")
+
+	// Reverting recreates the table before restoring its foreign key.
+	down := cs.DownSQL()
+	assert.Less(t, strings.Index(down, `CREATE TABLE IF NOT EXISTS "posts"`), strings.Index(down, `ADD CONSTRAINT "fk_posts_author_id"`))
+}
+
+func TestDiff_Columns(t *testing.T) {
+	cs := diffSchemas(t, diffBase, `
+database postgres {
+    model User {
+        id: uuid, primary, auto
+        email: string, required
+        name: string
+        age: decimal, required
+        role: enum(reader, admin), default(admin)
+        bio: text, default("")
+    }
+}
+`)
+
+	byDesc := make(map[string]*Change)
+	for _, ch := range cs.Changes {
+		byDesc[ch.Description] = ch
+	}
+
+	tests := []struct {
+		desc        string
+		destructive bool
+		up          []string
+		down        []string
+	}{
+		{
+			desc: "drop unique constraint on users.email",
+			up:   []string{`ALTER TABLE "users" DROP CONSTRAINT IF EXISTS "users_email_key"`},
+			down: []string{`ALTER TABLE "users" ADD CONSTRAINT "users_email_key" UNIQUE ("email")`},
+		},
+		{
+			desc:        "change type of users.name from TEXT to VARCHAR(255) (narrowing)",
+			destructive: true,
+			up:          []string{`ALTER TABLE "users" ALTER COLUMN "name" TYPE VARCHAR(255) USING "name"::VARCHAR(255)`},
+			down:        []string{`ALTER TABLE "users" ALTER COLUMN "name" TYPE TEXT USING "name"::TEXT`},
+		},
+		{
+			desc: "change type of users.age from INTEGER to NUMERIC",
+			up:   []string{`ALTER TABLE "users" ALTER COLUMN "age" TYPE NUMERIC USING "age"::NUMERIC`},
+			down: []string{`ALTER TABLE "users" ALTER COLUMN "age" TYPE INTEGER USING "age"::INTEGER`},
+		},
+		{
+			desc: "make users.age NOT NULL",
+			up:   []string{`ALTER TABLE "users" ALTER COLUMN "age" SET NOT NULL`},
+			down: []string{`ALTER TABLE "users" ALTER COLUMN "age" DROP NOT NULL`},
+		},
+		{
+			desc: "change default of users.role",
+			up:   []string{`ALTER TABLE "users" ALTER COLUMN "role" SET DEFAULT 'admin'`},
+			down: []string{`ALTER TABLE "users" ALTER COLUMN "role" SET DEFAULT 'reader'`},
+		},
+		{
+			desc:        "change allowed values of users.role (removes author)",
+			destructive: true,
+			up: []string{
+				`ALTER TABLE "users" DROP CONSTRAINT IF EXISTS "users_role_check"`,
+				`ALTER TABLE "users" ADD CONSTRAINT "users_role_check" CHECK ("role" IN ('reader', 'admin'))`,
+			},
+			down: []string{
+				`ALTER TABLE "users" DROP CONSTRAINT IF EXISTS "users_role_check"`,
+				`ALTER TABLE "users" ADD CONSTRAINT "users_role_check" CHECK ("role" IN ('reader', 'author', 'admin'))`,
+			},
+		},
+		{
+			desc: "add column users.bio",
+			up:   []string{`ALTER TABLE "users" ADD COLUMN "bio" TEXT DEFAULT ''`},
+			down: []string{`ALTER TABLE "users" DROP COLUMN "bio"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			ch, ok := byDesc[tt.desc]
+			require.True(t, ok, "missing change %q", tt.desc)
+			assert.Equal(t, tt.destructive, ch.Destructive)
+			assert.Equal(t, tt.up, ch.Up)
+			assert.Equal(t, tt.down, ch.Down)
+		})
+	}
+	assert.Len(t, cs.Changes, len(tests))
+}
+
+func TestDiff_DropColumn(t *testing.T) {
+	cs := diffSchemas(t, diffBase, `
+database postgres {
+    model User {
+        id: uuid, primary, auto
+        email: string, required, unique
+        name: text
+        role: enum(reader, author, admin), default(reader)
+    }
+}
+`)
+	require.Len(t, cs.Changes, 1)
+	ch := cs.Changes[0]
+	assert.Equal(t, ChangeDropColumn, ch.Kind)
+	assert.True(t, ch.Destructive)
+	assert.Equal(t, []string{`ALTER TABLE "users" DROP COLUMN "age"`}, ch.Up)
+	assert.Equal(t, []string{`ALTER TABLE "users" ADD COLUMN "age" INTEGER`}, ch.Down)
+}
+
+func TestDiff_IndexChanges(t *testing.T) {
+	from := `
+database postgres {
+    model Event {
+        id: uuid, primary, auto
+        name: string
+        kind: string
+        index: [name]
+    }
+}
+`
+	to := `
+database postgres {
+    model Event {
+        id: uuid, primary, auto
+        name: string
+        kind: string
+        index: [kind, name]
+    }
+}
+`
+	cs := diffSchemas(t, from, to)
+	require.Equal(t, []ChangeKind{ChangeDropIndex, ChangeAddIndex}, changeKinds(cs))
+	assert.Empty(t, cs.Destructive())
+	assert.Equal(t, []string{`DROP INDEX IF EXISTS "idx_events_name"`}, cs.Changes[0].Up)
+	assert.Equal(t, []string{`CREATE INDEX IF NOT EXISTS "idx_events_kind_name" ON "events" ("kind", "name")`}, cs.Changes[1].Up)
+}
+
+func TestDiff_LiveDefaults(t *testing.T) {
+	// Defaults read back from PostgreSQL carry casts and lower-case names.
+	live := &Schema{Tables: []*Table{{
+		Name: "users",
+		Columns: []*Column{
+			{Name: "id", Type: "UUID", Primary: true, Default: "gen_random_uuid()"},
+			{Name: "role", Type: "VARCHAR(255)", Nullable: true, Default: "'reader'::character varying", Enum: []string{"reader", "admin"}},
+			{Name: "tags", Type: "VARCHAR[]", Nullable: true, Default: "'{}'::character varying[]"},
+			{Name: "score", Type: "NUMERIC", Nullable: true, Default: "0"},
+			{Name: "created_at", Type: "TIMESTAMPTZ", Default: "now()"},
+		},
+		PrimaryKey: []string{"id"},
+	}}}
+	models := mustParseSchema(t, `
+database postgres {
+    model User {
+        id: uuid, primary, auto
+        role: enum(reader, admin), default(reader)
+        tags: list(string), default([])
+        score: decimal, default(0.0)
+        created_at: timestamp, auto
+    }
+}
+`)
+	cs := Diff(live, models)
+	assert.True(t, cs.Empty(), "unexpected changes: %v", changeKinds(cs))
+}
+
+func TestIsWidening(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{"INTEGER", "BIGINT", true},
+		{"SERIAL", "BIGINT", true},
+		{"INTEGER", "NUMERIC", true},
+		{"NUMERIC", "INTEGER", false},
+		{"VARCHAR(100)", "VARCHAR(255)", true},
+		{"VARCHAR(255)", "VARCHAR(100)", false},
+		{"VARCHAR(255)", "TEXT", true},
+		{"TEXT", "VARCHAR(255)", false},
+		{"DATE", "TIMESTAMPTZ", true},
+		{"TIMESTAMPTZ", "DATE", false},
+		{"VARCHAR(255)[]", "TEXT[]", true},
+		{"TEXT[]", "TEXT", false},
+		{"BOOLEAN", "INTEGER", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, isWidening(tt.from, tt.to), "%s -> %s", tt.from, tt.to)
+	}
+}
+
+func TestCheckValues(t *testing.T) {
+	def := `CHECK (((status)::text = ANY ((ARRAY['draft'::character varying, 'it''s'::character varying])::text[])))`
+	assert.Equal(t, []string{"draft", "it's"}, checkValues(def))
+	assert.Nil(t, checkValues(`CHECK ((age > 0))`))
+}
@@ -0,0 +1,491 @@
+package schema
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Manager applies and verifies compiled schemas against a PostgreSQL database.
+type Manager struct {
+	db *sql.DB
+}
+
+// NewManager creates a new Manager for the given database.
+func NewManager(db *sql.DB) *Manager {
+	return &Manager{db: db}
+}
+
+// Apply creates all missing tables, foreign keys and indexes of the schema
+// in a single transaction. Existing tables are left untouched; use
+// Verify to detect drift between the models and the database.
+func (m *Manager) Apply(ctx context.Context, s *Schema) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range s.Statements() {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("executing %q: %w", firstLine(stmt), err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing schema: %w", err)
+	}
+	return nil
+}
+
+// Verify checks that every table and column of the schema exists in the
+// database with a compatible type and nullability. It returns a
+// *VerifyError listing every mismatch found.
+func (m *Manager) Verify(ctx context.Context, s *Schema) error {
+	verr := &VerifyError{}
+
+	for _, t := range s.Tables {
+		columns, err := m.inspectColumns(ctx, t.Name)
+		if err != nil {
+			return fmt.Errorf("inspecting table %s: %w", t.Name, err)
+		}
+		if len(columns) == 0 {
+			verr.add("table %s does not exist", t.Name)
+			continue
+		}
+		live := &Table{Name: t.Name, Columns: columns}
+
+		for _, want := range t.Columns {
+			got, ok := live.Column(want.Name)
+			if !ok {
+				verr.add("column %s.%s does not exist", t.Name, want.Name)
+				continue
+			}
+			if !SameType(want.Type, got.Type) {
+				verr.add("column %s.%s has type %s, expected %s", t.Name, want.Name, got.Type, want.Type)
+			}
+			if !want.Nullable && got.Nullable && !want.Primary {
+				verr.add("column %s.%s is nullable, expected NOT NULL", t.Name, want.Name)
+			}
+		}
+	}
+
+	if len(verr.Problems) > 0 {
+		return verr
+	}
+	return nil
+}
+
+// RuntimeTablePrefix prefixes the names of tables CodeAI creates for itself.
+// Inspect skips every table with this prefix.
+const RuntimeTablePrefix = "codeai_"
+
+// runtimeTables are the tables CodeAI creates for itself outside the DSL
+// models, under names that predate RuntimeTablePrefix.
+var runtimeTables = map[string]bool{
+	"schema_migrations":        true,
+	"configs":                  true,
+	"deployments":              true,
+	"executions":               true,
+	"events":                   true,
+	"event_outbox":             true,
+	"event_replay_checkpoints": true,
+	"webhook_receipts":         true,
+	"api_keys":                 true,
+	"auth_refresh_tokens":      true,
+	"auth_revocations":         true,
+	"auth_signing_keys":        true,
+	"rbac_roles":               true,
+	"rbac_user_roles":          true,
+	"scheduler_jobs":           true,
+	"workflow_executions":      true,
+}
+
+// IsRuntimeTable reports whether name is a table CodeAI creates for itself,
+// rather than the table of a DSL model.
+func IsRuntimeTable(name string) bool {
+	return runtimeTables[name] || strings.HasPrefix(name, RuntimeTablePrefix)
+}
+
+// Inspect reads the current schema of the database: every table in the
+// current schema except CodeAI's own tables (see IsRuntimeTable), with its
+// columns, primary key, foreign keys, unique and enum check constraints,
+// and secondary indexes. Tables listed in ignore are skipped too.
+func (m *Manager) Inspect(ctx context.Context, ignore ...string) (*Schema, error) {
+	skip := make(map[string]bool)
+	for _, t := range ignore {
+		skip[t] = true
+	}
+
+	names, err := m.tableNames(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing tables: %w", err)
+	}
+
+	s := &Schema{}
+	for _, name := range names {
+		if skip[name] || IsRuntimeTable(name) {
+			continue
+		}
+		t, err := m.inspectTable(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("inspecting table %s: %w", name, err)
+		}
+		s.Tables = append(s.Tables, t)
+	}
+	return s, nil
+}
+
+// VerifyError reports the differences found by Manager.Verify.
+type VerifyError struct {
+	Problems []string
+}
+
+func (e *VerifyError) Error() string {
+	return fmt.Sprintf("schema does not match models (%d problem(s)): %s",
+		len(e.Problems), strings.Join(e.Problems, "; "))
+}
+
+func (e *VerifyError) add(format string, args ...interface{}) {
+	e.Problems = append(e.Problems, fmt.Sprintf(format, args...))
+}
+
+// inspectColumns loads the live column definitions of a table in ordinal
+// order. It returns no columns if the table does not exist.
+func (m *Manager) inspectColumns(ctx context.Context, table string) ([]*Column, error) {
+	rows, err := m.db.QueryContext(ctx, `
+		SELECT column_name, data_type, udt_name, character_maximum_length, is_nullable, column_default
+		FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = $1
+		ORDER BY ordinal_position`, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []*Column
+	for rows.Next() {
+		var (
+			name, dataType, udtName, isNullable string
+			maxLength                           sql.NullInt64
+			columnDefault                       sql.NullString
+		)
+		if err := rows.Scan(&name, &dataType, &udtName, &maxLength, &isNullable, &columnDefault); err != nil {
+			return nil, err
+		}
+		columns = append(columns, &Column{
+			Name:     name,
+			Type:     liveType(dataType, udtName, maxLength),
+			Nullable: isNullable == "YES",
+			Default:  columnDefault.String,
+		})
+	}
+	return columns, rows.Err()
+}
+
+// tableNames lists the base tables of the current schema.
+func (m *Manager) tableNames(ctx context.Context) ([]string, error) {
+	rows, err := m.db.QueryContext(ctx, `
+		SELECT table_name
+		FROM information_schema.tables
+		WHERE table_schema = current_schema() AND table_type = 'BASE TABLE'
+		ORDER BY table_name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// inspectTable loads the full definition of a single table.
+func (m *Manager) inspectTable(ctx context.Context, name string) (*Table, error) {
+	t := &Table{Name: name}
+
+	columns, err := m.inspectColumns(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	t.Columns = columns
+
+	pk, err := m.primaryKey(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	t.PrimaryKey = pk
+	for _, col := range pk {
+		if c, ok := t.Column(col); ok {
+			c.Primary = true
+		}
+	}
+
+	if err := m.inspectConstraints(ctx, t); err != nil {
+		return nil, err
+	}
+
+	indexes, err := m.inspectIndexes(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	t.Indexes = indexes
+
+	return t, nil
+}
+
+// primaryKey returns the primary key columns of a table in key order.
+func (m *Manager) primaryKey(ctx context.Context, table string) ([]string, error) {
+	rows, err := m.db.QueryContext(ctx, `
+		SELECT a.attname
+		FROM pg_index i
+		JOIN LATERAL unnest(i.indkey::int2[]) WITH ORDINALITY AS k(attnum, ord) ON TRUE
+		JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = k.attnum
+		WHERE i.indrelid = $1::regclass AND i.indisprimary
+		ORDER BY k.ord`, quoteIdent(table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cols []string
+	for rows.Next() {
+		var col string
+		if err := rows.Scan(&col); err != nil {
+			return nil, err
+		}
+		cols = append(cols, col)
+	}
+	return cols, rows.Err()
+}
+
+// inspectConstraints loads foreign keys, single-column unique constraints
+// and enum check constraints into the table.
+func (m *Manager) inspectConstraints(ctx context.Context, t *Table) error {
+	rows, err := m.db.QueryContext(ctx, `
+		SELECT c.conname, c.contype::text, pg_get_constraintdef(c.oid),
+		       array_to_string(ARRAY(
+		           SELECT a.attname FROM unnest(c.conkey) WITH ORDINALITY AS k(attnum, ord)
+		           JOIN pg_attribute a ON a.attrelid = c.conrelid AND a.attnum = k.attnum
+		           ORDER BY k.ord), ','),
+		       COALESCE(f.relname, ''),
+		       array_to_string(ARRAY(
+		           SELECT a.attname FROM unnest(c.confkey) WITH ORDINALITY AS k(attnum, ord)
+		           JOIN pg_attribute a ON a.attrelid = c.confrelid AND a.attnum = k.attnum
+		           ORDER BY k.ord), ','),
+		       c.confdeltype::text
+		FROM pg_constraint c
+		LEFT JOIN pg_class f ON f.oid = c.confrelid
+		WHERE c.conrelid = $1::regclass AND c.contype IN ('f', 'u', 'c')
+		ORDER BY c.conname`, quoteIdent(t.Name))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, kind, def, cols, refTable, refCols, onDelete string
+		if err := rows.Scan(&name, &kind, &def, &cols, &refTable, &refCols, &onDelete); err != nil {
+			return err
+		}
+		columns := strings.Split(cols, ",")
+
+		switch kind {
+		case "f":
+			if len(columns) != 1 {
+				continue
+			}
+			t.ForeignKeys = append(t.ForeignKeys, &ForeignKey{
+				Name:      name,
+				Column:    columns[0],
+				RefTable:  refTable,
+				RefColumn: refCols,
+				OnDelete:  onDeleteActions[onDelete],
+			})
+		case "u":
+			if len(columns) != 1 {
+				continue
+			}
+			if c, ok := t.Column(columns[0]); ok {
+				c.Unique = true
+			}
+		case "c":
+			if len(columns) != 1 {
+				continue
+			}
+			if values := checkValues(def); values != nil {
+				if c, ok := t.Column(columns[0]); ok {
+					c.Enum = values
+				}
+			}
+		}
+	}
+	return rows.Err()
+}
+
+// inspectIndexes loads the secondary indexes of a table. Indexes backing
+// primary key and unique constraints are excluded.
+func (m *Manager) inspectIndexes(ctx context.Context, table string) ([]*Index, error) {
+	rows, err := m.db.QueryContext(ctx, `
+		SELECT i.relname, ix.indisunique,
+		       array_to_string(ARRAY(
+		           SELECT a.attname FROM unnest(ix.indkey::int2[]) WITH ORDINALITY AS k(attnum, ord)
+		           JOIN pg_attribute a ON a.attrelid = ix.indrelid AND a.attnum = k.attnum
+		           ORDER BY k.ord), ',')
+		FROM pg_index ix
+		JOIN pg_class i ON i.oid = ix.indexrelid
+		WHERE ix.indrelid = $1::regclass AND NOT ix.indisprimary
+		  AND NOT EXISTS (SELECT 1 FROM pg_constraint c WHERE c.conindid = ix.indexrelid)
+		ORDER BY i.relname`, quoteIdent(table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var indexes []*Index
+	for rows.Next() {
+		var (
+			name, cols string
+			unique     bool
+		)
+		if err := rows.Scan(&name, &unique, &cols); err != nil {
+			return nil, err
+		}
+		indexes = append(indexes, &Index{
+			Name:    name,
+			Table:   table,
+			Columns: strings.Split(cols, ","),
+			Unique:  unique,
+		})
+	}
+	return indexes, rows.Err()
+}
+
+// onDeleteActions maps pg_constraint.confdeltype codes to SQL actions.
+var onDeleteActions = map[string]string{
+	"a": "NO ACTION",
+	"r": "RESTRICT",
+	"c": "CASCADE",
+	"n": "SET NULL",
+	"d": "SET DEFAULT",
+}
+
+// checkValuePattern matches the string literals of a check constraint.
+var checkValuePattern = regexp.MustCompile(`'((?:[^']|'')*)'`)
+
+// checkValues extracts the allowed values from an enum check constraint as
+// rendered by pg_get_constraintdef, e.g.
+// CHECK (((status)::text = ANY ((ARRAY['draft'::character varying, ...])::text[]))).
+// It returns nil for other check constraints.
+func checkValues(def string) []string {
+	if !strings.Contains(def, "ANY (") && !strings.Contains(def, " IN (") {
+		return nil
+	}
+	matches := checkValuePattern.FindAllStringSubmatch(def, -1)
+	if len(matches) == 0 {
+		return nil
+	}
+	values := make([]string, len(matches))
+	for i, m := range matches {
+		values[i] = strings.ReplaceAll(m[1], "''", "'")
+	}
+	return values
+}
+
+// liveTypes maps information_schema data types to the type names used by
+// the schema compiler.
+var liveTypes = map[string]string{
+	"uuid":                        "UUID",
+	"text":                        "TEXT",
+	"integer":                     "INTEGER",
+	"bigint":                      "BIGINT",
+	"smallint":                    "SMALLINT",
+	"numeric":                     "NUMERIC",
+	"boolean":                     "BOOLEAN",
+	"timestamp with time zone":    "TIMESTAMPTZ",
+	"timestamp without time zone": "TIMESTAMP",
+	"date":                        "DATE",
+	"time without time zone":      "TIME",
+	"jsonb":                       "JSONB",
+	"json":                        "JSON",
+	"double precision":            "DOUBLE PRECISION",
+	"real":                        "REAL",
+}
+
+// liveArrayElems maps array element udt names (without the leading
+// underscore) to schema compiler type names.
+var liveArrayElems = map[string]string{
+	"uuid":        "UUID",
+	"varchar":     "VARCHAR",
+	"text":        "TEXT",
+	"int4":        "INTEGER",
+	"int8":        "BIGINT",
+	"numeric":     "NUMERIC",
+	"bool":        "BOOLEAN",
+	"timestamptz": "TIMESTAMPTZ",
+	"date":        "DATE",
+	"time":        "TIME",
+	"jsonb":       "JSONB",
+}
+
+// liveType converts an information_schema column type to the schema
+// compiler's type vocabulary.
+func liveType(dataType, udtName string, maxLength sql.NullInt64) string {
+	switch dataType {
+	case "character varying":
+		if maxLength.Valid {
+			return fmt.Sprintf("VARCHAR(%d)", maxLength.Int64)
+		}
+		return "VARCHAR"
+	case "ARRAY":
+		if elem, ok := liveArrayElems[strings.TrimPrefix(udtName, "_")]; ok {
+			return elem + "[]"
+		}
+		return strings.ToUpper(strings.TrimPrefix(udtName, "_")) + "[]"
+	}
+	if t, ok := liveTypes[dataType]; ok {
+		return t
+	}
+	return strings.ToUpper(dataType)
+}
+
+// SameType reports whether two column types are equivalent. Serial types
+// compare equal to their integer storage type, and array element lengths
+// are ignored because PostgreSQL does not retain them.
+func SameType(a, b string) bool {
+	return canonicalType(a) == canonicalType(b)
+}
+
+func canonicalType(t string) string {
+	t = strings.ToUpper(strings.TrimSpace(t))
+	switch t {
+	case "SERIAL":
+		return "INTEGER"
+	case "BIGSERIAL":
+		return "BIGINT"
+	}
+	if strings.HasSuffix(t, "[]") {
+		elem := strings.TrimSuffix(t, "[]")
+		if i := strings.Index(elem, "("); i >= 0 {
+			elem = elem[:i]
+		}
+		return elem + "[]"
+	}
+	return t
+}
+
+// firstLine returns the first line of a statement for error messages.
+func firstLine(stmt string) string {
+	if i := strings.IndexByte(stmt, '\n'); i >= 0 {
+		return strings.TrimSpace(stmt[:i])
+	}
+	return stmt
+}
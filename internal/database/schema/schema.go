@@ -0,0 +1,364 @@
+// Package schema compiles DSL model declarations into a PostgreSQL schema.
+// It maps `database postgres { model ... }` declarations to tables, columns,
+// foreign keys and indexes, renders them as DDL, and applies or verifies
+// them against a live database.
+package schema
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bargom/codeai/internal/ast"
+)
+
+// Schema is the relational schema derived from a set of DSL models.
+type Schema struct {
+	Tables []*Table
+}
+
+// Table describes a database table generated from a model.
+type Table struct {
+	// Name is the SQL table name (e.g. "order_items").
+	Name string
+	// Model is the DSL model name the table was generated from (e.g. "OrderItem").
+	Model string
+	// Columns are the table columns in declaration order.
+	Columns []*Column
+	// PrimaryKey lists the primary key column names.
+	PrimaryKey []string
+	// ForeignKeys lists the foreign key constraints declared via ref() fields.
+	ForeignKeys []*ForeignKey
+	// Indexes lists the secondary indexes declared on the model.
+	Indexes []*Index
+}
+
+// Column describes a single table column.
+type Column struct {
+	Name     string
+	Type     string // PostgreSQL type, e.g. "UUID", "VARCHAR(255)", "TEXT[]"
+	Nullable bool
+	Unique   bool
+	Primary  bool
+	Default  string   // SQL default expression, empty if none
+	Enum     []string // allowed values for enum() fields
+}
+
+// ForeignKey describes a foreign key constraint generated from a ref() field.
+type ForeignKey struct {
+	Name      string
+	Column    string
+	RefTable  string
+	RefColumn string
+	OnDelete  string // "CASCADE" or "SET NULL"
+}
+
+// Index describes a secondary index on a table.
+type Index struct {
+	Name    string
+	Table   string
+	Columns []string
+	Unique  bool
+}
+
+// FromProgram builds a Schema from all PostgreSQL models in a program.
+// Models are collected from `database postgres { ... }` blocks and from
+// top-level model declarations. MongoDB collections are ignored.
+func FromProgram(program *ast.Program) (*Schema, error) {
+	if program == nil {
+		return &Schema{}, nil
+	}
+	return FromModels(CollectModels(program))
+}
+
+// CollectModels returns the PostgreSQL model declarations in a program in
+// declaration order.
+func CollectModels(program *ast.Program) []*ast.ModelDecl {
+	var models []*ast.ModelDecl
+	if program == nil {
+		return models
+	}
+
+	for _, stmt := range program.Statements {
+		switch s := stmt.(type) {
+		case *ast.DatabaseBlock:
+			if s.DBType != ast.DatabaseTypePostgres {
+				continue
+			}
+			for _, inner := range s.Statements {
+				if m, ok := inner.(*ast.ModelDecl); ok {
+					models = append(models, m)
+				}
+			}
+		case *ast.ModelDecl:
+			models = append(models, s)
+		}
+	}
+	return models
+}
+
+// FromModels builds a Schema from model declarations.
+// It returns an error if a model references an unknown model, uses an
+// unsupported type, or declares an index on an unknown field.
+func FromModels(models []*ast.ModelDecl) (*Schema, error) {
+	byName := make(map[string]*ast.ModelDecl, len(models))
+	for _, m := range models {
+		if _, exists := byName[m.Name]; exists {
+			return nil, fmt.Errorf("duplicate model %q", m.Name)
+		}
+		byName[m.Name] = m
+	}
+
+	s := &Schema{Tables: make([]*Table, 0, len(models))}
+	for _, m := range models {
+		table, err := buildTable(m, byName)
+		if err != nil {
+			return nil, fmt.Errorf("model %s: %w", m.Name, err)
+		}
+		s.Tables = append(s.Tables, table)
+	}
+
+	return s, nil
+}
+
+// Table returns the table generated for the given model or table name.
+func (s *Schema) Table(name string) (*Table, bool) {
+	for _, t := range s.Tables {
+		if t.Name == name || t.Model == name {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+// Column returns the column with the given name.
+func (t *Table) Column(name string) (*Column, bool) {
+	for _, c := range t.Columns {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+// SortedTables returns the tables ordered so that referenced tables come
+// before the tables referencing them. Tables involved in a reference cycle
+// keep their declaration order.
+func (s *Schema) SortedTables() []*Table {
+	byName := make(map[string]*Table, len(s.Tables))
+	for _, t := range s.Tables {
+		byName[t.Name] = t
+	}
+
+	sorted := make([]*Table, 0, len(s.Tables))
+	state := make(map[string]int) // 0 = unvisited, 1 = visiting, 2 = done
+
+	var visit func(t *Table)
+	visit = func(t *Table) {
+		if state[t.Name] != 0 {
+			return
+		}
+		state[t.Name] = 1
+		for _, fk := range t.ForeignKeys {
+			if dep, ok := byName[fk.RefTable]; ok && dep != t {
+				visit(dep)
+			}
+		}
+		state[t.Name] = 2
+		sorted = append(sorted, t)
+	}
+
+	for _, t := range s.Tables {
+		visit(t)
+	}
+	return sorted
+}
+
+// TableName converts a model name to its table name: snake_case, pluralized.
+// For example, User -> users, OrderItem -> order_items, Category -> categories.
+func TableName(model string) string {
+	return pluralize(toSnakeCase(model))
+}
+
+// buildTable converts a model declaration into a Table.
+func buildTable(m *ast.ModelDecl, models map[string]*ast.ModelDecl) (*Table, error) {
+	t := &Table{
+		Name:  TableName(m.Name),
+		Model: m.Name,
+	}
+
+	for _, f := range m.Fields {
+		col, fk, err := buildColumn(t.Name, f, models)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", f.Name, err)
+		}
+		t.Columns = append(t.Columns, col)
+		if col.Primary {
+			t.PrimaryKey = append(t.PrimaryKey, col.Name)
+		}
+		if fk != nil {
+			t.ForeignKeys = append(t.ForeignKeys, fk)
+		}
+	}
+
+	for _, idx := range m.Indexes {
+		for _, field := range idx.Fields {
+			if _, ok := t.Column(field); !ok {
+				return nil, fmt.Errorf("index references unknown field %q", field)
+			}
+		}
+		t.Indexes = append(t.Indexes, &Index{
+			Name:    indexName(t.Name, idx.Fields, idx.Unique),
+			Table:   t.Name,
+			Columns: append([]string(nil), idx.Fields...),
+			Unique:  idx.Unique,
+		})
+	}
+
+	return t, nil
+}
+
+// buildColumn converts a field declaration into a Column and, for ref()
+// fields, the matching ForeignKey.
+func buildColumn(table string, f *ast.FieldDecl, models map[string]*ast.ModelDecl) (*Column, *ForeignKey, error) {
+	mods := modifierSet(f.Modifiers)
+	col := &Column{
+		Name:     f.Name,
+		Nullable: true,
+		Unique:   mods.has("unique"),
+		Primary:  mods.has("primary"),
+	}
+
+	if mods.has("required") || col.Primary {
+		col.Nullable = false
+	}
+
+	typeRef := f.FieldType
+	if typeRef == nil {
+		return nil, nil, fmt.Errorf("missing type")
+	}
+
+	var fk *ForeignKey
+	switch typeRef.Name {
+	case "ref":
+		if len(typeRef.Params) != 1 {
+			return nil, nil, fmt.Errorf("ref() takes exactly one model name")
+		}
+		target, ok := models[typeRef.Params[0].Name]
+		if !ok {
+			return nil, nil, fmt.Errorf("ref() to unknown model %q", typeRef.Params[0].Name)
+		}
+		pk := primaryField(target)
+		if pk == nil {
+			return nil, nil, fmt.Errorf("referenced model %q has no primary key", target.Name)
+		}
+		col.Type = referenceType(pk.FieldType)
+		onDelete := "SET NULL"
+		if !col.Nullable {
+			onDelete = "CASCADE"
+		}
+		fk = &ForeignKey{
+			Name:      fmt.Sprintf("fk_%s_%s", table, f.Name),
+			Column:    f.Name,
+			RefTable:  TableName(target.Name),
+			RefColumn: pk.Name,
+			OnDelete:  onDelete,
+		}
+
+	case "enum":
+		if len(typeRef.Params) == 0 {
+			return nil, nil, fmt.Errorf("enum() requires at least one value")
+		}
+		col.Type = "VARCHAR(255)"
+		for _, p := range typeRef.Params {
+			col.Enum = append(col.Enum, p.Name)
+		}
+
+	default:
+		sqlType, err := columnType(typeRef, mods.has("auto") && col.Primary)
+		if err != nil {
+			return nil, nil, err
+		}
+		col.Type = sqlType
+	}
+
+	def, err := columnDefault(typeRef, mods)
+	if err != nil {
+		return nil, nil, err
+	}
+	col.Default = def
+
+	// Auto-populated timestamps are always present.
+	if mods.has("auto") && (typeRef.Name == "timestamp" || typeRef.Name == "date") {
+		col.Nullable = false
+	}
+
+	return col, fk, nil
+}
+
+// primaryField returns the primary key field of a model, if any.
+func primaryField(m *ast.ModelDecl) *ast.FieldDecl {
+	for _, f := range m.Fields {
+		for _, mod := range f.Modifiers {
+			if mod.Name == "primary" {
+				return f
+			}
+		}
+	}
+	return nil
+}
+
+// modifiers is a lookup of field modifiers by name.
+type modifiers map[string]*ast.Modifier
+
+func modifierSet(mods []*ast.Modifier) modifiers {
+	set := make(modifiers, len(mods))
+	for _, m := range mods {
+		set[m.Name] = m
+	}
+	return set
+}
+
+func (m modifiers) has(name string) bool {
+	_, ok := m[name]
+	return ok
+}
+
+// indexName builds a deterministic index name from the table and columns.
+func indexName(table string, columns []string, unique bool) string {
+	prefix := "idx"
+	if unique {
+		prefix = "uidx"
+	}
+	return fmt.Sprintf("%s_%s_%s", prefix, table, strings.Join(columns, "_"))
+}
+
+// toSnakeCase converts a PascalCase or camelCase name to snake_case.
+func toSnakeCase(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		if r >= 'A' && r <= 'Z' {
+			if i > 0 && s[i-1] != '_' {
+				b.WriteByte('_')
+			}
+			b.WriteRune(r + ('a' - 'A'))
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// pluralize applies simple English pluralization rules to a snake_case name.
+func pluralize(s string) string {
+	switch {
+	case s == "":
+		return s
+	case strings.HasSuffix(s, "s"), strings.HasSuffix(s, "x"), strings.HasSuffix(s, "z"),
+		strings.HasSuffix(s, "ch"), strings.HasSuffix(s, "sh"):
+		return s + "es"
+	case strings.HasSuffix(s, "y") && len(s) > 1 && !strings.ContainsRune("aeiou", rune(s[len(s)-2])):
+		return s[:len(s)-1] + "ies"
+	default:
+		return s + "s"
+	}
+}
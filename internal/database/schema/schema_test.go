@@ -0,0 +1,281 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bargom/codeai/internal/parser"
+)
+
+const blogModels = `
+database postgres {
+    model Post {
+        id: uuid, primary, auto
+        title: string, required
+        body: text
+        author_id: ref(User), required
+        editor_id: ref(User)
+        tags: list(string)
+        status: enum(draft, published), default(draft)
+        rating: decimal, default(0)
+        published: boolean, default(false)
+        created_at: timestamp, auto
+
+        index: [author_id]
+        index: [status, created_at]
+        index: [title] unique
+    }
+
+    model User {
+        id: uuid, primary, auto
+        email: string, required, unique
+        name: string, required
+    }
+}
+`
+
+func mustParseSchema(t *testing.T, input string) *Schema {
+	t.Helper()
+	program, err := parser.Parse(input)
+	require.NoError(t, err)
+	s, err := FromProgram(program)
+	require.NoError(t, err)
+	return s
+}
+
+func TestTableName(t *testing.T) {
+	tests := map[string]string{
+		"User":      "users",
+		"OrderItem": "order_items",
+		"Category":  "categories",
+		"Address":   "addresses",
+		"Day":       "days",
+		"AuditLog":  "audit_logs",
+		"Box":       "boxes",
+	}
+	for model, want := range tests {
+		assert.Equal(t, want, TableName(model), model)
+	}
+}
+
+func TestFromProgram(t *testing.T) {
+	s := mustParseSchema(t, blogModels)
+	require.Len(t, s.Tables, 2)
+
+	posts, ok := s.Table("Post")
+	require.True(t, ok)
+	assert.Equal(t, "posts", posts.Name)
+	assert.Equal(t, []string{"id"}, posts.PrimaryKey)
+
+	t.Run("maps scalar types", func(t *testing.T) {
+		cases := map[string]string{
+			"id":         "UUID",
+			"title":      "VARCHAR(255)",
+			"body":       "TEXT",
+			"tags":       "VARCHAR(255)[]",
+			"rating":     "NUMERIC",
+			"published":  "BOOLEAN",
+			"created_at": "TIMESTAMPTZ",
+		}
+		for name, want := range cases {
+			col, ok := posts.Column(name)
+			require.True(t, ok, name)
+			assert.Equal(t, want, col.Type, name)
+		}
+	})
+
+	t.Run("applies modifiers", func(t *testing.T) {
+		id, _ := posts.Column("id")
+		assert.True(t, id.Primary)
+		assert.Equal(t, "gen_random_uuid()", id.Default)
+
+		title, _ := posts.Column("title")
+		assert.False(t, title.Nullable)
+
+		body, _ := posts.Column("body")
+		assert.True(t, body.Nullable)
+
+		created, _ := posts.Column("created_at")
+		assert.False(t, created.Nullable)
+		assert.Equal(t, "NOW()", created.Default)
+
+		published, _ := posts.Column("published")
+		assert.Equal(t, "FALSE", published.Default)
+	})
+
+	t.Run("maps enums to checked columns", func(t *testing.T) {
+		status, _ := posts.Column("status")
+		assert.Equal(t, []string{"draft", "published"}, status.Enum)
+		assert.Equal(t, "'draft'", status.Default)
+	})
+
+	t.Run("builds foreign keys from refs", func(t *testing.T) {
+		require.Len(t, posts.ForeignKeys, 2)
+		author := posts.ForeignKeys[0]
+		assert.Equal(t, "author_id", author.Column)
+		assert.Equal(t, "users", author.RefTable)
+		assert.Equal(t, "id", author.RefColumn)
+		assert.Equal(t, "CASCADE", author.OnDelete)
+		assert.Equal(t, "SET NULL", posts.ForeignKeys[1].OnDelete)
+
+		col, _ := posts.Column("author_id")
+		assert.Equal(t, "UUID", col.Type)
+	})
+
+	t.Run("builds indexes", func(t *testing.T) {
+		require.Len(t, posts.Indexes, 3)
+		assert.Equal(t, "idx_posts_status_created_at", posts.Indexes[1].Name)
+		assert.Equal(t, "uidx_posts_title", posts.Indexes[2].Name)
+		assert.True(t, posts.Indexes[2].Unique)
+	})
+}
+
+func TestFromProgram_IgnoresMongoCollections(t *testing.T) {
+	s := mustParseSchema(t, `
+database mongodb {
+    collection Event {
+        _id: objectid, primary
+        name: string, required
+    }
+}
+`)
+	assert.Empty(t, s.Tables)
+}
+
+func TestFromProgram_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name: "unknown ref",
+			input: `database postgres {
+    model Post {
+        id: uuid, primary
+        author_id: ref(Author)
+    }
+}`,
+			want: `unknown model "Author"`,
+		},
+		{
+			name: "unsupported type",
+			input: `database postgres {
+    model Post {
+        id: uuid, primary
+        shape: polygon
+    }
+}`,
+			want: `unsupported type "polygon"`,
+		},
+		{
+			name: "ref without primary key",
+			input: `database postgres {
+    model Tag {
+        name: string
+    }
+    model Post {
+        id: uuid, primary
+        tag_id: ref(Tag)
+    }
+}`,
+			want: "has no primary key",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			program, err := parser.Parse(tt.input)
+			require.NoError(t, err)
+			_, err = FromProgram(program)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
+
+func TestSchema_Statements(t *testing.T) {
+	s := mustParseSchema(t, blogModels)
+	stmts := s.Statements()
+	require.Len(t, stmts, 5)
+
+	t.Run("creates referenced tables first", func(t *testing.T) {
+		assert.True(t, strings.HasPrefix(stmts[0], `CREATE TABLE IF NOT EXISTS "users"`))
+		assert.True(t, strings.HasPrefix(stmts[1], `CREATE TABLE IF NOT EXISTS "posts"`))
+	})
+
+	t.Run("renders column definitions", func(t *testing.T) {
+		users := stmts[0]
+		assert.Contains(t, users, `"id" UUID DEFAULT gen_random_uuid() PRIMARY KEY`)
+		assert.Contains(t, users, `"email" VARCHAR(255) NOT NULL UNIQUE`)
+
+		posts := stmts[1]
+		assert.Contains(t, posts, `"status" VARCHAR(255) DEFAULT 'draft' CHECK ("status" IN ('draft', 'published'))`)
+		assert.Contains(t, posts, `CONSTRAINT "fk_posts_author_id" FOREIGN KEY ("author_id") REFERENCES "users" ("id") ON DELETE CASCADE`)
+	})
+
+	t.Run("renders indexes", func(t *testing.T) {
+		assert.Equal(t, `CREATE INDEX IF NOT EXISTS "idx_posts_author_id" ON "posts" ("author_id")`, stmts[2])
+		assert.Equal(t, `CREATE UNIQUE INDEX IF NOT EXISTS "uidx_posts_title" ON "posts" ("title")`, stmts[4])
+	})
+}
+
+func TestSchema_Statements_ReferenceCycle(t *testing.T) {
+	s := mustParseSchema(t, `
+database postgres {
+    model Team {
+        id: uuid, primary, auto
+        owner_id: ref(Member)
+    }
+    model Member {
+        id: uuid, primary, auto
+        team_id: ref(Team), required
+    }
+}
+`)
+	stmts := s.Statements()
+	require.Len(t, stmts, 3)
+
+	// The cycle is broken by adding one constraint after both tables exist.
+	assert.True(t, strings.HasPrefix(stmts[0], `CREATE TABLE IF NOT EXISTS "members"`))
+	assert.NotContains(t, stmts[0], "FOREIGN KEY")
+	assert.Contains(t, stmts[1], `REFERENCES "members"`)
+	assert.Contains(t, stmts[2], `ALTER TABLE "members" ADD CONSTRAINT "fk_members_team_id"`)
+}
+
+func TestSchema_SelfReference(t *testing.T) {
+	s := mustParseSchema(t, `
+database postgres {
+    model Category {
+        id: int, primary, auto
+        parent_id: ref(Category)
+    }
+}
+`)
+	stmts := s.Statements()
+	require.Len(t, stmts, 1)
+	assert.Contains(t, stmts[0], `"id" SERIAL PRIMARY KEY`)
+	assert.Contains(t, stmts[0], `"parent_id" INTEGER`)
+	assert.Contains(t, stmts[0], `REFERENCES "categories" ("id") ON DELETE SET NULL`)
+}
+
+func TestSameType(t *testing.T) {
+	assert.True(t, SameType("SERIAL", "INTEGER"))
+	assert.True(t, SameType("VARCHAR(255)[]", "VARCHAR[]"))
+	assert.True(t, SameType("timestamptz", "TIMESTAMPTZ"))
+	assert.False(t, SameType("VARCHAR(255)", "TEXT"))
+}
+
+func TestIsRuntimeTable(t *testing.T) {
+	assert.True(t, IsRuntimeTable("schema_migrations"))
+	assert.True(t, IsRuntimeTable("event_outbox"))
+	assert.True(t, IsRuntimeTable("rbac_user_roles"))
+	assert.True(t, IsRuntimeTable("codeai_anything"))
+	assert.True(t, IsRuntimeTable("codeai_webhooks"))
+	assert.False(t, IsRuntimeTable("webhooks"), "a Webhook model's table is diffed")
+	assert.False(t, IsRuntimeTable("users"))
+	assert.False(t, IsRuntimeTable("orders"))
+}
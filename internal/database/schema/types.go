@@ -0,0 +1,142 @@
+package schema
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bargom/codeai/internal/ast"
+)
+
+// scalarTypes maps DSL scalar types to PostgreSQL column types.
+var scalarTypes = map[string]string{
+	"uuid":      "UUID",
+	"string":    "VARCHAR(255)",
+	"text":      "TEXT",
+	"int":       "INTEGER",
+	"integer":   "INTEGER",
+	"decimal":   "NUMERIC",
+	"bool":      "BOOLEAN",
+	"boolean":   "BOOLEAN",
+	"timestamp": "TIMESTAMPTZ",
+	"date":      "DATE",
+	"time":      "TIME",
+	"json":      "JSONB",
+	"jsonb":     "JSONB",
+}
+
+// columnType maps a DSL type reference to a PostgreSQL column type.
+// autoPrimary selects serial types for auto-incrementing integer keys.
+func columnType(t *ast.TypeRef, autoPrimary bool) (string, error) {
+	switch t.Name {
+	case "list", "array":
+		if len(t.Params) == 0 {
+			return "TEXT[]", nil
+		}
+		if len(t.Params) > 1 {
+			return "", fmt.Errorf("%s() takes exactly one element type", t.Name)
+		}
+		elem := t.Params[0]
+		if elem.Name == "ref" || elem.Name == "list" || elem.Name == "array" {
+			return "", fmt.Errorf("%s(%s) is not supported", t.Name, elem.String())
+		}
+		if elem.Name == "enum" {
+			return "VARCHAR(255)[]", nil
+		}
+		elemType, err := columnType(elem, false)
+		if err != nil {
+			return "", err
+		}
+		return elemType + "[]", nil
+	}
+
+	sqlType, ok := scalarTypes[t.Name]
+	if !ok {
+		return "", fmt.Errorf("unsupported type %q", t.Name)
+	}
+	if autoPrimary && sqlType == "INTEGER" {
+		return "SERIAL", nil
+	}
+	return sqlType, nil
+}
+
+// referenceType returns the column type used for a foreign key pointing at
+// a primary key of the given type. Serial keys are referenced as integers.
+func referenceType(pk *ast.TypeRef) string {
+	sqlType, err := columnType(pk, false)
+	if err != nil {
+		return "UUID"
+	}
+	return sqlType
+}
+
+// columnDefault renders the SQL DEFAULT expression for a field.
+func columnDefault(t *ast.TypeRef, mods modifiers) (string, error) {
+	if mod, ok := mods["default"]; ok && mod.Value != nil {
+		return defaultLiteral(mod.Value)
+	}
+
+	if mods.has("auto") || mods.has("auto_update") {
+		switch t.Name {
+		case "uuid":
+			return "gen_random_uuid()", nil
+		case "timestamp":
+			return "NOW()", nil
+		case "date":
+			return "CURRENT_DATE", nil
+		}
+	}
+
+	return "", nil
+}
+
+// defaultLiteral renders a default(...) modifier value as a SQL literal.
+func defaultLiteral(expr ast.Expression) (string, error) {
+	switch e := expr.(type) {
+	case *ast.StringLiteral:
+		return quoteLiteral(e.Value), nil
+	case *ast.NumberLiteral:
+		return strconv.FormatFloat(e.Value, 'f', -1, 64), nil
+	case *ast.BoolLiteral:
+		if e.Value {
+			return "TRUE", nil
+		}
+		return "FALSE", nil
+	case *ast.Identifier:
+		// Bare identifiers are enum members, e.g. default(reader).
+		return quoteLiteral(e.Name), nil
+	case *ast.FunctionCall:
+		switch strings.ToLower(e.Name) {
+		case "now":
+			return "NOW()", nil
+		case "uuid":
+			return "gen_random_uuid()", nil
+		}
+		return "", fmt.Errorf("unsupported default function %s()", e.Name)
+	case *ast.ArrayLiteral:
+		if len(e.Elements) == 0 {
+			return "'{}'", nil
+		}
+		elems := make([]string, len(e.Elements))
+		for i, el := range e.Elements {
+			lit, err := defaultLiteral(el)
+			if err != nil {
+				return "", err
+			}
+			elems[i] = lit
+		}
+		return "ARRAY[" + strings.Join(elems, ", ") + "]", nil
+	default:
+		return "", fmt.Errorf("unsupported default value %s", expr.String())
+	}
+}
+
+// quoteLiteral quotes a string as a SQL literal.
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// quoteIdent quotes an identifier for safe SQL usage.
+func quoteIdent(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
@@ -0,0 +1,21 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+)
+
+type txKey struct{}
+
+// WithTx returns a copy of ctx carrying tx. Stores that support it run their
+// statements in the transaction of their context, so that writes made by
+// different stores commit or roll back together.
+func WithTx(ctx context.Context, tx *sql.Tx) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// TxFromContext returns the transaction carried by ctx, or nil.
+func TxFromContext(ctx context.Context) *sql.Tx {
+	tx, _ := ctx.Value(txKey{}).(*sql.Tx)
+	return tx
+}
@@ -2,6 +2,8 @@ package bus
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"sync"
 )
 
@@ -14,6 +16,8 @@ type EventBus struct {
 	wg          sync.WaitGroup
 	closed      bool
 	closeMu     sync.RWMutex
+	transport   Transport
+	upcaster    Upcaster
 }
 
 // asyncEvent wraps an event for async processing.
@@ -95,12 +99,39 @@ func (eb *EventBus) Unsubscribe(eventType EventType, subscriber Subscriber) {
 	}
 }
 
-// Publish sends an event to all subscribers of the event type.
+// Publish sends an event to all subscribers of the event type and to the
+// subscribers of AllEvents.
 // Errors from individual subscribers are logged but don't affect other subscribers.
+// With a transport, the event is published to the transport instead, and
+// subscribers receive it when it is consumed from there; events published
+// during a replay are always delivered in process.
 func (eb *EventBus) Publish(ctx context.Context, event Event) error {
 	eb.mu.RLock()
-	subs := make([]Subscriber, len(eb.subscribers[event.Type]))
-	copy(subs, eb.subscribers[event.Type])
+	transport := eb.transport
+	eb.mu.RUnlock()
+
+	if transport != nil && !IsReplay(ctx) {
+		if err := transport.Publish(ctx, event); err != nil {
+			return fmt.Errorf("publishing to transport: %w", err)
+		}
+		return nil
+	}
+
+	// Subscriber errors are logged; they are not the publisher's
+	eb.deliver(ctx, event)
+	return nil
+}
+
+// deliver passes an event to the subscribers of its type and of AllEvents.
+// A failing subscriber does not keep the event from the others; their
+// errors are logged and returned together.
+func (eb *EventBus) deliver(ctx context.Context, event Event) error {
+	eb.mu.RLock()
+	subs := make([]Subscriber, 0, len(eb.subscribers[event.Type])+len(eb.subscribers[AllEvents]))
+	subs = append(subs, eb.subscribers[event.Type]...)
+	if event.Type != AllEvents {
+		subs = append(subs, eb.subscribers[AllEvents]...)
+	}
 	eb.mu.RUnlock()
 
 	if len(subs) == 0 {
@@ -111,8 +142,10 @@ func (eb *EventBus) Publish(ctx context.Context, event Event) error {
 	}
 
 	// Publish to all subscribers, isolating errors
+	var errs []error
 	for _, sub := range subs {
 		if err := eb.publishToSubscriber(ctx, sub, event); err != nil {
+			errs = append(errs, err)
 			if eb.logger != nil {
 				eb.logger.Error("subscriber error",
 					"eventType", string(event.Type),
@@ -123,8 +156,7 @@ func (eb *EventBus) Publish(ctx context.Context, event Event) error {
 			// Continue to other subscribers despite error
 		}
 	}
-
-	return nil
+	return errors.Join(errs...)
 }
 
 // publishToSubscriber safely calls a subscriber with panic recovery.
@@ -134,6 +166,7 @@ func (eb *EventBus) publishToSubscriber(ctx context.Context, sub Subscriber, eve
 			if eb.logger != nil {
 				eb.logger.Error("subscriber panicked", "eventType", string(event.Type), "eventID", event.ID, "panic", r)
 			}
+			err = fmt.Errorf("subscriber panicked: %v", r)
 		}
 	}()
 	return sub.Handle(ctx, event)
@@ -170,7 +203,8 @@ func (eb *EventBus) SubscriberCount(eventType EventType) int {
 	return len(eb.subscribers[eventType])
 }
 
-// Close gracefully shuts down the event bus, draining the async buffer.
+// Close gracefully shuts down the event bus, draining the async buffer,
+// and closes its transport, if any.
 func (eb *EventBus) Close() {
 	eb.closeMu.Lock()
 	eb.closed = true
@@ -178,4 +212,13 @@ func (eb *EventBus) Close() {
 
 	close(eb.asyncBuffer)
 	eb.wg.Wait()
+
+	eb.mu.RLock()
+	transport := eb.transport
+	eb.mu.RUnlock()
+	if transport != nil {
+		if err := transport.Close(); err != nil && eb.logger != nil {
+			eb.logger.Error("failed to close transport", "error", err.Error())
+		}
+	}
 }
@@ -121,6 +121,25 @@ func TestEventBus_PublishToMultipleSubscribers(t *testing.T) {
 	assert.Equal(t, int32(3), count.Load())
 }
 
+func TestEventBus_PublishToAllEventsSubscribers(t *testing.T) {
+	eb := NewEventBus(&mockLogger{})
+	defer eb.Close()
+
+	var received []EventType
+	eb.Subscribe(AllEvents, SubscriberFunc(func(ctx context.Context, event Event) error {
+		received = append(received, event.Type)
+		return nil
+	}))
+	eb.Subscribe(EventJobCompleted, SubscriberFunc(func(ctx context.Context, event Event) error {
+		return nil
+	}))
+
+	require.NoError(t, eb.Publish(context.Background(), Event{ID: "1", Type: EventJobCompleted}))
+	require.NoError(t, eb.Publish(context.Background(), Event{ID: "2", Type: EventJobFailed}))
+
+	assert.Equal(t, []EventType{EventJobCompleted, EventJobFailed}, received)
+}
+
 func TestEventBus_SubscriberErrorIsolation(t *testing.T) {
 	logger := &mockLogger{}
 	eb := NewEventBus(logger)
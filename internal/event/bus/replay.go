@@ -0,0 +1,19 @@
+package bus
+
+import "context"
+
+type replayKey struct{}
+
+// WithReplay marks ctx as the context of stored events being replayed.
+// Outbound side effects such as webhooks and emails are suppressed for
+// events handled in it, and events published in it stay in the process:
+// they are neither persisted nor sent through a transport.
+func WithReplay(ctx context.Context) context.Context {
+	return context.WithValue(ctx, replayKey{}, true)
+}
+
+// IsReplay reports whether ctx is the context of a replay.
+func IsReplay(ctx context.Context) bool {
+	replay, _ := ctx.Value(replayKey{}).(bool)
+	return replay
+}
@@ -0,0 +1,115 @@
+package bus
+
+import (
+	"context"
+	"fmt"
+)
+
+// Delivery is an event read from a transport.
+type Delivery struct {
+	Event   Event
+	Offset  string // Position of the event in the transport
+	Attempt int    // 1 on the first delivery of the event
+}
+
+// DeliveryHandler handles events read from a transport. Returning nil
+// acknowledges the event.
+type DeliveryHandler func(ctx context.Context, delivery Delivery) error
+
+// Transport carries events between the event buses of several instances.
+type Transport interface {
+	// Publish appends an event to the transport.
+	Publish(ctx context.Context, event Event) error
+
+	// Consume joins the transport's consumer group and delivers its events
+	// to handler in the background until ctx is done or the transport is
+	// closed. Each event is delivered to one consumer of the group. Events
+	// the handler does not acknowledge are delivered again, and
+	// dead-lettered after too many attempts. Consume returns once the
+	// consumer has joined, so events published afterwards are delivered.
+	Consume(ctx context.Context, handler DeliveryHandler) error
+
+	// Replay delivers the events from offset on, inclusive, to handler
+	// without acknowledging them, and returns when it reaches the last
+	// event or handler fails. An empty offset replays all events.
+	Replay(ctx context.Context, offset string, handler DeliveryHandler) error
+
+	// Close stops consuming and releases the transport.
+	Close() error
+}
+
+// Connect routes the events published on the bus through transport and
+// delivers the events consumed from it to the bus's subscribers, so that
+// buses of several instances sharing a transport see each other's events.
+// Publish then returns once the event is in the transport, and subscribers
+// receive it asynchronously. Events are acknowledged after they were passed
+// to every subscriber; subscriber errors are logged as without a transport.
+// Close closes the transport.
+func (eb *EventBus) Connect(ctx context.Context, transport Transport) error {
+	if err := transport.Consume(ctx, eb.handleDelivery); err != nil {
+		return fmt.Errorf("consuming from transport: %w", err)
+	}
+
+	eb.mu.Lock()
+	eb.transport = transport
+	eb.mu.Unlock()
+	return nil
+}
+
+// UseUpcaster migrates the events consumed from the transport with
+// upcaster before they are delivered, since instances sharing the
+// transport may publish earlier versions of an event, e.g. during a
+// rolling deployment.
+func (eb *EventBus) UseUpcaster(upcaster Upcaster) {
+	eb.mu.Lock()
+	eb.upcaster = upcaster
+	eb.mu.Unlock()
+}
+
+// handleDelivery delivers an event consumed from the transport. Subscriber
+// errors are returned, so the transport redelivers the event, to every
+// subscriber, and eventually dead-letters it. Events that cannot be upcast
+// are logged and dropped, since redelivering them would fail again.
+func (eb *EventBus) handleDelivery(ctx context.Context, delivery Delivery) error {
+	if eb.logger != nil && delivery.Attempt > 1 {
+		eb.logger.Warn("redelivering event",
+			"eventType", string(delivery.Event.Type),
+			"eventID", delivery.Event.ID,
+			"attempt", delivery.Attempt,
+		)
+	}
+
+	eb.mu.RLock()
+	upcaster := eb.upcaster
+	eb.mu.RUnlock()
+
+	event := delivery.Event
+	if upcaster != nil {
+		upcast, err := upcaster.Upcast(event)
+		if err != nil {
+			if eb.logger != nil {
+				eb.logger.Error("dropping event that cannot be upcast",
+					"eventType", string(event.Type),
+					"eventID", event.ID,
+					"error", err.Error(),
+				)
+			}
+			return nil
+		}
+		event = upcast
+	}
+	return eb.deliver(ctx, event)
+}
+
+// Replay delivers the events of the bus's transport from offset on to the
+// bus's subscribers.
+func (eb *EventBus) Replay(ctx context.Context, offset string) error {
+	eb.mu.RLock()
+	transport := eb.transport
+	eb.mu.RUnlock()
+
+	if transport == nil {
+		return fmt.Errorf("event bus has no transport to replay from")
+	}
+	return transport.Replay(ctx, offset, eb.handleDelivery)
+}
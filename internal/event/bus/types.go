@@ -29,19 +29,42 @@ const (
 	EventAgentExecuted      EventType = "agent.executed"
 	EventTestSuiteCompleted EventType = "test.suite.completed"
 	EventWebhookTriggered   EventType = "webhook.triggered"
+	EventWebhookDisabled    EventType = "webhook.disabled"
 	EventEmailSent          EventType = "email.sent"
 )
 
+// AllEvents subscribes to events of every type.
+const AllEvents EventType = "*"
+
 // Event represents an event in the system.
 type Event struct {
-	ID        string                 `json:"id"`
-	Type      EventType              `json:"type"`
-	Source    string                 `json:"source"`
+	ID     string    `json:"id"`
+	Type   EventType `json:"type"`
+	Source string    `json:"source"`
+	// Version is the version of the event type's schema Data conforms to;
+	// zero means the event type is not versioned or the version is unknown.
+	Version   int                    `json:"version,omitempty"`
 	Timestamp time.Time              `json:"timestamp"`
 	Data      map[string]interface{} `json:"data"`
 	Metadata  map[string]string      `json:"metadata"`
 }
 
+// Upcaster migrates events of an earlier version of their type's schema to
+// the latest version, so that subscribers only see the latest version.
+type Upcaster interface {
+	// Upcast returns event migrated to the latest version, or an error if
+	// it cannot be migrated.
+	Upcast(event Event) (Event, error)
+}
+
+// UpcasterFunc is a function type that implements the Upcaster interface.
+type UpcasterFunc func(event Event) (Event, error)
+
+// Upcast calls the function.
+func (f UpcasterFunc) Upcast(event Event) (Event, error) {
+	return f(event)
+}
+
 // Subscriber is the interface that must be implemented by event subscribers.
 type Subscriber interface {
 	// Handle processes an event. It should return an error if the event
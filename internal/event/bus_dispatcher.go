@@ -0,0 +1,78 @@
+package event
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/bargom/codeai/internal/event/bus"
+	busdispatcher "github.com/bargom/codeai/internal/event/dispatcher"
+)
+
+// BusDispatcher is a Dispatcher on top of dispatcher.EventDispatcher. Events
+// dispatched through it are validated, persisted and published to every bus
+// subscriber (metrics, logging, webhooks), and its handlers run as bus
+// subscribers, so DSL events and system events share one pipeline.
+type BusDispatcher struct {
+	events *busdispatcher.EventDispatcher
+	source func(ctx context.Context) string
+}
+
+// NewBusDispatcher creates a Dispatcher publishing to events. source returns
+// the source of events dispatched without one; it may be nil.
+func NewBusDispatcher(events *busdispatcher.EventDispatcher, source func(ctx context.Context) string) *BusDispatcher {
+	return &BusDispatcher{events: events, source: source}
+}
+
+// Dispatch validates, persists and publishes event. Unlike the in-memory
+// dispatcher it returns validation and persistence failures; handler
+// failures are logged by the bus.
+func (d *BusDispatcher) Dispatch(ctx context.Context, event Event) error {
+	if event.Source == "" && d.source != nil {
+		event.Source = d.source(ctx)
+	}
+	return d.events.Dispatch(ctx, ToBusEvent(event))
+}
+
+// Subscribe registers handler as a bus subscriber for the event type.
+func (d *BusDispatcher) Subscribe(eventType EventType, handler Handler) {
+	d.events.Subscribe(bus.EventType(eventType), bus.SubscriberFunc(func(ctx context.Context, event bus.Event) error {
+		return handler(ctx, FromBusEvent(event))
+	}))
+}
+
+// Unsubscribe is not supported: handlers are functions, which cannot be
+// compared to find their subscription.
+func (d *BusDispatcher) Unsubscribe(_ EventType, _ Handler) {}
+
+// ToBusEvent converts event to a bus event. Payloads that are not maps are
+// wrapped under "payload".
+func ToBusEvent(event Event) bus.Event {
+	id := event.ID
+	if id == "" {
+		id = uuid.New().String()
+	}
+	return bus.Event{
+		ID:        id,
+		Type:      bus.EventType(event.Type),
+		Source:    event.Source,
+		Version:   event.Version,
+		Timestamp: event.Timestamp,
+		Data:      payloadMap(event.Payload),
+		Metadata:  event.Metadata,
+	}
+}
+
+// FromBusEvent converts a bus event to an event with the bus event's data as
+// payload.
+func FromBusEvent(event bus.Event) Event {
+	return Event{
+		ID:        event.ID,
+		Type:      EventType(event.Type),
+		Source:    event.Source,
+		Version:   event.Version,
+		Payload:   event.Data,
+		Timestamp: event.Timestamp,
+		Metadata:  event.Metadata,
+	}
+}
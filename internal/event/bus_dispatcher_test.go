@@ -0,0 +1,79 @@
+package event
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bargom/codeai/internal/ast"
+	"github.com/bargom/codeai/internal/event/bus"
+	busdispatcher "github.com/bargom/codeai/internal/event/dispatcher"
+)
+
+func TestBusDispatcher(t *testing.T) {
+	eb := bus.NewEventBus(nil)
+	defer eb.Close()
+	events := busdispatcher.NewDispatcher(eb)
+
+	actions := &fakeActions{}
+	r := NewEventRegistry(
+		NewBusDispatcher(events, func(context.Context) string { return "codeai.dsl" }),
+		WithWebhookSender(actions),
+	)
+	busdispatcher.WithValidator(r)(events)
+
+	require.NoError(t, r.RegisterEventFromAST(&ast.EventDecl{
+		Name: "order.created",
+		Schema: &ast.EventSchema{Fields: []*ast.EventSchemaField{
+			{Name: "order_id", FieldType: "string"},
+		}},
+	}))
+	require.NoError(t, r.SubscribeHandlerFromAST(&ast.EventHandlerDecl{
+		EventName: "order.created", ActionType: "webhook", Target: "shipping",
+	}))
+
+	var published []bus.Event
+	events.Subscribe(bus.AllEvents, bus.SubscriberFunc(func(_ context.Context, event bus.Event) error {
+		published = append(published, event)
+		return nil
+	}))
+
+	require.NoError(t, r.EmitEvent(context.Background(), "order.created", map[string]interface{}{"order_id": "o-1"}))
+
+	require.Len(t, published, 1)
+	assert.NotEmpty(t, published[0].ID)
+	assert.Equal(t, "codeai.dsl", published[0].Source)
+	assert.Equal(t, "o-1", published[0].Data["order_id"])
+
+	calls := actions.recorded()
+	require.Len(t, calls, 1)
+	assert.Equal(t, "shipping", calls[0].target)
+	assert.Equal(t, map[string]interface{}{"order_id": "o-1"}, calls[0].input)
+
+	// Events published on the bus directly are validated too.
+	err := events.Dispatch(context.Background(), bus.Event{
+		ID: "e-2", Type: "order.created", Data: map[string]interface{}{"order_id": 42},
+	})
+	assert.ErrorIs(t, err, busdispatcher.ErrInvalidEvent)
+	assert.Len(t, published, 1)
+
+	// System events without a registered schema pass.
+	require.NoError(t, events.Dispatch(context.Background(), bus.Event{ID: "e-3", Type: bus.EventJobStarted}))
+	assert.Len(t, published, 2)
+}
+
+func TestEventConversion(t *testing.T) {
+	event := NewEvent("order.created", "raw")
+	event.Source = "codeai.dsl"
+
+	busEvent := ToBusEvent(event)
+	assert.Equal(t, event.ID, busEvent.ID)
+	assert.Equal(t, map[string]interface{}{"payload": "raw"}, busEvent.Data)
+
+	back := FromBusEvent(busEvent)
+	assert.Equal(t, event.ID, back.ID)
+	assert.Equal(t, event.Source, back.Source)
+	assert.Equal(t, EventType("order.created"), back.Type)
+}
@@ -3,18 +3,40 @@ package dispatcher
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
 
 	"github.com/bargom/codeai/internal/event/bus"
 	"github.com/bargom/codeai/internal/event/repository"
 )
 
+// ErrInvalidEvent is returned when an event is rejected by the validator.
+var ErrInvalidEvent = errors.New("invalid event")
+
+// Validator checks events before they are persisted and published.
+type Validator interface {
+	// Validate returns an error if event must not be published.
+	Validate(event bus.Event) error
+}
+
+// ValidatorFunc is a function type that implements the Validator interface.
+type ValidatorFunc func(event bus.Event) error
+
+// Validate calls the function.
+func (f ValidatorFunc) Validate(event bus.Event) error {
+	return f(event)
+}
+
 // EventDispatcher dispatches events to the bus and optionally persists them.
 type EventDispatcher struct {
 	bus        *bus.EventBus
 	repository repository.EventRepository
+	validator  Validator
+	upcaster   bus.Upcaster
 	logger     bus.Logger
 	persist    bool
+	mu         sync.RWMutex
 }
 
 // Option configures the EventDispatcher.
@@ -28,6 +50,40 @@ func WithRepository(repo repository.EventRepository) Option {
 	}
 }
 
+// WithValidator rejects events that fail validation, e.g. against the
+// event schemas declared in the DSL.
+func WithValidator(v Validator) Option {
+	return func(d *EventDispatcher) {
+		d.validator = v
+	}
+}
+
+// WithUpcaster migrates dispatched events of earlier versions, such as
+// events recorded in the outbox before a deployment, to the latest version
+// of their type before they are validated.
+func WithUpcaster(u bus.Upcaster) Option {
+	return func(d *EventDispatcher) {
+		d.upcaster = u
+	}
+}
+
+// UseValidator sets the validator of a dispatcher already in use, for
+// validators that need the dispatcher to be created, such as the event
+// registry of the DSL.
+func (d *EventDispatcher) UseValidator(v Validator) {
+	d.mu.Lock()
+	d.validator = v
+	d.mu.Unlock()
+}
+
+// UseUpcaster sets the upcaster of a dispatcher already in use; see
+// UseValidator.
+func (d *EventDispatcher) UseUpcaster(u bus.Upcaster) {
+	d.mu.Lock()
+	d.upcaster = u
+	d.mu.Unlock()
+}
+
 // WithLogger sets the logger for the dispatcher.
 func WithLogger(logger bus.Logger) Option {
 	return func(d *EventDispatcher) {
@@ -50,9 +106,16 @@ func NewDispatcher(b *bus.EventBus, opts ...Option) *EventDispatcher {
 }
 
 // Dispatch publishes an event to subscribers and persists it if configured.
+// Events failing validation are neither persisted nor published, and events
+// dispatched during a replay are not persisted.
 func (d *EventDispatcher) Dispatch(ctx context.Context, event bus.Event) error {
+	event, err := d.validate(event)
+	if err != nil {
+		return err
+	}
+
 	// Persist event first if repository is configured
-	if d.persist && d.repository != nil {
+	if d.persist && d.repository != nil && !bus.IsReplay(ctx) {
 		if err := d.repository.SaveEvent(ctx, event); err != nil {
 			if d.logger != nil {
 				d.logger.Error("failed to persist event",
@@ -89,8 +152,20 @@ func (d *EventDispatcher) Dispatch(ctx context.Context, event bus.Event) error {
 
 // DispatchAsync publishes an event asynchronously without blocking.
 func (d *EventDispatcher) DispatchAsync(ctx context.Context, event bus.Event) {
+	event, err := d.validate(event)
+	if err != nil {
+		if d.logger != nil {
+			d.logger.Error("dropping invalid async event",
+				"eventID", event.ID,
+				"eventType", string(event.Type),
+				"error", err.Error(),
+			)
+		}
+		return
+	}
+
 	// For async dispatch, we still persist synchronously to ensure durability
-	if d.persist && d.repository != nil {
+	if d.persist && d.repository != nil && !bus.IsReplay(ctx) {
 		if err := d.repository.SaveEvent(ctx, event); err != nil {
 			if d.logger != nil {
 				d.logger.Error("failed to persist async event",
@@ -114,6 +189,29 @@ func (d *EventDispatcher) DispatchAsync(ctx context.Context, event bus.Event) {
 	}
 }
 
+// validate upcasts event with the upcaster and checks it with the
+// validator, if any, and returns the upcast event.
+func (d *EventDispatcher) validate(event bus.Event) (bus.Event, error) {
+	d.mu.RLock()
+	upcaster, validator := d.upcaster, d.validator
+	d.mu.RUnlock()
+
+	if upcaster != nil {
+		upcast, err := upcaster.Upcast(event)
+		if err != nil {
+			return event, fmt.Errorf("%w %s: %w", ErrInvalidEvent, event.Type, err)
+		}
+		event = upcast
+	}
+	if validator == nil {
+		return event, nil
+	}
+	if err := validator.Validate(event); err != nil {
+		return event, fmt.Errorf("%w %s: %w", ErrInvalidEvent, event.Type, err)
+	}
+	return event, nil
+}
+
 // Subscribe adds a subscriber for the given event type.
 func (d *EventDispatcher) Subscribe(eventType bus.EventType, subscriber bus.Subscriber) {
 	d.bus.Subscribe(eventType, subscriber)
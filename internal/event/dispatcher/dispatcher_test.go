@@ -181,6 +181,89 @@ func TestDispatcher_DispatchPersistenceError(t *testing.T) {
 	assert.Contains(t, err.Error(), "persisting event")
 }
 
+func TestDispatcher_DispatchValidation(t *testing.T) {
+	eb := bus.NewEventBus(&mockLogger{})
+	defer eb.Close()
+
+	repo := &mockEventRepository{}
+	dispatcher := NewDispatcher(eb, WithRepository(repo), WithValidator(ValidatorFunc(func(event bus.Event) error {
+		if _, ok := event.Data["order_id"].(string); !ok {
+			return errors.New("order_id must be a string")
+		}
+		return nil
+	})))
+
+	var received atomic.Int32
+	eb.Subscribe(bus.EventType("order.created"), bus.SubscriberFunc(func(ctx context.Context, event bus.Event) error {
+		received.Add(1)
+		return nil
+	}))
+
+	err := dispatcher.Dispatch(context.Background(), bus.Event{ID: "1", Type: "order.created", Data: map[string]interface{}{"order_id": 7}})
+	assert.ErrorIs(t, err, ErrInvalidEvent)
+
+	err = dispatcher.Dispatch(context.Background(), bus.Event{ID: "2", Type: "order.created", Data: map[string]interface{}{"order_id": "o-1"}})
+	require.NoError(t, err)
+
+	assert.Equal(t, int32(1), received.Load())
+	assert.Len(t, repo.events, 1)
+}
+
+func TestDispatcher_UseValidator(t *testing.T) {
+	eb := bus.NewEventBus(&mockLogger{})
+	defer eb.Close()
+
+	dispatcher := NewDispatcher(eb)
+	require.NoError(t, dispatcher.Dispatch(context.Background(), bus.Event{ID: "1", Type: "order.created"}))
+
+	dispatcher.UseValidator(ValidatorFunc(func(event bus.Event) error {
+		return errors.New("rejected")
+	}))
+	err := dispatcher.Dispatch(context.Background(), bus.Event{ID: "2", Type: "order.created"})
+	assert.ErrorIs(t, err, ErrInvalidEvent)
+}
+
+func TestDispatcher_DispatchUpcast(t *testing.T) {
+	eb := bus.NewEventBus(&mockLogger{})
+	defer eb.Close()
+
+	repo := &mockEventRepository{}
+	upcaster := bus.UpcasterFunc(func(event bus.Event) (bus.Event, error) {
+		if event.Version > 2 {
+			return event, errors.New("unknown version")
+		}
+		if event.Version == 1 {
+			event.Data = map[string]interface{}{"order_id": event.Data["id"]}
+		}
+		event.Version = 2
+		return event, nil
+	})
+	dispatcher := NewDispatcher(eb, WithRepository(repo), WithUpcaster(upcaster), WithValidator(ValidatorFunc(func(event bus.Event) error {
+		if _, ok := event.Data["order_id"].(string); !ok {
+			return errors.New("order_id must be a string")
+		}
+		return nil
+	})))
+
+	var received []bus.Event
+	eb.Subscribe(bus.EventType("order.created"), bus.SubscriberFunc(func(ctx context.Context, event bus.Event) error {
+		received = append(received, event)
+		return nil
+	}))
+
+	err := dispatcher.Dispatch(context.Background(), bus.Event{ID: "1", Type: "order.created", Version: 1, Data: map[string]interface{}{"id": "o-1"}})
+	require.NoError(t, err, "events are upcast before they are validated")
+
+	err = dispatcher.Dispatch(context.Background(), bus.Event{ID: "2", Type: "order.created", Version: 3})
+	assert.ErrorIs(t, err, ErrInvalidEvent)
+
+	require.Len(t, received, 1)
+	assert.Equal(t, 2, received[0].Version)
+	assert.Equal(t, map[string]interface{}{"order_id": "o-1"}, received[0].Data)
+	require.Len(t, repo.events, 1)
+	assert.Equal(t, 2, repo.events[0].Version, "the upcast event is persisted")
+}
+
 func TestDispatcher_Subscribe(t *testing.T) {
 	logger := &mockLogger{}
 	eb := bus.NewEventBus(logger)
@@ -4,24 +4,92 @@ package event
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"regexp"
+	"sort"
 	"sync"
 
 	"github.com/bargom/codeai/internal/ast"
+	"github.com/bargom/codeai/internal/event/bus"
 )
 
 // EventRegistry manages registered events and their handlers.
 type EventRegistry struct {
-	mu        sync.RWMutex
-	events    map[string]*RegisteredEvent
-	handlers  map[string][]*RegisteredHandler
+	mu         sync.RWMutex
+	events     map[string]*RegisteredEvent
+	handlers   map[string][]*RegisteredHandler
 	dispatcher Dispatcher
+
+	workflows    WorkflowStarter
+	integrations IntegrationCaller
+	webhooks     WebhookSender
+	logger       *slog.Logger
+
+	// background tracks handlers running asynchronously
+	background sync.WaitGroup
+}
+
+// WorkflowStarter starts workflows for "do workflow" handlers.
+type WorkflowStarter interface {
+	// StartWorkflow starts the named workflow with input. If wait is true it
+	// blocks until the workflow completes and returns its failure, if any.
+	StartWorkflow(ctx context.Context, name string, input map[string]any, wait bool) error
+}
+
+// IntegrationCaller calls integrations for "do integration" handlers.
+type IntegrationCaller interface {
+	// CallIntegration calls target, an integration name optionally followed
+	// by ".operation", with payload.
+	CallIntegration(ctx context.Context, target string, payload any) error
+}
+
+// WebhookSender delivers webhooks for "do webhook" handlers.
+type WebhookSender interface {
+	// SendWebhook delivers event to the named webhook. If async is true the
+	// delivery is queued rather than awaited.
+	SendWebhook(ctx context.Context, name string, event Event, async bool) error
+}
+
+// RegistryOption configures an EventRegistry.
+type RegistryOption func(*EventRegistry)
+
+// WithWorkflowStarter sets where "do workflow" handlers start workflows.
+func WithWorkflowStarter(starter WorkflowStarter) RegistryOption {
+	return func(r *EventRegistry) {
+		r.workflows = starter
+	}
+}
+
+// WithIntegrationCaller sets where "do integration" handlers call integrations.
+func WithIntegrationCaller(caller IntegrationCaller) RegistryOption {
+	return func(r *EventRegistry) {
+		r.integrations = caller
+	}
+}
+
+// WithWebhookSender sets where "do webhook" handlers deliver webhooks.
+func WithWebhookSender(sender WebhookSender) RegistryOption {
+	return func(r *EventRegistry) {
+		r.webhooks = sender
+	}
+}
+
+// WithRegistryLogger sets the logger handler failures are reported to.
+func WithRegistryLogger(logger *slog.Logger) RegistryOption {
+	return func(r *EventRegistry) {
+		r.logger = logger
+	}
 }
 
 // RegisteredEvent represents an event registered from the DSL.
 type RegisteredEvent struct {
-	Name   string
+	Name string
+	// Version is the latest declared version, which events are published as.
+	Version int
+	// Schema is the schema of the latest version.
 	Schema *EventSchema
+	// Versions holds every declared version by number.
+	Versions map[int]*EventVersion
 }
 
 // EventSchema represents the schema for an event's payload.
@@ -38,16 +106,32 @@ type RegisteredHandler struct {
 	handler    Handler
 }
 
+// Name identifies the handler by its action and target, such as
+// "webhook:shipping".
+func (rh *RegisteredHandler) Name() string {
+	return rh.ActionType + ":" + rh.Target
+}
+
+// Handle runs the handler's action for event, as the dispatcher does.
+func (rh *RegisteredHandler) Handle(ctx context.Context, event Event) error {
+	return rh.handler(ctx, event)
+}
+
 // NewEventRegistry creates a new event registry with the given dispatcher.
-func NewEventRegistry(dispatcher Dispatcher) *EventRegistry {
+func NewEventRegistry(dispatcher Dispatcher, opts ...RegistryOption) *EventRegistry {
 	if dispatcher == nil {
 		dispatcher = NewDispatcher()
 	}
-	return &EventRegistry{
+	r := &EventRegistry{
 		events:     make(map[string]*RegisteredEvent),
 		handlers:   make(map[string][]*RegisteredHandler),
 		dispatcher: dispatcher,
+		logger:     slog.Default(),
 	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
 }
 
 // RegisterEventFromAST registers an event definition from the AST.
@@ -61,13 +145,7 @@ func (r *EventRegistry) RegisterEventFromAST(event *ast.EventDecl) error {
 		return fmt.Errorf("invalid event name '%s': must follow resource.action or resource_action pattern", event.Name)
 	}
 
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	// Check for duplicate event registration
-	if _, exists := r.events[event.Name]; exists {
-		return fmt.Errorf("event '%s' is already registered", event.Name)
-	}
+	version := event.SchemaVersion()
 
 	// Convert schema
 	var schema *EventSchema
@@ -80,9 +158,43 @@ func (r *EventRegistry) RegisterEventFromAST(event *ast.EventDecl) error {
 		}
 	}
 
-	r.events[event.Name] = &RegisteredEvent{
-		Name:   event.Name,
-		Schema: schema,
+	var upcaster *Upcaster
+	if event.Upcast != nil {
+		var err error
+		if upcaster, err = NewUpcaster(event.Upcast); err != nil {
+			return fmt.Errorf("event '%s' version %d: %w", event.Name, version, err)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	registered, exists := r.events[event.Name]
+	if !exists {
+		registered = &RegisteredEvent{
+			Name:     event.Name,
+			Versions: make(map[int]*EventVersion),
+		}
+		r.events[event.Name] = registered
+	}
+
+	// Check for duplicate event registration
+	if _, exists := registered.Versions[version]; exists {
+		if version == 1 {
+			return fmt.Errorf("event '%s' is already registered", event.Name)
+		}
+		return fmt.Errorf("event '%s' version %d is already registered", event.Name, version)
+	}
+
+	registered.Versions[version] = &EventVersion{
+		Version:  version,
+		Schema:   schema,
+		Breaking: event.Breaking,
+		Upcaster: upcaster,
+	}
+	if version > registered.Version {
+		registered.Version = version
+		registered.Schema = schema
 	}
 
 	return nil
@@ -115,47 +227,114 @@ func (r *EventRegistry) SubscribeHandlerFromAST(handler *ast.EventHandlerDecl) e
 }
 
 // createHandler creates a Handler function for the registered handler.
+// Failures are logged as well as returned, since dispatchers may drop them.
 func (r *EventRegistry) createHandler(rh *RegisteredHandler) Handler {
 	return func(ctx context.Context, event Event) error {
-		// Execute based on action type
-		switch rh.ActionType {
-		case "workflow":
-			return r.executeWorkflow(ctx, rh.Target, event.Payload)
-		case "integration":
-			return r.executeIntegration(ctx, rh.Target, event.Payload)
-		case "emit":
-			return r.emitEvent(ctx, rh.Target, event.Payload)
-		case "webhook":
-			return r.executeWebhook(ctx, rh.Target, event.Payload)
-		default:
-			return fmt.Errorf("unknown action type: %s", rh.ActionType)
+		err := r.executeAction(ctx, rh, event)
+		if err != nil {
+			r.logger.Error("event handler failed",
+				"event", rh.EventName,
+				"action", rh.ActionType,
+				"target", rh.Target,
+				"error", err,
+			)
+		}
+		return err
+	}
+}
+
+// executeAction runs the handler's action. Workflows and webhooks handle
+// Async themselves: async workflows are started without waiting for them
+// to complete and async webhooks are queued. Other async actions run in
+// the background.
+func (r *EventRegistry) executeAction(ctx context.Context, rh *RegisteredHandler, event Event) error {
+	switch rh.ActionType {
+	case "workflow":
+		return r.executeWorkflow(ctx, rh.Target, event.Payload, !rh.Async)
+	case "integration":
+		if rh.Async {
+			return r.runAsync(ctx, rh, func(ctx context.Context) error {
+				return r.executeIntegration(ctx, rh.Target, event.Payload)
+			})
 		}
+		return r.executeIntegration(ctx, rh.Target, event.Payload)
+	case "emit":
+		if rh.Async {
+			return r.runAsync(ctx, rh, func(ctx context.Context) error {
+				return r.emitEvent(ctx, rh.Target, event.Payload)
+			})
+		}
+		return r.emitEvent(ctx, rh.Target, event.Payload)
+	case "webhook":
+		return r.executeWebhook(ctx, rh.Target, event, rh.Async)
+	default:
+		return fmt.Errorf("unknown action type: %s", rh.ActionType)
 	}
 }
 
-// executeWorkflow executes a workflow for an event.
-func (r *EventRegistry) executeWorkflow(ctx context.Context, workflowName string, payload any) error {
-	// TODO: Integrate with Temporal workflow engine
-	// This is a placeholder for workflow execution
+// runAsync runs fn in the background, detached from ctx cancellation, and
+// logs its failure.
+func (r *EventRegistry) runAsync(ctx context.Context, rh *RegisteredHandler, fn func(ctx context.Context) error) error {
+	ctx = context.WithoutCancel(ctx)
+	r.background.Add(1)
+	go func() {
+		defer r.background.Done()
+		if err := fn(ctx); err != nil {
+			r.logger.Error("async event handler failed",
+				"event", rh.EventName,
+				"action", rh.ActionType,
+				"target", rh.Target,
+				"error", err,
+			)
+		}
+	}()
 	return nil
 }
 
+// Wait blocks until handlers running in the background have finished.
+func (r *EventRegistry) Wait() {
+	r.background.Wait()
+}
+
+// executeWorkflow starts a workflow for an event.
+func (r *EventRegistry) executeWorkflow(ctx context.Context, workflowName string, payload any, wait bool) error {
+	if r.workflows == nil {
+		return fmt.Errorf("no workflow engine configured to run workflow '%s'", workflowName)
+	}
+	return r.workflows.StartWorkflow(ctx, workflowName, payloadMap(payload), wait)
+}
+
 // executeIntegration calls an external integration.
 func (r *EventRegistry) executeIntegration(ctx context.Context, integrationName string, payload any) error {
-	// TODO: Integrate with integration registry
-	return nil
+	if r.integrations == nil {
+		return fmt.Errorf("no integrations configured to call '%s'", integrationName)
+	}
+	return r.integrations.CallIntegration(ctx, integrationName, payload)
 }
 
 // emitEvent emits another event.
 func (r *EventRegistry) emitEvent(ctx context.Context, eventName string, payload any) error {
-	event := NewEvent(EventType(eventName), payload)
-	return r.dispatcher.Dispatch(ctx, event)
+	return r.publish(ctx, eventName, payloadMap(payload))
 }
 
-// executeWebhook calls a webhook.
-func (r *EventRegistry) executeWebhook(ctx context.Context, webhookName string, payload any) error {
-	// TODO: Integrate with webhook dispatcher
-	return nil
+// executeWebhook delivers an event to a webhook.
+func (r *EventRegistry) executeWebhook(ctx context.Context, webhookName string, event Event, async bool) error {
+	if r.webhooks == nil {
+		return fmt.Errorf("no webhook service configured to deliver '%s'", webhookName)
+	}
+	return r.webhooks.SendWebhook(ctx, webhookName, event, async)
+}
+
+// payloadMap returns payload as a map, wrapping other values under "payload".
+func payloadMap(payload any) map[string]any {
+	switch p := payload.(type) {
+	case map[string]any:
+		return p
+	case nil:
+		return map[string]any{}
+	default:
+		return map[string]any{"payload": p}
+	}
 }
 
 // EmitEvent emits an event with the given name and payload.
@@ -167,18 +346,61 @@ func (r *EventRegistry) EmitEvent(ctx context.Context, name string, payload map[
 	if !exists {
 		return fmt.Errorf("event '%s' is not registered", name)
 	}
+	return r.publish(ctx, registeredEvent.Name, payload)
+}
 
-	// Validate payload against schema if defined
-	if registeredEvent.Schema != nil {
-		if err := validatePayload(payload, registeredEvent.Schema); err != nil {
-			return fmt.Errorf("payload validation failed: %w", err)
-		}
+// publish validates payload against the schema of the named event, if it
+// is registered with one, and dispatches the event.
+func (r *EventRegistry) publish(ctx context.Context, name string, payload map[string]interface{}) error {
+	if err := r.validate(name, 0, payload); err != nil {
+		return err
 	}
-
 	event := NewEvent(EventType(name), payload)
+	event.Version = r.latestVersion(name)
 	return r.dispatcher.Dispatch(ctx, event)
 }
 
+// latestVersion returns the version events of the named event are
+// published as, or zero if it is not registered.
+func (r *EventRegistry) latestVersion(name string) int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if registeredEvent, exists := r.events[name]; exists {
+		return registeredEvent.Version
+	}
+	return 0
+}
+
+// Validate checks the data of event against the schema of its version of
+// its registered event, or of the latest version if it has none, so that
+// the registry can validate every event published on the bus as a
+// dispatcher.Validator. Events that are not registered, such as system
+// events, are not checked.
+func (r *EventRegistry) Validate(event bus.Event) error {
+	return r.validate(string(event.Type), event.Version, event.Data)
+}
+
+func (r *EventRegistry) validate(name string, version int, payload map[string]interface{}) error {
+	r.mu.RLock()
+	registeredEvent, exists := r.events[name]
+	r.mu.RUnlock()
+
+	if !exists {
+		return nil
+	}
+	schema, err := registeredEvent.schemaFor(version)
+	if err != nil {
+		return err
+	}
+	if schema == nil {
+		return nil
+	}
+	if err := validatePayload(payload, schema); err != nil {
+		return fmt.Errorf("payload validation failed: %w", err)
+	}
+	return nil
+}
+
 // GetEvent returns a registered event by name.
 func (r *EventRegistry) GetEvent(name string) (*RegisteredEvent, bool) {
 	r.mu.RLock()
@@ -194,6 +416,25 @@ func (r *EventRegistry) GetHandlers(eventName string) []*RegisteredHandler {
 	return r.handlers[eventName]
 }
 
+// AllHandlers returns the handlers of all events, ordered by event name
+// and then by declaration.
+func (r *EventRegistry) AllHandlers() []*RegisteredHandler {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.handlers))
+	for name := range r.handlers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var handlers []*RegisteredHandler
+	for _, name := range names {
+		handlers = append(handlers, r.handlers[name]...)
+	}
+	return handlers
+}
+
 // EventCount returns the number of registered events.
 func (r *EventRegistry) EventCount() int {
 	r.mu.RLock()
@@ -0,0 +1,172 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bargom/codeai/internal/ast"
+)
+
+type recordedCall struct {
+	target string
+	input  any
+	flag   bool
+}
+
+type fakeActions struct {
+	mu    sync.Mutex
+	calls []recordedCall
+	err   error
+}
+
+func (f *fakeActions) record(call recordedCall) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, call)
+	return f.err
+}
+
+func (f *fakeActions) recorded() []recordedCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]recordedCall(nil), f.calls...)
+}
+
+func (f *fakeActions) StartWorkflow(_ context.Context, name string, input map[string]any, wait bool) error {
+	return f.record(recordedCall{target: name, input: input, flag: wait})
+}
+
+func (f *fakeActions) CallIntegration(_ context.Context, target string, payload any) error {
+	return f.record(recordedCall{target: target, input: payload})
+}
+
+func (f *fakeActions) SendWebhook(_ context.Context, name string, event Event, async bool) error {
+	return f.record(recordedCall{target: name, input: event.Payload, flag: async})
+}
+
+func newActionRegistry(t *testing.T, actions *fakeActions, handlers ...*ast.EventHandlerDecl) *EventRegistry {
+	t.Helper()
+	r := NewEventRegistry(nil,
+		WithWorkflowStarter(actions),
+		WithIntegrationCaller(actions),
+		WithWebhookSender(actions),
+	)
+	require.NoError(t, r.RegisterEventFromAST(&ast.EventDecl{Name: "order.created"}))
+	for _, h := range handlers {
+		require.NoError(t, r.SubscribeHandlerFromAST(h))
+	}
+	return r
+}
+
+func TestEventRegistry_WorkflowAction(t *testing.T) {
+	actions := &fakeActions{}
+	r := newActionRegistry(t, actions,
+		&ast.EventHandlerDecl{EventName: "order.created", ActionType: "workflow", Target: "fulfil"},
+		&ast.EventHandlerDecl{EventName: "order.created", ActionType: "workflow", Target: "notify", Async: true},
+	)
+
+	payload := map[string]interface{}{"id": "o-1"}
+	require.NoError(t, r.EmitEvent(context.Background(), "order.created", payload))
+
+	calls := actions.recorded()
+	require.Len(t, calls, 2)
+	assert.Equal(t, recordedCall{target: "fulfil", input: payload, flag: true}, calls[0], "sync workflows are awaited")
+	assert.Equal(t, recordedCall{target: "notify", input: payload, flag: false}, calls[1], "async workflows are only started")
+}
+
+func TestEventRegistry_WebhookAction(t *testing.T) {
+	actions := &fakeActions{}
+	r := newActionRegistry(t, actions,
+		&ast.EventHandlerDecl{EventName: "order.created", ActionType: "webhook", Target: "shipping"},
+		&ast.EventHandlerDecl{EventName: "order.created", ActionType: "webhook", Target: "analytics", Async: true},
+	)
+
+	require.NoError(t, r.EmitEvent(context.Background(), "order.created", map[string]interface{}{"id": "o-1"}))
+
+	calls := actions.recorded()
+	require.Len(t, calls, 2)
+	assert.Equal(t, "shipping", calls[0].target)
+	assert.False(t, calls[0].flag)
+	assert.Equal(t, "analytics", calls[1].target)
+	assert.True(t, calls[1].flag)
+}
+
+func TestEventRegistry_IntegrationAction(t *testing.T) {
+	actions := &fakeActions{}
+	r := newActionRegistry(t, actions,
+		&ast.EventHandlerDecl{EventName: "order.created", ActionType: "integration", Target: "crm.update_contact"},
+		&ast.EventHandlerDecl{EventName: "order.created", ActionType: "integration", Target: "analytics.track", Async: true},
+	)
+
+	payload := map[string]interface{}{"id": "o-1"}
+	require.NoError(t, r.EmitEvent(context.Background(), "order.created", payload))
+	r.Wait()
+
+	calls := actions.recorded()
+	require.Len(t, calls, 2)
+	targets := []string{calls[0].target, calls[1].target}
+	assert.ElementsMatch(t, []string{"crm.update_contact", "analytics.track"}, targets)
+	assert.Equal(t, payload, calls[0].input)
+}
+
+func TestEventRegistry_AllHandlers(t *testing.T) {
+	actions := &fakeActions{}
+	r := newActionRegistry(t, actions,
+		&ast.EventHandlerDecl{EventName: "order.shipped", ActionType: "webhook", Target: "tracking"},
+		&ast.EventHandlerDecl{EventName: "order.created", ActionType: "workflow", Target: "fulfil"},
+		&ast.EventHandlerDecl{EventName: "order.created", ActionType: "webhook", Target: "shipping"},
+	)
+
+	handlers := r.AllHandlers()
+	require.Len(t, handlers, 3)
+	assert.Equal(t, "workflow:fulfil", handlers[0].Name())
+	assert.Equal(t, "webhook:shipping", handlers[1].Name())
+	assert.Equal(t, "webhook:tracking", handlers[2].Name())
+
+	payload := map[string]interface{}{"id": "o-1"}
+	require.NoError(t, handlers[1].Handle(context.Background(), NewEvent("order.created", payload)))
+	calls := actions.recorded()
+	require.Len(t, calls, 1, "Handle runs only the handler")
+	assert.Equal(t, "shipping", calls[0].target)
+}
+
+func TestEventRegistry_ActionErrors(t *testing.T) {
+	t.Run("handler errors are returned", func(t *testing.T) {
+		actions := &fakeActions{err: errors.New("boom")}
+		r := newActionRegistry(t, actions)
+		h := r.createHandler(&RegisteredHandler{EventName: "order.created", ActionType: "workflow", Target: "fulfil"})
+
+		err := h(context.Background(), NewEvent("order.created", nil))
+		assert.EqualError(t, err, "boom")
+	})
+
+	t.Run("async handler errors are not", func(t *testing.T) {
+		actions := &fakeActions{err: errors.New("boom")}
+		r := newActionRegistry(t, actions)
+		h := r.createHandler(&RegisteredHandler{EventName: "order.created", ActionType: "integration", Target: "crm", Async: true})
+
+		assert.NoError(t, h(context.Background(), NewEvent("order.created", nil)))
+		r.Wait()
+		assert.Len(t, actions.recorded(), 1)
+	})
+
+	t.Run("unconfigured actions fail", func(t *testing.T) {
+		r := NewEventRegistry(nil)
+		for _, action := range []string{"workflow", "integration", "webhook"} {
+			h := r.createHandler(&RegisteredHandler{EventName: "order.created", ActionType: action, Target: "x"})
+			assert.Error(t, h(context.Background(), NewEvent("order.created", nil)), action)
+		}
+	})
+}
+
+func TestPayloadMap(t *testing.T) {
+	m := map[string]any{"id": 1}
+	assert.Equal(t, m, payloadMap(m))
+	assert.Equal(t, map[string]any{}, payloadMap(nil))
+	assert.Equal(t, map[string]any{"payload": "text"}, payloadMap("text"))
+}
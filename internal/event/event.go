@@ -5,6 +5,8 @@ import (
 	"context"
 	"sync"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 // EventType represents the type of an event.
@@ -23,14 +25,19 @@ const (
 
 // Event represents an application event.
 type Event struct {
+	ID        string
 	Type      EventType
+	Source    string
+	Version   int
 	Payload   any
 	Timestamp time.Time
+	Metadata  map[string]string
 }
 
-// NewEvent creates a new event with the current timestamp.
+// NewEvent creates a new event with a new ID and the current timestamp.
 func NewEvent(eventType EventType, payload any) Event {
 	return Event{
+		ID:        uuid.New().String(),
 		Type:      eventType,
 		Payload:   payload,
 		Timestamp: time.Now(),
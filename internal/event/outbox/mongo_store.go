@@ -0,0 +1,152 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/bargom/codeai/internal/event/bus"
+)
+
+const outboxCollection = "event_outbox"
+
+// MongoStore implements Store on MongoDB. Append joins the transaction of
+// its context when called with the session context of a transaction.
+type MongoStore struct {
+	messages *mongo.Collection
+}
+
+// NewMongoStore creates a store on the given MongoDB database.
+func NewMongoStore(db *mongo.Database) *MongoStore {
+	return &MongoStore{messages: db.Collection(outboxCollection)}
+}
+
+// mongoMessage is the document representation of a Message.
+type mongoMessage struct {
+	ID          string            `bson:"_id"`
+	Type        string            `bson:"type"`
+	Source      string            `bson:"source"`
+	Version     int               `bson:"version,omitempty"`
+	OccurredAt  time.Time         `bson:"occurredAt"`
+	Data        bson.M            `bson:"data"`
+	Metadata    map[string]string `bson:"metadata"`
+	Attempts    int               `bson:"attempts"`
+	LastError   string            `bson:"lastError"`
+	CreatedAt   time.Time         `bson:"createdAt"`
+	AvailableAt time.Time         `bson:"availableAt"`
+	PublishedAt *time.Time        `bson:"publishedAt"`
+}
+
+func (d *mongoMessage) message() *Message {
+	return &Message{
+		Event: bus.Event{
+			ID:        d.ID,
+			Type:      bus.EventType(d.Type),
+			Source:    d.Source,
+			Version:   d.Version,
+			Timestamp: d.OccurredAt,
+			Data:      d.Data,
+			Metadata:  d.Metadata,
+		},
+		Attempts:    d.Attempts,
+		LastError:   d.LastError,
+		CreatedAt:   d.CreatedAt,
+		AvailableAt: d.AvailableAt,
+		PublishedAt: d.PublishedAt,
+	}
+}
+
+// EnsureIndexes creates the index relays claim messages by.
+func (s *MongoStore) EnsureIndexes(ctx context.Context) error {
+	_, err := s.messages.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "publishedAt", Value: 1}, {Key: "availableAt", Value: 1}},
+	})
+	if err != nil {
+		return fmt.Errorf("creating outbox indexes: %w", err)
+	}
+	return nil
+}
+
+// Append implements Store.
+func (s *MongoStore) Append(ctx context.Context, msg *Message) error {
+	doc := mongoMessage{
+		ID:          msg.ID(),
+		Type:        string(msg.Event.Type),
+		Source:      msg.Event.Source,
+		Version:     msg.Event.Version,
+		OccurredAt:  msg.Event.Timestamp.UTC(),
+		Data:        msg.Event.Data,
+		Metadata:    msg.Event.Metadata,
+		Attempts:    msg.Attempts,
+		LastError:   msg.LastError,
+		CreatedAt:   msg.CreatedAt.UTC(),
+		AvailableAt: msg.AvailableAt.UTC(),
+	}
+	if _, err := s.messages.InsertOne(ctx, doc); err != nil && !mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("inserting outbox message: %w", err)
+	}
+	return nil
+}
+
+// Claim implements Store. Each message is leased with an atomic
+// find-and-modify, so concurrent relays never claim the same message.
+func (s *MongoStore) Claim(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]*Message, error) {
+	now = now.UTC()
+	filter := bson.M{"publishedAt": nil, "availableAt": bson.M{"$lte": now}}
+	update := bson.M{
+		"$set": bson.M{"availableAt": now.Add(lease)},
+		"$inc": bson.M{"attempts": 1},
+	}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "createdAt", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var messages []*Message
+	for len(messages) < limit {
+		var doc mongoMessage
+		err := s.messages.FindOneAndUpdate(ctx, filter, update, opts).Decode(&doc)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("claiming outbox message: %w", err)
+		}
+		messages = append(messages, doc.message())
+	}
+	return messages, nil
+}
+
+// MarkPublished implements Store.
+func (s *MongoStore) MarkPublished(ctx context.Context, id string, at time.Time) error {
+	return s.update(ctx, id, bson.M{"publishedAt": at.UTC()})
+}
+
+// Release implements Store.
+func (s *MongoStore) Release(ctx context.Context, id string, at time.Time, lastErr string) error {
+	return s.update(ctx, id, bson.M{"availableAt": at.UTC(), "lastError": lastErr})
+}
+
+// DeletePublished implements Store.
+func (s *MongoStore) DeletePublished(ctx context.Context, before time.Time) (int64, error) {
+	result, err := s.messages.DeleteMany(ctx, bson.M{"publishedAt": bson.M{"$ne": nil, "$lt": before.UTC()}})
+	if err != nil {
+		return 0, fmt.Errorf("deleting published outbox messages: %w", err)
+	}
+	return result.DeletedCount, nil
+}
+
+func (s *MongoStore) update(ctx context.Context, id string, set bson.M) error {
+	result, err := s.messages.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": set})
+	if err != nil {
+		return fmt.Errorf("updating outbox message: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return ErrMessageNotFound
+	}
+	return nil
+}
@@ -0,0 +1,317 @@
+package outbox
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/bargom/codeai/internal/event/bus"
+)
+
+// Logger defines the logging interface for the relay.
+type Logger interface {
+	Info(msg string, args ...any)
+	Error(msg string, args ...any)
+	Debug(msg string, args ...any)
+	Warn(msg string, args ...any)
+}
+
+// Publisher publishes outbox events, typically to the event bus.
+type Publisher interface {
+	Publish(ctx context.Context, event bus.Event) error
+}
+
+// PublisherFunc is a function type that implements the Publisher interface.
+type PublisherFunc func(ctx context.Context, event bus.Event) error
+
+// Publish calls the function.
+func (f PublisherFunc) Publish(ctx context.Context, event bus.Event) error {
+	return f(ctx, event)
+}
+
+// Config holds configuration for the relay.
+type Config struct {
+	PollInterval  time.Duration // How often to check the outbox
+	BatchSize     int           // Max number of messages claimed at once
+	Lease         time.Duration // How long a claimed message is reserved for the relay
+	RetryBackoff  time.Duration // Delay before the first retry, doubled on each attempt
+	MaxBackoff    time.Duration // Maximum delay between retries
+	Retention     time.Duration // How long published messages are kept; zero keeps them forever
+	PurgeInterval time.Duration // How often published messages older than Retention are deleted
+}
+
+// DefaultConfig returns a default relay configuration.
+func DefaultConfig() Config {
+	return Config{
+		PollInterval:  1 * time.Second,
+		BatchSize:     100,
+		Lease:         30 * time.Second,
+		RetryBackoff:  1 * time.Second,
+		MaxBackoff:    5 * time.Minute,
+		Retention:     7 * 24 * time.Hour,
+		PurgeInterval: 1 * time.Hour,
+	}
+}
+
+// Relay publishes outbox messages. A message is marked published only after
+// it was published successfully; failed messages are retried with
+// exponential backoff, so every message is published at least once.
+type Relay struct {
+	store     Store
+	publisher Publisher
+	config    Config
+	logger    Logger
+	now       func() time.Time
+	wake      chan struct{}
+	stop      chan struct{}
+	ctx       context.Context
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+	running   bool
+	mu        sync.Mutex
+}
+
+// Option configures the Relay.
+type Option func(*Relay)
+
+// WithLogger sets the logger for the relay.
+func WithLogger(logger Logger) Option {
+	return func(r *Relay) {
+		r.logger = logger
+	}
+}
+
+// WithConfig sets the configuration for the relay.
+func WithConfig(cfg Config) Option {
+	return func(r *Relay) {
+		r.config = cfg
+	}
+}
+
+// WithClock sets the function the relay gets the current time from.
+func WithClock(now func() time.Time) Option {
+	return func(r *Relay) {
+		r.now = now
+	}
+}
+
+// NewRelay creates a relay publishing the messages of store.
+func NewRelay(store Store, publisher Publisher, opts ...Option) *Relay {
+	r := &Relay{
+		store:     store,
+		publisher: publisher,
+		config:    DefaultConfig(),
+		now:       time.Now,
+		wake:      make(chan struct{}, 1),
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// Start begins relaying messages in the background.
+func (r *Relay) Start(ctx context.Context) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.running {
+		return
+	}
+
+	r.ctx, r.cancel = context.WithCancel(ctx)
+	r.stop = make(chan struct{})
+	r.running = true
+
+	r.wg.Add(1)
+	go r.run(r.ctx, r.stop)
+
+	if r.logger != nil {
+		r.logger.Info("outbox relay started",
+			"pollInterval", r.config.PollInterval,
+			"batchSize", r.config.BatchSize,
+		)
+	}
+}
+
+// Notify wakes the relay up to publish new messages without waiting for
+// the next poll. It never blocks.
+func (r *Relay) Notify() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Shutdown signals the relay to stop. The relay publishes the messages
+// available at that point before it stops.
+func (r *Relay) Shutdown() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.running {
+		return
+	}
+	r.running = false
+	close(r.stop)
+}
+
+// WaitForCompletion waits for the relay to stop, or for timeout, after
+// which in-flight publishes are canceled.
+func (r *Relay) WaitForCompletion(timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		r.mu.Lock()
+		if r.cancel != nil {
+			r.cancel()
+		}
+		r.mu.Unlock()
+		<-done
+	}
+}
+
+// Stop gracefully shuts down the relay.
+func (r *Relay) Stop() {
+	r.Shutdown()
+	r.wg.Wait()
+}
+
+// run is the main loop that polls the outbox.
+func (r *Relay) run(ctx context.Context, stop <-chan struct{}) {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.config.PollInterval)
+	defer ticker.Stop()
+
+	// Without a retention, published messages are never purged
+	var purge <-chan time.Time
+	if r.config.Retention > 0 && r.config.PurgeInterval > 0 {
+		purgeTicker := time.NewTicker(r.config.PurgeInterval)
+		defer purgeTicker.Stop()
+		purge = purgeTicker.C
+	}
+
+	r.drain(ctx)
+	r.purge(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-stop:
+			r.drain(ctx)
+			if r.logger != nil {
+				r.logger.Info("outbox relay stopped")
+			}
+			return
+		case <-ticker.C:
+			r.drain(ctx)
+		case <-r.wake:
+			r.drain(ctx)
+		case <-purge:
+			r.purge(ctx)
+		}
+	}
+}
+
+// purge deletes the published messages older than the retention.
+func (r *Relay) purge(ctx context.Context) {
+	if _, err := r.Purge(ctx); err != nil && r.logger != nil {
+		r.logger.Error("failed to purge outbox messages", "error", err)
+	}
+}
+
+// Purge deletes the messages published longer than Config.Retention ago and
+// returns how many were deleted. It deletes nothing without a retention.
+func (r *Relay) Purge(ctx context.Context) (int64, error) {
+	if r.config.Retention <= 0 {
+		return 0, nil
+	}
+	deleted, err := r.store.DeletePublished(ctx, r.now().Add(-r.config.Retention))
+	if err != nil {
+		return 0, err
+	}
+	if deleted > 0 && r.logger != nil {
+		r.logger.Debug("purged published outbox messages", "count", deleted)
+	}
+	return deleted, nil
+}
+
+// drain relays batches until no full batch is available.
+func (r *Relay) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		claimed, _, err := r.relay(ctx)
+		if err != nil {
+			if r.logger != nil {
+				r.logger.Error("failed to claim outbox messages", "error", err)
+			}
+			return
+		}
+		if claimed < r.config.BatchSize {
+			return
+		}
+	}
+}
+
+// RelayOnce claims one batch of available messages and publishes them. It
+// returns the number of messages published.
+func (r *Relay) RelayOnce(ctx context.Context) (int, error) {
+	_, published, err := r.relay(ctx)
+	return published, err
+}
+
+func (r *Relay) relay(ctx context.Context) (claimed, published int, err error) {
+	messages, err := r.store.Claim(ctx, r.now(), r.config.BatchSize, r.config.Lease)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for _, msg := range messages {
+		if err := r.publisher.Publish(ctx, msg.Event); err != nil {
+			retryAt := r.now().Add(r.backoff(msg.Attempts))
+			if r.logger != nil {
+				r.logger.Warn("failed to publish outbox message",
+					"id", msg.ID(),
+					"type", msg.Event.Type,
+					"attempt", msg.Attempts,
+					"retryAt", retryAt,
+					"error", err,
+				)
+			}
+			if err := r.store.Release(ctx, msg.ID(), retryAt, err.Error()); err != nil && r.logger != nil {
+				r.logger.Error("failed to release outbox message", "id", msg.ID(), "error", err)
+			}
+			continue
+		}
+
+		// A message that cannot be marked is published again once its
+		// lease expires, which at-least-once delivery allows.
+		if err := r.store.MarkPublished(ctx, msg.ID(), r.now()); err != nil && r.logger != nil {
+			r.logger.Error("failed to mark outbox message published", "id", msg.ID(), "error", err)
+		}
+		published++
+	}
+	return len(messages), published, nil
+}
+
+// backoff returns the delay before retrying a message after the given
+// number of attempts.
+func (r *Relay) backoff(attempts int) time.Duration {
+	delay := r.config.RetryBackoff
+	for i := 1; i < attempts && delay < r.config.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > r.config.MaxBackoff {
+		delay = r.config.MaxBackoff
+	}
+	return delay
+}
@@ -0,0 +1,186 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bargom/codeai/internal/event/bus"
+)
+
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// recordingPublisher records published events and fails while failing is set.
+type recordingPublisher struct {
+	mu        sync.Mutex
+	failing   bool
+	published []bus.Event
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, event bus.Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.failing {
+		return errors.New("bus unavailable")
+	}
+	p.published = append(p.published, event)
+	return nil
+}
+
+func (p *recordingPublisher) setFailing(failing bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.failing = failing
+}
+
+func (p *recordingPublisher) events() []bus.Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]bus.Event(nil), p.published...)
+}
+
+func TestRelay_RetriesFailedPublishes(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)}
+	store := NewMemoryStore()
+	publisher := &recordingPublisher{failing: true}
+	relay := NewRelay(store, publisher, WithClock(clock.Now))
+	ctx := context.Background()
+
+	require.NoError(t, store.Append(ctx, NewMessage(testEvent("e1"), clock.Now())))
+
+	published, err := relay.RelayOnce(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, published)
+	msg, _ := store.Get("e1")
+	assert.Nil(t, msg.PublishedAt)
+	assert.Equal(t, "bus unavailable", msg.LastError)
+	assert.Equal(t, clock.Now().Add(time.Second), msg.AvailableAt)
+
+	clock.Advance(time.Second)
+	_, err = relay.RelayOnce(ctx)
+	require.NoError(t, err)
+	msg, _ = store.Get("e1")
+	assert.Equal(t, clock.Now().Add(2*time.Second), msg.AvailableAt, "backoff doubles")
+
+	publisher.setFailing(false)
+	published, err = relay.RelayOnce(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, published, "not retried before its backoff")
+
+	clock.Advance(2 * time.Second)
+	published, err = relay.RelayOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, published)
+	msg, _ = store.Get("e1")
+	assert.NotNil(t, msg.PublishedAt)
+	require.Len(t, publisher.events(), 1)
+	assert.Equal(t, "e1", publisher.events()[0].ID)
+
+	published, err = relay.RelayOnce(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, published, "published messages are not published again")
+}
+
+func TestRelay_Backoff(t *testing.T) {
+	relay := NewRelay(NewMemoryStore(), &recordingPublisher{}, WithConfig(Config{
+		RetryBackoff: time.Second,
+		MaxBackoff:   10 * time.Second,
+	}))
+	assert.Equal(t, time.Second, relay.backoff(1))
+	assert.Equal(t, 4*time.Second, relay.backoff(3))
+	assert.Equal(t, 10*time.Second, relay.backoff(5))
+	assert.Equal(t, 10*time.Second, relay.backoff(100))
+}
+
+func TestRelay_StartAndShutdown(t *testing.T) {
+	store := NewMemoryStore()
+	publisher := &recordingPublisher{}
+	cfg := DefaultConfig()
+	cfg.PollInterval = time.Hour
+	relay := NewRelay(store, publisher, WithConfig(cfg))
+	ctx := context.Background()
+
+	relay.Start(ctx)
+	require.NoError(t, store.Append(ctx, NewMessage(testEvent("e1"), time.Now())))
+	relay.Notify()
+	assert.Eventually(t, func() bool { return len(publisher.events()) == 1 },
+		time.Second, 10*time.Millisecond, "notify wakes the relay")
+
+	require.NoError(t, store.Append(ctx, NewMessage(testEvent("e2"), time.Now())))
+	relay.Shutdown()
+	relay.WaitForCompletion(time.Second)
+
+	events := publisher.events()
+	require.Len(t, events, 2, "shutdown publishes the remaining messages")
+	assert.Equal(t, "e2", events[1].ID)
+}
+
+func TestRelay_PurgesPublishedMessages(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)}
+	store := NewMemoryStore()
+	cfg := DefaultConfig()
+	cfg.PollInterval = time.Hour
+	cfg.Retention = 24 * time.Hour
+	relay := NewRelay(store, &recordingPublisher{}, WithConfig(cfg), WithClock(clock.Now))
+	ctx := context.Background()
+
+	require.NoError(t, store.Append(ctx, NewMessage(testEvent("e1"), clock.Now())))
+	_, err := relay.RelayOnce(ctx)
+	require.NoError(t, err)
+	require.NoError(t, store.Append(ctx, NewMessage(testEvent("e2"), clock.Now())))
+
+	clock.Advance(23 * time.Hour)
+	deleted, err := relay.Purge(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, deleted, "messages are kept for the retention")
+
+	// The relay purges when it starts, then every PurgeInterval
+	clock.Advance(2 * time.Hour)
+	relay.Start(ctx)
+	defer relay.Stop()
+	assert.Eventually(t, func() bool {
+		_, ok := store.Get("e1")
+		return !ok
+	}, time.Second, 10*time.Millisecond, "published messages are purged after the retention")
+
+	_, ok := store.Get("e2")
+	assert.True(t, ok, "unpublished messages are never purged")
+}
+
+func TestRelay_PurgeWithoutRetention(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)}
+	store := NewMemoryStore()
+	relay := NewRelay(store, &recordingPublisher{}, WithConfig(Config{BatchSize: 10}), WithClock(clock.Now))
+	ctx := context.Background()
+
+	require.NoError(t, store.Append(ctx, NewMessage(testEvent("e1"), clock.Now())))
+	_, err := relay.RelayOnce(ctx)
+	require.NoError(t, err)
+
+	clock.Advance(365 * 24 * time.Hour)
+	deleted, err := relay.Purge(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, deleted)
+	_, ok := store.Get("e1")
+	assert.True(t, ok)
+}
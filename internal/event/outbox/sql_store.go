@@ -0,0 +1,205 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bargom/codeai/internal/database"
+	"github.com/bargom/codeai/internal/event/bus"
+	"github.com/bargom/codeai/internal/query"
+)
+
+// SQLStore implements Store on PostgreSQL. Append runs in the transaction
+// carried by its context (see database.WithTx), so messages commit or roll
+// back with the data change they describe.
+type SQLStore struct {
+	db *sql.DB
+}
+
+// NewSQLStore creates a store on the given PostgreSQL database.
+func NewSQLStore(db *sql.DB) *SQLStore {
+	return &SQLStore{db: db}
+}
+
+// CreateTable creates the event_outbox table if it doesn't exist.
+func (s *SQLStore) CreateTable(ctx context.Context) error {
+	queries := []string{`
+		CREATE TABLE IF NOT EXISTS event_outbox (
+			id TEXT PRIMARY KEY,
+			type TEXT NOT NULL,
+			source TEXT NOT NULL,
+			version INTEGER NOT NULL DEFAULT 0,
+			occurred_at TIMESTAMP NOT NULL,
+			data TEXT NOT NULL,
+			metadata TEXT NOT NULL,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			last_error TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP NOT NULL,
+			available_at TIMESTAMP NOT NULL,
+			published_at TIMESTAMP
+		)`, `
+		CREATE INDEX IF NOT EXISTS event_outbox_available_at_idx
+			ON event_outbox (available_at) WHERE published_at IS NULL`, `
+		CREATE INDEX IF NOT EXISTS event_outbox_published_at_idx
+			ON event_outbox (published_at) WHERE published_at IS NOT NULL`,
+	}
+	for _, query := range queries {
+		if _, err := s.db.ExecContext(ctx, query); err != nil {
+			return fmt.Errorf("creating event outbox table: %w", err)
+		}
+	}
+	return nil
+}
+
+// conn returns the transaction carried by ctx, or the database.
+func (s *SQLStore) conn(ctx context.Context) query.DB {
+	if tx := database.TxFromContext(ctx); tx != nil {
+		return tx
+	}
+	return s.db
+}
+
+// Append implements Store.
+func (s *SQLStore) Append(ctx context.Context, msg *Message) error {
+	dataJSON, err := json.Marshal(msg.Event.Data)
+	if err != nil {
+		return fmt.Errorf("marshaling event data: %w", err)
+	}
+	metadataJSON, err := json.Marshal(msg.Event.Metadata)
+	if err != nil {
+		return fmt.Errorf("marshaling event metadata: %w", err)
+	}
+
+	query := `
+		INSERT INTO event_outbox (id, type, source, version, occurred_at, data, metadata,
+			attempts, last_error, created_at, available_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (id) DO NOTHING
+	`
+	_, err = s.conn(ctx).ExecContext(ctx, query,
+		msg.ID(), string(msg.Event.Type), msg.Event.Source, msg.Event.Version, msg.Event.Timestamp.UTC(),
+		string(dataJSON), string(metadataJSON),
+		msg.Attempts, msg.LastError, msg.CreatedAt.UTC(), msg.AvailableAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("inserting outbox message: %w", err)
+	}
+	return nil
+}
+
+// Claim implements Store. Candidates are leased one by one with a
+// conditional update, so concurrent relays never claim the same message.
+func (s *SQLStore) Claim(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]*Message, error) {
+	now = now.UTC()
+	ids, err := s.dueIDs(ctx, now, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		UPDATE event_outbox
+		SET available_at = $1, attempts = attempts + 1
+		WHERE id = $2 AND published_at IS NULL AND available_at <= $3
+		RETURNING id, type, source, version, occurred_at, data, metadata,
+			attempts, last_error, created_at, available_at
+	`
+	var messages []*Message
+	for _, id := range ids {
+		msg, err := scanMessage(s.db.QueryRowContext(ctx, query, now.Add(lease), id, now))
+		if errors.Is(err, sql.ErrNoRows) {
+			continue // Claimed by another relay
+		}
+		if err != nil {
+			return nil, fmt.Errorf("claiming outbox message: %w", err)
+		}
+		messages = append(messages, msg)
+	}
+	return messages, nil
+}
+
+// dueIDs returns the IDs of up to limit unpublished messages available at now.
+func (s *SQLStore) dueIDs(ctx context.Context, now time.Time, limit int) ([]string, error) {
+	query := `
+		SELECT id FROM event_outbox
+		WHERE published_at IS NULL AND available_at <= $1
+		ORDER BY created_at
+		LIMIT $2
+	`
+	rows, err := s.db.QueryContext(ctx, query, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("querying outbox messages: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scanning outbox message: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func scanMessage(row *sql.Row) (*Message, error) {
+	var (
+		msg       Message
+		eventType string
+		data      string
+		metadata  string
+	)
+	err := row.Scan(&msg.Event.ID, &eventType, &msg.Event.Source, &msg.Event.Version, &msg.Event.Timestamp,
+		&data, &metadata, &msg.Attempts, &msg.LastError, &msg.CreatedAt, &msg.AvailableAt)
+	if err != nil {
+		return nil, err
+	}
+	msg.Event.Type = bus.EventType(eventType)
+	if err := json.Unmarshal([]byte(data), &msg.Event.Data); err != nil {
+		return nil, fmt.Errorf("unmarshaling event data: %w", err)
+	}
+	if err := json.Unmarshal([]byte(metadata), &msg.Event.Metadata); err != nil {
+		return nil, fmt.Errorf("unmarshaling event metadata: %w", err)
+	}
+	return &msg, nil
+}
+
+// MarkPublished implements Store.
+func (s *SQLStore) MarkPublished(ctx context.Context, id string, at time.Time) error {
+	return s.update(ctx, "UPDATE event_outbox SET published_at = $1 WHERE id = $2", at.UTC(), id)
+}
+
+// Release implements Store.
+func (s *SQLStore) Release(ctx context.Context, id string, at time.Time, lastErr string) error {
+	return s.update(ctx, "UPDATE event_outbox SET available_at = $1, last_error = $2 WHERE id = $3",
+		at.UTC(), lastErr, id)
+}
+
+// DeletePublished implements Store.
+func (s *SQLStore) DeletePublished(ctx context.Context, before time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx,
+		"DELETE FROM event_outbox WHERE published_at IS NOT NULL AND published_at < $1", before.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("deleting published outbox messages: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+func (s *SQLStore) update(ctx context.Context, query string, args ...any) error {
+	result, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("updating outbox message: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("updating outbox message: %w", err)
+	}
+	if n == 0 {
+		return ErrMessageNotFound
+	}
+	return nil
+}
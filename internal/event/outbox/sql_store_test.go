@@ -0,0 +1,130 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+
+	"github.com/bargom/codeai/internal/database"
+	"github.com/bargom/codeai/internal/event/bus"
+)
+
+func newSQLStore(t *testing.T) (*SQLStore, *sql.DB) {
+	t.Helper()
+	db, err := sql.Open("sqlite", ":memory:")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	store := NewSQLStore(db)
+	require.NoError(t, store.CreateTable(context.Background()))
+	return store, db
+}
+
+func testEvent(id string) bus.Event {
+	return bus.Event{
+		ID:        id,
+		Type:      "order.created",
+		Source:    "codeai.dsl",
+		Version:   2,
+		Timestamp: time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC),
+		Data:      map[string]interface{}{"order_id": "o-1", "total": 42.5},
+		Metadata:  map[string]string{"tenant": "acme"},
+	}
+}
+
+func TestSQLStore_Claim(t *testing.T) {
+	store, _ := newSQLStore(t)
+	ctx := context.Background()
+	now := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+
+	require.NoError(t, store.Append(ctx, NewMessage(testEvent("e1"), now)))
+	require.NoError(t, store.Append(ctx, NewMessage(testEvent("e2"), now.Add(time.Second))))
+	require.NoError(t, store.Append(ctx, NewMessage(testEvent("e1"), now)), "appending a duplicate is a no-op")
+
+	claimed, err := store.Claim(ctx, now.Add(time.Minute), 10, 30*time.Second)
+	require.NoError(t, err)
+	require.Len(t, claimed, 2)
+	assert.Equal(t, "e1", claimed[0].ID())
+	assert.Equal(t, "e2", claimed[1].ID())
+	assert.Equal(t, 1, claimed[0].Attempts)
+	assert.Equal(t, testEvent("e1"), claimed[0].Event)
+
+	claimed, err = store.Claim(ctx, now.Add(time.Minute), 10, 30*time.Second)
+	require.NoError(t, err)
+	assert.Empty(t, claimed, "leased messages are not claimed again")
+
+	require.NoError(t, store.MarkPublished(ctx, "e1", now.Add(time.Minute)))
+	require.NoError(t, store.Release(ctx, "e2", now.Add(2*time.Minute), "bus unavailable"))
+	assert.ErrorIs(t, store.MarkPublished(ctx, "missing", now), ErrMessageNotFound)
+
+	claimed, err = store.Claim(ctx, now.Add(2*time.Minute), 10, 30*time.Second)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	assert.Equal(t, "e2", claimed[0].ID())
+	assert.Equal(t, 2, claimed[0].Attempts)
+	assert.Equal(t, "bus unavailable", claimed[0].LastError)
+
+	claimed, err = store.Claim(ctx, now.Add(time.Hour), 10, 30*time.Second)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1, "expired leases are claimed again")
+	assert.Equal(t, "e2", claimed[0].ID())
+}
+
+func TestSQLStore_DeletePublished(t *testing.T) {
+	store, db := newSQLStore(t)
+	ctx := context.Background()
+	now := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+
+	for _, id := range []string{"e1", "e2", "e3"} {
+		require.NoError(t, store.Append(ctx, NewMessage(testEvent(id), now)))
+	}
+	require.NoError(t, store.MarkPublished(ctx, "e1", now))
+	require.NoError(t, store.MarkPublished(ctx, "e2", now.Add(time.Hour)))
+
+	deleted, err := store.DeletePublished(ctx, now.Add(30*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+
+	var ids []string
+	rows, err := db.QueryContext(ctx, "SELECT id FROM event_outbox ORDER BY id")
+	require.NoError(t, err)
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		require.NoError(t, rows.Scan(&id))
+		ids = append(ids, id)
+	}
+	require.NoError(t, rows.Err())
+	assert.Equal(t, []string{"e2", "e3"}, ids, "recent and unpublished messages are kept")
+}
+
+func TestSQLStore_AppendInTransaction(t *testing.T) {
+	store, db := newSQLStore(t)
+	ctx := context.Background()
+	now := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+
+	tx, err := db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	require.NoError(t, store.Append(database.WithTx(ctx, tx), NewMessage(testEvent("e1"), now)))
+	require.NoError(t, tx.Rollback())
+
+	claimed, err := store.Claim(ctx, now, 10, time.Minute)
+	require.NoError(t, err)
+	assert.Empty(t, claimed, "rolled back messages are discarded")
+
+	tx, err = db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	require.NoError(t, store.Append(database.WithTx(ctx, tx), NewMessage(testEvent("e2"), now)))
+	require.NoError(t, tx.Commit())
+
+	claimed, err = store.Claim(ctx, now, 10, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	assert.Equal(t, "e2", claimed[0].ID())
+}
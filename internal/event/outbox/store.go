@@ -0,0 +1,171 @@
+// Package outbox implements a transactional outbox for events.
+//
+// Events are appended to the outbox in the same transaction as the data
+// change they describe, so an event is recorded if and only if the change is
+// committed. A Relay then publishes outbox messages to the event bus with
+// at-least-once semantics: a message is marked published only after it was
+// published successfully, and is retried otherwise. The ID of a message is
+// the ID of its event and stays the same across redeliveries, so consumers
+// can use it as an idempotency key.
+package outbox
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bargom/codeai/internal/event/bus"
+)
+
+// ErrMessageNotFound indicates no outbox message has the given ID.
+var ErrMessageNotFound = errors.New("outbox message not found")
+
+// Message is an event waiting in the outbox.
+type Message struct {
+	Event       bus.Event
+	Attempts    int    // Number of times the message was claimed
+	LastError   string // Error of the last failed publish
+	CreatedAt   time.Time
+	AvailableAt time.Time // The message is not claimed before this time
+	PublishedAt *time.Time
+}
+
+// ID returns the idempotency key of the message, the ID of its event.
+func (m *Message) ID() string {
+	return m.Event.ID
+}
+
+// NewMessage creates a message for event, available immediately.
+func NewMessage(event bus.Event, now time.Time) *Message {
+	return &Message{Event: event, CreatedAt: now, AvailableAt: now}
+}
+
+// Store persists outbox messages.
+type Store interface {
+	// Append adds a message to the outbox, in the transaction carried by
+	// ctx if there is one. Appending a message whose ID is already in the
+	// outbox is a no-op.
+	Append(ctx context.Context, msg *Message) error
+
+	// Claim returns up to limit unpublished messages available at now, in
+	// the order they were appended, and makes them unavailable until
+	// now+lease. A message is claimed by one caller at a time, so several
+	// relays can share an outbox; a message whose relay dies before marking
+	// it is claimed again when its lease expires.
+	Claim(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]*Message, error)
+
+	// MarkPublished marks a message as published.
+	MarkPublished(ctx context.Context, id string, at time.Time) error
+
+	// Release makes a message whose publish failed available again at the
+	// given time.
+	Release(ctx context.Context, id string, at time.Time, lastErr string) error
+
+	// DeletePublished deletes the messages published before the given time
+	// and returns how many were deleted.
+	DeletePublished(ctx context.Context, before time.Time) (int64, error)
+}
+
+// MemoryStore implements Store in memory. It does not take part in
+// database transactions.
+type MemoryStore struct {
+	mu       sync.Mutex
+	messages map[string]*Message
+}
+
+// NewMemoryStore creates an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{messages: make(map[string]*Message)}
+}
+
+// Append implements Store.
+func (s *MemoryStore) Append(ctx context.Context, msg *Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.messages[msg.ID()]; !ok {
+		copied := *msg
+		s.messages[msg.ID()] = &copied
+	}
+	return nil
+}
+
+// Claim implements Store.
+func (s *MemoryStore) Claim(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]*Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []*Message
+	for _, msg := range s.messages {
+		if msg.PublishedAt == nil && !msg.AvailableAt.After(now) {
+			due = append(due, msg)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].CreatedAt.Before(due[j].CreatedAt)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	claimed := make([]*Message, len(due))
+	for i, msg := range due {
+		msg.AvailableAt = now.Add(lease)
+		msg.Attempts++
+		copied := *msg
+		claimed[i] = &copied
+	}
+	return claimed, nil
+}
+
+// MarkPublished implements Store.
+func (s *MemoryStore) MarkPublished(ctx context.Context, id string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	msg, ok := s.messages[id]
+	if !ok {
+		return ErrMessageNotFound
+	}
+	msg.PublishedAt = &at
+	return nil
+}
+
+// Release implements Store.
+func (s *MemoryStore) Release(ctx context.Context, id string, at time.Time, lastErr string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	msg, ok := s.messages[id]
+	if !ok {
+		return ErrMessageNotFound
+	}
+	msg.AvailableAt = at
+	msg.LastError = lastErr
+	return nil
+}
+
+// DeletePublished implements Store.
+func (s *MemoryStore) DeletePublished(ctx context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var deleted int64
+	for id, msg := range s.messages {
+		if msg.PublishedAt != nil && msg.PublishedAt.Before(before) {
+			delete(s.messages, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// Get returns a copy of the message with the given ID.
+func (s *MemoryStore) Get(id string) (*Message, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	msg, ok := s.messages[id]
+	if !ok {
+		return nil, false
+	}
+	copied := *msg
+	return &copied, true
+}
@@ -0,0 +1,219 @@
+package replay
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bargom/codeai/internal/event/repository"
+)
+
+// ErrCheckpointNotFound is returned when a checkpoint does not exist.
+var ErrCheckpointNotFound = errors.New("checkpoint not found")
+
+// Checkpoint records the progress of a replay.
+type Checkpoint struct {
+	Name      string                   `json:"name"`
+	Position  repository.EventPosition `json:"position"` // Position of the last event replayed
+	Replayed  int                      `json:"replayed"` // Events replayed under the checkpoint, over all runs
+	UpdatedAt time.Time                `json:"updated_at"`
+}
+
+// CheckpointStore persists replay checkpoints.
+type CheckpointStore interface {
+	// Load returns the checkpoint with the given name, or
+	// ErrCheckpointNotFound.
+	Load(ctx context.Context, name string) (*Checkpoint, error)
+
+	// Save creates or replaces a checkpoint.
+	Save(ctx context.Context, checkpoint Checkpoint) error
+
+	// List returns all checkpoints, by name.
+	List(ctx context.Context) ([]Checkpoint, error)
+
+	// Delete removes a checkpoint, so that a replay under its name starts
+	// over. Deleting a missing checkpoint is not an error.
+	Delete(ctx context.Context, name string) error
+}
+
+// MemoryCheckpointStore keeps checkpoints in memory.
+type MemoryCheckpointStore struct {
+	mu          sync.RWMutex
+	checkpoints map[string]Checkpoint
+}
+
+// NewMemoryCheckpointStore creates an empty in-memory checkpoint store.
+func NewMemoryCheckpointStore() *MemoryCheckpointStore {
+	return &MemoryCheckpointStore{checkpoints: make(map[string]Checkpoint)}
+}
+
+// Load implements CheckpointStore.
+func (s *MemoryCheckpointStore) Load(ctx context.Context, name string) (*Checkpoint, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	checkpoint, ok := s.checkpoints[name]
+	if !ok {
+		return nil, ErrCheckpointNotFound
+	}
+	return &checkpoint, nil
+}
+
+// Save implements CheckpointStore.
+func (s *MemoryCheckpointStore) Save(ctx context.Context, checkpoint Checkpoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.checkpoints[checkpoint.Name] = checkpoint
+	return nil
+}
+
+// List implements CheckpointStore.
+func (s *MemoryCheckpointStore) List(ctx context.Context) ([]Checkpoint, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	checkpoints := make([]Checkpoint, 0, len(s.checkpoints))
+	for _, checkpoint := range s.checkpoints {
+		checkpoints = append(checkpoints, checkpoint)
+	}
+	sort.Slice(checkpoints, func(i, j int) bool { return checkpoints[i].Name < checkpoints[j].Name })
+	return checkpoints, nil
+}
+
+// Delete implements CheckpointStore.
+func (s *MemoryCheckpointStore) Delete(ctx context.Context, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.checkpoints, name)
+	return nil
+}
+
+// SQLCheckpointStore keeps checkpoints in the event_replay_checkpoints
+// table, next to the events table.
+type SQLCheckpointStore struct {
+	db *sql.DB
+}
+
+// NewSQLCheckpointStore creates a checkpoint store on db.
+func NewSQLCheckpointStore(db *sql.DB) *SQLCheckpointStore {
+	return &SQLCheckpointStore{db: db}
+}
+
+// CreateTable creates the checkpoint table if it doesn't exist.
+func (s *SQLCheckpointStore) CreateTable(ctx context.Context) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS event_replay_checkpoints (
+			name VARCHAR(255) PRIMARY KEY,
+			event_timestamp TIMESTAMP NOT NULL,
+			event_id VARCHAR(36) NOT NULL,
+			replayed INTEGER NOT NULL DEFAULT 0,
+			updated_at TIMESTAMP NOT NULL
+		)
+	`
+
+	if _, err := s.db.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("creating replay checkpoint table: %w", err)
+	}
+	return nil
+}
+
+// Load implements CheckpointStore.
+func (s *SQLCheckpointStore) Load(ctx context.Context, name string) (*Checkpoint, error) {
+	query := `
+		SELECT name, event_timestamp, event_id, replayed, updated_at
+		FROM event_replay_checkpoints
+		WHERE name = $1
+	`
+
+	checkpoint, err := scanCheckpoint(s.db.QueryRowContext(ctx, query, name))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCheckpointNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("querying replay checkpoint: %w", err)
+	}
+	return checkpoint, nil
+}
+
+// Save implements CheckpointStore.
+func (s *SQLCheckpointStore) Save(ctx context.Context, checkpoint Checkpoint) error {
+	query := `
+		INSERT INTO event_replay_checkpoints (name, event_timestamp, event_id, replayed, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (name) DO UPDATE SET
+			event_timestamp = excluded.event_timestamp,
+			event_id = excluded.event_id,
+			replayed = excluded.replayed,
+			updated_at = excluded.updated_at
+	`
+
+	_, err := s.db.ExecContext(ctx, query,
+		checkpoint.Name,
+		checkpoint.Position.Timestamp.UTC(),
+		checkpoint.Position.ID,
+		checkpoint.Replayed,
+		checkpoint.UpdatedAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("saving replay checkpoint: %w", err)
+	}
+	return nil
+}
+
+// List implements CheckpointStore.
+func (s *SQLCheckpointStore) List(ctx context.Context) ([]Checkpoint, error) {
+	query := `
+		SELECT name, event_timestamp, event_id, replayed, updated_at
+		FROM event_replay_checkpoints
+		ORDER BY name
+	`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("querying replay checkpoints: %w", err)
+	}
+	defer rows.Close()
+
+	var checkpoints []Checkpoint
+	for rows.Next() {
+		checkpoint, err := scanCheckpoint(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning replay checkpoint: %w", err)
+		}
+		checkpoints = append(checkpoints, *checkpoint)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating replay checkpoints: %w", err)
+	}
+	return checkpoints, nil
+}
+
+// Delete implements CheckpointStore.
+func (s *SQLCheckpointStore) Delete(ctx context.Context, name string) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM event_replay_checkpoints WHERE name = $1`, name); err != nil {
+		return fmt.Errorf("deleting replay checkpoint: %w", err)
+	}
+	return nil
+}
+
+// scanCheckpoint scans a checkpoint row.
+func scanCheckpoint(row interface{ Scan(...any) error }) (*Checkpoint, error) {
+	var checkpoint Checkpoint
+	err := row.Scan(
+		&checkpoint.Name,
+		&checkpoint.Position.Timestamp,
+		&checkpoint.Position.ID,
+		&checkpoint.Replayed,
+		&checkpoint.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &checkpoint, nil
+}
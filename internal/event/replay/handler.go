@@ -0,0 +1,288 @@
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/bargom/codeai/internal/event/bus"
+	"github.com/bargom/codeai/internal/event/repository"
+)
+
+// AdminPermission is the permission the admin endpoints should require.
+const AdminPermission = "events:replay"
+
+// maxRequestBody limits the size of admin requests.
+const maxRequestBody = 64 << 10
+
+// Job states.
+const (
+	JobRunning   = "running"
+	JobCompleted = "completed"
+	JobFailed    = "failed"
+	JobCanceled  = "canceled"
+)
+
+// Handler serves the admin endpoints running replays in the background.
+type Handler struct {
+	replayer *Replayer
+	mu       sync.Mutex
+	jobs     map[string]*Job
+	wg       sync.WaitGroup
+}
+
+// NewHandler creates the admin handler for replayer.
+func NewHandler(replayer *Replayer) *Handler {
+	return &Handler{
+		replayer: replayer,
+		jobs:     make(map[string]*Job),
+	}
+}
+
+// RegisterRoutes registers the /admin/events/replays routes. They replay
+// events into live handlers, so they must be mounted behind authentication
+// and authorization middleware, such as one requiring AdminPermission.
+func (h *Handler) RegisterRoutes(r chi.Router) {
+	r.Route("/admin/events/replays", func(r chi.Router) {
+		r.Get("/", h.ListJobs)
+		r.Post("/", h.StartJob)
+		r.Get("/targets", h.ListTargets)
+		r.Get("/checkpoints", h.ListCheckpoints)
+		r.Delete("/checkpoints/{name}", h.DeleteCheckpoint)
+		r.Get("/{id}", h.GetJob)
+		r.Delete("/{id}", h.CancelJob)
+	})
+}
+
+// Request is the body of POST /admin/events/replays.
+type Request struct {
+	Types      []string  `json:"types,omitempty"`
+	Since      time.Time `json:"since,omitempty"`
+	Until      time.Time `json:"until,omitempty"`
+	Targets    []string  `json:"targets"`
+	DryRun     bool      `json:"dry_run,omitempty"`
+	Rate       float64   `json:"rate,omitempty"`
+	BatchSize  int       `json:"batch_size,omitempty"`
+	Checkpoint string    `json:"checkpoint,omitempty"`
+}
+
+// options returns the replay options of the request.
+func (req Request) options() Options {
+	types := make([]bus.EventType, len(req.Types))
+	for i, t := range req.Types {
+		types[i] = bus.EventType(t)
+	}
+	return Options{
+		Types:      types,
+		Since:      req.Since,
+		Until:      req.Until,
+		Targets:    req.Targets,
+		DryRun:     req.DryRun,
+		Rate:       req.Rate,
+		BatchSize:  req.BatchSize,
+		Checkpoint: req.Checkpoint,
+	}
+}
+
+// Job is a replay started through the admin endpoints.
+type Job struct {
+	ID         string                    `json:"id"`
+	Request    Request                   `json:"request"`
+	Status     string                    `json:"status"`
+	Replayed   int                       `json:"replayed"`
+	Resumed    bool                      `json:"resumed"`
+	Last       *repository.EventPosition `json:"last,omitempty"`
+	Error      string                    `json:"error,omitempty"`
+	StartedAt  time.Time                 `json:"started_at"`
+	FinishedAt *time.Time                `json:"finished_at,omitempty"`
+
+	cancel context.CancelFunc
+}
+
+// StartJob handles POST /admin/events/replays. The replay runs in the
+// background; its job is returned with status 202.
+func (h *Handler) StartJob(w http.ResponseWriter, r *http.Request) {
+	var req Request
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if len(req.Targets) == 0 {
+		writeError(w, http.StatusBadRequest, "targets are required")
+		return
+	}
+	if req.Rate < 0 {
+		writeError(w, http.StatusBadRequest, "rate must not be negative")
+		return
+	}
+	if _, err := h.replayer.selectTargets(req.Targets); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	h.mu.Lock()
+	if req.Checkpoint != "" && !req.DryRun {
+		for _, job := range h.jobs {
+			if job.Status == JobRunning && job.Request.Checkpoint == req.Checkpoint && !job.Request.DryRun {
+				h.mu.Unlock()
+				writeError(w, http.StatusConflict, "a replay with this checkpoint is running")
+				return
+			}
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &Job{
+		ID:        uuid.NewString(),
+		Request:   req,
+		Status:    JobRunning,
+		StartedAt: time.Now().UTC(),
+		cancel:    cancel,
+	}
+	h.jobs[job.ID] = job
+	snapshot := *job
+	h.mu.Unlock()
+
+	h.wg.Add(1)
+	go h.run(ctx, job)
+
+	writeJSON(w, http.StatusAccepted, snapshot)
+}
+
+// run runs the replay of job and records its outcome.
+func (h *Handler) run(ctx context.Context, job *Job) {
+	defer h.wg.Done()
+	defer job.cancel()
+
+	opts := job.Request.options()
+	opts.OnEvent = func(event bus.Event, _ []string) {
+		position := repository.PositionOf(event)
+		h.mu.Lock()
+		job.Replayed++
+		job.Last = &position
+		h.mu.Unlock()
+	}
+	result, err := h.replayer.Run(ctx, opts)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	now := time.Now().UTC()
+	job.FinishedAt = &now
+	job.Replayed = result.Replayed
+	job.Resumed = result.Resumed
+	job.Last = result.Last
+	switch {
+	case errors.Is(err, context.Canceled):
+		job.Status = JobCanceled
+	case err != nil:
+		job.Status = JobFailed
+		job.Error = err.Error()
+	default:
+		job.Status = JobCompleted
+	}
+}
+
+// ListJobs handles GET /admin/events/replays.
+func (h *Handler) ListJobs(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	jobs := make([]Job, 0, len(h.jobs))
+	for _, job := range h.jobs {
+		jobs = append(jobs, *job)
+	}
+	h.mu.Unlock()
+
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].StartedAt.After(jobs[j].StartedAt) })
+	writeJSON(w, http.StatusOK, map[string]any{"replays": jobs})
+}
+
+// GetJob handles GET /admin/events/replays/{id}.
+func (h *Handler) GetJob(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	job, ok := h.jobs[chi.URLParam(r, "id")]
+	var snapshot Job
+	if ok {
+		snapshot = *job
+	}
+	h.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "replay not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, snapshot)
+}
+
+// CancelJob handles DELETE /admin/events/replays/{id}. A canceled replay
+// with a checkpoint resumes where it stopped when started again.
+func (h *Handler) CancelJob(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	job, ok := h.jobs[chi.URLParam(r, "id")]
+	h.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "replay not found")
+		return
+	}
+	job.cancel()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListTargets handles GET /admin/events/replays/targets.
+func (h *Handler) ListTargets(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{"targets": h.replayer.Targets()})
+}
+
+// ListCheckpoints handles GET /admin/events/replays/checkpoints.
+func (h *Handler) ListCheckpoints(w http.ResponseWriter, r *http.Request) {
+	checkpoints, err := h.replayer.Checkpoints().List(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "cannot list checkpoints")
+		return
+	}
+	if checkpoints == nil {
+		checkpoints = []Checkpoint{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"checkpoints": checkpoints})
+}
+
+// DeleteCheckpoint handles DELETE /admin/events/replays/checkpoints/{name},
+// so that the next replay under the name starts over.
+func (h *Handler) DeleteCheckpoint(w http.ResponseWriter, r *http.Request) {
+	if err := h.replayer.Checkpoints().Delete(r.Context(), chi.URLParam(r, "name")); err != nil {
+		writeError(w, http.StatusInternalServerError, "cannot delete checkpoint")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Shutdown cancels the running replays and waits for them to stop. Their
+// checkpoints keep their progress.
+func (h *Handler) Shutdown() {
+	h.mu.Lock()
+	for _, job := range h.jobs {
+		job.cancel()
+	}
+	h.mu.Unlock()
+	h.wg.Wait()
+}
+
+// errorResponse is the body of error responses.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, errorResponse{Error: message})
+}
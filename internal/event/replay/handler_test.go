@@ -0,0 +1,154 @@
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bargom/codeai/internal/event/bus"
+)
+
+func newAdminRouter(t *testing.T, subscriber bus.Subscriber) (http.Handler, *Handler) {
+	t.Helper()
+	repo, _ := newEventStore(t, "order.created", "user.created", "order.created")
+	replayer := NewReplayer(repo, []Target{
+		{Name: "orders", EventType: "order.created", Subscriber: subscriber},
+		{Name: "all", EventType: bus.AllEvents, Subscriber: subscriber},
+	})
+	h := NewHandler(replayer)
+	t.Cleanup(h.Shutdown)
+
+	r := chi.NewRouter()
+	h.RegisterRoutes(r)
+	return r, h
+}
+
+func doRequest(t *testing.T, h http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+// waitForJob polls the job until it is no longer running.
+func waitForJob(t *testing.T, router http.Handler, id string) Job {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		rec := doRequest(t, router, http.MethodGet, "/admin/events/replays/"+id, "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		var job Job
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&job))
+		if job.Status != JobRunning {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("replay %s still running", id)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestHandler_Targets(t *testing.T) {
+	router, _ := newAdminRouter(t, &recorder{})
+
+	rec := doRequest(t, router, http.MethodGet, "/admin/events/replays/targets", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"targets":["all","orders"]}`, rec.Body.String())
+}
+
+func TestHandler_StartJob(t *testing.T) {
+	orders := &recorder{}
+	router, _ := newAdminRouter(t, orders)
+
+	rec := doRequest(t, router, http.MethodPost, "/admin/events/replays",
+		`{"types":["order.*"],"targets":["orders"],"checkpoint":"rebuild"}`)
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	var started Job
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&started))
+	assert.NotEmpty(t, started.ID)
+
+	job := waitForJob(t, router, started.ID)
+	assert.Equal(t, JobCompleted, job.Status)
+	assert.Equal(t, 2, job.Replayed)
+	require.NotNil(t, job.Last)
+	assert.Equal(t, "e3", job.Last.ID)
+	assert.Equal(t, []string{"e1", "e3"}, orders.handled())
+
+	rec = doRequest(t, router, http.MethodGet, "/admin/events/replays", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var list struct {
+		Replays []Job `json:"replays"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&list))
+	require.Len(t, list.Replays, 1)
+
+	rec = doRequest(t, router, http.MethodGet, "/admin/events/replays/checkpoints", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var checkpoints struct {
+		Checkpoints []Checkpoint `json:"checkpoints"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&checkpoints))
+	require.Len(t, checkpoints.Checkpoints, 1)
+	assert.Equal(t, "rebuild", checkpoints.Checkpoints[0].Name)
+
+	rec = doRequest(t, router, http.MethodDelete, "/admin/events/replays/checkpoints/rebuild", "")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	rec = doRequest(t, router, http.MethodGet, "/admin/events/replays/checkpoints", "")
+	assert.JSONEq(t, `{"checkpoints":[]}`, rec.Body.String())
+}
+
+func TestHandler_StartJob_Invalid(t *testing.T) {
+	router, _ := newAdminRouter(t, &recorder{})
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "malformed", body: `{`},
+		{name: "no targets", body: `{}`},
+		{name: "unknown target", body: `{"targets":["missing"]}`},
+		{name: "negative rate", body: `{"targets":["all"],"rate":-1}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := doRequest(t, router, http.MethodPost, "/admin/events/replays", tt.body)
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+		})
+	}
+}
+
+func TestHandler_CancelJob(t *testing.T) {
+	blocking := bus.SubscriberFunc(func(ctx context.Context, _ bus.Event) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	router, _ := newAdminRouter(t, blocking)
+
+	rec := doRequest(t, router, http.MethodPost, "/admin/events/replays", `{"targets":["all"],"checkpoint":"rebuild"}`)
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	var started Job
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&started))
+
+	// A second replay with the same checkpoint conflicts with the running one
+	rec = doRequest(t, router, http.MethodPost, "/admin/events/replays", `{"targets":["all"],"checkpoint":"rebuild"}`)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	rec = doRequest(t, router, http.MethodDelete, "/admin/events/replays/"+started.ID, "")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, JobCanceled, waitForJob(t, router, started.ID).Status)
+
+	rec = doRequest(t, router, http.MethodDelete, "/admin/events/replays/missing", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	rec = doRequest(t, router, http.MethodGet, "/admin/events/replays/missing", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,321 @@
+// Package replay re-runs stored events through event handlers, for example
+// to rebuild a projection after a handler changed.
+package replay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/bargom/codeai/internal/event/bus"
+	"github.com/bargom/codeai/internal/event/repository"
+)
+
+// ErrUnknownTarget is returned when a replay selects a target that does not
+// exist.
+var ErrUnknownTarget = errors.New("unknown replay target")
+
+// Logger defines the logging interface for replays.
+type Logger interface {
+	Info(msg string, args ...any)
+	Error(msg string, args ...any)
+	Debug(msg string, args ...any)
+	Warn(msg string, args ...any)
+}
+
+// Target receives replayed events: a DSL event handler or a subscriber.
+type Target struct {
+	Name       string        // Name the target is selected by; several targets may share one
+	EventType  bus.EventType // Type of the events the target receives, or bus.AllEvents
+	Subscriber bus.Subscriber
+}
+
+// accepts reports whether the target receives event.
+func (t Target) accepts(event bus.Event) bool {
+	return t.EventType == bus.AllEvents || t.EventType == event.Type
+}
+
+// Options select the events to replay and where to.
+type Options struct {
+	Types      []bus.EventType // Event types; "*" matches any characters. All types when empty
+	Since      time.Time       // Only events at or after Since, unless zero
+	Until      time.Time       // Only events at or before Until, unless zero
+	Targets    []string        // Names of the targets to replay into
+	DryRun     bool            // Report the events that would be replayed without delivering them
+	Rate       float64         // Maximum events per second; unlimited when 0
+	BatchSize  int             // Events read from the store at once
+	Checkpoint string          // Name progress is saved under; a replay with a saved checkpoint resumes after it
+
+	// OnEvent, when set, is called for each event after it was replayed, or
+	// in a dry run instead of replaying it, with the targets receiving it.
+	OnEvent func(event bus.Event, targets []string)
+}
+
+// Result reports the outcome of a replay.
+type Result struct {
+	Replayed int                       `json:"replayed"` // Events replayed by this run
+	Resumed  bool                      `json:"resumed"`  // Whether the run resumed from a checkpoint
+	Last     *repository.EventPosition `json:"last,omitempty"`
+	DryRun   bool                      `json:"dry_run"`
+}
+
+// Replayer replays events from an event repository.
+type Replayer struct {
+	events      repository.EventRepository
+	targets     []Target
+	checkpoints CheckpointStore
+	upcaster    bus.Upcaster
+	logger      Logger
+	wait        func(ctx context.Context, d time.Duration) error
+}
+
+// Option configures the Replayer.
+type Option func(*Replayer)
+
+// WithCheckpoints sets where replays with a checkpoint name save their
+// progress. Defaults to memory, so checkpoints last as long as the process.
+func WithCheckpoints(store CheckpointStore) Option {
+	return func(r *Replayer) {
+		r.checkpoints = store
+	}
+}
+
+// WithUpcaster migrates stored events of earlier versions to the latest
+// version of their type before they are replayed.
+func WithUpcaster(u bus.Upcaster) Option {
+	return func(r *Replayer) {
+		r.upcaster = u
+	}
+}
+
+// WithLogger sets the logger for the replayer.
+func WithLogger(logger Logger) Option {
+	return func(r *Replayer) {
+		r.logger = logger
+	}
+}
+
+// NewReplayer creates a replayer reading events from events and replaying
+// them into targets.
+func NewReplayer(events repository.EventRepository, targets []Target, opts ...Option) *Replayer {
+	r := &Replayer{
+		events:      events,
+		targets:     targets,
+		checkpoints: NewMemoryCheckpointStore(),
+		wait:        sleep,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// Targets returns the names of the targets events can be replayed into.
+func (r *Replayer) Targets() []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, t := range r.targets {
+		if !seen[t.Name] {
+			seen[t.Name] = true
+			names = append(names, t.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Checkpoints returns the store replays save their progress to.
+func (r *Replayer) Checkpoints() CheckpointStore {
+	return r.checkpoints
+}
+
+// Run replays the events selected by opts, oldest first, into the selected
+// targets. Events are delivered in a bus.WithReplay context, so outbound
+// webhooks and emails are suppressed. A target failing, or an event that
+// cannot be upcast, stops the replay; with a checkpoint, the replay resumes
+// at the failed event.
+func (r *Replayer) Run(ctx context.Context, opts Options) (Result, error) {
+	result := Result{DryRun: opts.DryRun}
+
+	targets, err := r.selectTargets(opts.Targets)
+	if err != nil {
+		return result, err
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+
+	filter := repository.EventFilter{Types: opts.Types, Ascending: true, Limit: opts.BatchSize}
+	if !opts.Since.IsZero() {
+		filter.StartTime = &opts.Since
+	}
+	if !opts.Until.IsZero() {
+		filter.EndTime = &opts.Until
+	}
+
+	var checkpoint *Checkpoint
+	var replayedBefore int
+	if opts.Checkpoint != "" {
+		checkpoint, err = r.checkpoints.Load(ctx, opts.Checkpoint)
+		switch {
+		case errors.Is(err, ErrCheckpointNotFound):
+			checkpoint = &Checkpoint{Name: opts.Checkpoint}
+		case err != nil:
+			return result, fmt.Errorf("loading checkpoint %s: %w", opts.Checkpoint, err)
+		default:
+			position := checkpoint.Position
+			filter.After = &position
+			replayedBefore = checkpoint.Replayed
+			result.Resumed = true
+			result.Last = &position
+		}
+	}
+
+	// save records the progress of the replay, unless it is a dry run. It
+	// also saves the progress of canceled replays.
+	save := func() error {
+		if checkpoint == nil || opts.DryRun || result.Last == nil {
+			return nil
+		}
+		checkpoint.Position = *result.Last
+		checkpoint.Replayed = replayedBefore + result.Replayed
+		checkpoint.UpdatedAt = time.Now().UTC()
+		if err := r.checkpoints.Save(context.WithoutCancel(ctx), *checkpoint); err != nil {
+			return fmt.Errorf("saving checkpoint %s: %w", checkpoint.Name, err)
+		}
+		return nil
+	}
+
+	var interval time.Duration
+	if opts.Rate > 0 {
+		interval = time.Duration(float64(time.Second) / opts.Rate)
+	}
+	replayCtx := bus.WithReplay(ctx)
+
+	if r.logger != nil {
+		r.logger.Info("replay started", "types", opts.Types, "targets", opts.Targets, "dryRun", opts.DryRun, "resumed", result.Resumed)
+	}
+
+	for {
+		events, err := r.events.ListEvents(ctx, filter)
+		if err != nil {
+			return result, errors.Join(fmt.Errorf("listing events: %w", err), save())
+		}
+
+		for _, event := range events {
+			position := repository.PositionOf(event)
+			receivers := accepting(targets, event)
+			if len(receivers) == 0 {
+				result.Last = &position
+				continue
+			}
+
+			if r.upcaster != nil {
+				if event, err = r.upcaster.Upcast(event); err != nil {
+					err = fmt.Errorf("upcasting event %s: %w", event.ID, err)
+					if r.logger != nil {
+						r.logger.Error("replay stopped", "eventID", event.ID, "eventType", string(event.Type), "error", err.Error())
+					}
+					return result, errors.Join(err, save())
+				}
+			}
+
+			if interval > 0 && !opts.DryRun {
+				if err := r.wait(ctx, interval); err != nil {
+					return result, errors.Join(err, save())
+				}
+			}
+			if !opts.DryRun {
+				if err := deliver(replayCtx, receivers, event); err != nil {
+					if r.logger != nil {
+						r.logger.Error("replay stopped", "eventID", event.ID, "eventType", string(event.Type), "error", err.Error())
+					}
+					return result, errors.Join(err, save())
+				}
+			}
+
+			result.Last = &position
+			result.Replayed++
+			if opts.OnEvent != nil {
+				names := make([]string, len(receivers))
+				for i, t := range receivers {
+					names[i] = t.Name
+				}
+				opts.OnEvent(event, names)
+			}
+		}
+
+		if err := save(); err != nil {
+			return result, err
+		}
+		if len(events) < opts.BatchSize {
+			break
+		}
+		filter.After = result.Last
+	}
+
+	if r.logger != nil {
+		r.logger.Info("replay completed", "replayed", result.Replayed, "dryRun", opts.DryRun)
+	}
+	return result, nil
+}
+
+// selectTargets returns the targets with the given names.
+func (r *Replayer) selectTargets(names []string) ([]Target, error) {
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no replay target selected")
+	}
+
+	var targets []Target
+	for _, name := range names {
+		found := false
+		for _, t := range r.targets {
+			if t.Name == name {
+				targets = append(targets, t)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%w %q", ErrUnknownTarget, name)
+		}
+	}
+	return targets, nil
+}
+
+// accepting returns the targets receiving event.
+func accepting(targets []Target, event bus.Event) []Target {
+	var accepting []Target
+	for _, t := range targets {
+		if t.accepts(event) {
+			accepting = append(accepting, t)
+		}
+	}
+	return accepting
+}
+
+// deliver passes event to targets, in order.
+func deliver(ctx context.Context, targets []Target, event bus.Event) error {
+	for _, t := range targets {
+		if err := t.Subscriber.Handle(ctx, event); err != nil {
+			return fmt.Errorf("replaying event %s to %s: %w", event.ID, t.Name, err)
+		}
+	}
+	return nil
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,304 @@
+package replay
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+
+	"github.com/bargom/codeai/internal/event/bus"
+	"github.com/bargom/codeai/internal/event/repository"
+)
+
+var epoch = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// newEventStore returns an event repository holding one event per type,
+// a minute apart, with IDs e1, e2, ...
+func newEventStore(t *testing.T, types ...bus.EventType) (*repository.PostgresEventRepository, *sql.DB) {
+	t.Helper()
+	db, err := sql.Open("sqlite", ":memory:")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	// The events table of CreateEventsTable, with column types SQLite
+	// scans timestamps from
+	ctx := context.Background()
+	_, err = db.ExecContext(ctx, `
+		CREATE TABLE events (
+			id VARCHAR(36) PRIMARY KEY,
+			type VARCHAR(100) NOT NULL,
+			source VARCHAR(255) NOT NULL,
+			version INTEGER NOT NULL DEFAULT 1,
+			timestamp TIMESTAMP NOT NULL,
+			data TEXT NOT NULL DEFAULT '{}',
+			metadata TEXT NOT NULL DEFAULT '{}'
+		)
+	`)
+	require.NoError(t, err)
+
+	repo := repository.NewPostgresEventRepository(db)
+	for i, typ := range types {
+		require.NoError(t, repo.SaveEvent(ctx, bus.Event{
+			ID:        fmt.Sprintf("e%d", i+1),
+			Type:      typ,
+			Source:    "test",
+			Timestamp: epoch.Add(time.Duration(i) * time.Minute),
+			Data:      map[string]interface{}{"n": float64(i + 1)},
+		}))
+	}
+	return repo, db
+}
+
+// recorder records the events it handles and fails on the event with ID
+// failOn.
+type recorder struct {
+	mu      sync.Mutex
+	events  []string
+	replays []bool
+	failOn  string
+}
+
+func (r *recorder) Handle(ctx context.Context, event bus.Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if event.ID == r.failOn {
+		return errors.New("boom")
+	}
+	r.events = append(r.events, event.ID)
+	r.replays = append(r.replays, bus.IsReplay(ctx))
+	return nil
+}
+
+func (r *recorder) handled() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.events...)
+}
+
+func TestReplayer_Run(t *testing.T) {
+	repo, _ := newEventStore(t, "order.created", "user.created", "order.shipped", "order.created")
+	orders := &recorder{}
+	all := &recorder{}
+	r := NewReplayer(repo, []Target{
+		{Name: "orders", EventType: "order.created", Subscriber: orders},
+		{Name: "orders", EventType: "order.shipped", Subscriber: orders},
+		{Name: "all", EventType: bus.AllEvents, Subscriber: all},
+	})
+
+	assert.Equal(t, []string{"all", "orders"}, r.Targets())
+
+	result, err := r.Run(context.Background(), Options{Targets: []string{"orders"}, BatchSize: 2})
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.Replayed)
+	assert.Equal(t, []string{"e1", "e3", "e4"}, orders.handled(), "events are replayed oldest first")
+	assert.Equal(t, []bool{true, true, true}, orders.replays, "handlers run in a replay context")
+	assert.Empty(t, all.handled())
+	require.NotNil(t, result.Last)
+	assert.Equal(t, "e4", result.Last.ID)
+}
+
+func TestReplayer_Run_Filters(t *testing.T) {
+	repo, _ := newEventStore(t, "order.created", "user.created", "order.shipped", "order_created")
+
+	tests := []struct {
+		name string
+		opts Options
+		want []string
+	}{
+		{name: "type pattern", opts: Options{Types: []bus.EventType{"order.*"}}, want: []string{"e1", "e3"}},
+		{name: "exact types", opts: Options{Types: []bus.EventType{"user.created", "order_created"}}, want: []string{"e2", "e4"}},
+		{name: "since", opts: Options{Since: epoch.Add(2 * time.Minute)}, want: []string{"e3", "e4"}},
+		{name: "until", opts: Options{Until: epoch.Add(time.Minute)}, want: []string{"e1", "e2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			all := &recorder{}
+			r := NewReplayer(repo, []Target{{Name: "all", EventType: bus.AllEvents, Subscriber: all}})
+
+			tt.opts.Targets = []string{"all"}
+			_, err := r.Run(context.Background(), tt.opts)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, all.handled())
+		})
+	}
+}
+
+func TestReplayer_Run_DryRun(t *testing.T) {
+	repo, _ := newEventStore(t, "order.created", "order.created")
+	all := &recorder{}
+	r := NewReplayer(repo, []Target{{Name: "all", EventType: bus.AllEvents, Subscriber: all}})
+
+	var reported []string
+	result, err := r.Run(context.Background(), Options{
+		Targets:    []string{"all"},
+		DryRun:     true,
+		Checkpoint: "rebuild",
+		OnEvent: func(event bus.Event, targets []string) {
+			reported = append(reported, event.ID)
+			assert.Equal(t, []string{"all"}, targets)
+		},
+	})
+	require.NoError(t, err)
+	assert.True(t, result.DryRun)
+	assert.Equal(t, 2, result.Replayed)
+	assert.Equal(t, []string{"e1", "e2"}, reported)
+	assert.Empty(t, all.handled(), "a dry run delivers nothing")
+
+	_, err = r.Checkpoints().Load(context.Background(), "rebuild")
+	assert.ErrorIs(t, err, ErrCheckpointNotFound, "a dry run saves no checkpoint")
+}
+
+func TestReplayer_Run_Rate(t *testing.T) {
+	repo, _ := newEventStore(t, "order.created", "order.created", "order.created")
+	r := NewReplayer(repo, []Target{{Name: "all", EventType: bus.AllEvents, Subscriber: &recorder{}}})
+	var waits []time.Duration
+	r.wait = func(_ context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+
+	_, err := r.Run(context.Background(), Options{Targets: []string{"all"}, Rate: 4})
+	require.NoError(t, err)
+	assert.Equal(t, []time.Duration{250 * time.Millisecond, 250 * time.Millisecond, 250 * time.Millisecond}, waits)
+}
+
+func TestReplayer_Run_Checkpoint(t *testing.T) {
+	repo, db := newEventStore(t, "order.created", "order.created", "order.created", "order.created")
+	store := NewSQLCheckpointStore(db)
+	require.NoError(t, store.CreateTable(context.Background()))
+
+	all := &recorder{failOn: "e3"}
+	r := NewReplayer(repo, []Target{{Name: "all", EventType: bus.AllEvents, Subscriber: all}}, WithCheckpoints(store))
+	opts := Options{Targets: []string{"all"}, Checkpoint: "rebuild"}
+
+	// The replay stops at the failing event and saves its progress
+	result, err := r.Run(context.Background(), opts)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "replaying event e3 to all")
+	assert.Equal(t, 2, result.Replayed)
+
+	checkpoint, err := store.Load(context.Background(), "rebuild")
+	require.NoError(t, err)
+	assert.Equal(t, "e2", checkpoint.Position.ID)
+	assert.Equal(t, 2, checkpoint.Replayed)
+
+	// The next run resumes at the failed event
+	all.failOn = ""
+	result, err = r.Run(context.Background(), opts)
+	require.NoError(t, err)
+	assert.True(t, result.Resumed)
+	assert.Equal(t, 2, result.Replayed)
+	assert.Equal(t, []string{"e1", "e2", "e3", "e4"}, all.handled())
+
+	checkpoints, err := store.List(context.Background())
+	require.NoError(t, err)
+	require.Len(t, checkpoints, 1)
+	assert.Equal(t, "e4", checkpoints[0].Position.ID)
+	assert.Equal(t, 4, checkpoints[0].Replayed)
+
+	// Deleting the checkpoint starts the replay over
+	require.NoError(t, store.Delete(context.Background(), "rebuild"))
+	result, err = r.Run(context.Background(), opts)
+	require.NoError(t, err)
+	assert.False(t, result.Resumed)
+	assert.Equal(t, 4, result.Replayed)
+}
+
+func TestReplayer_Run_Canceled(t *testing.T) {
+	repo, _ := newEventStore(t, "order.created", "order.created", "order.created")
+	ctx, cancel := context.WithCancel(context.Background())
+	r := NewReplayer(repo, []Target{{Name: "all", EventType: bus.AllEvents, Subscriber: &recorder{}}})
+	r.wait = func(ctx context.Context, _ time.Duration) error {
+		cancel()
+		return ctx.Err()
+	}
+
+	result, err := r.Run(ctx, Options{Targets: []string{"all"}, Rate: 1, Checkpoint: "rebuild"})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 0, result.Replayed)
+}
+
+func TestReplayer_Run_UnknownTarget(t *testing.T) {
+	repo, _ := newEventStore(t)
+	r := NewReplayer(repo, []Target{{Name: "all", EventType: bus.AllEvents, Subscriber: &recorder{}}})
+
+	_, err := r.Run(context.Background(), Options{Targets: []string{"missing"}})
+	assert.ErrorIs(t, err, ErrUnknownTarget)
+
+	_, err = r.Run(context.Background(), Options{})
+	assert.Error(t, err)
+}
+
+func TestReplayer_Run_StaysInProcess(t *testing.T) {
+	repo, _ := newEventStore(t, "order.created")
+
+	// A target publishing a follow-up event, as a DSL emit handler does
+	eventBus := bus.NewEventBus(nil)
+	defer eventBus.Close()
+	followUps := &recorder{}
+	eventBus.Subscribe("order.audited", followUps)
+	emitter := bus.SubscriberFunc(func(ctx context.Context, event bus.Event) error {
+		return eventBus.Publish(ctx, bus.Event{ID: "f-" + event.ID, Type: "order.audited", Timestamp: time.Now().UTC()})
+	})
+
+	r := NewReplayer(repo, []Target{{Name: "audit", EventType: "order.created", Subscriber: emitter}})
+	_, err := r.Run(context.Background(), Options{Targets: []string{"audit"}})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"f-e1"}, followUps.handled())
+	assert.Equal(t, []bool{true}, followUps.replays, "follow-up events carry the replay context")
+}
+
+// doubler upcasts events to version 2 by doubling n, and fails on events
+// of an unknown version.
+type doubler struct{}
+
+func (doubler) Upcast(event bus.Event) (bus.Event, error) {
+	if event.Version > 2 {
+		return event, errors.New("unknown version")
+	}
+	if event.Version == 1 {
+		event.Data = map[string]interface{}{"n": event.Data["n"].(float64) * 2}
+	}
+	event.Version = 2
+	return event, nil
+}
+
+func TestReplayer_Run_Upcaster(t *testing.T) {
+	repo, db := newEventStore(t, "order.created", "order.created", "order.created")
+	_, err := db.Exec(`UPDATE events SET version = 1 WHERE id IN ('e1', 'e2')`)
+	require.NoError(t, err)
+
+	var replayed []bus.Event
+	collect := bus.SubscriberFunc(func(_ context.Context, event bus.Event) error {
+		replayed = append(replayed, event)
+		return nil
+	})
+	r := NewReplayer(repo, []Target{{Name: "all", EventType: bus.AllEvents, Subscriber: collect}}, WithUpcaster(doubler{}))
+
+	_, err = r.Run(context.Background(), Options{Targets: []string{"all"}})
+	require.NoError(t, err)
+	require.Len(t, replayed, 3)
+	assert.Equal(t, map[string]interface{}{"n": float64(2)}, replayed[0].Data)
+	assert.Equal(t, map[string]interface{}{"n": float64(4)}, replayed[1].Data)
+	assert.Equal(t, map[string]interface{}{"n": float64(3)}, replayed[2].Data, "events without a version are not migrated")
+	for _, event := range replayed {
+		assert.Equal(t, 2, event.Version)
+	}
+
+	// An event that cannot be upcast stops the replay at that event
+	_, err = db.Exec(`UPDATE events SET version = 3 WHERE id = 'e2'`)
+	require.NoError(t, err)
+	replayed = nil
+	result, err := r.Run(context.Background(), Options{Targets: []string{"all"}})
+	assert.ErrorContains(t, err, "upcasting event e2")
+	assert.Equal(t, 1, result.Replayed)
+}
@@ -10,14 +10,29 @@ import (
 
 // EventFilter specifies criteria for filtering events.
 type EventFilter struct {
-	Types     []bus.EventType
+	Types     []bus.EventType // A "*" in a type matches any characters
 	Sources   []string
 	StartTime *time.Time
 	EndTime   *time.Time
+	After     *EventPosition // Only events after the position
+	Ascending bool           // Oldest events first; newest first by default
 	Limit     int
 	Offset    int
 }
 
+// EventPosition is the position of an event in the event store, where
+// events are ordered by timestamp, then ID. Listing events in ascending
+// order After the last event of a page returns the next page.
+type EventPosition struct {
+	Timestamp time.Time
+	ID        string
+}
+
+// PositionOf returns the position of event.
+func PositionOf(event bus.Event) EventPosition {
+	return EventPosition{Timestamp: event.Timestamp, ID: event.ID}
+}
+
 // EventRepository defines the interface for event persistence.
 type EventRepository interface {
 	// SaveEvent persists an event to the database.
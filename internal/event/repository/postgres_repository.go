@@ -25,7 +25,8 @@ func NewPostgresEventRepository(db *sql.DB) *PostgresEventRepository {
 	return &PostgresEventRepository{db: db}
 }
 
-// SaveEvent persists an event to the database.
+// SaveEvent persists an event to the database. Saving an event whose ID is
+// already stored is a no-op, so redelivered events are recorded once.
 func (r *PostgresEventRepository) SaveEvent(ctx context.Context, event bus.Event) error {
 	dataJSON, err := json.Marshal(event.Data)
 	if err != nil {
@@ -38,14 +39,16 @@ func (r *PostgresEventRepository) SaveEvent(ctx context.Context, event bus.Event
 	}
 
 	query := `
-		INSERT INTO events (id, type, source, timestamp, data, metadata)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO events (id, type, source, version, timestamp, data, metadata)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (id) DO NOTHING
 	`
 
 	_, err = r.db.ExecContext(ctx, query,
 		event.ID,
 		string(event.Type),
 		event.Source,
+		event.Version,
 		event.Timestamp,
 		dataJSON,
 		metadataJSON,
@@ -60,7 +63,7 @@ func (r *PostgresEventRepository) SaveEvent(ctx context.Context, event bus.Event
 // GetEvent retrieves an event by its ID.
 func (r *PostgresEventRepository) GetEvent(ctx context.Context, eventID string) (*bus.Event, error) {
 	query := `
-		SELECT id, type, source, timestamp, data, metadata
+		SELECT id, type, source, version, timestamp, data, metadata
 		FROM events
 		WHERE id = $1
 	`
@@ -72,6 +75,7 @@ func (r *PostgresEventRepository) GetEvent(ctx context.Context, eventID string)
 		&event.ID,
 		&event.Type,
 		&event.Source,
+		&event.Version,
 		&event.Timestamp,
 		&dataJSON,
 		&metadataJSON,
@@ -154,7 +158,7 @@ func (r *PostgresEventRepository) buildFilterQuery(filter EventFilter, countOnly
 	if countOnly {
 		selectClause = "SELECT COUNT(*)"
 	} else {
-		selectClause = "SELECT id, type, source, timestamp, data, metadata"
+		selectClause = "SELECT id, type, source, version, timestamp, data, metadata"
 	}
 
 	query := selectClause + " FROM events WHERE 1=1"
@@ -162,13 +166,21 @@ func (r *PostgresEventRepository) buildFilterQuery(filter EventFilter, countOnly
 	argNum := 1
 
 	if len(filter.Types) > 0 {
-		placeholders := make([]string, len(filter.Types))
-		for i, t := range filter.Types {
-			placeholders[i] = fmt.Sprintf("$%d", argNum)
-			args = append(args, string(t))
+		var placeholders, conditions []string
+		for _, t := range filter.Types {
+			if strings.Contains(string(t), "*") {
+				conditions = append(conditions, fmt.Sprintf(`type LIKE $%d ESCAPE '\'`, argNum))
+				args = append(args, likePattern(string(t)))
+			} else {
+				placeholders = append(placeholders, fmt.Sprintf("$%d", argNum))
+				args = append(args, string(t))
+			}
 			argNum++
 		}
-		query += fmt.Sprintf(" AND type IN (%s)", strings.Join(placeholders, ", "))
+		if len(placeholders) > 0 {
+			conditions = append(conditions, fmt.Sprintf("type IN (%s)", strings.Join(placeholders, ", ")))
+		}
+		query += fmt.Sprintf(" AND (%s)", strings.Join(conditions, " OR "))
 	}
 
 	if len(filter.Sources) > 0 {
@@ -193,8 +205,18 @@ func (r *PostgresEventRepository) buildFilterQuery(filter EventFilter, countOnly
 		argNum++
 	}
 
+	if filter.After != nil {
+		query += fmt.Sprintf(" AND (timestamp > $%d OR (timestamp = $%d AND id > $%d))", argNum, argNum, argNum+1)
+		args = append(args, filter.After.Timestamp, filter.After.ID)
+		argNum += 2
+	}
+
 	if !countOnly {
-		query += " ORDER BY timestamp DESC"
+		if filter.Ascending {
+			query += " ORDER BY timestamp ASC, id ASC"
+		} else {
+			query += " ORDER BY timestamp DESC, id DESC"
+		}
 
 		if filter.Limit > 0 {
 			query += fmt.Sprintf(" LIMIT $%d", argNum)
@@ -211,6 +233,13 @@ func (r *PostgresEventRepository) buildFilterQuery(filter EventFilter, countOnly
 	return query, args
 }
 
+// likePattern converts an event type pattern, where "*" matches any
+// characters, to a LIKE pattern.
+func likePattern(pattern string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(pattern)
+	return strings.ReplaceAll(escaped, "*", "%")
+}
+
 // queryEvents executes a query and returns the events.
 func (r *PostgresEventRepository) queryEvents(ctx context.Context, query string, args []interface{}) ([]bus.Event, error) {
 	rows, err := r.db.QueryContext(ctx, query, args...)
@@ -228,6 +257,7 @@ func (r *PostgresEventRepository) queryEvents(ctx context.Context, query string,
 			&event.ID,
 			&event.Type,
 			&event.Source,
+			&event.Version,
 			&event.Timestamp,
 			&dataJSON,
 			&metadataJSON,
@@ -253,7 +283,8 @@ func (r *PostgresEventRepository) queryEvents(ctx context.Context, query string,
 	return events, nil
 }
 
-// CreateEventsTable creates the events table if it doesn't exist.
+// CreateEventsTable creates the events table if it doesn't exist, and adds
+// the version column to tables created before events were versioned.
 func (r *PostgresEventRepository) CreateEventsTable(ctx context.Context) error {
 	query := `
 		CREATE TABLE IF NOT EXISTS events (
@@ -265,6 +296,9 @@ func (r *PostgresEventRepository) CreateEventsTable(ctx context.Context) error {
 			metadata JSONB NOT NULL DEFAULT '{}'
 		);
 
+		-- Events stored before versioning are of the first version
+		ALTER TABLE events ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+
 		CREATE INDEX IF NOT EXISTS idx_events_type ON events(type);
 		CREATE INDEX IF NOT EXISTS idx_events_source ON events(source);
 		CREATE INDEX IF NOT EXISTS idx_events_timestamp ON events(timestamp);
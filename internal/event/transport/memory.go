@@ -0,0 +1,250 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bargom/codeai/internal/event/bus"
+)
+
+// MemoryBroker holds the stream of in-memory transports. Transports on one
+// broker behave like instances sharing a Redis server.
+type MemoryBroker struct {
+	mu     sync.Mutex
+	events []bus.Event // The offset of events[i] is i+1
+	groups map[string]*memoryGroup
+	dead   []DeadLetter
+	notify chan struct{} // Closed and replaced when events are published
+}
+
+type memoryGroup struct {
+	next    int                     // Index of the next event to deliver
+	pending map[int]*memoryDelivery // Unacknowledged deliveries, by index
+}
+
+type memoryDelivery struct {
+	attempts    int
+	deliveredAt time.Time
+}
+
+// NewMemoryBroker creates an empty broker.
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{
+		groups: make(map[string]*memoryGroup),
+		notify: make(chan struct{}),
+	}
+}
+
+// Transport creates a transport on the broker.
+func (b *MemoryBroker) Transport(cfg Config, opts ...Option) *Memory {
+	return &Memory{
+		broker: b,
+		config: cfg,
+		logger: newOptions(opts).logger,
+		now:    time.Now,
+		done:   make(chan struct{}),
+	}
+}
+
+// Memory implements bus.Transport in memory. Events are lost when the
+// process exits.
+type Memory struct {
+	broker    *MemoryBroker
+	config    Config
+	logger    bus.Logger
+	now       func() time.Time
+	wg        sync.WaitGroup
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewMemory creates an in-memory transport on a new broker.
+func NewMemory(cfg Config, opts ...Option) *Memory {
+	return NewMemoryBroker().Transport(cfg, opts...)
+}
+
+// Broker returns the broker of the transport.
+func (m *Memory) Broker() *MemoryBroker {
+	return m.broker
+}
+
+// DeadLetters returns up to count dead-lettered events, oldest first.
+func (m *Memory) DeadLetters(ctx context.Context, count int) ([]DeadLetter, error) {
+	b := m.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	count = min(count, len(b.dead))
+	return append([]DeadLetter(nil), b.dead[:count]...), nil
+}
+
+// Publish implements bus.Transport.
+func (m *Memory) Publish(ctx context.Context, event bus.Event) error {
+	b := m.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.events = append(b.events, event)
+	close(b.notify)
+	b.notify = make(chan struct{})
+	return nil
+}
+
+// Consume implements bus.Transport. A group created by Consume starts at
+// the events published after it was created.
+func (m *Memory) Consume(ctx context.Context, handler bus.DeliveryHandler) error {
+	b := m.broker
+	b.mu.Lock()
+	if _, ok := b.groups[m.config.Group]; !ok {
+		b.groups[m.config.Group] = &memoryGroup{next: len(b.events), pending: make(map[int]*memoryDelivery)}
+	}
+	b.mu.Unlock()
+
+	m.wg.Add(1)
+	go m.consume(ctx, handler)
+	return nil
+}
+
+func (m *Memory) consume(ctx context.Context, handler bus.DeliveryHandler) {
+	defer m.wg.Done()
+
+	for {
+		deliveries, notify := m.claim()
+		for _, d := range deliveries {
+			if err := handler(ctx, d); err != nil {
+				if m.logger != nil {
+					m.logger.Warn("event not acknowledged", "offset", d.Offset, "attempt", d.Attempt, "error", err.Error())
+				}
+				continue
+			}
+			m.ack(d.Offset)
+		}
+		if len(deliveries) > 0 {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-m.done:
+			return
+		case <-notify:
+		case <-time.After(m.pollInterval()):
+		}
+	}
+}
+
+// pollInterval is how often a consumer without new events checks for
+// events to deliver again.
+func (m *Memory) pollInterval() time.Duration {
+	if m.config.RetryAfter > 0 && m.config.RetryAfter < time.Second {
+		return m.config.RetryAfter
+	}
+	return time.Second
+}
+
+// claim returns the events to deliver to the consumer: events whose
+// delivery is due again, then new events. Events delivered too often are
+// dead-lettered instead.
+func (m *Memory) claim() ([]bus.Delivery, <-chan struct{}) {
+	b := m.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	group := b.groups[m.config.Group]
+	now := m.now()
+	var deliveries []bus.Delivery
+
+	for i, p := range group.pending {
+		if len(deliveries) >= m.config.BatchSize {
+			break
+		}
+		if now.Sub(p.deliveredAt) < m.config.RetryAfter {
+			continue
+		}
+		if p.attempts >= m.config.MaxDeliveries {
+			b.dead = append(b.dead, DeadLetter{
+				Offset:   offset(i),
+				Event:    b.events[i],
+				Attempts: p.attempts,
+				Reason:   "max deliveries exceeded",
+			})
+			delete(group.pending, i)
+			if m.logger != nil {
+				m.logger.Error("event dead-lettered", "offset", offset(i), "attempts", p.attempts)
+			}
+			continue
+		}
+		p.attempts++
+		p.deliveredAt = now
+		deliveries = append(deliveries, bus.Delivery{Event: b.events[i], Offset: offset(i), Attempt: p.attempts})
+	}
+
+	for group.next < len(b.events) && len(deliveries) < m.config.BatchSize {
+		i := group.next
+		group.pending[i] = &memoryDelivery{attempts: 1, deliveredAt: now}
+		group.next++
+		deliveries = append(deliveries, bus.Delivery{Event: b.events[i], Offset: offset(i), Attempt: 1})
+	}
+	return deliveries, b.notify
+}
+
+func (m *Memory) ack(off string) {
+	i, err := parseOffset(off)
+	if err != nil {
+		return
+	}
+	b := m.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.groups[m.config.Group].pending, i)
+}
+
+// Replay implements bus.Transport. Offsets are event numbers, starting at 1.
+func (m *Memory) Replay(ctx context.Context, from string, handler bus.DeliveryHandler) error {
+	start := 0
+	if from != "" {
+		i, err := parseOffset(from)
+		if err != nil {
+			return err
+		}
+		start = max(i, 0)
+	}
+
+	b := m.broker
+	b.mu.Lock()
+	events := append([]bus.Event(nil), b.events...)
+	b.mu.Unlock()
+
+	for i := start; i < len(events); i++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := handler(ctx, bus.Delivery{Event: events[i], Offset: offset(i), Attempt: 1}); err != nil {
+			return fmt.Errorf("replaying event at offset %s: %w", offset(i), err)
+		}
+	}
+	return nil
+}
+
+// Close implements bus.Transport.
+func (m *Memory) Close() error {
+	m.closeOnce.Do(func() { close(m.done) })
+	m.wg.Wait()
+	return nil
+}
+
+// offset returns the offset of the event at index i.
+func offset(i int) string {
+	return strconv.Itoa(i + 1)
+}
+
+// parseOffset returns the index of the event at an offset.
+func parseOffset(off string) (int, error) {
+	n, err := strconv.Atoi(off)
+	if err != nil {
+		return 0, fmt.Errorf("invalid offset %q", off)
+	}
+	return n - 1, nil
+}
@@ -0,0 +1,276 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bargom/codeai/internal/event/bus"
+)
+
+func testConfig(group string) Config {
+	cfg := DefaultConfig()
+	cfg.Group = group
+	cfg.RetryAfter = 10 * time.Millisecond
+	cfg.MaxDeliveries = 3
+	return cfg
+}
+
+func newEvent(typ bus.EventType, data map[string]interface{}) bus.Event {
+	return bus.Event{ID: uuid.NewString(), Type: typ, Timestamp: time.Now(), Data: data}
+}
+
+// recorder collects the events delivered to it.
+type recorder struct {
+	mu     sync.Mutex
+	events []bus.Event
+}
+
+func (r *recorder) Handle(ctx context.Context, event bus.Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *recorder) ids() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ids := make([]string, len(r.events))
+	for i, event := range r.events {
+		ids[i] = event.ID
+	}
+	return ids
+}
+
+func TestMemory_ConsumeAcknowledges(t *testing.T) {
+	m := NewMemory(testConfig("g"))
+	defer m.Close()
+
+	var mu sync.Mutex
+	var deliveries []bus.Delivery
+	require.NoError(t, m.Consume(context.Background(), func(ctx context.Context, d bus.Delivery) error {
+		mu.Lock()
+		defer mu.Unlock()
+		deliveries = append(deliveries, d)
+		return nil
+	}))
+
+	require.NoError(t, m.Publish(context.Background(), newEvent("user.created", nil)))
+	require.NoError(t, m.Publish(context.Background(), newEvent("user.updated", nil)))
+
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(deliveries) == 2
+	}, time.Second, 5*time.Millisecond)
+
+	// Acknowledged events are not delivered again.
+	time.Sleep(50 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, deliveries, 2)
+	assert.Equal(t, "1", deliveries[0].Offset)
+	assert.Equal(t, bus.EventType("user.updated"), deliveries[1].Event.Type)
+	assert.Equal(t, 1, deliveries[1].Attempt)
+}
+
+func TestMemory_RedeliversThenDeadLetters(t *testing.T) {
+	m := NewMemory(testConfig("g"))
+	defer m.Close()
+
+	var mu sync.Mutex
+	var attempts []int
+	require.NoError(t, m.Consume(context.Background(), func(ctx context.Context, d bus.Delivery) error {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts = append(attempts, d.Attempt)
+		return errors.New("handler failed")
+	}))
+
+	event := newEvent("user.created", nil)
+	require.NoError(t, m.Publish(context.Background(), event))
+
+	var dead []DeadLetter
+	assert.Eventually(t, func() bool {
+		dead, _ = m.DeadLetters(context.Background(), 10)
+		return len(dead) == 1
+	}, time.Second, 5*time.Millisecond)
+
+	assert.Equal(t, event.ID, dead[0].Event.ID)
+	assert.Equal(t, "1", dead[0].Offset)
+	assert.Equal(t, 3, dead[0].Attempts)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []int{1, 2, 3}, attempts)
+}
+
+func TestMemory_Replay(t *testing.T) {
+	m := NewMemory(testConfig("g"))
+	defer m.Close()
+
+	for _, typ := range []bus.EventType{"a", "b", "c"} {
+		require.NoError(t, m.Publish(context.Background(), newEvent(typ, nil)))
+	}
+
+	var types []bus.EventType
+	collect := func(ctx context.Context, d bus.Delivery) error {
+		types = append(types, d.Event.Type)
+		return nil
+	}
+
+	require.NoError(t, m.Replay(context.Background(), "", collect))
+	assert.Equal(t, []bus.EventType{"a", "b", "c"}, types)
+
+	types = nil
+	require.NoError(t, m.Replay(context.Background(), "2", collect))
+	assert.Equal(t, []bus.EventType{"b", "c"}, types)
+
+	err := m.Replay(context.Background(), "1", func(ctx context.Context, d bus.Delivery) error {
+		return errors.New("stop")
+	})
+	assert.ErrorContains(t, err, "offset 1")
+
+	assert.Error(t, m.Replay(context.Background(), "x", collect))
+}
+
+func TestMemory_EventBusesShareEvents(t *testing.T) {
+	broker := NewMemoryBroker()
+
+	newBus := func(group string) (*bus.EventBus, *recorder) {
+		eb := bus.NewEventBus(nil)
+		rec := &recorder{}
+		eb.Subscribe(bus.AllEvents, rec)
+		require.NoError(t, eb.Connect(context.Background(), broker.Transport(testConfig(group))))
+		t.Cleanup(eb.Close)
+		return eb, rec
+	}
+
+	// Two instances of one service share a group; another service has its own.
+	first, firstRec := newBus("orders")
+	_, secondRec := newBus("orders")
+	_, otherRec := newBus("billing")
+
+	var published []string
+	for i := 0; i < 10; i++ {
+		event := newEvent("order.placed", nil)
+		published = append(published, event.ID)
+		require.NoError(t, first.Publish(context.Background(), event))
+	}
+
+	assert.Eventually(t, func() bool {
+		return len(firstRec.ids())+len(secondRec.ids()) == 10 && len(otherRec.ids()) == 10
+	}, time.Second, 5*time.Millisecond)
+
+	time.Sleep(50 * time.Millisecond)
+	assert.ElementsMatch(t, published, append(firstRec.ids(), secondRec.ids()...))
+	assert.Equal(t, published, otherRec.ids())
+}
+
+func TestMemory_ReplayedEventsStayInProcess(t *testing.T) {
+	broker := NewMemoryBroker()
+
+	local := bus.NewEventBus(nil)
+	localRec := &recorder{}
+	local.Subscribe(bus.AllEvents, localRec)
+	require.NoError(t, local.Connect(context.Background(), broker.Transport(testConfig("orders"))))
+	t.Cleanup(local.Close)
+
+	remote := bus.NewEventBus(nil)
+	remoteRec := &recorder{}
+	remote.Subscribe(bus.AllEvents, remoteRec)
+	require.NoError(t, remote.Connect(context.Background(), broker.Transport(testConfig("billing"))))
+	t.Cleanup(remote.Close)
+
+	event := newEvent("order.placed", nil)
+	require.NoError(t, local.Publish(bus.WithReplay(context.Background()), event))
+
+	assert.Equal(t, []string{event.ID}, localRec.ids(), "replayed events are delivered synchronously")
+	time.Sleep(50 * time.Millisecond)
+	assert.Empty(t, remoteRec.ids())
+}
+
+func TestMemory_ConsumedEventsAreUpcast(t *testing.T) {
+	broker := NewMemoryBroker()
+
+	// An instance still publishing version 1, and one that declares version 2
+	old := bus.NewEventBus(nil)
+	require.NoError(t, old.Connect(context.Background(), broker.Transport(testConfig("legacy"))))
+	t.Cleanup(old.Close)
+
+	current := bus.NewEventBus(nil)
+	rec := &recorder{}
+	current.Subscribe(bus.AllEvents, rec)
+	current.UseUpcaster(bus.UpcasterFunc(func(event bus.Event) (bus.Event, error) {
+		if event.Version > 2 {
+			return event, errors.New("unknown version")
+		}
+		event.Version = 2
+		return event, nil
+	}))
+	require.NoError(t, current.Connect(context.Background(), broker.Transport(testConfig("orders"))))
+	t.Cleanup(current.Close)
+
+	v1 := newEvent("order.placed", nil)
+	v1.Version = 1
+	unknown := newEvent("order.placed", nil)
+	unknown.Version = 3
+	require.NoError(t, old.Publish(context.Background(), unknown))
+	require.NoError(t, old.Publish(context.Background(), v1))
+
+	assert.Eventually(t, func() bool { return len(rec.ids()) == 1 }, time.Second, 5*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, []string{v1.ID}, rec.ids(), "events that cannot be upcast are dropped")
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	assert.Equal(t, 2, rec.events[0].Version)
+}
+
+func TestMemory_FailingSubscriberRedeliversThenDeadLetters(t *testing.T) {
+	m := NewMemory(testConfig("orders"))
+
+	eb := bus.NewEventBus(nil)
+	rec := &recorder{}
+	eb.Subscribe(bus.AllEvents, rec)
+
+	// Fails the first delivery of flaky events and every delivery of
+	// poison events
+	var mu sync.Mutex
+	attempts := make(map[string]int)
+	eb.Subscribe(bus.AllEvents, bus.SubscriberFunc(func(ctx context.Context, event bus.Event) error {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts[event.ID]++
+		if event.Type == "order.poison" || attempts[event.ID] == 1 {
+			return errors.New("handler failed")
+		}
+		return nil
+	}))
+	require.NoError(t, eb.Connect(context.Background(), m))
+	t.Cleanup(eb.Close)
+
+	flaky := newEvent("order.placed", nil)
+	poison := newEvent("order.poison", nil)
+	require.NoError(t, eb.Publish(context.Background(), flaky))
+	require.NoError(t, eb.Publish(context.Background(), poison))
+
+	var dead []DeadLetter
+	assert.Eventually(t, func() bool {
+		dead, _ = m.DeadLetters(context.Background(), 10)
+		return len(dead) == 1
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, poison.ID, dead[0].Event.ID)
+	assert.Equal(t, 3, dead[0].Attempts)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 2, attempts[flaky.ID], "a failed delivery is redelivered until it succeeds")
+	assert.Equal(t, 3, attempts[poison.ID])
+}
@@ -0,0 +1,293 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/bargom/codeai/internal/event/bus"
+)
+
+// RedisStreams implements bus.Transport on a Redis stream. Instances in the
+// same consumer group share the events of the stream; each event is
+// acknowledged once handled, and events left pending by a failed or
+// crashed consumer are claimed by another after RetryAfter.
+type RedisStreams struct {
+	client redis.UniversalClient
+	config Config
+	logger bus.Logger
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	mu     sync.Mutex
+}
+
+// NewRedisStreams creates a Redis Streams transport. The transport owns
+// client and closes it on Close.
+func NewRedisStreams(client redis.UniversalClient, cfg Config, opts ...Option) *RedisStreams {
+	return &RedisStreams{
+		client: client,
+		config: cfg,
+		logger: newOptions(opts).logger,
+	}
+}
+
+// Publish implements bus.Transport.
+func (r *RedisStreams) Publish(ctx context.Context, event bus.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("encoding event: %w", err)
+	}
+
+	args := &redis.XAddArgs{
+		Stream: r.config.Stream,
+		Values: map[string]interface{}{"event": data},
+	}
+	if r.config.MaxLen > 0 {
+		args.MaxLen = r.config.MaxLen
+		args.Approx = true
+	}
+	if err := r.client.XAdd(ctx, args).Err(); err != nil {
+		return fmt.Errorf("adding event to stream %s: %w", r.config.Stream, err)
+	}
+	return nil
+}
+
+// Consume implements bus.Transport. A group created by Consume starts at
+// the events added after it was created.
+func (r *RedisStreams) Consume(ctx context.Context, handler bus.DeliveryHandler) error {
+	err := r.client.XGroupCreateMkStream(ctx, r.config.Stream, r.config.Group, "$").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("creating consumer group %s: %w", r.config.Group, err)
+	}
+
+	r.mu.Lock()
+	ctx, cancel := context.WithCancel(ctx)
+	prev := r.cancel
+	r.cancel = func() {
+		if prev != nil {
+			prev()
+		}
+		cancel()
+	}
+	r.mu.Unlock()
+
+	r.wg.Add(1)
+	go r.consume(ctx, handler)
+	return nil
+}
+
+func (r *RedisStreams) consume(ctx context.Context, handler bus.DeliveryHandler) {
+	defer r.wg.Done()
+
+	for ctx.Err() == nil {
+		if err := r.retryPending(ctx, handler); err != nil {
+			r.logError(ctx, "failed to retry pending events", err)
+		}
+
+		streams, err := r.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    r.config.Group,
+			Consumer: r.config.Consumer,
+			Streams:  []string{r.config.Stream, ">"},
+			Count:    int64(r.config.BatchSize),
+			Block:    r.config.Block,
+		}).Result()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			r.logError(ctx, "failed to read events", err)
+			r.sleep(ctx, time.Second)
+			continue
+		}
+
+		for _, stream := range streams {
+			for _, msg := range stream.Messages {
+				r.handle(ctx, handler, msg, 1)
+			}
+		}
+	}
+}
+
+// retryPending claims the events of the group left unacknowledged for
+// RetryAfter, by any consumer, and delivers them again. Events delivered
+// MaxDeliveries times are dead-lettered instead.
+func (r *RedisStreams) retryPending(ctx context.Context, handler bus.DeliveryHandler) error {
+	pending, err := r.client.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: r.config.Stream,
+		Group:  r.config.Group,
+		Idle:   r.config.RetryAfter,
+		Start:  "-",
+		End:    "+",
+		Count:  int64(r.config.BatchSize),
+	}).Result()
+	if err != nil {
+		return err
+	}
+
+	for _, p := range pending {
+		// Claiming fails for events another consumer claimed first, and
+		// counts as a delivery.
+		msgs, err := r.client.XClaim(ctx, &redis.XClaimArgs{
+			Stream:   r.config.Stream,
+			Group:    r.config.Group,
+			Consumer: r.config.Consumer,
+			MinIdle:  r.config.RetryAfter,
+			Messages: []string{p.ID},
+		}).Result()
+		if err != nil {
+			return err
+		}
+		if len(msgs) == 0 {
+			continue
+		}
+
+		if int(p.RetryCount) >= r.config.MaxDeliveries {
+			event, _ := decodeEvent(msgs[0])
+			r.deadLetter(ctx, msgs[0].ID, event, int(p.RetryCount), "max deliveries exceeded")
+			continue
+		}
+		r.handle(ctx, handler, msgs[0], int(p.RetryCount)+1)
+	}
+	return nil
+}
+
+// handle delivers an event and acknowledges it if handler succeeds. Events
+// that cannot be decoded are dead-lettered at once.
+func (r *RedisStreams) handle(ctx context.Context, handler bus.DeliveryHandler, msg redis.XMessage, attempt int) {
+	event, err := decodeEvent(msg)
+	if err != nil {
+		r.deadLetter(ctx, msg.ID, event, attempt, err.Error())
+		return
+	}
+
+	if err := handler(ctx, bus.Delivery{Event: event, Offset: msg.ID, Attempt: attempt}); err != nil {
+		if r.logger != nil {
+			r.logger.Warn("event not acknowledged", "offset", msg.ID, "attempt", attempt, "error", err.Error())
+		}
+		return
+	}
+	if err := r.client.XAck(ctx, r.config.Stream, r.config.Group, msg.ID).Err(); err != nil {
+		r.logError(ctx, "failed to acknowledge event", err)
+	}
+}
+
+// deadLetter moves an event to the dead-letter stream and acknowledges it.
+func (r *RedisStreams) deadLetter(ctx context.Context, id string, event bus.Event, attempts int, reason string) {
+	data, _ := json.Marshal(event)
+	err := r.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: r.config.DeadLetterStream(),
+		Values: map[string]interface{}{
+			"offset":   id,
+			"event":    data,
+			"attempts": attempts,
+			"reason":   reason,
+		},
+	}).Err()
+	if err != nil {
+		r.logError(ctx, "failed to dead-letter event", err)
+		return
+	}
+	if err := r.client.XAck(ctx, r.config.Stream, r.config.Group, id).Err(); err != nil {
+		r.logError(ctx, "failed to acknowledge dead-lettered event", err)
+	}
+	if r.logger != nil {
+		r.logger.Error("event dead-lettered", "offset", id, "attempts", attempts, "reason", reason)
+	}
+}
+
+// DeadLetters returns up to count dead-lettered events, oldest first.
+func (r *RedisStreams) DeadLetters(ctx context.Context, count int) ([]DeadLetter, error) {
+	msgs, err := r.client.XRangeN(ctx, r.config.DeadLetterStream(), "-", "+", int64(count)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("reading dead-letter stream: %w", err)
+	}
+
+	letters := make([]DeadLetter, 0, len(msgs))
+	for _, msg := range msgs {
+		event, _ := decodeEvent(msg)
+		offset, _ := msg.Values["offset"].(string)
+		reason, _ := msg.Values["reason"].(string)
+		attempts, _ := strconv.Atoi(fmt.Sprint(msg.Values["attempts"]))
+		letters = append(letters, DeadLetter{Offset: offset, Event: event, Attempts: attempts, Reason: reason})
+	}
+	return letters, nil
+}
+
+// Replay implements bus.Transport. Offsets are Redis stream entry IDs.
+func (r *RedisStreams) Replay(ctx context.Context, from string, handler bus.DeliveryHandler) error {
+	start := from
+	if start == "" {
+		start = "-"
+	}
+	count := int64(r.config.BatchSize)
+
+	for {
+		msgs, err := r.client.XRangeN(ctx, r.config.Stream, start, "+", count).Result()
+		if err != nil {
+			return fmt.Errorf("reading stream %s: %w", r.config.Stream, err)
+		}
+
+		for _, msg := range msgs {
+			event, err := decodeEvent(msg)
+			if err != nil {
+				if r.logger != nil {
+					r.logger.Warn("skipping undecodable event", "offset", msg.ID, "error", err.Error())
+				}
+				continue
+			}
+			if err := handler(ctx, bus.Delivery{Event: event, Offset: msg.ID, Attempt: 1}); err != nil {
+				return fmt.Errorf("replaying event at offset %s: %w", msg.ID, err)
+			}
+		}
+
+		if int64(len(msgs)) < count {
+			return nil
+		}
+		start = "(" + msgs[len(msgs)-1].ID
+	}
+}
+
+// Close implements bus.Transport.
+func (r *RedisStreams) Close() error {
+	r.mu.Lock()
+	if r.cancel != nil {
+		r.cancel()
+	}
+	r.mu.Unlock()
+
+	r.wg.Wait()
+	return r.client.Close()
+}
+
+func (r *RedisStreams) logError(ctx context.Context, msg string, err error) {
+	if r.logger != nil && ctx.Err() == nil {
+		r.logger.Error(msg, "stream", r.config.Stream, "error", err.Error())
+	}
+}
+
+func (r *RedisStreams) sleep(ctx context.Context, d time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(d):
+	}
+}
+
+// decodeEvent decodes the event of a stream entry.
+func decodeEvent(msg redis.XMessage) (bus.Event, error) {
+	var event bus.Event
+	data, ok := msg.Values["event"].(string)
+	if !ok {
+		return event, fmt.Errorf("stream entry %s has no event", msg.ID)
+	}
+	if err := json.Unmarshal([]byte(data), &event); err != nil {
+		return event, fmt.Errorf("decoding event of stream entry %s: %w", msg.ID, err)
+	}
+	return event, nil
+}
@@ -0,0 +1,103 @@
+//go:build integration
+
+package transport
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	tcredis "github.com/testcontainers/testcontainers-go/modules/redis"
+
+	"github.com/bargom/codeai/internal/event/bus"
+)
+
+func TestRedisStreams_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	container, err := tcredis.Run(ctx, "redis:7-alpine")
+	require.NoError(t, err)
+	defer container.Terminate(context.Background())
+	endpoint, err := container.Endpoint(ctx, "")
+	require.NoError(t, err)
+
+	newTransport := func(group string) *RedisStreams {
+		cfg := testConfig(group)
+		cfg.Block = 50 * time.Millisecond
+		return NewRedisStreams(redis.NewClient(&redis.Options{Addr: endpoint}), cfg)
+	}
+
+	t.Run("shares events between buses", func(t *testing.T) {
+		newBus := func() (*bus.EventBus, *recorder) {
+			eb := bus.NewEventBus(nil)
+			rec := &recorder{}
+			eb.Subscribe(bus.AllEvents, rec)
+			require.NoError(t, eb.Connect(ctx, newTransport("orders")))
+			t.Cleanup(eb.Close)
+			return eb, rec
+		}
+		first, firstRec := newBus()
+		_, secondRec := newBus()
+
+		var published []string
+		for i := 0; i < 10; i++ {
+			event := newEvent("order.placed", map[string]interface{}{"n": float64(i)})
+			published = append(published, event.ID)
+			require.NoError(t, first.Publish(ctx, event))
+		}
+
+		require.Eventually(t, func() bool {
+			return len(firstRec.ids())+len(secondRec.ids()) == 10
+		}, 5*time.Second, 20*time.Millisecond)
+		assert.ElementsMatch(t, published, append(firstRec.ids(), secondRec.ids()...))
+
+		replay := newTransport("orders")
+		defer replay.Close()
+		var replayed []string
+		require.NoError(t, replay.Replay(ctx, "", func(ctx context.Context, d bus.Delivery) error {
+			replayed = append(replayed, d.Event.ID)
+			return nil
+		}))
+		assert.Equal(t, published, replayed)
+	})
+
+	t.Run("dead-letters unacknowledged events", func(t *testing.T) {
+		tr := newTransport("failing")
+		defer tr.Close()
+
+		var mu sync.Mutex
+		var attempts []int
+		require.NoError(t, tr.Consume(ctx, func(ctx context.Context, d bus.Delivery) error {
+			mu.Lock()
+			defer mu.Unlock()
+			attempts = append(attempts, d.Attempt)
+			return errors.New("handler failed")
+		}))
+
+		event := newEvent("order.failed", nil)
+		require.NoError(t, tr.Publish(ctx, event))
+
+		var dead []DeadLetter
+		require.Eventually(t, func() bool {
+			dead, err = tr.DeadLetters(ctx, 10)
+			require.NoError(t, err)
+			return len(dead) == 1
+		}, 5*time.Second, 20*time.Millisecond)
+		assert.Equal(t, event.ID, dead[0].Event.ID)
+		assert.Equal(t, 3, dead[0].Attempts)
+
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Equal(t, []int{1, 2, 3}, attempts)
+	})
+}
@@ -0,0 +1,85 @@
+// Package transport provides bus.Transport implementations that carry
+// events between instances: Redis Streams, and an in-memory transport for
+// tests and single instances.
+package transport
+
+import (
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bargom/codeai/internal/event/bus"
+)
+
+// Config holds configuration for a transport.
+type Config struct {
+	Stream        string        // Name of the stream events are appended to
+	Group         string        // Consumer group; each event goes to one consumer of the group
+	Consumer      string        // Name of this consumer within the group
+	MaxDeliveries int           // Delivery attempts before an event is dead-lettered
+	RetryAfter    time.Duration // How long an unacknowledged event waits before it is delivered again
+	BatchSize     int           // Max number of events read at once
+	Block         time.Duration // How long a read waits for new events
+	MaxLen        int64         // Approximate maximum stream length; 0 keeps all events
+}
+
+// DefaultConfig returns a default transport configuration. Instances share
+// the default group, so each event is handled by one of them.
+func DefaultConfig() Config {
+	return Config{
+		Stream:        "codeai:events",
+		Group:         "codeai",
+		Consumer:      defaultConsumer(),
+		MaxDeliveries: 5,
+		RetryAfter:    30 * time.Second,
+		BatchSize:     100,
+		Block:         2 * time.Second,
+	}
+}
+
+// defaultConsumer names the consumer after the host, made unique per
+// process.
+func defaultConsumer() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "codeai"
+	}
+	return host + "-" + uuid.NewString()[:8]
+}
+
+// DeadLetterStream returns the name of the stream dead-lettered events are
+// moved to.
+func (c Config) DeadLetterStream() string {
+	return c.Stream + ":dead"
+}
+
+// DeadLetter is an event that could not be delivered.
+type DeadLetter struct {
+	Offset   string // Offset of the event in the stream
+	Event    bus.Event
+	Attempts int
+	Reason   string
+}
+
+// Option configures a transport.
+type Option func(*options)
+
+type options struct {
+	logger bus.Logger
+}
+
+// WithLogger sets the logger of a transport.
+func WithLogger(logger bus.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+func newOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
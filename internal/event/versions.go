@@ -0,0 +1,152 @@
+package event
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/bargom/codeai/internal/ast"
+	"github.com/bargom/codeai/internal/event/bus"
+	"github.com/bargom/codeai/internal/workflow/expr"
+)
+
+// ErrUnknownVersion is returned for events of a version their event type
+// does not declare.
+var ErrUnknownVersion = errors.New("unknown event version")
+
+// EventVersion is one declared version of a registered event.
+type EventVersion struct {
+	Version  int
+	Schema   *EventSchema
+	Breaking bool
+	// Upcaster migrates payloads of the previous version to this one; nil
+	// if the version keeps the previous version's payloads as they are.
+	Upcaster *Upcaster
+}
+
+// Upcaster migrates event payloads from one version to the next.
+type Upcaster struct {
+	From     int
+	mappings []upcastMapping
+}
+
+type upcastMapping struct {
+	field   string
+	program *expr.Program
+}
+
+// NewUpcaster compiles the upcast declared by decl.
+func NewUpcaster(decl *ast.EventUpcast) (*Upcaster, error) {
+	u := &Upcaster{From: decl.From}
+	for _, m := range decl.Mappings {
+		program, err := expr.CompileWithVars(m.Expr, ast.UpcastVar)
+		if err != nil {
+			return nil, fmt.Errorf("upcast of field '%s': %w", m.Field, err)
+		}
+		u.mappings = append(u.mappings, upcastMapping{field: m.Field, program: program})
+	}
+	return u, nil
+}
+
+// Apply returns payload migrated to the next version: a copy of payload
+// with the mapped fields set to their expressions' values. Fields that
+// schema does not declare are dropped if it declares any.
+func (u *Upcaster) Apply(payload map[string]any, schema *EventSchema) (map[string]any, error) {
+	out := copyPayload(payload)
+	env := expr.Env{Vars: map[string]any{ast.UpcastVar: payload}}
+	for _, m := range u.mappings {
+		value, err := m.program.Eval(env)
+		if err != nil {
+			return nil, fmt.Errorf("upcast of field '%s': %w", m.field, err)
+		}
+		out[m.field] = value
+	}
+	return projectPayload(out, schema), nil
+}
+
+// projectPayload drops the fields of payload that schema does not declare.
+func projectPayload(payload map[string]any, schema *EventSchema) map[string]any {
+	if schema == nil || len(schema.Fields) == 0 {
+		return payload
+	}
+	for k := range payload {
+		if _, ok := schema.Fields[k]; !ok {
+			delete(payload, k)
+		}
+	}
+	return payload
+}
+
+// versionNumbers returns the declared versions of event in ascending order.
+func (e *RegisteredEvent) versionNumbers() []int {
+	versions := make([]int, 0, len(e.Versions))
+	for v := range e.Versions {
+		versions = append(versions, v)
+	}
+	sort.Ints(versions)
+	return versions
+}
+
+// schemaFor returns the schema of version, or of the latest version if
+// version is zero.
+func (e *RegisteredEvent) schemaFor(version int) (*EventSchema, error) {
+	if version == 0 {
+		return e.Schema, nil
+	}
+	v, ok := e.Versions[version]
+	if !ok {
+		return nil, fmt.Errorf("%w %d of event '%s'", ErrUnknownVersion, version, e.Name)
+	}
+	return v.Schema, nil
+}
+
+// Upcast migrates event to the latest version of its registered event,
+// running the upcasters of every version after the event's. Events of
+// unregistered types are returned unchanged and events without a version
+// are taken to be of the latest version. Stored events are upcast as they
+// are read, so handlers and subscribers only see the latest version.
+func (r *EventRegistry) Upcast(event bus.Event) (bus.Event, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	registeredEvent, exists := r.events[string(event.Type)]
+	if !exists {
+		return event, nil
+	}
+	if event.Version == 0 || event.Version == registeredEvent.Version {
+		event.Version = registeredEvent.Version
+		return event, nil
+	}
+	if _, ok := registeredEvent.Versions[event.Version]; !ok {
+		return event, fmt.Errorf("%w %d of event '%s'", ErrUnknownVersion, event.Version, event.Type)
+	}
+
+	data := event.Data
+	for _, version := range registeredEvent.versionNumbers() {
+		if version <= event.Version {
+			continue
+		}
+		next := registeredEvent.Versions[version]
+		if next.Upcaster == nil {
+			data = projectPayload(copyPayload(data), next.Schema)
+			continue
+		}
+		migrated, err := next.Upcaster.Apply(data, next.Schema)
+		if err != nil {
+			return event, fmt.Errorf("upcasting event '%s' to version %d: %w", event.Type, version, err)
+		}
+		data = migrated
+	}
+
+	event.Data = data
+	event.Version = registeredEvent.Version
+	return event, nil
+}
+
+func copyPayload(payload map[string]any) map[string]any {
+	out := make(map[string]any, len(payload))
+	for k, v := range payload {
+		out[k] = v
+	}
+	return out
+}
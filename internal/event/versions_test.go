@@ -0,0 +1,125 @@
+package event
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bargom/codeai/internal/ast"
+	"github.com/bargom/codeai/internal/event/bus"
+)
+
+func eventSchema(fields ...string) *ast.EventSchema {
+	schema := &ast.EventSchema{}
+	for i := 0; i < len(fields); i += 2 {
+		schema.Fields = append(schema.Fields, &ast.EventSchemaField{Name: fields[i], FieldType: fields[i+1]})
+	}
+	return schema
+}
+
+// newVersionedRegistry registers three versions of user.created: v2 renames
+// name to full_name and v3 adds an optional email.
+func newVersionedRegistry(t *testing.T, dispatcher Dispatcher) *EventRegistry {
+	t.Helper()
+	r := NewEventRegistry(dispatcher)
+	decls := []*ast.EventDecl{
+		{
+			Name:    "user.created",
+			Version: 3,
+			Schema:  eventSchema("user_id", "string", "full_name", "string", "email", "string"),
+		},
+		{
+			Name:    "user.created",
+			Version: 1,
+			Schema:  eventSchema("user_id", "string", "name", "string"),
+		},
+		{
+			Name:     "user.created",
+			Version:  2,
+			Breaking: true,
+			Schema:   eventSchema("user_id", "string", "full_name", "string"),
+			Upcast: &ast.EventUpcast{From: 1, Mappings: []*ast.EventUpcastMapping{
+				{Field: "full_name", Expr: "trim(payload.name)"},
+			}},
+		},
+	}
+	for _, decl := range decls {
+		require.NoError(t, r.RegisterEventFromAST(decl))
+	}
+	return r
+}
+
+func TestEventRegistry_RegisterVersions(t *testing.T) {
+	r := newVersionedRegistry(t, nil)
+
+	registered, ok := r.GetEvent("user.created")
+	require.True(t, ok)
+	assert.Equal(t, 3, registered.Version, "the latest version is current whatever the declaration order")
+	assert.Contains(t, registered.Schema.Fields, "email")
+	assert.Len(t, registered.Versions, 3)
+	assert.Equal(t, 1, r.EventCount())
+
+	err := r.RegisterEventFromAST(&ast.EventDecl{Name: "user.created", Version: 2})
+	assert.ErrorContains(t, err, "version 2 is already registered")
+
+	err = r.RegisterEventFromAST(&ast.EventDecl{Name: "user.deleted", Version: 2, Upcast: &ast.EventUpcast{
+		From:     1,
+		Mappings: []*ast.EventUpcastMapping{{Field: "reason", Expr: "payload.("}},
+	}})
+	assert.ErrorContains(t, err, "upcast of field 'reason'")
+}
+
+func TestEventRegistry_Upcast(t *testing.T) {
+	r := newVersionedRegistry(t, nil)
+
+	upcast, err := r.Upcast(bus.Event{
+		ID:      "e1",
+		Type:    "user.created",
+		Version: 1,
+		Data:    map[string]interface{}{"user_id": "u-1", "name": " Ada Lovelace "},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, upcast.Version)
+	assert.Equal(t, map[string]interface{}{"user_id": "u-1", "full_name": "Ada Lovelace"}, upcast.Data,
+		"fields the new schema does not declare are dropped")
+
+	upcast, err = r.Upcast(bus.Event{Type: "user.created", Data: map[string]interface{}{"user_id": "u-1"}})
+	require.NoError(t, err)
+	assert.Equal(t, 3, upcast.Version, "events without a version are of the latest version")
+
+	upcast, err = r.Upcast(bus.Event{Type: "workflow.started", Data: map[string]interface{}{"id": "w-1"}})
+	require.NoError(t, err)
+	assert.Equal(t, 0, upcast.Version, "unregistered events are not versioned")
+
+	_, err = r.Upcast(bus.Event{Type: "user.created", Version: 4})
+	assert.ErrorIs(t, err, ErrUnknownVersion)
+}
+
+func TestEventRegistry_ValidateVersion(t *testing.T) {
+	r := newVersionedRegistry(t, nil)
+
+	v1 := bus.Event{Type: "user.created", Version: 1, Data: map[string]interface{}{"name": "Ada"}}
+	assert.NoError(t, r.Validate(v1))
+
+	v1.Data["name"] = 42
+	assert.Error(t, r.Validate(v1), "events are validated against the schema of their version")
+
+	assert.ErrorIs(t, r.Validate(bus.Event{Type: "user.created", Version: 7}), ErrUnknownVersion)
+}
+
+func TestEventRegistry_PublishesLatestVersion(t *testing.T) {
+	dispatcher := NewDispatcher()
+	r := newVersionedRegistry(t, dispatcher)
+
+	var received Event
+	dispatcher.Subscribe("user.created", func(_ context.Context, event Event) error {
+		received = event
+		return nil
+	})
+
+	require.NoError(t, r.EmitEvent(context.Background(), "user.created", map[string]interface{}{"user_id": "u-1"}))
+	assert.Equal(t, 3, received.Version)
+	assert.Equal(t, 3, ToBusEvent(received).Version)
+}
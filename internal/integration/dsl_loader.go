@@ -2,11 +2,14 @@
 package integration
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strings"
 	"sync"
 	"time"
 
@@ -186,6 +189,59 @@ func (r *IntegrationRegistry) Count() int {
 	return len(r.integrations)
 }
 
+// CallIntegration POSTs payload as JSON to the integration named by target.
+// A target of the form "name.operation" posts to the operation's path, so
+// "crm.update_contact" posts to the crm base URL + "/update_contact".
+func (r *IntegrationRegistry) CallIntegration(ctx context.Context, target string, payload any) error {
+	_, err := r.InvokeIntegration(ctx, target, payload)
+	return err
+}
+
+// InvokeIntegration calls target like CallIntegration and returns the
+// response body. A body that is not JSON is returned as a JSON string, and
+// an empty body as nil.
+func (r *IntegrationRegistry) InvokeIntegration(ctx context.Context, target string, payload any) (json.RawMessage, error) {
+	name, operation, _ := strings.Cut(target, ".")
+	client, exists := r.GetClient(name)
+	if !exists {
+		return nil, fmt.Errorf("integration '%s' not found", name)
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling payload: %w", err)
+	}
+
+	path := ""
+	if operation != "" {
+		path = "/" + operation
+	}
+
+	resp, err := client.Do(ctx, http.MethodPost, path, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("calling integration '%s': %w", target, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response of integration '%s': %w", target, err)
+	}
+
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("integration '%s' returned status %d", target, resp.StatusCode)
+	}
+
+	data = bytes.TrimSpace(data)
+	switch {
+	case len(data) == 0:
+		return nil, nil
+	case json.Valid(data):
+		return json.RawMessage(data), nil
+	default:
+		return json.Marshal(string(data))
+	}
+}
+
 // Do executes an HTTP request through the integration client.
 func (c *Client) Do(ctx context.Context, method, path string, body io.Reader) (*http.Response, error) {
 	// Check circuit breaker
@@ -206,6 +262,10 @@ func (c *Client) Do(ctx context.Context, method, path string, body io.Reader) (*
 		return nil, err
 	}
 
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
 	// Apply authentication
 	if c.Auth != nil {
 		applyAuth(req, c.Auth)
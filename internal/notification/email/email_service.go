@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/bargom/codeai/internal/event"
+	"github.com/bargom/codeai/internal/event/bus"
 	"github.com/bargom/codeai/internal/notification/email/repository"
 	"github.com/bargom/codeai/internal/notification/email/templates"
 	"github.com/bargom/codeai/pkg/integration/brevo"
@@ -124,8 +125,13 @@ func (s *EmailService) SendTestResultsEmail(ctx context.Context, testRunID strin
 	return s.sendWithTemplate(ctx, tmpl, data, recipients, fmt.Sprintf("test:%s", testRunID))
 }
 
-// SendCustomEmail sends an arbitrary transactional email.
+// SendCustomEmail sends an arbitrary transactional email. No email is sent
+// while events are replayed.
 func (s *EmailService) SendCustomEmail(ctx context.Context, req EmailRequest) error {
+	if bus.IsReplay(ctx) {
+		return nil
+	}
+
 	tmpl, err := s.templates.GetTemplate(req.TemplateType)
 	if err != nil {
 		return fmt.Errorf("email: get template: %w", err)
@@ -174,8 +180,13 @@ type TestResults struct {
 	Duration     time.Duration
 }
 
-// sendWithTemplate renders and sends an email using a template.
+// sendWithTemplate renders and sends an email using a template. No email is
+// sent while events are replayed.
 func (s *EmailService) sendWithTemplate(ctx context.Context, tmpl *templates.Template, data map[string]interface{}, recipients []string, reference string) error {
+	if bus.IsReplay(ctx) {
+		return nil
+	}
+
 	htmlContent, err := s.templates.RenderTemplate(tmpl, data)
 	if err != nil {
 		return fmt.Errorf("email: render html: %w", err)
@@ -123,12 +123,18 @@ type pHandlerLogic struct {
 type pLogicStep struct {
 	pos       lexer.Position
 	Target    *string    `parser:"( @Ident Equals )?"`
-	Action    string     `parser:"@( Ident | Query | Body | Path | Header | Request | Response | Status | Do | Where | With | Middleware | RequireRole | GET | POST | PUT | DELETE | PATCH | Endpoint | From )"`
-	Args      []string   `parser:"LParen ( @( Ident | String | Number | Request | Response | Query | Body | Path | Header | Status | Do | Where | With | Middleware | RequireRole | GET | POST | PUT | DELETE | PATCH | Endpoint | From ) ( Comma @( Ident | String | Number | Request | Response | Query | Body | Path | Header | Status | Do | Where | With | Middleware | RequireRole | GET | POST | PUT | DELETE | PATCH | Endpoint | From ) )* )? RParen"`
+	Action    string     `parser:"@( Ident | Query | Body | Path | Header | Request | Response | Status | Do | Where | With | Middleware | RequireRole | GET | POST | PUT | DELETE | PATCH | Endpoint | From ) ( @Dot @Ident )*"`
+	Args      []*pArg    `parser:"LParen ( @@ ( Comma @@ )* )? RParen"`
 	Condition *string    `parser:"( Where @String )?"`
 	Options   []*pOption `parser:"@@?"`
 }
 
+// pArg is an argument of a logic step: a name, possibly qualified as in
+// request.id, a string or a number.
+type pArg struct {
+	Value string `parser:"@( Ident | String | Number | Request | Response | Query | Body | Path | Header | Status | Do | Where | With | Middleware | RequireRole | GET | POST | PUT | DELETE | PATCH | Endpoint | From ) ( @Dot @Ident )*"`
+}
+
 // pOption represents a key-value option in a logic step.
 // Example: with { cache: true, ttl: 300 }
 type pOption struct {
@@ -291,7 +297,7 @@ func convertLogicStep(s *pLogicStep) *ast.LogicStep {
 	// Convert args, unquoting strings
 	args := make([]string, len(s.Args))
 	for i, arg := range s.Args {
-		args[i] = unquote(arg)
+		args[i] = unquote(arg.Value)
 	}
 
 	// Convert options
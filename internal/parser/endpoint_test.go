@@ -454,6 +454,40 @@ func TestParseEndpoint_WithLogicOptions(t *testing.T) {
 	}
 }
 
+func TestParseEndpoint_WithQualifiedActionsAndArgs(t *testing.T) {
+	input := `endpoint PUT "/posts/:id" {
+		request UpdatePost from body
+		response Post status 200
+		do {
+			post = db.findOne(Post, request.id)
+			authorize(post, "post.update")
+			posts = db.find(Post) with { authorize: "post.read" }
+		}
+	}`
+
+	endpoint, err := ParseEndpoint(input)
+	if err != nil {
+		t.Fatalf("Failed to parse endpoint: %v", err)
+	}
+
+	steps := endpoint.Handler.Logic.Steps
+	if len(steps) != 3 {
+		t.Fatalf("Expected 3 logic steps, got %d", len(steps))
+	}
+	if steps[0].Action != "db.findOne" {
+		t.Errorf("Expected action db.findOne, got %s", steps[0].Action)
+	}
+	if len(steps[0].Args) != 2 || steps[0].Args[1] != "request.id" {
+		t.Errorf("Expected args [Post request.id], got %v", steps[0].Args)
+	}
+	if len(steps[1].Args) != 2 || steps[1].Args[1] != "post.update" {
+		t.Errorf("Expected args [post post.update], got %v", steps[1].Args)
+	}
+	if steps[2].Action != "db.find" || len(steps[2].Options) != 1 || steps[2].Options[0].Key != "authorize" {
+		t.Errorf("Expected db.find with an authorize option, got %s %v", steps[2].Action, steps[2].Options)
+	}
+}
+
 // =============================================================================
 // Multiple Endpoints Tests
 // =============================================================================
@@ -77,6 +77,55 @@ func TestParseEventWithSchema(t *testing.T) {
 	}
 }
 
+func TestParseEventVersions(t *testing.T) {
+	t.Parallel()
+
+	program, err := Parse(`
+event user.created {
+	schema {
+		user_id string
+		name string
+	}
+}
+
+event user.created version 2 {
+	breaking
+	schema {
+		user_id string
+		full_name string
+	}
+	upcast from 1 {
+		full_name: "default(payload.name, '')"
+	}
+}`)
+	require.NoError(t, err)
+	require.Len(t, program.Statements, 2)
+
+	v1, ok := program.Statements[0].(*ast.EventDecl)
+	require.True(t, ok, "expected EventDecl")
+	assert.Equal(t, "user.created", v1.Name)
+	assert.Equal(t, 1, v1.Version)
+	assert.False(t, v1.Breaking)
+	assert.Nil(t, v1.Upcast)
+	assert.Equal(t, 2, v1.Pos().Line)
+
+	v2, ok := program.Statements[1].(*ast.EventDecl)
+	require.True(t, ok, "expected EventDecl")
+	assert.Equal(t, "user.created", v2.Name)
+	assert.Equal(t, 2, v2.Version)
+	assert.True(t, v2.Breaking)
+	require.NotNil(t, v2.Schema)
+	assert.Len(t, v2.Schema.Fields, 2)
+	require.NotNil(t, v2.Upcast)
+	assert.Equal(t, 1, v2.Upcast.From)
+	require.Len(t, v2.Upcast.Mappings, 1)
+	assert.Equal(t, "full_name", v2.Upcast.Mappings[0].Field)
+	assert.Equal(t, "default(payload.name, '')", v2.Upcast.Mappings[0].Expr)
+
+	_, err = Parse(`event user.created version 1.5 {}`)
+	assert.Error(t, err)
+}
+
 func TestParseEventHandlers(t *testing.T) {
 	t.Parallel()
 
@@ -366,6 +415,56 @@ func TestParseWebhook(t *testing.T) {
 	}
 }
 
+func TestParseWebhookReceiver(t *testing.T) {
+	t.Parallel()
+
+	program, err := Parse(`
+		receive webhook stripe at "/hooks/stripe" {
+			verify hmac_sha256 header "Stripe-Signature" secret env(STRIPE_SECRET)
+			tolerance "10m"
+			delivery_id field "id"
+			emit "payment.received"
+		}
+
+		receive webhook github at "/hooks/github" {
+			verify hmac_sha256 header "X-Hub-Signature-256" secret env("GITHUB_SECRET")
+			delivery_id header "X-GitHub-Delivery"
+			emit "repo.pushed"
+		}
+
+		receive webhook partner at "/hooks/partner" {
+			verify standard_webhooks secret "whsec_b2xk"
+			emit "partner.updated"
+		}
+	`)
+	require.NoError(t, err)
+	require.Len(t, program.Statements, 3)
+
+	stripe, ok := program.Statements[0].(*ast.WebhookReceiverDecl)
+	require.True(t, ok, "expected WebhookReceiverDecl")
+	assert.Equal(t, "stripe", stripe.Name)
+	assert.Equal(t, "/hooks/stripe", stripe.Path)
+	assert.Equal(t, ast.ReceiverSchemeHMAC, stripe.Scheme)
+	assert.Equal(t, "Stripe-Signature", stripe.SignatureHeader)
+	assert.Equal(t, "STRIPE_SECRET", stripe.SecretEnv)
+	assert.Equal(t, "10m", stripe.Tolerance)
+	assert.Equal(t, "id", stripe.DeliveryField)
+	assert.Equal(t, "payment.received", stripe.Event)
+	assert.Equal(t, 2, stripe.Pos().Line)
+
+	github := program.Statements[1].(*ast.WebhookReceiverDecl)
+	assert.Equal(t, "GITHUB_SECRET", github.SecretEnv)
+	assert.Equal(t, "X-GitHub-Delivery", github.DeliveryHeader)
+
+	partner := program.Statements[2].(*ast.WebhookReceiverDecl)
+	assert.Equal(t, ast.ReceiverSchemeStandard, partner.Scheme)
+	assert.Empty(t, partner.SignatureHeader)
+	assert.Equal(t, "whsec_b2xk", partner.Secret)
+
+	_, err = Parse(`receive webhook stripe at "/hooks/stripe" { verify hmac_sha256 emit "payment.received" }`)
+	assert.Error(t, err, "verify requires a secret")
+}
+
 func TestParseInvalidEventName(t *testing.T) {
 	t.Parallel()
 
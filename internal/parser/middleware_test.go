@@ -103,6 +103,73 @@ func TestParseAuthWithJWKS(t *testing.T) {
 	assert.Equal(t, "api.example.com", authDecl.JWKS.Audience)
 }
 
+func TestParseAuthWithIssuer(t *testing.T) {
+	t.Parallel()
+
+	input := `auth app_tokens {
+		method jwt
+		issuer {
+			model: "User"
+			password_field: "password_hash"
+			issuer: "https://api.example.com"
+			access_ttl: "10m"
+			storage: database
+		}
+	}`
+
+	program, err := Parse(input)
+	require.NoError(t, err)
+	require.Len(t, program.Statements, 1)
+
+	authDecl, ok := program.Statements[0].(*ast.AuthDecl)
+	require.True(t, ok, "expected AuthDecl")
+	assert.Equal(t, ast.AuthMethodJWT, authDecl.Method)
+	assert.Nil(t, authDecl.JWKS)
+	assert.Empty(t, authDecl.Config)
+
+	require.NotNil(t, authDecl.Issuer, "expected issuer block")
+	assert.Equal(t, ast.NodeAuthIssuer, authDecl.Issuer.Type())
+	require.Len(t, authDecl.Issuer.Config, 5)
+
+	model, ok := authDecl.Issuer.Config["model"].(*ast.StringLiteral)
+	require.True(t, ok, "expected string model")
+	assert.Equal(t, "User", model.Value)
+
+	storage, ok := authDecl.Issuer.Config["storage"].(*ast.Identifier)
+	require.True(t, ok, "expected identifier storage")
+	assert.Equal(t, "database", storage.Name)
+}
+
+func TestParseAuthWithConfig(t *testing.T) {
+	t.Parallel()
+
+	input := `auth service_keys {
+		method apikey
+		config {
+			header: "X-Service-Key"
+			query: "key"
+			storage: memory
+		}
+	}`
+
+	program, err := Parse(input)
+	require.NoError(t, err)
+	require.Len(t, program.Statements, 1)
+
+	authDecl, ok := program.Statements[0].(*ast.AuthDecl)
+	require.True(t, ok, "expected AuthDecl")
+	assert.Equal(t, ast.AuthMethodAPIKey, authDecl.Method)
+	require.Len(t, authDecl.Config, 3)
+
+	header, ok := authDecl.Config["header"].(*ast.StringLiteral)
+	require.True(t, ok, "expected string header")
+	assert.Equal(t, "X-Service-Key", header.Value)
+
+	storage, ok := authDecl.Config["storage"].(*ast.Identifier)
+	require.True(t, ok, "expected identifier storage")
+	assert.Equal(t, "memory", storage.Name)
+}
+
 // =============================================================================
 // Role Parsing Tests
 // =============================================================================
@@ -201,6 +268,86 @@ role viewer {
 	}
 }
 
+func TestParsePolicyDecl(t *testing.T) {
+	t.Parallel()
+
+	input := `role admin {
+	permissions ["posts:delete"]
+}
+
+// Authors edit their own posts
+authorize post.update when resource.author_id == auth.sub or auth.role == "admin"
+  authorize post.read when resource.published
+`
+
+	program, err := Parse(input)
+	require.NoError(t, err)
+	require.Len(t, program.Statements, 3)
+
+	update, ok := program.Statements[1].(*ast.PolicyDecl)
+	require.True(t, ok, "expected PolicyDecl, got %T", program.Statements[1])
+	assert.Equal(t, "post", update.Resource)
+	assert.Equal(t, "update", update.Action)
+	assert.Equal(t, "post.update", update.Name())
+	assert.Equal(t, `resource.author_id == auth.sub or auth.role == "admin"`, update.Condition)
+	assert.Equal(t, 6, update.Pos().Line)
+	assert.Equal(t, 1, update.Pos().Column)
+
+	read, ok := program.Statements[2].(*ast.PolicyDecl)
+	require.True(t, ok)
+	assert.Equal(t, "resource.published", read.Condition)
+	assert.Equal(t, 7, read.Pos().Line)
+	assert.Equal(t, 3, read.Pos().Column)
+
+	app := program.ToApplication()
+	assert.Len(t, app.Policies, 2)
+}
+
+func TestParsePolicyDecl_Invalid(t *testing.T) {
+	t.Parallel()
+
+	for _, input := range []string{
+		`authorize post when resource.published`,
+		`authorize post.update resource.published`,
+		`authorize post.update when`,
+	} {
+		_, err := Parse(input)
+		assert.ErrorContains(t, err, "invalid policy", input)
+	}
+}
+
+func TestParseTenancyDecl(t *testing.T) {
+	t.Parallel()
+
+	input := `tenancy {
+	strategy: database, claim: "org_id"
+	header: "X-Tenant-ID"
+	subdomain: true
+}
+
+tenancy = 1
+`
+
+	program, err := Parse(input)
+	require.NoError(t, err)
+	require.Len(t, program.Statements, 2)
+
+	decl, ok := program.Statements[0].(*ast.TenancyDecl)
+	require.True(t, ok, "expected TenancyDecl, got %T", program.Statements[0])
+	assert.Equal(t, "database", decl.Strategy)
+	claim, ok := decl.Settings["claim"].(*ast.StringLiteral)
+	require.True(t, ok)
+	assert.Equal(t, "org_id", claim.Value)
+	assert.Contains(t, decl.Settings, "header")
+	assert.Contains(t, decl.Settings, "subdomain")
+	assert.Equal(t, 1, decl.Pos().Line)
+
+	_, ok = program.Statements[1].(*ast.Assignment)
+	assert.True(t, ok, "tenancy is still usable as a variable name")
+
+	assert.Same(t, decl, program.ToApplication().Tenancy)
+}
+
 // =============================================================================
 // Middleware Parsing Tests
 // =============================================================================
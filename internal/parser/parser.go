@@ -3,6 +3,7 @@ package parser
 
 import (
 	"os"
+	"regexp"
 	"strconv"
 	"strings"
 
@@ -141,6 +142,7 @@ var dslLexer = lexer.MustStateful(lexer.Rules{
 		{Name: "LBrace", Pattern: `\{`, Action: nil},
 		{Name: "RBrace", Pattern: `\}`, Action: nil},
 		{Name: "Comma", Pattern: `,`, Action: nil},
+		{Name: "Dot", Pattern: `\.`, Action: nil},
 	},
 	"Shell": {
 		// Capture everything until closing brace as ShellCommand
@@ -166,11 +168,13 @@ type pStatement struct {
 	DatabaseBlock   *pDatabaseBlock   `parser:"| @@"`
 	AuthDecl        *pAuthDecl        `parser:"| @@"`
 	RoleDecl        *pRoleDecl        `parser:"| @@"`
+	TenancyDecl     *pTenancyDecl     `parser:"| @@"`
 	MiddlewareDecl  *pMiddlewareDecl  `parser:"| @@"`
 	EventDecl       *pEventDecl       `parser:"| @@"`
 	EventHandler    *pEventHandler    `parser:"| @@"`
 	IntegrationDecl *pIntegrationDecl `parser:"| @@"`
 	WebhookDecl     *pWebhookDecl     `parser:"| @@"`
+	ReceiverDecl    *pReceiverDecl    `parser:"| @@"`
 	VarDecl         *pVarDecl         `parser:"| @@"`
 	IfStmt          *pIfStmt          `parser:"| @@"`
 	ForLoop         *pForLoop         `parser:"| @@"`
@@ -254,11 +258,21 @@ type pDatabaseBlock struct {
 // pAuthDecl is the Participle grammar for auth provider declaration.
 type pAuthDecl struct {
 	Pos      lexer.Position
-	Name     string   `parser:"Auth @Ident LBrace"`
-	Method   string   `parser:"Method @( Jwt | Oauth2 | Apikey | Basic )"`
-	JwksURL  *string  `parser:"( JwksUrl @String )?"`
-	Issuer   *string  `parser:"( Issuer @String )?"`
-	Audience *string  `parser:"( Audience @String )? RBrace"`
+	Name     string             `parser:"Auth @Ident LBrace"`
+	Method   string             `parser:"Method @( Jwt | Oauth2 | Apikey | Basic )"`
+	JwksURL  *string            `parser:"( JwksUrl @String )?"`
+	Issuer   *string            `parser:"( Issuer @String )?"`
+	Audience *string            `parser:"( Audience @String )?"`
+	Config   []*pConfigProperty `parser:"( Config LBrace @@* RBrace )?"`
+	Issuance *pAuthIssuer       `parser:"@@? RBrace"`
+}
+
+// pAuthIssuer is the Participle grammar for the token issuance block of an
+// auth provider.
+// Example: issuer { model: "User" access_ttl: "15m" }
+type pAuthIssuer struct {
+	Pos      lexer.Position
+	Settings []*pConfigProperty `parser:"Issuer LBrace @@* RBrace"`
 }
 
 // pRoleDecl is the Participle grammar for role declaration.
@@ -268,6 +282,13 @@ type pRoleDecl struct {
 	Permissions []string `parser:"Permissions LBracket ( @String ( Comma @String )* )? RBracket RBrace"`
 }
 
+// pTenancyDecl is the Participle grammar for the multi-tenancy block.
+// Example: tenancy { strategy: column claim: "org_id" }
+type pTenancyDecl struct {
+	Pos      lexer.Position
+	Settings []*pConfigProperty `parser:"\"tenancy\" LBrace ( @@ Comma? )* RBrace"`
+}
+
 // pMiddlewareDecl is the Participle grammar for middleware declaration.
 type pMiddlewareDecl struct {
 	Pos            lexer.Position
@@ -281,12 +302,15 @@ type pMiddlewareDecl struct {
 // =============================================================================
 
 // pEventDecl is the Participle grammar for event declaration.
-// Example: event user.created { schema { user_id string ... } }
+// Example: event user.created version 2 { breaking schema { user_id string ... } upcast from 1 { ... } }
 type pEventDecl struct {
-	Pos    lexer.Position
-	Name   string             `parser:"Event @Ident ((\".\") @Ident)*"`
-	Schema *pEventSchema      `parser:"LBrace (Schema @@)?"`
-	RBrace string             `parser:"RBrace"`
+	Pos      lexer.Position
+	Name     string        `parser:"Event @Ident (@Dot @Ident)*"`
+	Version  *int          `parser:"(\"version\" @Number)?"`
+	Breaking bool          `parser:"LBrace @\"breaking\"?"`
+	Schema   *pEventSchema `parser:"(Schema @@)?"`
+	Upcast   *pEventUpcast `parser:"@@?"`
+	RBrace   string        `parser:"RBrace"`
 }
 
 // pEventSchema is the Participle grammar for event schema.
@@ -302,6 +326,21 @@ type pEventSchemaField struct {
 	FieldType string `parser:"@(Ident | Text)"`
 }
 
+// pEventUpcast is the Participle grammar for an event upcast.
+// Example: upcast from 1 { full_name: "payload.first_name" }
+type pEventUpcast struct {
+	Pos      lexer.Position
+	From     int                    `parser:"\"upcast\" From @Number"`
+	Mappings []*pEventUpcastMapping `parser:"LBrace @@* RBrace"`
+}
+
+// pEventUpcastMapping is the Participle grammar for an event upcast field mapping.
+type pEventUpcastMapping struct {
+	Pos   lexer.Position
+	Field string `parser:"@(Ident | Method | Type | Event | Schema | On | Do | Workflow | Emit | Async | Integration | Auth | Token | Header | Value | Webhook | Url | Headers | Retry | Timeout | Text) Colon"`
+	Expr  string `parser:"@String"`
+}
+
 // pEventHandler is the Participle grammar for event handler declaration.
 // Example: on "user.created" do workflow "send_welcome_email" async
 type pEventHandler struct {
@@ -383,6 +422,45 @@ type pWebhookRetryPolicy struct {
 	Backoff         float64 `parser:"Backoff @Number"`
 }
 
+// pReceiverDecl is the Participle grammar for an inbound webhook receiver.
+// Example: receive webhook stripe at "/hooks/stripe" { verify hmac_sha256 header "Stripe-Signature" secret env(STRIPE_SECRET) emit "payment.received" }
+type pReceiverDecl struct {
+	Pos     lexer.Position
+	Name    string             `parser:"\"receive\" Webhook @Ident"`
+	Path    string             `parser:"\"at\" @String LBrace"`
+	Clauses []*pReceiverClause `parser:"@@* RBrace"`
+}
+
+// pReceiverClause is the Participle grammar for a clause of a webhook
+// receiver.
+type pReceiverClause struct {
+	Pos       lexer.Position
+	Verify    *pReceiverVerify `parser:"  @@"`
+	Timestamp *string          `parser:"| \"timestamp\" Header @String"`
+	Tolerance *string          `parser:"| \"tolerance\" @String"`
+	Delivery  *pReceiverID     `parser:"| \"delivery_id\" @@"`
+	Emit      *string          `parser:"| Emit @String"`
+}
+
+// pReceiverVerify is the Participle grammar for the signature verification
+// of a webhook receiver.
+// Example: verify hmac_sha256 header "Stripe-Signature" secret env(STRIPE_SECRET)
+type pReceiverVerify struct {
+	Pos       lexer.Position
+	Scheme    string  `parser:"\"verify\" @Ident"`
+	Header    *string `parser:"(Header @String)?"`
+	SecretEnv *string `parser:"\"secret\" ( \"env\" LParen @(Ident | String) RParen"`
+	Secret    *string `parser:"| @String )"`
+}
+
+// pReceiverID is the Participle grammar for where a webhook receiver finds
+// the delivery ID: a header or a payload field.
+type pReceiverID struct {
+	Pos    lexer.Position
+	Header *string `parser:"  Header @String"`
+	Field  *string `parser:"| \"field\" @String"`
+}
+
 // =============================================================================
 // PostgreSQL Model Grammar
 // =============================================================================
@@ -523,20 +601,35 @@ var parserInstance = participle.MustBuild[pProgram](
 
 // Parse parses the input string and returns an AST Program.
 func Parse(input string) (*ast.Program, error) {
+	return parse("", input)
+}
+
+// parse parses input read from filename, which is recorded in the positions
+// of the resulting declarations.
+func parse(filename, input string) (*ast.Program, error) {
 	// First, extract and parse any endpoint declarations separately
 	endpoints, cleanedInput, err := extractAndParseEndpoints(input)
 	if err != nil {
 		return nil, err
 	}
 
+	// Policies hold a free-form expression, so they are extracted too
+	policies, cleanedInput, err := extractPolicies(filename, cleanedInput)
+	if err != nil {
+		return nil, err
+	}
+
 	// Parse the main DSL without endpoints
-	parsed, err := parserInstance.ParseString("", cleanedInput)
+	parsed, err := parserInstance.ParseString(filename, cleanedInput)
 	if err != nil {
 		return nil, err
 	}
 
 	// Convert main program
 	program := convertProgram(parsed)
+	for _, policy := range policies {
+		program.Statements = append(program.Statements, policy)
+	}
 
 	// Add endpoint declarations to the program
 	for _, endpoint := range endpoints {
@@ -562,8 +655,8 @@ func ParseFile(filename string) (*ast.Program, error) {
 	if err != nil {
 		return nil, err
 	}
-	// Use Parse() which handles endpoint extraction
-	return Parse(string(data))
+	// Use parse() which handles endpoint extraction
+	return parse(filename, string(data))
 }
 
 // ParseApplicationFile parses a file and returns a structured Application.
@@ -597,6 +690,8 @@ func convertStatement(s *pStatement) ast.Statement {
 		return convertAuthDecl(s.AuthDecl)
 	case s.RoleDecl != nil:
 		return convertRoleDecl(s.RoleDecl)
+	case s.TenancyDecl != nil:
+		return convertTenancyDecl(s.TenancyDecl)
 	case s.MiddlewareDecl != nil:
 		return convertMiddlewareDecl(s.MiddlewareDecl)
 	case s.EventDecl != nil:
@@ -607,6 +702,8 @@ func convertStatement(s *pStatement) ast.Statement {
 		return convertIntegrationDecl(s.IntegrationDecl)
 	case s.WebhookDecl != nil:
 		return convertWebhookDecl(s.WebhookDecl)
+	case s.ReceiverDecl != nil:
+		return convertReceiverDecl(s.ReceiverDecl)
 	case s.VarDecl != nil:
 		return convertVarDecl(s.VarDecl)
 	case s.Assignment != nil:
@@ -760,11 +857,25 @@ func convertAuthDecl(a *pAuthDecl) *ast.AuthDecl {
 		}
 	}
 
+	config := make(map[string]ast.Expression)
+	for _, prop := range a.Config {
+		config[prop.Key] = convertExpression(prop.Value)
+	}
+
+	var issuer *ast.AuthIssuerDecl
+	if a.Issuance != nil {
+		issuer = &ast.AuthIssuerDecl{Config: make(map[string]ast.Expression)}
+		for _, prop := range a.Issuance.Settings {
+			issuer.Config[prop.Key] = convertExpression(prop.Value)
+		}
+	}
+
 	return &ast.AuthDecl{
 		Name:   a.Name,
 		Method: method,
 		JWKS:   jwksConfig,
-		Config: make(map[string]ast.Expression),
+		Config: config,
+		Issuer: issuer,
 	}
 }
 
@@ -779,6 +890,25 @@ func convertRoleDecl(r *pRoleDecl) *ast.RoleDecl {
 	}
 }
 
+func convertTenancyDecl(t *pTenancyDecl) *ast.TenancyDecl {
+	decl := &ast.TenancyDecl{Settings: make(map[string]ast.Expression)}
+	for _, prop := range t.Settings {
+		expr := convertExpression(prop.Value)
+		if prop.Key == "strategy" {
+			switch v := expr.(type) {
+			case *ast.Identifier:
+				decl.Strategy = v.Name
+			case *ast.StringLiteral:
+				decl.Strategy = v.Value
+			}
+			continue
+		}
+		decl.Settings[prop.Key] = expr
+	}
+	decl.SetPos(convertPos(t.Pos))
+	return decl
+}
+
 func convertMiddlewareDecl(m *pMiddlewareDecl) *ast.MiddlewareDecl {
 	config := make(map[string]ast.Expression)
 	for _, prop := range m.Config {
@@ -798,6 +928,16 @@ func safeUnquote(s *string) string {
 	return unquote(*s)
 }
 
+// convertPos converts a participle position to an AST position.
+func convertPos(pos lexer.Position) ast.Position {
+	return ast.Position{
+		Filename: pos.Filename,
+		Line:     pos.Line,
+		Column:   pos.Column,
+		Offset:   pos.Offset,
+	}
+}
+
 // =============================================================================
 // PostgreSQL Model Conversion Functions
 // =============================================================================
@@ -818,12 +958,14 @@ func convertModelDecl(m *pModelDecl) *ast.ModelDecl {
 		desc = unquote(*m.Description)
 	}
 
-	return &ast.ModelDecl{
+	model := &ast.ModelDecl{
 		Name:        m.Name,
 		Description: desc,
 		Fields:      fields,
 		Indexes:     indexes,
 	}
+	model.SetPos(convertPos(m.Pos))
+	return model
 }
 
 func convertFieldDecl(f *pFieldDecl) *ast.FieldDecl {
@@ -832,11 +974,13 @@ func convertFieldDecl(f *pFieldDecl) *ast.FieldDecl {
 		modifiers[i] = convertModifier(mod)
 	}
 
-	return &ast.FieldDecl{
+	field := &ast.FieldDecl{
 		Name:      f.Name,
 		FieldType: convertTypeRef(f.Type),
 		Modifiers: modifiers,
 	}
+	field.SetPos(convertPos(f.Pos))
+	return field
 }
 
 func convertTypeRef(t *pTypeRef) *ast.TypeRef {
@@ -897,12 +1041,14 @@ func convertCollectionDecl(c *pCollectionDecl) *ast.CollectionDecl {
 		desc = unquote(*c.Description)
 	}
 
-	return &ast.CollectionDecl{
+	collection := &ast.CollectionDecl{
 		Name:        c.Name,
 		Description: desc,
 		Fields:      fields,
 		Indexes:     indexes,
 	}
+	collection.SetPos(convertPos(c.Pos))
+	return collection
 }
 
 func convertMongoFieldDecl(f *pMongoFieldDecl) *ast.MongoFieldDecl {
@@ -911,11 +1057,13 @@ func convertMongoFieldDecl(f *pMongoFieldDecl) *ast.MongoFieldDecl {
 		modifiers[i] = convertModifier(mod)
 	}
 
-	return &ast.MongoFieldDecl{
+	field := &ast.MongoFieldDecl{
 		Name:      f.Name,
 		FieldType: convertMongoTypeRef(f.Type),
 		Modifiers: modifiers,
 	}
+	field.SetPos(convertPos(f.Pos))
+	return field
 }
 
 func convertMongoTypeRef(t *pMongoTypeRef) *ast.MongoTypeRef {
@@ -1090,12 +1238,25 @@ func convertEventDecl(e *pEventDecl) *ast.EventDecl {
 	if e.Schema != nil {
 		schema = convertEventSchema(e.Schema)
 	}
+	var upcast *ast.EventUpcast
+	if e.Upcast != nil {
+		upcast = convertEventUpcast(e.Upcast)
+	}
+	version := 1
+	if e.Version != nil {
+		version = *e.Version
+	}
 
-	return &ast.EventDecl{
+	decl := &ast.EventDecl{
 		Name:     e.Name,
+		Version:  version,
+		Breaking: e.Breaking,
 		Schema:   schema,
+		Upcast:   upcast,
 		Handlers: nil, // Handlers are parsed separately as EventHandler statements
 	}
+	decl.SetPos(convertPos(e.Pos))
+	return decl
 }
 
 func convertEventSchema(s *pEventSchema) *ast.EventSchema {
@@ -1105,12 +1266,30 @@ func convertEventSchema(s *pEventSchema) *ast.EventSchema {
 			Name:      f.Name,
 			FieldType: f.FieldType,
 		}
+		fields[i].SetPos(convertPos(f.Pos))
 	}
 	return &ast.EventSchema{
 		Fields: fields,
 	}
 }
 
+func convertEventUpcast(u *pEventUpcast) *ast.EventUpcast {
+	mappings := make([]*ast.EventUpcastMapping, len(u.Mappings))
+	for i, m := range u.Mappings {
+		mappings[i] = &ast.EventUpcastMapping{
+			Field: m.Field,
+			Expr:  unquote(m.Expr),
+		}
+		mappings[i].SetPos(convertPos(m.Pos))
+	}
+	upcast := &ast.EventUpcast{
+		From:     u.From,
+		Mappings: mappings,
+	}
+	upcast.SetPos(convertPos(u.Pos))
+	return upcast
+}
+
 func convertEventHandler(h *pEventHandler) *ast.EventHandlerDecl {
 	return &ast.EventHandlerDecl{
 		EventName:  unquote(h.EventName),
@@ -1247,6 +1426,33 @@ func convertWebhookRetryPolicy(p *pWebhookRetryPolicy) *ast.RetryPolicyDecl {
 	}
 }
 
+func convertReceiverDecl(r *pReceiverDecl) *ast.WebhookReceiverDecl {
+	decl := &ast.WebhookReceiverDecl{
+		Name: r.Name,
+		Path: unquote(r.Path),
+	}
+	for _, c := range r.Clauses {
+		switch {
+		case c.Verify != nil:
+			decl.Scheme = c.Verify.Scheme
+			decl.SignatureHeader = safeUnquote(c.Verify.Header)
+			decl.SecretEnv = safeUnquote(c.Verify.SecretEnv)
+			decl.Secret = safeUnquote(c.Verify.Secret)
+		case c.Timestamp != nil:
+			decl.TimestampHeader = unquote(*c.Timestamp)
+		case c.Tolerance != nil:
+			decl.Tolerance = unquote(*c.Tolerance)
+		case c.Delivery != nil:
+			decl.DeliveryHeader = safeUnquote(c.Delivery.Header)
+			decl.DeliveryField = safeUnquote(c.Delivery.Field)
+		case c.Emit != nil:
+			decl.Event = unquote(*c.Emit)
+		}
+	}
+	decl.SetPos(convertPos(r.Pos))
+	return decl
+}
+
 // =============================================================================
 // Endpoint Integration Helper Functions
 // =============================================================================
@@ -1313,7 +1519,11 @@ func extractAndParseEndpoints(input string) ([]*ast.EndpointDecl, string, error)
 				}
 				endpoints = append(endpoints, endpoint)
 
-				// Don't add these lines to cleaned input
+				// Blank out these lines so positions in the rest of the
+				// input still match the source
+				for j := endpointStart; j <= i; j++ {
+					cleanedLines = append(cleanedLines, "")
+				}
 			} else {
 				// Unclosed braces, treat as regular content
 				cleanedLines = append(cleanedLines, lines[endpointStart])
@@ -1326,8 +1536,45 @@ func extractAndParseEndpoints(input string) ([]*ast.EndpointDecl, string, error)
 	}
 
 	cleanedInput := strings.Join(cleanedLines, "\n")
-	cleanedInput = strings.TrimSpace(cleanedInput)
+	if strings.TrimSpace(cleanedInput) == "" {
+		cleanedInput = ""
+	}
 
 	return endpoints, cleanedInput, nil
 }
 
+
+// policyPattern matches a policy declaration line:
+// authorize <resource>.<action> when <condition>
+var policyPattern = regexp.MustCompile(`^authorize\s+([a-zA-Z_][a-zA-Z0-9_]*)\.([a-zA-Z_][a-zA-Z0-9_]*)\s+when\s+(\S.*)$`)
+
+// extractPolicies extracts the authorization policy lines from the input.
+// A policy takes a single line; its condition is checked by the validator.
+func extractPolicies(filename, input string) ([]*ast.PolicyDecl, string, error) {
+	var policies []*ast.PolicyDecl
+	lines := strings.Split(input, "\n")
+	offset := 0
+
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "authorize ") || trimmed == "authorize" {
+			column := strings.Index(line, "authorize")
+			pos := lexer.Position{Filename: filename, Line: i + 1, Column: column + 1, Offset: offset + column}
+
+			m := policyPattern.FindStringSubmatch(trimmed)
+			if m == nil {
+				return nil, "", participle.Errorf(pos, "invalid policy: expected authorize <resource>.<action> when <condition>")
+			}
+			policy := &ast.PolicyDecl{Resource: m[1], Action: m[2], Condition: strings.TrimSpace(m[3])}
+			policy.SetPos(convertPos(pos))
+			policies = append(policies, policy)
+
+			// Blank the line so positions in the rest of the input still
+			// match the source
+			lines[i] = ""
+		}
+		offset += len(line) + 1
+	}
+
+	return policies, strings.Join(lines, "\n"), nil
+}
@@ -675,6 +675,64 @@ var y = 42`
 	})
 }
 
+func TestParseDeclarationPositions(t *testing.T) {
+	t.Parallel()
+
+	// Endpoints are parsed separately; declarations after them must still
+	// report their lines in the original source.
+	input := `
+endpoint GET "/health" {
+	response Health status 200
+}
+
+database postgres {
+	model User {
+		id: uuid, primary
+		email: string, required
+	}
+}
+
+database mongodb {
+	collection Event {
+		_id: objectid, primary
+	}
+}`
+
+	tmpfile, err := os.CreateTemp("", "positions*.cai")
+	require.NoError(t, err)
+	defer os.Remove(tmpfile.Name())
+	_, err = tmpfile.WriteString(input)
+	require.NoError(t, err)
+	tmpfile.Close()
+
+	program, err := ParseFile(tmpfile.Name())
+	require.NoError(t, err)
+
+	var model *ast.ModelDecl
+	var collection *ast.CollectionDecl
+	for _, stmt := range program.Statements {
+		if db, ok := stmt.(*ast.DatabaseBlock); ok {
+			for _, s := range db.Statements {
+				switch decl := s.(type) {
+				case *ast.ModelDecl:
+					model = decl
+				case *ast.CollectionDecl:
+					collection = decl
+				}
+			}
+		}
+	}
+	require.NotNil(t, model)
+	require.NotNil(t, collection)
+
+	assert.Equal(t, tmpfile.Name(), model.Pos().Filename)
+	assert.Equal(t, 7, model.Pos().Line)
+	assert.Equal(t, 9, model.Fields[1].Pos().Line)
+	assert.Equal(t, 3, model.Fields[1].Pos().Column)
+	assert.Equal(t, 14, collection.Pos().Line)
+	assert.Equal(t, 15, collection.Fields[0].Pos().Line)
+}
+
 // =============================================================================
 // Config Parsing Tests
 // =============================================================================
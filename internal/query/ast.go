@@ -14,6 +14,7 @@ const (
 	QueryMax
 	QueryUpdate
 	QueryDelete
+	QueryInsert
 )
 
 // String returns a string representation of the QueryType.
@@ -35,6 +36,8 @@ func (qt QueryType) String() string {
 		return "UPDATE"
 	case QueryDelete:
 		return "DELETE"
+	case QueryInsert:
+		return "INSERT"
 	default:
 		return fmt.Sprintf("Unknown(%d)", qt)
 	}
@@ -52,8 +55,19 @@ type Query struct {
 	Include    []string      // Relations to load
 	GroupBy    []string
 	Having     *WhereClause
-	Updates    []UpdateSet   // For UPDATE queries
+	Updates    []UpdateSet   // For UPDATE queries, and column values for INSERT queries
 	AggField   string        // Field for aggregate functions (SUM, AVG, etc.)
+	Returning  []string      // RETURNING fields for INSERT, UPDATE and DELETE, "*" = all
+	Tenant     *TenantScope  // Tenant the query is restricted to
+}
+
+// TenantScope restricts a query to the data of one tenant.
+type TenantScope struct {
+	// ID is matched against the entity's TenantColumn, and set on inserted
+	// rows.
+	ID string
+	// Schema, if set, qualifies the entity's table.
+	Schema string
 }
 
 // WhereClause represents a WHERE clause with conditions.
@@ -242,13 +256,14 @@ type BetweenValue struct {
 
 // EntityMeta contains metadata about an entity for SQL compilation.
 type EntityMeta struct {
-	TableName   string
-	PrimaryKey  string
-	SoftDelete  string            // Column name for soft delete (e.g., "deleted_at")
-	Columns     map[string]string // Maps field names to column names
-	JSONColumns map[string]bool   // Columns that store JSON data
-	TSVColumns  map[string]string // Full-text search columns (field -> tsvector column)
-	Relations   map[string]*RelationMeta
+	TableName    string
+	PrimaryKey   string
+	SoftDelete   string            // Column name for soft delete (e.g., "deleted_at")
+	TenantColumn string            // Column holding each row's tenant; queries must then be tenant-scoped
+	Columns      map[string]string // Maps field names to column names
+	JSONColumns  map[string]bool   // Columns that store JSON data
+	TSVColumns   map[string]string // Full-text search columns (field -> tsvector column)
+	Relations    map[string]*RelationMeta
 }
 
 // RelationMeta describes a relation to another entity.
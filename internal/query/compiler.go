@@ -45,6 +45,8 @@ func (c *SQLCompiler) Compile(q *Query) (*CompiledQuery, error) {
 		sql, err = c.compileUpdate(q)
 	case QueryDelete:
 		sql, err = c.compileDelete(q)
+	case QueryInsert:
+		sql, err = c.compileInsert(q)
 	default:
 		err = ErrInvalidQueryType(q.Type)
 	}
@@ -79,10 +81,10 @@ func (c *SQLCompiler) compileSelect(q *Query) (string, error) {
 
 	// FROM
 	b.WriteString(" FROM ")
-	b.WriteString(c.quoteIdent(entity.TableName))
+	b.WriteString(c.tableName(entity, q.Tenant))
 
 	// WHERE
-	whereSQL, err := c.compileWhereWithSoftDelete(q.Where, entity)
+	whereSQL, err := c.compileScopedWhere(q.Where, entity, q.Tenant)
 	if err != nil {
 		return "", err
 	}
@@ -147,9 +149,9 @@ func (c *SQLCompiler) compileCount(q *Query) (string, error) {
 
 	var b strings.Builder
 	b.WriteString("SELECT COUNT(*) FROM ")
-	b.WriteString(c.quoteIdent(entity.TableName))
+	b.WriteString(c.tableName(entity, q.Tenant))
 
-	whereSQL, err := c.compileWhereWithSoftDelete(q.Where, entity)
+	whereSQL, err := c.compileScopedWhere(q.Where, entity, q.Tenant)
 	if err != nil {
 		return "", err
 	}
@@ -182,9 +184,9 @@ func (c *SQLCompiler) compileAggregateFunc(q *Query) (string, error) {
 
 	var b strings.Builder
 	b.WriteString(fmt.Sprintf("SELECT %s(%s) FROM ", funcName, c.quoteIdent(c.mapColumn(entity, q.AggField))))
-	b.WriteString(c.quoteIdent(entity.TableName))
+	b.WriteString(c.tableName(entity, q.Tenant))
 
-	whereSQL, err := c.compileWhereWithSoftDelete(q.Where, entity)
+	whereSQL, err := c.compileScopedWhere(q.Where, entity, q.Tenant)
 	if err != nil {
 		return "", err
 	}
@@ -205,11 +207,14 @@ func (c *SQLCompiler) compileUpdate(q *Query) (string, error) {
 
 	var b strings.Builder
 	b.WriteString("UPDATE ")
-	b.WriteString(c.quoteIdent(entity.TableName))
+	b.WriteString(c.tableName(entity, q.Tenant))
 	b.WriteString(" SET ")
 
 	sets := make([]string, 0, len(q.Updates))
 	for _, u := range q.Updates {
+		if entity.TenantColumn != "" && c.mapColumn(entity, u.Field) == entity.TenantColumn {
+			return "", ErrTenantColumnWrite(entity.TableName)
+		}
 		col := c.quoteIdent(c.mapColumn(entity, u.Field))
 		switch u.Op {
 		case UpdateSetValue:
@@ -236,7 +241,7 @@ func (c *SQLCompiler) compileUpdate(q *Query) (string, error) {
 	}
 	b.WriteString(strings.Join(sets, ", "))
 
-	whereSQL, err := c.compileWhereWithSoftDelete(q.Where, entity)
+	whereSQL, err := c.compileScopedWhere(q.Where, entity, q.Tenant)
 	if err != nil {
 		return "", err
 	}
@@ -245,6 +250,8 @@ func (c *SQLCompiler) compileUpdate(q *Query) (string, error) {
 		b.WriteString(whereSQL)
 	}
 
+	c.compileReturning(&b, q.Returning, entity)
+
 	return b.String(), nil
 }
 
@@ -260,16 +267,16 @@ func (c *SQLCompiler) compileDelete(q *Query) (string, error) {
 	// Use soft delete if configured
 	if entity.SoftDelete != "" {
 		b.WriteString("UPDATE ")
-		b.WriteString(c.quoteIdent(entity.TableName))
+		b.WriteString(c.tableName(entity, q.Tenant))
 		b.WriteString(" SET ")
 		b.WriteString(c.quoteIdent(entity.SoftDelete))
 		b.WriteString(" = NOW()")
 	} else {
 		b.WriteString("DELETE FROM ")
-		b.WriteString(c.quoteIdent(entity.TableName))
+		b.WriteString(c.tableName(entity, q.Tenant))
 	}
 
-	whereSQL, err := c.compileWhereWithSoftDelete(q.Where, entity)
+	whereSQL, err := c.compileScopedWhere(q.Where, entity, q.Tenant)
 	if err != nil {
 		return "", err
 	}
@@ -278,11 +285,98 @@ func (c *SQLCompiler) compileDelete(q *Query) (string, error) {
 		b.WriteString(whereSQL)
 	}
 
+	c.compileReturning(&b, q.Returning, entity)
+
+	return b.String(), nil
+}
+
+// compileInsert compiles an INSERT query from the SET values in q.Updates.
+func (c *SQLCompiler) compileInsert(q *Query) (string, error) {
+	entity := c.getEntity(q.Entity)
+	if entity == nil {
+		return "", ErrUnknownEntity(q.Entity)
+	}
+
+	updates, err := c.tenantInsertValues(q, entity)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	b.WriteString("INSERT INTO ")
+	b.WriteString(c.tableName(entity, q.Tenant))
+
+	if len(updates) == 0 {
+		b.WriteString(" DEFAULT VALUES")
+	} else {
+		cols := make([]string, 0, len(updates))
+		placeholders := make([]string, 0, len(updates))
+		for _, u := range updates {
+			if u.Op != UpdateSetValue {
+				return "", NewCompilerError(fmt.Sprintf("INSERT does not support %s on field %s", u.Op, u.Field))
+			}
+			c.paramIdx++
+			c.params = append(c.params, u.Value)
+			cols = append(cols, c.quoteIdent(c.mapColumn(entity, u.Field)))
+			placeholders = append(placeholders, fmt.Sprintf("$%d", c.paramIdx))
+		}
+		b.WriteString(" (")
+		b.WriteString(strings.Join(cols, ", "))
+		b.WriteString(") VALUES (")
+		b.WriteString(strings.Join(placeholders, ", "))
+		b.WriteString(")")
+	}
+
+	c.compileReturning(&b, q.Returning, entity)
+
 	return b.String(), nil
 }
 
-// compileWhereWithSoftDelete compiles WHERE clause and adds soft delete condition.
-func (c *SQLCompiler) compileWhereWithSoftDelete(where *WhereClause, entity *EntityMeta) (string, error) {
+// tenantInsertValues returns the values of an INSERT, with the tenant column
+// set for tenant-scoped entities. A tenant column value naming another
+// tenant is rejected.
+func (c *SQLCompiler) tenantInsertValues(q *Query, entity *EntityMeta) ([]UpdateSet, error) {
+	if entity.TenantColumn == "" {
+		return q.Updates, nil
+	}
+	if q.Tenant == nil || q.Tenant.ID == "" {
+		return nil, ErrTenantRequired(entity.TableName)
+	}
+
+	updates := make([]UpdateSet, 0, len(q.Updates)+1)
+	for _, u := range q.Updates {
+		if c.mapColumn(entity, u.Field) != entity.TenantColumn {
+			updates = append(updates, u)
+			continue
+		}
+		if u.Op != UpdateSetValue || fmt.Sprint(u.Value) != q.Tenant.ID {
+			return nil, ErrTenantColumnWrite(entity.TableName)
+		}
+	}
+	return append(updates, UpdateSet{Field: entity.TenantColumn, Op: UpdateSetValue, Value: q.Tenant.ID}), nil
+}
+
+// compileReturning appends a RETURNING clause for the given fields.
+func (c *SQLCompiler) compileReturning(b *strings.Builder, fields []string, entity *EntityMeta) {
+	if len(fields) == 0 {
+		return
+	}
+
+	cols := make([]string, len(fields))
+	for i, f := range fields {
+		if f == "*" {
+			cols[i] = "*"
+		} else {
+			cols[i] = c.quoteIdent(c.mapColumn(entity, f))
+		}
+	}
+	b.WriteString(" RETURNING ")
+	b.WriteString(strings.Join(cols, ", "))
+}
+
+// compileScopedWhere compiles a WHERE clause and adds the tenant and soft
+// delete conditions of the entity.
+func (c *SQLCompiler) compileScopedWhere(where *WhereClause, entity *EntityMeta, tenant *TenantScope) (string, error) {
 	var conditions []string
 
 	if where != nil {
@@ -295,6 +389,16 @@ func (c *SQLCompiler) compileWhereWithSoftDelete(where *WhereClause, entity *Ent
 		}
 	}
 
+	// Add tenant condition
+	if entity.TenantColumn != "" {
+		if tenant == nil || tenant.ID == "" {
+			return "", ErrTenantRequired(entity.TableName)
+		}
+		c.paramIdx++
+		c.params = append(c.params, tenant.ID)
+		conditions = append(conditions, fmt.Sprintf("%s = $%d", c.quoteIdent(entity.TenantColumn), c.paramIdx))
+	}
+
 	// Add soft delete condition
 	if entity.SoftDelete != "" {
 		conditions = append(conditions, fmt.Sprintf("%s IS NULL", c.quoteIdent(entity.SoftDelete)))
@@ -536,6 +640,15 @@ func (c *SQLCompiler) getEntity(name string) *EntityMeta {
 	return nil
 }
 
+// tableName returns the quoted table of entity, qualified with the tenant's
+// schema if it has one.
+func (c *SQLCompiler) tableName(entity *EntityMeta, tenant *TenantScope) string {
+	if tenant != nil && tenant.Schema != "" {
+		return c.quoteIdent(tenant.Schema) + "." + c.quoteIdent(entity.TableName)
+	}
+	return c.quoteIdent(entity.TableName)
+}
+
 // mapColumn maps a field name to its database column name.
 func (c *SQLCompiler) mapColumn(entity *EntityMeta, field string) string {
 	if entity.Columns != nil {
@@ -559,6 +559,58 @@ func TestCompiler_UpdateDecrement(t *testing.T) {
 	assert.Contains(t, compiled.SQL, `- $1`)
 }
 
+func TestCompiler_Insert(t *testing.T) {
+	compiler := NewSQLCompiler(testEntities())
+
+	q := &Query{
+		Type:   QueryInsert,
+		Entity: "posts",
+		Updates: []UpdateSet{
+			{Field: "title", Value: "Hello", Op: UpdateSetValue},
+			{Field: "userId", Value: 7, Op: UpdateSetValue},
+		},
+		Returning: []string{"*"},
+	}
+
+	compiled, err := compiler.Compile(q)
+	require.NoError(t, err)
+	assert.Equal(t, `INSERT INTO "posts" ("title", "user_id") VALUES ($1, $2) RETURNING *`, compiled.SQL)
+	assert.Equal(t, []interface{}{"Hello", 7}, compiled.Params)
+}
+
+func TestCompiler_InsertDefaultValues(t *testing.T) {
+	compiler := NewSQLCompiler(testEntities())
+
+	compiled, err := compiler.Compile(&Query{Type: QueryInsert, Entity: "orders", Returning: []string{"id"}})
+	require.NoError(t, err)
+	assert.Equal(t, `INSERT INTO "orders" DEFAULT VALUES RETURNING "id"`, compiled.SQL)
+}
+
+func TestCompiler_InsertRejectsIncrement(t *testing.T) {
+	compiler := NewSQLCompiler(testEntities())
+
+	_, err := compiler.Compile(&Query{
+		Type:    QueryInsert,
+		Entity:  "orders",
+		Updates: []UpdateSet{{Field: "amount", Value: 1, Op: UpdateIncrement}},
+	})
+	assert.Error(t, err)
+}
+
+func TestCompiler_UpdateReturning(t *testing.T) {
+	compiler := NewSQLCompiler(testEntities())
+
+	q := Update("posts").
+		Set("title", "Updated").
+		Where("id", OpEquals, 1).
+		Returning("id", "userId").
+		Build()
+
+	compiled, err := compiler.Compile(q)
+	require.NoError(t, err)
+	assert.Equal(t, `UPDATE "posts" SET "title" = $1 WHERE "id" = $2 RETURNING "id", "user_id"`, compiled.SQL)
+}
+
 func TestCompiler_Delete(t *testing.T) {
 	compiler := NewSQLCompiler(testEntities())
 
@@ -1100,3 +1152,106 @@ func TestCompiler_IncludesOperator(t *testing.T) {
 	require.NoError(t, err)
 	assert.Contains(t, compiled.SQL, "@>")
 }
+
+func tenantEntities() map[string]*EntityMeta {
+	return map[string]*EntityMeta{
+		"projects": {
+			TableName:    "projects",
+			PrimaryKey:   "id",
+			SoftDelete:   "deleted_at",
+			TenantColumn: "tenant_id",
+			Columns: map[string]string{
+				"id":       "id",
+				"name":     "name",
+				"tenantId": "tenant_id",
+			},
+		},
+	}
+}
+
+func TestCompiler_TenantScope(t *testing.T) {
+	entities := tenantEntities()
+	entities["posts"] = testEntities()["posts"]
+	compiler := NewSQLCompiler(entities)
+	acme := &TenantScope{ID: "acme"}
+
+	tests := []struct {
+		name       string
+		query      *Query
+		wantSQL    string
+		wantParams []interface{}
+	}{
+		{
+			name:       "select",
+			query:      Select("projects").Where("name", OpEquals, "x").Tenant(acme).Build(),
+			wantSQL:    `SELECT * FROM "projects" WHERE ("name" = $1) AND ("tenant_id" = $2) AND ("deleted_at" IS NULL)`,
+			wantParams: []interface{}{"x", "acme"},
+		},
+		{
+			name:       "count",
+			query:      &Query{Type: QueryCount, Entity: "projects", Tenant: acme},
+			wantSQL:    `SELECT COUNT(*) FROM "projects" WHERE ("tenant_id" = $1) AND ("deleted_at" IS NULL)`,
+			wantParams: []interface{}{"acme"},
+		},
+		{
+			name:       "update",
+			query:      Update("projects").Set("name", "y").Where("id", OpEquals, 1).Tenant(acme).Build(),
+			wantSQL:    `UPDATE "projects" SET "name" = $1 WHERE ("id" = $2) AND ("tenant_id" = $3) AND ("deleted_at" IS NULL)`,
+			wantParams: []interface{}{"y", 1, "acme"},
+		},
+		{
+			name:       "soft delete",
+			query:      Delete("projects").Where("id", OpEquals, 1).Tenant(acme).Build(),
+			wantSQL:    `UPDATE "projects" SET "deleted_at" = NOW() WHERE ("id" = $1) AND ("tenant_id" = $2) AND ("deleted_at" IS NULL)`,
+			wantParams: []interface{}{1, "acme"},
+		},
+		{
+			name:       "insert",
+			query:      Insert("projects").Set("name", "z").Tenant(acme).Build(),
+			wantSQL:    `INSERT INTO "projects" ("name", "tenant_id") VALUES ($1, $2)`,
+			wantParams: []interface{}{"z", "acme"},
+		},
+		{
+			name:       "insert with own tenant",
+			query:      Insert("projects").Set("name", "z").Set("tenantId", "acme").Tenant(acme).Build(),
+			wantSQL:    `INSERT INTO "projects" ("name", "tenant_id") VALUES ($1, $2)`,
+			wantParams: []interface{}{"z", "acme"},
+		},
+		{
+			name:       "schema",
+			query:      &Query{Type: QuerySelect, Entity: "posts", Tenant: &TenantScope{Schema: "tenant_acme"}},
+			wantSQL:    `SELECT * FROM "tenant_acme"."posts"`,
+			wantParams: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := compiler.Compile(tt.query)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantSQL, result.SQL)
+			assert.Equal(t, tt.wantParams, result.Params)
+		})
+	}
+}
+
+func TestCompiler_TenantScopeRequired(t *testing.T) {
+	compiler := NewSQLCompiler(tenantEntities())
+
+	queries := map[string]*Query{
+		"select without tenant": Select("projects").Build(),
+		"delete without tenant": Delete("projects").Where("id", OpEquals, 1).Build(),
+		"insert without tenant": Insert("projects").Set("name", "z").Build(),
+		"insert into another tenant": Insert("projects").Set("tenantId", "globex").
+			Tenant(&TenantScope{ID: "acme"}).Build(),
+		"update moving tenant": Update("projects").Set("tenantId", "globex").
+			Tenant(&TenantScope{ID: "acme"}).Build(),
+	}
+
+	for name, q := range queries {
+		t.Run(name, func(t *testing.T) {
+			_, err := compiler.Compile(q)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -239,6 +239,18 @@ func ErrUnknownEntity(name string) *QueryError {
 	return NewCompilerError(fmt.Sprintf("unknown entity %q", name))
 }
 
+// ErrTenantRequired creates an error for a query on a tenant-scoped entity
+// that has no tenant.
+func ErrTenantRequired(entity string) *QueryError {
+	return NewCompilerError(fmt.Sprintf("entity %q is tenant-scoped but the query has no tenant", entity))
+}
+
+// ErrTenantColumnWrite creates an error for a write that would move a row
+// to another tenant.
+func ErrTenantColumnWrite(entity string) *QueryError {
+	return NewCompilerError(fmt.Sprintf("cannot write the tenant column of entity %q", entity))
+}
+
 // ErrUnknownField creates an error for an unknown field.
 func ErrUnknownField(entity, field string) *QueryError {
 	return NewCompilerError(fmt.Sprintf("unknown field %q on entity %q", field, entity))
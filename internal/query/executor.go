@@ -130,6 +130,28 @@ func (e *Executor) ExecuteDelete(ctx context.Context, q *Query) (int64, error) {
 	return result.RowsAffected()
 }
 
+// ExecuteInsert executes an INSERT query and returns the inserted row.
+// All columns are returned unless q.Returning is set.
+func (e *Executor) ExecuteInsert(ctx context.Context, q *Query) (map[string]interface{}, error) {
+	if q.Type != QueryInsert {
+		return nil, NewCompilerError("expected INSERT query")
+	}
+	if len(q.Returning) == 0 {
+		q.Returning = []string{"*"}
+	}
+
+	results, err := e.Execute(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(results) == 0 {
+		return nil, nil
+	}
+
+	return results[0], nil
+}
+
 // ExecuteOne executes a query and returns a single result.
 func (e *Executor) ExecuteOne(ctx context.Context, q *Query) (map[string]interface{}, error) {
 	// Add LIMIT 1 if not already set
@@ -229,6 +251,16 @@ func Delete(entity string) *QueryBuilder {
 	}
 }
 
+// Insert creates a new INSERT query builder. Column values are added with Set.
+func Insert(entity string) *QueryBuilder {
+	return &QueryBuilder{
+		query: &Query{
+			Type:   QueryInsert,
+			Entity: entity,
+		},
+	}
+}
+
 // Fields specifies the fields to select.
 func (qb *QueryBuilder) Fields(fields ...string) *QueryBuilder {
 	qb.query.Fields = fields
@@ -259,6 +291,12 @@ func (qb *QueryBuilder) WhereClause(where *WhereClause) *QueryBuilder {
 	return qb
 }
 
+// Tenant restricts the query to the data of one tenant.
+func (qb *QueryBuilder) Tenant(scope *TenantScope) *QueryBuilder {
+	qb.query.Tenant = scope
+	return qb
+}
+
 // OrderBy adds an ORDER BY clause.
 func (qb *QueryBuilder) OrderBy(field string, direction OrderDirection) *QueryBuilder {
 	qb.query.OrderBy = append(qb.query.OrderBy, OrderClause{
@@ -316,6 +354,12 @@ func (qb *QueryBuilder) Decrement(field string, value interface{}) *QueryBuilder
 	return qb
 }
 
+// Returning sets the fields returned by an INSERT, UPDATE or DELETE query.
+func (qb *QueryBuilder) Returning(fields ...string) *QueryBuilder {
+	qb.query.Returning = fields
+	return qb
+}
+
 // Build returns the constructed query.
 func (qb *QueryBuilder) Build() *Query {
 	return qb.query
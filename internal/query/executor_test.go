@@ -84,6 +84,26 @@ func TestQueryBuilder_Update(t *testing.T) {
 	assert.Equal(t, UpdateIncrement, q.Updates[2].Op)
 }
 
+func TestQueryBuilder_Insert(t *testing.T) {
+	q := Insert("users").
+		Set("name", "John").
+		Set("status", "active").
+		Returning("id").
+		Build()
+
+	assert.Equal(t, QueryInsert, q.Type)
+	assert.Equal(t, "users", q.Entity)
+	assert.Len(t, q.Updates, 2)
+	assert.Equal(t, []string{"id"}, q.Returning)
+}
+
+func TestExecutor_ExecuteInsertRequiresInsert(t *testing.T) {
+	exec := NewExecutor(&MockDB{}, nil)
+
+	_, err := exec.ExecuteInsert(context.Background(), Select("users").Build())
+	assert.Error(t, err)
+}
+
 func TestQueryBuilder_Delete(t *testing.T) {
 	q := Delete("users").
 		Where("id", OpEquals, 123).
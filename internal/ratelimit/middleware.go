@@ -0,0 +1,106 @@
+package ratelimit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bargom/codeai/internal/auth"
+)
+
+// KeyFunc returns the key a request is counted under.
+type KeyFunc func(r *http.Request) string
+
+// KeyByIP counts requests per client IP address.
+func KeyByIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// KeyBySubject counts requests per authenticated user, using the subject of
+// the JWT validated by the auth middleware. Anonymous requests are counted
+// per IP.
+func KeyBySubject(r *http.Request) string {
+	if user := auth.UserFromContext(r.Context()); user != nil && user.ID != "" {
+		return "sub:" + user.ID
+	}
+	return KeyByIP(r)
+}
+
+// KeyByAPIKey counts requests per API key, read from header or else the
+// api_key query parameter. Keys are hashed so they are not stored in
+// plain text. Requests without a key are counted per IP.
+func KeyByAPIKey(header string) KeyFunc {
+	if header == "" {
+		header = "X-API-Key"
+	}
+	return func(r *http.Request) string {
+		key := r.Header.Get(header)
+		if key == "" {
+			key = r.URL.Query().Get("api_key")
+		}
+		if key == "" {
+			return KeyByIP(r)
+		}
+		sum := sha256.Sum256([]byte(key))
+		return "key:" + hex.EncodeToString(sum[:16])
+	}
+}
+
+// Middleware rejects requests over the limit with 429 Too Many Requests.
+// Every response carries the RateLimit-Limit, RateLimit-Remaining,
+// RateLimit-Reset and RateLimit-Policy headers, and rejected ones
+// Retry-After. If the store fails, requests are let through.
+func Middleware(limiter *Limiter, keyFunc KeyFunc) func(http.Handler) http.Handler {
+	if keyFunc == nil {
+		keyFunc = KeyByIP
+	}
+	cfg := limiter.Config()
+	policy := fmt.Sprintf("%d;w=%d", cfg.Limit, seconds(cfg.Window))
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			result, err := limiter.Allow(r.Context(), keyFunc(r))
+			if err != nil {
+				slog.Warn("rate limit check failed, allowing request", "error", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h := w.Header()
+			h.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+			h.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			h.Set("RateLimit-Reset", strconv.FormatInt(seconds(result.Reset), 10))
+			h.Set("RateLimit-Policy", policy)
+
+			if !result.Allowed {
+				retryAfter := max(seconds(result.RetryAfter), 1)
+				h.Set("Retry-After", strconv.FormatInt(retryAfter, 10))
+				h.Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusTooManyRequests)
+				_ = json.NewEncoder(w).Encode(map[string]any{
+					"error":   http.StatusText(http.StatusTooManyRequests),
+					"message": fmt.Sprintf("rate limit exceeded, retry in %d seconds", retryAfter),
+				})
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// seconds rounds d up to whole seconds, as the rate limit headers expect.
+func seconds(d time.Duration) int64 {
+	return int64(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,104 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bargom/codeai/internal/auth"
+)
+
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+})
+
+func TestMiddleware(t *testing.T) {
+	l, _ := newTestLimiter(t, Config{Limit: 2, Window: time.Minute})
+	handler := Middleware(l, KeyByIP)(okHandler)
+
+	for i := 1; i >= 0; i-- {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, string(rune('0'+i)), w.Header().Get("RateLimit-Remaining"))
+		assert.Equal(t, "60", w.Header().Get("RateLimit-Reset"))
+		assert.Equal(t, "2;w=60", w.Header().Get("RateLimit-Policy"))
+		assert.Empty(t, w.Header().Get("Retry-After"))
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+	assert.Contains(t, w.Body.String(), "rate limit exceeded")
+
+	// A different client is not affected
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "198.51.100.7:4321"
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+type failingStore struct{}
+
+func (failingStore) Incr(context.Context, string, time.Duration) (int64, error) {
+	return 0, errors.New("store unavailable")
+}
+
+func (failingStore) Count(context.Context, string) (int64, error) {
+	return 0, errors.New("store unavailable")
+}
+
+func (failingStore) Take(context.Context, string, int64, time.Duration) (int64, time.Duration, error) {
+	return 0, 0, errors.New("store unavailable")
+}
+
+func TestMiddleware_StoreFailureAllowsRequests(t *testing.T) {
+	l, err := New(Config{Limit: 1, Window: time.Minute}, failingStore{})
+	require.NoError(t, err)
+	handler := Middleware(l, nil)(okHandler)
+
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+}
+
+func TestKeyFuncs(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "203.0.113.9:1234"
+	assert.Equal(t, "ip:203.0.113.9", KeyByIP(req))
+
+	t.Run("subject", func(t *testing.T) {
+		assert.Equal(t, "ip:203.0.113.9", KeyBySubject(req), "anonymous requests fall back to IP")
+
+		authed := req.WithContext(auth.ContextWithUser(req.Context(), &auth.User{ID: "user-42"}))
+		assert.Equal(t, "sub:user-42", KeyBySubject(authed))
+	})
+
+	t.Run("api key", func(t *testing.T) {
+		keyFunc := KeyByAPIKey("")
+		assert.Equal(t, "ip:203.0.113.9", keyFunc(req))
+
+		withHeader := req.Clone(req.Context())
+		withHeader.Header.Set("X-API-Key", "secret")
+		withQuery := httptest.NewRequest("GET", "/?api_key=secret", nil)
+
+		key := keyFunc(withHeader)
+		assert.Equal(t, key, keyFunc(withQuery))
+		assert.NotContains(t, key, "secret")
+
+		custom := req.Clone(req.Context())
+		custom.Header.Set("Authorization-Key", "other")
+		assert.NotEqual(t, key, KeyByAPIKey("Authorization-Key")(custom))
+	})
+}
@@ -0,0 +1,211 @@
+// Package ratelimit limits how often clients may make requests, using
+// token-bucket, fixed-window or sliding-window strategies over pluggable
+// counter storage.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Strategy selects the rate limiting algorithm.
+type Strategy string
+
+const (
+	// TokenBucket allows bursts of up to Limit requests and refills one
+	// token every Window/Limit.
+	TokenBucket Strategy = "token_bucket"
+	// FixedWindow allows Limit requests in each Window-aligned period.
+	FixedWindow Strategy = "fixed_window"
+	// SlidingWindow approximates a rolling window by weighting the previous
+	// fixed window's count by how much of it still overlaps the rolling one.
+	SlidingWindow Strategy = "sliding_window"
+)
+
+// Config holds rate limiter configuration.
+type Config struct {
+	// Limit is the number of requests allowed per Window
+	Limit int
+	// Window is the period Limit applies to
+	Window time.Duration
+	// Strategy is the algorithm used; defaults to FixedWindow
+	Strategy Strategy
+	// Prefix namespaces the limiter's keys in the store
+	Prefix string
+}
+
+// Result describes the outcome of a rate limit check.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is the time until the limit is fully available again
+	Reset time.Duration
+	// RetryAfter is the time until a rejected request may be retried
+	RetryAfter time.Duration
+}
+
+// Limiter checks requests against a rate limit.
+type Limiter struct {
+	config Config
+	store  Store
+	now    func() time.Time
+}
+
+// New creates a limiter that keeps its state in store.
+func New(cfg Config, store Store) (*Limiter, error) {
+	if cfg.Limit <= 0 {
+		return nil, fmt.Errorf("rate limit must be positive, got %d", cfg.Limit)
+	}
+	if cfg.Window <= 0 {
+		return nil, fmt.Errorf("rate limit window must be positive, got %s", cfg.Window)
+	}
+	if store == nil {
+		return nil, fmt.Errorf("rate limit store is required")
+	}
+
+	switch cfg.Strategy {
+	case "":
+		cfg.Strategy = FixedWindow
+	case TokenBucket, FixedWindow, SlidingWindow:
+	default:
+		return nil, fmt.Errorf("unknown rate limit strategy %q", cfg.Strategy)
+	}
+
+	return &Limiter{config: cfg, store: store, now: time.Now}, nil
+}
+
+// Config returns the limiter configuration.
+func (l *Limiter) Config() Config {
+	return l.config
+}
+
+// Allow records a request for key and reports whether it is within the limit.
+func (l *Limiter) Allow(ctx context.Context, key string) (Result, error) {
+	switch l.config.Strategy {
+	case TokenBucket:
+		return l.allowTokenBucket(ctx, key)
+	case SlidingWindow:
+		return l.allowSlidingWindow(ctx, key)
+	default:
+		return l.allowFixedWindow(ctx, key)
+	}
+}
+
+func (l *Limiter) allowTokenBucket(ctx context.Context, key string) (Result, error) {
+	interval := l.config.Window / time.Duration(l.config.Limit)
+	if interval <= 0 {
+		interval = time.Nanosecond
+	}
+
+	remaining, wait, err := l.store.Take(ctx, l.storeKey(key, "tb"), int64(l.config.Limit), interval)
+	if err != nil {
+		return Result{}, fmt.Errorf("taking token: %w", err)
+	}
+
+	result := Result{
+		Allowed:   wait == 0,
+		Limit:     l.config.Limit,
+		Remaining: int(remaining),
+		Reset:     time.Duration(int64(l.config.Limit)-remaining) * interval,
+	}
+	if !result.Allowed {
+		result.RetryAfter = wait
+	}
+	return result, nil
+}
+
+func (l *Limiter) allowFixedWindow(ctx context.Context, key string) (Result, error) {
+	window, elapsed := l.window()
+	reset := l.config.Window - elapsed
+
+	count, err := l.store.Incr(ctx, l.windowKey(key, window), l.config.Window)
+	if err != nil {
+		return Result{}, fmt.Errorf("incrementing counter: %w", err)
+	}
+
+	result := Result{
+		Allowed:   count <= int64(l.config.Limit),
+		Limit:     l.config.Limit,
+		Remaining: max(l.config.Limit-int(count), 0),
+		Reset:     reset,
+	}
+	if !result.Allowed {
+		result.RetryAfter = reset
+	}
+	return result, nil
+}
+
+func (l *Limiter) allowSlidingWindow(ctx context.Context, key string) (Result, error) {
+	window, elapsed := l.window()
+	reset := l.config.Window - elapsed
+	overlap := 1 - float64(elapsed)/float64(l.config.Window)
+
+	previous, err := l.store.Count(ctx, l.windowKey(key, window-1))
+	if err != nil {
+		return Result{}, fmt.Errorf("reading counter: %w", err)
+	}
+	current, err := l.store.Count(ctx, l.windowKey(key, window))
+	if err != nil {
+		return Result{}, fmt.Errorf("reading counter: %w", err)
+	}
+
+	result := Result{Limit: l.config.Limit, Reset: reset}
+
+	// Requests over the limit are not counted, so a client that keeps
+	// retrying is not locked out of the next window.
+	if float64(previous)*overlap+float64(current+1) <= float64(l.config.Limit) {
+		// Keep the counter through the next window, where it is "previous"
+		current, err = l.store.Incr(ctx, l.windowKey(key, window), 2*l.config.Window)
+		if err != nil {
+			return Result{}, fmt.Errorf("incrementing counter: %w", err)
+		}
+		estimate := float64(previous)*overlap + float64(current)
+		if estimate <= float64(l.config.Limit) {
+			result.Allowed = true
+			result.Remaining = int(math.Floor(float64(l.config.Limit) - estimate))
+			return result, nil
+		}
+	}
+
+	result.RetryAfter = l.slidingRetryAfter(previous, current, elapsed)
+	return result, nil
+}
+
+// slidingRetryAfter returns how long until the weighted count of the
+// previous window has decayed enough to admit one more request.
+func (l *Limiter) slidingRetryAfter(previous, current int64, elapsed time.Duration) time.Duration {
+	untilNext := l.config.Window - elapsed
+	free := float64(int64(l.config.Limit) - current - 1)
+	if previous == 0 || free < 0 {
+		return untilNext
+	}
+
+	// previous * (1 - t/window) <= free  =>  t >= window * (1 - free/previous)
+	at := time.Duration(float64(l.config.Window) * (1 - free/float64(previous)))
+	if at <= elapsed {
+		return time.Millisecond
+	}
+	return min(at-elapsed, untilNext)
+}
+
+// window returns the index of the current fixed window and how far into it
+// the current time is.
+func (l *Limiter) window() (int64, time.Duration) {
+	now := l.now().UnixNano()
+	size := int64(l.config.Window)
+	return now / size, time.Duration(now % size)
+}
+
+func (l *Limiter) windowKey(key string, window int64) string {
+	return l.storeKey(key, fmt.Sprintf("%d", window))
+}
+
+func (l *Limiter) storeKey(key, suffix string) string {
+	if l.config.Prefix != "" {
+		return "ratelimit:" + l.config.Prefix + ":" + key + ":" + suffix
+	}
+	return "ratelimit:" + key + ":" + suffix
+}
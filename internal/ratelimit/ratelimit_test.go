@@ -0,0 +1,198 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bargom/codeai/internal/cache"
+)
+
+func newMemoryStore(t *testing.T) *MemoryStore {
+	t.Helper()
+	c := cache.NewMemoryCache(cache.Config{DefaultTTL: time.Hour})
+	t.Cleanup(func() { c.Close() })
+	return NewMemoryStore(c)
+}
+
+// newTestLimiter returns a limiter whose clock is set by the returned func.
+func newTestLimiter(t *testing.T, cfg Config) (*Limiter, func(time.Time)) {
+	t.Helper()
+	l, err := New(cfg, newMemoryStore(t))
+	require.NoError(t, err)
+
+	now := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { return now }
+	return l, func(t time.Time) { now = t }
+}
+
+func TestNew(t *testing.T) {
+	store := newMemoryStore(t)
+
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{"valid", Config{Limit: 10, Window: time.Minute}, false},
+		{"zero limit", Config{Limit: 0, Window: time.Minute}, true},
+		{"zero window", Config{Limit: 10}, true},
+		{"unknown strategy", Config{Limit: 10, Window: time.Minute, Strategy: "leaky"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.cfg, store)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	_, err := New(Config{Limit: 1, Window: time.Second}, nil)
+	assert.Error(t, err, "store is required")
+
+	l, err := New(Config{Limit: 1, Window: time.Second}, store)
+	require.NoError(t, err)
+	assert.Equal(t, FixedWindow, l.Config().Strategy)
+}
+
+func TestLimiter_FixedWindow(t *testing.T) {
+	l, setNow := newTestLimiter(t, Config{Limit: 3, Window: time.Minute, Strategy: FixedWindow})
+	ctx := context.Background()
+	start := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	setNow(start.Add(20 * time.Second))
+
+	for i := 2; i >= 0; i-- {
+		res, err := l.Allow(ctx, "client")
+		require.NoError(t, err)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, i, res.Remaining)
+		assert.Equal(t, 40*time.Second, res.Reset)
+	}
+
+	res, err := l.Allow(ctx, "client")
+	require.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+	assert.Equal(t, 40*time.Second, res.RetryAfter)
+
+	// Other keys have their own limit
+	res, err = l.Allow(ctx, "other")
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+
+	// The next window starts from zero
+	setNow(start.Add(time.Minute))
+	res, err = l.Allow(ctx, "client")
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 2, res.Remaining)
+}
+
+func TestLimiter_SlidingWindow(t *testing.T) {
+	l, setNow := newTestLimiter(t, Config{Limit: 4, Window: time.Minute, Strategy: SlidingWindow})
+	ctx := context.Background()
+	start := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+
+	setNow(start.Add(50 * time.Second))
+	for i := 0; i < 4; i++ {
+		res, err := l.Allow(ctx, "client")
+		require.NoError(t, err)
+		require.True(t, res.Allowed)
+	}
+
+	// 15s into the next window, 3 of the previous 4 requests still count
+	setNow(start.Add(75 * time.Second))
+	res, err := l.Allow(ctx, "client")
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+
+	res, err = l.Allow(ctx, "client")
+	require.NoError(t, err)
+	assert.False(t, res.Allowed)
+	// 1 + 4*(1-t/60) <= 3 once t >= 30s, 15s from now
+	assert.Equal(t, 15*time.Second, res.RetryAfter)
+
+	// Rejected requests are not counted
+	setNow(start.Add(90 * time.Second))
+	res, err = l.Allow(ctx, "client")
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+}
+
+func TestLimiter_TokenBucket(t *testing.T) {
+	l, _ := newTestLimiter(t, Config{Limit: 2, Window: time.Hour, Strategy: TokenBucket})
+	ctx := context.Background()
+
+	res, err := l.Allow(ctx, "client")
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 1, res.Remaining)
+
+	res, err = l.Allow(ctx, "client")
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+	assert.Equal(t, time.Hour, res.Reset)
+
+	res, err = l.Allow(ctx, "client")
+	require.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.InDelta(t, float64(30*time.Minute), float64(res.RetryAfter), float64(time.Second))
+}
+
+func TestBucket_Take(t *testing.T) {
+	const interval = 10 * time.Second
+	var b bucket
+	now := int64(1_000_000_000)
+
+	remaining, wait := b.take(3, interval, now)
+	assert.Equal(t, int64(2), remaining)
+	assert.Zero(t, wait)
+
+	b.take(3, interval, now)
+	b.take(3, interval, now)
+	remaining, wait = b.take(3, interval, now+int64(4*time.Second))
+	assert.Equal(t, int64(0), remaining)
+	assert.Equal(t, 6*time.Second, wait)
+
+	// Two intervals later two tokens are back; one is taken
+	remaining, wait = b.take(3, interval, now+int64(2*interval))
+	assert.Equal(t, int64(1), remaining)
+	assert.Zero(t, wait)
+
+	// Refills never exceed the capacity
+	remaining, _ = b.take(3, interval, now+int64(time.Hour))
+	assert.Equal(t, int64(2), remaining)
+}
+
+func TestMemoryStore_IncrExpiry(t *testing.T) {
+	store := newMemoryStore(t)
+	ctx := context.Background()
+
+	// The counter expires after its own TTL, not the cache's default
+	n, err := store.Incr(ctx, "counter", 100*time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	// Later increments keep the expiry of the first
+	time.Sleep(60 * time.Millisecond)
+	n, err = store.Incr(ctx, "counter", 100*time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+
+	time.Sleep(60 * time.Millisecond)
+	n, err = store.Count(ctx, "counter")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), n)
+
+	n, err = store.Incr(ctx, "counter", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n, "an expired counter starts again")
+}
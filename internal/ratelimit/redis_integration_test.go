@@ -0,0 +1,123 @@
+//go:build integration
+
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go/modules/redis"
+
+	"github.com/bargom/codeai/internal/cache"
+)
+
+func setupRedisStore(t *testing.T) (*RedisStore, func()) {
+	ctx := context.Background()
+
+	redisContainer, err := redis.Run(ctx, "redis:7-alpine")
+	require.NoError(t, err)
+
+	connStr, err := redisContainer.ConnectionString(ctx)
+	require.NoError(t, err)
+
+	c, err := cache.NewRedisCache(cache.Config{
+		Type:   "redis",
+		URL:    connStr,
+		Prefix: "test",
+	})
+	require.NoError(t, err)
+
+	cleanup := func() {
+		c.Close()
+		redisContainer.Terminate(ctx)
+	}
+
+	return NewRedisStore(c), cleanup
+}
+
+func TestRedisStore_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	store, cleanup := setupRedisStore(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	t.Run("counters", func(t *testing.T) {
+		n, err := store.Count(ctx, "counter")
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), n)
+
+		for i := int64(1); i <= 3; i++ {
+			n, err = store.Incr(ctx, "counter", time.Minute)
+			require.NoError(t, err)
+			assert.Equal(t, i, n)
+		}
+
+		n, err = store.Count(ctx, "counter")
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), n)
+	})
+
+	t.Run("counter expires", func(t *testing.T) {
+		_, err := store.Incr(ctx, "short", 100*time.Millisecond)
+		require.NoError(t, err)
+
+		time.Sleep(200 * time.Millisecond)
+
+		n, err := store.Count(ctx, "short")
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), n)
+	})
+
+	t.Run("counter without expiry", func(t *testing.T) {
+		// A counter left without an expiry by an interrupted increment
+		_, err := store.cache.Incr(ctx, "stuck")
+		require.NoError(t, err)
+
+		n, err := store.Incr(ctx, "stuck", 100*time.Millisecond)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), n)
+
+		time.Sleep(200 * time.Millisecond)
+
+		n, err = store.Count(ctx, "stuck")
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), n)
+	})
+
+	t.Run("token bucket", func(t *testing.T) {
+		for i := int64(1); i >= 0; i-- {
+			remaining, wait, err := store.Take(ctx, "bucket", 2, time.Hour)
+			require.NoError(t, err)
+			assert.Equal(t, i, remaining)
+			assert.Zero(t, wait)
+		}
+
+		_, wait, err := store.Take(ctx, "bucket", 2, time.Hour)
+		require.NoError(t, err)
+		assert.Greater(t, wait, 59*time.Minute)
+	})
+
+	t.Run("limiters share state", func(t *testing.T) {
+		cfg := Config{Limit: 2, Window: time.Minute, Strategy: SlidingWindow}
+		a, err := New(cfg, store)
+		require.NoError(t, err)
+		b, err := New(cfg, store)
+		require.NoError(t, err)
+
+		res, err := a.Allow(ctx, "client")
+		require.NoError(t, err)
+		assert.True(t, res.Allowed)
+		res, err = b.Allow(ctx, "client")
+		require.NoError(t, err)
+		assert.True(t, res.Allowed)
+		res, err = a.Allow(ctx, "client")
+		require.NoError(t, err)
+		assert.False(t, res.Allowed)
+	})
+}
@@ -0,0 +1,244 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bargom/codeai/internal/cache"
+)
+
+// Store holds rate limit state. Implementations must make each operation
+// atomic across all limiters sharing the store.
+type Store interface {
+	// Incr increments the counter at key and returns its new value. A new
+	// counter expires after ttl.
+	Incr(ctx context.Context, key string, ttl time.Duration) (int64, error)
+
+	// Count returns the value of the counter at key, or 0 if there is none.
+	Count(ctx context.Context, key string) (int64, error)
+
+	// Take takes a token from the bucket at key, which holds up to capacity
+	// tokens and regains one every interval. It returns the tokens left; if
+	// the bucket was empty, wait is the time until the next token.
+	Take(ctx context.Context, key string, capacity int64, interval time.Duration) (remaining int64, wait time.Duration, err error)
+}
+
+// MemoryStore keeps rate limit state in a cache.MemoryCache, so limits apply
+// per process.
+type MemoryStore struct {
+	cache *cache.MemoryCache
+	mu    sync.Mutex
+}
+
+// NewMemoryStore creates an in-process store.
+func NewMemoryStore(c *cache.MemoryCache) *MemoryStore {
+	return &MemoryStore{cache: c}
+}
+
+// counter is the persisted state of a MemoryStore counter. The cache resets
+// the expiry of a key on every write, so the counter carries the end of its
+// window and is rewritten with the time left.
+type counter struct {
+	N int64 `json:"n"`
+	// Expires is when the counter's window ends, in Unix nanoseconds
+	Expires int64 `json:"expires"`
+}
+
+// Incr implements Store. A new counter is set together with its expiry; an
+// existing one keeps its expiry.
+func (s *MemoryStore) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UnixNano()
+	var c counter
+	if err := s.cache.GetJSON(ctx, key, &c); err != nil && !errors.Is(err, cache.ErrCacheMiss) {
+		return 0, err
+	}
+	if c.Expires <= now {
+		c = counter{Expires: now + int64(ttl)}
+	}
+	c.N++
+
+	if err := s.cache.SetJSON(ctx, key, c, time.Duration(c.Expires-now)); err != nil {
+		return 0, err
+	}
+	return c.N, nil
+}
+
+// Count implements Store.
+func (s *MemoryStore) Count(ctx context.Context, key string) (int64, error) {
+	var c counter
+	if err := s.cache.GetJSON(ctx, key, &c); err != nil {
+		if errors.Is(err, cache.ErrCacheMiss) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	if c.Expires <= time.Now().UnixNano() {
+		return 0, nil
+	}
+	return c.N, nil
+}
+
+// bucket is the persisted state of a token bucket.
+type bucket struct {
+	Tokens int64 `json:"tokens"`
+	// Updated is when tokens were last refilled, in Unix nanoseconds
+	Updated int64 `json:"updated"`
+}
+
+// take refills b for the time since it was last updated and takes a token.
+// A missing bucket (zero Updated) starts full.
+func (b *bucket) take(capacity int64, interval time.Duration, now int64) (int64, time.Duration) {
+	if b.Updated == 0 {
+		b.Tokens = capacity
+		b.Updated = now
+	}
+	if elapsed := now - b.Updated; elapsed >= int64(interval) {
+		refill := elapsed / int64(interval)
+		b.Tokens += refill
+		b.Updated += refill * int64(interval)
+		if b.Tokens >= capacity {
+			b.Tokens = capacity
+			b.Updated = now
+		}
+	}
+
+	if b.Tokens == 0 {
+		return 0, time.Duration(b.Updated + int64(interval) - now)
+	}
+	b.Tokens--
+	return b.Tokens, 0
+}
+
+// Take implements Store.
+func (s *MemoryStore) Take(ctx context.Context, key string, capacity int64, interval time.Duration) (int64, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var b bucket
+	if err := s.cache.GetJSON(ctx, key, &b); err != nil && !errors.Is(err, cache.ErrCacheMiss) {
+		return 0, 0, err
+	}
+
+	remaining, wait := b.take(capacity, interval, time.Now().UnixNano())
+
+	// A bucket left alone for capacity intervals is full again, which is
+	// the same as no bucket at all.
+	if err := s.cache.SetJSON(ctx, key, b, time.Duration(capacity)*interval); err != nil {
+		return 0, 0, err
+	}
+	return remaining, wait, nil
+}
+
+// RedisStore keeps rate limit state in Redis, so limits are shared by every
+// instance using the same Redis.
+type RedisStore struct {
+	cache *cache.RedisCache
+}
+
+// NewRedisStore creates a distributed store.
+func NewRedisStore(c *cache.RedisCache) *RedisStore {
+	return &RedisStore{cache: c}
+}
+
+// incrScript increments a counter and sets its expiry in one step. A counter
+// without an expiry, left by an interrupted increment, is given one too.
+const incrScript = `
+local n = redis.call('INCR', KEYS[1])
+if redis.call('PTTL', KEYS[1]) == -1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return n
+`
+
+// Incr implements Store.
+func (s *RedisStore) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	result, err := s.cache.Eval(ctx, incrScript, []string{key}, max(ttl.Milliseconds(), 1))
+	if err != nil {
+		return 0, err
+	}
+	n, ok := result.(int64)
+	if !ok {
+		return 0, fmt.Errorf("unexpected counter reply %v", result)
+	}
+	return n, nil
+}
+
+// Count implements Store.
+func (s *RedisStore) Count(ctx context.Context, key string) (int64, error) {
+	data, err := s.cache.Get(ctx, key)
+	if errors.Is(err, cache.ErrCacheMiss) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("counter %s is not a number: %w", key, err)
+	}
+	return n, nil
+}
+
+// takeScript is bucket.take in Lua, using the Redis clock so that instances
+// with skewed clocks agree. Times are in microseconds.
+const takeScript = `
+local capacity = tonumber(ARGV[1])
+local interval = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'updated')
+local tokens = tonumber(state[1])
+local updated = tonumber(state[2])
+if tokens == nil or updated == nil then
+	tokens = capacity
+	updated = now
+end
+
+local elapsed = now - updated
+if elapsed >= interval then
+	local refill = math.floor(elapsed / interval)
+	tokens = tokens + refill
+	updated = updated + refill * interval
+	if tokens >= capacity then
+		tokens = capacity
+		updated = now
+	end
+end
+
+local wait = 0
+if tokens == 0 then
+	wait = updated + interval - now
+else
+	tokens = tokens - 1
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tokens, 'updated', updated)
+redis.call('PEXPIRE', KEYS[1], math.ceil(capacity * interval / 1000))
+return {tokens, wait}
+`
+
+// Take implements Store.
+func (s *RedisStore) Take(ctx context.Context, key string, capacity int64, interval time.Duration) (int64, time.Duration, error) {
+	micros := max(interval.Microseconds(), 1)
+
+	result, err := s.cache.Eval(ctx, takeScript, []string{key}, capacity, micros)
+	if err != nil {
+		return 0, 0, err
+	}
+	values, ok := result.([]any)
+	if !ok || len(values) != 2 {
+		return 0, 0, fmt.Errorf("unexpected token bucket reply %v", result)
+	}
+	remaining, _ := values[0].(int64)
+	wait, _ := values[1].(int64)
+	return remaining, time.Duration(wait) * time.Microsecond, nil
+}
@@ -0,0 +1,336 @@
+package rbac
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sort"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// AdminPermission is the permission required by the admin endpoints.
+const AdminPermission = "rbac:manage"
+
+// maxRequestBody limits the size of admin requests.
+const maxRequestBody = 64 << 10
+
+// Handler serves the admin endpoints managing roles and user role
+// assignments at runtime.
+type Handler struct {
+	engine      *Engine
+	storage     Storage
+	invalidator Invalidator
+	logger      *slog.Logger
+}
+
+// HandlerOption configures the admin handler.
+type HandlerOption func(*Handler)
+
+// WithInvalidator sets how caches are cleared after a change. By default
+// only the caches of the handler's engine are cleared; use a
+// RedisInvalidator when several instances share the storage.
+func WithInvalidator(invalidator Invalidator) HandlerOption {
+	return func(h *Handler) {
+		h.invalidator = invalidator
+	}
+}
+
+// WithHandlerLogger sets the logger of the handler.
+func WithHandlerLogger(logger *slog.Logger) HandlerOption {
+	return func(h *Handler) {
+		h.logger = logger.With("component", "rbac-admin")
+	}
+}
+
+// NewHandler creates the admin handler for the engine's storage.
+func NewHandler(engine *Engine, opts ...HandlerOption) *Handler {
+	h := &Handler{
+		engine:      engine,
+		storage:     engine.storage,
+		invalidator: localInvalidator{engine: engine},
+		logger:      slog.Default().With("component", "rbac-admin"),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// RegisterRoutes registers the /admin/roles and /admin/users/{id}/roles
+// routes. They require AdminPermission, so they must be mounted behind
+// authentication middleware.
+func (h *Handler) RegisterRoutes(r chi.Router) {
+	r.Group(func(r chi.Router) {
+		r.Use(NewMiddleware(h.engine).RequirePermission(AdminPermission))
+
+		r.Route("/admin/roles", func(r chi.Router) {
+			r.Get("/", h.ListRoles)
+			r.Post("/", h.CreateRole)
+			r.Get("/{name}", h.GetRole)
+			r.Put("/{name}", h.UpdateRole)
+			r.Delete("/{name}", h.DeleteRole)
+		})
+		r.Get("/admin/users/{id}/roles", h.GetUserRoles)
+		r.Put("/admin/users/{id}/roles", h.SetUserRoles)
+	})
+}
+
+// RoleRequest is the body of POST /admin/roles and PUT /admin/roles/{name}.
+type RoleRequest struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Permissions []string `json:"permissions"`
+	Parents     []string `json:"parents,omitempty"`
+}
+
+// RoleResponse represents a role in API responses.
+type RoleResponse struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Permissions []string `json:"permissions"`
+	Parents     []string `json:"parents"`
+}
+
+// UserRolesRequest is the body of PUT /admin/users/{id}/roles.
+type UserRolesRequest struct {
+	Roles []string `json:"roles"`
+}
+
+// UserRolesResponse lists the roles assigned to a user.
+type UserRolesResponse struct {
+	UserID string   `json:"user_id"`
+	Roles  []string `json:"roles"`
+}
+
+// ListRoles handles GET /admin/roles.
+func (h *Handler) ListRoles(w http.ResponseWriter, r *http.Request) {
+	roles, err := h.storage.ListRoles(r.Context())
+	if err != nil {
+		h.internalError(w, "cannot list roles", err)
+		return
+	}
+	sortRoles(roles)
+
+	resp := make([]RoleResponse, len(roles))
+	for i, role := range roles {
+		resp[i] = toRoleResponse(role)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"roles": resp})
+}
+
+// GetRole handles GET /admin/roles/{name}.
+func (h *Handler) GetRole(w http.ResponseWriter, r *http.Request) {
+	role, err := h.storage.GetRole(r.Context(), chi.URLParam(r, "name"))
+	if errors.Is(err, ErrRoleNotFound) {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		h.internalError(w, "cannot get role", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toRoleResponse(role))
+}
+
+// CreateRole handles POST /admin/roles.
+func (h *Handler) CreateRole(w http.ResponseWriter, r *http.Request) {
+	var req RoleRequest
+	if err := decodeRequest(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	_, err := h.storage.GetRole(r.Context(), req.Name)
+	if err == nil {
+		writeError(w, http.StatusConflict, ErrRoleAlreadyExists.Error())
+		return
+	}
+	if !errors.Is(err, ErrRoleNotFound) {
+		h.internalError(w, "cannot get role", err)
+		return
+	}
+
+	h.saveRole(w, r, req, http.StatusCreated)
+}
+
+// UpdateRole handles PUT /admin/roles/{name}, creating the role if it
+// doesn't exist.
+func (h *Handler) UpdateRole(w http.ResponseWriter, r *http.Request) {
+	var req RoleRequest
+	if err := decodeRequest(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	name := chi.URLParam(r, "name")
+	if req.Name != "" && req.Name != name {
+		writeError(w, http.StatusBadRequest, "role name does not match the URL")
+		return
+	}
+	req.Name = name
+
+	h.saveRole(w, r, req, http.StatusOK)
+}
+
+// DeleteRole handles DELETE /admin/roles/{name}.
+func (h *Handler) DeleteRole(w http.ResponseWriter, r *http.Request) {
+	err := h.storage.DeleteRole(r.Context(), chi.URLParam(r, "name"))
+	if errors.Is(err, ErrRoleNotFound) {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		h.internalError(w, "cannot delete role", err)
+		return
+	}
+	h.invalidate(r.Context())
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetUserRoles handles GET /admin/users/{id}/roles.
+func (h *Handler) GetUserRoles(w http.ResponseWriter, r *http.Request) {
+	store, ok := h.storage.(UserRoleStorage)
+	if !ok {
+		writeError(w, http.StatusNotImplemented, ErrUserRolesNotSupported.Error())
+		return
+	}
+
+	userID := chi.URLParam(r, "id")
+	roles, err := store.UserRoles(r.Context(), userID)
+	if err != nil {
+		h.internalError(w, "cannot get user roles", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, UserRolesResponse{UserID: userID, Roles: nonNilStrings(roles)})
+}
+
+// SetUserRoles handles PUT /admin/users/{id}/roles.
+func (h *Handler) SetUserRoles(w http.ResponseWriter, r *http.Request) {
+	store, ok := h.storage.(UserRoleStorage)
+	if !ok {
+		writeError(w, http.StatusNotImplemented, ErrUserRolesNotSupported.Error())
+		return
+	}
+
+	var req UserRolesRequest
+	if err := decodeRequest(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	roles, err := normalizeUserRoles(req.Roles)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	for _, name := range roles {
+		if _, err := h.storage.GetRole(r.Context(), name); err != nil {
+			if errors.Is(err, ErrRoleNotFound) {
+				writeError(w, http.StatusBadRequest, "unknown role: "+name)
+				return
+			}
+			h.internalError(w, "cannot get role", err)
+			return
+		}
+	}
+
+	userID := chi.URLParam(r, "id")
+	err = store.SetUserRoles(r.Context(), userID, roles)
+	if errors.Is(err, ErrUserRolesNotSupported) {
+		writeError(w, http.StatusNotImplemented, err.Error())
+		return
+	}
+	if err != nil {
+		h.internalError(w, "cannot set user roles", err)
+		return
+	}
+	h.invalidate(r.Context())
+	writeJSON(w, http.StatusOK, UserRolesResponse{UserID: userID, Roles: roles})
+}
+
+// saveRole validates and stores a role, checking that its parents exist
+// and don't inherit from it.
+func (h *Handler) saveRole(w http.ResponseWriter, r *http.Request, req RoleRequest, status int) {
+	role := &Role{
+		Name:        req.Name,
+		Description: req.Description,
+		Parents:     nonNilStrings(req.Parents),
+		Permissions: make([]Permission, len(req.Permissions)),
+	}
+	for i, p := range req.Permissions {
+		role.Permissions[i] = Permission(p)
+	}
+	if err := validateRole(role); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	roles, err := h.storage.ListRoles(r.Context())
+	if err != nil {
+		h.internalError(w, "cannot list roles", err)
+		return
+	}
+	policy := NewPolicy()
+	for _, existing := range roles {
+		policy.Roles[existing.Name] = existing
+	}
+	for _, parent := range role.Parents {
+		if _, ok := policy.Roles[parent]; !ok {
+			writeError(w, http.StatusBadRequest, "unknown parent role: "+parent)
+			return
+		}
+	}
+	if err := policy.checkCyclicInheritance(role.Name, role.Parents); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.storage.SaveRole(r.Context(), role); err != nil {
+		h.internalError(w, "cannot save role", err)
+		return
+	}
+	h.invalidate(r.Context())
+	writeJSON(w, status, toRoleResponse(role))
+}
+
+// invalidate clears cached permissions after a change. The change is
+// already stored, so a failure to notify other instances is only logged;
+// their caches catch up when their subscription is re-established.
+func (h *Handler) invalidate(ctx context.Context) {
+	if err := h.invalidator.Invalidate(ctx); err != nil {
+		h.logger.Error("cannot invalidate rbac caches", "error", err)
+	}
+}
+
+func (h *Handler) internalError(w http.ResponseWriter, msg string, err error) {
+	h.logger.Error(msg, "error", err)
+	writeError(w, http.StatusInternalServerError, "internal server error")
+}
+
+func toRoleResponse(role *Role) RoleResponse {
+	perms := make([]string, len(role.Permissions))
+	for i, p := range role.Permissions {
+		perms[i] = p.String()
+	}
+	return RoleResponse{
+		Name:        role.Name,
+		Description: role.Description,
+		Permissions: perms,
+		Parents:     nonNilStrings(role.Parents),
+	}
+}
+
+func sortRoles(roles []*Role) {
+	sort.Slice(roles, func(i, j int) bool { return roles[i].Name < roles[j].Name })
+}
+
+func decodeRequest(w http.ResponseWriter, r *http.Request, v any) error {
+	return json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody)).Decode(v)
+}
+
+func writeJSON(w http.ResponseWriter, status int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
@@ -0,0 +1,170 @@
+package rbac
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bargom/codeai/internal/auth"
+)
+
+// countingInvalidator records how often caches were invalidated.
+type countingInvalidator struct {
+	engine *Engine
+	calls  int
+}
+
+func (i *countingInvalidator) Invalidate(ctx context.Context) error {
+	i.calls++
+	i.engine.InvalidateCache()
+	return nil
+}
+
+func newAdminRouter(t *testing.T, user *auth.User) (http.Handler, *Engine, *countingInvalidator) {
+	t.Helper()
+	engine := NewEngine(NewMemoryStorageWithDefaults())
+	invalidator := &countingInvalidator{engine: engine}
+
+	r := chi.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if user != nil {
+				r = r.WithContext(auth.ContextWithUser(r.Context(), user))
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+	NewHandler(engine, WithInvalidator(invalidator)).RegisterRoutes(r)
+	return r, engine, invalidator
+}
+
+func doRequest(t *testing.T, h http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHandler_RequiresAdminPermission(t *testing.T) {
+	router, _, _ := newAdminRouter(t, nil)
+	rec := doRequest(t, router, http.MethodGet, "/admin/roles", "")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	router, _, _ = newAdminRouter(t, &auth.User{ID: "u1", Roles: []string{"editor"}})
+	rec = doRequest(t, router, http.MethodGet, "/admin/roles", "")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	router, _, _ = newAdminRouter(t, &auth.User{ID: "u1", Permissions: []string{AdminPermission}})
+	rec = doRequest(t, router, http.MethodGet, "/admin/roles", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestHandler_Roles(t *testing.T) {
+	router, engine, invalidator := newAdminRouter(t, &auth.User{ID: "root", Roles: []string{"admin"}})
+	ctx := context.Background()
+
+	rec := doRequest(t, router, http.MethodGet, "/admin/roles", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var list struct {
+		Roles []RoleResponse `json:"roles"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&list))
+	require.Len(t, list.Roles, 3)
+	assert.Equal(t, "admin", list.Roles[0].Name)
+
+	rec = doRequest(t, router, http.MethodPost, "/admin/roles",
+		`{"name":"auditor","permissions":["audit:read"],"parents":["viewer"]}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, 1, invalidator.calls)
+
+	rec = doRequest(t, router, http.MethodPost, "/admin/roles", `{"name":"auditor","permissions":[]}`)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	rec = doRequest(t, router, http.MethodGet, "/admin/roles/auditor", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var role RoleResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&role))
+	assert.Equal(t, []string{"audit:read"}, role.Permissions)
+	assert.Equal(t, []string{"viewer"}, role.Parents)
+
+	auditor := &auth.User{ID: "u1", Roles: []string{"auditor"}}
+	assert.True(t, engine.CheckPermission(ctx, auditor, "health:read"))
+
+	// Updating a parent changes the permissions of roles inheriting it
+	rec = doRequest(t, router, http.MethodPut, "/admin/roles/viewer", `{"permissions":["configs:read"]}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.False(t, engine.CheckPermission(ctx, auditor, "health:read"))
+
+	rec = doRequest(t, router, http.MethodPut, "/admin/roles/viewer", `{"permissions":[],"parents":["auditor"]}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), ErrCyclicInheritance.Error())
+
+	rec = doRequest(t, router, http.MethodPut, "/admin/roles/viewer", `{"permissions":[],"parents":["missing"]}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = doRequest(t, router, http.MethodPut, "/admin/roles/viewer", `{"name":"other","permissions":[]}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = doRequest(t, router, http.MethodPost, "/admin/roles", `{"name":"bad","permissions":["invalid"]}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = doRequest(t, router, http.MethodPost, "/admin/roles", `not json`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = doRequest(t, router, http.MethodDelete, "/admin/roles/auditor", "")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.False(t, engine.CheckPermission(ctx, auditor, "audit:read"))
+
+	rec = doRequest(t, router, http.MethodDelete, "/admin/roles/auditor", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	rec = doRequest(t, router, http.MethodGet, "/admin/roles/auditor", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	assert.Equal(t, 3, invalidator.calls)
+}
+
+func TestHandler_UserRoles(t *testing.T) {
+	router, engine, _ := newAdminRouter(t, &auth.User{ID: "root", Roles: []string{"admin"}})
+	ctx := context.Background()
+	user := &auth.User{ID: "user-1"}
+
+	rec := doRequest(t, router, http.MethodGet, "/admin/users/user-1/roles", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"user_id":"user-1","roles":[]}`, rec.Body.String())
+	assert.False(t, engine.CheckPermission(ctx, user, "configs:update"))
+
+	rec = doRequest(t, router, http.MethodPut, "/admin/users/user-1/roles", `{"roles":["editor"]}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"user_id":"user-1","roles":["editor"]}`, rec.Body.String())
+	assert.True(t, engine.CheckPermission(ctx, user, "configs:update"))
+
+	rec = doRequest(t, router, http.MethodPut, "/admin/users/user-1/roles", `{"roles":["missing"]}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = doRequest(t, router, http.MethodPut, "/admin/users/user-1/roles", `{"roles":[]}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.False(t, engine.CheckPermission(ctx, user, "configs:update"))
+}
+
+func TestHandler_UserRoles_NotSupported(t *testing.T) {
+	engine := NewEngine(rolesOnlyStorage{NewMemoryStorageWithDefaults()})
+	r := chi.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user := &auth.User{ID: "root", Roles: []string{"admin"}}
+			next.ServeHTTP(w, r.WithContext(auth.ContextWithUser(r.Context(), user)))
+		})
+	})
+	NewHandler(engine).RegisterRoutes(r)
+
+	rec := doRequest(t, r, http.MethodGet, "/admin/users/user-1/roles", "")
+	assert.Equal(t, http.StatusNotImplemented, rec.Code)
+}
@@ -0,0 +1,121 @@
+package rbac
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	goredis "github.com/redis/go-redis/v9"
+
+	"github.com/bargom/codeai/pkg/integration/redis"
+)
+
+// DefaultInvalidationChannel is the Redis channel role changes are
+// announced on.
+const DefaultInvalidationChannel = "codeai:rbac:invalidate"
+
+// Invalidator clears cached roles and permissions after a change.
+type Invalidator interface {
+	Invalidate(ctx context.Context) error
+}
+
+// localInvalidator clears the caches of a single engine.
+type localInvalidator struct {
+	engine *Engine
+}
+
+func (i localInvalidator) Invalidate(ctx context.Context) error {
+	i.engine.InvalidateCache()
+	return nil
+}
+
+// RedisInvalidator clears the caches of the engines of all instances
+// sharing a role storage. Invalidate clears the local engine and announces
+// the change on a Redis channel; Listen clears the local engine when
+// another instance announces one.
+type RedisInvalidator struct {
+	client   *redis.Client
+	engine   *Engine
+	channel  string
+	instance string
+	logger   *slog.Logger
+}
+
+// InvalidatorOption configures a RedisInvalidator.
+type InvalidatorOption func(*RedisInvalidator)
+
+// WithChannel sets the Redis channel used for invalidation messages.
+func WithChannel(channel string) InvalidatorOption {
+	return func(i *RedisInvalidator) {
+		i.channel = channel
+	}
+}
+
+// WithInvalidatorLogger sets the logger of the invalidator.
+func WithInvalidatorLogger(logger *slog.Logger) InvalidatorOption {
+	return func(i *RedisInvalidator) {
+		i.logger = logger.With("component", "rbac-invalidator")
+	}
+}
+
+// NewRedisInvalidator creates an invalidator for engine using client.
+func NewRedisInvalidator(client *redis.Client, engine *Engine, opts ...InvalidatorOption) *RedisInvalidator {
+	i := &RedisInvalidator{
+		client:   client,
+		engine:   engine,
+		channel:  DefaultInvalidationChannel,
+		instance: uuid.NewString(),
+		logger:   slog.Default().With("component", "rbac-invalidator"),
+	}
+	for _, opt := range opts {
+		opt(i)
+	}
+	return i
+}
+
+// Invalidate clears the local caches and tells other instances to clear
+// theirs.
+func (i *RedisInvalidator) Invalidate(ctx context.Context) error {
+	i.engine.InvalidateCache()
+	if err := i.client.RedisClient().Publish(ctx, i.channel, i.instance).Err(); err != nil {
+		return fmt.Errorf("publishing rbac invalidation: %w", err)
+	}
+	return nil
+}
+
+// Listen clears the local caches whenever another instance announces a
+// change, until ctx is done. The caches are also cleared each time the
+// subscription is (re)established, since changes may have been announced
+// while it was down.
+func (i *RedisInvalidator) Listen(ctx context.Context) error {
+	sub := i.client.RedisClient().Subscribe(ctx, i.channel)
+	defer sub.Close()
+
+	for {
+		msg, err := sub.Receive(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			i.logger.Warn("rbac invalidation subscription failed", "error", err)
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+
+		switch m := msg.(type) {
+		case *goredis.Subscription:
+			i.engine.InvalidateCache()
+		case *goredis.Message:
+			if m.Payload != i.instance {
+				i.logger.Debug("roles changed on another instance")
+				i.engine.InvalidateCache()
+			}
+		}
+	}
+}
@@ -0,0 +1,56 @@
+//go:build integration
+
+package rbac
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	tcredis "github.com/testcontainers/testcontainers-go/modules/redis"
+
+	"github.com/bargom/codeai/internal/auth"
+	"github.com/bargom/codeai/pkg/integration/redis"
+)
+
+func TestRedisInvalidator_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	container, err := tcredis.Run(ctx, "redis:7-alpine")
+	require.NoError(t, err)
+	defer container.Terminate(context.Background())
+	endpoint, err := container.Endpoint(ctx, "")
+	require.NoError(t, err)
+
+	cfg := redis.DefaultConfig()
+	cfg.Addr = endpoint
+	client, err := redis.NewClient(cfg)
+	require.NoError(t, err)
+	defer client.Close()
+
+	// Two instances sharing a storage, each with its own caches
+	backend := NewMemoryStorageWithDefaults()
+	first := NewEngine(NewCachedStorage(backend))
+	second := NewEngine(NewCachedStorage(backend))
+	firstInvalidator := NewRedisInvalidator(client, first)
+	secondInvalidator := NewRedisInvalidator(client, second)
+	go secondInvalidator.Listen(ctx)
+
+	user := &auth.User{ID: "user-1", Roles: []string{"viewer"}}
+	assert.False(t, first.CheckPermission(ctx, user, "reports:read"))
+	assert.False(t, second.CheckPermission(ctx, user, "reports:read"))
+
+	require.NoError(t, backend.SaveRole(ctx, &Role{Name: "viewer", Permissions: []Permission{"reports:read"}}))
+	require.Eventually(t, func() bool {
+		require.NoError(t, firstInvalidator.Invalidate(ctx))
+		return second.CheckPermission(ctx, user, "reports:read")
+	}, 5*time.Second, 100*time.Millisecond)
+	assert.True(t, first.CheckPermission(ctx, user, "reports:read"))
+}
@@ -0,0 +1,174 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	rolesCollection     = "rbac_roles"
+	userRolesCollection = "rbac_user_roles"
+)
+
+// MongoStorage implements Storage and UserRoleStorage on MongoDB.
+type MongoStorage struct {
+	roles     *mongo.Collection
+	userRoles *mongo.Collection
+}
+
+// NewMongoStorage creates a role storage on the given MongoDB database.
+func NewMongoStorage(db *mongo.Database) *MongoStorage {
+	return &MongoStorage{
+		roles:     db.Collection(rolesCollection),
+		userRoles: db.Collection(userRolesCollection),
+	}
+}
+
+// mongoRole is the document representation of a Role.
+type mongoRole struct {
+	Name        string       `bson:"_id"`
+	Description string       `bson:"description"`
+	Permissions []Permission `bson:"permissions"`
+	Parents     []string     `bson:"parents"`
+	UpdatedAt   time.Time    `bson:"updatedAt"`
+}
+
+// mongoUserRoles holds the roles assigned to a user.
+type mongoUserRoles struct {
+	UserID string   `bson:"_id"`
+	Roles  []string `bson:"roles"`
+}
+
+// EnsureIndexes creates the index used to remove assignments of deleted
+// roles.
+func (s *MongoStorage) EnsureIndexes(ctx context.Context) error {
+	_, err := s.userRoles.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "roles", Value: 1}},
+	})
+	if err != nil {
+		return fmt.Errorf("creating rbac indexes: %w", err)
+	}
+	return nil
+}
+
+// GetRole retrieves a role by name.
+func (s *MongoStorage) GetRole(ctx context.Context, name string) (*Role, error) {
+	var doc mongoRole
+	err := s.roles.FindOne(ctx, bson.M{"_id": name}).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrRoleNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("finding role: %w", err)
+	}
+	return doc.toRole(), nil
+}
+
+// ListRoles returns all roles ordered by name.
+func (s *MongoStorage) ListRoles(ctx context.Context) ([]*Role, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	cursor, err := s.roles.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("listing roles: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var docs []mongoRole
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("decoding roles: %w", err)
+	}
+	roles := make([]*Role, len(docs))
+	for i := range docs {
+		roles[i] = docs[i].toRole()
+	}
+	return roles, nil
+}
+
+// SaveRole creates or updates a role.
+func (s *MongoStorage) SaveRole(ctx context.Context, role *Role) error {
+	if err := validateRole(role); err != nil {
+		return err
+	}
+
+	doc := mongoRole{
+		Name:        role.Name,
+		Description: role.Description,
+		Permissions: nonNilPermissions(role.Permissions),
+		Parents:     nonNilStrings(role.Parents),
+		UpdatedAt:   time.Now().UTC(),
+	}
+	opts := options.Replace().SetUpsert(true)
+	if _, err := s.roles.ReplaceOne(ctx, bson.M{"_id": role.Name}, doc, opts); err != nil {
+		return fmt.Errorf("saving role: %w", err)
+	}
+	return nil
+}
+
+// DeleteRole removes a role and its assignments to users.
+func (s *MongoStorage) DeleteRole(ctx context.Context, name string) error {
+	result, err := s.roles.DeleteOne(ctx, bson.M{"_id": name})
+	if err != nil {
+		return fmt.Errorf("deleting role: %w", err)
+	}
+	if result.DeletedCount == 0 {
+		return ErrRoleNotFound
+	}
+
+	_, err = s.userRoles.UpdateMany(ctx, bson.M{"roles": name}, bson.M{"$pull": bson.M{"roles": name}})
+	if err != nil {
+		return fmt.Errorf("deleting role assignments: %w", err)
+	}
+	return nil
+}
+
+// UserRoles returns the roles assigned to a user, ordered by name.
+func (s *MongoStorage) UserRoles(ctx context.Context, userID string) ([]string, error) {
+	var doc mongoUserRoles
+	err := s.userRoles.FindOne(ctx, bson.M{"_id": userID}).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("finding user roles: %w", err)
+	}
+	sort.Strings(doc.Roles)
+	return doc.Roles, nil
+}
+
+// SetUserRoles replaces the roles assigned to a user.
+func (s *MongoStorage) SetUserRoles(ctx context.Context, userID string, roles []string) error {
+	roles, err := normalizeUserRoles(roles)
+	if err != nil {
+		return err
+	}
+
+	if len(roles) == 0 {
+		if _, err := s.userRoles.DeleteOne(ctx, bson.M{"_id": userID}); err != nil {
+			return fmt.Errorf("setting user roles: %w", err)
+		}
+		return nil
+	}
+
+	doc := mongoUserRoles{UserID: userID, Roles: roles}
+	opts := options.Replace().SetUpsert(true)
+	if _, err := s.userRoles.ReplaceOne(ctx, bson.M{"_id": userID}, doc, opts); err != nil {
+		return fmt.Errorf("setting user roles: %w", err)
+	}
+	return nil
+}
+
+func (d *mongoRole) toRole() *Role {
+	return &Role{
+		Name:        d.Name,
+		Description: d.Description,
+		Permissions: d.Permissions,
+		Parents:     d.Parents,
+	}
+}
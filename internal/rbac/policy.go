@@ -2,6 +2,7 @@ package rbac
 
 import (
 	"errors"
+	"sort"
 	"strings"
 )
 
@@ -12,6 +13,8 @@ var (
 	ErrInvalidPermission  = errors.New("invalid permission format")
 	ErrCyclicInheritance  = errors.New("cyclic role inheritance detected")
 	ErrInvalidRoleName    = errors.New("invalid role name")
+
+	ErrUserRolesNotSupported = errors.New("storage does not support user role assignments")
 )
 
 // DefaultRoles defines the standard roles with their permissions.
@@ -194,6 +197,53 @@ func validateRoleName(name string) error {
 	return nil
 }
 
+// validateRole checks the name and permissions of a role before it is stored.
+func validateRole(role *Role) error {
+	if err := validateRoleName(role.Name); err != nil {
+		return err
+	}
+	for _, perm := range role.Permissions {
+		if err := validatePermission(perm); err != nil {
+			return err
+		}
+	}
+	for _, parent := range role.Parents {
+		if err := validateRoleName(parent); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// normalizeUserRoles validates roles assigned to a user, removes duplicates
+// and sorts them.
+func normalizeUserRoles(roles []string) ([]string, error) {
+	seen := make(map[string]bool, len(roles))
+	unique := make([]string, 0, len(roles))
+	for _, role := range roles {
+		if err := validateRoleName(role); err != nil {
+			return nil, err
+		}
+		if !seen[role] {
+			seen[role] = true
+			unique = append(unique, role)
+		}
+	}
+	sort.Strings(unique)
+	return unique, nil
+}
+
+// removeRole returns roles without name.
+func removeRole(roles []string, name string) []string {
+	kept := roles[:0:0]
+	for _, role := range roles {
+		if role != name {
+			kept = append(kept, role)
+		}
+	}
+	return kept
+}
+
 // validatePermission checks if a permission string is valid.
 func validatePermission(perm Permission) error {
 	s := string(perm)
@@ -135,7 +135,7 @@ func (e *Engine) CheckPermission(ctx context.Context, user *auth.User, permissio
 	}
 
 	// Check permissions from roles
-	allPerms := e.resolveUserPermissions(ctx, user.Roles)
+	allPerms := e.resolveUserPermissions(ctx, e.userRoles(ctx, user))
 	for _, p := range allPerms {
 		if p.Matches(permission) {
 			e.logger.Debug("permission granted via role",
@@ -158,14 +158,15 @@ func (e *Engine) CheckRole(ctx context.Context, user *auth.User, roleName string
 		return false
 	}
 
-	for _, r := range user.Roles {
+	roles := e.userRoles(ctx, user)
+	for _, r := range roles {
 		if r == roleName {
 			return true
 		}
 	}
 
 	// Check inherited roles
-	for _, r := range user.Roles {
+	for _, r := range roles {
 		if e.hasInheritedRole(ctx, r, roleName) {
 			return true
 		}
@@ -239,10 +240,35 @@ func (e *Engine) resolveRolePermissions(ctx context.Context, roleName string, se
 	return perms
 }
 
-// InvalidateCache clears the permission cache.
+// userRoles returns the roles of the user's token together with the roles
+// assigned to the user in storage.
+func (e *Engine) userRoles(ctx context.Context, user *auth.User) []string {
+	store, ok := e.storage.(UserRoleStorage)
+	if !ok || user.ID == "" {
+		return user.Roles
+	}
+
+	assigned, err := store.UserRoles(ctx, user.ID)
+	if err != nil {
+		e.logger.Warn("failed to load user roles", "user", user.ID, "error", err)
+		return user.Roles
+	}
+	if len(assigned) == 0 {
+		return user.Roles
+	}
+	roles := make([]string, 0, len(user.Roles)+len(assigned))
+	roles = append(roles, user.Roles...)
+	return append(roles, assigned...)
+}
+
+// InvalidateCache clears the permission cache, and the role cache of a
+// CachedStorage backend.
 // Call this when roles or permissions are updated.
 func (e *Engine) InvalidateCache() {
 	e.cache.clear()
+	if cached, ok := e.storage.(*CachedStorage); ok {
+		cached.InvalidateCache()
+	}
 	e.logger.Debug("permission cache invalidated")
 }
 
@@ -259,7 +285,7 @@ func (e *Engine) GetUserPermissions(ctx context.Context, user *auth.User) []Perm
 	}
 
 	// Add role-based permissions
-	rolePerms := e.resolveUserPermissions(ctx, user.Roles)
+	rolePerms := e.resolveUserPermissions(ctx, e.userRoles(ctx, user))
 	perms = append(perms, rolePerms...)
 
 	// Deduplicate
@@ -302,7 +328,7 @@ func (e *Engine) GetUserRoles(ctx context.Context, user *auth.User) []string {
 		}
 	}
 
-	for _, r := range user.Roles {
+	for _, r := range e.userRoles(ctx, user) {
 		collectRoles(r)
 	}
 
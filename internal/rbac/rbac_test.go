@@ -677,3 +677,42 @@ func TestEngine_GetUserRoles_NoRolesInUser(t *testing.T) {
 	roles := engine.GetUserRoles(ctx, user)
 	assert.Empty(t, roles)
 }
+
+func TestEngine_AssignedRoles(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMemoryStorageWithDefaults()
+	engine := NewEngine(storage)
+	user := &auth.User{ID: "user-1", Roles: []string{"viewer"}}
+
+	assert.False(t, engine.CheckPermission(ctx, user, "configs:update"))
+	assert.False(t, engine.CheckRole(ctx, user, "editor"))
+
+	// Assigned roles are granted in addition to the token's roles
+	require.NoError(t, storage.SetUserRoles(ctx, "user-1", []string{"editor"}))
+	assert.True(t, engine.CheckPermission(ctx, user, "configs:update"))
+	assert.True(t, engine.CheckPermission(ctx, user, "health:read"))
+	assert.True(t, engine.CheckRole(ctx, user, "editor"))
+	assert.ElementsMatch(t, []string{"viewer", "editor"}, engine.GetUserRoles(ctx, user))
+	assert.Contains(t, engine.GetUserPermissions(ctx, user), Permission("configs:delete"))
+
+	// Assignments are per user
+	other := &auth.User{ID: "user-2", Roles: []string{"viewer"}}
+	assert.False(t, engine.CheckPermission(ctx, other, "configs:update"))
+}
+
+func TestEngine_InvalidateCache_CachedStorage(t *testing.T) {
+	ctx := context.Background()
+	backend := NewMemoryStorageWithDefaults()
+	engine := NewEngine(NewCachedStorage(backend))
+	user := &auth.User{ID: "user-1", Roles: []string{"viewer"}}
+
+	assert.False(t, engine.CheckPermission(ctx, user, "reports:read"))
+
+	// A change made through another instance's storage shows once the
+	// caches are invalidated
+	require.NoError(t, backend.SaveRole(ctx, &Role{Name: "viewer", Permissions: []Permission{"reports:read"}}))
+	assert.False(t, engine.CheckPermission(ctx, user, "reports:read"))
+
+	engine.InvalidateCache()
+	assert.True(t, engine.CheckPermission(ctx, user, "reports:read"))
+}
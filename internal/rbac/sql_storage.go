@@ -0,0 +1,215 @@
+package rbac
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SQLStorage implements Storage and UserRoleStorage on PostgreSQL.
+type SQLStorage struct {
+	db *sql.DB
+}
+
+// NewSQLStorage creates a role storage on the given PostgreSQL database.
+func NewSQLStorage(db *sql.DB) *SQLStorage {
+	return &SQLStorage{db: db}
+}
+
+// CreateTables creates the rbac_roles and rbac_user_roles tables if they
+// don't exist.
+func (s *SQLStorage) CreateTables(ctx context.Context) error {
+	queries := []string{
+		`CREATE TABLE IF NOT EXISTS rbac_roles (
+			name TEXT PRIMARY KEY,
+			description TEXT NOT NULL DEFAULT '',
+			permissions TEXT NOT NULL DEFAULT '[]',
+			parents TEXT NOT NULL DEFAULT '[]',
+			updated_at TIMESTAMP NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS rbac_user_roles (
+			user_id TEXT NOT NULL,
+			role TEXT NOT NULL,
+			PRIMARY KEY (user_id, role)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_rbac_user_roles_role ON rbac_user_roles (role)`,
+	}
+	for _, query := range queries {
+		if _, err := s.db.ExecContext(ctx, query); err != nil {
+			return fmt.Errorf("creating rbac tables: %w", err)
+		}
+	}
+	return nil
+}
+
+const selectRole = `SELECT name, description, permissions, parents FROM rbac_roles `
+
+// GetRole retrieves a role by name.
+func (s *SQLStorage) GetRole(ctx context.Context, name string) (*Role, error) {
+	return scanRole(s.db.QueryRowContext(ctx, selectRole+"WHERE name = $1", name))
+}
+
+// ListRoles returns all roles ordered by name.
+func (s *SQLStorage) ListRoles(ctx context.Context) ([]*Role, error) {
+	rows, err := s.db.QueryContext(ctx, selectRole+"ORDER BY name")
+	if err != nil {
+		return nil, fmt.Errorf("listing roles: %w", err)
+	}
+	defer rows.Close()
+
+	var roles []*Role
+	for rows.Next() {
+		role, err := scanRole(rows)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+	return roles, rows.Err()
+}
+
+// SaveRole creates or updates a role.
+func (s *SQLStorage) SaveRole(ctx context.Context, role *Role) error {
+	if err := validateRole(role); err != nil {
+		return err
+	}
+
+	perms, err := json.Marshal(nonNilPermissions(role.Permissions))
+	if err != nil {
+		return fmt.Errorf("marshaling permissions: %w", err)
+	}
+	parents, err := json.Marshal(nonNilStrings(role.Parents))
+	if err != nil {
+		return fmt.Errorf("marshaling parents: %w", err)
+	}
+
+	query := `
+		INSERT INTO rbac_roles (name, description, permissions, parents, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (name) DO UPDATE SET
+			description = excluded.description,
+			permissions = excluded.permissions,
+			parents = excluded.parents,
+			updated_at = excluded.updated_at
+	`
+	_, err = s.db.ExecContext(ctx, query,
+		role.Name, role.Description, string(perms), string(parents), time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("saving role: %w", err)
+	}
+	return nil
+}
+
+// DeleteRole removes a role and its assignments to users.
+func (s *SQLStorage) DeleteRole(ctx context.Context, name string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("deleting role: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM rbac_roles WHERE name = $1", name)
+	if err != nil {
+		return fmt.Errorf("deleting role: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("deleting role: %w", err)
+	}
+	if n == 0 {
+		return ErrRoleNotFound
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM rbac_user_roles WHERE role = $1", name); err != nil {
+		return fmt.Errorf("deleting role assignments: %w", err)
+	}
+	return tx.Commit()
+}
+
+// UserRoles returns the roles assigned to a user, ordered by name.
+func (s *SQLStorage) UserRoles(ctx context.Context, userID string) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT role FROM rbac_user_roles WHERE user_id = $1 ORDER BY role", userID)
+	if err != nil {
+		return nil, fmt.Errorf("listing user roles: %w", err)
+	}
+	defer rows.Close()
+
+	var roles []string
+	for rows.Next() {
+		var role string
+		if err := rows.Scan(&role); err != nil {
+			return nil, fmt.Errorf("scanning user role: %w", err)
+		}
+		roles = append(roles, role)
+	}
+	return roles, rows.Err()
+}
+
+// SetUserRoles replaces the roles assigned to a user.
+func (s *SQLStorage) SetUserRoles(ctx context.Context, userID string, roles []string) error {
+	roles, err := normalizeUserRoles(roles)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("setting user roles: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM rbac_user_roles WHERE user_id = $1", userID); err != nil {
+		return fmt.Errorf("setting user roles: %w", err)
+	}
+	for _, role := range roles {
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO rbac_user_roles (user_id, role) VALUES ($1, $2)", userID, role)
+		if err != nil {
+			return fmt.Errorf("setting user roles: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanRole(row scanner) (*Role, error) {
+	var (
+		role           Role
+		perms, parents string
+	)
+	err := row.Scan(&role.Name, &role.Description, &perms, &parents)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRoleNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scanning role: %w", err)
+	}
+
+	if err := json.Unmarshal([]byte(perms), &role.Permissions); err != nil {
+		return nil, fmt.Errorf("unmarshaling permissions: %w", err)
+	}
+	if err := json.Unmarshal([]byte(parents), &role.Parents); err != nil {
+		return nil, fmt.Errorf("unmarshaling parents: %w", err)
+	}
+	return &role, nil
+}
+
+func nonNilPermissions(p []Permission) []Permission {
+	if p == nil {
+		return []Permission{}
+	}
+	return p
+}
+
+func nonNilStrings(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
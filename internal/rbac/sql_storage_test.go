@@ -0,0 +1,113 @@
+package rbac
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+
+	"github.com/bargom/codeai/internal/auth"
+)
+
+func newSQLStorage(t *testing.T) *SQLStorage {
+	t.Helper()
+	db, err := sql.Open("sqlite", ":memory:")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	s := NewSQLStorage(db)
+	require.NoError(t, s.CreateTables(context.Background()))
+	return s
+}
+
+func TestSQLStorage_Roles(t *testing.T) {
+	ctx := context.Background()
+	s := newSQLStorage(t)
+
+	_, err := s.GetRole(ctx, "editor")
+	assert.ErrorIs(t, err, ErrRoleNotFound)
+
+	for i := range DefaultRoles {
+		require.NoError(t, s.SaveRole(ctx, &DefaultRoles[i]))
+	}
+
+	role, err := s.GetRole(ctx, "editor")
+	require.NoError(t, err)
+	assert.Equal(t, "Can read and modify resources", role.Description)
+	assert.Contains(t, role.Permissions, Permission("configs:update"))
+	assert.Equal(t, []string{"viewer"}, role.Parents)
+
+	// Saving an existing role updates it
+	role.Permissions = []Permission{"reports:read"}
+	role.Parents = nil
+	require.NoError(t, s.SaveRole(ctx, role))
+	role, err = s.GetRole(ctx, "editor")
+	require.NoError(t, err)
+	assert.Equal(t, []Permission{"reports:read"}, role.Permissions)
+	assert.Empty(t, role.Parents)
+
+	roles, err := s.ListRoles(ctx)
+	require.NoError(t, err)
+	require.Len(t, roles, 3)
+	assert.Equal(t, "admin", roles[0].Name)
+	assert.Equal(t, "viewer", roles[2].Name)
+
+	assert.ErrorIs(t, s.SaveRole(ctx, &Role{Name: "bad", Permissions: []Permission{"invalid"}}), ErrInvalidPermission)
+	assert.ErrorIs(t, s.SaveRole(ctx, &Role{Name: "bad role"}), ErrInvalidRoleName)
+
+	require.NoError(t, s.DeleteRole(ctx, "editor"))
+	_, err = s.GetRole(ctx, "editor")
+	assert.ErrorIs(t, err, ErrRoleNotFound)
+	assert.ErrorIs(t, s.DeleteRole(ctx, "editor"), ErrRoleNotFound)
+}
+
+func TestSQLStorage_UserRoles(t *testing.T) {
+	ctx := context.Background()
+	s := newSQLStorage(t)
+	for i := range DefaultRoles {
+		require.NoError(t, s.SaveRole(ctx, &DefaultRoles[i]))
+	}
+
+	roles, err := s.UserRoles(ctx, "user-1")
+	require.NoError(t, err)
+	assert.Empty(t, roles)
+
+	require.NoError(t, s.SetUserRoles(ctx, "user-1", []string{"viewer", "editor", "viewer"}))
+	require.NoError(t, s.SetUserRoles(ctx, "user-2", []string{"editor"}))
+	roles, err = s.UserRoles(ctx, "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"editor", "viewer"}, roles)
+
+	// Setting roles replaces the previous assignments
+	require.NoError(t, s.SetUserRoles(ctx, "user-1", []string{"admin"}))
+	roles, err = s.UserRoles(ctx, "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"admin"}, roles)
+
+	// Deleting a role removes its assignments
+	require.NoError(t, s.DeleteRole(ctx, "editor"))
+	roles, err = s.UserRoles(ctx, "user-2")
+	require.NoError(t, err)
+	assert.Empty(t, roles)
+
+	assert.ErrorIs(t, s.SetUserRoles(ctx, "user-1", []string{""}), ErrInvalidRoleName)
+}
+
+func TestSQLStorage_WithEngine(t *testing.T) {
+	ctx := context.Background()
+	s := newSQLStorage(t)
+	for i := range DefaultRoles {
+		require.NoError(t, s.SaveRole(ctx, &DefaultRoles[i]))
+	}
+	require.NoError(t, s.SetUserRoles(ctx, "user-1", []string{"editor"}))
+
+	engine := NewEngine(NewCachedStorage(s))
+	user := &auth.User{ID: "user-1"}
+	assert.True(t, engine.CheckPermission(ctx, user, "configs:update"))
+	assert.True(t, engine.CheckPermission(ctx, user, "health:read"))
+	assert.False(t, engine.CheckPermission(ctx, user, "users:delete"))
+}
@@ -21,16 +21,31 @@ type Storage interface {
 	DeleteRole(ctx context.Context, name string) error
 }
 
+// UserRoleStorage is implemented by storages that also keep the roles
+// assigned to users at runtime. The engine grants assigned roles in addition
+// to the roles of the user's token.
+type UserRoleStorage interface {
+	// UserRoles returns the roles assigned to a user, or none if the user
+	// has no assignments.
+	UserRoles(ctx context.Context, userID string) ([]string, error)
+
+	// SetUserRoles replaces the roles assigned to a user. An empty list
+	// removes all assignments.
+	SetUserRoles(ctx context.Context, userID string, roles []string) error
+}
+
 // MemoryStorage is an in-memory implementation of Storage.
 type MemoryStorage struct {
-	roles map[string]*Role
-	mu    sync.RWMutex
+	roles     map[string]*Role
+	userRoles map[string][]string
+	mu        sync.RWMutex
 }
 
 // NewMemoryStorage creates a new in-memory storage.
 func NewMemoryStorage() *MemoryStorage {
 	return &MemoryStorage{
-		roles: make(map[string]*Role),
+		roles:     make(map[string]*Role),
+		userRoles: make(map[string][]string),
 	}
 }
 
@@ -74,16 +89,10 @@ func (s *MemoryStorage) ListRoles(ctx context.Context) ([]*Role, error) {
 
 // SaveRole creates or updates a role.
 func (s *MemoryStorage) SaveRole(ctx context.Context, role *Role) error {
-	if err := validateRoleName(role.Name); err != nil {
+	if err := validateRole(role); err != nil {
 		return err
 	}
 
-	for _, perm := range role.Permissions {
-		if err := validatePermission(perm); err != nil {
-			return err
-		}
-	}
-
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -103,6 +112,37 @@ func (s *MemoryStorage) DeleteRole(ctx context.Context, name string) error {
 	}
 
 	delete(s.roles, name)
+	for userID, roles := range s.userRoles {
+		s.userRoles[userID] = removeRole(roles, name)
+		if len(s.userRoles[userID]) == 0 {
+			delete(s.userRoles, userID)
+		}
+	}
+	return nil
+}
+
+// UserRoles returns the roles assigned to a user.
+func (s *MemoryStorage) UserRoles(ctx context.Context, userID string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]string(nil), s.userRoles[userID]...), nil
+}
+
+// SetUserRoles replaces the roles assigned to a user.
+func (s *MemoryStorage) SetUserRoles(ctx context.Context, userID string, roles []string) error {
+	roles, err := normalizeUserRoles(roles)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(roles) == 0 {
+		delete(s.userRoles, userID)
+		return nil
+	}
+	s.userRoles[userID] = roles
 	return nil
 }
 
@@ -110,6 +150,7 @@ func (s *MemoryStorage) DeleteRole(ctx context.Context, name string) error {
 type CachedStorage struct {
 	backend Storage
 	cache   map[string]*cachedRole
+	users   map[string]*cachedUserRoles
 	listTTL time.Duration
 	ttl     time.Duration
 	mu      sync.RWMutex
@@ -120,6 +161,11 @@ type cachedRole struct {
 	expiresAt time.Time
 }
 
+type cachedUserRoles struct {
+	roles     []string
+	expiresAt time.Time
+}
+
 // CachedStorageOption configures the cached storage.
 type CachedStorageOption func(*CachedStorage)
 
@@ -142,6 +188,7 @@ func NewCachedStorage(backend Storage, opts ...CachedStorageOption) *CachedStora
 	s := &CachedStorage{
 		backend: backend,
 		cache:   make(map[string]*cachedRole),
+		users:   make(map[string]*cachedUserRoles),
 		ttl:     5 * time.Minute,
 		listTTL: 1 * time.Minute,
 	}
@@ -210,9 +257,57 @@ func (s *CachedStorage) DeleteRole(ctx context.Context, name string) error {
 		return err
 	}
 
-	// Invalidate cache
+	// Invalidate cache, including assignments of the deleted role
 	s.mu.Lock()
 	delete(s.cache, name)
+	s.users = make(map[string]*cachedUserRoles)
+	s.mu.Unlock()
+
+	return nil
+}
+
+// UserRoles returns the roles assigned to a user with caching. Backends
+// that don't keep assignments have none.
+func (s *CachedStorage) UserRoles(ctx context.Context, userID string) ([]string, error) {
+	backend, ok := s.backend.(UserRoleStorage)
+	if !ok {
+		return nil, nil
+	}
+
+	s.mu.RLock()
+	if cached, ok := s.users[userID]; ok && time.Now().Before(cached.expiresAt) {
+		s.mu.RUnlock()
+		return append([]string(nil), cached.roles...), nil
+	}
+	s.mu.RUnlock()
+
+	roles, err := backend.UserRoles(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.users[userID] = &cachedUserRoles{
+		roles:     roles,
+		expiresAt: time.Now().Add(s.ttl),
+	}
+	s.mu.Unlock()
+
+	return append([]string(nil), roles...), nil
+}
+
+// SetUserRoles replaces the roles assigned to a user and invalidates cache.
+func (s *CachedStorage) SetUserRoles(ctx context.Context, userID string, roles []string) error {
+	backend, ok := s.backend.(UserRoleStorage)
+	if !ok {
+		return ErrUserRolesNotSupported
+	}
+	if err := backend.SetUserRoles(ctx, userID, roles); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	delete(s.users, userID)
 	s.mu.Unlock()
 
 	return nil
@@ -223,6 +318,7 @@ func (s *CachedStorage) InvalidateCache() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.cache = make(map[string]*cachedRole)
+	s.users = make(map[string]*cachedUserRoles)
 }
 
 // InvalidateRole removes a specific role from the cache.
@@ -436,3 +436,67 @@ func TestCachedStorage_DeleteRole_BackendError(t *testing.T) {
 	err := s.DeleteRole(ctx, "nonexistent")
 	assert.Equal(t, ErrRoleNotFound, err)
 }
+
+func TestMemoryStorage_UserRoles(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStorageWithDefaults()
+
+	roles, err := s.UserRoles(ctx, "user-1")
+	require.NoError(t, err)
+	assert.Empty(t, roles)
+
+	require.NoError(t, s.SetUserRoles(ctx, "user-1", []string{"viewer", "editor", "viewer"}))
+	roles, err = s.UserRoles(ctx, "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"editor", "viewer"}, roles)
+
+	assert.ErrorIs(t, s.SetUserRoles(ctx, "user-1", []string{"bad role"}), ErrInvalidRoleName)
+
+	// Deleting a role removes its assignments
+	require.NoError(t, s.DeleteRole(ctx, "editor"))
+	roles, err = s.UserRoles(ctx, "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"viewer"}, roles)
+
+	require.NoError(t, s.SetUserRoles(ctx, "user-1", nil))
+	roles, err = s.UserRoles(ctx, "user-1")
+	require.NoError(t, err)
+	assert.Empty(t, roles)
+}
+
+func TestCachedStorage_UserRoles(t *testing.T) {
+	ctx := context.Background()
+	backend := NewMemoryStorageWithDefaults()
+	cached := NewCachedStorage(backend)
+
+	require.NoError(t, cached.SetUserRoles(ctx, "user-1", []string{"viewer"}))
+	roles, err := cached.UserRoles(ctx, "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"viewer"}, roles)
+
+	// Changes made behind the cache show after invalidation
+	require.NoError(t, backend.SetUserRoles(ctx, "user-1", []string{"admin"}))
+	roles, err = cached.UserRoles(ctx, "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"viewer"}, roles)
+
+	cached.InvalidateCache()
+	roles, err = cached.UserRoles(ctx, "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"admin"}, roles)
+}
+
+func TestCachedStorage_UserRoles_NotSupported(t *testing.T) {
+	ctx := context.Background()
+	cached := NewCachedStorage(rolesOnlyStorage{NewMemoryStorageWithDefaults()})
+
+	roles, err := cached.UserRoles(ctx, "user-1")
+	require.NoError(t, err)
+	assert.Empty(t, roles)
+	assert.ErrorIs(t, cached.SetUserRoles(ctx, "user-1", []string{"viewer"}), ErrUserRolesNotSupported)
+}
+
+// rolesOnlyStorage hides the user role methods of the wrapped storage.
+type rolesOnlyStorage struct {
+	Storage
+}
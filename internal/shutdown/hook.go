@@ -12,6 +12,13 @@ const (
 	// PriorityBackgroundWorkers is used for background worker shutdown.
 	PriorityBackgroundWorkers = 80
 
+	// PriorityEventBus is used for event bus shutdown, after the workers that publish to it.
+	PriorityEventBus = 75
+
+	// PriorityEventTargets is used for what event handlers call into, such as
+	// the workflow engine and background webhook deliveries, after the event bus.
+	PriorityEventTargets = 72
+
 	// PriorityDatabase is used for database connection shutdown.
 	PriorityDatabase = 70
 
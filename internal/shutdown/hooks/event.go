@@ -0,0 +1,164 @@
+package hooks
+
+import (
+	"context"
+	"time"
+
+	"github.com/bargom/codeai/internal/shutdown"
+)
+
+// EventBus defines the interface for an event bus that can be closed.
+type EventBus interface {
+	// Close stops accepting events and waits for async handlers to finish.
+	Close()
+}
+
+// EventBusShutdown creates a shutdown hook for an event bus.
+func EventBusShutdown(bus EventBus) shutdown.Hook {
+	return shutdown.Hook{
+		Name:     "event-bus",
+		Priority: shutdown.PriorityEventBus,
+		Fn: func(ctx context.Context) error {
+			done := make(chan struct{})
+			go func() {
+				bus.Close()
+				close(done)
+			}()
+
+			select {
+			case <-done:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	}
+}
+
+// OutboxRelayShutdown creates a shutdown hook for an event outbox relay. The
+// relay publishes what is left in the outbox before the event bus closes.
+func OutboxRelayShutdown(relay BackgroundWorker, waitTimeout time.Duration) shutdown.Hook {
+	return BackgroundWorkerShutdown("outbox-relay", relay, waitTimeout)
+}
+
+// EventReplays defines the interface for replays running in the background.
+type EventReplays interface {
+	// Shutdown cancels the running replays and waits for them to stop.
+	Shutdown()
+}
+
+// EventReplayShutdown creates a shutdown hook for the replays started
+// through the admin endpoints. It runs before the event bus closes, as the
+// replayed handlers publish to it; interrupted replays keep their
+// checkpoints.
+func EventReplayShutdown(replays EventReplays) shutdown.Hook {
+	return shutdown.Hook{
+		Name:     "event-replays",
+		Priority: shutdown.PriorityBackgroundWorkers,
+		Fn: func(ctx context.Context) error {
+			done := make(chan struct{})
+			go func() {
+				replays.Shutdown()
+				close(done)
+			}()
+
+			select {
+			case <-done:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	}
+}
+
+// WebhookRetryHandler defines the interface for a webhook retry handler.
+type WebhookRetryHandler interface {
+	// Stop stops retrying and waits for the current batch to finish.
+	Stop()
+}
+
+// WebhookRetryShutdown creates a shutdown hook for a webhook retry handler.
+// Deliveries it has claimed but not retried are retried by another instance
+// once their lease expires.
+func WebhookRetryShutdown(handler WebhookRetryHandler) shutdown.Hook {
+	return shutdown.Hook{
+		Name:     "webhook-retry",
+		Priority: shutdown.PriorityBackgroundWorkers,
+		Fn: func(ctx context.Context) error {
+			done := make(chan struct{})
+			go func() {
+				handler.Stop()
+				close(done)
+			}()
+
+			select {
+			case <-done:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	}
+}
+
+// WebhookDeliveries defines the interface for a webhook service with
+// deliveries queued or running in the background.
+type WebhookDeliveries interface {
+	// Stop drains the delivery queue and waits for the background
+	// deliveries to finish.
+	Stop()
+}
+
+// WebhookDeliveryShutdown creates a shutdown hook that drains queued and
+// background webhook deliveries. It runs after the event bus closes, so
+// that the handlers still draining from the bus can start deliveries.
+func WebhookDeliveryShutdown(deliveries WebhookDeliveries) shutdown.Hook {
+	return shutdown.Hook{
+		Name:     "webhook-deliveries",
+		Priority: shutdown.PriorityEventTargets,
+		Fn: func(ctx context.Context) error {
+			done := make(chan struct{})
+			go func() {
+				deliveries.Stop()
+				close(done)
+			}()
+
+			select {
+			case <-done:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	}
+}
+
+// WorkflowEngine defines the interface for a workflow engine with a worker.
+type WorkflowEngine interface {
+	// Stop stops the worker and closes the engine's client.
+	Stop() error
+}
+
+// WorkflowEngineShutdown creates a shutdown hook for a workflow engine. It
+// runs after the event bus closes, so that the handlers still draining
+// from the bus can start workflows.
+func WorkflowEngineShutdown(engine WorkflowEngine) shutdown.Hook {
+	return shutdown.Hook{
+		Name:     "workflow-engine",
+		Priority: shutdown.PriorityEventTargets,
+		Fn: func(ctx context.Context) error {
+			done := make(chan error, 1)
+			go func() {
+				done <- engine.Stop()
+			}()
+
+			select {
+			case err := <-done:
+				return err
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	}
+}
@@ -0,0 +1,125 @@
+package tenant
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/bargom/codeai/internal/auth"
+)
+
+// Resolver resolves the tenant of requests.
+type Resolver struct {
+	config Config
+}
+
+// NewResolver creates a resolver for the given configuration.
+func NewResolver(config Config) *Resolver {
+	if config.Strategy == "" {
+		config.Strategy = StrategyColumn
+	}
+	if config.Column == "" {
+		config.Column = DefaultColumn
+	}
+	return &Resolver{config: config}
+}
+
+// Config returns the resolver's configuration, with defaults applied.
+func (r *Resolver) Config() Config {
+	return r.config
+}
+
+// Resolve returns the tenant of req. With a claim configured, the tenant is
+// the user's claim: requests without it have no tenant, and a header or
+// subdomain naming another tenant is cross-tenant access. Otherwise the
+// header and subdomain name the tenant, and must agree when both do.
+func (r *Resolver) Resolve(req *http.Request) (string, error) {
+	var sources []string
+	if r.config.Header != "" {
+		if v := req.Header.Get(r.config.Header); v != "" {
+			sources = append(sources, v)
+		}
+	}
+	if r.config.Subdomain {
+		if v := subdomain(req.Host); v != "" {
+			sources = append(sources, v)
+		}
+	}
+
+	var id string
+	if r.config.Claim != "" {
+		user := auth.UserFromContext(req.Context())
+		if user == nil {
+			return "", fmt.Errorf("%w: authentication required", ErrNoTenant)
+		}
+		v, ok := user.Claims[r.config.Claim]
+		if !ok || v == nil {
+			return "", fmt.Errorf("%w: user has no %q claim", ErrNoTenant, r.config.Claim)
+		}
+		id = claimString(v)
+	} else {
+		if len(sources) == 0 {
+			return "", ErrNoTenant
+		}
+		id, sources = sources[0], sources[1:]
+	}
+
+	for _, other := range sources {
+		if other != id {
+			return "", fmt.Errorf("%w: %q and %q", ErrCrossTenant, id, other)
+		}
+	}
+	if err := ValidateID(id); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// Middleware resolves the tenant of each request into its context. It
+// responds 403 to cross-tenant access and 400 when no valid tenant can be
+// resolved. It must run after authentication when tenants come from a
+// claim.
+func (r *Resolver) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		id, err := r.Resolve(req)
+		if err != nil {
+			status := http.StatusBadRequest
+			if errors.Is(err, ErrCrossTenant) {
+				status = http.StatusForbidden
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		next.ServeHTTP(w, req.WithContext(WithTenant(req.Context(), id)))
+	})
+}
+
+func claimString(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return fmt.Sprintf("%.0f", v)
+	}
+	return fmt.Sprint(v)
+}
+
+// subdomain returns the first label of host if host has a subdomain.
+func subdomain(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if net.ParseIP(host) != nil {
+		return ""
+	}
+	labels := strings.Split(host, ".")
+	if len(labels) < 3 {
+		return ""
+	}
+	return labels[0]
+}
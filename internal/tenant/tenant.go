@@ -0,0 +1,115 @@
+// Package tenant resolves the tenant of a request and carries it in the
+// request context, so that a single deployment can serve many tenants.
+//
+// A tenant is resolved from a JWT claim of the authenticated user, a
+// request header or the request's subdomain. Data access code reads the
+// tenant from the context and scopes queries, cache keys and events to it;
+// with no tenant in the context, tenant-scoped data is not accessible.
+package tenant
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// Strategy is how the data of tenants is kept apart.
+type Strategy string
+
+const (
+	// StrategyColumn stores all tenants in the same tables, with a tenant
+	// column every query is filtered on.
+	StrategyColumn Strategy = "column"
+	// StrategySchema stores each tenant in its own PostgreSQL schema, or
+	// its own collection prefix in MongoDB.
+	StrategySchema Strategy = "schema"
+	// StrategyDatabase stores each tenant in its own database.
+	StrategyDatabase Strategy = "database"
+)
+
+// DefaultColumn is the tenant column of the column strategy.
+const DefaultColumn = "tenant_id"
+
+// Tenant errors.
+var (
+	ErrNoTenant      = errors.New("tenant could not be resolved")
+	ErrInvalidTenant = errors.New("invalid tenant id")
+	ErrCrossTenant   = errors.New("cross-tenant access denied")
+)
+
+// idPattern restricts tenant ids to characters that are safe in schema
+// names, cache keys and collection names.
+var idPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]{0,62}$`)
+
+// Config configures how tenants are resolved and isolated.
+type Config struct {
+	// Strategy is how the data of tenants is kept apart; defaults to
+	// StrategyColumn.
+	Strategy Strategy
+
+	// Claim is the JWT claim holding the user's tenant.
+	Claim string
+
+	// Header is a request header naming the tenant.
+	Header string
+
+	// Subdomain resolves the tenant from the first label of the host,
+	// e.g. acme for acme.example.com.
+	Subdomain bool
+
+	// Column is the tenant column of the column strategy; defaults to
+	// DefaultColumn.
+	Column string
+}
+
+// ParseStrategy parses a strategy name.
+func ParseStrategy(s string) (Strategy, error) {
+	switch Strategy(s) {
+	case StrategyColumn, StrategySchema, StrategyDatabase:
+		return Strategy(s), nil
+	}
+	return "", fmt.Errorf("unknown tenancy strategy %q (expected column, schema or database)", s)
+}
+
+// ValidateID checks that id can be used as a tenant id.
+func ValidateID(id string) error {
+	if !idPattern.MatchString(id) {
+		return fmt.Errorf("%w: %q", ErrInvalidTenant, id)
+	}
+	return nil
+}
+
+// SchemaName returns the PostgreSQL schema of a tenant under the schema
+// strategy.
+func SchemaName(id string) string {
+	return "tenant_" + id
+}
+
+// KeyPrefix returns the prefix of the cache keys of a tenant.
+func KeyPrefix(id string) string {
+	return "tenant:" + id + ":"
+}
+
+// Source returns the event source for events published on behalf of the
+// tenant in ctx: source prefixed with the tenant id, or source unchanged
+// if ctx has no tenant.
+func Source(ctx context.Context, source string) string {
+	if id := FromContext(ctx); id != "" {
+		return id + "/" + source
+	}
+	return source
+}
+
+type contextKey struct{}
+
+// WithTenant returns a context carrying the tenant id.
+func WithTenant(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the tenant id carried by ctx, or "" if there is none.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
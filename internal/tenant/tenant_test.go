@@ -0,0 +1,171 @@
+package tenant
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bargom/codeai/internal/auth"
+)
+
+func requestFor(user *auth.User, host string, headers map[string]string) *http.Request {
+	req := httptest.NewRequest("GET", "/posts", nil)
+	req.Host = host
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	if user != nil {
+		req = req.WithContext(auth.ContextWithUser(req.Context(), user))
+	}
+	return req
+}
+
+func TestResolver_Resolve(t *testing.T) {
+	acme := &auth.User{ID: "u1", Claims: map[string]any{"org_id": "acme"}}
+	numeric := &auth.User{ID: "u2", Claims: map[string]any{"org_id": float64(42)}}
+
+	tests := []struct {
+		name    string
+		config  Config
+		req     *http.Request
+		want    string
+		wantErr error
+	}{
+		{
+			name:   "claim",
+			config: Config{Claim: "org_id"},
+			req:    requestFor(acme, "api.example.com", nil),
+			want:   "acme",
+		},
+		{
+			name:   "numeric claim",
+			config: Config{Claim: "org_id"},
+			req:    requestFor(numeric, "api.example.com", nil),
+			want:   "42",
+		},
+		{
+			name:   "header",
+			config: Config{Header: "X-Tenant-ID"},
+			req:    requestFor(nil, "api.example.com", map[string]string{"X-Tenant-ID": "globex"}),
+			want:   "globex",
+		},
+		{
+			name:   "subdomain",
+			config: Config{Subdomain: true},
+			req:    requestFor(nil, "initech.example.com:8080", nil),
+			want:   "initech",
+		},
+		{
+			name:    "no subdomain",
+			config:  Config{Subdomain: true},
+			req:     requestFor(nil, "example.com", nil),
+			wantErr: ErrNoTenant,
+		},
+		{
+			name:   "claim and header agree",
+			config: Config{Claim: "org_id", Header: "X-Tenant-ID"},
+			req:    requestFor(acme, "api.example.com", map[string]string{"X-Tenant-ID": "acme"}),
+			want:   "acme",
+		},
+		{
+			name:    "header names another tenant",
+			config:  Config{Claim: "org_id", Header: "X-Tenant-ID"},
+			req:     requestFor(acme, "api.example.com", map[string]string{"X-Tenant-ID": "globex"}),
+			wantErr: ErrCrossTenant,
+		},
+		{
+			name:    "subdomain names another tenant",
+			config:  Config{Claim: "org_id", Subdomain: true},
+			req:     requestFor(acme, "globex.example.com", nil),
+			wantErr: ErrCrossTenant,
+		},
+		{
+			name:    "anonymous",
+			config:  Config{Claim: "org_id"},
+			req:     requestFor(nil, "api.example.com", nil),
+			wantErr: ErrNoTenant,
+		},
+		{
+			name:    "header without the claim",
+			config:  Config{Claim: "org_id", Header: "X-Tenant-ID"},
+			req:     requestFor(&auth.User{ID: "u3"}, "api.example.com", map[string]string{"X-Tenant-ID": "globex"}),
+			wantErr: ErrNoTenant,
+		},
+		{
+			name:    "anonymous header",
+			config:  Config{Claim: "org_id", Header: "X-Tenant-ID"},
+			req:     requestFor(nil, "api.example.com", map[string]string{"X-Tenant-ID": "globex"}),
+			wantErr: ErrNoTenant,
+		},
+		{
+			name:    "anonymous subdomain",
+			config:  Config{Claim: "org_id", Subdomain: true},
+			req:     requestFor(nil, "globex.example.com", nil),
+			wantErr: ErrNoTenant,
+		},
+		{
+			name:    "invalid id",
+			config:  Config{Header: "X-Tenant-ID"},
+			req:     requestFor(nil, "api.example.com", map[string]string{"X-Tenant-ID": "acme;drop"}),
+			wantErr: ErrInvalidTenant,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewResolver(tt.config).Resolve(tt.req)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestResolver_Middleware(t *testing.T) {
+	var got string
+	handler := NewResolver(Config{Claim: "org_id", Header: "X-Tenant-ID"}).Middleware(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = FromContext(r.Context())
+		}))
+
+	acme := &auth.User{ID: "u1", Claims: map[string]any{"org_id": "acme"}}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, requestFor(acme, "api.example.com", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "acme", got)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, requestFor(acme, "api.example.com", map[string]string{"X-Tenant-ID": "globex"}))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, requestFor(nil, "api.example.com", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestContext(t *testing.T) {
+	ctx := context.Background()
+	assert.Empty(t, FromContext(ctx))
+	assert.Equal(t, "codeai.dsl", Source(ctx, "codeai.dsl"))
+
+	ctx = WithTenant(ctx, "acme")
+	assert.Equal(t, "acme", FromContext(ctx))
+	assert.Equal(t, "acme/codeai.dsl", Source(ctx, "codeai.dsl"))
+}
+
+func TestParseStrategy(t *testing.T) {
+	s, err := ParseStrategy("schema")
+	require.NoError(t, err)
+	assert.Equal(t, StrategySchema, s)
+
+	_, err = ParseStrategy("table")
+	assert.Error(t, err)
+}
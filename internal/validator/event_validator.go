@@ -4,18 +4,26 @@ package validator
 import (
 	"net/url"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/bargom/codeai/internal/ast"
+	"github.com/bargom/codeai/internal/workflow/expr"
 )
 
 // EventValidation extends the Validator with event-related state.
 type EventValidation struct {
 	events       map[string]*ast.EventDecl
+	versions     map[string]map[int]*ast.EventDecl
 	handlers     []*ast.EventHandlerDecl
 	integrations map[string]*ast.IntegrationDecl
 	webhooks     map[string]*ast.WebhookDecl
 	workflows    map[string]*ast.WorkflowDecl
+
+	receivers     map[string]*ast.WebhookReceiverDecl
+	receiverPaths map[string]*ast.WebhookReceiverDecl
 }
 
 // initEventValidation initializes event validation state.
@@ -23,10 +31,14 @@ func (v *Validator) initEventValidation() {
 	if v.eventValidation == nil {
 		v.eventValidation = &EventValidation{
 			events:       make(map[string]*ast.EventDecl),
+			versions:     make(map[string]map[int]*ast.EventDecl),
 			handlers:     make([]*ast.EventHandlerDecl, 0),
 			integrations: make(map[string]*ast.IntegrationDecl),
 			webhooks:     make(map[string]*ast.WebhookDecl),
 			workflows:    make(map[string]*ast.WorkflowDecl),
+
+			receivers:     make(map[string]*ast.WebhookReceiverDecl),
+			receiverPaths: make(map[string]*ast.WebhookReceiverDecl),
 		}
 	}
 }
@@ -41,13 +53,24 @@ func (v *Validator) validateEventDecl(event *ast.EventDecl) {
 			"event name '"+event.Name+"' should follow resource.action or resource_action pattern"))
 	}
 
-	// Check for duplicate event
-	if existing, exists := v.eventValidation.events[event.Name]; exists {
+	// Check for duplicate event; each version may be declared once
+	version := event.SchemaVersion()
+	versions := v.eventValidation.versions[event.Name]
+	if existing, exists := versions[version]; exists {
+		label := "event '" + event.Name + "'"
+		if version > 1 {
+			label += " version " + strconv.Itoa(version)
+		}
 		v.errors.Add(newSemanticError(event.Pos(),
-			"duplicate event '"+event.Name+"'; first declared at "+existing.Pos().String()))
+			"duplicate "+label+"; first declared at "+existing.Pos().String()))
 		return
 	}
-	v.eventValidation.events[event.Name] = event
+	if versions == nil {
+		versions = make(map[int]*ast.EventDecl)
+		v.eventValidation.versions[event.Name] = versions
+		v.eventValidation.events[event.Name] = event
+	}
+	versions[version] = event
 
 	// Validate schema if present
 	if event.Schema != nil {
@@ -81,6 +104,127 @@ func (v *Validator) validateEventSchema(schema *ast.EventSchema, eventName strin
 	}
 }
 
+// validateEventVersions checks each version of an event against the
+// previous one: its upcast must migrate from the previous version, and
+// unless it is marked breaking it may add fields but not remove fields or
+// change their types.
+func (v *Validator) validateEventVersions() {
+	names := make([]string, 0, len(v.eventValidation.versions))
+	for name := range v.eventValidation.versions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		byVersion := v.eventValidation.versions[name]
+		numbers := make([]int, 0, len(byVersion))
+		for number := range byVersion {
+			numbers = append(numbers, number)
+		}
+		sort.Ints(numbers)
+
+		var previous *ast.EventDecl
+		for _, number := range numbers {
+			decl := byVersion[number]
+			v.validateEventUpcast(decl, number, previous)
+			if previous != nil && !decl.Breaking {
+				v.validateEventCompatibility(decl, number, previous)
+			}
+			previous = decl
+		}
+	}
+}
+
+// validateEventUpcast validates the upcast of version of an event, which
+// must migrate from previous.
+func (v *Validator) validateEventUpcast(decl *ast.EventDecl, version int, previous *ast.EventDecl) {
+	upcast := decl.Upcast
+	if upcast == nil {
+		return
+	}
+	label := "event '" + decl.Name + "' version " + strconv.Itoa(version)
+	if previous == nil {
+		v.errors.Add(newSemanticError(upcast.Pos(),
+			label+" has no previous version to upcast from"))
+		return
+	}
+	if previousVersion := previous.SchemaVersion(); upcast.From != previousVersion {
+		v.errors.Add(newSemanticError(upcast.Pos(),
+			label+" must upcast from version "+strconv.Itoa(previousVersion)+", its previous version"))
+	}
+
+	fields := eventSchemaFields(decl.Schema)
+	mapped := make(map[string]bool)
+	for _, m := range upcast.Mappings {
+		if mapped[m.Field] {
+			v.errors.Add(newSemanticError(m.Pos(),
+				"duplicate upcast of field '"+m.Field+"' in "+label))
+		}
+		mapped[m.Field] = true
+
+		if len(fields) > 0 {
+			if _, declared := fields[m.Field]; !declared {
+				v.errors.Add(newSemanticError(m.Pos(),
+					"upcast of "+label+" sets field '"+m.Field+"' its schema does not declare"))
+			}
+		}
+		if _, err := expr.CompileWithVars(m.Expr, ast.UpcastVar); err != nil {
+			v.errors.Add(newSemanticError(m.Pos(),
+				"invalid upcast of field '"+m.Field+"' in "+label+": "+err.Error()))
+		}
+	}
+}
+
+// validateEventCompatibility reports the changes from previous that break
+// consumers of an event version not marked breaking.
+func (v *Validator) validateEventCompatibility(decl *ast.EventDecl, version int, previous *ast.EventDecl) {
+	label := "event '" + decl.Name + "' version " + strconv.Itoa(version)
+	fields := eventSchemaFields(decl.Schema)
+	if previous.Schema == nil {
+		return
+	}
+	for _, field := range previous.Schema.Fields {
+		fieldType, exists := fields[field.Name]
+		switch {
+		case !exists:
+			v.errors.Add(newSemanticError(decl.Pos(),
+				label+" removes field '"+field.Name+"' and is not backward compatible; mark the version breaking"))
+		case canonicalFieldType(fieldType) != canonicalFieldType(field.FieldType):
+			v.errors.Add(newSemanticError(decl.Pos(),
+				label+" changes the type of field '"+field.Name+"' from "+field.FieldType+" to "+fieldType+
+					" and is not backward compatible; mark the version breaking"))
+		}
+	}
+}
+
+// eventSchemaFields returns the types of the fields of schema by name.
+func eventSchemaFields(schema *ast.EventSchema) map[string]string {
+	fields := make(map[string]string)
+	if schema == nil {
+		return fields
+	}
+	for _, field := range schema.Fields {
+		fields[field.Name] = field.FieldType
+	}
+	return fields
+}
+
+// canonicalFieldType maps the aliases of event field types to one name.
+func canonicalFieldType(fieldType string) string {
+	switch t := strings.ToLower(fieldType); t {
+	case "integer":
+		return "int"
+	case "float":
+		return "decimal"
+	case "boolean":
+		return "bool"
+	case "datetime":
+		return "timestamp"
+	default:
+		return t
+	}
+}
+
 // validateEventHandler validates an event handler declaration.
 func (v *Validator) validateEventHandler(handler *ast.EventHandlerDecl) {
 	v.initEventValidation()
@@ -240,6 +384,58 @@ func (v *Validator) validateWebhookDecl(webhook *ast.WebhookDecl) {
 	}
 }
 
+// validateWebhookReceiverDecl validates an inbound webhook receiver.
+func (v *Validator) validateWebhookReceiverDecl(receiver *ast.WebhookReceiverDecl) {
+	v.initEventValidation()
+
+	// Check for duplicate receiver names and paths
+	if existing, exists := v.eventValidation.receivers[receiver.Name]; exists {
+		v.errors.Add(newSemanticError(receiver.Pos(),
+			"duplicate webhook receiver '"+receiver.Name+"'; first declared at "+existing.Pos().String()))
+		return
+	}
+	v.eventValidation.receivers[receiver.Name] = receiver
+
+	if !strings.HasPrefix(receiver.Path, "/") {
+		v.errors.Add(newSemanticError(receiver.Pos(),
+			"path of webhook receiver '"+receiver.Name+"' must start with '/'"))
+	} else if existing, exists := v.eventValidation.receiverPaths[receiver.Path]; exists {
+		v.errors.Add(newSemanticError(receiver.Pos(),
+			"webhook receiver '"+receiver.Name+"' uses the path '"+receiver.Path+"' of receiver '"+existing.Name+"'"))
+	} else {
+		v.eventValidation.receiverPaths[receiver.Path] = receiver
+	}
+
+	switch receiver.Scheme {
+	case ast.ReceiverSchemeHMAC, ast.ReceiverSchemeStandard:
+	case "":
+		v.errors.Add(newSemanticError(receiver.Pos(),
+			"webhook receiver '"+receiver.Name+"' requires a verify clause"))
+		return
+	default:
+		v.errors.Add(newSemanticError(receiver.Pos(),
+			"unknown verify scheme '"+receiver.Scheme+"' in webhook receiver '"+receiver.Name+"'; "+
+				"valid schemes: "+ast.ReceiverSchemeHMAC+", "+ast.ReceiverSchemeStandard))
+	}
+
+	if receiver.Secret == "" && receiver.SecretEnv == "" {
+		v.errors.Add(newSemanticError(receiver.Pos(),
+			"webhook receiver '"+receiver.Name+"' requires a secret"))
+	}
+
+	if receiver.Tolerance != "" {
+		if d, err := time.ParseDuration(receiver.Tolerance); err != nil || d <= 0 {
+			v.errors.Add(newSemanticError(receiver.Pos(),
+				"invalid tolerance '"+receiver.Tolerance+"' in webhook receiver '"+receiver.Name+"'; expected a positive duration such as \"5m\""))
+		}
+	}
+
+	if receiver.Event == "" {
+		v.errors.Add(newSemanticError(receiver.Pos(),
+			"webhook receiver '"+receiver.Name+"' requires an emit event"))
+	}
+}
+
 // validateEventReferences validates that all event handler references are valid.
 // This should be called after all declarations have been collected.
 func (v *Validator) validateEventReferences() {
@@ -247,6 +443,8 @@ func (v *Validator) validateEventReferences() {
 		return
 	}
 
+	v.validateEventVersions()
+
 	for _, handler := range v.eventValidation.handlers {
 		switch handler.ActionType {
 		case "workflow":
@@ -276,6 +474,18 @@ func (v *Validator) validateEventReferences() {
 		}
 	}
 
+	// Receivers emit their requests on the bus, where only declared events
+	// are accepted.
+	for _, receiver := range v.eventValidation.receivers {
+		if receiver.Event == "" {
+			continue
+		}
+		if _, exists := v.eventValidation.events[receiver.Event]; !exists {
+			v.errors.Add(newSemanticError(receiver.Pos(),
+				"webhook receiver '"+receiver.Name+"' emits undeclared event '"+receiver.Event+"'"))
+		}
+	}
+
 	// Validate that webhooks reference valid events (warning only)
 	for _, webhook := range v.eventValidation.webhooks {
 		if _, exists := v.eventValidation.events[webhook.Event]; !exists {
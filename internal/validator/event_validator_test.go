@@ -0,0 +1,191 @@
+package validator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bargom/codeai/internal/parser"
+)
+
+func TestEventVersions_Valid(t *testing.T) {
+	sources := []string{
+		// Adding a field and changing a type's alias are compatible
+		`event user.created {
+	schema { user_id string active bool }
+}
+event user.created version 2 {
+	schema { user_id string active boolean email string }
+	upcast from 1 { email: "default(payload.email, '')" }
+}`,
+		// Breaking versions may remove and retype fields
+		`event user.created {
+	schema { user_id string name string }
+}
+event user.created version 2 {
+	breaking
+	schema { user_id int full_name string }
+	upcast from 1 { full_name: "payload.name" user_id: "number(payload.user_id)" }
+}`,
+		// Versions need not be consecutive or declared in order
+		`event order.placed version 3 {
+	schema { order_id string total decimal }
+	upcast from 1 { total: "default(payload.total, 0)" }
+}
+event order.placed {
+	schema { order_id string }
+}`,
+	}
+
+	for _, source := range sources {
+		prog, err := parser.Parse(source)
+		require.NoError(t, err, "parse error")
+		assert.NoError(t, New().Validate(prog), source)
+	}
+}
+
+func TestEventVersions_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		wantErr string
+	}{
+		{
+			name: "duplicate version",
+			source: `event user.created version 2 {}
+event user.created version 2 {}`,
+			wantErr: "duplicate event 'user.created' version 2",
+		},
+		{
+			name: "removed field",
+			source: `event user.created { schema { user_id string name string } }
+event user.created version 2 { schema { user_id string } }`,
+			wantErr: "event 'user.created' version 2 removes field 'name' and is not backward compatible; mark the version breaking",
+		},
+		{
+			name: "changed type",
+			source: `event user.created { schema { user_id string } }
+event user.created version 2 { schema { user_id int } }`,
+			wantErr: "changes the type of field 'user_id' from string to int",
+		},
+		{
+			name:    "upcast of first version",
+			source:  `event user.created version 2 { upcast from 1 {} }`,
+			wantErr: "event 'user.created' version 2 has no previous version to upcast from",
+		},
+		{
+			name: "upcast from wrong version",
+			source: `event user.created {}
+event user.created version 2 {}
+event user.created version 3 { upcast from 1 {} }`,
+			wantErr: "event 'user.created' version 3 must upcast from version 2, its previous version",
+		},
+		{
+			name: "upcast of undeclared field",
+			source: `event user.created { schema { user_id string } }
+event user.created version 2 {
+	schema { user_id string email string }
+	upcast from 1 { mail: "''" }
+}`,
+			wantErr: "sets field 'mail' its schema does not declare",
+		},
+		{
+			name: "invalid upcast expression",
+			source: `event user.created {}
+event user.created version 2 { upcast from 1 { email: "payload." } }`,
+			wantErr: "invalid upcast of field 'email' in event 'user.created' version 2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prog, err := parser.Parse(tt.source)
+			require.NoError(t, err, "parse error")
+
+			err = New().Validate(prog)
+			require.Error(t, err, "validation should fail")
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestWebhookReceiver_Valid(t *testing.T) {
+	source := `event payment.received { schema { id string } }
+receive webhook stripe at "/hooks/stripe" {
+	verify hmac_sha256 header "Stripe-Signature" secret env(STRIPE_SECRET)
+	tolerance "10m"
+	emit "payment.received"
+}
+receive webhook partner at "/hooks/partner" {
+	verify standard_webhooks secret "whsec_b2xk"
+	emit "payment.received"
+}`
+
+	prog, err := parser.Parse(source)
+	require.NoError(t, err, "parse error")
+	assert.NoError(t, New().Validate(prog))
+}
+
+func TestWebhookReceiver_Invalid(t *testing.T) {
+	const event = "event payment.received {}\n"
+	tests := []struct {
+		name    string
+		source  string
+		wantErr string
+	}{
+		{
+			name: "duplicate name",
+			source: event + `receive webhook stripe at "/a" { verify hmac_sha256 secret "s" emit "payment.received" }
+receive webhook stripe at "/b" { verify hmac_sha256 secret "s" emit "payment.received" }`,
+			wantErr: "duplicate webhook receiver 'stripe'",
+		},
+		{
+			name: "duplicate path",
+			source: event + `receive webhook stripe at "/a" { verify hmac_sha256 secret "s" emit "payment.received" }
+receive webhook github at "/a" { verify hmac_sha256 secret "s" emit "payment.received" }`,
+			wantErr: "webhook receiver 'github' uses the path '/a' of receiver 'stripe'",
+		},
+		{
+			name:    "relative path",
+			source:  event + `receive webhook stripe at "hooks" { verify hmac_sha256 secret "s" emit "payment.received" }`,
+			wantErr: "path of webhook receiver 'stripe' must start with '/'",
+		},
+		{
+			name:    "unknown scheme",
+			source:  event + `receive webhook stripe at "/a" { verify rsa secret "s" emit "payment.received" }`,
+			wantErr: "unknown verify scheme 'rsa'",
+		},
+		{
+			name:    "missing verify",
+			source:  event + `receive webhook stripe at "/a" { emit "payment.received" }`,
+			wantErr: "webhook receiver 'stripe' requires a verify clause",
+		},
+		{
+			name:    "invalid tolerance",
+			source:  event + `receive webhook stripe at "/a" { verify hmac_sha256 secret "s" tolerance "soon" emit "payment.received" }`,
+			wantErr: "invalid tolerance 'soon'",
+		},
+		{
+			name:    "missing emit",
+			source:  event + `receive webhook stripe at "/a" { verify hmac_sha256 secret "s" }`,
+			wantErr: "webhook receiver 'stripe' requires an emit event",
+		},
+		{
+			name:    "undeclared event",
+			source:  `receive webhook stripe at "/a" { verify hmac_sha256 secret "s" emit "payment.received" }`,
+			wantErr: "webhook receiver 'stripe' emits undeclared event 'payment.received'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prog, err := parser.Parse(tt.source)
+			require.NoError(t, err, "parse error")
+
+			err = New().Validate(prog)
+			require.Error(t, err, "validation should fail")
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"net/url"
 	"regexp"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/bargom/codeai/internal/ast"
 )
@@ -74,9 +76,207 @@ func ValidateAuth(auth *ast.AuthDecl) error {
 		}
 	}
 
+	if err := validateAuthConfig(auth); err != nil {
+		return fmt.Errorf("auth %s: %w", auth.Name, err)
+	}
+	if err := validateAuthIssuer(auth); err != nil {
+		return fmt.Errorf("auth %s: %w", auth.Name, err)
+	}
+
 	return nil
 }
 
+// authSetting describes a setting allowed in the config block of an auth
+// provider.
+type authSetting struct {
+	duration bool     // a duration string such as "5m"
+	choices  []string // allowed values, as identifiers or strings
+}
+
+// authSettings lists the config settings of each auth method; other
+// settings are plain strings, given as literals or env() calls.
+var authSettings = map[ast.AuthMethod]map[string]authSetting{
+	ast.AuthMethodAPIKey: {
+		"header":             {},
+		"query":              {},
+		"last_used_interval": {duration: true},
+		"storage":            {choices: []string{"database", "memory"}},
+	},
+	ast.AuthMethodBasic: {
+		"realm":             {},
+		"file":              {},
+		"model":             {},
+		"username_field":    {},
+		"password_field":    {},
+		"roles_field":       {},
+		"permissions_field": {},
+	},
+	ast.AuthMethodOAuth2: {
+		"introspection_url": {},
+		"client_id":         {},
+		"client_secret":     {},
+		"cache_ttl":         {duration: true},
+		"cache":             {choices: []string{"memory", "redis"}},
+		"issuer":            {},
+		"audience":          {},
+		"roles_claim":       {},
+		"permissions_claim": {},
+	},
+}
+
+// issuerSettings lists the settings of the issuer block of a JWT provider.
+var issuerSettings = map[string]authSetting{
+	"model":             {},
+	"file":              {},
+	"username_field":    {},
+	"password_field":    {},
+	"roles_field":       {},
+	"permissions_field": {},
+	"issuer":            {},
+	"audience":          {},
+	"algorithm": {choices: []string{
+		"ES256", "ES384", "ES512", "RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "EdDSA",
+	}},
+	"access_ttl":   {duration: true},
+	"refresh_ttl":  {duration: true},
+	"key_rotation": {duration: true},
+	"storage":      {choices: []string{"database", "memory"}},
+}
+
+// validateAuthConfig validates the config block of an auth provider.
+func validateAuthConfig(auth *ast.AuthDecl) error {
+	if len(auth.Config) == 0 {
+		return nil
+	}
+	settings, ok := authSettings[auth.Method]
+	if !ok {
+		return fmt.Errorf("config is not supported for %s providers", auth.Method)
+	}
+
+	for _, key := range sortedKeys(auth.Config) {
+		setting, ok := settings[key]
+		if !ok {
+			valid := make([]string, 0, len(settings))
+			for name := range settings {
+				valid = append(valid, name)
+			}
+			sort.Strings(valid)
+			return fmt.Errorf("unknown %s setting '%s'; valid settings: %s", auth.Method, key, strings.Join(valid, ", "))
+		}
+		if err := validateAuthSetting(key, setting, auth.Config[key]); err != nil {
+			return err
+		}
+	}
+
+	switch auth.Method {
+	case ast.AuthMethodBasic:
+		_, hasFile := auth.Config["file"]
+		_, hasModel := auth.Config["model"]
+		if hasFile && hasModel {
+			return fmt.Errorf("file and model are mutually exclusive")
+		}
+	case ast.AuthMethodOAuth2:
+		if _, ok := auth.Config["introspection_url"]; !ok {
+			return fmt.Errorf("introspection_url is required in config")
+		}
+		if value, ok := auth.Config["introspection_url"].(*ast.StringLiteral); ok {
+			if u, err := url.Parse(value.Value); err != nil || u.Scheme == "" || u.Host == "" {
+				return fmt.Errorf("invalid introspection_url %q", value.Value)
+			}
+		}
+	}
+	return nil
+}
+
+// validateAuthIssuer validates the issuer block of an auth provider.
+func validateAuthIssuer(auth *ast.AuthDecl) error {
+	if auth.Issuer == nil {
+		return nil
+	}
+	if auth.Method != ast.AuthMethodJWT {
+		return fmt.Errorf("issuer is only supported for jwt providers")
+	}
+	if auth.JWKS != nil {
+		return fmt.Errorf("issuer and jwks_url are mutually exclusive")
+	}
+
+	config := auth.Issuer.Config
+	for _, key := range sortedKeys(config) {
+		setting, ok := issuerSettings[key]
+		if !ok {
+			valid := make([]string, 0, len(issuerSettings))
+			for name := range issuerSettings {
+				valid = append(valid, name)
+			}
+			sort.Strings(valid)
+			return fmt.Errorf("unknown issuer setting '%s'; valid settings: %s", key, strings.Join(valid, ", "))
+		}
+		if err := validateAuthSetting(key, setting, config[key]); err != nil {
+			return fmt.Errorf("issuer: %w", err)
+		}
+	}
+
+	_, hasFile := config["file"]
+	_, hasModel := config["model"]
+	switch {
+	case hasFile && hasModel:
+		return fmt.Errorf("issuer: file and model are mutually exclusive")
+	case !hasFile && !hasModel:
+		return fmt.Errorf("issuer: model or file is required")
+	}
+	return nil
+}
+
+// validateAuthSetting validates the value of a single auth setting.
+func validateAuthSetting(key string, setting authSetting, expr ast.Expression) error {
+	switch {
+	case setting.duration:
+		value, ok := expr.(*ast.StringLiteral)
+		if !ok {
+			return fmt.Errorf("%s must be a duration string", key)
+		}
+		if d, err := time.ParseDuration(value.Value); err != nil || d <= 0 {
+			return fmt.Errorf("invalid %s %q", key, value.Value)
+		}
+	case setting.choices != nil:
+		var choice string
+		switch value := expr.(type) {
+		case *ast.StringLiteral:
+			choice = value.Value
+		case *ast.Identifier:
+			choice = value.Name
+		}
+		for _, c := range setting.choices {
+			if choice == c {
+				return nil
+			}
+		}
+		return fmt.Errorf("invalid %s; valid values: %s", key, strings.Join(setting.choices, ", "))
+	default:
+		switch value := expr.(type) {
+		case *ast.StringLiteral:
+			if value.Value != "" {
+				return nil
+			}
+		case *ast.FunctionCall:
+			if value.Name == "env" && len(value.Args) == 1 {
+				return nil
+			}
+		}
+		return fmt.Errorf("%s must be a non-empty string or env() call", key)
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]ast.Expression) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // ValidateJWKS validates JWKS configuration
 func ValidateJWKS(jwks *ast.JWKSConfig) error {
 	if jwks == nil {
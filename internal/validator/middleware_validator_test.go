@@ -132,6 +132,214 @@ func TestValidateAuth(t *testing.T) {
 			expectError: true,
 			errorMsg:    "jwks_url must use HTTPS",
 		},
+		{
+			name: "valid API key auth",
+			auth: &ast.AuthDecl{
+				Name:   "api_keys",
+				Method: ast.AuthMethodAPIKey,
+				Config: map[string]ast.Expression{
+					"header":             &ast.StringLiteral{Value: "X-Service-Key"},
+					"query":              &ast.StringLiteral{Value: "key"},
+					"last_used_interval": &ast.StringLiteral{Value: "5m"},
+					"storage":            &ast.Identifier{Name: "memory"},
+				},
+			},
+			expectError: false,
+		},
+		{
+			name: "API key auth with invalid storage",
+			auth: &ast.AuthDecl{
+				Name:   "api_keys",
+				Method: ast.AuthMethodAPIKey,
+				Config: map[string]ast.Expression{
+					"storage": &ast.StringLiteral{Value: "redis"},
+				},
+			},
+			expectError: true,
+			errorMsg:    "auth api_keys: invalid storage",
+		},
+		{
+			name: "API key auth with invalid interval",
+			auth: &ast.AuthDecl{
+				Name:   "api_keys",
+				Method: ast.AuthMethodAPIKey,
+				Config: map[string]ast.Expression{
+					"last_used_interval": &ast.StringLiteral{Value: "often"},
+				},
+			},
+			expectError: true,
+			errorMsg:    "invalid last_used_interval",
+		},
+		{
+			name: "API key auth with unknown setting",
+			auth: &ast.AuthDecl{
+				Name:   "api_keys",
+				Method: ast.AuthMethodAPIKey,
+				Config: map[string]ast.Expression{
+					"prefix": &ast.StringLiteral{Value: "key_"},
+				},
+			},
+			expectError: true,
+			errorMsg:    "unknown apikey setting 'prefix'",
+		},
+		{
+			name: "valid basic auth from a model",
+			auth: &ast.AuthDecl{
+				Name:   "staff",
+				Method: ast.AuthMethodBasic,
+				Config: map[string]ast.Expression{
+					"model":          &ast.StringLiteral{Value: "User"},
+					"username_field": &ast.StringLiteral{Value: "email"},
+					"roles_field":    &ast.StringLiteral{Value: "role"},
+				},
+			},
+			expectError: false,
+		},
+		{
+			name: "basic auth with file and model",
+			auth: &ast.AuthDecl{
+				Name:   "staff",
+				Method: ast.AuthMethodBasic,
+				Config: map[string]ast.Expression{
+					"file":  &ast.StringLiteral{Value: "users.htpasswd"},
+					"model": &ast.StringLiteral{Value: "User"},
+				},
+			},
+			expectError: true,
+			errorMsg:    "file and model are mutually exclusive",
+		},
+		{
+			name: "valid OAuth2 introspection auth",
+			auth: &ast.AuthDecl{
+				Name:   "partners",
+				Method: ast.AuthMethodOAuth2,
+				Config: map[string]ast.Expression{
+					"introspection_url": &ast.StringLiteral{Value: "https://auth.example.com/introspect"},
+					"client_id":         &ast.StringLiteral{Value: "api"},
+					"client_secret": &ast.FunctionCall{Name: "env", Args: []ast.Expression{
+						&ast.StringLiteral{Value: "INTROSPECTION_SECRET"},
+					}},
+					"cache_ttl": &ast.StringLiteral{Value: "2m"},
+					"cache":     &ast.Identifier{Name: "redis"},
+				},
+			},
+			expectError: false,
+		},
+		{
+			name: "OAuth2 config without introspection URL",
+			auth: &ast.AuthDecl{
+				Name:   "partners",
+				Method: ast.AuthMethodOAuth2,
+				Config: map[string]ast.Expression{
+					"client_id": &ast.StringLiteral{Value: "api"},
+				},
+			},
+			expectError: true,
+			errorMsg:    "introspection_url is required",
+		},
+		{
+			name: "OAuth2 with invalid cache",
+			auth: &ast.AuthDecl{
+				Name:   "partners",
+				Method: ast.AuthMethodOAuth2,
+				Config: map[string]ast.Expression{
+					"introspection_url": &ast.StringLiteral{Value: "https://auth.example.com/introspect"},
+					"cache":             &ast.Identifier{Name: "disk"},
+				},
+			},
+			expectError: true,
+			errorMsg:    "invalid cache; valid values: memory, redis",
+		},
+		{
+			name: "config on JWT auth",
+			auth: &ast.AuthDecl{
+				Name:   "jwt_auth",
+				Method: ast.AuthMethodJWT,
+				Config: map[string]ast.Expression{
+					"header": &ast.StringLiteral{Value: "X-Token"},
+				},
+			},
+			expectError: true,
+			errorMsg:    "config is not supported for jwt providers",
+		},
+		{
+			name: "valid JWT issuer",
+			auth: &ast.AuthDecl{
+				Name:   "app_tokens",
+				Method: ast.AuthMethodJWT,
+				Issuer: &ast.AuthIssuerDecl{Config: map[string]ast.Expression{
+					"model":      &ast.StringLiteral{Value: "User"},
+					"issuer":     &ast.StringLiteral{Value: "https://api.example.com"},
+					"algorithm":  &ast.StringLiteral{Value: "EdDSA"},
+					"access_ttl": &ast.StringLiteral{Value: "10m"},
+					"storage":    &ast.Identifier{Name: "database"},
+				}},
+			},
+			expectError: false,
+		},
+		{
+			name: "issuer on API key auth",
+			auth: &ast.AuthDecl{
+				Name:   "api_keys",
+				Method: ast.AuthMethodAPIKey,
+				Issuer: &ast.AuthIssuerDecl{Config: map[string]ast.Expression{
+					"model": &ast.StringLiteral{Value: "User"},
+				}},
+			},
+			expectError: true,
+			errorMsg:    "issuer is only supported for jwt providers",
+		},
+		{
+			name: "issuer with JWKS URL",
+			auth: &ast.AuthDecl{
+				Name:   "app_tokens",
+				Method: ast.AuthMethodJWT,
+				JWKS:   &ast.JWKSConfig{URL: "https://auth.example.com/jwks.json", Issuer: "https://auth.example.com", Audience: "api"},
+				Issuer: &ast.AuthIssuerDecl{Config: map[string]ast.Expression{
+					"model": &ast.StringLiteral{Value: "User"},
+				}},
+			},
+			expectError: true,
+			errorMsg:    "issuer and jwks_url are mutually exclusive",
+		},
+		{
+			name: "issuer without credentials",
+			auth: &ast.AuthDecl{
+				Name:   "app_tokens",
+				Method: ast.AuthMethodJWT,
+				Issuer: &ast.AuthIssuerDecl{Config: map[string]ast.Expression{
+					"access_ttl": &ast.StringLiteral{Value: "10m"},
+				}},
+			},
+			expectError: true,
+			errorMsg:    "issuer: model or file is required",
+		},
+		{
+			name: "issuer with HMAC algorithm",
+			auth: &ast.AuthDecl{
+				Name:   "app_tokens",
+				Method: ast.AuthMethodJWT,
+				Issuer: &ast.AuthIssuerDecl{Config: map[string]ast.Expression{
+					"file":      &ast.StringLiteral{Value: "users.htpasswd"},
+					"algorithm": &ast.StringLiteral{Value: "HS256"},
+				}},
+			},
+			expectError: true,
+			errorMsg:    "issuer: invalid algorithm",
+		},
+		{
+			name: "issuer with unknown setting",
+			auth: &ast.AuthDecl{
+				Name:   "app_tokens",
+				Method: ast.AuthMethodJWT,
+				Issuer: &ast.AuthIssuerDecl{Config: map[string]ast.Expression{
+					"file":   &ast.StringLiteral{Value: "users.htpasswd"},
+					"secret": &ast.StringLiteral{Value: "x"},
+				}},
+			},
+			expectError: true,
+			errorMsg:    "unknown issuer setting 'secret'",
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,123 @@
+package validator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bargom/codeai/internal/abac"
+	"github.com/bargom/codeai/internal/ast"
+)
+
+// implicitRecordFields are fields every record has without declaring them.
+var implicitRecordFields = map[string]bool{
+	"id":         true,
+	"_id":        true,
+	"created_at": true,
+	"updated_at": true,
+}
+
+// validatePolicyDecl validates an authorization policy declaration.
+func (v *Validator) validatePolicyDecl(policy *ast.PolicyDecl) {
+	name := policy.Name()
+	if existing, exists := v.policies[name]; exists {
+		v.errors.Add(newSemanticError(policy.Pos(),
+			"duplicate policy '"+name+"'; first declared at "+existing.Pos().String()))
+		return
+	}
+	v.policies[name] = policy
+
+	if _, err := abac.Compile(name, policy.Condition); err != nil {
+		v.errors.Add(newSemanticError(policy.Pos(), "invalid condition in "+err.Error()))
+	}
+}
+
+// validatePolicyReferences checks the resource fields used by policies
+// against the model or collection of the same name, and the policies
+// referenced by endpoint logic. It runs after all declarations have been
+// collected.
+func (v *Validator) validatePolicyReferences(program *ast.Program) {
+	records := declaredRecords(program.Statements)
+
+	for _, policy := range v.policies {
+		record, ok := records[strings.ToLower(policy.Resource)]
+		if !ok {
+			continue
+		}
+		compiled, err := abac.Compile(policy.Name(), policy.Condition)
+		if err != nil {
+			continue // already reported
+		}
+		for _, field := range compiled.ResourceFields() {
+			if !record.fields[field] && !implicitRecordFields[field] {
+				v.errors.Add(newSemanticError(policy.Pos(),
+					fmt.Sprintf("policy '%s' references unknown field '%s' of %s", policy.Name(), field, record.name)))
+			}
+		}
+	}
+
+	for _, stmt := range program.Statements {
+		ep, ok := stmt.(*ast.EndpointDecl)
+		if !ok || ep.Handler == nil || ep.Handler.Logic == nil {
+			continue
+		}
+		for _, step := range ep.Handler.Logic.Steps {
+			for _, name := range policyRefs(step) {
+				if _, exists := v.policies[name]; !exists {
+					v.errors.Add(newSemanticError(ep.Pos(),
+						fmt.Sprintf("endpoint %s %s references unknown policy '%s'", ep.Method, ep.Path, name)))
+				}
+			}
+		}
+	}
+}
+
+// policyRefs returns the policies a logic step references:
+// authorize(post, "post.update") and db.find(Post) with { authorize: "post.read" }.
+// Arguments of authorize without a dot are role names.
+func policyRefs(step *ast.LogicStep) []string {
+	var refs []string
+	if step.Action == "authorize" && len(step.Args) > 1 && strings.Contains(step.Args[1], ".") {
+		refs = append(refs, step.Args[1])
+	}
+	for _, opt := range step.Options {
+		if opt.Key != "authorize" {
+			continue
+		}
+		if lit, ok := opt.Value.(*ast.StringLiteral); ok {
+			refs = append(refs, lit.Value)
+		}
+	}
+	return refs
+}
+
+// declaredRecord is a model or collection a policy may apply to.
+type declaredRecord struct {
+	name   string
+	fields map[string]bool
+}
+
+// declaredRecords returns the models and collections, by lowercase name.
+func declaredRecords(stmts []ast.Statement) map[string]declaredRecord {
+	records := make(map[string]declaredRecord)
+	for _, stmt := range stmts {
+		switch decl := stmt.(type) {
+		case *ast.DatabaseBlock:
+			for name, record := range declaredRecords(decl.Statements) {
+				records[name] = record
+			}
+		case *ast.ModelDecl:
+			record := declaredRecord{name: "model '" + decl.Name + "'", fields: make(map[string]bool)}
+			for _, f := range decl.Fields {
+				record.fields[f.Name] = true
+			}
+			records[strings.ToLower(decl.Name)] = record
+		case *ast.CollectionDecl:
+			record := declaredRecord{name: "collection '" + decl.Name + "'", fields: make(map[string]bool)}
+			for _, f := range decl.Fields {
+				record.fields[f.Name] = true
+			}
+			records[strings.ToLower(decl.Name)] = record
+		}
+	}
+	return records
+}
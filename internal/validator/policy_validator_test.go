@@ -0,0 +1,109 @@
+package validator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bargom/codeai/internal/parser"
+)
+
+const policyModels = `
+database postgres {
+	model Post {
+		id: uuid, primary, auto
+		author_id: uuid, required
+		published: boolean
+	}
+}
+`
+
+func TestPolicyDecl_Valid(t *testing.T) {
+	source := policyModels + `
+authorize post.update when resource.author_id == auth.sub or auth.role == "admin"
+authorize post.read when resource.published or resource.author_id == auth.sub
+authorize report.read when contains(auth.roles, "auditor")
+
+endpoint PUT "/posts/:id" {
+	request UpdatePost from body
+	response Post status 200
+	do {
+		post = db.findOne(Post, request.id)
+		authorize(post, "post.update")
+		authorize(request, "admin")
+	}
+}
+
+endpoint GET "/posts" {
+	response PostList status 200
+	do {
+		posts = db.find(Post) with { authorize: "post.read" }
+	}
+}
+`
+
+	prog, err := parser.Parse(source)
+	require.NoError(t, err, "parse error")
+	assert.NoError(t, New().Validate(prog))
+}
+
+func TestPolicyDecl_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		wantErr string
+	}{
+		{
+			name: "duplicate policy",
+			source: `authorize post.update when true
+authorize post.update when false`,
+			wantErr: "duplicate policy 'post.update'",
+		},
+		{
+			name:    "invalid condition",
+			source:  `authorize post.update when resource.author_id ==`,
+			wantErr: "invalid condition in policy post.update",
+		},
+		{
+			name:    "unknown variable",
+			source:  `authorize post.update when user.id == resource.author_id`,
+			wantErr: `unknown identifier "user"`,
+		},
+		{
+			name:    "unknown resource field",
+			source:  policyModels + `authorize post.update when resource.owner_id == auth.sub`,
+			wantErr: "policy 'post.update' references unknown field 'owner_id' of model 'Post'",
+		},
+		{
+			name: "unknown policy in authorize step",
+			source: `endpoint DELETE "/posts/:id" {
+	do {
+		post = db.findOne(Post, request.id)
+		authorize(post, "post.delete")
+	}
+}`,
+			wantErr: "endpoint DELETE /posts/:id references unknown policy 'post.delete'",
+		},
+		{
+			name: "unknown policy in row filter",
+			source: `endpoint GET "/posts" {
+	do {
+		posts = db.find(Post) with { authorize: "post.list" }
+	}
+}`,
+			wantErr: "references unknown policy 'post.list'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prog, err := parser.Parse(tt.source)
+			require.NoError(t, err, "parse error")
+
+			err = New().Validate(prog)
+			require.Error(t, err, "validation should fail")
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
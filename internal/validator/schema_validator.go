@@ -0,0 +1,66 @@
+package validator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bargom/codeai/internal/ast"
+)
+
+// validateSchemaReferences checks that validate steps in endpoint logic
+// refer to a declared model or collection. validate(request) checks the
+// endpoint's request type, and validate(x, Model) names the model
+// explicitly; validate(x) alone only checks x against a record of the same
+// name when there is one. A program without models or collections has
+// nothing to validate against, and the runtime skips these steps, so they
+// are not checked. It runs after all declarations have been collected.
+func (v *Validator) validateSchemaReferences(program *ast.Program) {
+	records := declaredRecords(program.Statements)
+	if len(records) == 0 {
+		return
+	}
+
+	for _, stmt := range program.Statements {
+		ep, ok := stmt.(*ast.EndpointDecl)
+		if !ok || ep.Handler == nil || ep.Handler.Logic == nil {
+			continue
+		}
+		for _, step := range ep.Handler.Logic.Steps {
+			if step.Action != "validate" {
+				continue
+			}
+
+			var schemaName string
+			switch {
+			case len(step.Args) > 1:
+				schemaName = step.Args[1]
+			case len(step.Args) == 0 || step.Args[0] == "request" || step.Args[0] == "input":
+				if ep.Handler.Request == nil {
+					v.errors.Add(newSemanticError(ep.Pos(),
+						fmt.Sprintf("endpoint %s %s validates the request but declares no request type", ep.Method, ep.Path)))
+					continue
+				}
+				schemaName = ep.Handler.Request.TypeName
+			default:
+				continue
+			}
+
+			if !hasSchema(records, schemaName) {
+				v.errors.Add(newSemanticError(ep.Pos(),
+					fmt.Sprintf("endpoint %s %s validates against unknown model or collection '%s'", ep.Method, ep.Path, schemaName)))
+			}
+		}
+	}
+}
+
+// hasSchema reports whether name is a declared record. Names are matched
+// case-insensitively, and request names such as CreateUser or UserInput fall
+// back to the model they are derived from, as at runtime.
+func hasSchema(records map[string]declaredRecord, name string) bool {
+	for _, candidate := range ast.SchemaCandidates(name) {
+		if _, ok := records[strings.ToLower(candidate)]; ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package validator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bargom/codeai/internal/parser"
+)
+
+const schemaModels = `
+database postgres {
+	model User {
+		id: uuid, primary, auto
+		email: string, required
+	}
+}
+`
+
+func TestValidateStep_KnownSchemas(t *testing.T) {
+	source := schemaModels + `
+endpoint POST "/users" {
+	request CreateUser from body
+	response User status 201
+	do {
+		validate(request)
+	}
+}
+
+endpoint PUT "/users/:id" {
+	request UserInput from body
+	response User status 200
+	do {
+		validate(id)
+		validate(request, user)
+	}
+}
+`
+	prog, err := parser.Parse(source)
+	require.NoError(t, err)
+	assert.NoError(t, New().Validate(prog))
+}
+
+func TestValidateStep_UnknownSchemas(t *testing.T) {
+	tests := []struct {
+		name     string
+		endpoint string
+		wantErr  string
+	}{
+		{
+			name: "misspelled request type",
+			endpoint: `endpoint POST "/users" {
+	request CreateUsr from body
+	response User status 201
+	do {
+		validate(request)
+	}
+}`,
+			wantErr: "endpoint POST /users validates against unknown model or collection 'CreateUsr'",
+		},
+		{
+			name: "unknown explicit model",
+			endpoint: `endpoint POST "/users" {
+	request CreateUser from body
+	response User status 201
+	do {
+		validate(request, Account)
+	}
+}`,
+			wantErr: "endpoint POST /users validates against unknown model or collection 'Account'",
+		},
+		{
+			name: "no request type",
+			endpoint: `endpoint POST "/users" {
+	response User status 201
+	do {
+		validate(request)
+	}
+}`,
+			wantErr: "endpoint POST /users validates the request but declares no request type",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prog, err := parser.Parse(schemaModels + tt.endpoint)
+			require.NoError(t, err)
+
+			err = New().Validate(prog)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestValidateStep_NoSchemasDeclared(t *testing.T) {
+	// Without models or collections the runtime skips validate steps, so the
+	// validator doesn't report them either.
+	source := `
+endpoint POST "/users" {
+	request CreateUser from body
+	do {
+		validate(request)
+		validate(request, Account)
+	}
+}
+`
+	prog, err := parser.Parse(source)
+	require.NoError(t, err)
+	assert.NoError(t, New().Validate(prog))
+}
@@ -0,0 +1,103 @@
+package validator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bargom/codeai/internal/ast"
+	"github.com/bargom/codeai/internal/tenant"
+)
+
+// validateTenancyDecl validates the multi-tenancy configuration.
+func (v *Validator) validateTenancyDecl(decl *ast.TenancyDecl) {
+	if v.tenancy != nil {
+		v.errors.Add(newSemanticError(decl.Pos(),
+			"duplicate tenancy block; first declared at "+v.tenancy.Pos().String()))
+		return
+	}
+	v.tenancy = decl
+
+	if decl.Strategy != "" {
+		if _, err := tenant.ParseStrategy(decl.Strategy); err != nil {
+			v.errors.Add(newSemanticError(decl.Pos(), err.Error()))
+		}
+	}
+
+	keys := make([]string, 0, len(decl.Settings))
+	for key := range decl.Settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	sources := 0
+	for _, key := range keys {
+		switch value := decl.Settings[key]; key {
+		case "claim", "header", "column":
+			lit, ok := value.(*ast.StringLiteral)
+			if !ok || lit.Value == "" {
+				v.errors.Add(newSemanticError(decl.Pos(), "tenancy setting '"+key+"' must be a non-empty string"))
+				continue
+			}
+			if key != "column" {
+				sources++
+			}
+		case "subdomain":
+			lit, ok := value.(*ast.BoolLiteral)
+			if !ok {
+				v.errors.Add(newSemanticError(decl.Pos(), "tenancy setting 'subdomain' must be true or false"))
+				continue
+			}
+			if lit.Value {
+				sources++
+			}
+		default:
+			v.errors.Add(newSemanticError(decl.Pos(),
+				"unknown tenancy setting '"+key+"'; valid settings: strategy, claim, header, subdomain, column"))
+		}
+	}
+	if sources == 0 {
+		v.errors.Add(newSemanticError(decl.Pos(),
+			"tenancy block must resolve the tenant from a claim, header or subdomain"))
+	}
+}
+
+// validateTenancyReferences checks that the strategy is supported by the
+// database, and that under the column strategy every model and collection
+// has the tenant column. It runs after all declarations have been
+// collected.
+func (v *Validator) validateTenancyReferences(program *ast.Program) {
+	if v.tenancy == nil {
+		return
+	}
+
+	// The server has one connection to PostgreSQL, and the event outbox
+	// shares its transactions, so tenants cannot have their own databases
+	if v.tenancy.Strategy == string(tenant.StrategyDatabase) &&
+		(v.configDecl == nil || v.configDecl.DatabaseType != ast.DatabaseTypeMongoDB) {
+		v.errors.Add(newSemanticError(v.tenancy.Pos(),
+			"tenancy strategy 'database' requires database_type 'mongodb'; use 'column' or 'schema' on PostgreSQL"))
+		return
+	}
+	if v.tenancy.Strategy != "" && v.tenancy.Strategy != string(tenant.StrategyColumn) {
+		return
+	}
+
+	column := tenant.DefaultColumn
+	if lit, ok := v.tenancy.Settings["column"].(*ast.StringLiteral); ok && lit.Value != "" {
+		column = lit.Value
+	}
+
+	records := declaredRecords(program.Statements)
+	names := make([]string, 0, len(records))
+	for name := range records {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if record := records[name]; !record.fields[column] {
+			v.errors.Add(newSemanticError(v.tenancy.Pos(),
+				fmt.Sprintf("%s has no tenant column '%s'", strings.ToUpper(record.name[:1])+record.name[1:], column)))
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package validator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bargom/codeai/internal/parser"
+)
+
+func TestTenancyDecl_Valid(t *testing.T) {
+	sources := []string{
+		`tenancy { strategy: column, claim: "org_id" }
+database postgres {
+	model Project {
+		id: uuid, primary, auto
+		tenant_id: string, required
+	}
+}`,
+		`tenancy { strategy: schema header: "X-Tenant-ID" subdomain: true }
+database postgres {
+	model Project {
+		id: uuid, primary, auto
+	}
+}`,
+		`config {
+	database_type: "mongodb"
+	mongodb_uri: "mongodb://localhost:27017"
+	mongodb_database: "app"
+}
+tenancy { claim: "org_id" column: "org_id" }
+database mongodb {
+	collection Project {
+		org_id: string
+	}
+}`,
+		`config {
+	database_type: "mongodb"
+	mongodb_uri: "mongodb://localhost:27017"
+	mongodb_database: "app"
+}
+tenancy { strategy: database claim: "org_id" }
+database mongodb {
+	collection Project {
+		name: string
+	}
+}`,
+	}
+
+	for _, source := range sources {
+		prog, err := parser.Parse(source)
+		require.NoError(t, err, "parse error")
+		assert.NoError(t, New().Validate(prog), source)
+	}
+}
+
+func TestTenancyDecl_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		wantErr string
+	}{
+		{
+			name:    "unknown strategy",
+			source:  `tenancy { strategy: table, claim: "org_id" }`,
+			wantErr: `unknown tenancy strategy "table"`,
+		},
+		{
+			name:    "no source",
+			source:  `tenancy { strategy: column subdomain: false }`,
+			wantErr: "must resolve the tenant from a claim, header or subdomain",
+		},
+		{
+			name:    "unknown setting",
+			source:  `tenancy { claim: "org_id" cookie: "tenant" }`,
+			wantErr: "unknown tenancy setting 'cookie'",
+		},
+		{
+			name:    "claim not a string",
+			source:  `tenancy { claim: org_id }`,
+			wantErr: "tenancy setting 'claim' must be a non-empty string",
+		},
+		{
+			name: "duplicate block",
+			source: `tenancy { claim: "org_id" }
+tenancy { header: "X-Tenant-ID" }`,
+			wantErr: "duplicate tenancy block",
+		},
+		{
+			name: "model without tenant column",
+			source: `tenancy { strategy: column, claim: "org_id" }
+database postgres {
+	model Project {
+		id: uuid, primary, auto
+	}
+}`,
+			wantErr: "Model 'Project' has no tenant column 'tenant_id'",
+		},
+		{
+			name: "database strategy on postgres",
+			source: `tenancy { strategy: database, claim: "org_id" }
+database postgres {
+	model Project {
+		id: uuid, primary, auto
+	}
+}`,
+			wantErr: "tenancy strategy 'database' requires database_type 'mongodb'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prog, err := parser.Parse(tt.source)
+			require.NoError(t, err, "parse error")
+
+			err = New().Validate(prog)
+			require.Error(t, err, "validation should fail")
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
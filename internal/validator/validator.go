@@ -2,6 +2,11 @@
 package validator
 
 import (
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/bargom/codeai/internal/ast"
 )
 
@@ -15,8 +20,11 @@ type Validator struct {
 	databaseBlocks []*ast.DatabaseBlock // Track database blocks for validation
 	// Auth, Role, and Middleware tracking
 	authProviders map[string]*ast.AuthDecl
+	tokenIssuer   *ast.AuthDecl // The provider with an issuer block, if any
 	roles         map[string]*ast.RoleDecl
+	policies      map[string]*ast.PolicyDecl
 	middlewares   map[string]*ast.MiddlewareDecl
+	tenancy       *ast.TenancyDecl
 	// Event, Integration, and Webhook tracking
 	eventValidation *EventValidation
 }
@@ -31,6 +39,7 @@ func New() *Validator {
 		types:         make(map[string]Type),
 		authProviders: make(map[string]*ast.AuthDecl),
 		roles:         make(map[string]*ast.RoleDecl),
+		policies:      make(map[string]*ast.PolicyDecl),
 		middlewares:   make(map[string]*ast.MiddlewareDecl),
 	}
 }
@@ -57,6 +66,15 @@ func (v *Validator) Validate(program *ast.Program) error {
 	// Validate event handler references (second pass after all declarations collected)
 	v.validateEventReferences()
 
+	// Validate the fields and endpoint references of policies
+	v.validatePolicyReferences(program)
+
+	// Validate that tenant-scoped records have the tenant column
+	v.validateTenancyReferences(program)
+
+	// Validate the models and collections referenced by validate steps
+	v.validateSchemaReferences(program)
+
 	// Return aggregated errors if any
 	if v.errors.HasErrors() {
 		return v.errors
@@ -106,6 +124,10 @@ func (v *Validator) validateStatement(stmt ast.Statement) {
 		v.validateAuthDecl(s)
 	case *ast.RoleDecl:
 		v.validateRoleDecl(s)
+	case *ast.PolicyDecl:
+		v.validatePolicyDecl(s)
+	case *ast.TenancyDecl:
+		v.validateTenancyDecl(s)
 	case *ast.MiddlewareDecl:
 		v.validateMiddlewareDecl(s)
 	case *ast.EventDecl:
@@ -116,6 +138,8 @@ func (v *Validator) validateStatement(stmt ast.Statement) {
 		v.validateIntegrationDecl(s)
 	case *ast.WebhookDecl:
 		v.validateWebhookDecl(s)
+	case *ast.WebhookReceiverDecl:
+		v.validateWebhookReceiverDecl(s)
 	}
 }
 
@@ -362,6 +386,15 @@ func (v *Validator) validateConfigDecl(cfg *ast.ConfigDecl) {
 				"mongodb_database is required when database_type is 'mongodb'"))
 		}
 	}
+
+	// Validate event_transport value
+	if expr, ok := cfg.Properties["event_transport"]; ok {
+		lit, isString := expr.(*ast.StringLiteral)
+		if !isString || (lit.Value != "local" && lit.Value != "memory" && lit.Value != "redis") {
+			v.errors.Add(newSemanticError(cfg.Pos(),
+				"invalid event_transport: must be 'local', 'memory' or 'redis'"))
+		}
+	}
 }
 
 // validateDatabaseBlock validates a database block declaration.
@@ -441,7 +474,7 @@ func (v *Validator) validateModelDecl(model *ast.ModelDecl) {
 		v.validateFieldType(field.FieldType, model.Name)
 
 		// Validate modifiers
-		v.validateModifiers(field.Modifiers, model.Name, field.Name)
+		v.validateModifiers(field.Modifiers, field.FieldType, model.Name, field.Name)
 	}
 
 	// Validate indexes
@@ -474,9 +507,9 @@ func (v *Validator) validateFieldType(typeRef *ast.TypeRef, modelName string) {
 			"unknown type '"+typeRef.Name+"' in model '"+modelName+"'; valid types: uuid, string, text, integer, decimal, boolean, timestamp, date, time, json, list, ref, enum"))
 	}
 
-	// Validate type parameters - skip for ref() since params are model names, not types
-	// For ref(User), the param "User" refers to a model, not a type
-	if typeRef.Name != "ref" {
+	// Validate type parameters - skip for ref() and enum() since params are not types
+	// For ref(User), the param "User" refers to a model; for enum(a, b) params are values
+	if typeRef.Name != "ref" && typeRef.Name != "enum" {
 		for _, param := range typeRef.Params {
 			v.validateFieldType(param, modelName)
 		}
@@ -485,7 +518,7 @@ func (v *Validator) validateFieldType(typeRef *ast.TypeRef, modelName string) {
 }
 
 // validateModifiers validates field modifiers.
-func (v *Validator) validateModifiers(modifiers []*ast.Modifier, modelName, fieldName string) {
+func (v *Validator) validateModifiers(modifiers []*ast.Modifier, fieldType *ast.TypeRef, modelName, fieldName string) {
 	seenModifiers := make(map[string]bool)
 
 	for _, mod := range modifiers {
@@ -496,8 +529,8 @@ func (v *Validator) validateModifiers(modifiers []*ast.Modifier, modelName, fiel
 		}
 		seenModifiers[mod.Name] = true
 
-		// Validate modifier values
-		if mod.Value != nil {
+		// Validate modifier values; enum defaults name a member, e.g. default(draft)
+		if mod.Value != nil && !isEnumMember(fieldType, mod) {
 			v.validateExpression(mod.Value)
 		}
 	}
@@ -509,6 +542,24 @@ func (v *Validator) validateModifiers(modifiers []*ast.Modifier, modelName, fiel
 	}
 }
 
+// isEnumMember reports whether a modifier value is a bare member of the
+// field's enum type, as in `status: enum(draft, published), default(draft)`.
+func isEnumMember(fieldType *ast.TypeRef, mod *ast.Modifier) bool {
+	if fieldType == nil || fieldType.Name != "enum" {
+		return false
+	}
+	ident, ok := mod.Value.(*ast.Identifier)
+	if !ok {
+		return false
+	}
+	for _, p := range fieldType.Params {
+		if p.Name == ident.Name {
+			return true
+		}
+	}
+	return false
+}
+
 // =============================================================================
 // MongoDB Collection Validation
 // =============================================================================
@@ -652,6 +703,25 @@ func (v *Validator) validateAuthDecl(auth *ast.AuthDecl) {
 		v.validateJWKSConfig(auth.JWKS, auth.Name)
 	}
 
+	if err := validateAuthConfig(auth); err != nil {
+		v.errors.Add(newSemanticError(auth.Pos(),
+			"auth provider '"+auth.Name+"': "+err.Error()))
+	}
+	if err := validateAuthIssuer(auth); err != nil {
+		v.errors.Add(newSemanticError(auth.Pos(),
+			"auth provider '"+auth.Name+"': "+err.Error()))
+	}
+
+	// Issuers serve fixed routes such as /auth/login, so only one may exist
+	if auth.Issuer != nil {
+		if v.tokenIssuer != nil {
+			v.errors.Add(newSemanticError(auth.Pos(),
+				"auth provider '"+auth.Name+"' declares an issuer, but '"+v.tokenIssuer.Name+"' already does; only one issuer is allowed"))
+		} else {
+			v.tokenIssuer = auth
+		}
+	}
+
 	// JWT auth should have JWKS URL for production use
 	if auth.Method == ast.AuthMethodJWT && auth.JWKS == nil {
 		// This is a warning, not an error - JWT can use secret-based validation
@@ -779,33 +849,64 @@ func (v *Validator) validateAuthorizationMiddleware(mw *ast.MiddlewareDecl) {
 // validateRateLimitingMiddleware validates rate limiting middleware config.
 func (v *Validator) validateRateLimitingMiddleware(mw *ast.MiddlewareDecl) {
 	// Check for required config: requests and window
-	_, hasRequests := mw.Config["requests"]
-	_, hasWindow := mw.Config["window"]
+	requestsExpr, hasRequests := mw.Config["requests"]
+	windowExpr, hasWindow := mw.Config["window"]
 
 	if !hasRequests {
 		v.errors.Add(newSemanticError(mw.Pos(),
 			"rate_limiting middleware '"+mw.Name+"' requires 'requests' in config"))
+	} else if n, ok := requestsExpr.(*ast.NumberLiteral); ok && n.Value < 1 {
+		v.errors.Add(newSemanticError(mw.Pos(),
+			"rate_limiting middleware '"+mw.Name+"' requires a positive 'requests'"))
 	}
 
 	if !hasWindow {
 		v.errors.Add(newSemanticError(mw.Pos(),
 			"rate_limiting middleware '"+mw.Name+"' requires 'window' in config"))
+	} else if !validRateLimitWindow(windowExpr) {
+		v.errors.Add(newSemanticError(mw.Pos(),
+			"invalid window in rate_limiting middleware '"+mw.Name+"'; "+
+				"expected a positive duration such as \"1m\" or a number of seconds"))
 	}
 
-	// Validate strategy if present
-	if strategyExpr, hasStrategy := mw.Config["strategy"]; hasStrategy {
-		if ident, ok := strategyExpr.(*ast.Identifier); ok {
-			validStrategies := map[string]bool{
-				"fixed_window":   true,
-				"sliding_window": true,
-				"token_bucket":   true,
-				"leaky_bucket":   true,
-			}
-			if !validStrategies[ident.Name] {
-				v.errors.Add(newSemanticError(mw.Pos(),
-					"unknown rate limiting strategy '"+ident.Name+"' in middleware '"+mw.Name+"'; "+
-						"valid strategies: fixed_window, sliding_window, token_bucket, leaky_bucket"))
-			}
+	// Validate strategy, key_by and storage if present
+	v.validateRateLimitOption(mw, "strategy", "fixed_window", "sliding_window", "token_bucket")
+	v.validateRateLimitOption(mw, "key_by", "ip", "user", "subject", "jwt", "api_key")
+	v.validateRateLimitOption(mw, "storage", "memory", "redis")
+}
+
+// validateRateLimitOption checks that the identifier or string value of a
+// rate limiting config key, when present, is one of valid.
+func (v *Validator) validateRateLimitOption(mw *ast.MiddlewareDecl, key string, valid ...string) {
+	var value string
+	switch e := mw.Config[key].(type) {
+	case *ast.Identifier:
+		value = e.Name
+	case *ast.StringLiteral:
+		value = e.Value
+	default:
+		return
+	}
+	if !slices.Contains(valid, value) {
+		v.errors.Add(newSemanticError(mw.Pos(),
+			"unknown rate limiting "+key+" '"+value+"' in middleware '"+mw.Name+"'; "+
+				"valid values: "+strings.Join(valid, ", ")))
+	}
+}
+
+// validRateLimitWindow reports whether expr is a positive window: a number of
+// seconds, or a string holding a number of seconds or a Go duration.
+func validRateLimitWindow(expr ast.Expression) bool {
+	switch e := expr.(type) {
+	case *ast.NumberLiteral:
+		return e.Value > 0
+	case *ast.StringLiteral:
+		if n, err := strconv.ParseFloat(e.Value, 64); err == nil {
+			return n > 0
 		}
+		d, err := time.ParseDuration(e.Value)
+		return err == nil && d > 0
+	default:
+		return true
 	}
 }
@@ -128,6 +128,15 @@ func TestValidPrograms(t *testing.T) {
 			name:   "variable reference in array",
 			source: `var x = 1` + "\n" + `var arr = [x, 2, 3]`,
 		},
+		{
+			name: "model with enum field",
+			source: `database postgres {
+    model Post {
+        id: uuid, primary, auto
+        status: enum(draft, published), default(draft)
+    }
+}`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -1270,6 +1279,14 @@ func TestConfigDecl_Valid(t *testing.T) {
 			name:   "database block without config defaults to postgres",
 			source: `database postgres { }`,
 		},
+		{
+			name: "redis event transport",
+			source: `config {
+				database_type: "postgres"
+				event_transport: "redis"
+				event_group: "orders"
+			}`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -1316,6 +1333,13 @@ func TestConfigDecl_Invalid(t *testing.T) {
 			}`,
 			expectedErr: "duplicate config declaration",
 		},
+		{
+			name: "unknown event transport",
+			source: `config {
+				event_transport: "kafka"
+			}`,
+			expectedErr: "invalid event_transport",
+		},
 		{
 			name: "config specifies postgres but database block is mongodb",
 			source: `config {
@@ -1373,3 +1397,78 @@ func TestDatabaseTypeConsistency_NoConfigNoBlocks(t *testing.T) {
 	v.validateDatabaseTypeConsistency()
 	assert.False(t, v.errors.HasErrors())
 }
+
+func TestAuthIssuer_OnlyOneAllowed(t *testing.T) {
+	source := `auth staff_tokens {
+		method jwt
+		issuer { file: "staff.htpasswd" }
+	}
+	auth customer_tokens {
+		method jwt
+		issuer { model: "Customer" }
+	}`
+
+	prog, err := parser.Parse(source)
+	require.NoError(t, err, "parse error")
+
+	v := New()
+	err = v.Validate(prog)
+	require.Error(t, err, "validation should fail")
+	assert.Contains(t, err.Error(), "auth provider 'customer_tokens' declares an issuer, but 'staff_tokens' already does")
+}
+
+func TestRateLimitingMiddleware_Config(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr string
+	}{
+		{"valid", `requests: 10
+			window: "1m"
+			strategy: token_bucket
+			key_by: "api_key"
+			storage: "redis"`, ""},
+		{"window in seconds", `requests: 10
+			window: 30`, ""},
+		{"bad window", `requests: 10
+			window: "1 minute"`, "invalid window in rate_limiting middleware 'limit'"},
+		{"zero window", `requests: 10
+			window: "0s"`, "invalid window in rate_limiting middleware 'limit'"},
+		{"zero requests", `requests: 0
+			window: "1m"`, "requires a positive 'requests'"},
+		{"unsupported strategy", `requests: 10
+			window: "1m"
+			strategy: leaky_bucket`, "unknown rate limiting strategy 'leaky_bucket'"},
+		{"string strategy", `requests: 10
+			window: "1m"
+			strategy: "fixed"`, "unknown rate limiting strategy 'fixed'"},
+		{"unknown key_by", `requests: 10
+			window: "1m"
+			key_by: "cookie"`, "unknown rate limiting key_by 'cookie'"},
+		{"unknown storage", `requests: 10
+			window: "1m"
+			storage: "disk"`, "unknown rate limiting storage 'disk'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := `middleware limit {
+		type rate_limiting
+		config {
+			` + tt.config + `
+		}
+	}`
+
+			prog, err := parser.Parse(source)
+			require.NoError(t, err, "parse error")
+
+			err = New().Validate(prog)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err, "validation should fail")
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
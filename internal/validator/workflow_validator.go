@@ -3,12 +3,14 @@ package validator
 
 import (
 	"fmt"
+	"maps"
 	"regexp"
 	"time"
 
 	"github.com/robfig/cron/v3"
 
 	"github.com/bargom/codeai/internal/ast"
+	"github.com/bargom/codeai/internal/workflow/expr"
 )
 
 // WorkflowValidator performs semantic validation on workflow and job declarations.
@@ -102,7 +104,7 @@ func (v *WorkflowValidator) validateWorkflow(decl *ast.WorkflowDecl) {
 
 	stepNames := make(map[string]bool)
 	for _, step := range decl.Steps {
-		v.validateWorkflowStep(step, stepNames)
+		v.validateWorkflowStep(step, stepNames, stepNames)
 	}
 
 	// Validate retry policy if present
@@ -144,16 +146,19 @@ func (v *WorkflowValidator) validateTrigger(trigger *ast.Trigger) {
 	}
 }
 
-// validateWorkflowStep validates a single workflow step.
-func (v *WorkflowValidator) validateWorkflowStep(step *ast.WorkflowStep, stepNames map[string]bool) {
+// validateWorkflowStep validates a single workflow step. completed holds the
+// steps whose output is available to the step's expressions.
+func (v *WorkflowValidator) validateWorkflowStep(step *ast.WorkflowStep, stepNames, completed map[string]bool) {
 	if step == nil {
 		return
 	}
 
 	if step.Parallel {
-		// Validate parallel block
+		// Parallel steps run concurrently, so they can only reference steps
+		// that finished before the block
+		before := maps.Clone(stepNames)
 		for _, nestedStep := range step.Steps {
-			v.validateWorkflowStep(nestedStep, stepNames)
+			v.validateWorkflowStep(nestedStep, stepNames, before)
 		}
 		return
 	}
@@ -183,14 +188,24 @@ func (v *WorkflowValidator) validateWorkflowStep(step *ast.WorkflowStep, stepNam
 		}
 	}
 
+	// Validate condition
+	if step.Condition != "" {
+		program, err := expr.Compile(step.Condition)
+		if err != nil {
+			v.errors.Add(newSemanticError(step.Pos(), fmt.Sprintf("invalid condition for step %q: %v", step.Name, err)))
+		} else {
+			v.validateStepRefs(step, program, completed)
+		}
+	}
+
 	// Validate input mappings
 	for _, mapping := range step.Input {
-		v.validateInputMapping(mapping)
+		v.validateInputMapping(step, mapping, completed)
 	}
 }
 
 // validateInputMapping validates an input mapping.
-func (v *WorkflowValidator) validateInputMapping(mapping *ast.InputMapping) {
+func (v *WorkflowValidator) validateInputMapping(step *ast.WorkflowStep, mapping *ast.InputMapping, completed map[string]bool) {
 	if mapping == nil {
 		return
 	}
@@ -200,7 +215,27 @@ func (v *WorkflowValidator) validateInputMapping(mapping *ast.InputMapping) {
 		v.errors.Add(newSemanticError(mapping.Pos(), fmt.Sprintf("invalid input mapping key: %q", mapping.Key)))
 	}
 
-	// Value can be any expression reference - skip strict validation
+	// Values referencing workflow or steps must be valid expressions;
+	// anything else is passed to the activity as a literal
+	program, err := expr.CompileMapping(mapping.Value)
+	if err != nil {
+		v.errors.Add(newSemanticError(mapping.Pos(), fmt.Sprintf("invalid input mapping %q: %v", mapping.Key, err)))
+		return
+	}
+	v.validateStepRefs(step, program, completed)
+}
+
+// validateStepRefs checks that an expression only references the output of
+// steps that run before the given step.
+func (v *WorkflowValidator) validateStepRefs(step *ast.WorkflowStep, program *expr.Program, completed map[string]bool) {
+	for _, ref := range program.StepRefs() {
+		switch {
+		case ref == step.Name:
+			v.errors.Add(newSemanticError(step.Pos(), fmt.Sprintf("step %q cannot reference its own output", step.Name)))
+		case !completed[ref]:
+			v.errors.Add(newSemanticError(step.Pos(), fmt.Sprintf("step %q references step %q, which does not run before it", step.Name, ref)))
+		}
+	}
 }
 
 // validateRetryPolicy validates a retry policy.
@@ -0,0 +1,97 @@
+package validator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bargom/codeai/internal/ast"
+)
+
+func TestValidateWorkflowExpressions(t *testing.T) {
+	step := func(name, condition string, input ...*ast.InputMapping) *ast.WorkflowStep {
+		return &ast.WorkflowStep{Name: name, Activity: "tasks." + name, Condition: condition, Input: input}
+	}
+	mapping := func(key, value string) *ast.InputMapping {
+		return &ast.InputMapping{Key: key, Value: value}
+	}
+
+	tests := []struct {
+		name     string
+		steps    []*ast.WorkflowStep
+		errorMsg string
+	}{
+		{
+			name: "valid references",
+			steps: []*ast.WorkflowStep{
+				step("check", "", mapping("user", "workflow.input.user_id")),
+				step("notify", "steps.check.output.approved == true && exists(workflow.input.email)",
+					mapping("score", "steps.check.output.score"),
+					mapping("template", "approval_email"),
+				),
+			},
+		},
+		{
+			name:     "condition syntax error",
+			steps:    []*ast.WorkflowStep{step("notify", "workflow.input.ok ==")},
+			errorMsg: `invalid condition for step "notify"`,
+		},
+		{
+			name:     "unknown function",
+			steps:    []*ast.WorkflowStep{step("notify", "now() > 0")},
+			errorMsg: `unknown function "now"`,
+		},
+		{
+			name:     "invalid mapping path",
+			steps:    []*ast.WorkflowStep{step("notify", "", mapping("user", "workflow.inputs.user"))},
+			errorMsg: `invalid input mapping "user"`,
+		},
+		{
+			name: "reference to later step",
+			steps: []*ast.WorkflowStep{
+				step("first", "", mapping("x", "steps.second.output.x")),
+				step("second", ""),
+			},
+			errorMsg: `step "first" references step "second", which does not run before it`,
+		},
+		{
+			name:     "reference to unknown step",
+			steps:    []*ast.WorkflowStep{step("notify", "steps.missing.output.ok")},
+			errorMsg: `references step "missing"`,
+		},
+		{
+			name:     "self reference",
+			steps:    []*ast.WorkflowStep{step("notify", "steps.notify.output.ok")},
+			errorMsg: "cannot reference its own output",
+		},
+		{
+			name: "reference between parallel steps",
+			steps: []*ast.WorkflowStep{
+				step("fetch", ""),
+				{Parallel: true, Steps: []*ast.WorkflowStep{
+					step("a", "", mapping("data", "steps.fetch.output.data")),
+					step("b", "", mapping("data", "steps.a.output.data")),
+				}},
+			},
+			errorMsg: `step "b" references step "a"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewWorkflowValidator()
+			err := v.ValidateWorkflows([]*ast.WorkflowDecl{{
+				Name:    "wf",
+				Trigger: &ast.Trigger{TrigType: ast.TriggerTypeManual},
+				Steps:   tt.steps,
+			}})
+			if tt.errorMsg == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errorMsg)
+		})
+	}
+}
@@ -0,0 +1,131 @@
+// Package filter selects and reshapes the events delivered to webhook
+// subscriptions.
+//
+// A subscription may declare a filter expression, which events must match
+// to be delivered, and a projection, which replaces the event payload with
+// the fields it names:
+//
+//	filter:     payload.amount > 1000 && payload.currency == "EUR"
+//	projection: {"amount": "payload.amount", "customer": "payload.customer.id"}
+//
+// Both use the expression language of internal/workflow/expr with the
+// variables payload, the event data, and event, with the event's id, type,
+// source, version and metadata.
+package filter
+
+import (
+	"fmt"
+	"path"
+	"sort"
+
+	"github.com/bargom/codeai/internal/event/bus"
+	"github.com/bargom/codeai/internal/workflow/expr"
+)
+
+// Variables of filter and projection expressions.
+const (
+	PayloadVar = "payload"
+	EventVar   = "event"
+)
+
+// Rule is the compiled filter and projection of a webhook subscription.
+type Rule struct {
+	filter     *expr.Program
+	projection []projectedField
+}
+
+type projectedField struct {
+	name    string
+	program *expr.Program
+}
+
+// Compile compiles a subscription's filter and projection. An empty filter
+// matches every event and an empty projection keeps the payload as it is.
+func Compile(filter string, projection map[string]string) (*Rule, error) {
+	r := &Rule{}
+	if filter != "" {
+		program, err := expr.CompileWithVars(filter, PayloadVar, EventVar)
+		if err != nil {
+			return nil, fmt.Errorf("invalid filter: %w", err)
+		}
+		r.filter = program
+	}
+
+	names := make([]string, 0, len(projection))
+	for name := range projection {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if name == "" {
+			return nil, fmt.Errorf("invalid projection: field name is empty")
+		}
+		program, err := expr.CompileWithVars(projection[name], PayloadVar, EventVar)
+		if err != nil {
+			return nil, fmt.Errorf("invalid projection of field '%s': %w", name, err)
+		}
+		r.projection = append(r.projection, projectedField{name: name, program: program})
+	}
+	return r, nil
+}
+
+// Apply reports whether event matches the rule's filter and returns it with
+// its payload projected. An error is returned if an expression cannot be
+// evaluated against the event, such as a comparison of a string field with
+// a number.
+func (r *Rule) Apply(event bus.Event) (bus.Event, bool, error) {
+	env := expr.Env{Vars: map[string]any{
+		PayloadVar: event.Data,
+		EventVar: map[string]any{
+			"id":       event.ID,
+			"type":     string(event.Type),
+			"source":   event.Source,
+			"version":  event.Version,
+			"metadata": event.Metadata,
+		},
+	}}
+
+	if r.filter != nil {
+		matched, err := r.filter.EvalBool(env)
+		if err != nil {
+			return event, false, fmt.Errorf("evaluating filter: %w", err)
+		}
+		if !matched {
+			return event, false, nil
+		}
+	}
+
+	if len(r.projection) > 0 {
+		data := make(map[string]interface{}, len(r.projection))
+		for _, f := range r.projection {
+			value, err := f.program.Eval(env)
+			if err != nil {
+				return event, false, fmt.Errorf("projecting field '%s': %w", f.name, err)
+			}
+			data[f.name] = value
+		}
+		event.Data = data
+	}
+	return event, true, nil
+}
+
+// ValidatePattern checks an event pattern of a subscription. Patterns are
+// event types in which * matches any characters, such as order.* or *.
+func ValidatePattern(pattern string) error {
+	if pattern == "" {
+		return fmt.Errorf("event pattern is empty")
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("invalid event pattern '%s'", pattern)
+	}
+	return nil
+}
+
+// MatchEvent reports whether an event of eventType matches pattern.
+func MatchEvent(pattern string, eventType bus.EventType) bool {
+	if pattern == string(eventType) {
+		return true
+	}
+	matched, err := path.Match(pattern, string(eventType))
+	return err == nil && matched
+}
@@ -0,0 +1,98 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bargom/codeai/internal/event/bus"
+)
+
+func orderEvent(amount float64, currency string) bus.Event {
+	return bus.Event{
+		ID:       "evt-1",
+		Type:     "order.created",
+		Source:   "codeai.dsl",
+		Data:     map[string]interface{}{"amount": amount, "currency": currency, "customer": map[string]interface{}{"id": "c-1"}},
+		Metadata: map[string]string{"tenant": "acme"},
+	}
+}
+
+func TestRule_Filter(t *testing.T) {
+	rule, err := Compile(`payload.amount > 1000 && payload.currency == "EUR"`, nil)
+	require.NoError(t, err)
+
+	event, matched, err := rule.Apply(orderEvent(1500, "EUR"))
+	require.NoError(t, err)
+	assert.True(t, matched)
+	assert.Equal(t, orderEvent(1500, "EUR"), event, "events are delivered as they are without a projection")
+
+	_, matched, err = rule.Apply(orderEvent(1500, "USD"))
+	require.NoError(t, err)
+	assert.False(t, matched)
+
+	rule, err = Compile(`event.type == "order.created" && event.metadata.tenant == "acme"`, nil)
+	require.NoError(t, err)
+	_, matched, err = rule.Apply(orderEvent(1, "EUR"))
+	require.NoError(t, err)
+	assert.True(t, matched, "filters can reference the event's attributes")
+
+	rule, err = Compile(`payload.currency`, nil)
+	require.NoError(t, err)
+	_, _, err = rule.Apply(orderEvent(1, "EUR"))
+	assert.ErrorContains(t, err, "must evaluate to a boolean")
+}
+
+func TestRule_Projection(t *testing.T) {
+	rule, err := Compile("", map[string]string{
+		"total":       "payload.amount",
+		"customer_id": "payload.customer.id",
+		"currency":    "lower(payload.currency)",
+	})
+	require.NoError(t, err)
+
+	event, matched, err := rule.Apply(orderEvent(1500, "EUR"))
+	require.NoError(t, err)
+	assert.True(t, matched)
+	assert.Equal(t, map[string]interface{}{"total": 1500.0, "customer_id": "c-1", "currency": "eur"}, event.Data)
+	assert.Equal(t, "evt-1", event.ID)
+}
+
+func TestCompile_Invalid(t *testing.T) {
+	_, err := Compile("payload.amount >", nil)
+	assert.ErrorContains(t, err, "invalid filter")
+
+	_, err = Compile("workflow.input.x == 1 && other.y", nil)
+	assert.ErrorContains(t, err, "invalid filter")
+
+	_, err = Compile("", map[string]string{"total": "payload.("})
+	assert.ErrorContains(t, err, "invalid projection of field 'total'")
+
+	_, err = Compile("", map[string]string{"": "payload.amount"})
+	assert.ErrorContains(t, err, "field name is empty")
+}
+
+func TestMatchEvent(t *testing.T) {
+	tests := []struct {
+		pattern   string
+		eventType bus.EventType
+		want      bool
+	}{
+		{"order.created", "order.created", true},
+		{"order.created", "order.updated", false},
+		{"order.*", "order.created", true},
+		{"order.*", "order.item.added", true},
+		{"order.*", "orders.created", false},
+		{"*", "user.created", true},
+		{"*.created", "user.created", true},
+		{"order.[", "order.[", true},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, MatchEvent(tt.pattern, tt.eventType), "%s ~ %s", tt.pattern, tt.eventType)
+	}
+
+	assert.NoError(t, ValidatePattern("order.*"))
+	assert.Error(t, ValidatePattern("order.["))
+	assert.Error(t, ValidatePattern(""))
+}
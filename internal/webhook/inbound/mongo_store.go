@@ -0,0 +1,70 @@
+package inbound
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const receiptsCollection = "webhook_receipts"
+
+// MongoStore implements Store on MongoDB.
+type MongoStore struct {
+	receipts *mongo.Collection
+}
+
+// NewMongoStore creates a store on the given MongoDB database.
+func NewMongoStore(db *mongo.Database) *MongoStore {
+	return &MongoStore{receipts: db.Collection(receiptsCollection)}
+}
+
+// EnsureIndexes creates the TTL index that purges expired receipts.
+func (s *MongoStore) EnsureIndexes(ctx context.Context) error {
+	_, err := s.receipts.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		return fmt.Errorf("creating webhook receipt indexes: %w", err)
+	}
+	return nil
+}
+
+// Claim records a delivery ID unless it is already recorded. The upsert
+// only matches an expired receipt, so inserting over a current one fails
+// on the unique _id.
+func (s *MongoStore) Claim(ctx context.Context, id string, now, until time.Time) (bool, error) {
+	_, err := s.receipts.UpdateOne(ctx,
+		bson.M{"_id": id, "expiresAt": bson.M{"$lte": now}},
+		bson.M{"$set": bson.M{"expiresAt": until}},
+		options.Update().SetUpsert(true),
+	)
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("claiming webhook receipt: %w", err)
+	}
+	return true, nil
+}
+
+// Release removes a claimed delivery ID.
+func (s *MongoStore) Release(ctx context.Context, id string) error {
+	if _, err := s.receipts.DeleteOne(ctx, bson.M{"_id": id}); err != nil {
+		return fmt.Errorf("releasing webhook receipt: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpired removes the delivery IDs that expired before the given
+// time. The TTL index does the same in the background.
+func (s *MongoStore) DeleteExpired(ctx context.Context, before time.Time) error {
+	if _, err := s.receipts.DeleteMany(ctx, bson.M{"expiresAt": bson.M{"$lt": before}}); err != nil {
+		return fmt.Errorf("deleting expired webhook receipts: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,369 @@
+// Package inbound receives webhooks from other services: it verifies their
+// signatures, drops deliveries it has already received and turns each
+// request into an event.
+package inbound
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bargom/codeai/internal/webhook/security"
+)
+
+// ErrInvalidPayload is returned by an EmitFunc when the payload does not
+// match the event's schema. The sender is told the request is unprocessable
+// rather than asked to retry it.
+var ErrInvalidPayload = errors.New("invalid webhook payload")
+
+// EmitFunc turns the JSON payload of a verified webhook into an event.
+type EmitFunc func(ctx context.Context, payload map[string]interface{}) error
+
+// Logger defines the logging interface for the receiver.
+type Logger interface {
+	Info(msg string, args ...any)
+	Error(msg string, args ...any)
+	Debug(msg string, args ...any)
+	Warn(msg string, args ...any)
+}
+
+// Config holds the configuration of a receiver.
+type Config struct {
+	Name             string        // Prefixes the receiver's delivery IDs in the store
+	Scheme           string        // security.SchemeHMAC or security.SchemeStandard
+	Secrets          []string      // Secrets a request may be signed with
+	SignatureHeader  string        // Header of the HMAC signature; defaults to X-Webhook-Signature
+	TimestampHeader  string        // Header of the signed HMAC timestamp, if not in the signature header
+	Tolerance        time.Duration // Maximum age of a signed timestamp
+	DeliveryIDHeader string        // Header of the delivery ID
+	DeliveryIDField  string        // Dot-separated payload field of the delivery ID, if not a header
+	DedupTTL         time.Duration // How long delivery IDs are remembered
+	MaxBodySize      int64         // Maximum request body size in bytes
+}
+
+// DefaultConfig returns a default receiver configuration.
+func DefaultConfig() Config {
+	return Config{
+		Scheme:      security.SchemeHMAC,
+		Tolerance:   security.DefaultTolerance,
+		DedupTTL:    24 * time.Hour,
+		MaxBodySize: 1 << 20,
+	}
+}
+
+// pruneInterval is how often a receiver deletes expired delivery IDs.
+const pruneInterval = 10 * time.Minute
+
+// Receiver is the http.Handler of a webhook endpoint.
+//
+// A request is accepted if it is signed with any of the secrets. With the
+// HMAC scheme the signature is the hex HMAC-SHA256 of the body, or, when
+// the request carries a timestamp, of "<timestamp>.<body>" as in Stripe's
+// "t=<timestamp>,v1=<signature>" header; the timestamp must then be within
+// the tolerance. Standard Webhooks requests are verified as specified.
+//
+// Deliveries are deduplicated by the delivery ID header or payload field,
+// the webhook-id of Standard Webhooks, or else the hash of the body.
+type Receiver struct {
+	config Config
+	emit   EmitFunc
+	store  Store
+	logger Logger
+	now    func() time.Time
+
+	mu       sync.Mutex
+	prunedAt time.Time
+}
+
+// Option configures the Receiver.
+type Option func(*Receiver)
+
+// WithStore sets the store delivery IDs are recorded in.
+func WithStore(store Store) Option {
+	return func(r *Receiver) {
+		r.store = store
+	}
+}
+
+// WithLogger sets the logger for the receiver.
+func WithLogger(logger Logger) Option {
+	return func(r *Receiver) {
+		r.logger = logger
+	}
+}
+
+// NewReceiver creates a receiver that emits verified webhooks with emit.
+// Zero values in config take their defaults. Without a store, delivery
+// IDs are kept in memory.
+func NewReceiver(config Config, emit EmitFunc, opts ...Option) (*Receiver, error) {
+	defaults := DefaultConfig()
+	if config.Scheme == "" {
+		config.Scheme = defaults.Scheme
+	}
+	if err := security.ValidateScheme(config.Scheme); err != nil {
+		return nil, err
+	}
+	if len(config.Secrets) == 0 {
+		return nil, fmt.Errorf("no secret to verify signatures with")
+	}
+	for _, secret := range config.Secrets {
+		if secret == "" {
+			return nil, fmt.Errorf("empty secret")
+		}
+	}
+	if emit == nil {
+		return nil, fmt.Errorf("no emit function")
+	}
+	if config.SignatureHeader == "" {
+		config.SignatureHeader = security.SignatureHeader
+	}
+	if config.Tolerance <= 0 {
+		config.Tolerance = defaults.Tolerance
+	}
+	if config.DedupTTL <= 0 {
+		config.DedupTTL = defaults.DedupTTL
+	}
+	if config.MaxBodySize <= 0 {
+		config.MaxBodySize = defaults.MaxBodySize
+	}
+
+	r := &Receiver{config: config, emit: emit, now: time.Now}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if r.store == nil {
+		r.store = NewMemoryStore()
+	}
+	return r, nil
+}
+
+// ServeHTTP verifies, deduplicates and emits a webhook request.
+func (r *Receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, req.Body, r.config.MaxBodySize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeStatus(w, http.StatusRequestEntityTooLarge, "payload too large")
+			return
+		}
+		writeStatus(w, http.StatusBadRequest, "cannot read body")
+		return
+	}
+
+	now := r.now()
+	if err := r.verify(req.Header, body, now); err != nil {
+		r.logDebug("rejected webhook", "receiver", r.config.Name, "error", err)
+		writeStatus(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal(body, &payload); err != nil || payload == nil {
+		writeStatus(w, http.StatusBadRequest, "payload must be a JSON object")
+		return
+	}
+
+	deliveryID, err := r.deliveryID(req.Header, body, payload)
+	if err != nil {
+		writeStatus(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	key := r.config.Name + ":" + deliveryID
+
+	claimed, err := r.store.Claim(ctx, key, now, now.Add(r.config.DedupTTL))
+	if err != nil {
+		r.logError("cannot record webhook delivery", "receiver", r.config.Name, "deliveryID", deliveryID, "error", err)
+		writeStatus(w, http.StatusInternalServerError, "cannot record delivery")
+		return
+	}
+	if !claimed {
+		r.logDebug("dropped duplicate webhook", "receiver", r.config.Name, "deliveryID", deliveryID)
+		writeStatus(w, http.StatusOK, "duplicate")
+		return
+	}
+	r.prune(ctx, now)
+
+	if err := r.emit(ctx, payload); err != nil {
+		// Let the sender's retry through
+		if releaseErr := r.store.Release(ctx, key); releaseErr != nil {
+			r.logError("cannot release webhook delivery", "receiver", r.config.Name, "deliveryID", deliveryID, "error", releaseErr)
+		}
+		if errors.Is(err, ErrInvalidPayload) {
+			writeStatus(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		r.logError("cannot emit webhook event", "receiver", r.config.Name, "deliveryID", deliveryID, "error", err)
+		writeStatus(w, http.StatusInternalServerError, "cannot emit event")
+		return
+	}
+
+	r.logDebug("received webhook", "receiver", r.config.Name, "deliveryID", deliveryID)
+	writeStatus(w, http.StatusOK, "received")
+}
+
+// verify checks the signature of a request.
+func (r *Receiver) verify(header http.Header, body []byte, now time.Time) error {
+	if r.config.Scheme == security.SchemeStandard {
+		return security.VerifyStandard(r.config.Secrets, header, body, now, r.config.Tolerance)
+	}
+
+	value := header.Get(r.config.SignatureHeader)
+	if value == "" {
+		return security.ErrMissingSignature
+	}
+	ts, signatures := parseSignatureHeader(value)
+	if r.config.TimestampHeader != "" {
+		ts = header.Get(r.config.TimestampHeader)
+		if ts == "" {
+			return security.ErrInvalidTimestamp
+		}
+	}
+
+	if ts == "" {
+		for _, secret := range r.config.Secrets {
+			for _, signature := range signatures {
+				if security.VerifySignature(secret, body, signature) {
+					return nil
+				}
+			}
+		}
+		return security.ErrSignatureMismatch
+	}
+
+	timestamp, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return security.ErrInvalidTimestamp
+	}
+	if diff := now.Sub(time.Unix(timestamp, 0)); diff > r.config.Tolerance || diff < -r.config.Tolerance {
+		return security.ErrInvalidTimestamp
+	}
+	for _, secret := range r.config.Secrets {
+		for _, signature := range signatures {
+			if security.VerifySignatureWithTimestamp(secret, timestamp, body, signature) {
+				return nil
+			}
+		}
+	}
+	return security.ErrSignatureMismatch
+}
+
+// parseSignatureHeader splits a signature header into its timestamp and
+// signatures. It accepts a bare signature, a prefixed one such as
+// "sha256=<signature>", and comma-separated lists such as Stripe's
+// "t=<timestamp>,v1=<signature>,v1=<signature>".
+func parseSignatureHeader(value string) (string, []string) {
+	var (
+		timestamp  string
+		signatures []string
+	)
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		key, val, ok := strings.Cut(part, "=")
+		switch {
+		case !ok:
+			signatures = append(signatures, part)
+		case key == "t":
+			timestamp = val
+		default:
+			signatures = append(signatures, val)
+		}
+	}
+	return timestamp, signatures
+}
+
+// deliveryID returns the ID a request is deduplicated by.
+func (r *Receiver) deliveryID(header http.Header, body []byte, payload map[string]interface{}) (string, error) {
+	switch {
+	case r.config.DeliveryIDHeader != "":
+		if id := header.Get(r.config.DeliveryIDHeader); id != "" {
+			return id, nil
+		}
+		return "", fmt.Errorf("missing delivery ID header %s", r.config.DeliveryIDHeader)
+	case r.config.DeliveryIDField != "":
+		if id, ok := lookupField(payload, r.config.DeliveryIDField); ok {
+			return id, nil
+		}
+		return "", fmt.Errorf("missing delivery ID field %s", r.config.DeliveryIDField)
+	case r.config.Scheme == security.SchemeStandard:
+		return header.Get(security.StandardIDHeader), nil
+	default:
+		sum := sha256.Sum256(body)
+		return hex.EncodeToString(sum[:]), nil
+	}
+}
+
+// lookupField returns the value of a dot-separated field of a payload.
+func lookupField(payload map[string]interface{}, path string) (string, bool) {
+	var value interface{} = payload
+	for _, name := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return "", false
+		}
+		if value, ok = object[name]; !ok {
+			return "", false
+		}
+	}
+	switch v := value.(type) {
+	case nil, map[string]interface{}, []interface{}:
+		return "", false
+	case string:
+		return v, v != ""
+	default:
+		return fmt.Sprint(v), true
+	}
+}
+
+// prune deletes expired delivery IDs, at most once every pruneInterval.
+func (r *Receiver) prune(ctx context.Context, now time.Time) {
+	r.mu.Lock()
+	if now.Sub(r.prunedAt) < pruneInterval {
+		r.mu.Unlock()
+		return
+	}
+	r.prunedAt = now
+	r.mu.Unlock()
+
+	if err := r.store.DeleteExpired(ctx, now); err != nil {
+		r.logWarn("cannot delete expired webhook deliveries", "receiver", r.config.Name, "error", err)
+	}
+}
+
+func writeStatus(w http.ResponseWriter, status int, message string) {
+	key := "status"
+	if status >= http.StatusBadRequest {
+		key = "error"
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{key: message})
+}
+
+func (r *Receiver) logDebug(msg string, args ...any) {
+	if r.logger != nil {
+		r.logger.Debug(msg, args...)
+	}
+}
+
+func (r *Receiver) logWarn(msg string, args ...any) {
+	if r.logger != nil {
+		r.logger.Warn(msg, args...)
+	}
+}
+
+func (r *Receiver) logError(msg string, args ...any) {
+	if r.logger != nil {
+		r.logger.Error(msg, args...)
+	}
+}
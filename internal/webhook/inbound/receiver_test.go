@@ -0,0 +1,233 @@
+package inbound
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bargom/codeai/internal/webhook/security"
+)
+
+type recorder struct {
+	payloads []map[string]interface{}
+	err      error
+}
+
+func (r *recorder) emit(ctx context.Context, payload map[string]interface{}) error {
+	if r.err != nil {
+		return r.err
+	}
+	r.payloads = append(r.payloads, payload)
+	return nil
+}
+
+func newTestReceiver(t *testing.T, config Config, rec *recorder, now time.Time) *Receiver {
+	t.Helper()
+	config.Name = "test"
+	receiver, err := NewReceiver(config, rec.emit)
+	require.NoError(t, err)
+	receiver.now = func() time.Time { return now }
+	return receiver
+}
+
+func post(receiver *Receiver, body string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/hooks", strings.NewReader(body))
+	for name, value := range header {
+		req.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	receiver.ServeHTTP(w, req)
+	return w
+}
+
+func stripeHeader(secret string, timestamp int64, body string) string {
+	return fmt.Sprintf("t=%d,v1=%s", timestamp, security.SignPayloadWithTimestamp(secret, timestamp, []byte(body)))
+}
+
+func TestReceiver_Stripe(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	rec := &recorder{}
+	receiver := newTestReceiver(t, Config{
+		SignatureHeader: "Stripe-Signature",
+		Secrets:         []string{"whsec_test"},
+		DeliveryIDField: "id",
+	}, rec, now)
+
+	body := `{"id":"evt_1","type":"payment_intent.succeeded","data":{"amount":100}}`
+	w := post(receiver, body, map[string]string{"Stripe-Signature": stripeHeader("whsec_test", now.Unix(), body)})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"status":"received"}`, w.Body.String())
+	require.Len(t, rec.payloads, 1)
+	assert.Equal(t, "evt_1", rec.payloads[0]["id"])
+
+	// Stripe retries with a new timestamp and signature
+	retry := stripeHeader("whsec_test", now.Unix()-60, body)
+	w = post(receiver, body, map[string]string{"Stripe-Signature": retry})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status":"duplicate"}`, w.Body.String())
+	assert.Len(t, rec.payloads, 1, "a duplicate delivery is not emitted")
+
+	tests := []struct {
+		name   string
+		header string
+		body   string
+		status int
+	}{
+		{"missing signature", "", body, http.StatusUnauthorized},
+		{"wrong secret", stripeHeader("other", now.Unix(), body), body, http.StatusUnauthorized},
+		{"tampered body", stripeHeader("whsec_test", now.Unix(), body), `{"id":"evt_2"}`, http.StatusUnauthorized},
+		{"old timestamp", stripeHeader("whsec_test", now.Add(-10*time.Minute).Unix(), body), body, http.StatusUnauthorized},
+		{"future timestamp", stripeHeader("whsec_test", now.Add(10*time.Minute).Unix(), body), body, http.StatusUnauthorized},
+		{"not an object", stripeHeader("whsec_test", now.Unix(), `[1]`), `[1]`, http.StatusBadRequest},
+		{"missing delivery ID", stripeHeader("whsec_test", now.Unix(), `{"type":"x"}`), `{"type":"x"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := post(receiver, tt.body, map[string]string{"Stripe-Signature": tt.header})
+			assert.Equal(t, tt.status, w.Code, w.Body.String())
+		})
+	}
+	assert.Len(t, rec.payloads, 1)
+}
+
+func TestReceiver_GitHub(t *testing.T) {
+	rec := &recorder{}
+	receiver := newTestReceiver(t, Config{
+		SignatureHeader:  "X-Hub-Signature-256",
+		Secrets:          []string{"new", "old"},
+		DeliveryIDHeader: "X-GitHub-Delivery",
+	}, rec, time.Now())
+
+	body := `{"action":"opened"}`
+	header := map[string]string{
+		"X-Hub-Signature-256": "sha256=" + security.SignPayload("old", []byte(body)),
+		"X-GitHub-Delivery":   "d-1",
+	}
+	w := post(receiver, body, header)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Len(t, rec.payloads, 1, "any secret may sign the request")
+
+	header["X-GitHub-Delivery"] = "d-2"
+	w = post(receiver, body, header)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, rec.payloads, 2, "deliveries are deduplicated by their header")
+
+	delete(header, "X-GitHub-Delivery")
+	w = post(receiver, body, header)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestReceiver_TimestampHeader(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	rec := &recorder{}
+	receiver := newTestReceiver(t, Config{
+		Secrets:         []string{"s3cret"},
+		TimestampHeader: security.TimestampHeader,
+		Tolerance:       time.Minute,
+	}, rec, now)
+
+	body := `{"n":1}`
+	signed := func(ts int64) map[string]string {
+		return map[string]string{
+			security.SignatureHeader: security.SignPayloadWithTimestamp("s3cret", ts, []byte(body)),
+			security.TimestampHeader: fmt.Sprint(ts),
+		}
+	}
+	assert.Equal(t, http.StatusOK, post(receiver, body, signed(now.Unix()-30)).Code)
+	assert.Equal(t, http.StatusUnauthorized, post(receiver, body, signed(now.Unix()-90)).Code)
+
+	header := signed(now.Unix())
+	delete(header, security.TimestampHeader)
+	assert.Equal(t, http.StatusUnauthorized, post(receiver, body, header).Code)
+
+	// Without a delivery ID the payload's hash identifies the delivery
+	assert.JSONEq(t, `{"status":"duplicate"}`, post(receiver, body, signed(now.Unix())).Body.String())
+}
+
+func TestReceiver_StandardWebhooks(t *testing.T) {
+	// The example of the Standard Webhooks specification
+	now := time.Unix(1614265330, 0)
+	rec := &recorder{}
+	receiver := newTestReceiver(t, Config{
+		Scheme:  security.SchemeStandard,
+		Secrets: []string{"whsec_MfKQ9r8GKYqrTwjUPD8ILPZIo2LaLaSw"},
+	}, rec, now)
+
+	body := `{"test": 2432232314}`
+	header := map[string]string{
+		security.StandardIDHeader:        "msg_p5jXN8AQM9LWM0D4loKWxJek",
+		security.StandardTimestampHeader: "1614265330",
+		security.StandardSignatureHeader: "v1,g0hM9SsE+OTPJTGt/tmIKtSyZlE3uFJELVlNIOLJ1OE=",
+	}
+	assert.Equal(t, http.StatusOK, post(receiver, body, header).Code)
+	assert.JSONEq(t, `{"status":"duplicate"}`, post(receiver, body, header).Body.String())
+	require.Len(t, rec.payloads, 1)
+
+	header[security.StandardSignatureHeader] = "v1,bm90IHRoaXMgb25l"
+	assert.Equal(t, http.StatusUnauthorized, post(receiver, body, header).Code)
+}
+
+func TestReceiver_EmitFailure(t *testing.T) {
+	rec := &recorder{}
+	receiver := newTestReceiver(t, Config{Secrets: []string{"s"}}, rec, time.Now())
+	body := `{"id":"1"}`
+	header := map[string]string{security.SignatureHeader: security.SignPayload("s", []byte(body))}
+
+	rec.err = fmt.Errorf("%w: field 'amount' is required", ErrInvalidPayload)
+	w := post(receiver, body, header)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	var response map[string]string
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Contains(t, response["error"], "field 'amount' is required")
+
+	rec.err = errors.New("bus closed")
+	assert.Equal(t, http.StatusInternalServerError, post(receiver, body, header).Code)
+
+	// A delivery that failed is accepted when the sender retries it
+	rec.err = nil
+	assert.JSONEq(t, `{"status":"received"}`, post(receiver, body, header).Body.String())
+	assert.Len(t, rec.payloads, 1)
+}
+
+func TestReceiver_BodyLimit(t *testing.T) {
+	receiver := newTestReceiver(t, Config{Secrets: []string{"s"}, MaxBodySize: 8}, &recorder{}, time.Now())
+	assert.Equal(t, http.StatusRequestEntityTooLarge, post(receiver, `{"long":"payload"}`, nil).Code)
+}
+
+func TestNewReceiver_Invalid(t *testing.T) {
+	emit := (&recorder{}).emit
+
+	_, err := NewReceiver(Config{}, emit)
+	assert.ErrorContains(t, err, "no secret")
+
+	_, err = NewReceiver(Config{Secrets: []string{""}}, emit)
+	assert.ErrorContains(t, err, "empty secret")
+
+	_, err = NewReceiver(Config{Scheme: "rsa", Secrets: []string{"s"}}, emit)
+	assert.ErrorContains(t, err, "unknown signature scheme")
+
+	_, err = NewReceiver(Config{Secrets: []string{"s"}}, nil)
+	assert.ErrorContains(t, err, "no emit function")
+}
+
+func TestParseSignatureHeader(t *testing.T) {
+	ts, signatures := parseSignatureHeader("t=123,v1=abc,v1=def")
+	assert.Equal(t, "123", ts)
+	assert.Equal(t, []string{"abc", "def"}, signatures)
+
+	ts, signatures = parseSignatureHeader("sha256=abc")
+	assert.Empty(t, ts)
+	assert.Equal(t, []string{"abc"}, signatures)
+
+	_, signatures = parseSignatureHeader("abc")
+	assert.Equal(t, []string{"abc"}, signatures)
+}
@@ -0,0 +1,66 @@
+package inbound
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// SQLStore implements Store on PostgreSQL.
+type SQLStore struct {
+	db *sql.DB
+}
+
+// NewSQLStore creates a store on the given PostgreSQL database.
+func NewSQLStore(db *sql.DB) *SQLStore {
+	return &SQLStore{db: db}
+}
+
+// CreateTables creates the webhook_receipts table if it doesn't exist.
+func (s *SQLStore) CreateTables(ctx context.Context) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS webhook_receipts (
+			id TEXT PRIMARY KEY,
+			expires_at TIMESTAMP NOT NULL
+		)`
+	if _, err := s.db.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("creating webhook receipts table: %w", err)
+	}
+	return nil
+}
+
+// Claim records a delivery ID unless it is already recorded. An expired
+// receipt is claimed again.
+func (s *SQLStore) Claim(ctx context.Context, id string, now, until time.Time) (bool, error) {
+	query := `
+		INSERT INTO webhook_receipts (id, expires_at) VALUES ($1, $2)
+		ON CONFLICT (id) DO UPDATE SET expires_at = EXCLUDED.expires_at
+		WHERE webhook_receipts.expires_at <= $3
+	`
+	result, err := s.db.ExecContext(ctx, query, id, until.UTC(), now.UTC())
+	if err != nil {
+		return false, fmt.Errorf("claiming webhook receipt: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("claiming webhook receipt: %w", err)
+	}
+	return n > 0, nil
+}
+
+// Release removes a claimed delivery ID.
+func (s *SQLStore) Release(ctx context.Context, id string) error {
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM webhook_receipts WHERE id = $1", id); err != nil {
+		return fmt.Errorf("releasing webhook receipt: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpired removes the delivery IDs that expired before the given time.
+func (s *SQLStore) DeleteExpired(ctx context.Context, before time.Time) error {
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM webhook_receipts WHERE expires_at < $1", before.UTC()); err != nil {
+		return fmt.Errorf("deleting expired webhook receipts: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package inbound
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Store records the delivery IDs of received webhooks, so that a webhook
+// the sender delivers again is only turned into an event once.
+type Store interface {
+	// Claim records a delivery ID until the given time. It reports false if
+	// the ID is already recorded and has not expired at now. Checking and
+	// recording is atomic, so concurrent deliveries of a webhook are claimed
+	// once.
+	Claim(ctx context.Context, id string, now, until time.Time) (bool, error)
+
+	// Release removes a claimed delivery ID, so that a webhook whose event
+	// could not be emitted is accepted when the sender retries it.
+	Release(ctx context.Context, id string) error
+
+	// DeleteExpired removes the delivery IDs that expired before the given
+	// time.
+	DeleteExpired(ctx context.Context, before time.Time) error
+}
+
+// MemoryStore is an in-memory implementation of Store.
+type MemoryStore struct {
+	receipts map[string]time.Time
+	mu       sync.Mutex
+}
+
+// NewMemoryStore creates a new in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{receipts: make(map[string]time.Time)}
+}
+
+// Claim records a delivery ID unless it is already recorded.
+func (s *MemoryStore) Claim(ctx context.Context, id string, now, until time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if expiresAt, ok := s.receipts[id]; ok && expiresAt.After(now) {
+		return false, nil
+	}
+	s.receipts[id] = until
+	return true, nil
+}
+
+// Release removes a claimed delivery ID.
+func (s *MemoryStore) Release(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.receipts, id)
+	return nil
+}
+
+// DeleteExpired removes the delivery IDs that expired before the given time.
+func (s *MemoryStore) DeleteExpired(ctx context.Context, before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, expiresAt := range s.receipts {
+		if expiresAt.Before(before) {
+			delete(s.receipts, id)
+		}
+	}
+	return nil
+}
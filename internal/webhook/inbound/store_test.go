@@ -0,0 +1,68 @@
+package inbound
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+func newSQLStore(t *testing.T) *SQLStore {
+	t.Helper()
+	db, err := sql.Open("sqlite", ":memory:")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	store := NewSQLStore(db)
+	require.NoError(t, store.CreateTables(context.Background()))
+	return store
+}
+
+func TestStores(t *testing.T) {
+	stores := map[string]func(t *testing.T) Store{
+		"memory": func(t *testing.T) Store { return NewMemoryStore() },
+		"sql":    func(t *testing.T) Store { return newSQLStore(t) },
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			store := newStore(t)
+			ctx := context.Background()
+			now := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+
+			claimed, err := store.Claim(ctx, "stripe:evt_1", now, now.Add(time.Hour))
+			require.NoError(t, err)
+			assert.True(t, claimed)
+
+			claimed, err = store.Claim(ctx, "stripe:evt_1", now.Add(time.Minute), now.Add(time.Hour))
+			require.NoError(t, err)
+			assert.False(t, claimed, "a recorded delivery is not claimed twice")
+
+			claimed, err = store.Claim(ctx, "github:evt_1", now, now.Add(time.Hour))
+			require.NoError(t, err)
+			assert.True(t, claimed, "delivery IDs are distinct per receiver")
+
+			claimed, err = store.Claim(ctx, "stripe:evt_1", now.Add(2*time.Hour), now.Add(3*time.Hour))
+			require.NoError(t, err)
+			assert.True(t, claimed, "an expired delivery is claimed again")
+
+			require.NoError(t, store.Release(ctx, "stripe:evt_1"))
+			claimed, err = store.Claim(ctx, "stripe:evt_1", now.Add(2*time.Hour), now.Add(3*time.Hour))
+			require.NoError(t, err)
+			assert.True(t, claimed, "a released delivery is claimed again")
+
+			require.NoError(t, store.DeleteExpired(ctx, now.Add(2*time.Hour)))
+			claimed, err = store.Claim(ctx, "github:evt_1", now, now.Add(time.Hour))
+			require.NoError(t, err)
+			assert.True(t, claimed, "expired deliveries are deleted")
+			claimed, err = store.Claim(ctx, "stripe:evt_1", now.Add(2*time.Hour), now.Add(3*time.Hour))
+			require.NoError(t, err)
+			assert.False(t, claimed, "current deliveries are kept")
+		})
+	}
+}
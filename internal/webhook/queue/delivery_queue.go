@@ -6,6 +6,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/bargom/codeai/internal/ratelimit"
 	"github.com/bargom/codeai/internal/webhook/repository"
 	"github.com/bargom/codeai/pkg/integration/webhook"
 )
@@ -20,10 +21,15 @@ type Logger interface {
 
 // Config holds configuration for the delivery queue.
 type Config struct {
-	QueueSize     int
-	WorkerCount   int
-	BatchSize     int
-	DrainTimeout  time.Duration
+	QueueSize    int
+	WorkerCount  int
+	BatchSize    int
+	DrainTimeout time.Duration
+
+	// EndpointConcurrency limits the deliveries in flight to each webhook;
+	// 0 means no limit. Deliveries over the limit wait without holding a
+	// worker and count towards QueueSize.
+	EndpointConcurrency int
 }
 
 // DefaultConfig returns a default queue configuration.
@@ -43,6 +49,18 @@ type DeliveryItem struct {
 	EventID   string
 }
 
+// HealthTracker keeps track of the failures of webhooks, such as the
+// webhook service, which disables webhooks that keep failing.
+type HealthTracker interface {
+	RecordDelivery(ctx context.Context, webhookID string, success bool)
+}
+
+// endpoint tracks the deliveries to a webhook under the concurrency limit.
+type endpoint struct {
+	inFlight int
+	waiting  []DeliveryItem
+}
+
 // DeliveryQueue manages asynchronous webhook delivery with a worker pool.
 type DeliveryQueue struct {
 	queue      chan DeliveryItem
@@ -55,6 +73,15 @@ type DeliveryQueue struct {
 	cancel     context.CancelFunc
 	stopped    bool
 	mu         sync.RWMutex
+
+	size         int
+	drainTimeout time.Duration
+	concurrency  int
+	limiter      *ratelimit.Limiter
+	health       HealthTracker
+	endpoints    map[string]*endpoint
+	waiting      int
+	endpointsMu  sync.Mutex
 }
 
 // NewDeliveryQueue creates a new delivery queue with the specified configuration.
@@ -70,12 +97,20 @@ func NewDeliveryQueue(
 	if cfg.WorkerCount <= 0 {
 		cfg.WorkerCount = 10
 	}
+	if cfg.DrainTimeout <= 0 {
+		cfg.DrainTimeout = 30 * time.Second
+	}
 
 	q := &DeliveryQueue{
 		queue:      make(chan DeliveryItem, cfg.QueueSize),
 		workers:    cfg.WorkerCount,
 		client:     client,
 		repository: repo,
+
+		size:         cfg.QueueSize,
+		drainTimeout: cfg.DrainTimeout,
+		concurrency:  cfg.EndpointConcurrency,
+		endpoints:    make(map[string]*endpoint),
 	}
 
 	for _, opt := range opts {
@@ -95,6 +130,23 @@ func WithLogger(logger Logger) Option {
 	}
 }
 
+// WithRateLimiter limits the rate of deliveries to each webhook, keyed by
+// its ID. Waiting for the limiter holds a worker. A limiter on a shared
+// store applies the limit across instances.
+func WithRateLimiter(limiter *ratelimit.Limiter) Option {
+	return func(q *DeliveryQueue) {
+		q.limiter = limiter
+	}
+}
+
+// WithHealthTracker sets the tracker the outcome of each delivery is
+// reported to, in place of updating the webhook's failure count directly.
+func WithHealthTracker(health HealthTracker) Option {
+	return func(q *DeliveryQueue) {
+		q.health = health
+	}
+}
+
 // Start begins processing webhooks from the queue.
 func (q *DeliveryQueue) Start(ctx context.Context) {
 	q.mu.Lock()
@@ -117,7 +169,8 @@ func (q *DeliveryQueue) Start(ctx context.Context) {
 	}
 }
 
-// Stop gracefully shuts down the queue, waiting for pending deliveries.
+// Stop gracefully shuts down the queue, waiting for pending deliveries for
+// up to the drain timeout.
 func (q *DeliveryQueue) Stop() {
 	q.mu.Lock()
 	if q.stopped {
@@ -127,15 +180,27 @@ func (q *DeliveryQueue) Stop() {
 	q.stopped = true
 	q.mu.Unlock()
 
+	// Close the queue to signal workers to stop once it is drained
+	close(q.queue)
+
+	// Wait for workers to finish, and abort the deliveries in flight after
+	// the drain timeout
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(q.drainTimeout):
+		if q.logger != nil {
+			q.logger.Warn("delivery queue drain timed out", "pending", q.Pending())
+		}
+	}
 	if q.cancel != nil {
 		q.cancel()
 	}
-
-	// Close the queue to signal workers to stop
-	close(q.queue)
-
-	// Wait for workers to finish
-	q.wg.Wait()
+	<-done
 
 	if q.logger != nil {
 		q.logger.Info("delivery queue stopped")
@@ -143,7 +208,8 @@ func (q *DeliveryQueue) Stop() {
 }
 
 // Enqueue adds a webhook to the delivery queue.
-// Returns false if the queue is full or stopped.
+// Returns false if the queue is full or stopped. Deliveries waiting for the
+// concurrency limit of their webhook count towards the queue size.
 func (q *DeliveryQueue) Enqueue(item DeliveryItem) bool {
 	q.mu.RLock()
 	stopped := q.stopped
@@ -158,6 +224,16 @@ func (q *DeliveryQueue) Enqueue(item DeliveryItem) bool {
 		return false
 	}
 
+	if q.Pending() >= q.size {
+		if q.logger != nil {
+			q.logger.Warn("queue full, dropping webhook",
+				"webhookID", item.WebhookID,
+				"eventID", item.EventID,
+			)
+		}
+		return false
+	}
+
 	select {
 	case q.queue <- item:
 		if q.logger != nil {
@@ -180,7 +256,9 @@ func (q *DeliveryQueue) Enqueue(item DeliveryItem) bool {
 
 // Pending returns the number of items waiting in the queue.
 func (q *DeliveryQueue) Pending() int {
-	return len(q.queue)
+	q.endpointsMu.Lock()
+	defer q.endpointsMu.Unlock()
+	return len(q.queue) + q.waiting
 }
 
 // worker processes webhooks from the queue.
@@ -192,7 +270,7 @@ func (q *DeliveryQueue) worker(id int) {
 	}
 
 	for item := range q.queue {
-		q.processItem(item)
+		q.deliver(item)
 	}
 
 	if q.logger != nil {
@@ -200,6 +278,113 @@ func (q *DeliveryQueue) worker(id int) {
 	}
 }
 
+// deliver processes item unless its webhook has reached its concurrency
+// limit, in which case the item waits. Once done, the worker goes on with
+// the deliveries that waited for the webhook.
+func (q *DeliveryQueue) deliver(item DeliveryItem) {
+	if !q.acquire(item) {
+		return
+	}
+	for {
+		q.throttle(item.WebhookID)
+		q.processItem(item)
+
+		next, ok := q.release(item.WebhookID)
+		if !ok {
+			return
+		}
+		item = next
+	}
+}
+
+// acquire reserves a delivery slot of item's webhook, or makes the item wait
+// for one and reports false.
+func (q *DeliveryQueue) acquire(item DeliveryItem) bool {
+	if q.concurrency <= 0 {
+		return true
+	}
+
+	q.endpointsMu.Lock()
+	defer q.endpointsMu.Unlock()
+
+	ep, ok := q.endpoints[item.WebhookID]
+	if !ok {
+		ep = &endpoint{}
+		q.endpoints[item.WebhookID] = ep
+	}
+	if ep.inFlight >= q.concurrency {
+		ep.waiting = append(ep.waiting, item)
+		q.waiting++
+		if q.logger != nil {
+			q.logger.Debug("webhook waiting for concurrency limit",
+				"webhookID", item.WebhookID,
+				"eventID", item.EventID,
+				"waiting", len(ep.waiting),
+			)
+		}
+		return false
+	}
+	ep.inFlight++
+	return true
+}
+
+// release hands the delivery slot of a webhook to its next waiting item, or
+// frees it if there is none.
+func (q *DeliveryQueue) release(webhookID string) (DeliveryItem, bool) {
+	if q.concurrency <= 0 {
+		return DeliveryItem{}, false
+	}
+
+	q.endpointsMu.Lock()
+	defer q.endpointsMu.Unlock()
+
+	ep := q.endpoints[webhookID]
+	if len(ep.waiting) > 0 {
+		next := ep.waiting[0]
+		ep.waiting = ep.waiting[1:]
+		q.waiting--
+		return next, true
+	}
+	ep.inFlight--
+	if ep.inFlight == 0 {
+		delete(q.endpoints, webhookID)
+	}
+	return DeliveryItem{}, false
+}
+
+// throttle waits until the rate limiter allows a delivery to a webhook. If
+// the limiter fails, the delivery is sent rather than held back.
+func (q *DeliveryQueue) throttle(webhookID string) {
+	if q.limiter == nil {
+		return
+	}
+	ctx := q.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	for {
+		result, err := q.limiter.Allow(ctx, webhookID)
+		if err != nil {
+			if q.logger != nil {
+				q.logger.Warn("rate limiter failed", "webhookID", webhookID, "error", err.Error())
+			}
+			return
+		}
+		if result.Allowed {
+			return
+		}
+
+		timer := time.NewTimer(result.RetryAfter)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
 // processItem handles a single webhook delivery.
 func (q *DeliveryQueue) processItem(item DeliveryItem) {
 	ctx := q.ctx
@@ -241,16 +426,8 @@ func (q *DeliveryQueue) processItem(item DeliveryItem) {
 	}
 
 	// Handle success/failure
+	q.recordOutcome(ctx, item.WebhookID, delivery.Success)
 	if delivery.Success {
-		if err := q.repository.ResetFailureCount(ctx, item.WebhookID); err != nil {
-			if q.logger != nil {
-				q.logger.Error("failed to reset failure count",
-					"webhookID", item.WebhookID,
-					"error", err.Error(),
-				)
-			}
-		}
-
 		if q.logger != nil {
 			q.logger.Info("webhook delivered",
 				"webhookID", item.WebhookID,
@@ -260,15 +437,6 @@ func (q *DeliveryQueue) processItem(item DeliveryItem) {
 			)
 		}
 	} else {
-		if err := q.repository.IncrementFailureCount(ctx, item.WebhookID); err != nil {
-			if q.logger != nil {
-				q.logger.Error("failed to increment failure count",
-					"webhookID", item.WebhookID,
-					"error", err.Error(),
-				)
-			}
-		}
-
 		// Schedule retry
 		retryPolicy := webhook.DefaultRetryPolicy()
 		if delivery.Attempts < retryPolicy.MaxAttempts {
@@ -293,3 +461,33 @@ func (q *DeliveryQueue) processItem(item DeliveryItem) {
 		}
 	}
 }
+
+// recordOutcome reports the outcome of a delivery to the health tracker, or
+// updates the webhook's failure count if there is none.
+func (q *DeliveryQueue) recordOutcome(ctx context.Context, webhookID string, success bool) {
+	if q.health != nil {
+		q.health.RecordDelivery(ctx, webhookID, success)
+		return
+	}
+
+	if success {
+		if err := q.repository.ResetFailureCount(ctx, webhookID); err != nil {
+			if q.logger != nil {
+				q.logger.Error("failed to reset failure count",
+					"webhookID", webhookID,
+					"error", err.Error(),
+				)
+			}
+		}
+		return
+	}
+
+	if err := q.repository.IncrementFailureCount(ctx, webhookID); err != nil {
+		if q.logger != nil {
+			q.logger.Error("failed to increment failure count",
+				"webhookID", webhookID,
+				"error", err.Error(),
+			)
+		}
+	}
+}
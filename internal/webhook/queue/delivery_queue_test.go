@@ -0,0 +1,180 @@
+package queue
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bargom/codeai/internal/cache"
+	"github.com/bargom/codeai/internal/ratelimit"
+	"github.com/bargom/codeai/internal/webhook/repository"
+	"github.com/bargom/codeai/pkg/integration/webhook"
+)
+
+// endpointServer records the deliveries in flight to each path.
+type endpointServer struct {
+	mu          sync.Mutex
+	inFlight    map[string]int
+	maxInFlight map[string]int
+	delivered   map[string]int
+	delay       time.Duration
+}
+
+func newEndpointServer(t *testing.T, delay time.Duration) (*endpointServer, string) {
+	t.Helper()
+	s := &endpointServer{
+		inFlight:    make(map[string]int),
+		maxInFlight: make(map[string]int),
+		delivered:   make(map[string]int),
+		delay:       delay,
+	}
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+	return s, srv.URL
+}
+
+func (s *endpointServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.inFlight[r.URL.Path]++
+	s.maxInFlight[r.URL.Path] = max(s.maxInFlight[r.URL.Path], s.inFlight[r.URL.Path])
+	s.mu.Unlock()
+
+	time.Sleep(s.delay)
+
+	s.mu.Lock()
+	s.inFlight[r.URL.Path]--
+	s.delivered[r.URL.Path]++
+	s.mu.Unlock()
+
+	if r.URL.Path == "/fail" {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func newItem(url, webhookID string) DeliveryItem {
+	return DeliveryItem{
+		Webhook: &webhook.Webhook{
+			ID:          uuid.New().String(),
+			URL:         url,
+			EventID:     "evt-1",
+			Payload:     []byte(`{}`),
+			Timeout:     time.Second,
+			RetryPolicy: &webhook.RetryPolicy{MaxAttempts: 1},
+		},
+		WebhookID: webhookID,
+		EventID:   "evt-1",
+	}
+}
+
+type healthRecorder struct {
+	mu       sync.Mutex
+	outcomes map[string][]bool
+}
+
+func (h *healthRecorder) RecordDelivery(ctx context.Context, webhookID string, success bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.outcomes[webhookID] = append(h.outcomes[webhookID], success)
+}
+
+func TestDeliveryQueue_EndpointConcurrency(t *testing.T) {
+	server, url := newEndpointServer(t, 20*time.Millisecond)
+	repo := repository.NewMemoryRepository()
+	q := NewDeliveryQueue(webhook.NewClient(webhook.DefaultConfig()), repo, Config{
+		QueueSize:           100,
+		WorkerCount:         4,
+		EndpointConcurrency: 2,
+	})
+	q.Start(context.Background())
+
+	for i := 0; i < 6; i++ {
+		require.True(t, q.Enqueue(newItem(url+"/a", "wh-a")))
+		require.True(t, q.Enqueue(newItem(url+"/b", "wh-b")))
+	}
+	q.Stop()
+
+	assert.Equal(t, 6, server.delivered["/a"], "waiting deliveries are sent before the queue stops")
+	assert.Equal(t, 6, server.delivered["/b"])
+	assert.LessOrEqual(t, server.maxInFlight["/a"], 2)
+	assert.LessOrEqual(t, server.maxInFlight["/b"], 2)
+	assert.Empty(t, q.endpoints)
+	assert.Equal(t, 0, q.Pending())
+}
+
+func TestDeliveryQueue_QueueSizeCountsWaiting(t *testing.T) {
+	q := NewDeliveryQueue(webhook.NewClient(webhook.DefaultConfig()), repository.NewMemoryRepository(), Config{
+		QueueSize:           2,
+		WorkerCount:         1,
+		EndpointConcurrency: 1,
+	})
+
+	// A delivery in flight, and one waiting for it
+	require.True(t, q.acquire(newItem("http://example.com", "wh-1")))
+	require.False(t, q.acquire(newItem("http://example.com", "wh-1")))
+	assert.Equal(t, 1, q.Pending())
+
+	assert.True(t, q.Enqueue(newItem("http://example.com", "wh-2")))
+	assert.False(t, q.Enqueue(newItem("http://example.com", "wh-2")), "the queue is full")
+
+	next, ok := q.release("wh-1")
+	require.True(t, ok, "the slot is handed to the waiting delivery")
+	assert.Equal(t, "wh-1", next.WebhookID)
+	_, ok = q.release("wh-1")
+	assert.False(t, ok)
+	assert.Equal(t, 1, q.Pending())
+}
+
+func TestDeliveryQueue_RateLimiter(t *testing.T) {
+	server, url := newEndpointServer(t, 0)
+	c := cache.NewMemoryCache(cache.Config{DefaultTTL: time.Hour})
+	t.Cleanup(func() { c.Close() })
+	limiter, err := ratelimit.New(ratelimit.Config{
+		Limit:    1,
+		Window:   50 * time.Millisecond,
+		Strategy: ratelimit.TokenBucket,
+	}, ratelimit.NewMemoryStore(c))
+	require.NoError(t, err)
+
+	q := NewDeliveryQueue(webhook.NewClient(webhook.DefaultConfig()), repository.NewMemoryRepository(),
+		Config{QueueSize: 10, WorkerCount: 3}, WithRateLimiter(limiter))
+	q.Start(context.Background())
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		require.True(t, q.Enqueue(newItem(url+"/a", "wh-a")))
+	}
+	q.Stop()
+
+	assert.Equal(t, 3, server.delivered["/a"])
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond, "deliveries to a webhook are spaced by the rate limit")
+}
+
+func TestDeliveryQueue_HealthTracker(t *testing.T) {
+	_, url := newEndpointServer(t, 0)
+	repo := repository.NewMemoryRepository()
+	ctx := context.Background()
+	require.NoError(t, repo.CreateWebhook(ctx, &repository.WebhookConfig{ID: "wh-fail", URL: url + "/fail", Active: true}))
+
+	health := &healthRecorder{outcomes: make(map[string][]bool)}
+	q := NewDeliveryQueue(webhook.NewClient(webhook.DefaultConfig()), repo, Config{WorkerCount: 1},
+		WithHealthTracker(health))
+	q.Start(ctx)
+	require.True(t, q.Enqueue(newItem(url+"/ok", "wh-ok")))
+	require.True(t, q.Enqueue(newItem(url+"/fail", "wh-fail")))
+	q.Stop()
+
+	assert.Equal(t, map[string][]bool{"wh-ok": {true}, "wh-fail": {false}}, health.outcomes)
+
+	config, err := repo.GetWebhook(ctx, "wh-fail")
+	require.NoError(t, err)
+	assert.Equal(t, 0, config.FailureCount, "the tracker updates the failure count in place of the queue")
+}
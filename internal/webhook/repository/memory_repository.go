@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
@@ -92,18 +93,8 @@ func (r *MemoryRepository) GetWebhooksByEvent(ctx context.Context, eventType bus
 			continue
 		}
 
-		// If no events specified, webhook receives all events
-		if len(webhook.Events) == 0 {
+		if webhook.Subscribes(eventType) {
 			webhooks = append(webhooks, *webhook)
-			continue
-		}
-
-		// Check if event type is in the list
-		for _, et := range webhook.Events {
-			if et == eventType {
-				webhooks = append(webhooks, *webhook)
-				break
-			}
 		}
 	}
 
@@ -126,12 +117,33 @@ func (r *MemoryRepository) UpdateWebhook(ctx context.Context, webhookID string,
 	if update.Events != nil {
 		webhook.Events = update.Events
 	}
+	if update.Filter != nil {
+		webhook.Filter = *update.Filter
+	}
+	if update.Projection != nil {
+		webhook.Projection = update.Projection
+	}
 	if update.Secret != nil {
 		webhook.Secret = *update.Secret
 	}
+	if update.SignatureScheme != nil {
+		webhook.SignatureScheme = *update.SignatureScheme
+	}
+	if update.PreviousSecret != nil {
+		webhook.PreviousSecret = *update.PreviousSecret
+	}
+	if update.PreviousSecretExpiresAt != nil {
+		webhook.PreviousSecretExpiresAt = update.PreviousSecretExpiresAt
+	}
 	if update.Headers != nil {
 		webhook.Headers = update.Headers
 	}
+	if update.Method != nil {
+		webhook.Method = *update.Method
+	}
+	if update.RetryPolicy != nil {
+		webhook.RetryPolicy = update.RetryPolicy
+	}
 	if update.Active != nil {
 		webhook.Active = *update.Active
 	}
@@ -192,6 +204,25 @@ func (r *MemoryRepository) ResetFailureCount(ctx context.Context, webhookID stri
 	return nil
 }
 
+// DisableFailingWebhook disables a webhook if it is active and its failure
+// count reached maxFailures.
+func (r *MemoryRepository) DisableFailingWebhook(ctx context.Context, webhookID string, maxFailures int) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	webhook, exists := r.webhooks[webhookID]
+	if !exists {
+		return false, fmt.Errorf("webhook %s not found", webhookID)
+	}
+	if !webhook.Active || webhook.FailureCount < maxFailures {
+		return false, nil
+	}
+
+	webhook.Active = false
+	webhook.UpdatedAt = time.Now()
+	return true, nil
+}
+
 // SaveDelivery persists a delivery attempt.
 func (r *MemoryRepository) SaveDelivery(ctx context.Context, delivery *WebhookDelivery) error {
 	r.mu.Lock()
@@ -272,6 +303,31 @@ func (r *MemoryRepository) GetFailedDeliveries(ctx context.Context, limit int) (
 	return deliveries, nil
 }
 
+// ClaimFailedDeliveries leases failed deliveries ready for retry.
+func (r *MemoryRepository) ClaimFailedDeliveries(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deliveries []WebhookDelivery
+	for _, delivery := range r.deliveries {
+		if delivery.Success {
+			continue
+		}
+		if delivery.NextRetryAt == nil || now.Before(*delivery.NextRetryAt) {
+			continue
+		}
+		leasedUntil := now.Add(lease)
+		delivery.NextRetryAt = &leasedUntil
+		deliveries = append(deliveries, *delivery)
+
+		if limit > 0 && len(deliveries) >= limit {
+			break
+		}
+	}
+
+	return deliveries, nil
+}
+
 // UpdateDeliveryRetry updates the next retry time for a delivery.
 func (r *MemoryRepository) UpdateDeliveryRetry(ctx context.Context, deliveryID string, nextRetryAt time.Time) error {
 	r.mu.Lock()
@@ -301,3 +357,73 @@ func (r *MemoryRepository) DeleteOldDeliveries(ctx context.Context, before time.
 
 	return deleted, nil
 }
+
+// ListDeadLetters retrieves dead letters, most recent first.
+func (r *MemoryRepository) ListDeadLetters(ctx context.Context, filter DeadLetterFilter) ([]WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	deadLetters := r.deadLetters(filter)
+	sort.Slice(deadLetters, func(i, j int) bool {
+		if !deadLetters[i].DeliveredAt.Equal(deadLetters[j].DeliveredAt) {
+			return deadLetters[i].DeliveredAt.After(deadLetters[j].DeliveredAt)
+		}
+		return deadLetters[i].ID < deadLetters[j].ID
+	})
+
+	var deliveries []WebhookDelivery
+	for i, delivery := range deadLetters {
+		if i < filter.Offset {
+			continue
+		}
+		if filter.Limit > 0 && len(deliveries) >= filter.Limit {
+			break
+		}
+		deliveries = append(deliveries, *delivery)
+	}
+	return deliveries, nil
+}
+
+// RedriveDeadLetters schedules the oldest dead letters matching the filter
+// for retry.
+func (r *MemoryRepository) RedriveDeadLetters(ctx context.Context, filter DeadLetterFilter, at time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	deadLetters := r.deadLetters(filter)
+	sort.Slice(deadLetters, func(i, j int) bool {
+		if !deadLetters[i].DeliveredAt.Equal(deadLetters[j].DeliveredAt) {
+			return deadLetters[i].DeliveredAt.Before(deadLetters[j].DeliveredAt)
+		}
+		return deadLetters[i].ID < deadLetters[j].ID
+	})
+	if filter.Limit > 0 && len(deadLetters) > filter.Limit {
+		deadLetters = deadLetters[:filter.Limit]
+	}
+
+	for _, delivery := range deadLetters {
+		nextRetryAt := at
+		delivery.Attempts = 0
+		delivery.NextRetryAt = &nextRetryAt
+	}
+	return int64(len(deadLetters)), nil
+}
+
+// deadLetters returns the stored dead letters matching the filter. The
+// caller must hold the lock.
+func (r *MemoryRepository) deadLetters(filter DeadLetterFilter) []*WebhookDelivery {
+	var deadLetters []*WebhookDelivery
+	for _, delivery := range r.deliveries {
+		if delivery.Success || delivery.NextRetryAt != nil {
+			continue
+		}
+		if filter.WebhookID != "" && delivery.WebhookID != filter.WebhookID {
+			continue
+		}
+		if delivery.DeliveredAt.Before(filter.Since) {
+			continue
+		}
+		deadLetters = append(deadLetters, delivery)
+	}
+	return deadLetters
+}
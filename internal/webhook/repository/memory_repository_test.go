@@ -135,6 +135,31 @@ func TestMemoryRepository_GetWebhooksByEvent(t *testing.T) {
 	assert.Len(t, webhooks, 2) // wh-2 and wh-3
 }
 
+func TestMemoryRepository_GetWebhooksByEvent_Patterns(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := context.Background()
+
+	require.NoError(t, repo.CreateWebhook(ctx, &WebhookConfig{
+		ID:     "wh-jobs",
+		Events: []bus.EventType{"job.*"},
+		Active: true,
+	}))
+	require.NoError(t, repo.CreateWebhook(ctx, &WebhookConfig{
+		ID:     "wh-completed",
+		Events: []bus.EventType{"*.completed"},
+		Active: true,
+	}))
+
+	webhooks, err := repo.GetWebhooksByEvent(ctx, bus.EventJobCompleted)
+	require.NoError(t, err)
+	assert.Len(t, webhooks, 2)
+
+	webhooks, err = repo.GetWebhooksByEvent(ctx, bus.EventJobFailed)
+	require.NoError(t, err)
+	require.Len(t, webhooks, 1)
+	assert.Equal(t, "wh-jobs", webhooks[0].ID)
+}
+
 func TestMemoryRepository_UpdateWebhook(t *testing.T) {
 	repo := NewMemoryRepository()
 	ctx := context.Background()
@@ -159,6 +184,27 @@ func TestMemoryRepository_UpdateWebhook(t *testing.T) {
 	assert.True(t, retrieved.Active)
 }
 
+func TestMemoryRepository_RotateSecret(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := context.Background()
+	now := time.Now()
+
+	require.NoError(t, repo.CreateWebhook(ctx, &WebhookConfig{ID: "wh-1", Secret: "old", Active: true}))
+
+	secret, previous, expiresAt := "new", "old", now.Add(time.Hour)
+	require.NoError(t, repo.UpdateWebhook(ctx, "wh-1", WebhookUpdate{
+		Secret:                  &secret,
+		PreviousSecret:          &previous,
+		PreviousSecretExpiresAt: &expiresAt,
+	}))
+
+	retrieved, err := repo.GetWebhook(ctx, "wh-1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"new", "old"}, retrieved.SigningSecrets(now))
+	assert.Equal(t, []string{"new"}, retrieved.SigningSecrets(expiresAt), "the previous secret stops signing when it expires")
+	assert.Empty(t, (&WebhookConfig{}).SigningSecrets(now))
+}
+
 func TestMemoryRepository_DeleteWebhook(t *testing.T) {
 	repo := NewMemoryRepository()
 	ctx := context.Background()
@@ -197,6 +243,33 @@ func TestMemoryRepository_FailureCount(t *testing.T) {
 	assert.Equal(t, 0, retrieved.FailureCount)
 }
 
+func TestMemoryRepository_DisableFailingWebhook(t *testing.T) {
+	testDisableFailingWebhook(t, NewMemoryRepository())
+}
+
+func testDisableFailingWebhook(t *testing.T, repo WebhookRepository) {
+	ctx := context.Background()
+	require.NoError(t, repo.CreateWebhook(ctx, &WebhookConfig{ID: "wh-1", URL: "https://example.com", Active: true}))
+	require.NoError(t, repo.IncrementFailureCount(ctx, "wh-1"))
+
+	disabled, err := repo.DisableFailingWebhook(ctx, "wh-1", 2)
+	require.NoError(t, err)
+	assert.False(t, disabled, "a webhook below the maximum is kept")
+
+	require.NoError(t, repo.IncrementFailureCount(ctx, "wh-1"))
+	disabled, err = repo.DisableFailingWebhook(ctx, "wh-1", 2)
+	require.NoError(t, err)
+	assert.True(t, disabled)
+
+	retrieved, err := repo.GetWebhook(ctx, "wh-1")
+	require.NoError(t, err)
+	assert.False(t, retrieved.Active)
+
+	disabled, err = repo.DisableFailingWebhook(ctx, "wh-1", 2)
+	require.NoError(t, err)
+	assert.False(t, disabled, "a disabled webhook is not disabled again")
+}
+
 func TestMemoryRepository_Deliveries(t *testing.T) {
 	repo := NewMemoryRepository()
 	ctx := context.Background()
@@ -263,6 +336,32 @@ func TestMemoryRepository_GetFailedDeliveries(t *testing.T) {
 	assert.Equal(t, "d-1", failed[0].ID)
 }
 
+func TestMemoryRepository_ClaimFailedDeliveries(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := context.Background()
+
+	now := time.Now()
+	require.NoError(t, repo.SaveDelivery(ctx, &WebhookDelivery{
+		ID:          "d-1",
+		WebhookID:   "wh-1",
+		DeliveredAt: now.Add(-1 * time.Hour),
+		NextRetryAt: ptr(now.Add(-10 * time.Minute)),
+	}))
+
+	claimed, err := repo.ClaimFailedDeliveries(ctx, now, 10, 5*time.Minute)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	assert.Equal(t, now.Add(5*time.Minute), *claimed[0].NextRetryAt)
+
+	claimed, err = repo.ClaimFailedDeliveries(ctx, now, 10, 5*time.Minute)
+	require.NoError(t, err)
+	assert.Empty(t, claimed, "leased deliveries are not claimed again")
+
+	claimed, err = repo.ClaimFailedDeliveries(ctx, now.Add(6*time.Minute), 10, 5*time.Minute)
+	require.NoError(t, err)
+	assert.Len(t, claimed, 1, "deliveries are claimed again once their lease expires")
+}
+
 func TestMemoryRepository_DeleteOldDeliveries(t *testing.T) {
 	repo := NewMemoryRepository()
 	ctx := context.Background()
@@ -297,6 +396,60 @@ func TestMemoryRepository_DeleteOldDeliveries(t *testing.T) {
 	assert.Len(t, deliveries, 3)
 }
 
+func TestMemoryRepository_DeadLetters(t *testing.T) {
+	testDeadLetters(t, NewMemoryRepository())
+}
+
+func testDeadLetters(t *testing.T, repo WebhookRepository) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+
+	deliveries := []*WebhookDelivery{
+		{ID: "d-1", WebhookID: "wh-1", Attempts: 3, DeliveredAt: now.Add(-3 * time.Hour)},
+		{ID: "d-2", WebhookID: "wh-1", Attempts: 3, DeliveredAt: now.Add(-2 * time.Hour)},
+		{ID: "d-3", WebhookID: "wh-2", Attempts: 3, DeliveredAt: now.Add(-time.Hour)},
+		{ID: "d-4", WebhookID: "wh-1", DeliveredAt: now, NextRetryAt: ptr(now.Add(time.Minute))}, // Retry scheduled
+		{ID: "d-5", WebhookID: "wh-1", DeliveredAt: now, Success: true},
+	}
+	for _, d := range deliveries {
+		require.NoError(t, repo.SaveDelivery(ctx, d))
+	}
+
+	deadLetters, err := repo.ListDeadLetters(ctx, DeadLetterFilter{})
+	require.NoError(t, err)
+	require.Len(t, deadLetters, 3)
+	assert.Equal(t, "d-3", deadLetters[0].ID, "the most recent dead letters come first")
+
+	deadLetters, err = repo.ListDeadLetters(ctx, DeadLetterFilter{WebhookID: "wh-1", Limit: 1, Offset: 1})
+	require.NoError(t, err)
+	require.Len(t, deadLetters, 1)
+	assert.Equal(t, "d-1", deadLetters[0].ID)
+
+	deadLetters, err = repo.ListDeadLetters(ctx, DeadLetterFilter{WebhookID: "wh-1", Since: now.Add(-2 * time.Hour)})
+	require.NoError(t, err)
+	require.Len(t, deadLetters, 1)
+	assert.Equal(t, "d-2", deadLetters[0].ID)
+
+	redriven, err := repo.RedriveDeadLetters(ctx, DeadLetterFilter{WebhookID: "wh-1", Limit: 1}, now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), redriven)
+
+	delivery, err := repo.GetDelivery(ctx, "d-1")
+	require.NoError(t, err)
+	assert.Equal(t, 0, delivery.Attempts, "the oldest dead letters are redriven first")
+	require.NotNil(t, delivery.NextRetryAt)
+	assert.True(t, now.Equal(*delivery.NextRetryAt))
+
+	redriven, err = repo.RedriveDeadLetters(ctx, DeadLetterFilter{WebhookID: "wh-1"}, now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), redriven, "redriven deliveries are no longer dead letters")
+
+	deadLetters, err = repo.ListDeadLetters(ctx, DeadLetterFilter{})
+	require.NoError(t, err)
+	require.Len(t, deadLetters, 1)
+	assert.Equal(t, "d-3", deadLetters[0].ID)
+}
+
 func ptr[T any](v T) *T {
 	return &v
 }
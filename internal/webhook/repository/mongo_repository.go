@@ -0,0 +1,405 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/bargom/codeai/internal/event/bus"
+)
+
+const (
+	webhooksCollection   = "webhooks"
+	deliveriesCollection = "webhook_deliveries"
+)
+
+// MongoRepository implements WebhookRepository on MongoDB, so webhooks and
+// their delivery history survive restarts and are shared by every instance.
+type MongoRepository struct {
+	webhooks   *mongo.Collection
+	deliveries *mongo.Collection
+}
+
+// NewMongoRepository creates a webhook repository on the given MongoDB
+// database.
+func NewMongoRepository(db *mongo.Database) *MongoRepository {
+	return &MongoRepository{
+		webhooks:   db.Collection(webhooksCollection),
+		deliveries: db.Collection(deliveriesCollection),
+	}
+}
+
+// EnsureIndexes creates the indexes deliveries are listed, retried and
+// cleaned up by.
+func (r *MongoRepository) EnsureIndexes(ctx context.Context) error {
+	if _, err := r.webhooks.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "active", Value: 1}},
+	}); err != nil {
+		return fmt.Errorf("creating webhook indexes: %w", err)
+	}
+	_, err := r.deliveries.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "webhook_id", Value: 1}, {Key: "delivered_at", Value: -1}}},
+		{Keys: bson.D{{Key: "success", Value: 1}, {Key: "next_retry_at", Value: 1}}},
+		{Keys: bson.D{{Key: "delivered_at", Value: 1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("creating webhook delivery indexes: %w", err)
+	}
+	return nil
+}
+
+// CreateWebhook creates a new webhook configuration.
+func (r *MongoRepository) CreateWebhook(ctx context.Context, webhook *WebhookConfig) error {
+	if _, err := r.webhooks.InsertOne(ctx, webhook); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return fmt.Errorf("webhook %s already exists", webhook.ID)
+		}
+		return fmt.Errorf("inserting webhook: %w", err)
+	}
+	return nil
+}
+
+// GetWebhook retrieves a webhook by its ID.
+func (r *MongoRepository) GetWebhook(ctx context.Context, webhookID string) (*WebhookConfig, error) {
+	var webhook WebhookConfig
+	err := r.webhooks.FindOne(ctx, bson.M{"_id": webhookID}).Decode(&webhook)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fmt.Errorf("webhook %s not found", webhookID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("finding webhook: %w", err)
+	}
+	return &webhook, nil
+}
+
+// ListWebhooks retrieves webhooks matching the filter criteria, oldest first.
+func (r *MongoRepository) ListWebhooks(ctx context.Context, filter WebhookFilter) ([]WebhookConfig, error) {
+	query := bson.M{}
+	if filter.Active != nil {
+		query["active"] = *filter.Active
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+	setPage(opts, filter.Limit, filter.Offset)
+
+	var webhooks []WebhookConfig
+	if err := r.find(ctx, r.webhooks, query, opts, &webhooks); err != nil {
+		return nil, fmt.Errorf("listing webhooks: %w", err)
+	}
+	return webhooks, nil
+}
+
+// GetWebhooksByEvent retrieves active webhooks subscribed to an event type.
+// Webhooks without events receive all events.
+func (r *MongoRepository) GetWebhooksByEvent(ctx context.Context, eventType bus.EventType) ([]WebhookConfig, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+
+	// The query selects the webhooks without events, those listing eventType
+	// and those with a pattern, which are matched here
+	query := bson.M{
+		"active": true,
+		"$or": bson.A{
+			bson.M{"events": nil},
+			bson.M{"events": bson.M{"$size": 0}},
+			bson.M{"events": string(eventType)},
+			bson.M{"events": bson.M{"$regex": `[*?[\\]`}},
+		},
+	}
+	var candidates []WebhookConfig
+	if err := r.find(ctx, r.webhooks, query, opts, &candidates); err != nil {
+		return nil, fmt.Errorf("listing webhooks: %w", err)
+	}
+
+	var webhooks []WebhookConfig
+	for _, webhook := range candidates {
+		if webhook.Subscribes(eventType) {
+			webhooks = append(webhooks, webhook)
+		}
+	}
+	return webhooks, nil
+}
+
+// UpdateWebhook updates a webhook configuration.
+func (r *MongoRepository) UpdateWebhook(ctx context.Context, webhookID string, update WebhookUpdate) error {
+	set := bson.M{"updated_at": time.Now().UTC()}
+	if update.URL != nil {
+		set["url"] = *update.URL
+	}
+	if update.Events != nil {
+		set["events"] = update.Events
+	}
+	if update.Filter != nil {
+		set["filter"] = *update.Filter
+	}
+	if update.Projection != nil {
+		set["projection"] = update.Projection
+	}
+	if update.Secret != nil {
+		set["secret"] = *update.Secret
+	}
+	if update.SignatureScheme != nil {
+		set["signature_scheme"] = *update.SignatureScheme
+	}
+	if update.PreviousSecret != nil {
+		set["previous_secret"] = *update.PreviousSecret
+	}
+	if update.PreviousSecretExpiresAt != nil {
+		set["previous_secret_expires_at"] = *update.PreviousSecretExpiresAt
+	}
+	if update.Headers != nil {
+		set["headers"] = update.Headers
+	}
+	if update.Method != nil {
+		set["method"] = *update.Method
+	}
+	if update.RetryPolicy != nil {
+		set["retry_policy"] = update.RetryPolicy
+	}
+	if update.Active != nil {
+		set["active"] = *update.Active
+	}
+	if update.LastDelivery != nil {
+		set["last_delivery"] = update.LastDelivery.UTC()
+	}
+	if update.FailureCount != nil {
+		set["failure_count"] = *update.FailureCount
+	}
+	if update.Metadata != nil {
+		set["metadata"] = update.Metadata
+	}
+	return r.updateWebhook(ctx, webhookID, bson.M{"$set": set})
+}
+
+// DeleteWebhook removes a webhook configuration.
+func (r *MongoRepository) DeleteWebhook(ctx context.Context, webhookID string) error {
+	result, err := r.webhooks.DeleteOne(ctx, bson.M{"_id": webhookID})
+	if err != nil {
+		return fmt.Errorf("deleting webhook: %w", err)
+	}
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("webhook %s not found", webhookID)
+	}
+	return nil
+}
+
+// IncrementFailureCount increments the failure count for a webhook.
+func (r *MongoRepository) IncrementFailureCount(ctx context.Context, webhookID string) error {
+	return r.updateWebhook(ctx, webhookID, bson.M{
+		"$inc": bson.M{"failure_count": 1},
+		"$set": bson.M{"updated_at": time.Now().UTC()},
+	})
+}
+
+// ResetFailureCount resets the failure count for a webhook.
+func (r *MongoRepository) ResetFailureCount(ctx context.Context, webhookID string) error {
+	return r.updateWebhook(ctx, webhookID, bson.M{
+		"$set": bson.M{"failure_count": 0, "updated_at": time.Now().UTC()},
+	})
+}
+
+// DisableFailingWebhook disables a webhook if it is active and its failure
+// count reached maxFailures, with a single conditional update.
+func (r *MongoRepository) DisableFailingWebhook(ctx context.Context, webhookID string, maxFailures int) (bool, error) {
+	query := bson.M{"_id": webhookID, "active": true, "failure_count": bson.M{"$gte": maxFailures}}
+	result, err := r.webhooks.UpdateOne(ctx, query, bson.M{
+		"$set": bson.M{"active": false, "updated_at": time.Now().UTC()},
+	})
+	if err != nil {
+		return false, fmt.Errorf("disabling webhook: %w", err)
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+func (r *MongoRepository) updateWebhook(ctx context.Context, webhookID string, update bson.M) error {
+	result, err := r.webhooks.UpdateOne(ctx, bson.M{"_id": webhookID}, update)
+	if err != nil {
+		return fmt.Errorf("updating webhook: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("webhook %s not found", webhookID)
+	}
+	return nil
+}
+
+// SaveDelivery persists a delivery attempt, replacing an earlier record of
+// the same delivery.
+func (r *MongoRepository) SaveDelivery(ctx context.Context, delivery *WebhookDelivery) error {
+	opts := options.Replace().SetUpsert(true)
+	if _, err := r.deliveries.ReplaceOne(ctx, bson.M{"_id": delivery.ID}, delivery, opts); err != nil {
+		return fmt.Errorf("saving delivery: %w", err)
+	}
+	return nil
+}
+
+// GetDelivery retrieves a delivery by its ID.
+func (r *MongoRepository) GetDelivery(ctx context.Context, deliveryID string) (*WebhookDelivery, error) {
+	var delivery WebhookDelivery
+	err := r.deliveries.FindOne(ctx, bson.M{"_id": deliveryID}).Decode(&delivery)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fmt.Errorf("delivery %s not found", deliveryID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("finding delivery: %w", err)
+	}
+	return &delivery, nil
+}
+
+// ListDeliveries retrieves deliveries for a webhook, most recent first.
+func (r *MongoRepository) ListDeliveries(ctx context.Context, webhookID string, filter DeliveryFilter) ([]WebhookDelivery, error) {
+	query := bson.M{"webhook_id": webhookID}
+	if filter.Success != nil {
+		query["success"] = *filter.Success
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "delivered_at", Value: -1}, {Key: "_id", Value: 1}})
+	setPage(opts, filter.Limit, filter.Offset)
+
+	var deliveries []WebhookDelivery
+	if err := r.find(ctx, r.deliveries, query, opts, &deliveries); err != nil {
+		return nil, fmt.Errorf("listing deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// GetFailedDeliveries retrieves failed deliveries ready for retry.
+func (r *MongoRepository) GetFailedDeliveries(ctx context.Context, limit int) ([]WebhookDelivery, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "next_retry_at", Value: 1}})
+	setPage(opts, limit, 0)
+
+	var deliveries []WebhookDelivery
+	if err := r.find(ctx, r.deliveries, dueFilter(time.Now().UTC()), opts, &deliveries); err != nil {
+		return nil, fmt.Errorf("listing failed deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// ClaimFailedDeliveries leases failed deliveries ready for retry. Each
+// delivery is leased with an atomic find-and-modify, so concurrent retry
+// handlers never claim the same delivery.
+func (r *MongoRepository) ClaimFailedDeliveries(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]WebhookDelivery, error) {
+	now = now.UTC()
+	update := bson.M{"$set": bson.M{"next_retry_at": now.Add(lease)}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "next_retry_at", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var deliveries []WebhookDelivery
+	for limit <= 0 || len(deliveries) < limit {
+		var delivery WebhookDelivery
+		err := r.deliveries.FindOneAndUpdate(ctx, dueFilter(now), update, opts).Decode(&delivery)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("claiming delivery: %w", err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, nil
+}
+
+// dueFilter matches failed deliveries due for retry at now.
+func dueFilter(now time.Time) bson.M {
+	return bson.M{"success": false, "next_retry_at": bson.M{"$lte": now}}
+}
+
+// UpdateDeliveryRetry updates the next retry time for a delivery.
+func (r *MongoRepository) UpdateDeliveryRetry(ctx context.Context, deliveryID string, nextRetryAt time.Time) error {
+	result, err := r.deliveries.UpdateOne(ctx, bson.M{"_id": deliveryID},
+		bson.M{"$set": bson.M{"next_retry_at": nextRetryAt.UTC()}})
+	if err != nil {
+		return fmt.Errorf("updating delivery: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("delivery %s not found", deliveryID)
+	}
+	return nil
+}
+
+// DeleteOldDeliveries removes deliveries older than the specified time.
+func (r *MongoRepository) DeleteOldDeliveries(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.deliveries.DeleteMany(ctx, bson.M{"delivered_at": bson.M{"$lt": before.UTC()}})
+	if err != nil {
+		return 0, fmt.Errorf("deleting deliveries: %w", err)
+	}
+	return result.DeletedCount, nil
+}
+
+// ListDeadLetters retrieves dead letters, most recent first.
+func (r *MongoRepository) ListDeadLetters(ctx context.Context, filter DeadLetterFilter) ([]WebhookDelivery, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "delivered_at", Value: -1}, {Key: "_id", Value: 1}})
+	setPage(opts, filter.Limit, filter.Offset)
+
+	var deliveries []WebhookDelivery
+	if err := r.find(ctx, r.deliveries, deadLetterFilter(filter), opts, &deliveries); err != nil {
+		return nil, fmt.Errorf("listing dead letters: %w", err)
+	}
+	return deliveries, nil
+}
+
+// RedriveDeadLetters schedules the oldest dead letters matching the filter
+// for retry. The update repeats the dead letter condition, so a delivery
+// redriven concurrently is counted once.
+func (r *MongoRepository) RedriveDeadLetters(ctx context.Context, filter DeadLetterFilter, at time.Time) (int64, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "delivered_at", Value: 1}, {Key: "_id", Value: 1}}).
+		SetProjection(bson.M{"_id": 1})
+	setPage(opts, filter.Limit, 0)
+
+	var candidates []struct {
+		ID string `bson:"_id"`
+	}
+	if err := r.find(ctx, r.deliveries, deadLetterFilter(filter), opts, &candidates); err != nil {
+		return 0, fmt.Errorf("listing dead letters: %w", err)
+	}
+	if len(candidates) == 0 {
+		return 0, nil
+	}
+	ids := make([]string, len(candidates))
+	for i, candidate := range candidates {
+		ids[i] = candidate.ID
+	}
+
+	query := bson.M{"_id": bson.M{"$in": ids}, "success": false, "next_retry_at": nil}
+	result, err := r.deliveries.UpdateMany(ctx, query, bson.M{
+		"$set": bson.M{"attempts": 0, "next_retry_at": at.UTC()},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("redriving deliveries: %w", err)
+	}
+	return result.ModifiedCount, nil
+}
+
+// deadLetterFilter matches the dead letters of a filter. A null or missing
+// next retry time means no retry is scheduled.
+func deadLetterFilter(filter DeadLetterFilter) bson.M {
+	query := bson.M{"success": false, "next_retry_at": nil}
+	if filter.WebhookID != "" {
+		query["webhook_id"] = filter.WebhookID
+	}
+	if !filter.Since.IsZero() {
+		query["delivered_at"] = bson.M{"$gte": filter.Since.UTC()}
+	}
+	return query
+}
+
+func (r *MongoRepository) find(ctx context.Context, coll *mongo.Collection, query bson.M, opts *options.FindOptions, results any) error {
+	cursor, err := coll.Find(ctx, query, opts)
+	if err != nil {
+		return err
+	}
+	return cursor.All(ctx, results)
+}
+
+// setPage sets the limit and offset of a page on opts.
+func setPage(opts *options.FindOptions, limit, offset int) {
+	if limit > 0 {
+		opts.SetLimit(int64(limit))
+	}
+	if offset > 0 {
+		opts.SetSkip(int64(offset))
+	}
+}
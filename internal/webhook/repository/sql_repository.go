@@ -0,0 +1,661 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bargom/codeai/internal/event/bus"
+)
+
+// SQLRepository implements WebhookRepository on PostgreSQL, so webhooks and
+// their delivery history survive restarts and are shared by every instance.
+type SQLRepository struct {
+	db *sql.DB
+}
+
+// NewSQLRepository creates a webhook repository on the given PostgreSQL
+// database.
+func NewSQLRepository(db *sql.DB) *SQLRepository {
+	return &SQLRepository{db: db}
+}
+
+// CreateTables creates the webhooks and codeai_webhook_deliveries tables and their
+// indexes if they don't exist.
+func (r *SQLRepository) CreateTables(ctx context.Context) error {
+	queries := []string{`
+		CREATE TABLE IF NOT EXISTS codeai_webhooks (
+			id TEXT PRIMARY KEY,
+			url TEXT NOT NULL,
+			events TEXT NOT NULL DEFAULT '[]',
+			filter TEXT NOT NULL DEFAULT '',
+			projection TEXT NOT NULL DEFAULT 'null',
+			secret TEXT NOT NULL DEFAULT '',
+			signature_scheme TEXT NOT NULL DEFAULT '',
+			previous_secret TEXT NOT NULL DEFAULT '',
+			previous_secret_expires_at TIMESTAMP,
+			headers TEXT NOT NULL DEFAULT 'null',
+			method TEXT NOT NULL DEFAULT '',
+			retry_policy TEXT NOT NULL DEFAULT 'null',
+			active BOOLEAN NOT NULL,
+			created_at TIMESTAMP NOT NULL,
+			updated_at TIMESTAMP NOT NULL,
+			last_delivery TIMESTAMP,
+			failure_count INTEGER NOT NULL DEFAULT 0,
+			metadata TEXT NOT NULL DEFAULT 'null'
+		)`, `
+		CREATE TABLE IF NOT EXISTS codeai_webhook_deliveries (
+			id TEXT PRIMARY KEY,
+			webhook_id TEXT NOT NULL,
+			event_id TEXT NOT NULL,
+			event_type TEXT NOT NULL,
+			url TEXT NOT NULL,
+			status_code INTEGER NOT NULL DEFAULT 0,
+			request_body TEXT NOT NULL DEFAULT '',
+			response_body TEXT NOT NULL DEFAULT '',
+			duration_ns BIGINT NOT NULL DEFAULT 0,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			success BOOLEAN NOT NULL,
+			error TEXT NOT NULL DEFAULT '',
+			delivered_at TIMESTAMP NOT NULL,
+			next_retry_at TIMESTAMP
+		)`, `
+		CREATE INDEX IF NOT EXISTS codeai_webhook_deliveries_webhook_id_idx
+			ON codeai_webhook_deliveries (webhook_id)`, `
+		CREATE INDEX IF NOT EXISTS codeai_webhook_deliveries_next_retry_at_idx
+			ON codeai_webhook_deliveries (next_retry_at) WHERE next_retry_at IS NOT NULL`, `
+		CREATE INDEX IF NOT EXISTS codeai_webhook_deliveries_delivered_at_idx
+			ON codeai_webhook_deliveries (delivered_at)`,
+	}
+	for _, query := range queries {
+		if _, err := r.db.ExecContext(ctx, query); err != nil {
+			return fmt.Errorf("creating webhook tables: %w", err)
+		}
+	}
+	return nil
+}
+
+const webhookColumns = `id, url, events, filter, projection, secret, signature_scheme, previous_secret,
+	previous_secret_expires_at, headers, method, retry_policy, active, created_at, updated_at, last_delivery,
+	failure_count, metadata`
+
+const deliveryColumns = `id, webhook_id, event_id, event_type, url, status_code, request_body,
+	response_body, duration_ns, attempts, success, error, delivered_at, next_retry_at`
+
+// CreateWebhook creates a new webhook configuration.
+func (r *SQLRepository) CreateWebhook(ctx context.Context, webhook *WebhookConfig) error {
+	events, err := marshalJSON(webhook.Events)
+	if err != nil {
+		return fmt.Errorf("marshaling events: %w", err)
+	}
+	projection, err := marshalJSON(webhook.Projection)
+	if err != nil {
+		return fmt.Errorf("marshaling projection: %w", err)
+	}
+	headers, err := marshalJSON(webhook.Headers)
+	if err != nil {
+		return fmt.Errorf("marshaling headers: %w", err)
+	}
+	retryPolicy, err := marshalJSON(webhook.RetryPolicy)
+	if err != nil {
+		return fmt.Errorf("marshaling retry policy: %w", err)
+	}
+	metadata, err := marshalJSON(webhook.Metadata)
+	if err != nil {
+		return fmt.Errorf("marshaling metadata: %w", err)
+	}
+
+	query := `
+		INSERT INTO codeai_webhooks (` + webhookColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
+		ON CONFLICT (id) DO NOTHING
+	`
+	result, err := r.db.ExecContext(ctx, query,
+		webhook.ID, webhook.URL, events, webhook.Filter, projection, webhook.Secret, webhook.SignatureScheme,
+		webhook.PreviousSecret, nullTime(webhook.PreviousSecretExpiresAt), headers, webhook.Method, retryPolicy,
+		webhook.Active, webhook.CreatedAt.UTC(), webhook.UpdatedAt.UTC(), nullTime(webhook.LastDelivery),
+		webhook.FailureCount, metadata,
+	)
+	if err != nil {
+		return fmt.Errorf("inserting webhook: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("inserting webhook: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("webhook %s already exists", webhook.ID)
+	}
+	return nil
+}
+
+// GetWebhook retrieves a webhook by its ID.
+func (r *SQLRepository) GetWebhook(ctx context.Context, webhookID string) (*WebhookConfig, error) {
+	query := `SELECT ` + webhookColumns + ` FROM codeai_webhooks WHERE id = $1`
+	webhook, err := scanWebhook(r.db.QueryRowContext(ctx, query, webhookID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("webhook %s not found", webhookID)
+	}
+	return webhook, err
+}
+
+// ListWebhooks retrieves webhooks matching the filter criteria, oldest first.
+func (r *SQLRepository) ListWebhooks(ctx context.Context, filter WebhookFilter) ([]WebhookConfig, error) {
+	query := `SELECT ` + webhookColumns + ` FROM codeai_webhooks`
+	var args []any
+	if filter.Active != nil {
+		args = append(args, *filter.Active)
+		query += ` WHERE active = $1`
+	}
+	query += ` ORDER BY created_at, id`
+	query, args = limitOffset(query, args, filter.Limit, filter.Offset)
+	return r.queryWebhooks(ctx, query, args...)
+}
+
+// GetWebhooksByEvent retrieves active webhooks subscribed to an event type.
+// Webhooks without events receive all events. The query selects the
+// webhooks without events, those listing eventType and those with a
+// pattern, which are then matched here.
+func (r *SQLRepository) GetWebhooksByEvent(ctx context.Context, eventType bus.EventType) ([]WebhookConfig, error) {
+	exact, err := marshalJSON(string(eventType))
+	if err != nil {
+		return nil, fmt.Errorf("encoding event type: %w", err)
+	}
+	query := `SELECT ` + webhookColumns + ` FROM codeai_webhooks
+		WHERE active = $1 AND (
+			events IN ('[]', 'null')
+			OR events LIKE $2 ESCAPE '\'
+			OR events LIKE '%*%' OR events LIKE '%?%' OR events LIKE '_%[%'
+			OR events LIKE '%\\%' ESCAPE '\'
+		)
+		ORDER BY created_at, id`
+	candidates, err := r.queryWebhooks(ctx, query, true, "%"+escapeLike(exact)+"%")
+	if err != nil {
+		return nil, err
+	}
+
+	var webhooks []WebhookConfig
+	for _, webhook := range candidates {
+		if webhook.Subscribes(eventType) {
+			webhooks = append(webhooks, webhook)
+		}
+	}
+	return webhooks, nil
+}
+
+func (r *SQLRepository) queryWebhooks(ctx context.Context, query string, args ...any) ([]WebhookConfig, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	var webhooks []WebhookConfig
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, *webhook)
+	}
+	return webhooks, rows.Err()
+}
+
+// UpdateWebhook updates a webhook configuration.
+func (r *SQLRepository) UpdateWebhook(ctx context.Context, webhookID string, update WebhookUpdate) error {
+	var (
+		sets []string
+		args []any
+	)
+	set := func(column string, value any) {
+		args = append(args, value)
+		sets = append(sets, fmt.Sprintf("%s = $%d", column, len(args)))
+	}
+	setJSON := func(column string, value any) error {
+		data, err := marshalJSON(value)
+		if err != nil {
+			return fmt.Errorf("marshaling %s: %w", column, err)
+		}
+		set(column, data)
+		return nil
+	}
+
+	if update.URL != nil {
+		set("url", *update.URL)
+	}
+	if update.Events != nil {
+		if err := setJSON("events", update.Events); err != nil {
+			return err
+		}
+	}
+	if update.Filter != nil {
+		set("filter", *update.Filter)
+	}
+	if update.Projection != nil {
+		if err := setJSON("projection", update.Projection); err != nil {
+			return err
+		}
+	}
+	if update.Secret != nil {
+		set("secret", *update.Secret)
+	}
+	if update.SignatureScheme != nil {
+		set("signature_scheme", *update.SignatureScheme)
+	}
+	if update.PreviousSecret != nil {
+		set("previous_secret", *update.PreviousSecret)
+	}
+	if update.PreviousSecretExpiresAt != nil {
+		set("previous_secret_expires_at", update.PreviousSecretExpiresAt.UTC())
+	}
+	if update.Headers != nil {
+		if err := setJSON("headers", update.Headers); err != nil {
+			return err
+		}
+	}
+	if update.Method != nil {
+		set("method", *update.Method)
+	}
+	if update.RetryPolicy != nil {
+		if err := setJSON("retry_policy", update.RetryPolicy); err != nil {
+			return err
+		}
+	}
+	if update.Active != nil {
+		set("active", *update.Active)
+	}
+	if update.LastDelivery != nil {
+		set("last_delivery", update.LastDelivery.UTC())
+	}
+	if update.FailureCount != nil {
+		set("failure_count", *update.FailureCount)
+	}
+	if update.Metadata != nil {
+		if err := setJSON("metadata", update.Metadata); err != nil {
+			return err
+		}
+	}
+	set("updated_at", time.Now().UTC())
+
+	args = append(args, webhookID)
+	query := fmt.Sprintf("UPDATE codeai_webhooks SET %s WHERE id = $%d", strings.Join(sets, ", "), len(args))
+	return r.updateWebhook(ctx, webhookID, query, args...)
+}
+
+// DeleteWebhook removes a webhook configuration.
+func (r *SQLRepository) DeleteWebhook(ctx context.Context, webhookID string) error {
+	return r.updateWebhook(ctx, webhookID, `DELETE FROM codeai_webhooks WHERE id = $1`, webhookID)
+}
+
+// IncrementFailureCount increments the failure count for a webhook.
+func (r *SQLRepository) IncrementFailureCount(ctx context.Context, webhookID string) error {
+	query := `UPDATE codeai_webhooks SET failure_count = failure_count + 1, updated_at = $1 WHERE id = $2`
+	return r.updateWebhook(ctx, webhookID, query, time.Now().UTC(), webhookID)
+}
+
+// ResetFailureCount resets the failure count for a webhook.
+func (r *SQLRepository) ResetFailureCount(ctx context.Context, webhookID string) error {
+	query := `UPDATE codeai_webhooks SET failure_count = 0, updated_at = $1 WHERE id = $2`
+	return r.updateWebhook(ctx, webhookID, query, time.Now().UTC(), webhookID)
+}
+
+// DisableFailingWebhook disables a webhook if it is active and its failure
+// count reached maxFailures, with a single conditional update.
+func (r *SQLRepository) DisableFailingWebhook(ctx context.Context, webhookID string, maxFailures int) (bool, error) {
+	query := `
+		UPDATE codeai_webhooks SET active = $1, updated_at = $2
+		WHERE id = $3 AND active = $4 AND failure_count >= $5`
+	result, err := r.db.ExecContext(ctx, query, false, time.Now().UTC(), webhookID, true, maxFailures)
+	if err != nil {
+		return false, fmt.Errorf("disabling webhook: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("disabling webhook: %w", err)
+	}
+	return n > 0, nil
+}
+
+func (r *SQLRepository) updateWebhook(ctx context.Context, webhookID, query string, args ...any) error {
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("updating webhook: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("updating webhook: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("webhook %s not found", webhookID)
+	}
+	return nil
+}
+
+// SaveDelivery persists a delivery attempt, replacing an earlier record of
+// the same delivery.
+func (r *SQLRepository) SaveDelivery(ctx context.Context, delivery *WebhookDelivery) error {
+	query := `
+		INSERT INTO codeai_webhook_deliveries (` + deliveryColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		ON CONFLICT (id) DO UPDATE SET
+			webhook_id = EXCLUDED.webhook_id,
+			event_id = EXCLUDED.event_id,
+			event_type = EXCLUDED.event_type,
+			url = EXCLUDED.url,
+			status_code = EXCLUDED.status_code,
+			request_body = EXCLUDED.request_body,
+			response_body = EXCLUDED.response_body,
+			duration_ns = EXCLUDED.duration_ns,
+			attempts = EXCLUDED.attempts,
+			success = EXCLUDED.success,
+			error = EXCLUDED.error,
+			delivered_at = EXCLUDED.delivered_at,
+			next_retry_at = EXCLUDED.next_retry_at
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		delivery.ID, delivery.WebhookID, delivery.EventID, string(delivery.EventType), delivery.URL,
+		delivery.StatusCode, string(delivery.RequestBody), delivery.ResponseBody, int64(delivery.Duration),
+		delivery.Attempts, delivery.Success, delivery.Error, delivery.DeliveredAt.UTC(), nullTime(delivery.NextRetryAt),
+	)
+	if err != nil {
+		return fmt.Errorf("saving delivery: %w", err)
+	}
+	return nil
+}
+
+// GetDelivery retrieves a delivery by its ID.
+func (r *SQLRepository) GetDelivery(ctx context.Context, deliveryID string) (*WebhookDelivery, error) {
+	query := `SELECT ` + deliveryColumns + ` FROM codeai_webhook_deliveries WHERE id = $1`
+	delivery, err := scanDelivery(r.db.QueryRowContext(ctx, query, deliveryID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("delivery %s not found", deliveryID)
+	}
+	return delivery, err
+}
+
+// ListDeliveries retrieves deliveries for a webhook, most recent first.
+func (r *SQLRepository) ListDeliveries(ctx context.Context, webhookID string, filter DeliveryFilter) ([]WebhookDelivery, error) {
+	query := `SELECT ` + deliveryColumns + ` FROM codeai_webhook_deliveries WHERE webhook_id = $1`
+	args := []any{webhookID}
+	if filter.Success != nil {
+		args = append(args, *filter.Success)
+		query += ` AND success = $2`
+	}
+	query += ` ORDER BY delivered_at DESC, id`
+	query, args = limitOffset(query, args, filter.Limit, filter.Offset)
+	return r.queryDeliveries(ctx, query, args...)
+}
+
+// GetFailedDeliveries retrieves failed deliveries ready for retry.
+func (r *SQLRepository) GetFailedDeliveries(ctx context.Context, limit int) ([]WebhookDelivery, error) {
+	query := `
+		SELECT ` + deliveryColumns + ` FROM codeai_webhook_deliveries
+		WHERE success = $1 AND next_retry_at <= $2
+		ORDER BY next_retry_at`
+	query, args := limitOffset(query, []any{false, time.Now().UTC()}, limit, 0)
+	return r.queryDeliveries(ctx, query, args...)
+}
+
+// ClaimFailedDeliveries leases failed deliveries ready for retry. Candidates
+// are leased one by one with a conditional update, so concurrent retry
+// handlers never claim the same delivery.
+func (r *SQLRepository) ClaimFailedDeliveries(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]WebhookDelivery, error) {
+	now = now.UTC()
+	ids, err := r.dueDeliveryIDs(ctx, now, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		UPDATE codeai_webhook_deliveries
+		SET next_retry_at = $1
+		WHERE id = $2 AND success = $3 AND next_retry_at <= $4
+		RETURNING ` + deliveryColumns
+	var deliveries []WebhookDelivery
+	for _, id := range ids {
+		delivery, err := scanDelivery(r.db.QueryRowContext(ctx, query, now.Add(lease), id, false, now))
+		if errors.Is(err, sql.ErrNoRows) {
+			continue // Claimed by another instance
+		}
+		if err != nil {
+			return nil, fmt.Errorf("claiming delivery: %w", err)
+		}
+		deliveries = append(deliveries, *delivery)
+	}
+	return deliveries, nil
+}
+
+// dueDeliveryIDs returns the IDs of up to limit failed deliveries due for
+// retry at now.
+func (r *SQLRepository) dueDeliveryIDs(ctx context.Context, now time.Time, limit int) ([]string, error) {
+	query := `
+		SELECT id FROM codeai_webhook_deliveries
+		WHERE success = $1 AND next_retry_at <= $2
+		ORDER BY next_retry_at`
+	query, args := limitOffset(query, []any{false, now}, limit, 0)
+	return r.queryIDs(ctx, query, args...)
+}
+
+func (r *SQLRepository) queryIDs(ctx context.Context, query string, args ...any) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scanning delivery: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (r *SQLRepository) queryDeliveries(ctx context.Context, query string, args ...any) ([]WebhookDelivery, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []WebhookDelivery
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning delivery: %w", err)
+		}
+		deliveries = append(deliveries, *delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+// UpdateDeliveryRetry updates the next retry time for a delivery.
+func (r *SQLRepository) UpdateDeliveryRetry(ctx context.Context, deliveryID string, nextRetryAt time.Time) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE codeai_webhook_deliveries SET next_retry_at = $1 WHERE id = $2`,
+		nextRetryAt.UTC(), deliveryID)
+	if err != nil {
+		return fmt.Errorf("updating delivery: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("updating delivery: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("delivery %s not found", deliveryID)
+	}
+	return nil
+}
+
+// DeleteOldDeliveries removes deliveries older than the specified time.
+func (r *SQLRepository) DeleteOldDeliveries(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM codeai_webhook_deliveries WHERE delivered_at < $1`, before.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("deleting deliveries: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// ListDeadLetters retrieves dead letters, most recent first.
+func (r *SQLRepository) ListDeadLetters(ctx context.Context, filter DeadLetterFilter) ([]WebhookDelivery, error) {
+	where, args := deadLetterWhere(filter)
+	query := `SELECT ` + deliveryColumns + ` FROM codeai_webhook_deliveries WHERE ` + where + ` ORDER BY delivered_at DESC, id`
+	query, args = limitOffset(query, args, filter.Limit, filter.Offset)
+	return r.queryDeliveries(ctx, query, args...)
+}
+
+// RedriveDeadLetters schedules the oldest dead letters matching the filter
+// for retry. Candidates are scheduled one by one with a conditional update,
+// so a delivery redriven concurrently is counted once.
+func (r *SQLRepository) RedriveDeadLetters(ctx context.Context, filter DeadLetterFilter, at time.Time) (int64, error) {
+	where, args := deadLetterWhere(filter)
+	query := `SELECT id FROM codeai_webhook_deliveries WHERE ` + where + ` ORDER BY delivered_at, id`
+	query, args = limitOffset(query, args, filter.Limit, 0)
+	ids, err := r.queryIDs(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	update := `
+		UPDATE codeai_webhook_deliveries SET attempts = 0, next_retry_at = $1
+		WHERE id = $2 AND success = $3 AND next_retry_at IS NULL`
+	var redriven int64
+	for _, id := range ids {
+		result, err := r.db.ExecContext(ctx, update, at.UTC(), id, false)
+		if err != nil {
+			return redriven, fmt.Errorf("redriving delivery: %w", err)
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return redriven, fmt.Errorf("redriving delivery: %w", err)
+		}
+		redriven += n
+	}
+	return redriven, nil
+}
+
+// deadLetterWhere returns the WHERE condition matching the dead letters of
+// a filter, and its arguments.
+func deadLetterWhere(filter DeadLetterFilter) (string, []any) {
+	where := `success = $1 AND next_retry_at IS NULL`
+	args := []any{false}
+	if filter.WebhookID != "" {
+		args = append(args, filter.WebhookID)
+		where += fmt.Sprintf(` AND webhook_id = $%d`, len(args))
+	}
+	if !filter.Since.IsZero() {
+		args = append(args, filter.Since.UTC())
+		where += fmt.Sprintf(` AND delivered_at >= $%d`, len(args))
+	}
+	return where, args
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanWebhook(row scanner) (*WebhookConfig, error) {
+	var (
+		webhook                                            WebhookConfig
+		events, projection, headers, retryPolicy, metadata string
+		previousSecretExpiresAt, lastDelivery              sql.NullTime
+	)
+	err := row.Scan(&webhook.ID, &webhook.URL, &events, &webhook.Filter, &projection, &webhook.Secret,
+		&webhook.SignatureScheme, &webhook.PreviousSecret, &previousSecretExpiresAt, &headers, &webhook.Method,
+		&retryPolicy, &webhook.Active, &webhook.CreatedAt, &webhook.UpdatedAt, &lastDelivery,
+		&webhook.FailureCount, &metadata)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scanning webhook: %w", err)
+	}
+
+	if err := json.Unmarshal([]byte(events), &webhook.Events); err != nil {
+		return nil, fmt.Errorf("unmarshaling events: %w", err)
+	}
+	if err := json.Unmarshal([]byte(projection), &webhook.Projection); err != nil {
+		return nil, fmt.Errorf("unmarshaling projection: %w", err)
+	}
+	if err := json.Unmarshal([]byte(headers), &webhook.Headers); err != nil {
+		return nil, fmt.Errorf("unmarshaling headers: %w", err)
+	}
+	if err := json.Unmarshal([]byte(retryPolicy), &webhook.RetryPolicy); err != nil {
+		return nil, fmt.Errorf("unmarshaling retry policy: %w", err)
+	}
+	if err := json.Unmarshal([]byte(metadata), &webhook.Metadata); err != nil {
+		return nil, fmt.Errorf("unmarshaling metadata: %w", err)
+	}
+	webhook.PreviousSecretExpiresAt = timePtr(previousSecretExpiresAt)
+	webhook.LastDelivery = timePtr(lastDelivery)
+	return &webhook, nil
+}
+
+func scanDelivery(row scanner) (*WebhookDelivery, error) {
+	var (
+		delivery    WebhookDelivery
+		eventType   string
+		requestBody string
+		duration    int64
+		nextRetryAt sql.NullTime
+	)
+	err := row.Scan(&delivery.ID, &delivery.WebhookID, &delivery.EventID, &eventType, &delivery.URL,
+		&delivery.StatusCode, &requestBody, &delivery.ResponseBody, &duration, &delivery.Attempts,
+		&delivery.Success, &delivery.Error, &delivery.DeliveredAt, &nextRetryAt)
+	if err != nil {
+		return nil, err
+	}
+	delivery.EventType = bus.EventType(eventType)
+	if requestBody != "" {
+		delivery.RequestBody = json.RawMessage(requestBody)
+	}
+	delivery.Duration = time.Duration(duration)
+	delivery.NextRetryAt = timePtr(nextRetryAt)
+	return &delivery, nil
+}
+
+// limitOffset appends the LIMIT and OFFSET clauses of a page to query.
+func limitOffset(query string, args []any, limit, offset int) (string, []any) {
+	if limit > 0 {
+		args = append(args, limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	if offset > 0 {
+		args = append(args, offset)
+		query += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+	return query, args
+}
+
+func marshalJSON(v any) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// escapeLike escapes the LIKE wildcards in s, for patterns with ESCAPE '\'.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+func nullTime(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
+
+func timePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
@@ -0,0 +1,274 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+
+	"github.com/bargom/codeai/internal/event/bus"
+	"github.com/bargom/codeai/pkg/integration/webhook"
+)
+
+func newSQLRepository(t *testing.T) *SQLRepository {
+	t.Helper()
+	db, err := sql.Open("sqlite", ":memory:")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	repo := NewSQLRepository(db)
+	require.NoError(t, repo.CreateTables(context.Background()))
+	require.NoError(t, repo.CreateTables(context.Background()), "creating the tables is idempotent")
+	return repo
+}
+
+func TestSQLRepository_Webhooks(t *testing.T) {
+	repo := newSQLRepository(t)
+	ctx := context.Background()
+	created := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+
+	wh := &WebhookConfig{
+		ID:          "wh-1",
+		URL:         "https://example.com/webhook",
+		Events:      []bus.EventType{bus.EventJobCompleted},
+		Filter:      `payload.status == "ok"`,
+		Projection:  map[string]string{"job": "payload.job_id"},
+		Secret:      "s3cret",
+		Headers:     map[string]string{"X-Custom": "value"},
+		Method:      "PUT",
+		RetryPolicy: webhook.DefaultRetryPolicy(),
+		Active:      true,
+		CreatedAt:   created,
+		UpdatedAt:   created,
+		Metadata:    map[string]interface{}{"source": "dsl"},
+
+		SignatureScheme:         "standard-webhooks",
+		PreviousSecret:          "old-s3cret",
+		PreviousSecretExpiresAt: ptr(created.Add(24 * time.Hour)),
+	}
+	require.NoError(t, repo.CreateWebhook(ctx, wh))
+	assert.ErrorContains(t, repo.CreateWebhook(ctx, wh), "already exists")
+
+	got, err := repo.GetWebhook(ctx, "wh-1")
+	require.NoError(t, err)
+	assert.Equal(t, wh, got)
+
+	_, err = repo.GetWebhook(ctx, "nonexistent")
+	assert.ErrorContains(t, err, "webhook nonexistent not found")
+
+	require.NoError(t, repo.CreateWebhook(ctx, &WebhookConfig{
+		ID: "wh-all", URL: "https://example.com/all", Active: true, CreatedAt: created.Add(time.Second),
+	}))
+	require.NoError(t, repo.CreateWebhook(ctx, &WebhookConfig{
+		ID: "wh-off", URL: "https://example.com/off", CreatedAt: created.Add(2 * time.Second),
+	}))
+
+	subscribed, err := repo.GetWebhooksByEvent(ctx, bus.EventJobCompleted)
+	require.NoError(t, err)
+	require.Len(t, subscribed, 2)
+	assert.Equal(t, "wh-1", subscribed[0].ID)
+	assert.Equal(t, "wh-all", subscribed[1].ID, "webhooks without events receive all events")
+
+	subscribed, err = repo.GetWebhooksByEvent(ctx, bus.EventJobFailed)
+	require.NoError(t, err)
+	require.Len(t, subscribed, 1)
+	assert.Equal(t, "wh-all", subscribed[0].ID)
+
+	events := []bus.EventType{"job.*"}
+	require.NoError(t, repo.UpdateWebhook(ctx, "wh-1", WebhookUpdate{Events: events}))
+	subscribed, err = repo.GetWebhooksByEvent(ctx, bus.EventJobFailed)
+	require.NoError(t, err)
+	assert.Len(t, subscribed, 2, "events may be patterns")
+
+	require.NoError(t, repo.CreateWebhook(ctx, &WebhookConfig{
+		ID: "wh-star", URL: "https://example.com/star", Active: true, CreatedAt: created.Add(3 * time.Second),
+		Events: []bus.EventType{bus.AllEvents},
+	}))
+	require.NoError(t, repo.CreateWebhook(ctx, &WebhookConfig{
+		ID: "wh-orders", URL: "https://example.com/orders", Active: true, CreatedAt: created.Add(4 * time.Second),
+		Events: []bus.EventType{"order_created", "order.paid"},
+	}))
+	subscribed, err = repo.GetWebhooksByEvent(ctx, "order_created")
+	require.NoError(t, err)
+	require.Len(t, subscribed, 3)
+	assert.Equal(t, []string{"wh-all", "wh-star", "wh-orders"}, []string{subscribed[0].ID, subscribed[1].ID, subscribed[2].ID})
+
+	subscribed, err = repo.GetWebhooksByEvent(ctx, "orderXcreated")
+	require.NoError(t, err)
+	assert.Len(t, subscribed, 2, "an underscore in an event type is not a wildcard")
+
+	active := false
+	inactive, err := repo.ListWebhooks(ctx, WebhookFilter{Active: &active})
+	require.NoError(t, err)
+	require.Len(t, inactive, 1)
+	assert.Equal(t, "wh-off", inactive[0].ID)
+
+	page, err := repo.ListWebhooks(ctx, WebhookFilter{Limit: 1, Offset: 1})
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	assert.Equal(t, "wh-all", page[0].ID)
+}
+
+func TestSQLRepository_UpdateWebhook(t *testing.T) {
+	repo := newSQLRepository(t)
+	ctx := context.Background()
+
+	require.NoError(t, repo.CreateWebhook(ctx, &WebhookConfig{ID: "wh-1", URL: "https://example.com/old", Active: true}))
+
+	url := "https://example.com/new"
+	filterSrc := "payload.amount > 10"
+	active := false
+	lastDelivery := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	scheme, previousSecret := "standard-webhooks", "old-s3cret"
+	require.NoError(t, repo.UpdateWebhook(ctx, "wh-1", WebhookUpdate{
+		URL:          &url,
+		Events:       []bus.EventType{"job.*"},
+		Filter:       &filterSrc,
+		Projection:   map[string]string{"amount": "payload.amount"},
+		Active:       &active,
+		LastDelivery: &lastDelivery,
+
+		SignatureScheme:         &scheme,
+		PreviousSecret:          &previousSecret,
+		PreviousSecretExpiresAt: &lastDelivery,
+	}))
+
+	got, err := repo.GetWebhook(ctx, "wh-1")
+	require.NoError(t, err)
+	assert.Equal(t, url, got.URL)
+	assert.Equal(t, []bus.EventType{"job.*"}, got.Events)
+	assert.Equal(t, filterSrc, got.Filter)
+	assert.Equal(t, map[string]string{"amount": "payload.amount"}, got.Projection)
+	assert.False(t, got.Active)
+	assert.Equal(t, &lastDelivery, got.LastDelivery)
+	assert.Equal(t, scheme, got.SignatureScheme)
+	assert.Equal(t, previousSecret, got.PreviousSecret)
+	assert.Equal(t, &lastDelivery, got.PreviousSecretExpiresAt)
+
+	require.NoError(t, repo.IncrementFailureCount(ctx, "wh-1"))
+	require.NoError(t, repo.IncrementFailureCount(ctx, "wh-1"))
+	got, err = repo.GetWebhook(ctx, "wh-1")
+	require.NoError(t, err)
+	assert.Equal(t, 2, got.FailureCount)
+
+	require.NoError(t, repo.ResetFailureCount(ctx, "wh-1"))
+	got, err = repo.GetWebhook(ctx, "wh-1")
+	require.NoError(t, err)
+	assert.Equal(t, 0, got.FailureCount)
+
+	assert.Error(t, repo.UpdateWebhook(ctx, "nonexistent", WebhookUpdate{URL: &url}))
+	assert.Error(t, repo.IncrementFailureCount(ctx, "nonexistent"))
+
+	require.NoError(t, repo.DeleteWebhook(ctx, "wh-1"))
+	assert.Error(t, repo.DeleteWebhook(ctx, "wh-1"))
+}
+
+func TestSQLRepository_Deliveries(t *testing.T) {
+	repo := newSQLRepository(t)
+	ctx := context.Background()
+	now := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+
+	delivery := &WebhookDelivery{
+		ID:          "d-1",
+		WebhookID:   "wh-1",
+		EventID:     "evt-1",
+		EventType:   bus.EventJobCompleted,
+		URL:         "https://example.com/webhook",
+		StatusCode:  500,
+		RequestBody: json.RawMessage(`{"id":"evt-1"}`),
+		Duration:    1500 * time.Microsecond,
+		Attempts:    3,
+		Error:       "server error",
+		DeliveredAt: now,
+		NextRetryAt: ptr(now.Add(time.Minute)),
+	}
+	require.NoError(t, repo.SaveDelivery(ctx, delivery))
+
+	got, err := repo.GetDelivery(ctx, "d-1")
+	require.NoError(t, err)
+	assert.Equal(t, delivery, got)
+
+	// Saving a delivery again replaces it
+	delivery.Success = true
+	delivery.StatusCode = 200
+	delivery.NextRetryAt = nil
+	delivery.DeliveredAt = now.Add(time.Hour)
+	require.NoError(t, repo.SaveDelivery(ctx, delivery))
+	got, err = repo.GetDelivery(ctx, "d-1")
+	require.NoError(t, err)
+	assert.Equal(t, delivery, got)
+
+	require.NoError(t, repo.SaveDelivery(ctx, &WebhookDelivery{ID: "d-2", WebhookID: "wh-1", DeliveredAt: now}))
+	require.NoError(t, repo.SaveDelivery(ctx, &WebhookDelivery{ID: "d-3", WebhookID: "wh-2", DeliveredAt: now}))
+
+	deliveries, err := repo.ListDeliveries(ctx, "wh-1", DeliveryFilter{})
+	require.NoError(t, err)
+	require.Len(t, deliveries, 2)
+	assert.Equal(t, "d-1", deliveries[0].ID, "deliveries are listed most recent first")
+
+	failed := false
+	deliveries, err = repo.ListDeliveries(ctx, "wh-1", DeliveryFilter{Success: &failed})
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, "d-2", deliveries[0].ID)
+
+	_, err = repo.GetDelivery(ctx, "nonexistent")
+	assert.ErrorContains(t, err, "delivery nonexistent not found")
+
+	deleted, err := repo.DeleteOldDeliveries(ctx, now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), deleted)
+}
+
+func TestSQLRepository_ClaimFailedDeliveries(t *testing.T) {
+	repo := newSQLRepository(t)
+	ctx := context.Background()
+	now := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+
+	deliveries := []*WebhookDelivery{
+		{ID: "d-1", WebhookID: "wh-1", DeliveredAt: now, NextRetryAt: ptr(now.Add(-time.Minute))},
+		{ID: "d-2", WebhookID: "wh-1", DeliveredAt: now, NextRetryAt: ptr(now.Add(-2 * time.Minute))},
+		{ID: "d-3", WebhookID: "wh-1", DeliveredAt: now, NextRetryAt: ptr(now.Add(time.Minute))}, // Not due
+		{ID: "d-4", WebhookID: "wh-1", DeliveredAt: now, Success: true},
+		{ID: "d-5", WebhookID: "wh-1", DeliveredAt: now}, // Not retryable
+	}
+	for _, d := range deliveries {
+		require.NoError(t, repo.SaveDelivery(ctx, d))
+	}
+
+	claimed, err := repo.ClaimFailedDeliveries(ctx, now, 10, 5*time.Minute)
+	require.NoError(t, err)
+	require.Len(t, claimed, 2)
+	assert.Equal(t, "d-2", claimed[0].ID, "the longest due deliveries are claimed first")
+	assert.Equal(t, "d-1", claimed[1].ID)
+	assert.Equal(t, now.Add(5*time.Minute), *claimed[0].NextRetryAt)
+
+	claimed, err = repo.ClaimFailedDeliveries(ctx, now.Add(2*time.Minute), 10, 5*time.Minute)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1, "leased deliveries are not claimed again")
+	assert.Equal(t, "d-3", claimed[0].ID)
+
+	claimed, err = repo.ClaimFailedDeliveries(ctx, now.Add(6*time.Minute), 1, 5*time.Minute)
+	require.NoError(t, err)
+	assert.Len(t, claimed, 1, "deliveries are claimed again once their lease expires")
+
+	// GetFailedDeliveries still lists due deliveries without leasing them
+	require.NoError(t, repo.UpdateDeliveryRetry(ctx, "d-5", time.Now().Add(-time.Minute)))
+	failed, err := repo.GetFailedDeliveries(ctx, 10)
+	require.NoError(t, err)
+	assert.Len(t, failed, 4)
+}
+
+func TestSQLRepository_DisableFailingWebhook(t *testing.T) {
+	testDisableFailingWebhook(t, newSQLRepository(t))
+}
+
+func TestSQLRepository_DeadLetters(t *testing.T) {
+	testDeadLetters(t, newSQLRepository(t))
+}
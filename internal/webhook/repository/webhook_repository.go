@@ -7,33 +7,83 @@ import (
 	"time"
 
 	"github.com/bargom/codeai/internal/event/bus"
+	"github.com/bargom/codeai/internal/webhook/filter"
+	"github.com/bargom/codeai/pkg/integration/webhook"
 )
 
 // WebhookConfig represents a webhook subscription configuration.
 type WebhookConfig struct {
-	ID           string                 `json:"id" bson:"_id"`
-	URL          string                 `json:"url" bson:"url"`
-	Events       []bus.EventType        `json:"events" bson:"events"`
+	ID     string          `json:"id" bson:"_id"`
+	URL    string          `json:"url" bson:"url"`
+	Events []bus.EventType `json:"events" bson:"events"` // Event types or patterns such as order.*
+	// Filter is an expression events must match to be delivered, and
+	// Projection maps the fields of the delivered payload to expressions;
+	// see package filter.
+	Filter       string                 `json:"filter,omitempty" bson:"filter,omitempty"`
+	Projection   map[string]string      `json:"projection,omitempty" bson:"projection,omitempty"`
 	Secret       string                 `json:"-" bson:"secret"` // Hidden in JSON responses
 	Headers      map[string]string      `json:"headers,omitempty" bson:"headers,omitempty"`
+	Method       string                 `json:"method,omitempty" bson:"method,omitempty"` // Defaults to POST
+	RetryPolicy  *webhook.RetryPolicy   `json:"retry_policy,omitempty" bson:"retry_policy,omitempty"`
 	Active       bool                   `json:"active" bson:"active"`
 	CreatedAt    time.Time              `json:"created_at" bson:"created_at"`
 	UpdatedAt    time.Time              `json:"updated_at" bson:"updated_at"`
 	LastDelivery *time.Time             `json:"last_delivery,omitempty" bson:"last_delivery,omitempty"`
 	FailureCount int                    `json:"failure_count" bson:"failure_count"`
 	Metadata     map[string]interface{} `json:"metadata,omitempty" bson:"metadata,omitempty"`
+	// SignatureScheme is the scheme requests are signed with, such as
+	// security.SchemeStandard; empty means the HMAC scheme. While the secret
+	// is rotated, PreviousSecret signs requests too until it expires.
+	SignatureScheme         string     `json:"signature_scheme,omitempty" bson:"signature_scheme,omitempty"`
+	PreviousSecret          string     `json:"-" bson:"previous_secret,omitempty"`
+	PreviousSecretExpiresAt *time.Time `json:"previous_secret_expires_at,omitempty" bson:"previous_secret_expires_at,omitempty"`
+}
+
+// Subscribes reports whether the webhook receives events of eventType:
+// webhooks without events receive all events.
+func (c *WebhookConfig) Subscribes(eventType bus.EventType) bool {
+	if len(c.Events) == 0 {
+		return true
+	}
+	for _, pattern := range c.Events {
+		if filter.MatchEvent(string(pattern), eventType) {
+			return true
+		}
+	}
+	return false
+}
+
+// SigningSecrets returns the secrets requests are signed with at now: the
+// current secret and, within its grace period, the previous secret.
+func (c *WebhookConfig) SigningSecrets(now time.Time) []string {
+	var secrets []string
+	if c.Secret != "" {
+		secrets = append(secrets, c.Secret)
+	}
+	if c.PreviousSecret != "" && c.PreviousSecretExpiresAt != nil && now.Before(*c.PreviousSecretExpiresAt) {
+		secrets = append(secrets, c.PreviousSecret)
+	}
+	return secrets
 }
 
 // WebhookUpdate represents fields to update on a webhook.
 type WebhookUpdate struct {
 	URL          *string
 	Events       []bus.EventType
+	Filter       *string
+	Projection   map[string]string
 	Secret       *string
 	Headers      map[string]string
+	Method       *string
+	RetryPolicy  *webhook.RetryPolicy
 	Active       *bool
 	LastDelivery *time.Time
 	FailureCount *int
 	Metadata     map[string]interface{}
+	// The signature scheme and the previous secret of a rotation
+	SignatureScheme         *string
+	PreviousSecret          *string
+	PreviousSecretExpiresAt *time.Time
 }
 
 // WebhookFilter specifies criteria for filtering webhooks.
@@ -68,6 +118,16 @@ type DeliveryFilter struct {
 	Offset  int
 }
 
+// DeadLetterFilter specifies criteria for filtering dead letters: failed
+// deliveries that have no retry scheduled, because they exhausted their
+// retries or their webhook was disabled.
+type DeadLetterFilter struct {
+	WebhookID string    // Dead letters of all webhooks if empty
+	Since     time.Time // Dead letters delivered at or after; all if zero
+	Limit     int
+	Offset    int
+}
+
 // WebhookRepository defines the interface for webhook persistence.
 type WebhookRepository interface {
 	// CreateWebhook creates a new webhook configuration.
@@ -94,6 +154,11 @@ type WebhookRepository interface {
 	// ResetFailureCount resets the failure count for a webhook.
 	ResetFailureCount(ctx context.Context, webhookID string) error
 
+	// DisableFailingWebhook disables a webhook if it is active and its
+	// failure count reached maxFailures, and reports whether it did. The
+	// check and the update are atomic, so only one caller disables it.
+	DisableFailingWebhook(ctx context.Context, webhookID string, maxFailures int) (bool, error)
+
 	// Delivery tracking
 	// SaveDelivery persists a delivery attempt.
 	SaveDelivery(ctx context.Context, delivery *WebhookDelivery) error
@@ -107,9 +172,23 @@ type WebhookRepository interface {
 	// GetFailedDeliveries retrieves failed deliveries ready for retry.
 	GetFailedDeliveries(ctx context.Context, limit int) ([]WebhookDelivery, error)
 
+	// ClaimFailedDeliveries leases up to limit failed deliveries due for
+	// retry at now by moving their next retry time to now+lease, so that
+	// retry handlers on other instances skip them. A delivery whose retry
+	// is not saved before the lease expires is claimed again.
+	ClaimFailedDeliveries(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]WebhookDelivery, error)
+
 	// UpdateDeliveryRetry updates the next retry time for a delivery.
 	UpdateDeliveryRetry(ctx context.Context, deliveryID string, nextRetryAt time.Time) error
 
 	// DeleteOldDeliveries removes deliveries older than the specified time.
 	DeleteOldDeliveries(ctx context.Context, before time.Time) (int64, error)
+
+	// ListDeadLetters retrieves dead letters, most recent first.
+	ListDeadLetters(ctx context.Context, filter DeadLetterFilter) ([]WebhookDelivery, error)
+
+	// RedriveDeadLetters schedules the oldest dead letters matching the
+	// filter for retry at the given time with their attempts reset, and
+	// returns how many it scheduled. The offset of the filter is ignored.
+	RedriveDeadLetters(ctx context.Context, filter DeadLetterFilter, at time.Time) (int64, error)
 }
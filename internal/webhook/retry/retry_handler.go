@@ -23,6 +23,7 @@ type Config struct {
 	CheckInterval time.Duration // How often to check for retryable deliveries
 	BatchSize     int           // Max number of deliveries to retry per check
 	MaxRetries    int           // Maximum retry attempts
+	LeaseDuration time.Duration // How long a claimed delivery is hidden from other instances
 }
 
 // DefaultConfig returns a default retry handler configuration.
@@ -31,10 +32,15 @@ func DefaultConfig() Config {
 		CheckInterval: 1 * time.Minute,
 		BatchSize:     100,
 		MaxRetries:    5,
+		LeaseDuration: 5 * time.Minute,
 	}
 }
 
 // RetryHandler periodically checks for and retries failed webhook deliveries.
+// Deliveries are leased from the repository before they are retried, so
+// handlers on several instances sharing a repository never retry the same
+// delivery twice. The deliveries of an instance that stops mid-batch are
+// retried elsewhere once their lease expires.
 type RetryHandler struct {
 	repository     repository.WebhookRepository
 	webhookService *service.WebhookService
@@ -150,17 +156,17 @@ func (h *RetryHandler) run() {
 	}
 }
 
-// processRetries fetches and retries failed deliveries.
+// processRetries claims and retries failed deliveries.
 func (h *RetryHandler) processRetries() {
 	ctx := h.ctx
 	if ctx == nil {
 		ctx = context.Background()
 	}
 
-	failures, err := h.repository.GetFailedDeliveries(ctx, h.config.BatchSize)
+	failures, err := h.repository.ClaimFailedDeliveries(ctx, time.Now(), h.config.BatchSize, h.config.LeaseDuration)
 	if err != nil {
 		if h.logger != nil {
-			h.logger.Error("failed to get failed deliveries", "error", err.Error())
+			h.logger.Error("failed to claim failed deliveries", "error", err.Error())
 		}
 		return
 	}
@@ -239,7 +245,7 @@ func (h *RetryHandler) processRetries() {
 
 		// Check for cancellation between retries
 		select {
-		case <-h.ctx.Done():
+		case <-ctx.Done():
 			return
 		default:
 		}
@@ -3,6 +3,7 @@ package security
 
 import (
 	"crypto/hmac"
+	"crypto/rand"
 	"crypto/sha256"
 	"crypto/subtle"
 	"encoding/hex"
@@ -119,10 +120,10 @@ func GenerateSecret(length int) (string, error) {
 	}
 
 	bytes := make([]byte, length)
-	// Using a simple approach - in production, use crypto/rand
-	h := sha256.New()
-	h.Write(bytes)
-	return hex.EncodeToString(h.Sum(nil)[:length]), nil
+	if _, err := rand.Read(bytes); err != nil {
+		return "", fmt.Errorf("generating secret: %w", err)
+	}
+	return hex.EncodeToString(bytes), nil
 }
 
 // Signer wraps a secret and provides convenient signing methods.
@@ -0,0 +1,256 @@
+package security
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Signature schemes of webhook subscriptions.
+const (
+	// SchemeHMAC signs the payload with a hex HMAC-SHA256 in the
+	// X-Webhook-Signature header. It is the default scheme.
+	SchemeHMAC = "hmac-sha256"
+
+	// SchemeStandard signs requests as specified by Standard Webhooks
+	// (https://www.standardwebhooks.com) with the webhook-id,
+	// webhook-timestamp and webhook-signature headers.
+	SchemeStandard = "standard-webhooks"
+)
+
+const (
+	// StandardIDHeader is the Standard Webhooks header of the message ID,
+	// which is the same for every attempt to deliver a message.
+	StandardIDHeader = "webhook-id"
+
+	// StandardTimestampHeader is the Standard Webhooks header of the time
+	// of the attempt in Unix seconds.
+	StandardTimestampHeader = "webhook-timestamp"
+
+	// StandardSignatureHeader is the Standard Webhooks header of the
+	// space-delimited signatures, such as "v1,<base64>".
+	StandardSignatureHeader = "webhook-signature"
+
+	// StandardSecretPrefix prefixes base64-encoded Standard Webhooks secrets.
+	StandardSecretPrefix = "whsec_"
+
+	// PreviousSignatureHeader is the HTTP header of the signatures of the
+	// previous secrets of the HMAC scheme while secrets are rotated.
+	PreviousSignatureHeader = "X-Webhook-Signature-Previous"
+
+	// DefaultTolerance is how far the timestamp of a Standard Webhooks
+	// request may be from the receiver's clock.
+	DefaultTolerance = 5 * time.Minute
+
+	standardVersion = "v1"
+)
+
+var (
+	// ErrMissingSignature is returned when a request carries no signature
+	// headers of the scheme.
+	ErrMissingSignature = errors.New("missing webhook signature")
+
+	// ErrInvalidTimestamp is returned when a request's timestamp is not in
+	// Unix seconds or outside the tolerance.
+	ErrInvalidTimestamp = errors.New("invalid webhook timestamp")
+
+	// ErrSignatureMismatch is returned when none of a request's signatures
+	// match any of the secrets.
+	ErrSignatureMismatch = errors.New("no matching webhook signature")
+)
+
+// ValidateScheme checks a signature scheme name. The empty scheme is
+// SchemeHMAC.
+func ValidateScheme(scheme string) error {
+	switch scheme {
+	case "", SchemeHMAC, SchemeStandard:
+		return nil
+	default:
+		return fmt.Errorf("unknown signature scheme '%s' (expected %s or %s)", scheme, SchemeHMAC, SchemeStandard)
+	}
+}
+
+// GenerateSchemeSecret generates a secret for a signature scheme: a
+// "whsec_"-prefixed base64 key for SchemeStandard and a hex key otherwise.
+func GenerateSchemeSecret(scheme string) (string, error) {
+	if scheme != SchemeStandard {
+		return GenerateSecret(32)
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("generating secret: %w", err)
+	}
+	return StandardSecretPrefix + base64.StdEncoding.EncodeToString(key), nil
+}
+
+// standardKey returns the signing key of a Standard Webhooks secret. Secrets
+// with the "whsec_" prefix are base64-encoded; others are used as they are.
+func standardKey(secret string) ([]byte, error) {
+	if !strings.HasPrefix(secret, StandardSecretPrefix) {
+		return []byte(secret), nil
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(secret, StandardSecretPrefix))
+	if err != nil {
+		return nil, fmt.Errorf("invalid secret: %w", err)
+	}
+	return key, nil
+}
+
+// SignStandard returns the Standard Webhooks signature, "v1,<base64>", of a
+// message with the given ID and timestamp in Unix seconds.
+func SignStandard(secret, msgID string, timestamp int64, payload []byte) (string, error) {
+	key, err := standardKey(secret)
+	if err != nil {
+		return "", err
+	}
+	return standardVersion + "," + base64.StdEncoding.EncodeToString(standardMAC(key, msgID, timestamp, payload)), nil
+}
+
+func standardMAC(key []byte, msgID string, timestamp int64, payload []byte) []byte {
+	h := hmac.New(sha256.New, key)
+	fmt.Fprintf(h, "%s.%d.", msgID, timestamp)
+	h.Write(payload)
+	return h.Sum(nil)
+}
+
+// VerifyStandard verifies the Standard Webhooks headers of a request. The
+// request is valid if its timestamp is within tolerance of now and any of
+// its signatures matches any of the secrets, so that receivers can accept
+// both the old and new secret while secrets are rotated. A tolerance of
+// zero uses DefaultTolerance.
+func VerifyStandard(secrets []string, header http.Header, payload []byte, now time.Time, tolerance time.Duration) error {
+	msgID := header.Get(StandardIDHeader)
+	ts := header.Get(StandardTimestampHeader)
+	signatures := header.Get(StandardSignatureHeader)
+	if msgID == "" || ts == "" || signatures == "" {
+		return ErrMissingSignature
+	}
+
+	timestamp, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return ErrInvalidTimestamp
+	}
+	if tolerance <= 0 {
+		tolerance = DefaultTolerance
+	}
+	if diff := now.Sub(time.Unix(timestamp, 0)); diff > tolerance || diff < -tolerance {
+		return ErrInvalidTimestamp
+	}
+
+	for _, secret := range secrets {
+		key, err := standardKey(secret)
+		if err != nil {
+			return err
+		}
+		expected := standardMAC(key, msgID, timestamp, payload)
+		for _, signature := range strings.Fields(signatures) {
+			version, value, ok := strings.Cut(signature, ",")
+			if !ok || version != standardVersion {
+				continue
+			}
+			decoded, err := base64.StdEncoding.DecodeString(value)
+			if err != nil {
+				continue
+			}
+			if hmac.Equal(expected, decoded) {
+				return nil
+			}
+		}
+	}
+	return ErrSignatureMismatch
+}
+
+// VerifyHMAC verifies the HMAC scheme signatures of a request, including the
+// signatures of previous secrets sent while secrets are rotated.
+func VerifyHMAC(secrets []string, header http.Header, payload []byte) error {
+	signature, ok := ExtractSignature(header)
+	if !ok {
+		return ErrMissingSignature
+	}
+	signatures := append([]string{signature}, strings.Fields(header.Get(PreviousSignatureHeader))...)
+	for _, secret := range secrets {
+		for _, signature := range signatures {
+			if VerifySignature(secret, payload, signature) {
+				return nil
+			}
+		}
+	}
+	return ErrSignatureMismatch
+}
+
+// Verify verifies the signatures of a request of the given scheme against
+// any of the secrets. The tolerance applies to SchemeStandard timestamps.
+func Verify(scheme string, secrets []string, header http.Header, payload []byte, now time.Time, tolerance time.Duration) error {
+	if err := ValidateScheme(scheme); err != nil {
+		return err
+	}
+	if len(secrets) == 0 {
+		return fmt.Errorf("no secret to verify the signature with")
+	}
+	if scheme == SchemeStandard {
+		return VerifyStandard(secrets, header, payload, now, tolerance)
+	}
+	return VerifyHMAC(secrets, header, payload)
+}
+
+// RequestSigner signs outgoing webhook requests with a signature scheme. Its
+// first secret is the current one; further secrets are previous secrets
+// within their rotation grace period, which sign the request too.
+type RequestSigner struct {
+	scheme  string
+	secrets []string
+	now     func() time.Time
+}
+
+// NewRequestSigner creates a RequestSigner for a scheme and its secrets,
+// current first. Empty secrets are ignored.
+func NewRequestSigner(scheme string, secrets ...string) *RequestSigner {
+	s := &RequestSigner{scheme: scheme, now: time.Now}
+	for _, secret := range secrets {
+		if secret != "" {
+			s.secrets = append(s.secrets, secret)
+		}
+	}
+	return s
+}
+
+// SignRequest sets the signature headers of a request. The webhook ID is the
+// Standard Webhooks message ID.
+func (s *RequestSigner) SignRequest(header http.Header, webhookID string, payload []byte) error {
+	if len(s.secrets) == 0 {
+		return nil
+	}
+
+	if s.scheme != SchemeStandard {
+		AddSignatureHeaders(header, s.secrets[0], payload)
+		if len(s.secrets) > 1 {
+			previous := make([]string, 0, len(s.secrets)-1)
+			for _, secret := range s.secrets[1:] {
+				previous = append(previous, SignPayload(secret, payload))
+			}
+			header.Set(PreviousSignatureHeader, strings.Join(previous, " "))
+		}
+		return nil
+	}
+
+	timestamp := s.now().Unix()
+	signatures := make([]string, 0, len(s.secrets))
+	for _, secret := range s.secrets {
+		signature, err := SignStandard(secret, webhookID, timestamp, payload)
+		if err != nil {
+			return err
+		}
+		signatures = append(signatures, signature)
+	}
+	header.Set(StandardIDHeader, webhookID)
+	header.Set(StandardTimestampHeader, strconv.FormatInt(timestamp, 10))
+	header.Set(StandardSignatureHeader, strings.Join(signatures, " "))
+	return nil
+}
@@ -0,0 +1,107 @@
+package security
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The example of the Standard Webhooks specification
+const (
+	specSecret    = "whsec_MfKQ9r8GKYqrTwjUPD8ILPZIo2LaLaSw"
+	specID        = "msg_p5jXN8AQM9LWM0D4loKWxJek"
+	specTimestamp = 1614265330
+	specPayload   = `{"test": 2432232314}`
+	specSignature = "v1,g0hM9SsE+OTPJTGt/tmIKtSyZlE3uFJELVlNIOLJ1OE="
+)
+
+func TestSignStandard(t *testing.T) {
+	signature, err := SignStandard(specSecret, specID, specTimestamp, []byte(specPayload))
+	require.NoError(t, err)
+	assert.Equal(t, specSignature, signature)
+
+	_, err = SignStandard("whsec_not base64!", specID, specTimestamp, []byte(specPayload))
+	assert.ErrorContains(t, err, "invalid secret")
+}
+
+func TestVerifyStandard(t *testing.T) {
+	now := time.Unix(specTimestamp, 0).Add(time.Minute)
+	header := http.Header{}
+	header.Set(StandardIDHeader, specID)
+	header.Set(StandardTimestampHeader, "1614265330")
+	header.Set(StandardSignatureHeader, "v1,bm90IHRoaXMgb25l "+specSignature)
+
+	assert.NoError(t, VerifyStandard([]string{specSecret}, header, []byte(specPayload), now, 0), "any signature may match")
+	assert.NoError(t, VerifyStandard([]string{"whsec_b2xk", specSecret}, header, []byte(specPayload), now, 0), "any secret may match")
+
+	assert.ErrorIs(t, VerifyStandard([]string{specSecret}, header, []byte(`{"test": 1}`), now, 0), ErrSignatureMismatch)
+	assert.ErrorIs(t, VerifyStandard([]string{specSecret}, header, []byte(specPayload), now.Add(time.Hour), 0), ErrInvalidTimestamp)
+	assert.ErrorIs(t, VerifyStandard([]string{specSecret}, header, []byte(specPayload), now.Add(-time.Hour), 0), ErrInvalidTimestamp)
+	assert.NoError(t, VerifyStandard([]string{specSecret}, header, []byte(specPayload), now.Add(time.Hour), 2*time.Hour))
+
+	header.Del(StandardIDHeader)
+	assert.ErrorIs(t, VerifyStandard([]string{specSecret}, header, []byte(specPayload), now, 0), ErrMissingSignature)
+}
+
+func TestRequestSigner_Standard(t *testing.T) {
+	signer := NewRequestSigner(SchemeStandard, specSecret, "", "whsec_b2xk")
+	signer.now = func() time.Time { return time.Unix(specTimestamp, 0) }
+
+	header := http.Header{}
+	require.NoError(t, signer.SignRequest(header, specID, []byte(specPayload)))
+
+	assert.Equal(t, specID, header.Get(StandardIDHeader))
+	assert.Equal(t, "1614265330", header.Get(StandardTimestampHeader))
+	signatures := strings.Fields(header.Get(StandardSignatureHeader))
+	require.Len(t, signatures, 2, "the current and previous secret both sign")
+	assert.Equal(t, specSignature, signatures[0])
+
+	now := time.Unix(specTimestamp, 0)
+	assert.NoError(t, Verify(SchemeStandard, []string{"whsec_b2xk"}, header, []byte(specPayload), now, 0))
+	assert.Empty(t, header.Get(SignatureHeader))
+}
+
+func TestRequestSigner_HMAC(t *testing.T) {
+	payload := []byte(`{"event":"test"}`)
+
+	header := http.Header{}
+	require.NoError(t, NewRequestSigner("", "new-secret", "old-secret").SignRequest(header, "d-1", payload))
+
+	assert.Equal(t, SignPayload("new-secret", payload), header.Get(SignatureHeader))
+	assert.Equal(t, SignPayload("old-secret", payload), header.Get(PreviousSignatureHeader))
+	assert.NoError(t, Verify(SchemeHMAC, []string{"old-secret"}, header, payload, time.Now(), 0))
+	assert.NoError(t, Verify(SchemeHMAC, []string{"new-secret"}, header, payload, time.Now(), 0))
+	assert.ErrorIs(t, Verify(SchemeHMAC, []string{"other"}, header, payload, time.Now(), 0), ErrSignatureMismatch)
+
+	header = http.Header{}
+	require.NoError(t, NewRequestSigner(SchemeHMAC).SignRequest(header, "d-1", payload))
+	assert.Empty(t, header, "requests are not signed without a secret")
+}
+
+func TestGenerateSchemeSecret(t *testing.T) {
+	secret, err := GenerateSchemeSecret(SchemeStandard)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(secret, StandardSecretPrefix))
+	key, err := standardKey(secret)
+	require.NoError(t, err)
+	assert.Len(t, key, 32)
+
+	secret, err = GenerateSchemeSecret(SchemeHMAC)
+	require.NoError(t, err)
+	assert.Len(t, secret, 64)
+
+	other, err := GenerateSchemeSecret(SchemeHMAC)
+	require.NoError(t, err)
+	assert.NotEqual(t, secret, other)
+}
+
+func TestValidateScheme(t *testing.T) {
+	assert.NoError(t, ValidateScheme(""))
+	assert.NoError(t, ValidateScheme(SchemeHMAC))
+	assert.NoError(t, ValidateScheme(SchemeStandard))
+	assert.ErrorContains(t, ValidateScheme("rsa"), "unknown signature scheme")
+}
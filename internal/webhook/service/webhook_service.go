@@ -5,12 +5,15 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 
 	"github.com/bargom/codeai/internal/event/bus"
+	"github.com/bargom/codeai/internal/webhook/queue"
 	"github.com/bargom/codeai/internal/webhook/repository"
+	"github.com/bargom/codeai/internal/webhook/security"
 	"github.com/bargom/codeai/pkg/integration/webhook"
 )
 
@@ -22,17 +25,25 @@ type Logger interface {
 	Warn(msg string, args ...any)
 }
 
+// EventPublisher publishes the events of the webhook service, such as
+// webhook.disabled.
+type EventPublisher interface {
+	Dispatch(ctx context.Context, event bus.Event) error
+}
+
 // Config holds configuration for the webhook service.
 type Config struct {
-	MaxFailureCount int           // Disable webhook after this many consecutive failures
-	DefaultTimeout  time.Duration // Default timeout for webhook delivery
+	MaxFailureCount   int           // Disable webhook after this many consecutive failures; 0 never disables
+	DefaultTimeout    time.Duration // Default timeout for webhook delivery
+	SecretGracePeriod time.Duration // How long a rotated secret keeps signing requests
 }
 
 // DefaultConfig returns a default service configuration.
 func DefaultConfig() Config {
 	return Config{
-		MaxFailureCount: 10,
-		DefaultTimeout:  30 * time.Second,
+		MaxFailureCount:   10,
+		DefaultTimeout:    30 * time.Second,
+		SecretGracePeriod: 24 * time.Hour,
 	}
 }
 
@@ -40,8 +51,15 @@ func DefaultConfig() Config {
 type WebhookService struct {
 	client     *webhook.Client
 	repository repository.WebhookRepository
+	queue      *queue.DeliveryQueue
 	config     Config
 	logger     Logger
+	events     EventPublisher
+	// background counts the deliveries running without a queue
+	background sync.WaitGroup
+
+	mu        sync.RWMutex
+	listeners []func(webhookID string)
 }
 
 // NewWebhookService creates a new webhook service.
@@ -80,30 +98,62 @@ func WithConfig(cfg Config) Option {
 	}
 }
 
+// WithDeliveryQueue creates the queue, with cfg and opts, that asynchronous
+// deliveries and the deliveries of events are handed to, so that its limits
+// per webhook apply to them. The queue reports the outcome of its
+// deliveries to the service, so that failing webhooks are disabled
+// whichever way they are delivered. Start starts it and Stop drains it.
+func WithDeliveryQueue(cfg queue.Config, opts ...queue.Option) Option {
+	return func(s *WebhookService) {
+		opts = append([]queue.Option{queue.WithHealthTracker(s)}, opts...)
+		s.queue = queue.NewDeliveryQueue(s.client, s.repository, cfg, opts...)
+	}
+}
+
+// WithEventPublisher sets the publisher of the service's events.
+func WithEventPublisher(events EventPublisher) Option {
+	return func(s *WebhookService) {
+		s.events = events
+	}
+}
+
 // RegisterWebhookRequest represents a request to register a new webhook.
 type RegisterWebhookRequest struct {
-	URL      string                 `json:"url" validate:"required,url"`
-	Events   []bus.EventType        `json:"events"`
-	Secret   string                 `json:"secret,omitempty"`
-	Headers  map[string]string      `json:"headers,omitempty"`
-	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	URL        string                 `json:"url" validate:"required,url"`
+	Events     []bus.EventType        `json:"events"`
+	Filter     string                 `json:"filter,omitempty"`
+	Projection map[string]string      `json:"projection,omitempty"`
+	Secret     string                 `json:"secret,omitempty"`
+	Headers    map[string]string      `json:"headers,omitempty"`
+	Metadata   map[string]interface{} `json:"metadata,omitempty"`
+	// SignatureScheme is security.SchemeHMAC, the default, or
+	// security.SchemeStandard.
+	SignatureScheme string `json:"signature_scheme,omitempty"`
 }
 
 // RegisterWebhook creates a new webhook subscription.
 func (s *WebhookService) RegisterWebhook(ctx context.Context, req RegisterWebhookRequest) (string, error) {
+	if err := security.ValidateScheme(req.SignatureScheme); err != nil {
+		return "", err
+	}
+
 	webhookID := uuid.New().String()
 	now := time.Now()
 
 	config := &repository.WebhookConfig{
-		ID:        webhookID,
-		URL:       req.URL,
-		Events:    req.Events,
-		Secret:    req.Secret,
-		Headers:   req.Headers,
-		Active:    true,
-		CreatedAt: now,
-		UpdatedAt: now,
-		Metadata:  req.Metadata,
+		ID:         webhookID,
+		URL:        req.URL,
+		Events:     req.Events,
+		Filter:     req.Filter,
+		Projection: req.Projection,
+		Secret:     req.Secret,
+		Headers:    req.Headers,
+		Active:     true,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+		Metadata:   req.Metadata,
+
+		SignatureScheme: req.SignatureScheme,
 	}
 
 	if err := s.repository.CreateWebhook(ctx, config); err != nil {
@@ -133,28 +183,49 @@ func (s *WebhookService) ListWebhooks(ctx context.Context, filter repository.Web
 
 // UpdateWebhookRequest represents a request to update a webhook.
 type UpdateWebhookRequest struct {
-	URL      *string                `json:"url,omitempty"`
-	Events   []bus.EventType        `json:"events,omitempty"`
-	Secret   *string                `json:"secret,omitempty"`
-	Headers  map[string]string      `json:"headers,omitempty"`
-	Active   *bool                  `json:"active,omitempty"`
-	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	URL        *string                `json:"url,omitempty"`
+	Events     []bus.EventType        `json:"events,omitempty"`
+	Filter     *string                `json:"filter,omitempty"`
+	Projection map[string]string      `json:"projection,omitempty"`
+	Secret     *string                `json:"secret,omitempty"`
+	Headers    map[string]string      `json:"headers,omitempty"`
+	Active     *bool                  `json:"active,omitempty"`
+	Metadata   map[string]interface{} `json:"metadata,omitempty"`
+
+	SignatureScheme *string `json:"signature_scheme,omitempty"`
 }
 
-// UpdateWebhook updates a webhook configuration.
+// UpdateWebhook updates a webhook configuration. Enabling a webhook resets
+// its failure count, so that a webhook disabled for failing is not disabled
+// again by its next failure.
 func (s *WebhookService) UpdateWebhook(ctx context.Context, webhookID string, req UpdateWebhookRequest) error {
+	if req.SignatureScheme != nil {
+		if err := security.ValidateScheme(*req.SignatureScheme); err != nil {
+			return err
+		}
+	}
+
 	update := repository.WebhookUpdate{
-		URL:      req.URL,
-		Events:   req.Events,
-		Secret:   req.Secret,
-		Headers:  req.Headers,
-		Active:   req.Active,
-		Metadata: req.Metadata,
+		URL:        req.URL,
+		Events:     req.Events,
+		Filter:     req.Filter,
+		Projection: req.Projection,
+		Secret:     req.Secret,
+		Headers:    req.Headers,
+		Active:     req.Active,
+		Metadata:   req.Metadata,
+
+		SignatureScheme: req.SignatureScheme,
+	}
+	if req.Active != nil && *req.Active {
+		failureCount := 0
+		update.FailureCount = &failureCount
 	}
 
 	if err := s.repository.UpdateWebhook(ctx, webhookID, update); err != nil {
 		return fmt.Errorf("updating webhook: %w", err)
 	}
+	s.changed(webhookID)
 
 	if s.logger != nil {
 		s.logger.Info("webhook updated", "webhookID", webhookID)
@@ -163,11 +234,85 @@ func (s *WebhookService) UpdateWebhook(ctx context.Context, webhookID string, re
 	return nil
 }
 
+// RotateSecret replaces a webhook's secret with a newly generated one for
+// its signature scheme and returns it. The old secret keeps signing requests
+// next to the new one for gracePeriod, or the configured SecretGracePeriod
+// if it is zero, so receivers can switch to the new secret without
+// rejecting deliveries.
+func (s *WebhookService) RotateSecret(ctx context.Context, webhookID string, gracePeriod time.Duration) (string, time.Time, error) {
+	config, err := s.repository.GetWebhook(ctx, webhookID)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("getting webhook: %w", err)
+	}
+
+	secret, err := security.GenerateSchemeSecret(config.SignatureScheme)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if gracePeriod <= 0 {
+		gracePeriod = s.config.SecretGracePeriod
+	}
+	expiresAt := time.Now().Add(gracePeriod)
+
+	update := repository.WebhookUpdate{
+		Secret:                  &secret,
+		PreviousSecret:          &config.Secret,
+		PreviousSecretExpiresAt: &expiresAt,
+	}
+	if err := s.repository.UpdateWebhook(ctx, webhookID, update); err != nil {
+		return "", time.Time{}, fmt.Errorf("rotating webhook secret: %w", err)
+	}
+	s.changed(webhookID)
+
+	if s.logger != nil {
+		s.logger.Info("webhook secret rotated", "webhookID", webhookID, "previousSecretExpiresAt", expiresAt)
+	}
+
+	return secret, expiresAt, nil
+}
+
+// EnsureWebhook creates a webhook with config's ID, or updates the existing
+// one to match config. It is used for webhooks declared in the DSL, which
+// keep the same ID across restarts. An existing webhook's Active flag is
+// left as it is.
+func (s *WebhookService) EnsureWebhook(ctx context.Context, config *repository.WebhookConfig) error {
+	if _, err := s.repository.GetWebhook(ctx, config.ID); err != nil {
+		now := time.Now()
+		created := *config
+		created.CreatedAt = now
+		created.UpdatedAt = now
+		if err := s.repository.CreateWebhook(ctx, &created); err != nil {
+			return fmt.Errorf("creating webhook: %w", err)
+		}
+		return nil
+	}
+
+	headers := config.Headers
+	if headers == nil {
+		headers = map[string]string{}
+	}
+	update := repository.WebhookUpdate{
+		URL:         &config.URL,
+		Events:      config.Events,
+		Secret:      &config.Secret,
+		Headers:     headers,
+		Method:      &config.Method,
+		RetryPolicy: config.RetryPolicy,
+		Metadata:    config.Metadata,
+	}
+	if err := s.repository.UpdateWebhook(ctx, config.ID, update); err != nil {
+		return fmt.Errorf("updating webhook: %w", err)
+	}
+	s.changed(config.ID)
+	return nil
+}
+
 // DeleteWebhook removes a webhook subscription.
 func (s *WebhookService) DeleteWebhook(ctx context.Context, webhookID string) error {
 	if err := s.repository.DeleteWebhook(ctx, webhookID); err != nil {
 		return fmt.Errorf("deleting webhook: %w", err)
 	}
+	s.changed(webhookID)
 
 	if s.logger != nil {
 		s.logger.Info("webhook deleted", "webhookID", webhookID)
@@ -176,14 +321,74 @@ func (s *WebhookService) DeleteWebhook(ctx context.Context, webhookID string) er
 	return nil
 }
 
-// DeliverWebhooksForEvent sends webhooks to all subscribed endpoints for an event.
-func (s *WebhookService) DeliverWebhooksForEvent(ctx context.Context, event bus.Event) error {
-	webhooks, err := s.repository.GetWebhooksByEvent(ctx, event.Type)
+// OnChange registers fn to be called with the ID of each webhook the
+// service updates, disables or deletes, so that what is cached about it can
+// be dropped. Changes made by other instances are not reported.
+func (s *WebhookService) OnChange(fn func(webhookID string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listeners = append(s.listeners, fn)
+}
+
+// changed calls the OnChange functions for webhookID.
+func (s *WebhookService) changed(webhookID string) {
+	s.mu.RLock()
+	listeners := s.listeners
+	s.mu.RUnlock()
+	for _, fn := range listeners {
+		fn(webhookID)
+	}
+}
+
+// WebhooksForEvent returns the active webhooks subscribed to an event.
+// Webhooks declared in the DSL are left out: they are delivered by their
+// "do webhook" event handlers, and would otherwise receive events twice.
+// No webhooks are returned for events replayed from the event store.
+func (s *WebhookService) WebhooksForEvent(ctx context.Context, event bus.Event) ([]repository.WebhookConfig, error) {
+	if s.suppressed(ctx, event) {
+		return nil, nil
+	}
+
+	subscribed, err := s.repository.GetWebhooksByEvent(ctx, event.Type)
 	if err != nil {
-		return fmt.Errorf("getting webhooks for event: %w", err)
+		return nil, fmt.Errorf("getting webhooks for event: %w", err)
 	}
+	webhooks := make([]repository.WebhookConfig, 0, len(subscribed))
+	for _, wh := range subscribed {
+		if wh.Metadata["source"] != "dsl" {
+			webhooks = append(webhooks, wh)
+		}
+	}
+	return webhooks, nil
+}
 
-	if len(webhooks) == 0 {
+// DeliverEvent sends an event to a webhook returned by WebhooksForEvent and
+// records the delivery. With a delivery queue, the delivery is handed to the
+// queue and DeliverEvent returns without waiting.
+func (s *WebhookService) DeliverEvent(ctx context.Context, config *repository.WebhookConfig, event bus.Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("marshaling event: %w", err)
+	}
+	if s.queue != nil {
+		return s.enqueue(config, event, payload)
+	}
+	return s.deliverToWebhook(ctx, config, event, payload)
+}
+
+// DeliverWebhook sends an event to a single webhook. If async is true the
+// delivery is handed to the delivery queue, or run in the background when
+// the service has no queue, and DeliverWebhook returns without waiting.
+// Nothing is sent for events replayed from the event store.
+func (s *WebhookService) DeliverWebhook(ctx context.Context, webhookID string, event bus.Event, async bool) error {
+	config, err := s.repository.GetWebhook(ctx, webhookID)
+	if err != nil {
+		return fmt.Errorf("getting webhook: %w", err)
+	}
+	if !config.Active {
+		return fmt.Errorf("webhook %s is disabled", config.ID)
+	}
+	if s.suppressed(ctx, event) {
 		return nil
 	}
 
@@ -192,37 +397,109 @@ func (s *WebhookService) DeliverWebhooksForEvent(ctx context.Context, event bus.
 		return fmt.Errorf("marshaling event: %w", err)
 	}
 
-	for _, wh := range webhooks {
-		if err := s.deliverToWebhook(ctx, &wh, event, payload); err != nil {
-			if s.logger != nil {
-				s.logger.Error("webhook delivery failed",
-					"webhookID", wh.ID,
-					"url", wh.URL,
-					"eventID", event.ID,
-					"error", err.Error(),
-				)
-			}
-			// Continue to other webhooks despite error
-		}
+	if !async {
+		return s.deliverToWebhook(ctx, config, event, payload)
 	}
 
+	if s.queue != nil {
+		return s.enqueue(config, event, payload)
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	s.background.Add(1)
+	go func() {
+		defer s.background.Done()
+		if err := s.deliverToWebhook(ctx, config, event, payload); err != nil && s.logger != nil {
+			s.logger.Error("webhook delivery failed",
+				"webhookID", config.ID,
+				"url", config.URL,
+				"eventID", event.ID,
+				"error", err.Error(),
+			)
+		}
+	}()
+	return nil
+}
+
+// enqueue hands the delivery of event to config to the delivery queue.
+func (s *WebhookService) enqueue(config *repository.WebhookConfig, event bus.Event, payload []byte) error {
+	item := queue.DeliveryItem{
+		Webhook:   s.newWebhook(uuid.New().String(), config, event, payload),
+		WebhookID: config.ID,
+		EventID:   event.ID,
+	}
+	if !s.queue.Enqueue(item) {
+		return fmt.Errorf("delivery queue rejected webhook %s", config.ID)
+	}
 	return nil
 }
 
+// Start starts the workers of the delivery queue, if the service has one.
+func (s *WebhookService) Start(ctx context.Context) {
+	if s.queue != nil {
+		s.queue.Start(ctx)
+	}
+}
+
+// Stop drains and stops the delivery queue, if the service has one, and
+// waits for the deliveries running in the background.
+func (s *WebhookService) Stop() {
+	if s.queue != nil {
+		s.queue.Stop()
+	}
+	s.background.Wait()
+}
+
+// Wait waits for the deliveries DeliverWebhook runs in the background,
+// when the service has no delivery queue, to finish.
+func (s *WebhookService) Wait() {
+	s.background.Wait()
+}
+
+// suppressed reports whether webhooks for event are suppressed because it
+// is being replayed.
+func (s *WebhookService) suppressed(ctx context.Context, event bus.Event) bool {
+	if !bus.IsReplay(ctx) {
+		return false
+	}
+	if s.logger != nil {
+		s.logger.Debug("suppressing webhooks during replay", "eventID", event.ID, "eventType", string(event.Type))
+	}
+	return true
+}
+
+// signer returns the signer of requests to config, which signs with the
+// previous secret too during a rotation, or nil if it has no secret.
+func (s *WebhookService) signer(config *repository.WebhookConfig) webhook.Signer {
+	secrets := config.SigningSecrets(time.Now())
+	if len(secrets) == 0 {
+		return nil
+	}
+	return security.NewRequestSigner(config.SignatureScheme, secrets...)
+}
+
+// newWebhook builds the client request delivering payload to config.
+func (s *WebhookService) newWebhook(deliveryID string, config *repository.WebhookConfig, event bus.Event, payload []byte) *webhook.Webhook {
+	return &webhook.Webhook{
+		ID:          deliveryID,
+		URL:         config.URL,
+		Method:      config.Method,
+		EventType:   string(event.Type),
+		EventID:     event.ID,
+		Payload:     payload,
+		Headers:     config.Headers,
+		Secret:      config.Secret,
+		Signer:      s.signer(config),
+		Timeout:     s.config.DefaultTimeout,
+		RetryPolicy: config.RetryPolicy,
+	}
+}
+
 // deliverToWebhook sends an event to a specific webhook and records the delivery.
 func (s *WebhookService) deliverToWebhook(ctx context.Context, config *repository.WebhookConfig, event bus.Event, payload []byte) error {
 	deliveryID := uuid.New().String()
 
-	wh := &webhook.Webhook{
-		ID:        deliveryID,
-		URL:       config.URL,
-		EventType: string(event.Type),
-		EventID:   event.ID,
-		Payload:   payload,
-		Headers:   config.Headers,
-		Secret:    config.Secret,
-		Timeout:   s.config.DefaultTimeout,
-	}
+	wh := s.newWebhook(deliveryID, config, event, payload)
 
 	result, err := s.client.Send(ctx, wh)
 
@@ -256,37 +533,8 @@ func (s *WebhookService) deliverToWebhook(ctx context.Context, config *repositor
 	}
 
 	// Update webhook stats
-	now := time.Now()
-	if delivery.Success {
-		if err := s.repository.ResetFailureCount(ctx, config.ID); err != nil {
-			if s.logger != nil {
-				s.logger.Error("failed to reset failure count", "webhookID", config.ID, "error", err.Error())
-			}
-		}
-		if err := s.repository.UpdateWebhook(ctx, config.ID, repository.WebhookUpdate{
-			LastDelivery: &now,
-		}); err != nil {
-			if s.logger != nil {
-				s.logger.Error("failed to update last delivery", "webhookID", config.ID, "error", err.Error())
-			}
-		}
-	} else {
-		if err := s.repository.IncrementFailureCount(ctx, config.ID); err != nil {
-			if s.logger != nil {
-				s.logger.Error("failed to increment failure count", "webhookID", config.ID, "error", err.Error())
-			}
-		}
-
-		// Check if webhook should be disabled
-		wh, _ := s.repository.GetWebhook(ctx, config.ID)
-		if wh != nil && wh.FailureCount >= s.config.MaxFailureCount {
-			if disableErr := s.DisableWebhook(ctx, config.ID); disableErr != nil {
-				if s.logger != nil {
-					s.logger.Error("failed to disable webhook", "webhookID", config.ID, "error", disableErr.Error())
-				}
-			}
-		}
-
+	s.RecordDelivery(ctx, config.ID, delivery.Success)
+	if !delivery.Success {
 		// Schedule retry
 		retryPolicy := webhook.DefaultRetryPolicy()
 		nextRetryAt := time.Now().Add(retryPolicy.CalculateBackoff(delivery.Attempts))
@@ -317,14 +565,17 @@ func (s *WebhookService) RetryFailedWebhook(ctx context.Context, deliveryID stri
 	}
 
 	wh := &webhook.Webhook{
-		ID:        delivery.ID,
-		URL:       config.URL,
-		EventType: string(delivery.EventType),
-		EventID:   delivery.EventID,
-		Payload:   delivery.RequestBody,
-		Headers:   config.Headers,
-		Secret:    config.Secret,
-		Timeout:   s.config.DefaultTimeout,
+		ID:          delivery.ID,
+		URL:         config.URL,
+		Method:      config.Method,
+		EventType:   string(delivery.EventType),
+		EventID:     delivery.EventID,
+		Payload:     delivery.RequestBody,
+		Headers:     config.Headers,
+		Secret:      config.Secret,
+		Signer:      s.signer(config),
+		Timeout:     s.config.DefaultTimeout,
+		RetryPolicy: config.RetryPolicy,
 	}
 
 	result, sendErr := s.client.Send(ctx, wh)
@@ -360,6 +611,7 @@ func (s *WebhookService) RetryFailedWebhook(ctx context.Context, deliveryID stri
 			s.logger.Error("failed to update delivery", "deliveryID", deliveryID, "error", saveErr.Error())
 		}
 	}
+	s.RecordDelivery(ctx, config.ID, delivery.Success)
 
 	if s.logger != nil {
 		s.logger.Info("retry attempted",
@@ -372,6 +624,91 @@ func (s *WebhookService) RetryFailedWebhook(ctx context.Context, deliveryID stri
 	return sendErr
 }
 
+// RecordDelivery updates the failure count of a webhook after a delivery:
+// a success resets it and a failure increments it. A webhook whose count of
+// consecutive failures reaches MaxFailureCount is disabled, and a
+// webhook.disabled event is published. The retries of its failed
+// deliveries are dropped, which leaves them as dead letters.
+func (s *WebhookService) RecordDelivery(ctx context.Context, webhookID string, success bool) {
+	if success {
+		if err := s.repository.ResetFailureCount(ctx, webhookID); err != nil {
+			if s.logger != nil {
+				s.logger.Error("failed to reset failure count", "webhookID", webhookID, "error", err.Error())
+			}
+		}
+		now := time.Now()
+		if err := s.repository.UpdateWebhook(ctx, webhookID, repository.WebhookUpdate{
+			LastDelivery: &now,
+		}); err != nil {
+			if s.logger != nil {
+				s.logger.Error("failed to update last delivery", "webhookID", webhookID, "error", err.Error())
+			}
+		}
+		return
+	}
+
+	if err := s.repository.IncrementFailureCount(ctx, webhookID); err != nil {
+		if s.logger != nil {
+			s.logger.Error("failed to increment failure count", "webhookID", webhookID, "error", err.Error())
+		}
+		return
+	}
+	if s.config.MaxFailureCount <= 0 {
+		return
+	}
+
+	disabled, err := s.repository.DisableFailingWebhook(ctx, webhookID, s.config.MaxFailureCount)
+	if err != nil {
+		if s.logger != nil {
+			s.logger.Error("failed to disable webhook", "webhookID", webhookID, "error", err.Error())
+		}
+		return
+	}
+	if !disabled {
+		return
+	}
+	s.changed(webhookID)
+
+	config, err := s.repository.GetWebhook(ctx, webhookID)
+	if err != nil {
+		if s.logger != nil {
+			s.logger.Error("failed to get disabled webhook", "webhookID", webhookID, "error", err.Error())
+		}
+		return
+	}
+	if s.logger != nil {
+		s.logger.Warn("webhook disabled due to failures",
+			"webhookID", webhookID,
+			"url", config.URL,
+			"failureCount", config.FailureCount,
+		)
+	}
+	s.publishDisabled(ctx, config)
+}
+
+// publishDisabled publishes the webhook.disabled event of a webhook
+// disabled for failing.
+func (s *WebhookService) publishDisabled(ctx context.Context, config *repository.WebhookConfig) {
+	if s.events == nil {
+		return
+	}
+
+	event := bus.Event{
+		ID:        uuid.New().String(),
+		Type:      bus.EventWebhookDisabled,
+		Source:    "codeai.webhook",
+		Timestamp: time.Now(),
+		Data: map[string]interface{}{
+			"webhook_id":    config.ID,
+			"url":           config.URL,
+			"failure_count": config.FailureCount,
+		},
+	}
+	if err := s.events.Dispatch(ctx, event); err != nil && s.logger != nil {
+		s.logger.Error("failed to publish webhook disabled event", "webhookID", config.ID, "error", err.Error())
+	}
+}
+
 // DisableWebhook disables a webhook that has been failing.
 func (s *WebhookService) DisableWebhook(ctx context.Context, webhookID string) error {
 	active := false
@@ -380,6 +717,7 @@ func (s *WebhookService) DisableWebhook(ctx context.Context, webhookID string) e
 	}); err != nil {
 		return fmt.Errorf("disabling webhook: %w", err)
 	}
+	s.changed(webhookID)
 
 	if s.logger != nil {
 		s.logger.Warn("webhook disabled due to failures", "webhookID", webhookID)
@@ -398,6 +736,39 @@ func (s *WebhookService) GetDeliveries(ctx context.Context, webhookID string, fi
 	return s.repository.ListDeliveries(ctx, webhookID, filter)
 }
 
+// GetDeadLetters retrieves the failed deliveries that will not be retried,
+// because they exhausted their retries or their webhook was disabled.
+func (s *WebhookService) GetDeadLetters(ctx context.Context, filter repository.DeadLetterFilter) ([]repository.WebhookDelivery, error) {
+	return s.repository.ListDeadLetters(ctx, filter)
+}
+
+// RedriveDeadLetters schedules up to limit dead letters of a webhook
+// delivered since the given time for an immediate retry, oldest first, and
+// returns how many it scheduled. A limit of 0 redrives them all. The retry
+// handler sends them again with their attempts reset. The webhook must be
+// active, so a disabled webhook is enabled before it is redriven.
+func (s *WebhookService) RedriveDeadLetters(ctx context.Context, webhookID string, since time.Time, limit int) (int64, error) {
+	config, err := s.repository.GetWebhook(ctx, webhookID)
+	if err != nil {
+		return 0, fmt.Errorf("getting webhook: %w", err)
+	}
+	if !config.Active {
+		return 0, fmt.Errorf("webhook %s is disabled", config.ID)
+	}
+
+	filter := repository.DeadLetterFilter{WebhookID: webhookID, Since: since, Limit: limit}
+	redriven, err := s.repository.RedriveDeadLetters(ctx, filter, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("redriving dead letters: %w", err)
+	}
+
+	if s.logger != nil {
+		s.logger.Info("dead letters redriven", "webhookID", webhookID, "since", since, "count", redriven)
+	}
+
+	return redriven, nil
+}
+
 // SendTestWebhook sends a test event to a webhook to verify it's working.
 func (s *WebhookService) SendTestWebhook(ctx context.Context, webhookID string) (*repository.WebhookDelivery, error) {
 	config, err := s.repository.GetWebhook(ctx, webhookID)
@@ -434,6 +805,7 @@ func (s *WebhookService) SendTestWebhook(ctx context.Context, webhookID string)
 		Payload:   payload,
 		Headers:   config.Headers,
 		Secret:    config.Secret,
+		Signer:    s.signer(config),
 		Timeout:   s.config.DefaultTimeout,
 	}
 
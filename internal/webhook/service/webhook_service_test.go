@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bargom/codeai/internal/event/bus"
+	"github.com/bargom/codeai/internal/webhook/queue"
+	"github.com/bargom/codeai/internal/webhook/repository"
+	"github.com/bargom/codeai/pkg/integration/webhook"
+)
+
+type eventRecorder struct {
+	events []bus.Event
+}
+
+func (r *eventRecorder) Dispatch(ctx context.Context, event bus.Event) error {
+	r.events = append(r.events, event)
+	return nil
+}
+
+func TestWebhookService_AutoDisable(t *testing.T) {
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer endpoint.Close()
+
+	ctx := context.Background()
+	repo := repository.NewMemoryRepository()
+	require.NoError(t, repo.CreateWebhook(ctx, &repository.WebhookConfig{
+		ID:          "wh-1",
+		URL:         endpoint.URL,
+		Active:      true,
+		RetryPolicy: &webhook.RetryPolicy{MaxAttempts: 1},
+	}))
+
+	events := &eventRecorder{}
+	svc := NewWebhookService(webhook.NewClient(webhook.DefaultConfig()), repo,
+		WithConfig(Config{MaxFailureCount: 2, DefaultTimeout: time.Second}),
+		WithEventPublisher(events),
+	)
+
+	event := bus.Event{ID: "evt-1", Type: "order.created"}
+	assert.Error(t, svc.DeliverWebhook(ctx, "wh-1", event, false))
+	assert.Empty(t, events.events)
+	assert.Error(t, svc.DeliverWebhook(ctx, "wh-1", event, false))
+
+	config, err := repo.GetWebhook(ctx, "wh-1")
+	require.NoError(t, err)
+	assert.False(t, config.Active)
+	require.Len(t, events.events, 1)
+	assert.Equal(t, bus.EventWebhookDisabled, events.events[0].Type)
+	assert.Equal(t, "wh-1", events.events[0].Data["webhook_id"])
+	assert.Equal(t, 2, events.events[0].Data["failure_count"])
+
+	assert.ErrorContains(t, svc.DeliverWebhook(ctx, "wh-1", event, false), "disabled")
+	svc.RecordDelivery(ctx, "wh-1", false)
+	assert.Len(t, events.events, 1, "a disabled webhook is not disabled again")
+
+	// Re-enabled webhooks start counting failures from zero
+	active := true
+	require.NoError(t, svc.UpdateWebhook(ctx, "wh-1", UpdateWebhookRequest{Active: &active}))
+	svc.RecordDelivery(ctx, "wh-1", false)
+	config, err = repo.GetWebhook(ctx, "wh-1")
+	require.NoError(t, err)
+	assert.True(t, config.Active)
+	assert.Equal(t, 1, config.FailureCount)
+}
+
+func TestWebhookService_AutoDisableOff(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryRepository()
+	require.NoError(t, repo.CreateWebhook(ctx, &repository.WebhookConfig{ID: "wh-1", Active: true}))
+
+	svc := NewWebhookService(webhook.NewClient(webhook.DefaultConfig()), repo, WithConfig(Config{}))
+	for i := 0; i < 20; i++ {
+		svc.RecordDelivery(ctx, "wh-1", false)
+	}
+
+	config, err := repo.GetWebhook(ctx, "wh-1")
+	require.NoError(t, err)
+	assert.True(t, config.Active, "webhooks are never disabled without a maximum failure count")
+	assert.Equal(t, 20, config.FailureCount)
+}
+
+func TestWebhookService_WaitForBackgroundDeliveries(t *testing.T) {
+	release := make(chan struct{})
+	var received atomic.Int32
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		received.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer endpoint.Close()
+
+	ctx := context.Background()
+	repo := repository.NewMemoryRepository()
+	require.NoError(t, repo.CreateWebhook(ctx, &repository.WebhookConfig{ID: "wh-1", URL: endpoint.URL, Active: true}))
+
+	svc := NewWebhookService(webhook.NewClient(webhook.DefaultConfig()), repo)
+	require.NoError(t, svc.DeliverWebhook(ctx, "wh-1", bus.Event{ID: "evt-1", Type: "order.created"}, true))
+
+	done := make(chan struct{})
+	go func() {
+		svc.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("Wait returned before the delivery finished")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Wait did not return after the delivery finished")
+	}
+	assert.Equal(t, int32(1), received.Load())
+}
+
+func TestWebhookService_OnChange(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryRepository()
+	require.NoError(t, repo.CreateWebhook(ctx, &repository.WebhookConfig{ID: "wh-1", Active: true}))
+	require.NoError(t, repo.CreateWebhook(ctx, &repository.WebhookConfig{ID: "wh-2", Active: true}))
+
+	svc := NewWebhookService(webhook.NewClient(webhook.DefaultConfig()), repo)
+	var changed []string
+	svc.OnChange(func(webhookID string) {
+		changed = append(changed, webhookID)
+	})
+
+	filter := `payload.total > 100`
+	require.NoError(t, svc.UpdateWebhook(ctx, "wh-1", UpdateWebhookRequest{Filter: &filter}))
+	require.NoError(t, svc.DisableWebhook(ctx, "wh-2"))
+	require.NoError(t, svc.DeleteWebhook(ctx, "wh-1"))
+	assert.Equal(t, []string{"wh-1", "wh-2", "wh-1"}, changed)
+
+	assert.Error(t, svc.DeleteWebhook(ctx, "wh-1"))
+	assert.Len(t, changed, 3, "failed changes are not reported")
+}
+
+func TestWebhookService_DeliverEventThroughQueue(t *testing.T) {
+	var inFlight, maxInFlight, received atomic.Int32
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		for {
+			max := maxInFlight.Load()
+			if n <= max || maxInFlight.CompareAndSwap(max, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		inFlight.Add(-1)
+		received.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer endpoint.Close()
+
+	ctx := context.Background()
+	repo := repository.NewMemoryRepository()
+	config := &repository.WebhookConfig{ID: "wh-1", URL: endpoint.URL, Active: true}
+	require.NoError(t, repo.CreateWebhook(ctx, config))
+
+	svc := NewWebhookService(webhook.NewClient(webhook.DefaultConfig()), repo,
+		WithDeliveryQueue(queue.Config{WorkerCount: 4, EndpointConcurrency: 1}),
+	)
+	svc.Start(ctx)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, svc.DeliverEvent(ctx, config, bus.Event{ID: "evt-" + strconv.Itoa(i), Type: "order.created"}))
+	}
+	svc.Stop()
+
+	assert.Equal(t, int32(3), received.Load(), "Stop drains the queue")
+	assert.Equal(t, int32(1), maxInFlight.Load(), "the queue's limit per webhook applies to event deliveries")
+
+	deliveries, err := repo.ListDeliveries(ctx, "wh-1", repository.DeliveryFilter{})
+	require.NoError(t, err)
+	assert.Len(t, deliveries, 3)
+}
@@ -3,16 +3,36 @@ package subscriber
 
 import (
 	"context"
+	"reflect"
+	"sync"
 
 	"github.com/bargom/codeai/internal/event/bus"
+	"github.com/bargom/codeai/internal/webhook/filter"
+	"github.com/bargom/codeai/internal/webhook/repository"
 	"github.com/bargom/codeai/internal/webhook/service"
 )
 
 // WebhookEventSubscriber listens to events and triggers webhook deliveries.
+// Each webhook receives the events that match its filter, with the payload
+// its projection selects.
 type WebhookEventSubscriber struct {
 	webhookService *service.WebhookService
 	eventTypes     []bus.EventType
 	logger         Logger
+
+	mu    sync.Mutex
+	rules map[string]compiledRule // By webhook ID
+}
+
+// compiledRule caches the rule compiled from a webhook's filter and
+// projection until they change. Rules are evicted when the webhook service
+// updates or deletes their webhook; a webhook changed by another instance
+// is recompiled when its filter or projection no longer match.
+type compiledRule struct {
+	filter     string
+	projection map[string]string
+	rule       *filter.Rule
+	err        error
 }
 
 // Logger defines the logging interface for the subscriber.
@@ -56,16 +76,21 @@ func NewWebhookEventSubscriber(svc *service.WebhookService, opts ...Option) *Web
 			bus.EventAgentExecuted,
 			bus.EventTestSuiteCompleted,
 		},
+		rules: make(map[string]compiledRule),
 	}
 
 	for _, opt := range opts {
 		opt(s)
 	}
+	if svc != nil {
+		svc.OnChange(s.evict)
+	}
 
 	return s
 }
 
-// Handle processes an event and delivers it to subscribed webhooks.
+// Handle processes an event and delivers it to subscribed webhooks whose
+// filter it matches. A failed delivery does not stop the others.
 func (s *WebhookEventSubscriber) Handle(ctx context.Context, event bus.Event) error {
 	if s.logger != nil {
 		s.logger.Debug("handling event for webhooks",
@@ -74,7 +99,8 @@ func (s *WebhookEventSubscriber) Handle(ctx context.Context, event bus.Event) er
 		)
 	}
 
-	if err := s.webhookService.DeliverWebhooksForEvent(ctx, event); err != nil {
+	webhooks, err := s.webhookService.WebhooksForEvent(ctx, event)
+	if err != nil {
 		if s.logger != nil {
 			s.logger.Error("failed to deliver webhooks for event",
 				"eventType", string(event.Type),
@@ -85,9 +111,68 @@ func (s *WebhookEventSubscriber) Handle(ctx context.Context, event bus.Event) er
 		return err
 	}
 
+	for _, wh := range webhooks {
+		delivered, ok := s.apply(&wh, event)
+		if !ok {
+			continue
+		}
+		if err := s.webhookService.DeliverEvent(ctx, &wh, delivered); err != nil && s.logger != nil {
+			s.logger.Error("webhook delivery failed",
+				"webhookID", wh.ID,
+				"url", wh.URL,
+				"eventID", event.ID,
+				"error", err.Error(),
+			)
+		}
+	}
+
 	return nil
 }
 
+// apply returns the event to deliver to wh and whether wh receives it.
+// Events are not delivered to webhooks whose rule cannot be compiled or
+// evaluated against the event.
+func (s *WebhookEventSubscriber) apply(wh *repository.WebhookConfig, event bus.Event) (bus.Event, bool) {
+	rule, err := s.rule(wh)
+	if err == nil {
+		var matched bool
+		event, matched, err = rule.Apply(event)
+		if err == nil {
+			return event, matched
+		}
+	}
+
+	if s.logger != nil {
+		s.logger.Warn("skipping webhook",
+			"webhookID", wh.ID,
+			"eventID", event.ID,
+			"error", err.Error(),
+		)
+	}
+	return event, false
+}
+
+// rule returns the compiled rule of wh.
+func (s *WebhookEventSubscriber) rule(wh *repository.WebhookConfig) (*filter.Rule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cached, ok := s.rules[wh.ID]
+	if !ok || cached.filter != wh.Filter || !reflect.DeepEqual(cached.projection, wh.Projection) {
+		rule, err := filter.Compile(wh.Filter, wh.Projection)
+		cached = compiledRule{filter: wh.Filter, projection: wh.Projection, rule: rule, err: err}
+		s.rules[wh.ID] = cached
+	}
+	return cached.rule, cached.err
+}
+
+// evict drops the cached rule of a webhook.
+func (s *WebhookEventSubscriber) evict(webhookID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.rules, webhookID)
+}
+
 // SubscribedEvents returns the event types this subscriber handles.
 func (s *WebhookEventSubscriber) SubscribedEvents() []bus.EventType {
 	return s.eventTypes
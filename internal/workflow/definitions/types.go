@@ -15,6 +15,7 @@ const (
 	StatusCompleted Status = "completed"
 	StatusFailed    Status = "failed"
 	StatusCanceled  Status = "canceled"
+	StatusSkipped   Status = "skipped"
 )
 
 // RetryConfig defines retry behavior for workflows and activities.
@@ -22,6 +22,7 @@ func TestStatusConstants(t *testing.T) {
 	assert.Equal(t, Status("completed"), StatusCompleted)
 	assert.Equal(t, Status("failed"), StatusFailed)
 	assert.Equal(t, Status("canceled"), StatusCanceled)
+	assert.Equal(t, Status("skipped"), StatusSkipped)
 }
 
 func TestAgentConfig(t *testing.T) {
@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"time"
 
 	"go.temporal.io/sdk/temporal"
@@ -12,6 +13,7 @@ import (
 
 	"github.com/bargom/codeai/internal/ast"
 	"github.com/bargom/codeai/internal/workflow/definitions"
+	"github.com/bargom/codeai/internal/workflow/expr"
 )
 
 // DSLWorkflowConfig holds configuration for a DSL-loaded workflow.
@@ -155,8 +157,17 @@ func convertWorkflowStep(step *ast.WorkflowStep) (DSLWorkflowStep, error) {
 		Input:     make(map[string]string),
 	}
 
+	if step.Condition != "" {
+		if _, err := expr.Compile(step.Condition); err != nil {
+			return DSLWorkflowStep{}, fmt.Errorf("invalid condition %q: %w", step.Condition, err)
+		}
+	}
+
 	// Convert input mappings
 	for _, mapping := range step.Input {
+		if _, err := expr.CompileMapping(mapping.Value); err != nil {
+			return DSLWorkflowStep{}, fmt.Errorf("invalid input mapping %q: %w", mapping.Key, err)
+		}
 		dslStep.Input[mapping.Key] = mapping.Value
 	}
 
@@ -227,6 +238,29 @@ func ExecuteDSLWorkflow(ctx workflow.Context, config DSLWorkflowConfig, input DS
 type stepExecutionContext struct {
 	workflowInput map[string]any
 	stepOutputs   map[string]json.RawMessage
+	// stepValues holds the decoded step outputs for expression evaluation
+	stepValues map[string]any
+}
+
+// setOutput records the output of a completed step.
+func (c *stepExecutionContext) setOutput(stepName string, output json.RawMessage) {
+	c.stepOutputs[stepName] = output
+
+	var value any
+	if len(output) > 0 {
+		if err := json.Unmarshal(output, &value); err != nil {
+			value = string(output)
+		}
+	}
+	if c.stepValues == nil {
+		c.stepValues = make(map[string]any)
+	}
+	c.stepValues[stepName] = value
+}
+
+// env returns the expression environment for the current state.
+func (c *stepExecutionContext) env() expr.Env {
+	return expr.Env{Input: c.workflowInput, Steps: c.stepValues}
 }
 
 // executeStep executes a single workflow step.
@@ -247,7 +281,7 @@ func executeStep(ctx workflow.Context, step DSLWorkflowStep, stepCtx *stepExecut
 			logger.Info("Skipping step due to condition", "step", step.Name)
 			output.StepResults[step.Name] = StepResult{
 				StepName: step.Name,
-				Status:   definitions.StatusCompleted,
+				Status:   definitions.StatusSkipped,
 			}
 			return nil
 		}
@@ -284,7 +318,7 @@ func executeStep(ctx workflow.Context, step DSLWorkflowStep, stepCtx *stepExecut
 	output.StepResults[step.Name] = stepResult
 
 	// Store output for later steps to reference
-	stepCtx.stepOutputs[step.Name] = activityResult
+	stepCtx.setOutput(step.Name, activityResult)
 
 	return nil
 }
@@ -301,6 +335,21 @@ func executeParallelSteps(ctx workflow.Context, steps []DSLWorkflowStep, stepCtx
 	for i, step := range steps {
 		stepNames[i] = step.Name
 
+		if step.Condition != "" {
+			shouldRun, err := evaluateCondition(step.Condition, stepCtx)
+			if err != nil {
+				return fmt.Errorf("condition evaluation failed for parallel step %q: %w", step.Name, err)
+			}
+			if !shouldRun {
+				logger.Info("Skipping parallel step due to condition", "step", step.Name)
+				output.StepResults[step.Name] = StepResult{
+					StepName: step.Name,
+					Status:   definitions.StatusSkipped,
+				}
+				continue
+			}
+		}
+
 		// Resolve input for each parallel step
 		resolvedInput, err := resolveInputMappings(step.Input, stepCtx)
 		if err != nil {
@@ -312,6 +361,9 @@ func executeParallelSteps(ctx workflow.Context, steps []DSLWorkflowStep, stepCtx
 
 	// Wait for all parallel steps to complete
 	for i, future := range futures {
+		if future == nil {
+			continue // Skipped by its condition
+		}
 		stepName := stepNames[i]
 		stepResult := StepResult{
 			StepName:  stepName,
@@ -335,7 +387,7 @@ func executeParallelSteps(ctx workflow.Context, steps []DSLWorkflowStep, stepCtx
 		stepResult.Status = definitions.StatusCompleted
 		stepResult.Output = activityResult
 		output.StepResults[stepName] = stepResult
-		stepCtx.stepOutputs[stepName] = activityResult
+		stepCtx.setOutput(stepName, activityResult)
 	}
 
 	return nil
@@ -345,8 +397,15 @@ func executeParallelSteps(ctx workflow.Context, steps []DSLWorkflowStep, stepCtx
 func resolveInputMappings(mappings map[string]string, stepCtx *stepExecutionContext) (map[string]any, error) {
 	result := make(map[string]any)
 
-	for key, mapping := range mappings {
-		value, err := resolveMapping(mapping, stepCtx)
+	// Resolve in key order so failures are reported deterministically on replay
+	keys := make([]string, 0, len(mappings))
+	for key := range mappings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value, err := resolveMapping(mappings[key], stepCtx)
 		if err != nil {
 			return nil, fmt.Errorf("failed to resolve mapping %q: %w", key, err)
 		}
@@ -362,17 +421,21 @@ func resolveInputMappings(mappings map[string]string, stepCtx *stepExecutionCont
 // - "steps.stepName.output.field" - references previous step output
 // - literal string values
 func resolveMapping(mapping string, stepCtx *stepExecutionContext) (any, error) {
-	// For now, return the mapping as a literal
-	// Full expression evaluation can be added later
-	return mapping, nil
+	program, err := expr.CompileMapping(mapping)
+	if err != nil {
+		return nil, err
+	}
+	return program.Eval(stepCtx.env())
 }
 
 // evaluateCondition evaluates a condition expression.
 // Returns true if the step should execute, false if it should be skipped.
 func evaluateCondition(condition string, stepCtx *stepExecutionContext) (bool, error) {
-	// For now, return true to execute all steps
-	// Full condition evaluation can be added later
-	return true, nil
+	program, err := expr.Compile(condition)
+	if err != nil {
+		return false, err
+	}
+	return program.EvalBool(stepCtx.env())
 }
 
 // DSLWorkflowRegistry manages loaded DSL workflows.
@@ -0,0 +1,119 @@
+package workflow
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/testsuite"
+
+	"github.com/bargom/codeai/internal/ast"
+	"github.com/bargom/codeai/internal/workflow/definitions"
+)
+
+func TestExecuteDSLWorkflow_Expressions(t *testing.T) {
+	decl := &ast.WorkflowDecl{
+		Name:    "review",
+		Trigger: &ast.Trigger{TrigType: ast.TriggerTypeManual},
+		Steps: []*ast.WorkflowStep{
+			{
+				Name:     "check",
+				Activity: "review.check",
+				Input:    []*ast.InputMapping{{Key: "user", Value: "workflow.input.user_id"}},
+			},
+			{
+				Name:      "approve",
+				Activity:  "review.approve",
+				Condition: "steps.check.output.approved == true",
+				Input: []*ast.InputMapping{
+					{Key: "user", Value: "workflow.input.user_id"},
+					{Key: "score", Value: "steps.check.output.score"},
+					{Key: "template", Value: "approved_email"},
+					{Key: "subject", Value: "workflow complete"},
+				},
+			},
+			{
+				Parallel: true,
+				Steps: []*ast.WorkflowStep{
+					{Name: "reject", Activity: "review.reject", Condition: "!steps.check.output.approved"},
+					{Name: "audit", Activity: "review.audit", Condition: "len(workflow.input.tags) > 0"},
+				},
+			},
+		},
+	}
+	config, err := LoadWorkflowFromAST(decl)
+	require.NoError(t, err)
+
+	var testSuite testsuite.WorkflowTestSuite
+	env := testSuite.NewTestWorkflowEnvironment()
+
+	calls := make(map[string]map[string]any)
+	register := func(name string, result map[string]any) {
+		env.RegisterActivityWithOptions(func(_ context.Context, input map[string]any) (map[string]any, error) {
+			calls[name] = input
+			return result, nil
+		}, activity.RegisterOptions{Name: name})
+	}
+	register("review.check", map[string]any{"approved": true, "score": 0.9})
+	register("review.approve", map[string]any{"sent": true})
+	register("review.reject", nil)
+	register("review.audit", nil)
+
+	env.ExecuteWorkflow(ExecuteDSLWorkflow, *config, DSLWorkflowInput{
+		WorkflowID: "wf-1",
+		Input:      map[string]any{"user_id": "u-7", "tags": []any{"priority"}},
+	})
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	var out DSLWorkflowOutput
+	require.NoError(t, env.GetWorkflowResult(&out))
+	assert.Equal(t, definitions.StatusCompleted, out.Status, out.Error)
+
+	assert.Equal(t, map[string]any{"user": "u-7"}, calls["review.check"])
+	assert.Equal(t, map[string]any{"user": "u-7", "score": 0.9, "template": "approved_email", "subject": "workflow complete"}, calls["review.approve"])
+	assert.NotContains(t, calls, "review.reject", "reject is skipped by its condition")
+	assert.Contains(t, calls, "review.audit")
+	assert.Equal(t, definitions.StatusSkipped, out.StepResults["reject"].Status)
+	assert.Empty(t, out.StepResults["reject"].Output)
+}
+
+func TestExecuteDSLWorkflow_ConditionError(t *testing.T) {
+	config := DSLWorkflowConfig{
+		Name: "bad",
+		Steps: []DSLWorkflowStep{
+			{Name: "notify", Activity: "notify", Condition: "workflow.input.user_id"},
+		},
+	}
+
+	var testSuite testsuite.WorkflowTestSuite
+	env := testSuite.NewTestWorkflowEnvironment()
+	env.ExecuteWorkflow(ExecuteDSLWorkflow, config, DSLWorkflowInput{Input: map[string]any{"user_id": "u-7"}})
+	require.NoError(t, env.GetWorkflowError())
+
+	var out DSLWorkflowOutput
+	require.NoError(t, env.GetWorkflowResult(&out))
+	assert.Equal(t, definitions.StatusFailed, out.Status)
+	assert.Contains(t, out.Error, "must evaluate to a boolean")
+}
+
+func TestLoadWorkflowFromAST_InvalidExpressions(t *testing.T) {
+	base := func(step *ast.WorkflowStep) *ast.WorkflowDecl {
+		return &ast.WorkflowDecl{
+			Name:    "wf",
+			Trigger: &ast.Trigger{TrigType: ast.TriggerTypeManual},
+			Steps:   []*ast.WorkflowStep{step},
+		}
+	}
+
+	_, err := LoadWorkflowFromAST(base(&ast.WorkflowStep{Name: "a", Activity: "x", Condition: "steps.a.output =="}))
+	assert.ErrorContains(t, err, "invalid condition")
+
+	_, err = LoadWorkflowFromAST(base(&ast.WorkflowStep{
+		Name: "a", Activity: "x",
+		Input: []*ast.InputMapping{{Key: "id", Value: "workflow.inputs.id"}},
+	}))
+	assert.ErrorContains(t, err, `invalid input mapping "id"`)
+}
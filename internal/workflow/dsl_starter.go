@@ -0,0 +1,115 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+	"go.temporal.io/sdk/client"
+
+	"github.com/bargom/codeai/internal/workflow/definitions"
+	"github.com/bargom/codeai/internal/workflow/engine"
+)
+
+// WorkflowExecutor starts Temporal workflow executions. It is implemented by
+// engine.Engine.
+type WorkflowExecutor interface {
+	ExecuteWorkflow(ctx context.Context, workflowID string, workflow interface{}, args ...interface{}) (client.WorkflowRun, error)
+}
+
+// DSLWorkflowStarter starts workflows from a DSLWorkflowRegistry on Temporal.
+// Workers must register ExecuteDSLWorkflow for the workflows to run.
+type DSLWorkflowStarter struct {
+	registry *DSLWorkflowRegistry
+	executor WorkflowExecutor
+}
+
+// NewDSLWorkflowStarter creates a starter for the workflows in registry.
+func NewDSLWorkflowStarter(registry *DSLWorkflowRegistry, executor WorkflowExecutor) *DSLWorkflowStarter {
+	return &DSLWorkflowStarter{
+		registry: registry,
+		executor: executor,
+	}
+}
+
+// StartWorkflow starts the named workflow with input. If wait is true it
+// blocks until the workflow completes and returns an error if it failed.
+func (s *DSLWorkflowStarter) StartWorkflow(ctx context.Context, name string, input map[string]any, wait bool) error {
+	config, ok := s.registry.Get(name)
+	if !ok {
+		return fmt.Errorf("workflow %q is not registered", name)
+	}
+
+	workflowID := fmt.Sprintf("%s-%s", name, uuid.New().String())
+	run, err := s.executor.ExecuteWorkflow(ctx, workflowID, ExecuteDSLWorkflow, *config, DSLWorkflowInput{
+		WorkflowID: workflowID,
+		Input:      input,
+	})
+	if err != nil {
+		return fmt.Errorf("starting workflow %q: %w", name, err)
+	}
+	if !wait {
+		return nil
+	}
+
+	var output DSLWorkflowOutput
+	if err := run.Get(ctx, &output); err != nil {
+		return fmt.Errorf("workflow %q failed: %w", name, err)
+	}
+	if output.Status == definitions.StatusFailed {
+		return fmt.Errorf("workflow %q failed: %s", name, output.Error)
+	}
+	return nil
+}
+
+// IntegrationInvoker calls an integration and returns its response. It is
+// implemented by integration.IntegrationRegistry.
+type IntegrationInvoker interface {
+	InvokeIntegration(ctx context.Context, target string, payload any) (json.RawMessage, error)
+}
+
+// RegisterDSLWorkflows registers ExecuteDSLWorkflow with eng, along with an
+// activity for every activity the workflows in registry call. Activities
+// are named like integration targets: "payments.process" posts the step's
+// input to the process operation of the payments integration, and the
+// response is the step's output.
+func RegisterDSLWorkflows(eng *engine.Engine, registry *DSLWorkflowRegistry, integrations IntegrationInvoker) {
+	eng.RegisterWorkflow(ExecuteDSLWorkflow)
+	for _, name := range registry.Activities() {
+		eng.RegisterActivityWithName(name, IntegrationActivity(integrations, name))
+	}
+}
+
+// IntegrationActivity returns the activity that calls target.
+func IntegrationActivity(integrations IntegrationInvoker, target string) func(ctx context.Context, input map[string]any) (json.RawMessage, error) {
+	return func(ctx context.Context, input map[string]any) (json.RawMessage, error) {
+		return integrations.InvokeIntegration(ctx, target, input)
+	}
+}
+
+// Activities returns the names of the activities called by the registered
+// workflows, including those in parallel blocks, in sorted order.
+func (r *DSLWorkflowRegistry) Activities() []string {
+	seen := make(map[string]bool)
+	var collect func(steps []DSLWorkflowStep)
+	collect = func(steps []DSLWorkflowStep) {
+		for _, step := range steps {
+			if step.Activity != "" {
+				seen[step.Activity] = true
+			}
+			collect(step.Steps)
+		}
+	}
+	for _, config := range r.workflows {
+		collect(config.Steps)
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
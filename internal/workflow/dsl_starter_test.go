@@ -0,0 +1,153 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/client"
+
+	"github.com/bargom/codeai/internal/ast"
+	"github.com/bargom/codeai/internal/workflow/definitions"
+)
+
+type fakeRun struct {
+	client.WorkflowRun
+	output DSLWorkflowOutput
+	err    error
+	waited bool
+}
+
+func (r *fakeRun) Get(_ context.Context, valuePtr interface{}) error {
+	r.waited = true
+	if r.err != nil {
+		return r.err
+	}
+	*valuePtr.(*DSLWorkflowOutput) = r.output
+	return nil
+}
+
+type fakeExecutor struct {
+	run        *fakeRun
+	err        error
+	workflowID string
+	args       []interface{}
+}
+
+func (e *fakeExecutor) ExecuteWorkflow(_ context.Context, workflowID string, _ interface{}, args ...interface{}) (client.WorkflowRun, error) {
+	e.workflowID = workflowID
+	e.args = args
+	if e.err != nil {
+		return nil, e.err
+	}
+	return e.run, nil
+}
+
+func newStarterRegistry(t *testing.T) *DSLWorkflowRegistry {
+	t.Helper()
+	registry := NewDSLWorkflowRegistry()
+	err := registry.LoadWorkflows([]*ast.WorkflowDecl{{
+		Name:    "order_fulfillment",
+		Trigger: &ast.Trigger{TrigType: ast.TriggerTypeEvent, Value: "order.created"},
+	}})
+	require.NoError(t, err)
+	return registry
+}
+
+func TestDSLWorkflowStarter_StartWorkflow(t *testing.T) {
+	ctx := context.Background()
+	input := map[string]any{"order_id": "o-1"}
+
+	t.Run("starts without waiting", func(t *testing.T) {
+		executor := &fakeExecutor{run: &fakeRun{}}
+		starter := NewDSLWorkflowStarter(newStarterRegistry(t), executor)
+
+		require.NoError(t, starter.StartWorkflow(ctx, "order_fulfillment", input, false))
+		assert.Contains(t, executor.workflowID, "order_fulfillment-")
+		require.Len(t, executor.args, 2)
+		assert.Equal(t, "order_fulfillment", executor.args[0].(DSLWorkflowConfig).Name)
+		wfInput := executor.args[1].(DSLWorkflowInput)
+		assert.Equal(t, executor.workflowID, wfInput.WorkflowID)
+		assert.Equal(t, input, wfInput.Input)
+		assert.False(t, executor.run.waited)
+	})
+
+	t.Run("waits for the result", func(t *testing.T) {
+		executor := &fakeExecutor{run: &fakeRun{output: DSLWorkflowOutput{Status: definitions.StatusCompleted}}}
+		starter := NewDSLWorkflowStarter(newStarterRegistry(t), executor)
+
+		require.NoError(t, starter.StartWorkflow(ctx, "order_fulfillment", input, true))
+		assert.True(t, executor.run.waited)
+	})
+
+	t.Run("reports failed workflows", func(t *testing.T) {
+		executor := &fakeExecutor{run: &fakeRun{output: DSLWorkflowOutput{
+			Status: definitions.StatusFailed,
+			Error:  "payment declined",
+		}}}
+		starter := NewDSLWorkflowStarter(newStarterRegistry(t), executor)
+
+		err := starter.StartWorkflow(ctx, "order_fulfillment", input, true)
+		assert.ErrorContains(t, err, "payment declined")
+	})
+
+	t.Run("reports start failures", func(t *testing.T) {
+		executor := &fakeExecutor{err: errors.New("engine not started")}
+		starter := NewDSLWorkflowStarter(newStarterRegistry(t), executor)
+
+		err := starter.StartWorkflow(ctx, "order_fulfillment", input, false)
+		assert.ErrorContains(t, err, "engine not started")
+	})
+
+	t.Run("unknown workflow", func(t *testing.T) {
+		starter := NewDSLWorkflowStarter(newStarterRegistry(t), &fakeExecutor{})
+
+		err := starter.StartWorkflow(ctx, "missing", input, false)
+		assert.ErrorContains(t, err, "not registered")
+	})
+}
+
+type fakeIntegrations struct {
+	target  string
+	payload any
+}
+
+func (f *fakeIntegrations) InvokeIntegration(_ context.Context, target string, payload any) (json.RawMessage, error) {
+	f.target = target
+	f.payload = payload
+	return json.RawMessage(`{"ok":true}`), nil
+}
+
+func TestDSLWorkflowRegistry_Activities(t *testing.T) {
+	registry := NewDSLWorkflowRegistry()
+	require.NoError(t, registry.Register(&DSLWorkflowConfig{
+		Name: "order_fulfillment",
+		Steps: []DSLWorkflowStep{
+			{Name: "validate", Activity: "orders.validate"},
+			{Name: "fanout", Parallel: true, Steps: []DSLWorkflowStep{
+				{Name: "reserve", Activity: "inventory.reserve"},
+				{Name: "notify", Activity: "warehouse.notify"},
+			}},
+		},
+	}))
+	require.NoError(t, registry.Register(&DSLWorkflowConfig{
+		Name:  "refund",
+		Steps: []DSLWorkflowStep{{Name: "validate", Activity: "orders.validate"}},
+	}))
+
+	assert.Equal(t, []string{"inventory.reserve", "orders.validate", "warehouse.notify"}, registry.Activities())
+}
+
+func TestIntegrationActivity(t *testing.T) {
+	integrations := &fakeIntegrations{}
+	activity := IntegrationActivity(integrations, "payments.process")
+
+	output, err := activity(context.Background(), map[string]any{"amount": 10})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"ok":true}`, string(output))
+	assert.Equal(t, "payments.process", integrations.target)
+	assert.Equal(t, map[string]any{"amount": 10}, integrations.payload)
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"sync"
 
+	"go.temporal.io/sdk/activity"
 	"go.temporal.io/sdk/client"
 	"go.temporal.io/sdk/worker"
 )
@@ -17,13 +18,14 @@ type ActivityFunc interface{}
 
 // Engine orchestrates workflow execution using Temporal.
 type Engine struct {
-	client    client.Client
-	worker    worker.Worker
-	config    Config
-	mu        sync.RWMutex
-	running   bool
-	workflows []WorkflowFunc
-	activities []ActivityFunc
+	client          client.Client
+	worker          worker.Worker
+	config          Config
+	mu              sync.RWMutex
+	running         bool
+	workflows       []WorkflowFunc
+	activities      []ActivityFunc
+	namedActivities map[string]ActivityFunc
 }
 
 // NewEngine creates a new workflow engine with the given configuration.
@@ -33,9 +35,10 @@ func NewEngine(cfg Config) (*Engine, error) {
 	}
 
 	return &Engine{
-		config:     cfg,
-		workflows:  make([]WorkflowFunc, 0),
-		activities: make([]ActivityFunc, 0),
+		config:          cfg,
+		workflows:       make([]WorkflowFunc, 0),
+		activities:      make([]ActivityFunc, 0),
+		namedActivities: make(map[string]ActivityFunc),
 	}, nil
 }
 
@@ -53,6 +56,14 @@ func (e *Engine) RegisterActivity(act ActivityFunc) {
 	e.activities = append(e.activities, act)
 }
 
+// RegisterActivityWithName registers an activity function under name, so
+// that workflows can execute it by that name.
+func (e *Engine) RegisterActivityWithName(name string, act ActivityFunc) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.namedActivities[name] = act
+}
+
 // Start initializes the Temporal client and worker, then starts processing.
 func (e *Engine) Start(ctx context.Context) error {
 	e.mu.Lock()
@@ -74,9 +85,9 @@ func (e *Engine) Start(ctx context.Context) error {
 
 	// Create worker
 	workerOptions := worker.Options{
-		MaxConcurrentWorkflowTaskExecutionSize:  e.config.MaxConcurrentWorkflows,
-		MaxConcurrentActivityExecutionSize:      e.config.MaxConcurrentActivities,
-		Identity:                                e.config.WorkerID,
+		MaxConcurrentWorkflowTaskExecutionSize: e.config.MaxConcurrentWorkflows,
+		MaxConcurrentActivityExecutionSize:     e.config.MaxConcurrentActivities,
+		Identity:                               e.config.WorkerID,
 	}
 
 	e.worker = worker.New(e.client, e.config.TaskQueue, workerOptions)
@@ -88,6 +99,9 @@ func (e *Engine) Start(ctx context.Context) error {
 	for _, act := range e.activities {
 		e.worker.RegisterActivity(act)
 	}
+	for name, act := range e.namedActivities {
+		e.worker.RegisterActivityWithOptions(act, activity.RegisterOptions{Name: name})
+	}
 
 	// Start worker in background
 	if err := e.worker.Start(); err != nil {
@@ -115,8 +129,9 @@ func (e *Engine) Stop() error {
 	return nil
 }
 
-// ExecuteWorkflow starts a new workflow execution.
-func (e *Engine) ExecuteWorkflow(ctx context.Context, workflowID string, workflow interface{}, input interface{}) (client.WorkflowRun, error) {
+// ExecuteWorkflow starts a new workflow execution, passing args to the
+// workflow function.
+func (e *Engine) ExecuteWorkflow(ctx context.Context, workflowID string, workflow interface{}, args ...interface{}) (client.WorkflowRun, error) {
 	e.mu.RLock()
 	if !e.running {
 		e.mu.RUnlock()
@@ -133,7 +148,7 @@ func (e *Engine) ExecuteWorkflow(ctx context.Context, workflowID string, workflo
 		WorkflowExecutionTimeout: timeout,
 	}
 
-	run, err := c.ExecuteWorkflow(ctx, options, workflow, input)
+	run, err := c.ExecuteWorkflow(ctx, options, workflow, args...)
 	if err != nil {
 		return nil, fmt.Errorf("executing workflow: %w", err)
 	}
@@ -152,6 +152,18 @@ func TestEngineRegisterActivity(t *testing.T) {
 	assert.Equal(t, 1, len(eng.activities))
 }
 
+func TestEngineRegisterActivityWithName(t *testing.T) {
+	cfg := DefaultConfig()
+	eng, err := NewEngine(cfg)
+	require.NoError(t, err)
+
+	eng.RegisterActivityWithName("crm.lookup", func() {})
+	eng.RegisterActivityWithName("crm.update", func() {})
+
+	assert.Len(t, eng.namedActivities, 2)
+	assert.Contains(t, eng.namedActivities, "crm.lookup")
+}
+
 func TestEngineNotStartedErrors(t *testing.T) {
 	cfg := DefaultConfig()
 	eng, err := NewEngine(cfg)
@@ -0,0 +1,446 @@
+package expr
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// node is an element of a compiled expression.
+type node interface {
+	eval(env *Env) (any, error)
+}
+
+type literal struct{ value any }
+
+type inputRef struct{}
+
+type stepRef struct{ step string }
+
+type varRef struct{ name string }
+
+type member struct {
+	x    node
+	name string
+}
+
+type index struct {
+	x     node
+	index node
+}
+
+type unary struct {
+	op string
+	x  node
+}
+
+type binary struct {
+	op          string
+	left, right node
+}
+
+type call struct {
+	name string
+	fn   func(args []any) (any, error)
+	args []node
+}
+
+func (n *literal) eval(*Env) (any, error) {
+	return n.value, nil
+}
+
+func (n *inputRef) eval(env *Env) (any, error) {
+	if env.Input == nil {
+		return nil, nil
+	}
+	return normalize(env.Input), nil
+}
+
+func (n *stepRef) eval(env *Env) (any, error) {
+	return normalize(env.Steps[n.step]), nil
+}
+
+func (n *varRef) eval(env *Env) (any, error) {
+	return normalize(env.Vars[n.name]), nil
+}
+
+func (n *member) eval(env *Env) (any, error) {
+	x, err := n.x.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	return field(x, n.name)
+}
+
+// field returns a field of x. Missing fields evaluate to null so conditions
+// can test for them; accessing a field of a non-object is an error.
+func field(x any, name string) (any, error) {
+	if x == nil {
+		return nil, nil
+	}
+	m, ok := x.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("cannot access field %q of %s", name, typeName(x))
+	}
+	return m[name], nil
+}
+
+func (n *index) eval(env *Env) (any, error) {
+	x, err := n.x.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	idx, err := n.index.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	return element(x, idx)
+}
+
+// element returns the element of a list or object x at idx.
+func element(x, idx any) (any, error) {
+	if x == nil {
+		return nil, nil
+	}
+	switch x := x.(type) {
+	case map[string]any:
+		key, ok := idx.(string)
+		if !ok {
+			return nil, fmt.Errorf("object index must be a string, got %s", typeName(idx))
+		}
+		return x[key], nil
+	case []any:
+		f, ok := idx.(float64)
+		if !ok || f != math.Trunc(f) {
+			return nil, fmt.Errorf("list index must be an integer, got %s", typeName(idx))
+		}
+		i := int(f)
+		if i < 0 || i >= len(x) {
+			return nil, nil
+		}
+		return x[i], nil
+	}
+	return nil, fmt.Errorf("cannot index %s", typeName(x))
+}
+
+func (n *unary) eval(env *Env) (any, error) {
+	x, err := n.x.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "!":
+		b, ok := x.(bool)
+		if !ok {
+			return nil, fmt.Errorf("operator ! requires a boolean, got %s", typeName(x))
+		}
+		return !b, nil
+	default:
+		f, ok := x.(float64)
+		if !ok {
+			return nil, fmt.Errorf("operator - requires a number, got %s", typeName(x))
+		}
+		return -f, nil
+	}
+}
+
+func (n *binary) eval(env *Env) (any, error) {
+	left, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	// Boolean operators short-circuit
+	if n.op == "&&" || n.op == "||" {
+		l, ok := left.(bool)
+		if !ok {
+			return nil, fmt.Errorf("operator %s requires booleans, got %s", n.op, typeName(left))
+		}
+		if (n.op == "&&" && !l) || (n.op == "||" && l) {
+			return l, nil
+		}
+		right, err := n.right.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		r, ok := right.(bool)
+		if !ok {
+			return nil, fmt.Errorf("operator %s requires booleans, got %s", n.op, typeName(right))
+		}
+		return r, nil
+	}
+
+	right, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	return comparison(n.op, left, right)
+}
+
+// comparison applies the comparison operator op to left and right.
+func comparison(op string, left, right any) (any, error) {
+	switch op {
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	}
+
+	cmp, err := compare(left, right, op)
+	if err != nil {
+		return nil, err
+	}
+	switch op {
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	default:
+		return cmp >= 0, nil
+	}
+}
+
+func (n *call) eval(env *Env) (any, error) {
+	args := make([]any, len(n.args))
+	for i, arg := range n.args {
+		v, err := arg.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	v, err := n.fn(args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", n.name, err)
+	}
+	return v, nil
+}
+
+func equal(a, b any) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+func compare(a, b any, op string) (int, error) {
+	switch a := a.(type) {
+	case float64:
+		if b, ok := b.(float64); ok {
+			switch {
+			case a < b:
+				return -1, nil
+			case a > b:
+				return 1, nil
+			}
+			return 0, nil
+		}
+	case string:
+		if b, ok := b.(string); ok {
+			return strings.Compare(a, b), nil
+		}
+	}
+	return 0, fmt.Errorf("operator %s cannot compare %s and %s", op, typeName(a), typeName(b))
+}
+
+// normalize converts Go values into the JSON value model the evaluator
+// works with: nil, bool, float64, string, []any and map[string]any.
+func normalize(v any) any {
+	switch v := v.(type) {
+	case nil, bool, float64, string:
+		return v
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return v.String()
+		}
+		return f
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, e := range v {
+			out[k] = normalize(e)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, e := range v {
+			out[i] = normalize(e)
+		}
+		return out
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint())
+	case reflect.Float32:
+		return rv.Float()
+	}
+
+	// Fall back to a JSON round trip for structs and typed collections
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	var out any
+	if err := json.Unmarshal(data, &out); err != nil {
+		return fmt.Sprint(v)
+	}
+	return out
+}
+
+func typeName(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "list"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// function is a built-in function callable from expressions.
+type function struct {
+	arity int
+	impl  func(args []any) (any, error)
+}
+
+var functions = map[string]function{
+	"len":        {1, fnLen},
+	"lower":      {1, stringFunc(strings.ToLower)},
+	"upper":      {1, stringFunc(strings.ToUpper)},
+	"trim":       {1, stringFunc(strings.TrimSpace)},
+	"contains":   {2, fnContains},
+	"startsWith": {2, stringPredicate(strings.HasPrefix)},
+	"endsWith":   {2, stringPredicate(strings.HasSuffix)},
+	"exists":     {1, fnExists},
+	"default":    {2, fnDefault},
+	"string":     {1, fnString},
+	"number":     {1, fnNumber},
+}
+
+func fnLen(args []any) (any, error) {
+	switch v := args[0].(type) {
+	case nil:
+		return 0.0, nil
+	case string:
+		return float64(len([]rune(v))), nil
+	case []any:
+		return float64(len(v)), nil
+	case map[string]any:
+		return float64(len(v)), nil
+	}
+	return nil, fmt.Errorf("expected string, list or object, got %s", typeName(args[0]))
+}
+
+func stringFunc(f func(string) string) func([]any) (any, error) {
+	return func(args []any) (any, error) {
+		s, ok := args[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("expected string, got %s", typeName(args[0]))
+		}
+		return f(s), nil
+	}
+}
+
+func stringPredicate(f func(s, sub string) bool) func([]any) (any, error) {
+	return func(args []any) (any, error) {
+		s, ok1 := args[0].(string)
+		sub, ok2 := args[1].(string)
+		if !ok1 || !ok2 {
+			return nil, fmt.Errorf("expected strings, got %s and %s", typeName(args[0]), typeName(args[1]))
+		}
+		return f(s, sub), nil
+	}
+}
+
+// fnContains tests for a substring, a list element or an object key.
+func fnContains(args []any) (any, error) {
+	switch v := args[0].(type) {
+	case nil:
+		return false, nil
+	case string:
+		sub, ok := args[1].(string)
+		if !ok {
+			return nil, fmt.Errorf("expected string, got %s", typeName(args[1]))
+		}
+		return strings.Contains(v, sub), nil
+	case []any:
+		for _, e := range v {
+			if equal(e, args[1]) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case map[string]any:
+		key, ok := args[1].(string)
+		if !ok {
+			return nil, fmt.Errorf("expected string key, got %s", typeName(args[1]))
+		}
+		_, exists := v[key]
+		return exists, nil
+	}
+	return nil, fmt.Errorf("expected string, list or object, got %s", typeName(args[0]))
+}
+
+func fnExists(args []any) (any, error) {
+	return args[0] != nil, nil
+}
+
+func fnDefault(args []any) (any, error) {
+	if args[0] == nil {
+		return args[1], nil
+	}
+	return args[0], nil
+}
+
+func fnString(args []any) (any, error) {
+	switch v := args[0].(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	}
+	// Lists and objects are rendered as JSON; map keys are sorted by
+	// encoding/json, so the result is deterministic
+	data, err := json.Marshal(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func fnNumber(args []any) (any, error) {
+	switch v := args[0].(type) {
+	case float64:
+		return v, nil
+	case bool:
+		if v {
+			return 1.0, nil
+		}
+		return 0.0, nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return nil, fmt.Errorf("cannot convert %q to a number", v)
+		}
+		return f, nil
+	}
+	return nil, fmt.Errorf("cannot convert %s to a number", typeName(args[0]))
+}
@@ -0,0 +1,229 @@
+// Package expr implements the expression language used by DSL workflow
+// input mappings and step conditions.
+//
+// Expressions reference the workflow input and the output of earlier steps:
+//
+//	workflow.input.user_id
+//	steps.check.output.approved == true && len(steps.check.output.items) > 0
+//
+// Expressions compiled with CompileWithVars may also reference named
+// variables supplied in Env.Vars, such as the resource and auth variables of
+// authorization policies.
+//
+// Evaluation has no side effects and no access to the clock, randomness or
+// the environment, so expressions can be evaluated inside Temporal workflow
+// code without breaking determinism.
+package expr
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Env holds the values an expression can reference.
+type Env struct {
+	// Input is the workflow input, referenced as workflow.input.
+	Input map[string]any
+	// Steps maps step names to their decoded output, referenced as
+	// steps.<name>.output. Steps that have not run are absent.
+	Steps map[string]any
+	// Vars holds the named variables of expressions compiled with
+	// CompileWithVars. Missing variables are null.
+	Vars map[string]any
+}
+
+// Program is a compiled expression.
+type Program struct {
+	src   string
+	root  node
+	steps []string
+}
+
+// SyntaxError reports an expression that cannot be compiled.
+type SyntaxError struct {
+	Offset int
+	Msg    string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s at offset %d", e.Msg, e.Offset)
+}
+
+// Compile parses and checks an expression.
+func Compile(src string) (*Program, error) {
+	return CompileWithVars(src)
+}
+
+// CompileWithVars parses and checks an expression that may reference the
+// named variables in addition to workflow.input and steps.
+func CompileWithVars(src string, vars ...string) (*Program, error) {
+	p := &parser{lex: lexer{src: src}, vars: make(map[string]bool, len(vars))}
+	for _, name := range vars {
+		p.vars[name] = true
+	}
+	root, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+
+	refs := make(map[string]bool)
+	collectStepRefs(root, refs)
+	steps := make([]string, 0, len(refs))
+	for name := range refs {
+		steps = append(steps, name)
+	}
+	sort.Strings(steps)
+
+	return &Program{src: src, root: root, steps: steps}, nil
+}
+
+// CompileMapping compiles an input mapping value. Values that are not
+// expressions over workflow or steps are treated as string literals, so plain
+// values like "welcome_email", "reports/daily/" or "workflow complete" keep
+// working. A value that starts a workflow or steps path but does not parse,
+// such as "workflow.input.", is an error.
+func CompileMapping(src string) (*Program, error) {
+	p, err := Compile(src)
+	if err == nil && referencesScope(p.root) {
+		return p, nil
+	}
+	if err != nil && hasScopePath(src) {
+		return nil, err
+	}
+	return &Program{src: src, root: &literal{value: src}}, nil
+}
+
+// String returns the source of the expression.
+func (p *Program) String() string {
+	return p.src
+}
+
+// StepRefs returns the sorted names of the steps whose output the expression
+// references.
+func (p *Program) StepRefs() []string {
+	return p.steps
+}
+
+// VarFields returns the sorted names of the fields of variable name that
+// the expression references, such as author_id for resource.author_id.
+func (p *Program) VarFields(name string) []string {
+	refs := make(map[string]bool)
+	collectVarFields(p.root, name, refs)
+	fields := make([]string, 0, len(refs))
+	for field := range refs {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// Eval evaluates the expression against env.
+func (p *Program) Eval(env Env) (any, error) {
+	return p.root.eval(&env)
+}
+
+// EvalBool evaluates the expression and requires a boolean result.
+func (p *Program) EvalBool(env Env) (bool, error) {
+	v, err := p.Eval(env)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("expression must evaluate to a boolean, got %s", typeName(v))
+	}
+	return b, nil
+}
+
+// referencesScope reports whether the expression n reads workflow.input or
+// the output of a step.
+func referencesScope(n node) bool {
+	switch n := n.(type) {
+	case *inputRef, *stepRef:
+		return true
+	case *member:
+		return referencesScope(n.x)
+	case *index:
+		return referencesScope(n.x) || referencesScope(n.index)
+	case *unary:
+		return referencesScope(n.x)
+	case *binary:
+		return referencesScope(n.left) || referencesScope(n.right)
+	case *call:
+		for _, arg := range n.args {
+			if referencesScope(arg) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasScopePath reports whether src contains workflow or steps followed by
+// a dot outside of string literals, the start of a reference.
+func hasScopePath(src string) bool {
+	l := lexer{src: src}
+	var prev token
+	for {
+		tok, err := l.next()
+		if err != nil || tok.kind == tokEOF {
+			return false
+		}
+		if prev.kind == tokIdent && (prev.text == "workflow" || prev.text == "steps") &&
+			tok.kind == tokOp && tok.text == "." {
+			return true
+		}
+		prev = tok
+	}
+}
+
+func collectStepRefs(n node, refs map[string]bool) {
+	switch n := n.(type) {
+	case *stepRef:
+		refs[n.step] = true
+	case *member:
+		collectStepRefs(n.x, refs)
+	case *index:
+		collectStepRefs(n.x, refs)
+		collectStepRefs(n.index, refs)
+	case *unary:
+		collectStepRefs(n.x, refs)
+	case *binary:
+		collectStepRefs(n.left, refs)
+		collectStepRefs(n.right, refs)
+	case *call:
+		for _, arg := range n.args {
+			collectStepRefs(arg, refs)
+		}
+	}
+}
+
+func collectVarFields(n node, name string, refs map[string]bool) {
+	switch n := n.(type) {
+	case *member:
+		if v, ok := n.x.(*varRef); ok && v.name == name {
+			refs[n.name] = true
+			return
+		}
+		collectVarFields(n.x, name, refs)
+	case *index:
+		if v, ok := n.x.(*varRef); ok && v.name == name {
+			if key, ok := n.index.(*literal); ok {
+				if s, ok := key.value.(string); ok {
+					refs[s] = true
+				}
+			}
+		}
+		collectVarFields(n.x, name, refs)
+		collectVarFields(n.index, name, refs)
+	case *unary:
+		collectVarFields(n.x, name, refs)
+	case *binary:
+		collectVarFields(n.left, name, refs)
+		collectVarFields(n.right, name, refs)
+	case *call:
+		for _, arg := range n.args {
+			collectVarFields(arg, name, refs)
+		}
+	}
+}
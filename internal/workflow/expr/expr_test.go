@@ -0,0 +1,256 @@
+package expr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testEnv = Env{
+	Input: map[string]any{
+		"user_id": "u-1",
+		"amount":  42,
+		"tags":    []any{"vip", "beta"},
+		"profile": map[string]any{"name": "Ada", "email": "ADA@example.com"},
+	},
+	Steps: map[string]any{
+		"check": map[string]any{
+			"approved": true,
+			"score":    float64(0.8),
+			"items":    []any{map[string]any{"sku": "A1"}, map[string]any{"sku": "B2"}},
+		},
+	},
+}
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		expr string
+		want any
+	}{
+		{"workflow.input.user_id", "u-1"},
+		{"workflow.input.amount", 42.0},
+		{"workflow.input.profile.name", "Ada"},
+		{"workflow.input.missing", nil},
+		{"workflow.input.missing.deeper", nil},
+		{"workflow.input['user_id']", "u-1"},
+		{"workflow.input.tags[1]", "beta"},
+		{"workflow.input.tags[5]", nil},
+		{"steps.check.output.approved", true},
+		{"steps.check.output.items[0].sku", "A1"},
+		{"steps.skipped.output.value", nil},
+		{"steps.check.output.approved == true", true},
+		{"steps.check.output.score >= 0.5 && workflow.input.amount < 100", true},
+		{"steps.check.output.score > 0.9 || workflow.input.user_id == 'u-1'", true},
+		{"not steps.check.output.approved or false", false},
+		{"!(workflow.input.amount != 42)", true},
+		{"workflow.input.amount > -1", true},
+		{"'abc' < 'abd'", true},
+		{"workflow.input.missing == null", true},
+		{"workflow.input.tags == workflow.input.tags", true},
+		{"len(workflow.input.tags)", 2.0},
+		{"len(steps.check.output.items) > 1", true},
+		{"lower(workflow.input.profile.email)", "ada@example.com"},
+		{"upper('x')", "X"},
+		{"trim('  x ')", "x"},
+		{"contains(workflow.input.tags, 'vip')", true},
+		{"contains(workflow.input.profile, 'email')", true},
+		{"contains('hello', 'ell')", true},
+		{"startsWith(workflow.input.user_id, 'u-')", true},
+		{"endsWith(workflow.input.user_id, '-2')", false},
+		{"exists(workflow.input.missing)", false},
+		{"default(workflow.input.missing, 'fallback')", "fallback"},
+		{"string(workflow.input.amount)", "42"},
+		{"number('3.5') == 3.5", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			p, err := Compile(tt.expr)
+			require.NoError(t, err)
+			got, err := p.Eval(testEnv)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestEval_ShortCircuit(t *testing.T) {
+	// The right-hand side would fail if it were evaluated
+	p, err := Compile("exists(steps.skipped.output) && steps.skipped.output.count > 1")
+	require.NoError(t, err)
+	ok, err := p.EvalBool(testEnv)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestEval_Errors(t *testing.T) {
+	tests := []string{
+		"workflow.input.amount > 'ten'",
+		"workflow.input.user_id && true",
+		"!workflow.input.amount",
+		"workflow.input.user_id.name",
+		"workflow.input.tags['x']",
+		"len(workflow.input.amount)",
+		"number('abc')",
+	}
+	for _, src := range tests {
+		t.Run(src, func(t *testing.T) {
+			p, err := Compile(src)
+			require.NoError(t, err)
+			_, err = p.Eval(testEnv)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestEvalBool(t *testing.T) {
+	p, err := Compile("workflow.input.user_id")
+	require.NoError(t, err)
+	_, err = p.EvalBool(testEnv)
+	assert.ErrorContains(t, err, "must evaluate to a boolean")
+}
+
+func TestCompile_Errors(t *testing.T) {
+	tests := []struct {
+		expr string
+		msg  string
+	}{
+		{"", "empty expression"},
+		{"workflow.inputs.id", `expected "input"`},
+		{"steps.check.result", `expected "output"`},
+		{"steps..output", "expected identifier"},
+		{"config.value", `unknown identifier "config"`},
+		{"now()", `unknown function "now"`},
+		{"len(1, 2)", "expects 1 argument(s), got 2"},
+		{"workflow.input.a ==", "unexpected end of expression"},
+		{"(workflow.input.a", `expected ")"`},
+		{"workflow.input.a workflow.input.b", `unexpected "workflow"`},
+		{"'open", "unterminated string"},
+		{"workflow.input.a / 2", "unexpected character"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := Compile(tt.expr)
+			require.Error(t, err)
+			var syntaxErr *SyntaxError
+			assert.ErrorAs(t, err, &syntaxErr)
+			assert.Contains(t, err.Error(), tt.msg)
+		})
+	}
+}
+
+func TestCompileMapping(t *testing.T) {
+	for _, src := range []string{"daily", "reports/sales/", "config.reports.recipients", "order confirmation",
+		"workflow complete", "3 steps left", "steps", "true"} {
+		p, err := CompileMapping(src)
+		require.NoError(t, err)
+		v, err := p.Eval(Env{})
+		require.NoError(t, err)
+		assert.Equal(t, src, v, "plain values are literals")
+	}
+
+	p, err := CompileMapping("steps.fetch.output.orders")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"fetch"}, p.StepRefs())
+
+	p, err = CompileMapping("default(workflow.input.name, 'guest')")
+	require.NoError(t, err)
+	v, err := p.Eval(Env{Input: map[string]any{"name": "ada"}})
+	require.NoError(t, err)
+	assert.Equal(t, "ada", v)
+
+	_, err = CompileMapping("workflow.input.")
+	assert.Error(t, err, "expressions referencing workflow or steps must be valid")
+	_, err = CompileMapping("steps.fetch.output +")
+	assert.Error(t, err)
+}
+
+func TestStepRefs(t *testing.T) {
+	p, err := Compile("steps.b.output.x == steps.a.output.y && exists(steps.b.output) && workflow.input.z")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, p.StepRefs())
+	assert.Equal(t, "steps.b.output.x == steps.a.output.y && exists(steps.b.output) && workflow.input.z", p.String())
+}
+
+func TestCompileWithVars(t *testing.T) {
+	p, err := CompileWithVars("resource.author_id == auth.sub or auth.role == 'admin'", "resource", "auth")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"author_id"}, p.VarFields("resource"))
+	assert.Equal(t, []string{"role", "sub"}, p.VarFields("auth"))
+
+	env := Env{Vars: map[string]any{
+		"resource": map[string]any{"author_id": "u-1"},
+		"auth":     map[string]any{"sub": "u-1"},
+	}}
+	ok, err := p.EvalBool(env)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	env.Vars["auth"] = map[string]any{"sub": "u-2", "role": "admin"}
+	ok, err = p.EvalBool(env)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	env.Vars["auth"] = map[string]any{"sub": "u-2"}
+	ok, err = p.EvalBool(env)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	_, err = CompileWithVars("other.id == 1", "resource")
+	assert.ErrorContains(t, err, `unknown identifier "other"`)
+	_, err = Compile("resource.id == 1")
+	assert.Error(t, err)
+}
+
+func TestFilter(t *testing.T) {
+	env := Env{Vars: map[string]any{
+		"auth": map[string]any{"sub": "u-1", "role": "editor", "roles": []any{"editor"}, "level": 3},
+	}}
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"resource.author_id == auth.sub", "author_id == u-1"},
+		{"auth.sub == resource.author_id", "author_id == u-1"},
+		{"resource.author_id == auth.sub or auth.role == 'admin'", "author_id == u-1"},
+		{"resource.author_id == auth.sub or auth.role == 'editor'", "true"},
+		{"auth.role == 'admin' and resource.public", "false"},
+		{"resource.public or resource.author_id == auth.sub", "(public == true || author_id == u-1)"},
+		{"resource.public and (resource.rank < 3 or resource.rank > 7)", "(public == true && (rank < 3 || rank > 7))"},
+		{"auth.level >= resource.level", "level <= 3"},
+		{"not (resource.public and resource.rank <= 2)", "(public == false || rank > 2)"},
+		{"not not resource.public", "public == true"},
+		{"not (resource.public == true)", "public != true"},
+		{"resource.owner.id == auth.sub", "owner.id == u-1"},
+		{"resource['status'] != 'draft'", "status != draft"},
+		{"exists(resource.published_at)", "published_at != <nil>"},
+		{"contains(auth.roles, 'editor') && resource.team == null", "team == <nil>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			p, err := CompileWithVars(tt.expr, "resource", "auth")
+			require.NoError(t, err)
+			f, err := p.Filter(env, "resource")
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, f.String())
+		})
+	}
+}
+
+func TestFilter_Errors(t *testing.T) {
+	env := Env{Vars: map[string]any{"auth": map[string]any{"sub": "u-1"}}}
+	for _, src := range []string{
+		"resource",
+		"resource == auth",
+		"resource.a == resource.b",
+		"len(resource.tags) > 1",
+		"contains(resource.tags, 'x')",
+		"-resource.rank < 1",
+		"auth.sub",
+	} {
+		p, err := CompileWithVars(src, "resource", "auth")
+		require.NoError(t, err, src)
+		_, err = p.Filter(env, "resource")
+		assert.Error(t, err, src)
+	}
+}
@@ -0,0 +1,394 @@
+package expr
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FilterKind identifies the shape of a Filter.
+type FilterKind int
+
+const (
+	// FilterTrue matches every record.
+	FilterTrue FilterKind = iota
+	// FilterFalse matches no record.
+	FilterFalse
+	// FilterAnd matches records matching all of Filters.
+	FilterAnd
+	// FilterOr matches records matching any of Filters.
+	FilterOr
+	// FilterCompare matches records whose Field compares to Value with Op.
+	FilterCompare
+)
+
+// Filter is what remains of a boolean expression once every value but the
+// fields of one variable is known: a condition on those fields, such as
+// the WHERE clause of a query for the records the expression accepts.
+//
+// Negations are pushed down into the comparisons, so a Filter contains no
+// NOT, and constants are folded, so FilterTrue and FilterFalse only appear
+// at the top.
+//
+// As in evaluation, a field compared with != to a value other than null
+// also matches records where the field is null or missing, and ordering
+// comparisons never match them.
+type Filter struct {
+	Kind FilterKind
+	// Filters are the operands of FilterAnd and FilterOr.
+	Filters []*Filter
+	// Field is the dotted path of the compared field, relative to the
+	// variable, e.g. author_id for resource.author_id.
+	Field string
+	// Op is one of ==, !=, <, <=, > and >=.
+	Op string
+	// Value is the constant the field is compared to, in the JSON value
+	// model of the evaluator.
+	Value any
+
+	// condition is set for a boolean field used as a condition, which,
+	// unlike field == true, fails to evaluate when the field is null, so
+	// its negation is field == false rather than field != true.
+	condition bool
+}
+
+func (f *Filter) String() string {
+	switch f.Kind {
+	case FilterTrue:
+		return "true"
+	case FilterFalse:
+		return "false"
+	case FilterCompare:
+		return fmt.Sprintf("%s %s %v", f.Field, f.Op, f.Value)
+	}
+	op := " && "
+	if f.Kind == FilterOr {
+		op = " || "
+	}
+	parts := make([]string, len(f.Filters))
+	for i, sub := range f.Filters {
+		parts[i] = sub.String()
+	}
+	return "(" + strings.Join(parts, op) + ")"
+}
+
+// Filter partially evaluates a boolean expression against env, leaving the
+// variable named unknown as a record whose fields are not known.
+//
+// Only comparisons between a field of the record and a value that doesn't
+// depend on the record, exists() of a field, boolean fields and their
+// combinations with and, or and not can be turned into a filter; other uses
+// of the record are an error.
+func (p *Program) Filter(env Env, unknown string) (*Filter, error) {
+	pe := &partialEval{env: &env, unknown: unknown}
+	return pe.boolean(p.root)
+}
+
+// partial is the result of partially evaluating a node: a known value, a
+// field of the unknown record, or a filter.
+type partial struct {
+	value  any
+	field  []string
+	record bool // the value refers to the record or one of its fields
+	filter *Filter
+}
+
+type partialEval struct {
+	env     *Env
+	unknown string
+}
+
+func (pe *partialEval) eval(n node) (partial, error) {
+	switch n := n.(type) {
+	case *varRef:
+		if n.name == pe.unknown {
+			return partial{record: true}, nil
+		}
+
+	case *member:
+		x, err := pe.eval(n.x)
+		if err != nil {
+			return partial{}, err
+		}
+		if x.filter != nil {
+			return partial{}, fmt.Errorf("cannot access field %q of a condition", n.name)
+		}
+		if x.record {
+			return partial{record: true, field: appendPath(x.field, n.name)}, nil
+		}
+		v, err := field(x.value, n.name)
+		return partial{value: v}, err
+
+	case *index:
+		x, err := pe.eval(n.x)
+		if err != nil {
+			return partial{}, err
+		}
+		idx, err := pe.eval(n.index)
+		if err != nil {
+			return partial{}, err
+		}
+		if idx.record || idx.filter != nil || x.filter != nil {
+			return partial{}, fmt.Errorf("cannot filter on a computed index of %s", pe.unknown)
+		}
+		if x.record {
+			key, ok := idx.value.(string)
+			if !ok {
+				return partial{}, fmt.Errorf("fields of %s must be indexed with strings, got %s", pe.unknown, typeName(idx.value))
+			}
+			return partial{record: true, field: appendPath(x.field, key)}, nil
+		}
+		v, err := element(x.value, idx.value)
+		return partial{value: v}, err
+
+	case *unary:
+		if n.op == "!" {
+			x, err := pe.boolean(n.x)
+			if err != nil {
+				return partial{}, err
+			}
+			return partial{filter: negate(x)}, nil
+		}
+
+	case *binary:
+		if n.op == "&&" || n.op == "||" {
+			f, err := pe.logical(n)
+			return partial{filter: f}, err
+		}
+		return pe.comparison(n)
+
+	case *call:
+		return pe.call(n)
+	}
+
+	// Nodes that don't involve the record evaluate normally
+	if refersTo(n, pe.unknown) {
+		return partial{}, fmt.Errorf("cannot filter on this use of %s", pe.unknown)
+	}
+	v, err := n.eval(pe.env)
+	return partial{value: v}, err
+}
+
+// boolean partially evaluates n as a condition.
+func (pe *partialEval) boolean(n node) (*Filter, error) {
+	x, err := pe.eval(n)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case x.filter != nil:
+		return x.filter, nil
+	case x.record:
+		// A boolean field used as a condition
+		if len(x.field) == 0 {
+			return nil, fmt.Errorf("%s is not a condition", pe.unknown)
+		}
+		f := compareFilter(x.field, "==", true)
+		f.condition = true
+		return f, nil
+	}
+	b, ok := x.value.(bool)
+	if !ok {
+		return nil, fmt.Errorf("expression must evaluate to a boolean, got %s", typeName(x.value))
+	}
+	return constFilter(b), nil
+}
+
+func (pe *partialEval) logical(n *binary) (*Filter, error) {
+	left, err := pe.boolean(n.left)
+	if err != nil {
+		return nil, err
+	}
+	// Short-circuit like evaluation does
+	if (n.op == "&&" && left.Kind == FilterFalse) || (n.op == "||" && left.Kind == FilterTrue) {
+		return left, nil
+	}
+	right, err := pe.boolean(n.right)
+	if err != nil {
+		return nil, err
+	}
+	if n.op == "&&" {
+		return combine(FilterAnd, left, right), nil
+	}
+	return combine(FilterOr, left, right), nil
+}
+
+func (pe *partialEval) comparison(n *binary) (partial, error) {
+	left, err := pe.eval(n.left)
+	if err != nil {
+		return partial{}, err
+	}
+	right, err := pe.eval(n.right)
+	if err != nil {
+		return partial{}, err
+	}
+	if left.filter != nil || right.filter != nil {
+		return partial{}, fmt.Errorf("operator %s cannot compare conditions", n.op)
+	}
+
+	switch {
+	case !left.record && !right.record:
+		v, err := comparison(n.op, left.value, right.value)
+		return partial{value: v}, err
+	case left.record && right.record:
+		return partial{}, fmt.Errorf("cannot compare two fields of %s", pe.unknown)
+	case left.record:
+		if len(left.field) == 0 {
+			return partial{}, fmt.Errorf("cannot compare %s itself", pe.unknown)
+		}
+		return partial{filter: compareFilter(left.field, n.op, right.value)}, nil
+	default:
+		if len(right.field) == 0 {
+			return partial{}, fmt.Errorf("cannot compare %s itself", pe.unknown)
+		}
+		return partial{filter: compareFilter(right.field, mirror(n.op), left.value)}, nil
+	}
+}
+
+func (pe *partialEval) call(n *call) (partial, error) {
+	args := make([]any, len(n.args))
+	for i, arg := range n.args {
+		x, err := pe.eval(arg)
+		if err != nil {
+			return partial{}, err
+		}
+		if x.record || x.filter != nil {
+			if n.name == "exists" && x.record && len(x.field) > 0 {
+				return partial{filter: compareFilter(x.field, "!=", nil)}, nil
+			}
+			return partial{}, fmt.Errorf("cannot filter on %s() of %s", n.name, pe.unknown)
+		}
+		args[i] = x.value
+	}
+	v, err := n.fn(args)
+	if err != nil {
+		return partial{}, fmt.Errorf("%s: %w", n.name, err)
+	}
+	return partial{value: v}, nil
+}
+
+// refersTo reports whether n references the variable name.
+func refersTo(n node, name string) bool {
+	switch n := n.(type) {
+	case *varRef:
+		return n.name == name
+	case *member:
+		return refersTo(n.x, name)
+	case *index:
+		return refersTo(n.x, name) || refersTo(n.index, name)
+	case *unary:
+		return refersTo(n.x, name)
+	case *binary:
+		return refersTo(n.left, name) || refersTo(n.right, name)
+	case *call:
+		for _, arg := range n.args {
+			if refersTo(arg, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func appendPath(path []string, name string) []string {
+	out := make([]string, len(path), len(path)+1)
+	copy(out, path)
+	return append(out, name)
+}
+
+func constFilter(b bool) *Filter {
+	if b {
+		return &Filter{Kind: FilterTrue}
+	}
+	return &Filter{Kind: FilterFalse}
+}
+
+func compareFilter(path []string, op string, value any) *Filter {
+	return &Filter{Kind: FilterCompare, Field: strings.Join(path, "."), Op: op, Value: value}
+}
+
+// combine joins two filters with and or or, folding constants and
+// flattening nested filters of the same kind.
+func combine(kind FilterKind, left, right *Filter) *Filter {
+	// The neutral element of the operator is dropped, the absorbing one wins
+	neutral, absorbing := FilterTrue, FilterFalse
+	if kind == FilterOr {
+		neutral, absorbing = FilterFalse, FilterTrue
+	}
+
+	out := &Filter{Kind: kind}
+	for _, f := range []*Filter{left, right} {
+		switch f.Kind {
+		case absorbing:
+			return f
+		case neutral:
+		case kind:
+			out.Filters = append(out.Filters, f.Filters...)
+		default:
+			out.Filters = append(out.Filters, f)
+		}
+	}
+	switch len(out.Filters) {
+	case 0:
+		return &Filter{Kind: neutral}
+	case 1:
+		return out.Filters[0]
+	}
+	return out
+}
+
+// negate returns the negation of f, applying De Morgan's laws.
+func negate(f *Filter) *Filter {
+	switch f.Kind {
+	case FilterTrue:
+		return constFilter(false)
+	case FilterFalse:
+		return constFilter(true)
+	case FilterCompare:
+		if f.condition {
+			return &Filter{Kind: FilterCompare, Field: f.Field, Op: "==", Value: f.Value != true, condition: true}
+		}
+		return &Filter{Kind: FilterCompare, Field: f.Field, Op: inverse(f.Op), Value: f.Value}
+	}
+	kind := FilterOr
+	if f.Kind == FilterOr {
+		kind = FilterAnd
+	}
+	out := &Filter{Kind: kind, Filters: make([]*Filter, len(f.Filters))}
+	for i, sub := range f.Filters {
+		out.Filters[i] = negate(sub)
+	}
+	return out
+}
+
+// inverse returns the operator matching when op doesn't.
+func inverse(op string) string {
+	switch op {
+	case "==":
+		return "!="
+	case "!=":
+		return "=="
+	case "<":
+		return ">="
+	case "<=":
+		return ">"
+	case ">":
+		return "<="
+	default:
+		return "<"
+	}
+}
+
+// mirror returns the operator to use when the operands of op are swapped.
+func mirror(op string) string {
+	switch op {
+	case "<":
+		return ">"
+	case "<=":
+		return ">="
+	case ">":
+		return "<"
+	case ">=":
+		return "<="
+	}
+	return op
+}
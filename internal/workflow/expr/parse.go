@@ -0,0 +1,412 @@
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokNumber
+	tokString
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// lexer splits an expression into tokens.
+type lexer struct {
+	src string
+	pos int
+}
+
+var operators = []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "(", ")", "[", "]", ",", ".", "-"}
+
+func (l *lexer) next() (token, error) {
+	for l.pos < len(l.src) && isSpace(l.src[l.pos]) {
+		l.pos++
+	}
+	if l.pos >= len(l.src) {
+		return token{kind: tokEOF, pos: l.pos}, nil
+	}
+
+	start := l.pos
+	c := l.src[l.pos]
+	switch {
+	case isIdentStart(c):
+		for l.pos < len(l.src) && isIdentPart(l.src[l.pos]) {
+			l.pos++
+		}
+		return token{kind: tokIdent, text: l.src[start:l.pos], pos: start}, nil
+
+	case isDigit(c):
+		for l.pos < len(l.src) && (isDigit(l.src[l.pos]) || l.src[l.pos] == '.') {
+			l.pos++
+		}
+		return token{kind: tokNumber, text: l.src[start:l.pos], pos: start}, nil
+
+	case c == '\'' || c == '"':
+		s, err := l.scanString(c)
+		if err != nil {
+			return token{}, err
+		}
+		return token{kind: tokString, text: s, pos: start}, nil
+	}
+
+	for _, op := range operators {
+		if strings.HasPrefix(l.src[l.pos:], op) {
+			l.pos += len(op)
+			return token{kind: tokOp, text: op, pos: start}, nil
+		}
+	}
+	return token{}, &SyntaxError{Offset: start, Msg: fmt.Sprintf("unexpected character %q", c)}
+}
+
+func (l *lexer) scanString(quote byte) (string, error) {
+	start := l.pos
+	l.pos++
+
+	var sb strings.Builder
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == quote:
+			l.pos++
+			return sb.String(), nil
+		case c == '\\' && l.pos+1 < len(l.src):
+			l.pos++
+			switch e := l.src[l.pos]; e {
+			case 'n':
+				sb.WriteByte('\n')
+			case 't':
+				sb.WriteByte('\t')
+			default:
+				sb.WriteByte(e)
+			}
+		default:
+			sb.WriteByte(c)
+		}
+		l.pos++
+	}
+	return "", &SyntaxError{Offset: start, Msg: "unterminated string"}
+}
+
+func isSpace(c byte) bool      { return c == ' ' || c == '\t' || c == '\n' || c == '\r' }
+func isDigit(c byte) bool      { return c >= '0' && c <= '9' }
+func isIdentStart(c byte) bool { return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') }
+func isIdentPart(c byte) bool  { return isIdentStart(c) || isDigit(c) }
+
+// parser is a recursive descent parser for the grammar:
+//
+//	or      = and { ("||" | "or") and }
+//	and     = not { ("&&" | "and") not }
+//	not     = ("!" | "not") not | compare
+//	compare = unary [ ("==" | "!=" | "<" | "<=" | ">" | ">=") unary ]
+//	unary   = "-" unary | postfix
+//	postfix = primary { "." ident | "[" or "]" }
+//	primary = number | string | "true" | "false" | "null"
+//	        | ident "(" [ or { "," or } ] ")"
+//	        | "workflow" "." "input"
+//	        | "steps" "." ident "." "output"
+//	        | var
+//	        | "(" or ")"
+type parser struct {
+	lex  lexer
+	tok  token
+	vars map[string]bool
+}
+
+func (p *parser) parse() (node, error) {
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if p.tok.kind == tokEOF {
+		return nil, &SyntaxError{Offset: 0, Msg: "empty expression"}
+	}
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokEOF {
+		return nil, p.unexpected()
+	}
+	return n, nil
+}
+
+func (p *parser) advance() error {
+	tok, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+// is reports whether the current token is the operator or keyword s.
+func (p *parser) is(s string) bool {
+	return (p.tok.kind == tokOp || p.tok.kind == tokIdent) && p.tok.text == s
+}
+
+func (p *parser) expect(s string) error {
+	if !p.is(s) {
+		return &SyntaxError{Offset: p.tok.pos, Msg: fmt.Sprintf("expected %q, found %s", s, p.describe())}
+	}
+	return p.advance()
+}
+
+func (p *parser) expectIdent() (string, error) {
+	if p.tok.kind != tokIdent {
+		return "", &SyntaxError{Offset: p.tok.pos, Msg: fmt.Sprintf("expected identifier, found %s", p.describe())}
+	}
+	name := p.tok.text
+	return name, p.advance()
+}
+
+func (p *parser) unexpected() error {
+	return &SyntaxError{Offset: p.tok.pos, Msg: "unexpected " + p.describe()}
+}
+
+func (p *parser) describe() string {
+	switch p.tok.kind {
+	case tokEOF:
+		return "end of expression"
+	case tokString:
+		return strconv.Quote(p.tok.text)
+	default:
+		return fmt.Sprintf("%q", p.tok.text)
+	}
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.is("||") || p.is("or") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &binary{op: "||", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.is("&&") || p.is("and") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &binary{op: "&&", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (node, error) {
+	if p.is("!") || p.is("not") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		x, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &unary{op: "!", x: x}, nil
+	}
+	return p.parseCompare()
+}
+
+func (p *parser) parseCompare() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokOp {
+		return left, nil
+	}
+	switch op := p.tok.text; op {
+	case "==", "!=", "<", "<=", ">", ">=":
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &binary{op: op, left: left, right: right}, nil
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.is("-") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unary{op: "-", x: x}, nil
+	}
+	return p.parsePostfix()
+}
+
+func (p *parser) parsePostfix() (node, error) {
+	x, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.is("."):
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			name, err := p.expectIdent()
+			if err != nil {
+				return nil, err
+			}
+			x = &member{x: x, name: name}
+		case p.is("["):
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			idx, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			x = &index{x: x, index: idx}
+		default:
+			return x, nil
+		}
+	}
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.tok
+	switch tok.kind {
+	case tokNumber:
+		f, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, &SyntaxError{Offset: tok.pos, Msg: fmt.Sprintf("invalid number %q", tok.text)}
+		}
+		return &literal{value: f}, p.advance()
+
+	case tokString:
+		return &literal{value: tok.text}, p.advance()
+
+	case tokIdent:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		switch tok.text {
+		case "true":
+			return &literal{value: true}, nil
+		case "false":
+			return &literal{value: false}, nil
+		case "null":
+			return &literal{value: nil}, nil
+		case "workflow":
+			if err := p.expect("."); err != nil {
+				return nil, err
+			}
+			if err := p.expect("input"); err != nil {
+				return nil, err
+			}
+			return &inputRef{}, nil
+		case "steps":
+			if err := p.expect("."); err != nil {
+				return nil, err
+			}
+			name, err := p.expectIdent()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("."); err != nil {
+				return nil, err
+			}
+			if err := p.expect("output"); err != nil {
+				return nil, err
+			}
+			return &stepRef{step: name}, nil
+		}
+		if p.is("(") {
+			return p.parseCall(tok)
+		}
+		if p.vars[tok.text] {
+			return &varRef{name: tok.text}, nil
+		}
+		if len(p.vars) > 0 {
+			return nil, &SyntaxError{Offset: tok.pos, Msg: fmt.Sprintf("unknown identifier %q", tok.text)}
+		}
+		return nil, &SyntaxError{Offset: tok.pos, Msg: fmt.Sprintf("unknown identifier %q (expected workflow.input or steps.<name>.output)", tok.text)}
+
+	case tokOp:
+		if tok.text == "(" {
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			x, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			return x, p.expect(")")
+		}
+	}
+	return nil, p.unexpected()
+}
+
+func (p *parser) parseCall(name token) (node, error) {
+	fn, ok := functions[name.text]
+	if !ok {
+		return nil, &SyntaxError{Offset: name.pos, Msg: fmt.Sprintf("unknown function %q", name.text)}
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	var args []node
+	for !p.is(")") {
+		if len(args) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		arg, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	if len(args) != fn.arity {
+		return nil, &SyntaxError{Offset: name.pos, Msg: fmt.Sprintf("function %s expects %d argument(s), got %d", name.text, fn.arity, len(args))}
+	}
+	return &call{name: name.text, fn: fn.impl, args: args}, nil
+}
@@ -184,7 +184,12 @@ func (c *Client) ValidateEndpoint(ctx context.Context, url string) error {
 
 // doSend performs the actual HTTP request.
 func (c *Client) doSend(ctx context.Context, webhook *Webhook) (int, string, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(webhook.Payload))
+	method := webhook.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, webhook.URL, bytes.NewReader(webhook.Payload))
 	if err != nil {
 		return 0, "", fmt.Errorf("creating request: %w", err)
 	}
@@ -197,8 +202,13 @@ func (c *Client) doSend(ctx context.Context, webhook *Webhook) (int, string, err
 		req.Header.Set(key, value)
 	}
 
-	// Add HMAC signature if secret is provided
-	if webhook.Secret != "" {
+	// Sign the request with the webhook's signer, or with an HMAC
+	// signature if a secret is provided
+	if webhook.Signer != nil {
+		if err := webhook.Signer.SignRequest(req.Header, webhook.ID, webhook.Payload); err != nil {
+			return 0, "", fmt.Errorf("signing request: %w", err)
+		}
+	} else if webhook.Secret != "" {
 		signature := signPayload(webhook.Secret, webhook.Payload)
 		req.Header.Set("X-Webhook-Signature", signature)
 		req.Header.Set("X-Webhook-Signature-Algorithm", "sha256")
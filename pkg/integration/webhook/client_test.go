@@ -136,6 +136,41 @@ func TestClient_Send_WithSignature(t *testing.T) {
 	assert.NotEmpty(t, receivedSignature)
 }
 
+type headerSigner struct{}
+
+func (headerSigner) SignRequest(header http.Header, webhookID string, payload []byte) error {
+	header.Set("webhook-id", webhookID)
+	header.Set("webhook-signature", "v1,"+string(payload))
+	return nil
+}
+
+func TestClient_Send_WithSigner(t *testing.T) {
+	var received http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Clone()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewClient(DefaultConfig())
+
+	webhook := &Webhook{
+		ID:      "test-webhook-5",
+		URL:     server.URL,
+		Payload: json.RawMessage(`{}`),
+		Secret:  "my-secret-key",
+		Signer:  headerSigner{},
+	}
+
+	result, err := client.Send(context.Background(), webhook)
+
+	require.NoError(t, err)
+	assert.True(t, result.Success)
+	assert.Equal(t, "test-webhook-5", received.Get("webhook-id"))
+	assert.Equal(t, "v1,{}", received.Get("webhook-signature"))
+	assert.Empty(t, received.Get("X-Webhook-Signature"), "the signer takes the place of the secret's signature")
+}
+
 func TestClient_SendBatch(t *testing.T) {
 	responseCount := 0
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
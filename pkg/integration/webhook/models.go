@@ -3,6 +3,7 @@ package webhook
 
 import (
 	"encoding/json"
+	"net/http"
 	"time"
 )
 
@@ -10,15 +11,24 @@ import (
 type Webhook struct {
 	ID          string            `json:"id"`
 	URL         string            `json:"url"`
+	Method      string            `json:"method,omitempty"` // Defaults to POST
 	EventType   string            `json:"event_type"`
 	EventID     string            `json:"event_id"`
 	Payload     json.RawMessage   `json:"payload"`
 	Headers     map[string]string `json:"headers,omitempty"`
 	Secret      string            `json:"-"` // For HMAC signing, not serialized
+	Signer      Signer            `json:"-"` // Signs the request in place of Secret if set
 	Timeout     time.Duration     `json:"-"`
 	RetryPolicy *RetryPolicy      `json:"-"`
 }
 
+// Signer signs webhook requests, for example with another signature scheme
+// or several secrets. It is called before every delivery attempt with the
+// webhook ID and payload.
+type Signer interface {
+	SignRequest(header http.Header, webhookID string, payload []byte) error
+}
+
 // DeliveryResult represents the outcome of a webhook delivery attempt.
 type DeliveryResult struct {
 	WebhookID    string        `json:"webhook_id"`
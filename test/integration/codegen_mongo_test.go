@@ -0,0 +1,77 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bargom/codeai/internal/codegen"
+	"github.com/bargom/codeai/internal/database/mongodb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMongoAdapterCRUD(t *testing.T) {
+	_, client := mongodb.SetupTestContainerWithClient(t, "codegen_mongo_test")
+	adapter := codegen.NewMongoAdapter(client, nil)
+	ctx := context.Background()
+
+	inserted, err := adapter.Insert(ctx, "tasks", map[string]interface{}{"title": "write tests", "done": false})
+	require.NoError(t, err)
+	oid, ok := inserted.(primitive.ObjectID)
+	require.True(t, ok, "expected ObjectID, got %T", inserted)
+	id := oid.Hex()
+
+	t.Run("find one by hex id", func(t *testing.T) {
+		doc, err := adapter.FindOne(ctx, "tasks", id)
+		require.NoError(t, err)
+		require.NotNil(t, doc)
+		assert.Equal(t, "write tests", doc["title"])
+	})
+
+	t.Run("update reports counts", func(t *testing.T) {
+		result, err := adapter.Update(ctx, "tasks", id, map[string]interface{}{"done": true})
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), result.Matched)
+		assert.Equal(t, int64(1), result.Modified)
+
+		doc, err := adapter.FindOne(ctx, "tasks", id)
+		require.NoError(t, err)
+		assert.Equal(t, true, doc["done"])
+		assert.NotContains(t, doc, "updatedAt")
+		stamped := doc["updated_at"]
+		require.NotNil(t, stamped)
+
+		result, err = adapter.Update(ctx, "tasks", id, map[string]interface{}{"done": true})
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), result.Matched)
+		assert.Zero(t, result.Modified)
+
+		doc, err = adapter.FindOne(ctx, "tasks", id)
+		require.NoError(t, err)
+		assert.Equal(t, stamped, doc["updated_at"])
+	})
+
+	t.Run("missing documents", func(t *testing.T) {
+		missing := primitive.NewObjectID().Hex()
+
+		doc, err := adapter.FindOne(ctx, "tasks", missing)
+		require.NoError(t, err)
+		assert.Nil(t, doc)
+
+		var notFound *codegen.NotFoundError
+		_, err = adapter.Update(ctx, "tasks", missing, map[string]interface{}{"done": true})
+		assert.ErrorAs(t, err, &notFound)
+		assert.ErrorAs(t, adapter.Delete(ctx, "tasks", missing), &notFound)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		require.NoError(t, adapter.Delete(ctx, "tasks", id))
+
+		doc, err := adapter.FindOne(ctx, "tasks", id)
+		require.NoError(t, err)
+		assert.Nil(t, doc)
+	})
+}
@@ -0,0 +1,189 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bargom/codeai/internal/database/schema"
+	"github.com/bargom/codeai/internal/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const schemaTestModels = `
+database postgres {
+    model User {
+        id: uuid, primary, auto
+        email: string, required, unique
+        role: enum(reader, author), default(reader)
+        created_at: timestamp, auto
+    }
+
+    model Post {
+        id: uuid, primary, auto
+        title: string, required
+        tags: list(string)
+        author_id: ref(User), required
+        reviewer_id: ref(User)
+
+        index: [author_id]
+    }
+}
+`
+
+func TestSchemaApplyAndVerify(t *testing.T) {
+	tc := SetupPostgresTestContainer(t, "schema_test")
+	ctx := context.Background()
+
+	program, err := parser.Parse(schemaTestModels)
+	require.NoError(t, err)
+	s, err := schema.FromProgram(program)
+	require.NoError(t, err)
+
+	mgr := schema.NewManager(tc.DB)
+
+	t.Run("verify fails before apply", func(t *testing.T) {
+		err := mgr.Verify(ctx, s)
+		var verr *schema.VerifyError
+		require.ErrorAs(t, err, &verr)
+		assert.Contains(t, verr.Problems, "table users does not exist")
+	})
+
+	t.Run("apply creates tables", func(t *testing.T) {
+		require.NoError(t, mgr.Apply(ctx, s))
+		require.NoError(t, mgr.Verify(ctx, s))
+	})
+
+	t.Run("apply is idempotent", func(t *testing.T) {
+		require.NoError(t, mgr.Apply(ctx, s))
+	})
+
+	t.Run("generated constraints are enforced", func(t *testing.T) {
+		var userID string
+		err := tc.DB.QueryRowContext(ctx,
+			`INSERT INTO users (email) VALUES ('a@example.com') RETURNING id`).Scan(&userID)
+		require.NoError(t, err)
+
+		_, err = tc.DB.ExecContext(ctx, `INSERT INTO users (email, role) VALUES ('b@example.com', 'owner')`)
+		assert.Error(t, err, "enum check should reject unknown role")
+
+		_, err = tc.DB.ExecContext(ctx,
+			`INSERT INTO posts (title, author_id) VALUES ('x', '00000000-0000-0000-0000-000000000000')`)
+		assert.Error(t, err, "foreign key should reject unknown author")
+
+		_, err = tc.DB.ExecContext(ctx,
+			`INSERT INTO posts (title, tags, author_id) VALUES ('hello', ARRAY['go'], $1)`, userID)
+		require.NoError(t, err)
+
+		_, err = tc.DB.ExecContext(ctx, `DELETE FROM users WHERE id = $1`, userID)
+		require.NoError(t, err)
+
+		var count int
+		require.NoError(t, tc.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM posts`).Scan(&count))
+		assert.Zero(t, count, "required ref should cascade on delete")
+	})
+
+	t.Run("verify reports drift", func(t *testing.T) {
+		_, err := tc.DB.ExecContext(ctx, `ALTER TABLE posts DROP COLUMN reviewer_id`)
+		require.NoError(t, err)
+
+		err = mgr.Verify(ctx, s)
+		var verr *schema.VerifyError
+		require.ErrorAs(t, err, &verr)
+		assert.Equal(t, []string{"column posts.reviewer_id does not exist"}, verr.Problems)
+	})
+}
+
+func TestSchemaInspectRoundTrip(t *testing.T) {
+	tc := SetupPostgresTestContainer(t, "schema_inspect_test")
+	ctx := context.Background()
+
+	program, err := parser.Parse(schemaTestModels)
+	require.NoError(t, err)
+	s, err := schema.FromProgram(program)
+	require.NoError(t, err)
+
+	mgr := schema.NewManager(tc.DB)
+	require.NoError(t, mgr.Apply(ctx, s))
+
+	live, err := mgr.Inspect(ctx)
+	require.NoError(t, err)
+
+	changes := schema.Diff(live, s)
+	for _, ch := range changes.Changes {
+		t.Errorf("unexpected change after apply: %s", ch.Description)
+	}
+
+	t.Run("generated migration applies cleanly", func(t *testing.T) {
+		next, err := parser.Parse(`
+database postgres {
+    model User {
+        id: uuid, primary, auto
+        email: text, required
+        role: enum(reader, author, editor), default(author)
+        created_at: timestamp, auto
+    }
+
+    model Post {
+        id: uuid, primary, auto
+        title: string, required
+        tags: list(string)
+        author_id: ref(User), required
+
+        index: [author_id, title]
+    }
+}
+`)
+		require.NoError(t, err)
+		target, err := schema.FromProgram(next)
+		require.NoError(t, err)
+
+		changes := schema.Diff(live, target)
+		require.NotEmpty(t, changes.Destructive(), "dropping posts.reviewer_id is destructive")
+
+		_, err = tc.DB.ExecContext(ctx, changes.UpSQL())
+		require.NoError(t, err)
+
+		after, err := mgr.Inspect(ctx)
+		require.NoError(t, err)
+		assert.True(t, schema.Diff(after, target).Empty(), "schema should match target after up migration")
+
+		_, err = tc.DB.ExecContext(ctx, changes.DownSQL())
+		require.NoError(t, err)
+
+		reverted, err := mgr.Inspect(ctx)
+		require.NoError(t, err)
+		assert.True(t, schema.Diff(reverted, s).Empty(), "schema should match original after down migration")
+	})
+}
+
+func TestSchemaInspectSkipsRuntimeTables(t *testing.T) {
+	tc := SetupPostgresTestContainer(t, "schema_runtime_tables_test")
+	ctx := context.Background()
+
+	program, err := parser.Parse(schemaTestModels)
+	require.NoError(t, err)
+	s, err := schema.FromProgram(program)
+	require.NoError(t, err)
+
+	mgr := schema.NewManager(tc.DB)
+	require.NoError(t, mgr.Apply(ctx, s))
+
+	// Tables the server creates for itself next to the model tables
+	for _, stmt := range []string{
+		`CREATE TABLE event_outbox (id TEXT PRIMARY KEY)`,
+		`CREATE TABLE configs (id UUID PRIMARY KEY)`,
+		`CREATE TABLE codeai_webhooks (id TEXT PRIMARY KEY)`,
+	} {
+		_, err := tc.DB.ExecContext(ctx, stmt)
+		require.NoError(t, err)
+	}
+
+	live, err := mgr.Inspect(ctx)
+	require.NoError(t, err)
+
+	changes := schema.Diff(live, s)
+	assert.True(t, changes.Empty(), "runtime tables are not dropped: %v", changes.Changes)
+}